@@ -9,4 +9,70 @@ new ApiStack(app, 'HaikuApiStack', {
     region: process.env.CDK_DEFAULT_REGION 
   },
   ipRateLimit: parseInt(process.env.IP_RATE_LIMIT || ''),
+  promptExperiment: process.env.PROMPT_EXPERIMENT,
+  moderationGuardrailId: process.env.MODERATION_GUARDRAIL_ID,
+  moderationGuardrailVersion: process.env.MODERATION_GUARDRAIL_VERSION,
+  illustrationModelId: process.env.ILLUSTRATION_MODEL_ID,
+  canaryModelId: process.env.CANARY_MODEL_ID,
+  canaryPercent: process.env.CANARY_PERCENT,
+  shadowPercent: process.env.SHADOW_PERCENT,
+  shadowPromptVersion: process.env.SHADOW_PROMPT_VERSION,
+  shadowModelId: process.env.SHADOW_MODEL_ID,
+  shadowDailyTokens: process.env.SHADOW_DAILY_TOKENS,
+  voiceId: process.env.VOICE_ID,
+  githubAppId: process.env.GITHUB_APP_ID,
+  githubAppPrivateKey: process.env.GITHUB_APP_PRIVATE_KEY,
+  githubWebhookSecret: process.env.GITHUB_WEBHOOK_SECRET,
+  githubIssueLookup: process.env.GITHUB_ISSUE_LOOKUP,
+  jiraUrl: process.env.JIRA_URL,
+  jiraEmail: process.env.JIRA_EMAIL,
+  jiraApiToken: process.env.JIRA_API_TOKEN,
+  issueBindings: process.env.ISSUE_BINDINGS,
+  gitlabUrl: process.env.GITLAB_URL,
+  gitlabToken: process.env.GITLAB_TOKEN,
+  gitlabWebhookToken: process.env.GITLAB_WEBHOOK_TOKEN,
+  bitbucketToken: process.env.BITBUCKET_TOKEN,
+  bitbucketWebhookSecret: process.env.BITBUCKET_WEBHOOK_SECRET,
+  slackSigningSecret: process.env.SLACK_SIGNING_SECRET,
+  slackNotifyWebhooks: process.env.SLACK_NOTIFY_WEBHOOKS,
+  discordPublicKey: process.env.DISCORD_PUBLIC_KEY,
+  teamsWebhookSecret: process.env.TEAMS_WEBHOOK_SECRET,
+  moodRules: process.env.MOOD_RULES,
+  mergeQueueBranches: process.env.MERGE_QUEUE_BRANCHES,
+  mergeQueueHaiku: process.env.MERGE_QUEUE_HAIKU,
+  errorReporting: process.env.ERROR_REPORTING,
+  sentryDsn: process.env.SENTRY_DSN,
+  errorMetrics: process.env.ERROR_METRICS,
+  logLevel: process.env.LOG_LEVEL,
+  logFormat: process.env.LOG_FORMAT,
+  logCommitContent: process.env.LOG_COMMIT_CONTENT,
+  piiRedaction: process.env.PII_REDACTION,
+  fallbackHaiku: process.env.FALLBACK_HAIKU,
+  sharedResponseCache: process.env.SHARED_RESPONSE_CACHE,
+  haikuJobs: process.env.HAIKU_JOBS,
+  callbackSecret: process.env.CALLBACK_SECRET,
+  jobTtl: process.env.JOB_TTL,
+  dailyHaiku: process.env.DAILY_HAIKU,
+  renga: process.env.RENGA,
+  haikuVotes: process.env.HAIKU_VOTES,
+  publicationGuardrailId: process.env.PUBLICATION_GUARDRAIL_ID,
+  publicationGuardrailVersion: process.env.PUBLICATION_GUARDRAIL_VERSION,
+  haikuRetention: process.env.HAIKU_RETENTION,
+  haikuExport: process.env.HAIKU_EXPORT,
+  exportRetentionDays: process.env.EXPORT_RETENTION_DAYS,
+  haikuEvents: process.env.HAIKU_EVENTS,
+  eventBus: process.env.EVENT_BUS,
+  statsToken: process.env.STATS_TOKEN,
+  adminToken: process.env.ADMIN_TOKEN,
+  digestSender: process.env.DIGEST_SENDER,
+  publicUrl: process.env.PUBLIC_URL,
+  requireApiKey: process.env.REQUIRE_API_KEY,
+  warmUpMinutes: process.env.WARM_UP_MINUTES,
+  tracing: process.env.TRACING,
+  stage: process.env.STAGE,
+  featureFlags: process.env.FEATURE_FLAGS,
+  appConfigApplication: process.env.APPCONFIG_APPLICATION,
+  appConfigEnvironment: process.env.APPCONFIG_ENVIRONMENT,
+  appConfigProfile: process.env.APPCONFIG_PROFILE,
+  appConfigExtensionArn: process.env.APPCONFIG_EXTENSION_ARN,
 });
\ No newline at end of file
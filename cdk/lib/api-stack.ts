@@ -2,14 +2,141 @@ import * as cdk from 'aws-cdk-lib';
 import { Construct } from 'constructs';
 import * as apigateway from 'aws-cdk-lib/aws-apigateway';
 import * as lambda from 'aws-cdk-lib/aws-lambda';
+import * as lambdaEventSources from 'aws-cdk-lib/aws-lambda-event-sources';
 import * as wafv2 from 'aws-cdk-lib/aws-wafv2';
 import * as iam from 'aws-cdk-lib/aws-iam';
 import * as logs from 'aws-cdk-lib/aws-logs';
+import * as s3 from 'aws-cdk-lib/aws-s3';
+import * as sns from 'aws-cdk-lib/aws-sns';
+import * as dynamodb from 'aws-cdk-lib/aws-dynamodb';
+import * as events from 'aws-cdk-lib/aws-events';
+import * as targets from 'aws-cdk-lib/aws-events-targets';
 import { WafConstruct } from './constructs/waf';
 
 export interface ApiStackProps extends cdk.StackProps {
   /** WAF rate limit per 5-minute window per IP */
   ipRateLimit?: number;
+  /** Prompt version traffic split, e.g. "v1:90,v2:10" */
+  promptExperiment?: string;
+  /** Optional Bedrock guardrail applied to generated haiku */
+  moderationGuardrailId?: string;
+  moderationGuardrailVersion?: string;
+  /** Optional Bedrock image model used to render haiku illustrations */
+  illustrationModelId?: string;
+  /** Optional Claude model, or inference profile, serving canaryPercent of requests to try it before switching */
+  canaryModelId?: string;
+  canaryPercent?: string;
+  /** Optional percent of haiku written again after the response with shadowPromptVersion and/or shadowModelId, kept in DynamoDB */
+  shadowPercent?: string;
+  shadowPromptVersion?: string;
+  shadowModelId?: string;
+  /** Optional tokens the shadow may spend per UTC day (default: 100000) */
+  shadowDailyTokens?: string;
+  /** Optional Polly voice used to read haiku aloud */
+  voiceId?: string;
+  /** Optional GitHub App that posts haiku back to commits and pull requests */
+  githubAppId?: string;
+  githubAppPrivateKey?: string;
+  /** Optional secret enabling POST /webhooks/github */
+  githubWebhookSecret?: string;
+  /** Optional 'true' to look up the titles of #456 issue references with the GitHub App */
+  githubIssueLookup?: string;
+  /** Optional Jira site, account and API token used to look up issue keys such as JIRA-4521 */
+  jiraUrl?: string;
+  jiraEmail?: string;
+  jiraApiToken?: string;
+  /** Optional JSON list of the Jira projects and GitHub repositories whose issues each tenant's commits are described with */
+  issueBindings?: string;
+  /** Optional GitLab instance and access token used to post haiku back */
+  gitlabUrl?: string;
+  gitlabToken?: string;
+  /** Optional secret token enabling POST /webhooks/gitlab */
+  gitlabWebhookToken?: string;
+  /** Optional Bitbucket Cloud access token used to post haiku back */
+  bitbucketToken?: string;
+  /** Optional secret enabling POST /webhooks/bitbucket */
+  bitbucketWebhookSecret?: string;
+  /** Optional signing secret enabling POST /integrations/slack */
+  slackSigningSecret?: string;
+  /** Optional JSON list of Slack incoming webhooks newly written haiku are pushed to, queueing them in DynamoDB */
+  slackNotifyWebhooks?: string;
+  /** Optional Discord application public key enabling POST /integrations/discord */
+  discordPublicKey?: string;
+  /** Optional Teams outgoing webhook security token enabling POST /integrations/teams */
+  teamsWebhookSecret?: string;
+  /** Optional webhook mood rules, e.g. branch:release/*=humorous,path:octo/=technical */
+  moodRules?: string;
+  /** Optional merge queue branch prefixes whose webhook events are ignored */
+  mergeQueueBranches?: string;
+  /** Optional 'true' to generate haiku for merge queue branches too */
+  mergeQueueHaiku?: string;
+  /** Optional error reporting sink: 'log' (default), 'sentry' or 'off' */
+  errorReporting?: string;
+  /** Optional Sentry DSN receiving panics and 5xx responses when errorReporting is 'sentry' */
+  sentryDsn?: string;
+  /** Optional 'false' to stop counting failed requests by error class as CloudWatch metrics */
+  errorMetrics?: string;
+  /** Optional least severe level logged: 'debug', 'info' (default), 'warn' or 'error' */
+  logLevel?: string;
+  /** Optional log line format: 'json' (default in Lambda) or 'text' */
+  logFormat?: string;
+  /** Optional way commit messages appear in logs: 'hash' (default), 'redact' or 'full' */
+  logCommitContent?: string;
+  /** Optional removal of personal data from commit text before it reaches the model: 'off' (default), 'patterns' or 'comprehend' */
+  piiRedaction?: string;
+  /** Optional 'true' to return a locally written haiku while Bedrock is unavailable */
+  fallbackHaiku?: string;
+  /** Optional 'true' to share cached haiku between Lambda instances through DynamoDB */
+  sharedResponseCache?: string;
+  /** Optional 'true' to enable POST /haiku/jobs, keeping background jobs in DynamoDB */
+  haikuJobs?: string;
+  /** Optional secret signing the callbacks posted when background jobs finish */
+  callbackSecret?: string;
+  /** Optional Go duration background jobs are kept for, e.g. "1h" (default: 24h) */
+  jobTtl?: string;
+  /** Optional 'true' to enable GET /haiku/daily, keeping the haiku of the day in DynamoDB */
+  dailyHaiku?: string;
+  /** Optional 'true' to enable POST and GET /haiku/renga, keeping repositories' renga in DynamoDB */
+  renga?: string;
+  /** Optional 'true' to enable POST /haiku/{id}/vote and GET /haiku/top, keeping served haiku and votes in DynamoDB */
+  haikuVotes?: string;
+  /** Optional Bedrock guardrail served haiku must pass before they can be voted for or listed */
+  publicationGuardrailId?: string;
+  publicationGuardrailVersion?: string;
+  /** Optional Go duration served haiku and their votes are kept for, e.g. "720h" (default: 90 days) */
+  haikuRetention?: string;
+  /** Optional 'true' to export kept haiku to S3 daily as JSON Lines, for Athena; needs haikuVotes */
+  haikuExport?: string;
+  /** Optional days exported haiku are kept in S3 (default: 90) */
+  exportRetentionDays?: string;
+  /** Optional 'true' to publish domain events, such as one for each newly written haiku, to an SNS topic */
+  haikuEvents?: string;
+  /** Optional existing EventBridge event bus, by name or ARN, domain events are put on */
+  eventBus?: string;
+  /** Optional bearer token enabling GET /stats, keeping daily usage counters in DynamoDB */
+  statsToken?: string;
+  /** Optional bearer token enabling the /admin/keys API, keeping API keys in DynamoDB */
+  adminToken?: string;
+  /** Optional SES verified address weekly haiku digests are emailed from; needs adminToken and haikuVotes */
+  digestSender?: string;
+  /** Optional public URL of the API, e.g. https://haiku.example.com, used for digest unsubscribe links */
+  publicUrl?: string;
+  /** Optional 'true' to require an API key on the haiku endpoints */
+  requireApiKey?: string;
+  /** Optional minutes between warm-up invocations that keep an instance ready (default: none) */
+  warmUpMinutes?: string;
+  /** Optional 'true' to trace requests with X-Ray, each stage as a subsegment */
+  tracing?: string;
+  /** Optional stage the stack is deployed to, e.g. 'prod', which feature flags can be limited to */
+  stage?: string;
+  /** Optional feature flags as JSON, e.g. '{"export": {"enabled": false}}' */
+  featureFlags?: string;
+  /** Optional AppConfig feature flag profile, read through the AppConfig Lambda extension over featureFlags */
+  appConfigApplication?: string;
+  appConfigEnvironment?: string;
+  appConfigProfile?: string;
+  /** ARN of the AppConfig Lambda extension layer for the stack's region; needed with appConfigProfile */
+  appConfigExtensionArn?: string;
 }
 
 export class ApiStack extends cdk.Stack {
@@ -20,6 +147,147 @@ export class ApiStack extends cdk.Stack {
   constructor(scope: Construct, id: string, props: ApiStackProps) {
     super(scope, id, props);
 
+    // Prompt templates are read from SSM Parameter Store so they can be tuned without a deploy
+    const promptParameterPath = '/commits-fall-like-leaves/prompts';
+
+    // Share cards and audio are disposable renders, so they expire shortly after their links do
+    const artifactBucket = new s3.Bucket(this, 'ArtifactBucket', {
+      blockPublicAccess: s3.BlockPublicAccess.BLOCK_ALL,
+      encryption: s3.BucketEncryption.S3_MANAGED,
+      enforceSSL: true,
+      removalPolicy: cdk.RemovalPolicy.DESTROY,
+      autoDeleteObjects: true,
+      lifecycleRules: [{ expiration: cdk.Duration.days(7) }]
+    });
+
+    // Exports are kept for analysis after the API stops serving their haiku, but they still expire
+    const exportRetentionDays = parseInt(props.exportRetentionDays ?? '', 10) || 90;
+    const exportBucket = props.haikuExport === 'true' && props.haikuVotes === 'true'
+      ? new s3.Bucket(this, 'ExportBucket', {
+          blockPublicAccess: s3.BlockPublicAccess.BLOCK_ALL,
+          encryption: s3.BucketEncryption.S3_MANAGED,
+          enforceSSL: true,
+          removalPolicy: cdk.RemovalPolicy.RETAIN,
+          lifecycleRules: [{ expiration: cdk.Duration.days(exportRetentionDays) }]
+        })
+      : undefined;
+
+    // Cached haiku are shared across callers, so the table is opt-in. DynamoDB deletes expired entries
+    const responseCacheTable = props.sharedResponseCache === 'true'
+      ? new dynamodb.Table(this, 'ResponseCacheTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // Background jobs are polled from whichever instance answers, so they are kept in DynamoDB until they expire
+    const jobTable = props.haikuJobs === 'true'
+      ? new dynamodb.Table(this, 'JobTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // The haiku of the day is written by whichever instance is asked first and read by the rest
+    const dailyTable = props.dailyHaiku === 'true'
+      ? new dynamodb.Table(this, 'DailyHaikuTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // A renga's verses are added through whichever instance answers, and kept for as long as served haiku
+    const rengaTable = props.renga === 'true'
+      ? new dynamodb.Table(this, 'RengaTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.RETAIN
+        })
+      : undefined;
+
+    // Consumers downstream subscribe to the topic rather than read the tables
+    const eventsTopic = props.haikuEvents === 'true'
+      ? new sns.Topic(this, 'HaikuEventsTopic', {
+          displayName: 'Commit haiku domain events'
+        })
+      : undefined;
+
+    // Haiku are queued by whichever instance writes them and posted by the scheduled flush
+    const notifyTable = props.slackNotifyWebhooks
+      ? new dynamodb.Table(this, 'NotifyTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // Haiku are voted on through whichever instance answers, so they and their votes are kept in DynamoDB until they expire.
+    // With usage statistics, the table's stream counts each haiku as it is kept
+    const statsFromStream = Boolean(props.statsToken && props.haikuVotes === 'true');
+    const voteTable = props.haikuVotes === 'true'
+      ? new dynamodb.Table(this, 'VoteTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          stream: statsFromStream ? dynamodb.StreamViewType.NEW_IMAGE : undefined,
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // Shadow haiku are written by whichever instance takes the asynchronous invocation, and compared offline
+    const shadowTable = parseInt(props.shadowPercent ?? '', 10) > 0
+      ? new dynamodb.Table(this, 'ShadowTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // Usage counters are added to by every instance, so they are kept in DynamoDB until they expire
+    const statsTable = props.statsToken
+      ? new dynamodb.Table(this, 'StatsTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.DESTROY
+        })
+      : undefined;
+
+    // API keys are checked by every instance and live until revoked, so they are kept in DynamoDB.
+    // Their monthly usage counters share the table and expire once they are no longer billed
+    const keyTable = props.adminToken || props.requireApiKey === 'true'
+      ? new dynamodb.Table(this, 'KeyTable', {
+          partitionKey: { name: 'key', type: dynamodb.AttributeType.STRING },
+          billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+          timeToLiveAttribute: 'expiresAt',
+          removalPolicy: cdk.RemovalPolicy.RETAIN
+        })
+      : undefined;
+
+    // Audit records are kept apart from the function's log, for longer, and outlive the stack
+    const auditLogGroup = new logs.LogGroup(this, 'AuditLogGroup', {
+      retention: logs.RetentionDays.ONE_YEAR,
+      removalPolicy: cdk.RemovalPolicy.RETAIN
+    });
+
+    // Digests are compiled from kept haiku, with subscriptions managed through the admin API
+    const digests = Boolean(props.digestSender && props.adminToken && voteTable);
+
+    // Flags in AppConfig are read through its Lambda extension, which caches them locally
+    const appConfigEnabled = !!(props.appConfigApplication && props.appConfigEnvironment && props.appConfigProfile && props.appConfigExtensionArn);
+    const appConfigExtension = appConfigEnabled
+      ? lambda.LayerVersion.fromLayerVersionArn(this, 'AppConfigExtension', props.appConfigExtensionArn!)
+      : undefined;
+
     // Create Lambda function
     this.lambdaFunction = new lambda.Function(this, 'HaikuLambdaFunction', {
       runtime: lambda.Runtime.PROVIDED_AL2023,
@@ -28,9 +296,174 @@ export class ApiStack extends cdk.Stack {
       timeout: cdk.Duration.seconds(30),
       memorySize: 256,
       architecture: lambda.Architecture.X86_64,
-      description: 'Lambda function to generate haiku from commit messages'
+      description: 'Lambda function to generate haiku from commit messages',
+      tracing: props.tracing === 'true' ? lambda.Tracing.ACTIVE : lambda.Tracing.DISABLED,
+      layers: appConfigExtension ? [appConfigExtension] : undefined,
+      environment: {
+        PROMPT_PARAMETER_PATH: promptParameterPath,
+        PROMPT_EXPERIMENT: props.promptExperiment ?? '',
+        MODERATION_GUARDRAIL_ID: props.moderationGuardrailId ?? '',
+        MODERATION_GUARDRAIL_VERSION: props.moderationGuardrailVersion ?? '',
+        ILLUSTRATION_MODEL_ID: props.illustrationModelId ?? '',
+        CANARY_MODEL_ID: props.canaryModelId ?? '',
+        CANARY_PERCENT: props.canaryPercent ?? '',
+        SHADOW_PERCENT: props.shadowPercent ?? '',
+        SHADOW_PROMPT_VERSION: props.shadowPromptVersion ?? '',
+        SHADOW_MODEL_ID: props.shadowModelId ?? '',
+        SHADOW_DAILY_TOKENS: props.shadowDailyTokens ?? '',
+        SHADOW_TABLE: shadowTable?.tableName ?? '',
+        ARTIFACT_BUCKET: artifactBucket.bucketName,
+        VOICE_ID: props.voiceId ?? '',
+        GITHUB_APP_ID: props.githubAppId ?? '',
+        GITHUB_APP_PRIVATE_KEY: props.githubAppPrivateKey ?? '',
+        GITHUB_WEBHOOK_SECRET: props.githubWebhookSecret ?? '',
+        GITHUB_ISSUE_LOOKUP: props.githubIssueLookup ?? '',
+        JIRA_URL: props.jiraUrl ?? '',
+        JIRA_EMAIL: props.jiraEmail ?? '',
+        JIRA_API_TOKEN: props.jiraApiToken ?? '',
+        ISSUE_BINDINGS: props.issueBindings ?? '',
+        GITLAB_URL: props.gitlabUrl ?? '',
+        GITLAB_TOKEN: props.gitlabToken ?? '',
+        GITLAB_WEBHOOK_TOKEN: props.gitlabWebhookToken ?? '',
+        BITBUCKET_TOKEN: props.bitbucketToken ?? '',
+        BITBUCKET_WEBHOOK_SECRET: props.bitbucketWebhookSecret ?? '',
+        SLACK_SIGNING_SECRET: props.slackSigningSecret ?? '',
+        SLACK_NOTIFY_WEBHOOKS: props.slackNotifyWebhooks ?? '',
+        NOTIFY_TABLE: notifyTable?.tableName ?? '',
+        DISCORD_PUBLIC_KEY: props.discordPublicKey ?? '',
+        TEAMS_WEBHOOK_SECRET: props.teamsWebhookSecret ?? '',
+        MOOD_RULES: props.moodRules ?? '',
+        MERGE_QUEUE_BRANCHES: props.mergeQueueBranches ?? '',
+        MERGE_QUEUE_HAIKU: props.mergeQueueHaiku ?? '',
+        ERROR_REPORTING: props.errorReporting ?? '',
+        SENTRY_DSN: props.sentryDsn ?? '',
+        ERROR_METRICS: props.errorMetrics ?? '',
+        LOG_LEVEL: props.logLevel ?? '',
+        LOG_FORMAT: props.logFormat ?? '',
+        LOG_COMMIT_CONTENT: props.logCommitContent ?? '',
+        PII_REDACTION: props.piiRedaction ?? '',
+        AUDIT_LOG_GROUP: auditLogGroup.logGroupName,
+        FALLBACK_HAIKU: props.fallbackHaiku ?? '',
+        RESPONSE_CACHE_TABLE: responseCacheTable?.tableName ?? '',
+        JOB_TABLE: jobTable?.tableName ?? '',
+        JOB_TTL: props.jobTtl ?? '',
+        CALLBACK_SECRET: props.callbackSecret ?? '',
+        DAILY_HAIKU_TABLE: dailyTable?.tableName ?? '',
+        RENGA_TABLE: rengaTable?.tableName ?? '',
+        VOTE_TABLE: voteTable?.tableName ?? '',
+        HAIKU_RETENTION: props.haikuRetention ?? '',
+        EXPORT_BUCKET: exportBucket?.bucketName ?? '',
+        HAIKU_EVENTS_TOPIC_ARN: eventsTopic?.topicArn ?? '',
+        EVENT_BUS_NAME: props.eventBus ?? '',
+        PUBLICATION_GUARDRAIL_ID: props.publicationGuardrailId ?? '',
+        PUBLICATION_GUARDRAIL_VERSION: props.publicationGuardrailVersion ?? '',
+        STATS_TOKEN: props.statsToken ?? '',
+        STATS_TABLE: statsTable?.tableName ?? '',
+        STATS_FROM_STREAM: statsFromStream ? 'true' : '',
+        ADMIN_TOKEN: props.adminToken ?? '',
+        KEY_TABLE: keyTable?.tableName ?? '',
+        DIGEST_SENDER: digests ? props.digestSender! : '',
+        PUBLIC_URL: props.publicUrl ?? '',
+        REQUIRE_API_KEY: props.requireApiKey ?? '',
+        STAGE: props.stage ?? '',
+        FEATURE_FLAGS: props.featureFlags ?? '',
+        APPCONFIG_APPLICATION: appConfigEnabled ? props.appConfigApplication! : '',
+        APPCONFIG_ENVIRONMENT: appConfigEnabled ? props.appConfigEnvironment! : '',
+        APPCONFIG_PROFILE: appConfigEnabled ? props.appConfigProfile! : '',
+      }
     });
 
+    artifactBucket.grantPut(this.lambdaFunction);
+    artifactBucket.grantRead(this.lambdaFunction);
+    responseCacheTable?.grantReadWriteData(this.lambdaFunction);
+    jobTable?.grantReadWriteData(this.lambdaFunction);
+    dailyTable?.grantReadWriteData(this.lambdaFunction);
+    rengaTable?.grantReadWriteData(this.lambdaFunction);
+    notifyTable?.grantReadWriteData(this.lambdaFunction);
+    eventsTopic?.grantPublish(this.lambdaFunction);
+    voteTable?.grantReadWriteData(this.lambdaFunction);
+    statsTable?.grantReadWriteData(this.lambdaFunction);
+    keyTable?.grantReadWriteData(this.lambdaFunction);
+    shadowTable?.grantReadWriteData(this.lambdaFunction);
+    auditLogGroup.grantWrite(this.lambdaFunction);
+
+    // Yesterday's haiku are exported shortly after midnight UTC
+    if (exportBucket) {
+      exportBucket.grantPut(this.lambdaFunction);
+      // Days left without haiku, e.g. by an erasure, have their export deleted
+      exportBucket.grantDelete(this.lambdaFunction);
+      new events.Rule(this, 'ExportRule', {
+        schedule: events.Schedule.cron({ minute: '30', hour: '0' }),
+        targets: [new targets.LambdaFunction(this.lambdaFunction, {
+          event: events.RuleTargetInput.fromObject({ export: true })
+        })]
+      });
+    }
+
+    // Weekly digests are emailed on Monday mornings UTC
+    if (digests) {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['ses:SendEmail'],
+        resources: [
+          `arn:aws:ses:${this.region}:${this.account}:identity/*`,
+        ]
+      }));
+      new events.Rule(this, 'DigestRule', {
+        schedule: events.Schedule.cron({ minute: '0', hour: '9', weekDay: 'MON' }),
+        targets: [new targets.LambdaFunction(this.lambdaFunction, {
+          event: events.RuleTargetInput.fromObject({ digest: true })
+        })]
+      });
+    }
+
+    // Newly kept haiku are counted from the vote table's stream; a failed record is delivered again from where it failed
+    if (statsFromStream && voteTable) {
+      this.lambdaFunction.addEventSource(new lambdaEventSources.DynamoEventSource(voteTable, {
+        startingPosition: lambda.StartingPosition.LATEST,
+        batchSize: 100,
+        maxBatchingWindow: cdk.Duration.seconds(5),
+        retryAttempts: 10,
+        reportBatchItemFailures: true,
+        filters: [lambda.FilterCriteria.filter({
+          eventName: lambda.FilterRule.isEqual('INSERT'),
+          dynamodb: { NewImage: { key: { S: lambda.FilterRule.beginsWith('haiku:') } } },
+        })],
+      }));
+    }
+
+    // Queued haiku are posted to their Slack webhooks every minute, in one message per webhook
+    if (notifyTable) {
+      new events.Rule(this, 'NotifyRule', {
+        schedule: events.Schedule.rate(cdk.Duration.minutes(1)),
+        targets: [new targets.LambdaFunction(this.lambdaFunction, {
+          event: events.RuleTargetInput.fromObject({ notify: true })
+        })]
+      });
+    }
+
+    // DynamoDB deletes expired items within a few days; a daily cleanup
+    // bounds how long commit derived content outlives its expiry
+    if (responseCacheTable || jobTable || dailyTable || voteTable || notifyTable) {
+      new events.Rule(this, 'CleanupRule', {
+        schedule: events.Schedule.rate(cdk.Duration.days(1)),
+        targets: [new targets.LambdaFunction(this.lambdaFunction, {
+          event: events.RuleTargetInput.fromObject({ cleanup: true })
+        })]
+      });
+    }
+
+    // Warm-up invocations build the function's clients without calling Bedrock
+    const warmUpMinutes = parseInt(props.warmUpMinutes ?? '', 10);
+    if (warmUpMinutes > 0) {
+      new events.Rule(this, 'WarmUpRule', {
+        schedule: events.Schedule.rate(cdk.Duration.minutes(warmUpMinutes)),
+        targets: [new targets.LambdaFunction(this.lambdaFunction, {
+          event: events.RuleTargetInput.fromObject({ warmUp: true })
+        })]
+      });
+    }
+
     const bedrockModelID = "anthropic.claude-haiku-4-5-20251001-v1:0"
 
     this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
@@ -42,6 +475,112 @@ export class ApiStack extends cdk.Stack {
       ]
     }));
 
+    if (appConfigEnabled) {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['appconfig:StartConfigurationSession', 'appconfig:GetLatestConfiguration'],
+        resources: [
+          `arn:aws:appconfig:${props.env?.region}:${props.env?.account}:application/*`,
+        ]
+      }));
+    }
+
+    if (props.eventBus) {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['events:PutEvents'],
+        resources: [
+          props.eventBus.startsWith('arn:')
+            ? props.eventBus
+            : `arn:aws:events:${props.env?.region}:${props.env?.account}:event-bus/${props.eventBus}`,
+        ]
+      }));
+    }
+
+    if (props.moderationGuardrailId) {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['bedrock:ApplyGuardrail'],
+        resources: [
+          `arn:aws:bedrock:${props.env?.region}:${props.env?.account}:guardrail/${props.moderationGuardrailId}`,
+        ]
+      }));
+    }
+
+    if (props.publicationGuardrailId && props.publicationGuardrailId !== props.moderationGuardrailId) {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['bedrock:ApplyGuardrail'],
+        resources: [
+          `arn:aws:bedrock:${props.env?.region}:${props.env?.account}:guardrail/${props.publicationGuardrailId}`,
+        ]
+      }));
+    }
+
+    if (props.piiRedaction === 'comprehend') {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['comprehend:DetectPiiEntities'],
+        resources: ['*']
+      }));
+    }
+
+    // Canary and shadow models are invoked alongside the configured one
+    for (const modelId of [props.canaryModelId, props.shadowModelId]) {
+      if (!modelId) {
+        continue;
+      }
+      // Inference profiles route to the foundation model in any region
+      const foundationModel = modelId.replace(/^(global|us|eu|apac)\./, '');
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['bedrock:InvokeModel'],
+        resources: [
+          `arn:aws:bedrock:${props.env?.region}:${props.env?.account}:inference-profile/${modelId}`,
+          `arn:aws:bedrock:*::foundation-model/${foundationModel}`,
+        ]
+      }));
+    }
+
+    if (props.illustrationModelId) {
+      this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+        effect: iam.Effect.ALLOW,
+        actions: ['bedrock:InvokeModel'],
+        resources: [
+          `arn:aws:bedrock:*::foundation-model/${props.illustrationModelId}`,
+        ]
+      }));
+    }
+
+    this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+      effect: iam.Effect.ALLOW,
+      actions: ['polly:SynthesizeSpeech'],
+      resources: ['*']
+    }));
+
+    this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+      effect: iam.Effect.ALLOW,
+      actions: ['ssm:GetParametersByPath'],
+      resources: [
+        `arn:aws:ssm:${props.env?.region}:${props.env?.account}:parameter${promptParameterPath}`,
+        `arn:aws:ssm:${props.env?.region}:${props.env?.account}:parameter${promptParameterPath}/*`,
+      ]
+    }));
+
+    // Slack and Discord commands and background jobs are acknowledged immediately and finished by an asynchronous
+    // invocation of the same function. The ARN is matched by name because
+    // referencing the function's own ARN from its role would be circular.
+    this.lambdaFunction.addToRolePolicy(new iam.PolicyStatement({
+      effect: iam.Effect.ALLOW,
+      actions: ['lambda:InvokeFunction'],
+      resources: [
+        `arn:aws:lambda:${props.env?.region}:${props.env?.account}:function:${this.stackName}-HaikuLambdaFunction*`,
+      ]
+    }));
+
+    // A retried response would post the haiku twice, and failures are already reported to the user
+    this.lambdaFunction.configureAsyncInvoke({ retryAttempts: 0 });
+
     const apiGatewayCloudWatchRole = new iam.Role(this, 'ApiGatewayCloudWatchRole', {
       assumedBy: new iam.ServicePrincipal('apigateway.amazonaws.com'),
       managedPolicies: [
@@ -59,6 +598,7 @@ export class ApiStack extends cdk.Stack {
         loggingLevel: apigateway.MethodLoggingLevel.INFO,
         dataTraceEnabled: true,
         metricsEnabled: true,
+        tracingEnabled: props.tracing === 'true',
         accessLogDestination: new apigateway.LogGroupLogDestination(new logs.LogGroup(this, 'ApiAccessLogs')),
         accessLogFormat: apigateway.AccessLogFormat.jsonWithStandardFields()
       },
@@ -97,13 +637,117 @@ export class ApiStack extends cdk.Stack {
           commitMessage: {
             type: apigateway.JsonSchemaType.STRING,
             minLength: 1,
-            maxLength: 500,
-            description: 'The commit message to generate a haiku from'
+            maxLength: 10000,
+            description: 'The commit message to generate a haiku from; long messages are truncated'
           },
           mood: {
             type: apigateway.JsonSchemaType.STRING,
-            enum: ['humorous', 'reflective', 'technical'],
-            description: 'Optional mood for the haiku'
+            enum: ['humorous', 'reflective', 'technical', 'auto'],
+            description: 'Optional mood for the haiku; auto picks the one suiting the kind of commit'
+          },
+          register: {
+            type: apigateway.JsonSchemaType.STRING,
+            enum: ['formal', 'casual', 'playful'],
+            description: 'Optional formality of the haiku diction'
+          },
+          maxTokens: {
+            type: apigateway.JsonSchemaType.INTEGER,
+            minimum: 1,
+            description: 'Optional output token limit; clamped to the model limit'
+          },
+          temperature: {
+            type: apigateway.JsonSchemaType.NUMBER,
+            minimum: 0,
+            description: 'Optional sampling temperature; clamped to the model limit'
+          },
+          includeSummary: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Also return a one-sentence plain-language summary of the commit'
+          },
+          includeShareCard: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Also return a link to a PNG share card of the haiku'
+          },
+          includeAudio: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Also return a link to an MP3 reading of the haiku'
+          },
+          repository: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            properties: {
+              name: {
+                type: apigateway.JsonSchemaType.STRING,
+                maxLength: 200
+              },
+              language: {
+                type: apigateway.JsonSchemaType.STRING,
+                maxLength: 100
+              },
+              description: {
+                type: apigateway.JsonSchemaType.STRING,
+                maxLength: 1000
+              }
+            },
+            required: ['name'],
+            additionalProperties: false
+          },
+          author: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            properties: {
+              name: {
+                type: apigateway.JsonSchemaType.STRING,
+                maxLength: 200
+              },
+              email: {
+                type: apigateway.JsonSchemaType.STRING,
+                maxLength: 320
+              },
+              handle: {
+                type: apigateway.JsonSchemaType.STRING,
+                maxLength: 200
+              }
+            },
+            additionalProperties: false,
+            description: 'Optional commit author, ranked on the leaderboard by name or else handle'
+          },
+          commitRef: {
+            type: apigateway.JsonSchemaType.STRING,
+            maxLength: 100,
+            description: 'Optional commit the message belongs to, e.g. its SHA, passed on in haiku events'
+          },
+          includeIllustration: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Also return a companion illustration prompt, and image when configured'
+          },
+          includePairing: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Reflect the commit\'s Co-authored-by trailers in the haiku'
+          },
+          pairingStyle: {
+            type: apigateway.JsonSchemaType.STRING,
+            enum: ['acknowledge', 'verses'],
+            description: 'How co-authors are reflected; implies includePairing'
+          },
+          includeAuthor: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Let the haiku allude to the author, by handle or else name'
+          },
+          strict: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Fail with 422 unless a 5-7-5 haiku is produced within the retry budget'
+          },
+          noCache: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Generate a new haiku rather than reuse a cached one'
+          },
+          variants: {
+            type: apigateway.JsonSchemaType.INTEGER,
+            minimum: 0,
+            description: 'Also return this many candidate haiku, written at varied temperatures; clamped to the service limit'
+          },
+          deterministic: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'Always give the same commit the same haiku: cached first, canonical prompt, temperature 0'
           }
         },
         required: ['commitMessage'],
@@ -120,6 +764,93 @@ export class ApiStack extends cdk.Stack {
         properties: {
           haiku: {
             type: apigateway.JsonSchemaType.STRING
+          },
+          summary: {
+            type: apigateway.JsonSchemaType.STRING
+          },
+          svg: {
+            type: apigateway.JsonSchemaType.STRING
+          },
+          timings: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            additionalProperties: { type: apigateway.JsonSchemaType.NUMBER },
+            description: 'Milliseconds spent in each stage, when requested with ?timings=true'
+          },
+          degraded: {
+            type: apigateway.JsonSchemaType.BOOLEAN,
+            description: 'The haiku was written locally because Bedrock was unavailable'
+          },
+          shareCard: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            properties: {
+              url: {
+                type: apigateway.JsonSchemaType.STRING
+              },
+              expiresAt: {
+                type: apigateway.JsonSchemaType.STRING
+              }
+            }
+          },
+          audio: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            properties: {
+              url: {
+                type: apigateway.JsonSchemaType.STRING
+              },
+              expiresAt: {
+                type: apigateway.JsonSchemaType.STRING
+              }
+            }
+          },
+          illustration: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            properties: {
+              prompt: {
+                type: apigateway.JsonSchemaType.STRING
+              },
+              image: {
+                type: apigateway.JsonSchemaType.STRING,
+                description: 'Base64-encoded PNG'
+              }
+            }
+          },
+          metadata: {
+            type: apigateway.JsonSchemaType.OBJECT,
+            properties: {
+              promptVersion: {
+                type: apigateway.JsonSchemaType.STRING
+              },
+              register: {
+                type: apigateway.JsonSchemaType.STRING
+              },
+              model: {
+                type: apigateway.JsonSchemaType.STRING
+              },
+              cached: {
+                type: apigateway.JsonSchemaType.BOOLEAN,
+                description: 'The haiku was reused from the response cache'
+              },
+              warnings: {
+                type: apigateway.JsonSchemaType.ARRAY,
+                items: {
+                  type: apigateway.JsonSchemaType.STRING
+                }
+              },
+              coAuthors: {
+                type: apigateway.JsonSchemaType.ARRAY,
+                items: {
+                  type: apigateway.JsonSchemaType.OBJECT,
+                  properties: {
+                    name: {
+                      type: apigateway.JsonSchemaType.STRING
+                    },
+                    email: {
+                      type: apigateway.JsonSchemaType.STRING
+                    }
+                  }
+                }
+              }
+            }
           }
         },
         required: ['haiku']
@@ -215,6 +946,10 @@ export class ApiStack extends cdk.Stack {
       requestModels: {
         'application/json': haikuRequestModel
       },
+      requestParameters: {
+        // ?svg=true also returns the haiku rendered as an SVG card
+        'method.request.querystring.svg': false
+      },
       methodResponses: [
         {
           statusCode: '200',
@@ -234,6 +969,15 @@ export class ApiStack extends cdk.Stack {
             'method.response.header.Access-Control-Allow-Origin': true
           }
         },
+        {
+          statusCode: '422',
+          responseModels: {
+            'application/json': errorResponseModel
+          },
+          responseParameters: {
+            'method.response.header.Access-Control-Allow-Origin': true
+          }
+        },
         {
           statusCode: '500',
           responseModels: {
@@ -246,6 +990,134 @@ export class ApiStack extends cdk.Stack {
       ]
     });
 
+    const corsResponseParameters = { 'method.response.header.Access-Control-Allow-Origin': true };
+    const jsonMethodResponses: apigateway.MethodResponse[] = [
+      { statusCode: '200', responseParameters: corsResponseParameters },
+      { statusCode: '400', responseModels: { 'application/json': errorResponseModel }, responseParameters: corsResponseParameters },
+      { statusCode: '500', responseModels: { 'application/json': errorResponseModel }, responseParameters: corsResponseParameters }
+    ];
+
+    // POST /haiku/release-notes - Generate a sequence of haiku from release notes
+    haikuResource.addResource('release-notes').addMethod('POST', lambdaIntegration, {
+      methodResponses: jsonMethodResponses
+    });
+
+    // POST /haiku/changelog - Generate a haiku per category of a Keep a Changelog release
+    haikuResource.addResource('changelog').addMethod('POST', lambdaIntegration, {
+      methodResponses: jsonMethodResponses
+    });
+
+    // POST /haiku/pulse - Weave commits from across repositories into one poem of linked verses
+    haikuResource.addResource('pulse').addMethod('POST', lambdaIntegration, {
+      methodResponses: jsonMethodResponses
+    });
+
+    // Webhooks are proxied untouched so deliveries can be verified against the raw body and headers
+    const webhooksResource = this.api.root.addResource('webhooks');
+    const webhookIntegration = new apigateway.LambdaIntegration(this.lambdaFunction);
+
+    // POST /webhooks/github - Post haiku back to GitHub for push and pull request events
+    webhooksResource.addResource('github').addMethod('POST', webhookIntegration);
+
+    // POST /webhooks/gitlab - Post haiku back to GitLab for push and merge request events
+    webhooksResource.addResource('gitlab').addMethod('POST', webhookIntegration);
+
+    // POST /webhooks/bitbucket - Post haiku back to Bitbucket for push events
+    webhooksResource.addResource('bitbucket').addMethod('POST', webhookIntegration);
+
+    // POST /integrations/slack - Answer the /haiku slash command, proxied so the signature can be verified
+    const integrationsResource = this.api.root.addResource('integrations');
+    integrationsResource.addResource('slack').addMethod('POST', webhookIntegration);
+
+    // POST /integrations/discord - Answer the /haiku application command, proxied so the signature can be verified
+    integrationsResource.addResource('discord').addMethod('POST', webhookIntegration);
+
+    // POST /integrations/teams - Reply to outgoing webhook messages, proxied so the signature can be verified
+    integrationsResource.addResource('teams').addMethod('POST', webhookIntegration);
+
+    // GET /health - Report whether the service is serving, and the state of its Bedrock retry budget
+    this.api.root.addResource('health').addMethod('GET', webhookIntegration);
+
+    // POST /haiku/jobs - Generate a haiku in the background; GET /haiku/jobs/{id} - Poll its result
+    if (jobTable) {
+      const jobsResource = haikuResource.addResource('jobs');
+      jobsResource.addMethod('POST', webhookIntegration);
+      jobsResource.addResource('{id}').addMethod('GET', webhookIntegration);
+    }
+
+    // GET /haiku/daily - The haiku of the day, shared by every caller
+    if (dailyTable) {
+      haikuResource.addResource('daily').addMethod('GET', webhookIntegration);
+    }
+
+    // POST /haiku/renga - Add a commit's verse to its repository's renga; GET /haiku/renga - Its latest verses
+    if (rengaTable) {
+      const rengaResource = haikuResource.addResource('renga');
+      rengaResource.addMethod('POST', webhookIntegration);
+      rengaResource.addMethod('GET', webhookIntegration);
+    }
+
+    // POST /haiku/{id}/vote - Upvote a haiku; GET /haiku/top - The most voted haiku;
+    // POST /haiku/{id}/regenerate - Rewrite a kept haiku, validated like POST /haiku
+    if (voteTable) {
+      const haikuIdResource = haikuResource.addResource('{id}');
+      haikuIdResource.addResource('vote').addMethod('POST', webhookIntegration);
+      haikuIdResource.addResource('regenerate').addMethod('POST', webhookIntegration, {
+        requestValidator: requestValidator,
+        requestModels: {
+          'application/json': haikuRequestModel
+        }
+      });
+      haikuResource.addResource('top').addMethod('GET', webhookIntegration);
+    }
+
+    // GET /export - Every haiku kept for the caller's API key, as CSV or JSON Lines
+    if (voteTable && keyTable) {
+      this.api.root.addResource('export').addMethod('GET', webhookIntegration);
+    }
+
+    // GET /stats - Usage statistics, for callers holding the stats token
+    if (statsTable) {
+      this.api.root.addResource('stats').addMethod('GET', webhookIntegration);
+      // GET /leaderboard - Repositories and authors ranked from the same counters
+      this.api.root.addResource('leaderboard').addMethod('GET', webhookIntegration);
+    }
+
+    // POST /admin/keys, DELETE /admin/keys/{id}, PATCH /admin/keys/{id}/quota - Manage API keys
+    // GET, PUT and DELETE /admin/style - Manage the caller's tenant's style guide
+    // POST and GET /admin/digests, DELETE /admin/digests/{id} - Manage weekly digest subscriptions
+    if (keyTable && props.adminToken) {
+      const adminResource = this.api.root.addResource('admin');
+      const keysResource = adminResource.addResource('keys');
+      keysResource.addMethod('POST', webhookIntegration);
+      const keyResource = keysResource.addResource('{id}');
+      keyResource.addMethod('DELETE', webhookIntegration);
+      keyResource.addResource('quota').addMethod('PATCH', webhookIntegration);
+      const styleResource = adminResource.addResource('style');
+      styleResource.addMethod('GET', webhookIntegration);
+      styleResource.addMethod('PUT', webhookIntegration);
+      styleResource.addMethod('DELETE', webhookIntegration);
+      if (digests) {
+        const digestsResource = adminResource.addResource('digests');
+        digestsResource.addMethod('POST', webhookIntegration);
+        digestsResource.addMethod('GET', webhookIntegration);
+        digestsResource.addResource('{id}').addMethod('DELETE', webhookIntegration);
+      }
+    }
+
+    // GET /digests/{id}/unsubscribe - Unsubscribe link in digest emails
+    if (digests) {
+      this.api.root.addResource('digests').addResource('{id}').addResource('unsubscribe').addMethod('GET', webhookIntegration);
+    }
+
+    // DELETE /authors/{id}/haiku - Erase the haiku kept for a commit author
+    if (voteTable && props.adminToken) {
+      this.api.root.addResource('authors').addResource('{id}').addResource('haiku').addMethod('DELETE', webhookIntegration);
+    }
+
+    // GET /openapi.json - OpenAPI 3 spec of the haiku endpoints
+    this.api.root.addResource('openapi.json').addMethod('GET', webhookIntegration);
+
     this.waf = new WafConstruct(this, 'HaikuWaf', {
       name: 'HaikuApiWaf',
       rateLimit: props.ipRateLimit ?? 50,
@@ -283,5 +1155,13 @@ export class ApiStack extends cdk.Stack {
       description: 'ARN of the Haiku Lambda function',
       exportName: 'HaikuLambdaArn'
     });
+
+    if (eventsTopic) {
+      new cdk.CfnOutput(this, 'HaikuEventsTopicArn', {
+        value: eventsTopic.topicArn,
+        description: 'ARN of the SNS topic domain events are published to',
+        exportName: 'HaikuEventsTopicArn'
+      });
+    }
   }
 }
\ No newline at end of file
@@ -0,0 +1,89 @@
+import * as fs from 'fs';
+import * as path from 'path';
+import * as cdk from 'aws-cdk-lib';
+import * as lambda from 'aws-cdk-lib/aws-lambda';
+import { Template } from 'aws-cdk-lib/assertions';
+import { ApiStack, ApiStackProps } from '../lib/api-stack';
+
+// The request model is kept in step with the service's request types, which
+// are read from its Go source
+const haikuSource = (file: string) =>
+  fs.readFileSync(path.join(__dirname, '../../internal/service/haiku', file), 'utf8');
+
+function jsonFields(source: string, type: string): string[] {
+  const struct = source.match(new RegExp(`type ${type} struct \\{([\\s\\S]*?)\\n\\}`));
+  if (!struct) {
+    throw new Error(`${type} not found`);
+  }
+  return [...struct[1].matchAll(/json:"([^",]+)/g)].map(match => match[1]).sort();
+}
+
+function stringValues(source: string, type: string): string[] {
+  return [...source.matchAll(new RegExp(`\\b${type} = "([^"]+)"`, 'g'))].map(match => match[1]).sort();
+}
+
+function intConstant(source: string, name: string): number {
+  const constant = source.match(new RegExp(`const ${name} = (\\d+)`));
+  if (!constant) {
+    throw new Error(`${name} not found`);
+  }
+  return parseInt(constant[1], 10);
+}
+
+function template(props: ApiStackProps = {}): Template {
+  // The function's zip is built by the deploy workflow; any asset will do here
+  jest.spyOn(lambda.Code, 'fromAsset').mockImplementation(() => new lambda.AssetCode(__dirname));
+  return Template.fromStack(new ApiStack(new cdk.App(), 'TestStack', props));
+}
+
+function requestSchema(): any {
+  const models = template().findResources('AWS::ApiGateway::Model', {
+    Properties: { Name: 'HaikuRequest' }
+  });
+  const [model] = Object.values(models);
+  return model.Properties.Schema;
+}
+
+describe('HaikuRequestModel', () => {
+  const model = haikuSource('model.go');
+  const schema = requestSchema();
+
+  test('accepts every HaikuCommitRequest field', () => {
+    expect(schema.additionalProperties).toBe(false);
+    expect(Object.keys(schema.properties).sort()).toEqual(jsonFields(model, 'HaikuCommitRequest'));
+  });
+
+  test('accepts every Repository and Author field', () => {
+    expect(Object.keys(schema.properties.repository.properties).sort()).toEqual(jsonFields(model, 'Repository'));
+    expect(Object.keys(schema.properties.author.properties).sort()).toEqual(jsonFields(model, 'Author'));
+    expect(schema.properties.author.additionalProperties).toBe(false);
+  });
+
+  test('accepts the values the service accepts', () => {
+    expect([...schema.properties.mood.enum].sort()).toEqual(stringValues(model, 'Mood'));
+    expect([...schema.properties.register.enum].sort()).toEqual(stringValues(model, 'Register'));
+    expect([...schema.properties.pairingStyle.enum].sort()).toEqual(stringValues(model, 'PairingStyle'));
+    expect(schema.properties.variants.minimum).toBe(0);
+    // Larger variants are clamped by the service, with a warning, rather than refused
+    expect(schema.properties.variants.maximum).toBeUndefined();
+    expect(schema.properties.commitRef.maxLength).toBe(intConstant(haikuSource('events.go'), 'MaxCommitRefLength'));
+  });
+});
+
+describe('POST /haiku/{id}/regenerate', () => {
+  test('is validated against the haiku request model', () => {
+    const stack = template({ haikuVotes: 'true' });
+    const resources = stack.findResources('AWS::ApiGateway::Resource', {
+      Properties: { PathPart: 'regenerate' }
+    });
+    const [resourceId] = Object.keys(resources);
+    expect(resourceId).toBeDefined();
+
+    const methods = stack.findResources('AWS::ApiGateway::Method', {
+      Properties: { HttpMethod: 'POST', ResourceId: { Ref: resourceId } }
+    });
+    const [method] = Object.values(methods);
+    expect(method.Properties.RequestValidatorId).toBeDefined();
+    expect(method.Properties.RequestModels['application/json']).toEqual({ Ref: expect.stringMatching(/^HaikuRequestModel/) });
+  });
+});
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+// haikuTrailer is the git trailer the hook adds the haiku under.
+const haikuTrailer = "Haiku:"
+
+// scissorsLine marks the start of the diff git appends for commit --verbose.
+const scissorsLine = "# ------------------------ >8 ------------------------"
+
+// runHook implements the hook subcommand, run by git as a prepare-commit-msg
+// or commit-msg hook with the path of the commit message file. The haiku is
+// added to the message as a trailer. The hook never blocks a commit: any
+// failure, including a timeout, is reported on stderr and the message is left
+// as it was.
+func runHook(args []string, stderr io.Writer) int {
+	flags := flag.NewFlagSet("haiku-cli hook", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: haiku-cli hook [flags] <commit message file> [source]\n\nAdds a %s trailer to the commit message, for use as a git\nprepare-commit-msg or commit-msg hook.\n\n", haikuTrailer)
+		flags.PrintDefaults()
+	}
+
+	apiURL := flags.String("url", os.Getenv("HAIKU_API_URL"), "haiku API URL; the service runs locally when empty")
+	mood := flags.String("mood", "", "haiku mood: humorous, reflective or technical")
+	timeout := flags.Duration("timeout", 10*time.Second, "how long to wait for the haiku before committing without one")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() < 1 {
+		flags.Usage()
+		return 2
+	}
+
+	// Merges and squashes already describe other commits.
+	if source := flags.Arg(1); source == "merge" || source == "squash" {
+		return 0
+	}
+
+	if err := addHaikuTrailer(flags.Arg(0), haiku.Mood(*mood), *apiURL, *timeout); err != nil {
+		fmt.Fprintf(stderr, "haiku-cli: committing without a haiku: %v\n", err)
+	}
+	return 0
+}
+
+func addHaikuTrailer(path string, mood haiku.Mood, apiURL string, timeout time.Duration) error {
+	if mood != "" && !mood.IsValid() {
+		return fmt.Errorf("unknown mood %q", mood)
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	message, _ := splitComments(string(contents))
+	if strings.TrimSpace(message) == "" || hasHaikuTrailer(message) {
+		return nil
+	}
+
+	log.SetOutput(io.Discard)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request := haiku.HaikuCommitRequest{
+		CommitMessage: strings.TrimSpace(message),
+		Mood:          mood,
+	}
+
+	// Not every step of starting the service locally honours ctx, so the
+	// hook stops waiting at the deadline rather than relying on it.
+	type result struct {
+		haiku string
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		text, err := generateHaiku(ctx, apiURL, request)
+		done <- result{text, err}
+	}()
+
+	var generated result
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case generated = <-done:
+	}
+	if generated.err != nil {
+		return generated.err
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(appendHaikuTrailer(string(contents), generated.haiku)), info.Mode().Perm())
+}
+
+func generateHaiku(ctx context.Context, apiURL string, request haiku.HaikuCommitRequest) (string, error) {
+	service, closeService, err := haikuService(ctx, apiURL, &request)
+	if err != nil {
+		return "", err
+	}
+	defer closeService()
+
+	response, err := service.CreateHaiku(ctx, request)
+	if err != nil {
+		return "", err
+	}
+	return response.Haiku, nil
+}
+
+// appendHaikuTrailer adds haiku to the trailers at the end of a commit
+// message, ahead of any trailing comments git strips after the hook runs.
+// Each line after the first is folded onto a continuation line, which git
+// reads as part of the same trailer.
+func appendHaikuTrailer(contents string, text string) string {
+	message, comments := splitComments(contents)
+	message = strings.TrimRight(message, " \t\n")
+
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return contents
+	}
+	trailer := haikuTrailer + " " + strings.Join(lines, "\n ")
+
+	separator := "\n\n"
+	if endsWithTrailers(message) {
+		separator = "\n"
+	}
+
+	result := message + separator + trailer + "\n"
+	if comments != "" {
+		result += "\n" + comments
+	}
+	return result
+}
+
+// splitComments separates a commit message file into the message and the
+// block of comment lines git adds after it, such as the status summary and a
+// --verbose diff.
+func splitComments(contents string) (string, string) {
+	lines := strings.SplitAfter(contents, "\n")
+
+	end := len(lines)
+	for i, line := range lines {
+		if strings.TrimRight(line, "\n") == scissorsLine {
+			end = i
+			break
+		}
+	}
+	for end > 0 {
+		line := strings.TrimSpace(lines[end-1])
+		if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+		end--
+	}
+
+	message := strings.Join(lines[:end], "")
+	comments := strings.TrimLeft(strings.Join(lines[end:], ""), "\n")
+	return message, comments
+}
+
+// endsWithTrailers reports whether the last paragraph of a message with a
+// subject is a block of "Key: value" trailers, e.g. Signed-off-by lines.
+func endsWithTrailers(message string) bool {
+	paragraphs := strings.Split(strings.TrimSpace(message), "\n\n")
+	if len(paragraphs) < 2 {
+		return false
+	}
+
+	for _, line := range strings.Split(paragraphs[len(paragraphs)-1], "\n") {
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		key, _, ok := strings.Cut(line, ": ")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return false
+		}
+	}
+	return true
+}
+
+// hasHaikuTrailer reports whether the message already carries a haiku, e.g.
+// when amending a commit.
+func hasHaikuTrailer(message string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		if strings.HasPrefix(line, haikuTrailer) {
+			return true
+		}
+	}
+	return false
+}
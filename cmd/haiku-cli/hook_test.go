@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs\n"
+
+func TestAppendHaikuTrailer(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		expected string
+	}{
+		{
+			name:     "Subject only",
+			contents: "fix: resolved login issue\n",
+			expected: "fix: resolved login issue\n\nHaiku: Old cracks mended now\n the login door swings open\n quiet in the logs\n",
+		},
+		{
+			name:     "Joins existing trailers",
+			contents: "fix: resolved login issue\n\nSigned-off-by: Ada <ada@example.com>\n",
+			expected: "fix: resolved login issue\n\nSigned-off-by: Ada <ada@example.com>\nHaiku: Old cracks mended now\n the login door swings open\n quiet in the logs\n",
+		},
+		{
+			name:     "Body is not a trailer block",
+			contents: "fix: resolved login issue\n\nThe session check ran twice.\n",
+			expected: "fix: resolved login issue\n\nThe session check ran twice.\n\nHaiku: Old cracks mended now\n the login door swings open\n quiet in the logs\n",
+		},
+		{
+			name:     "Added ahead of comments",
+			contents: "fix: resolved login issue\n\n# Please enter the commit message for your changes.\n#\n" + scissorsLine + "\ndiff --git a/login.go b/login.go\n",
+			expected: "fix: resolved login issue\n\nHaiku: Old cracks mended now\n the login door swings open\n quiet in the logs\n\n# Please enter the commit message for your changes.\n#\n" + scissorsLine + "\ndiff --git a/login.go b/login.go\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result := appendHaikuTrailer(tc.contents, testHaiku)
+			if result != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestHasHaikuTrailer(t *testing.T) {
+	message := appendHaikuTrailer("fix: resolved login issue\n", testHaiku)
+	if !hasHaikuTrailer(message) {
+		t.Errorf("Expected a haiku trailer in %q", message)
+	}
+	if hasHaikuTrailer("fix: resolved login issue\n") {
+		t.Errorf("Expected no haiku trailer")
+	}
+}
@@ -0,0 +1,174 @@
+// Command haiku-cli prints a haiku for a commit message given as arguments or
+// on stdin, e.g.
+//
+//	git log -1 --format=%B | haiku-cli
+//
+// It calls the API at -url (default $HAIKU_API_URL), or runs the service
+// in-process with the local AWS credentials and configuration when no URL is
+// set.
+//
+// "haiku-cli hook" runs as a git prepare-commit-msg or commit-msg hook, adding
+// the haiku to the commit message as a trailer.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/api"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/app"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/haikuapi"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/config"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+// maxInput bounds the commit message read from stdin.
+const maxInput = 1 << 20
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin *os.File, stdout io.Writer, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "hook" {
+		return runHook(args[1:], stderr)
+	}
+
+	flags := flag.NewFlagSet("haiku-cli", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: haiku-cli [flags] [commit message]\n\nReads the commit message from stdin when none is given.\n\n")
+		flags.PrintDefaults()
+	}
+
+	apiURL := flags.String("url", os.Getenv("HAIKU_API_URL"), "haiku API URL; the service runs locally when empty")
+	mood := flags.String("mood", "", "haiku mood: humorous, reflective or technical")
+	register := flags.String("register", "", "haiku register: formal, casual or playful")
+	strict := flags.Bool("strict", false, "fail unless the haiku is 5-7-5, e.g. as a CI check")
+	asJSON := flags.Bool("json", false, "print the full response as JSON")
+	timeout := flags.Duration("timeout", 30*time.Second, "how long to wait for the haiku")
+	verbose := flags.Bool("v", false, "log service activity to stderr")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	message, err := commitMessage(flags.Args(), stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "haiku-cli: %v\n", err)
+		return 1
+	}
+	if message == "" {
+		flags.Usage()
+		return 2
+	}
+
+	request := haiku.HaikuCommitRequest{
+		CommitMessage: message,
+		Mood:          haiku.Mood(*mood),
+		Register:      haiku.Register(*register),
+		Strict:        *strict,
+	}
+	if request.Mood != "" && !request.Mood.IsValid() {
+		fmt.Fprintf(stderr, "haiku-cli: unknown mood %q\n", *mood)
+		return 2
+	}
+	if request.Register != "" && !request.Register.IsValid() {
+		fmt.Fprintf(stderr, "haiku-cli: unknown register %q\n", *register)
+		return 2
+	}
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	service, closeService, err := haikuService(ctx, *apiURL, &request)
+	if err != nil {
+		fmt.Fprintf(stderr, "haiku-cli: %v\n", err)
+		return 1
+	}
+	defer closeService()
+
+	response, err := service.CreateHaiku(ctx, request)
+	if err != nil {
+		fmt.Fprintf(stderr, "haiku-cli: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(response); err != nil {
+			fmt.Fprintf(stderr, "haiku-cli: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	fmt.Fprintln(stdout, strings.TrimSpace(response.Haiku))
+	return 0
+}
+
+// commitMessage joins the arguments, or reads stdin when there are none or the
+// only argument is "-". An interactive stdin is not read.
+func commitMessage(args []string, stdin *os.File) (string, error) {
+	if len(args) > 0 && !(len(args) == 1 && args[0] == "-") {
+		return strings.TrimSpace(strings.Join(args, " ")), nil
+	}
+
+	if info, err := stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && len(args) == 0 {
+		return "", nil
+	}
+
+	input, err := io.ReadAll(io.LimitReader(stdin, maxInput))
+	if err != nil {
+		return "", fmt.Errorf("reading commit message: %w", err)
+	}
+	return strings.TrimSpace(string(input)), nil
+}
+
+// haikuService returns a client for the API at apiURL, or the service itself
+// when apiURL is empty. Running locally skips the API, so its commit length
+// limit is applied to request here.
+func haikuService(ctx context.Context, apiURL string, request *haiku.HaikuCommitRequest) (HaikuService, func(), error) {
+	if apiURL != "" {
+		return haikuapi.NewDefaultHaikuAPIClient(apiURL), func() {}, nil
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading aws config: %w", err)
+	}
+
+	appConfig := config.Load()
+	if len(request.CommitMessage) > appConfig.MaxCommitLength {
+		if api.LengthStrategy(appConfig.CommitLengthStrategy) == api.LengthStrategyReject {
+			return nil, nil, fmt.Errorf("commit message exceeds %d characters", appConfig.MaxCommitLength)
+		}
+		request.CommitMessage = api.TruncateCommitMessage(request.CommitMessage, appConfig.MaxCommitLength, appConfig.TruncatedBodyLength)
+	}
+
+	haikuApp := app.New(cfg, appConfig)
+	haikuApp.SetupLogging(log.Writer())
+	return haikuApp.HaikuService(), func() {
+		_ = haikuApp.Close(context.Background())
+	}, nil
+}
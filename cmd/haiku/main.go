@@ -2,39 +2,29 @@ package main
 
 import (
 	"context"
+	"os"
 
-	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/config"
-	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
-	"github.com/brianherrera/commits-fall-like-leaves/internal/api"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/app"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
 	"github.com/gin-gonic/gin"
 )
 
-var ginLambda *ginadapter.GinLambda
-
-func init() {
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		panic("failed to load aws config")
-	}
-
+func main() {
 	gin.SetMode(gin.ReleaseMode)
 
-	router := gin.New()
-
-	haikuAPI := api.NewDefaultHaikuAPI(cfg)
-	haikuAPI.SetupMiddleware(router)
-	haikuAPI.SetupRoutes(router)
-
-	// Lambda adapter
-	ginLambda = ginadapter.New(router)
-}
-
-func Handler(ctx context.Context, req events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
-	return ginLambda.ProxyWithContext(ctx, req)
-}
+	handler := app.NewDefaultLambda()
+
+	// Provisioned instances are initialized before they take traffic, so do
+	// the work then. On-demand instances build the app on their first
+	// invocation, which a scheduled warm-up can make ahead of real requests.
+	if os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE") == "provisioned-concurrency" {
+		if haiku, err := handler.Init(context.Background()); err != nil {
+			logging.Errorf("[APP] error initializing, retrying on first invocation: %v\n", err)
+		} else if err := haiku.WarmUp(context.Background()); err != nil {
+			logging.Errorf("[APP] error warming up: %v\n", err)
+		}
+	}
 
-func main() {
-	lambda.Start(Handler)
+	lambda.Start(handler.Handle)
 }
@@ -2,36 +2,303 @@ package api
 
 import (
 	"context"
+	"crypto/ed25519"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/reporting"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/daily"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/discord"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/erasure"
 	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/renga"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/slack"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/styles"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/teams"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
 	"github.com/gin-gonic/gin"
 )
 
 type HaikuService interface {
 	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+	RegenerateHaiku(ctx context.Context, id string, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+	CreateReleaseNotesHaiku(ctx context.Context, request haiku.ReleaseNotesRequest) (haiku.ReleaseNotesResponse, error)
+	CreateChangelogHaiku(ctx context.Context, request haiku.ChangelogRequest) (haiku.ChangelogResponse, error)
+	CreatePulseHaiku(ctx context.Context, request haiku.PulseRequest) (haiku.PulseResponse, error)
+}
+
+type WebhookService interface {
+	HandleEvent(ctx context.Context, event webhook.Event) (haiku.HaikuCommitResponse, error)
+}
+
+// SlackDispatcher schedules the response to an acknowledged slash command.
+type SlackDispatcher interface {
+	Dispatch(ctx context.Context, command slack.Command) error
+}
+
+// DiscordDispatcher schedules the response to a deferred Discord interaction.
+type DiscordDispatcher interface {
+	Dispatch(ctx context.Context, interaction discord.Interaction) error
+}
+
+// JobService runs haiku requests in the background.
+type JobService interface {
+	Submit(ctx context.Context, request haiku.HaikuCommitRequest, opts *jobs.SubmitOptions) (jobs.Job, error)
+	Get(ctx context.Context, id string) (jobs.Job, error)
+}
+
+// DailyService returns the haiku of the day, the same for every caller.
+type DailyService interface {
+	Get(ctx context.Context) (daily.Daily, error)
+}
+
+// RengaService chains the haiku of a repository's commits into a renga.
+type RengaService interface {
+	Continue(ctx context.Context, request renga.ContinueRequest) (renga.Verse, error)
+	Get(ctx context.Context, repository string, limit int) (renga.Renga, error)
+}
+
+// VoteService counts votes for stored haiku.
+type VoteService interface {
+	Vote(ctx context.Context, id string, voter string) (votes.Haiku, error)
+	Top(ctx context.Context, days int, limit int) (votes.Top, error)
+	History(ctx context.Context, keyID string, fn func(saved votes.Saved) error) error
+}
+
+// ErasureService erases the haiku kept for a commit author.
+type ErasureService interface {
+	Erase(ctx context.Context, author string, requestedBy string) (erasure.Erasure, error)
+}
+
+// StatsService summarizes usage of the haiku endpoints.
+type StatsService interface {
+	Summary(ctx context.Context, days int) (stats.Stats, error)
+}
+
+// LeaderboardService ranks repositories and authors by their haiku.
+type LeaderboardService interface {
+	Leaderboard(ctx context.Context, board stats.Board, ranking stats.Ranking, days int, limit int) (stats.Leaderboard, error)
+}
+
+// KeyService issues and checks API keys.
+type KeyService interface {
+	Create(ctx context.Context, request keys.CreateKeyRequest) (keys.CreatedKey, error)
+	Delete(ctx context.Context, id string) error
+	SetQuota(ctx context.Context, id string, quota keys.Quota) (keys.Key, error)
+	Authenticate(ctx context.Context, token string) (keys.Key, error)
+}
+
+// StyleService keeps tenants' style guides.
+type StyleService interface {
+	Get(ctx context.Context) (styles.Style, error)
+	Set(ctx context.Context, request styles.SetStyleRequest) (styles.Style, error)
+	Delete(ctx context.Context) error
+}
+
+// DigestService emails weekly digests of tenants' haiku to subscribers.
+type DigestService interface {
+	Subscribe(ctx context.Context, request digest.SubscribeRequest) (digest.Subscription, error)
+	List(ctx context.Context) ([]digest.Subscription, error)
+	Unsubscribe(ctx context.Context, id string) error
+	UnsubscribeWithToken(ctx context.Context, id string, token string) error
+}
+
+// QuotaService counts API key usage against monthly quotas.
+type QuotaService interface {
+	Admit(ctx context.Context, key keys.Key) (quotas.Usage, error)
+	AddOutputTokens(ctx context.Context, keyID string, tokens int) error
+}
+
+// TeamsResponder replies to a message sent to a Teams outgoing webhook.
+type TeamsResponder interface {
+	Reply(ctx context.Context, message teams.Message) (teams.Activity, error)
 }
 
 type HaikuAPI struct {
 	haikuService HaikuService
+	options      Options
 }
 
-func NewHaikuAPI(haikuService HaikuService) *HaikuAPI {
-	return &HaikuAPI{
-		haikuService: haikuService,
+type Options struct {
+	MaxCommitLength        int                // Maximum commit message length sent to the model (default: 100)
+	LengthStrategy         LengthStrategy     // How longer commit messages are handled (default: truncate)
+	TruncatedBodyLength    int                // Body characters kept after the subject line when truncating (default: 50)
+	GitHubWebhooks         WebhookService     // Handles GitHub webhook events (default: none, GitHub webhook disabled)
+	GitHubWebhookSecret    string             // Secret verifying GitHub webhook deliveries (default: none, GitHub webhook disabled)
+	GitLabWebhooks         WebhookService     // Handles GitLab webhook events (default: none, GitLab webhook disabled)
+	GitLabWebhookToken     string             // Token verifying GitLab webhook deliveries (default: none, GitLab webhook disabled)
+	BitbucketWebhooks      WebhookService     // Handles Bitbucket webhook events (default: none, Bitbucket webhook disabled)
+	BitbucketWebhookSecret string             // Secret verifying Bitbucket webhook deliveries (default: none, Bitbucket webhook disabled)
+	SlackCommands          SlackDispatcher    // Responds to Slack slash commands (default: none, Slack command disabled)
+	SlackSigningSecret     string             // Signing secret verifying Slack requests (default: none, Slack command disabled)
+	DiscordInteractions    DiscordDispatcher  // Responds to Discord slash commands (default: none, Discord interactions disabled)
+	DiscordPublicKey       ed25519.PublicKey  // Application public key verifying Discord requests (default: none, Discord interactions disabled)
+	TeamsMessages          TeamsResponder     // Replies to Teams outgoing webhook messages (default: none, Teams webhook disabled)
+	TeamsWebhookSecret     []byte             // Decoded security token verifying Teams requests (default: none, Teams webhook disabled)
+	Reporter               reporting.Reporter // Receives panics and 5xx responses (default: none, only logged)
+	Metrics                ErrorMetrics       // Counts failed requests by error class (default: none, not counted)
+	Audit                  AuditLog           // Records who made each request and what it was served (default: none, not audited)
+	Jobs                   JobService         // Runs haiku requests in the background (default: none, jobs disabled)
+	Daily                  DailyService       // Returns the haiku of the day (default: none, daily haiku disabled)
+	Renga                  RengaService       // Chains repositories' commit haiku into renga (default: none, renga disabled)
+	Votes                  VoteService        // Counts votes for stored haiku (default: none, voting disabled)
+	Erasure                ErasureService     // Erases the haiku kept for an author (default: none, erasure disabled)
+	Stats                  StatsService       // Summarizes usage statistics (default: none, stats disabled)
+	StatsToken             string             // Bearer token required to read usage statistics (default: none, stats disabled)
+	Leaderboard            LeaderboardService // Ranks repositories and authors (default: none, leaderboard disabled)
+	Keys                   KeyService         // Issues and checks API keys (default: none, admin API disabled)
+	AdminToken             string             // Bearer token managing keys alongside admin keys (default: none, admin API disabled)
+	Styles                 StyleService       // Keeps tenants' style guides, managed with the admin API (default: none, style guides disabled)
+	Digests                DigestService      // Keeps weekly digest subscriptions, managed with the admin API (default: none, digests disabled)
+	RequireAPIKey          bool               // Whether the haiku endpoints require an API key (default: false)
+	Quotas                 QuotaService       // Enforces API key quotas when keys are required (default: none, unlimited)
+	Flags                  FeatureFlags       // Turns flagged routes off per caller (default: none, all on)
+	Tracer                 timing.Tracer      // Traces each stage of a request (default: none, only timed)
+	RetryBudget            RetryBudget        // Reported by /health (default: none, not reported)
+}
+
+func DefaultOptions() Options {
+	return Options{
+		MaxCommitLength:     MaxCommitLength,
+		LengthStrategy:      LengthStrategyTruncate,
+		TruncatedBodyLength: TruncatedBodyLength,
 	}
 }
 
-func NewDefaultHaikuAPI(cfg aws.Config) *HaikuAPI {
-	return NewHaikuAPI(haiku.NewDefaultHaikuService(cfg))
+func NewHaikuAPI(haikuService HaikuService, opts *Options) *HaikuAPI {
+	defaults := DefaultOptions()
+	options := defaults
+	if opts != nil {
+		options = *opts
+		if options.MaxCommitLength <= 0 {
+			options.MaxCommitLength = defaults.MaxCommitLength
+		}
+		if !options.LengthStrategy.IsValid() {
+			options.LengthStrategy = defaults.LengthStrategy
+		}
+		if options.TruncatedBodyLength <= 0 {
+			options.TruncatedBodyLength = defaults.TruncatedBodyLength
+		}
+	}
+
+	registerFieldNames()
+
+	return &HaikuAPI{
+		haikuService: haikuService,
+		options:      options,
+	}
 }
 
 func (api *HaikuAPI) SetupMiddleware(router *gin.Engine) {
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	router.Use(identifyRequests())
+	router.Use(accessLog())
+	// Errors are counted and requests audited outside recovery, so that
+	// recovered panics are too.
+	if api.options.Metrics != nil {
+		router.Use(api.countErrors())
+	}
+	if api.options.Audit != nil {
+		router.Use(api.auditRequests())
+	}
+	router.Use(api.recovery())
+	router.Use(serverTiming(api.options.Tracer))
 }
 
 // API Endpoints
 func (api *HaikuAPI) SetupRoutes(router *gin.Engine) {
-	router.POST("/haiku", api.postHaiku)
+	// Requests that generate haiku count against the key's quota; polling
+	// for a job's result, reading the haiku of the day and voting don't.
+	haikuRoutes := router.Group("")
+	if api.options.RequireAPIKey {
+		haikuRoutes.Use(api.requireAPIKey(keys.ScopeHaiku))
+	}
+	generateRoutes := haikuRoutes.Group("")
+	if api.options.RequireAPIKey && api.options.Quotas != nil {
+		generateRoutes.Use(api.enforceQuota)
+	}
+	generateRoutes.POST("/haiku", api.postHaiku)
+	generateRoutes.POST("/haiku/release-notes", api.postReleaseNotesHaiku)
+	generateRoutes.POST("/haiku/changelog", api.postChangelogHaiku)
+	generateRoutes.POST("/haiku/pulse", api.postPulseHaiku)
+	router.GET("/openapi.json", api.getOpenAPI)
+	router.GET("/health", api.getHealth)
+
+	if api.options.Jobs != nil {
+		generateRoutes.POST("/haiku/jobs", api.postHaikuJob)
+		haikuRoutes.GET("/haiku/jobs/:id", api.getHaikuJob)
+	}
+	if api.options.Daily != nil {
+		haikuRoutes.GET("/haiku/daily", api.getDailyHaiku)
+	}
+	if api.options.Renga != nil {
+		generateRoutes.POST("/haiku/renga", api.postRenga)
+		haikuRoutes.GET("/haiku/renga", api.getRenga)
+	}
+	// Haiku can only be regenerated while they are kept, as they are for
+	// voting.
+	if api.options.Votes != nil {
+		generateRoutes.POST("/haiku/:id/regenerate", api.postRegenerateHaiku)
+		haikuRoutes.POST("/haiku/:id/vote", api.postVote)
+		haikuRoutes.GET("/haiku/top", api.getTopHaiku)
+	}
+	// An export is scoped to the caller's key, so it needs one even when
+	// the haiku endpoints don't.
+	if api.options.Votes != nil && api.options.Keys != nil {
+		router.GET("/export", api.requireAPIKey(keys.ScopeHaiku), api.requireFlag(flags.Export), api.getExport)
+	}
+	if api.options.Leaderboard != nil {
+		haikuRoutes.GET("/leaderboard", api.getLeaderboard)
+	}
+	if api.options.Stats != nil && api.options.StatsToken != "" {
+		router.GET("/stats", api.getStats)
+	}
+	if api.options.Keys != nil && api.options.AdminToken != "" {
+		admin := router.Group("/admin", api.requireAdmin)
+		admin.POST("/keys", api.postKey)
+		admin.DELETE("/keys/:id", api.deleteKey)
+		admin.PATCH("/keys/:id/quota", api.patchKeyQuota)
+		if api.options.Styles != nil {
+			style := admin.Group("/style", api.requireFlag(flags.StyleGuides))
+			style.GET("", api.getStyle)
+			style.PUT("", api.putStyle)
+			style.DELETE("", api.deleteStyle)
+		}
+		if api.options.Digests != nil {
+			admin.POST("/digests", api.postDigest)
+			admin.GET("/digests", api.getDigests)
+			admin.DELETE("/digests/:id", api.deleteDigest)
+		}
+	}
+	// Digests link here to unsubscribe, with a token only the digest carries.
+	if api.options.Digests != nil {
+		router.GET("/digests/:id/unsubscribe", api.getUnsubscribeDigest)
+	}
+	if api.options.Erasure != nil && api.options.AdminToken != "" {
+		router.DELETE("/authors/:id/haiku", api.requireAdmin, api.deleteAuthorHaiku)
+	}
+
+	if api.options.GitHubWebhooks != nil && api.options.GitHubWebhookSecret != "" {
+		router.POST("/webhooks/github", api.postGitHubWebhook)
+	}
+	if api.options.GitLabWebhooks != nil && api.options.GitLabWebhookToken != "" {
+		router.POST("/webhooks/gitlab", api.postGitLabWebhook)
+	}
+	if api.options.BitbucketWebhooks != nil && api.options.BitbucketWebhookSecret != "" {
+		router.POST("/webhooks/bitbucket", api.postBitbucketWebhook)
+	}
+	if api.options.SlackCommands != nil && api.options.SlackSigningSecret != "" {
+		router.POST("/integrations/slack", api.postSlackCommand)
+	}
+	if api.options.DiscordInteractions != nil && len(api.options.DiscordPublicKey) == ed25519.PublicKeySize {
+		router.POST("/integrations/discord", api.postDiscordInteraction)
+	}
+	if api.options.TeamsMessages != nil && len(api.options.TeamsWebhookSecret) > 0 {
+		router.POST("/integrations/teams", api.postTeamsMessage)
+	}
 }
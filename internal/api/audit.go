@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/audit"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/gin-gonic/gin"
+)
+
+// auditTimeout bounds how long a request waits for its audit record.
+const auditTimeout = 3 * time.Second
+
+// adminTokenContextKey marks requests authenticated with the admin token.
+const adminTokenContextKey = "adminToken"
+
+// AuditLog keeps a tamper-evident record of each request.
+type AuditLog interface {
+	Record(ctx context.Context, record audit.Record) (audit.Record, error)
+}
+
+// auditRequests records who made each request, what it was served and how
+// its haiku were moderated. Records are written before the response is
+// returned, as a Lambda may be frozen straight after; a record that can't be
+// written is logged, and doesn't fail the request.
+func (api *HaikuAPI) auditRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		ctx, trail := audit.NewContext(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		record := audit.Record{
+			Time:       started,
+			Tenant:     keys.TenantFromContext(c.Request.Context()),
+			SourceIP:   c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Method:     c.Request.Method,
+			Route:      c.FullPath(),
+			Path:       c.Request.URL.Path,
+			Status:     c.Writer.Status(),
+			Haiku:      trail.Haiku(),
+			Moderation: trail.Moderation(),
+		}
+		if value, ok := c.Get(apiKeyContextKey); ok {
+			record.Caller = "key:" + value.(keys.Key).ID
+		} else if c.GetBool(adminTokenContextKey) {
+			record.Caller = RequestedByAdminToken
+		}
+		ids := requestIDs(c.Request.Context())
+		record.RequestID, record.APIRequestID = ids.ID, ids.APIRequestID
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), auditTimeout)
+		defer cancel()
+		if _, err := api.options.Audit.Record(ctx, record); err != nil {
+			logging.Errorf("[HAIKU API] error writing audit record for %s %s: %v", record.Method, record.Path, err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/audit"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+type MockAuditLog struct {
+	Records []audit.Record
+}
+
+func (m *MockAuditLog) Record(ctx context.Context, record audit.Record) (audit.Record, error) {
+	m.Records = append(m.Records, record)
+	return record, nil
+}
+
+func TestAuditRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		header         string
+		value          string
+		expectedStatus int
+		expectedCaller string
+		expectedTenant string
+		expectedRoute  string
+	}{
+		{
+			name:           "API key",
+			path:           "/haiku",
+			body:           `{"commitMessage":"fix: resolved login issue"}`,
+			header:         APIKeyHeader,
+			value:          "acme-key",
+			expectedStatus: http.StatusOK,
+			expectedCaller: "key:acme",
+			expectedTenant: "acme",
+			expectedRoute:  "/haiku",
+		},
+		{
+			name:           "Admin token",
+			path:           "/admin/keys",
+			body:           `{"name":"ci"}`,
+			header:         "Authorization",
+			value:          "Bearer " + testAdminToken,
+			expectedStatus: http.StatusCreated,
+			expectedCaller: RequestedByAdminToken,
+			expectedRoute:  "/admin/keys",
+		},
+		{
+			name:           "Refused without a key",
+			path:           "/haiku",
+			body:           `{"commitMessage":"fix: resolved login issue"}`,
+			expectedStatus: http.StatusUnauthorized,
+			expectedRoute:  "/haiku",
+		},
+		{
+			name:           "Unknown route",
+			path:           "/wp-login.php",
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			auditLog := &MockAuditLog{}
+			mockService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"}}
+			api := NewHaikuAPI(mockService, &Options{
+				Keys:          newTestKeyService(),
+				AdminToken:    testAdminToken,
+				RequireAPIKey: true,
+				Audit:         auditLog,
+			})
+
+			router := gin.New()
+			api.SetupMiddleware(router)
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", tc.path, bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("User-Agent", "haiku-cli/1.0")
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedStatus, w.Code, w.Body.String())
+			}
+			if len(auditLog.Records) != 1 {
+				t.Fatalf("Expected one audit record, got %+v", auditLog.Records)
+			}
+
+			record := auditLog.Records[0]
+			if record.Caller != tc.expectedCaller || record.Tenant != tc.expectedTenant {
+				t.Errorf("Expected caller %q of tenant %q, got %q of %q", tc.expectedCaller, tc.expectedTenant, record.Caller, record.Tenant)
+			}
+			if record.Route != tc.expectedRoute || record.Path != tc.path || record.Method != "POST" || record.Status != tc.expectedStatus {
+				t.Errorf("Expected POST %s (%s) answered %d, got %+v", tc.path, tc.expectedRoute, tc.expectedStatus, record)
+			}
+			if record.UserAgent != "haiku-cli/1.0" || record.Time.IsZero() {
+				t.Errorf("Expected the user agent and time recorded, got %+v", record)
+			}
+		})
+	}
+}
+
+func TestAuditRequestsTrail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	auditLog := &MockAuditLog{}
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Audit: auditLog})
+
+	router := gin.New()
+	api.SetupMiddleware(router)
+	router.POST("/haiku", func(c *gin.Context) {
+		audit.Moderated(c.Request.Context(), audit.Moderation{Blocked: true, Reason: "guardrail"})
+		audit.Moderated(c.Request.Context(), audit.Moderation{})
+		audit.Served(c.Request.Context(), audit.Haiku{ID: "abc123", Model: "claude", PromptVersion: "v2"})
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/haiku", nil))
+
+	if len(auditLog.Records) != 1 {
+		t.Fatalf("Expected one audit record, got %+v", auditLog.Records)
+	}
+	record := auditLog.Records[0]
+	if len(record.Moderation) != 2 || !record.Moderation[0].Blocked || record.Moderation[0].Reason != "guardrail" {
+		t.Errorf("Expected a blocked then an allowed haiku, got %+v", record.Moderation)
+	}
+	expected := audit.Haiku{ID: "abc123", Model: "claude", PromptVersion: "v2"}
+	if len(record.Haiku) != 1 || record.Haiku[0] != expected {
+		t.Errorf("Expected %+v served, got %+v", expected, record.Haiku)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+type bitbucketPushEvent struct {
+	Push struct {
+		Changes []struct {
+			New *struct {
+				Type   string `json:"type"`
+				Name   string `json:"name"`
+				Target struct {
+					Type    string `json:"type"`
+					Hash    string `json:"hash"`
+					Message string `json:"message"`
+				} `json:"target"`
+			} `json:"new"`
+		} `json:"changes"`
+	} `json:"push"`
+	Repository struct {
+		FullName string `json:"full_name"`
+	} `json:"repository"`
+}
+
+func (api *HaikuAPI) postBitbucketWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookPayload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error reading bitbucket webhook: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	if !validSignature(api.options.BitbucketWebhookSecret, payload, c.GetHeader("X-Hub-Signature")) {
+		logging.Warnf("[HAIKU API] invalid bitbucket webhook signature")
+		problem(c, http.StatusUnauthorized, CodeInvalidSignature, InvalidSignature, "")
+		return
+	}
+
+	event, ok, err := parseBitbucketEvent(c.GetHeader("X-Event-Key"), payload)
+	if err != nil {
+		logging.Errorf("[HAIKU API] error parsing bitbucket webhook: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ignored",
+		})
+		return
+	}
+
+	api.handleWebhookEvent(c, api.options.BitbucketWebhooks, event)
+}
+
+// parseBitbucketEvent converts repo:push deliveries to a webhook event for the
+// newest commit pushed. Other deliveries, and pushes that only delete branches
+// or tags, are reported as not ok.
+func parseBitbucketEvent(eventKey string, payload []byte) (webhook.Event, bool, error) {
+	if eventKey != "repo:push" {
+		return webhook.Event{}, false, nil
+	}
+
+	var push bitbucketPushEvent
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return webhook.Event{}, false, err
+	}
+
+	// A push can update several refs; the last change is the most recent one.
+	changes := push.Push.Changes
+	for i := len(changes) - 1; i >= 0; i-- {
+		change := changes[i]
+		if change.New == nil || change.New.Target.Type != "commit" || change.New.Target.Hash == "" {
+			continue
+		}
+		event := webhook.Event{
+			CommitMessage: change.New.Target.Message,
+			Target: webhook.Target{
+				Repository: push.Repository.FullName,
+				CommitSHA:  change.New.Target.Hash,
+			},
+		}
+		if change.New.Type == "branch" {
+			event.Branch = change.New.Name
+		}
+		return event, true, nil
+	}
+
+	return webhook.Event{}, false, nil
+}
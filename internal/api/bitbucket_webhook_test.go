@@ -0,0 +1,104 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostBitbucketWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	push := `{"push":{"changes":[{"old":null,"new":{"type":"branch","name":"main","target":{"type":"commit","hash":"abc123","message":"fix: resolved login issue\n"}}}]},"repository":{"full_name":"leafy/leaves"}}`
+
+	tests := []struct {
+		name               string
+		eventKey           string
+		payload            string
+		signature          string
+		expectedStatusCode int
+		expectedEvent      *webhook.Event
+	}{
+		{
+			name:               "Push",
+			eventKey:           "repo:push",
+			payload:            push,
+			expectedStatusCode: http.StatusOK,
+			expectedEvent: &webhook.Event{
+				CommitMessage: "fix: resolved login issue\n",
+				Target:        webhook.Target{Repository: "leafy/leaves", CommitSHA: "abc123"},
+				Branch:        "main",
+			},
+		},
+		{
+			name:               "Branch deletion ignored",
+			eventKey:           "repo:push",
+			payload:            `{"push":{"changes":[{"old":{"type":"branch","name":"old"},"new":null}]},"repository":{"full_name":"leafy/leaves"}}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Other events ignored",
+			eventKey:           "pullrequest:created",
+			payload:            `{}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid signature",
+			eventKey:           "repo:push",
+			payload:            push,
+			signature:          "sha256=00",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Malformed payload",
+			eventKey:           "repo:push",
+			payload:            `{"push":`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			webhooks := &MockWebhookService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"},
+			}
+
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				BitbucketWebhooks:      webhooks,
+				BitbucketWebhookSecret: testWebhookSecret,
+			})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			signature := tc.signature
+			if signature == "" {
+				signature = signPayload([]byte(tc.payload))
+			}
+
+			req, _ := http.NewRequest("POST", "/webhooks/bitbucket", bytes.NewBufferString(tc.payload))
+			req.Header.Set("X-Event-Key", tc.eventKey)
+			req.Header.Set("X-Hub-Signature", signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+
+			if tc.expectedEvent == nil {
+				if webhooks.LastEvent != nil {
+					t.Errorf("Expected the event to be ignored, got %+v", webhooks.LastEvent)
+				}
+				return
+			}
+			if *webhooks.LastEvent != *tc.expectedEvent {
+				t.Errorf("Expected event %+v, got %+v", tc.expectedEvent, webhooks.LastEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func (api *HaikuAPI) postChangelogHaiku(c *gin.Context) {
+	var request haiku.ChangelogRequest
+
+	// Validate request format
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logging.Errorf("[HAIKU API] error binding changelog request: %v", err)
+		bindingError(c, err)
+		return
+	}
+
+	if request.Mood != "" && !request.Mood.IsValid() {
+		logging.Warnf("[HAIKU API] invalid mood: %s", request.Mood)
+		invalidRequest(c, "", invalidValue("mood", haiku.Moods))
+		return
+	}
+
+	// Enforce max changelog length
+	if len(request.Changelog) > MaxChangelogLength {
+		logging.Warnf("[HAIKU API] changelog exceeds %d characters", MaxChangelogLength)
+		invalidRequest(c, "", tooLong("changelog", MaxChangelogLength))
+		return
+	}
+
+	response, err := api.haikuService.CreateChangelogHaiku(c.Request.Context(), request)
+
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostChangelogHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestBody        any
+		mockResponse       haiku.ChangelogResponse
+		mockError          error
+		expectedStatusCode int
+		expectedError      string
+	}{
+		{
+			name: "Successful request",
+			requestBody: haiku.ChangelogRequest{
+				Changelog: "## [1.1.0] - 2024-03-05\n### Added\n- Changelog anthology",
+			},
+			mockResponse: haiku.ChangelogResponse{
+				Version: "1.1.0",
+				Date:    "2024-03-05",
+				Haiku: []haiku.CategoryHaiku{
+					{Category: "Added", Haiku: "Seeds in fresh soil\nnew branches reach for the light\nthe garden expands"},
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Missing changelog",
+			requestBody:        map[string]string{"version": "1.1.0"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Changelog too long",
+			requestBody: haiku.ChangelogRequest{
+				Changelog: strings.Repeat("a", MaxChangelogLength+1),
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Service returns bad haiku request error",
+			requestBody: haiku.ChangelogRequest{
+				Changelog: "not a changelog",
+			},
+			mockError:          haiku.ErrBadHaikuRequest,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Service returns internal error",
+			requestBody: haiku.ChangelogRequest{
+				Changelog: "### Fixed\n- Login timeout",
+			},
+			mockError:          errors.New("some internal error"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedError:      InternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{
+				ChangelogResponseToReturn: tc.mockResponse,
+				ErrorToReturn:             tc.mockError,
+			}
+
+			api := NewHaikuAPI(mockService, nil)
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			requestBody, err := json.Marshal(tc.requestBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/haiku/changelog", bytes.NewBuffer(requestBody))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var response haiku.ChangelogResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Version != tc.mockResponse.Version || len(response.Haiku) != len(tc.mockResponse.Haiku) {
+					t.Errorf("Expected response %+v, got %+v", tc.mockResponse, response)
+				}
+			} else {
+				var response map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if errorMsg, ok := response["title"].(string); !ok || errorMsg != tc.expectedError {
+					t.Errorf("Expected error %q, got %q", tc.expectedError, errorMsg)
+				}
+			}
+		})
+	}
+}
@@ -1,8 +1,37 @@
 package api
 
+import "time"
+
 const (
 	InvalidRequest      = "Invalid request format"
 	InternalServerError = "Server encounted error processing request"
+	ContentBlocked      = "Generated haiku was blocked by the content filter"
+	InvalidHaiku        = "Could not generate a valid 5-7-5 haiku"
+	InvalidSignature    = "Invalid webhook signature"
+	Unauthorized        = "Missing or invalid bearer token"
+	InvalidAPIKey       = "Missing or invalid API key"
+	Forbidden           = "API key lacks the required scope"
+	NotFound            = "Resource not found"
+	AlreadyVoted        = "Already voted for this haiku"
+	NotPublished        = "Haiku is awaiting moderation, try again shortly"
+	RengaBusy           = "Other verses were being added to the renga, try again"
+	Throttled           = "Too many requests to the model, try again shortly"
+	QuotaExceeded       = "Model quota exceeded, try again later"
+	KeyQuotaExceeded    = "API key's monthly quota is used up"
+	ModelUnavailable    = "Model is currently unavailable, try again later"
+
+	SlackUsage         = "Usage: /haiku <commit message>"
+	SlackAcknowledged  = "Writing your haiku..."
+	DiscordUsage       = "Usage: /haiku message:<commit message>"
+	TeamsUsage         = "Usage: @<webhook name> <commit message>"
+	CommandUnavailable = "Sorry, the haiku could not be written right now. Please try again."
+	DigestUnsubscribed = "You have been unsubscribed from the weekly haiku digest."
 
-	MaxCommitLength = 100
+	MaxCommitLength       = 100
+	TruncatedBodyLength   = 50
+	MaxReleaseNotesLength = 5000
+	MaxChangelogLength    = 10000
+	MaxWebhookPayload     = 5 << 20
+	SlackRequestMaxAge    = 5 * time.Minute
+	TeamsReplyTimeout     = 5 * time.Second
 )
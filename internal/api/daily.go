@@ -0,0 +1,24 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getDailyHaiku returns the haiku of the day. Displays and proxies may cache
+// it until midnight UTC, when the next day's haiku replaces it.
+func (api *HaikuAPI) getDailyHaiku(c *gin.Context) {
+	daily, err := api.options.Daily.Get(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	maxAge := max(math.Ceil(time.Until(daily.ExpiresAt).Seconds()), 0)
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge)))
+	c.JSON(http.StatusOK, daily)
+}
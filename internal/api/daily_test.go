@@ -0,0 +1,101 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/daily"
+	"github.com/gin-gonic/gin"
+)
+
+type MockDailyService struct {
+	DailyToReturn daily.Daily
+	ErrorToReturn error
+}
+
+func (m *MockDailyService) Get(ctx context.Context) (daily.Daily, error) {
+	return m.DailyToReturn, m.ErrorToReturn
+}
+
+func TestGetDailyHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		mockError          error
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:               "Haiku of the day",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Model throttled",
+			mockError:          bedrock.ErrThrottling,
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedCode:       CodeThrottled,
+		},
+		{
+			name:               "Store fails",
+			mockError:          errors.Join(daily.ErrGetDaily, errors.New("table not found")),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       CodeInternalError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDaily := &MockDailyService{
+				DailyToReturn: daily.Daily{Date: "2025-10-01", Haiku: "Old cracks mended now", ExpiresAt: time.Now().Add(time.Hour)},
+				ErrorToReturn: tc.mockError,
+			}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Daily: mockDaily})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/haiku/daily", nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+				return
+			}
+
+			maxAge, err := strconv.Atoi(strings.TrimPrefix(w.Header().Get("Cache-Control"), "public, max-age="))
+			if err != nil || maxAge < 3599 || maxAge > 3600 {
+				t.Errorf("Expected the haiku cached for an hour, got %q", w.Header().Get("Cache-Control"))
+			}
+			var response daily.Daily
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Date != "2025-10-01" || response.Haiku != "Old cracks mended now" {
+				t.Errorf("Expected the haiku of the day, got %+v", response)
+			}
+		})
+	}
+}
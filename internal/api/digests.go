@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+	"github.com/gin-gonic/gin"
+)
+
+// postDigest subscribes an address to the weekly digest of the caller's
+// tenant.
+func (api *HaikuAPI) postDigest(c *gin.Context) {
+	var request digest.SubscribeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	subscription, err := api.options.Digests.Subscribe(c.Request.Context(), request)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, subscription)
+}
+
+// getDigests lists the digest subscriptions of the caller's tenant.
+func (api *HaikuAPI) getDigests(c *gin.Context) {
+	subscriptions, err := api.options.Digests.List(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, digest.Subscriptions{Subscriptions: subscriptions})
+}
+
+func (api *HaikuAPI) deleteDigest(c *gin.Context) {
+	if err := api.options.Digests.Unsubscribe(c.Request.Context(), c.Param("id")); err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getUnsubscribeDigest removes a subscription for whoever follows the link in
+// one of its digests, so it answers in plain text rather than JSON.
+func (api *HaikuAPI) getUnsubscribeDigest(c *gin.Context) {
+	if err := api.options.Digests.UnsubscribeWithToken(c.Request.Context(), c.Param("id"), c.Query("token")); err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.String(http.StatusOK, DigestUnsubscribed)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/gin-gonic/gin"
+)
+
+type MockDigestService struct {
+	ErrorToReturn error
+	LastTenant    string
+	LastID        string
+	LastToken     string
+}
+
+func (m *MockDigestService) Subscribe(ctx context.Context, request digest.SubscribeRequest) (digest.Subscription, error) {
+	m.LastTenant = keys.TenantFromContext(ctx)
+	return digest.Subscription{Tenant: m.LastTenant, Email: request.Email}, m.ErrorToReturn
+}
+
+func (m *MockDigestService) List(ctx context.Context) ([]digest.Subscription, error) {
+	m.LastTenant = keys.TenantFromContext(ctx)
+	return nil, m.ErrorToReturn
+}
+
+func (m *MockDigestService) Unsubscribe(ctx context.Context, id string) error {
+	m.LastTenant, m.LastID = keys.TenantFromContext(ctx), id
+	return m.ErrorToReturn
+}
+
+func (m *MockDigestService) UnsubscribeWithToken(ctx context.Context, id string, token string) error {
+	m.LastID, m.LastToken = id, token
+	return m.ErrorToReturn
+}
+
+func TestDigestRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		authorization      string
+		mockError          error
+		expectedStatusCode int
+		expectedTenant     string
+		expectedToken      string
+	}{
+		{name: "Subscribe", method: "POST", path: "/admin/digests", body: `{"email":"mona@example.com"}`, authorization: "acme-key", expectedStatusCode: http.StatusCreated, expectedTenant: "acme"},
+		{name: "Subscribe without an email", method: "POST", path: "/admin/digests", body: `{}`, authorization: testAdminToken, expectedStatusCode: http.StatusBadRequest},
+		{name: "Subscribe a bad address", method: "POST", path: "/admin/digests", body: `{"email":"mona"}`, authorization: testAdminToken, mockError: digest.ErrBadSubscription, expectedStatusCode: http.StatusBadRequest},
+		{name: "List", method: "GET", path: "/admin/digests", authorization: testAdminToken, expectedStatusCode: http.StatusOK},
+		{name: "Delete", method: "DELETE", path: "/admin/digests/abc", authorization: "acme-key", expectedStatusCode: http.StatusNoContent, expectedTenant: "acme"},
+		{name: "Delete unknown", method: "DELETE", path: "/admin/digests/abc", authorization: testAdminToken, mockError: digest.ErrSubscriptionNotFound, expectedStatusCode: http.StatusNotFound},
+		{name: "Without the admin token", method: "GET", path: "/admin/digests", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Unsubscribe link", method: "GET", path: "/digests/abc/unsubscribe?token=secret", expectedStatusCode: http.StatusOK, expectedToken: "secret"},
+		{name: "Unsubscribe with a wrong token", method: "GET", path: "/digests/abc/unsubscribe?token=wrong", mockError: digest.ErrSubscriptionNotFound, expectedStatusCode: http.StatusNotFound, expectedToken: "wrong"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDigests := &MockDigestService{ErrorToReturn: tc.mockError}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: newTestKeyService(), AdminToken: testAdminToken, Digests: mockDigests})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if mockDigests.LastTenant != tc.expectedTenant {
+				t.Errorf("Expected the subscriptions of tenant %q, got %q", tc.expectedTenant, mockDigests.LastTenant)
+			}
+			if mockDigests.LastToken != tc.expectedToken {
+				t.Errorf("Expected token %q, got %q", tc.expectedToken, mockDigests.LastToken)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/discord"
+	"github.com/gin-gonic/gin"
+)
+
+// Discord interaction types, response types and flags.
+const (
+	discordPing                = 1
+	discordApplicationCommand  = 2
+	discordCommandOptionString = 3
+
+	discordPong                   = 1
+	discordChannelMessage         = 4
+	discordDeferredChannelMessage = 5
+	discordEphemeral              = 1 << 6
+)
+
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+type discordInteraction struct {
+	Type          int    `json:"type"`
+	ApplicationID string `json:"application_id"`
+	Token         string `json:"token"`
+	Data          struct {
+		Options []struct {
+			Type  int             `json:"type"`
+			Value json.RawMessage `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	// Member is set for commands run in a server and User for direct messages.
+	Member *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User *discordUser `json:"user"`
+}
+
+// text returns the command's first string option.
+func (i discordInteraction) text() string {
+	for _, option := range i.Data.Options {
+		var value string
+		if option.Type == discordCommandOptionString && json.Unmarshal(option.Value, &value) == nil {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+func (i discordInteraction) userID() string {
+	if i.Member != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// postDiscordInteraction answers pings and defers /haiku commands, whose
+// haiku replaces the deferred response once it is ready.
+func (api *HaikuAPI) postDiscordInteraction(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookPayload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error reading discord interaction: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	if !validDiscordSignature(api.options.DiscordPublicKey, c.GetHeader("X-Signature-Timestamp"), payload, c.GetHeader("X-Signature-Ed25519")) {
+		logging.Warnf("[HAIKU API] invalid discord interaction signature")
+		problem(c, http.StatusUnauthorized, CodeInvalidSignature, InvalidSignature, "")
+		return
+	}
+
+	var interaction discordInteraction
+	if err := json.Unmarshal(payload, &interaction); err != nil {
+		logging.Errorf("[HAIKU API] error parsing discord interaction: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	switch interaction.Type {
+	case discordPing:
+		c.JSON(http.StatusOK, gin.H{"type": discordPong})
+		return
+	case discordApplicationCommand:
+	default:
+		logging.Warnf("[HAIKU API] unsupported discord interaction type %d", interaction.Type)
+		invalidRequest(c, "")
+		return
+	}
+
+	command := discord.Interaction{
+		ApplicationID: interaction.ApplicationID,
+		Token:         interaction.Token,
+		Text:          interaction.text(),
+		UserID:        interaction.userID(),
+	}
+	if command.Text == "" {
+		discordMessage(c, DiscordUsage)
+		return
+	}
+
+	if len(command.Text) > api.options.MaxCommitLength {
+		command.Text = TruncateCommitMessage(command.Text, api.options.MaxCommitLength, api.options.TruncatedBodyLength)
+	}
+
+	if err := api.options.DiscordInteractions.Dispatch(c.Request.Context(), command); err != nil {
+		if errors.Is(err, discord.ErrBadInteraction) {
+			logging.Warnf("[HAIKU API] bad discord interaction: %v", err)
+			invalidRequest(c, err.Error())
+			return
+		}
+
+		logging.Errorf("[HAIKU API] error dispatching discord interaction: %v", err)
+		discordMessage(c, CommandUnavailable)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"type": discordDeferredChannelMessage})
+}
+
+// discordMessage responds with a message only the user who ran the command
+// can see.
+func discordMessage(c *gin.Context, content string) {
+	c.JSON(http.StatusOK, gin.H{
+		"type": discordChannelMessage,
+		"data": gin.H{
+			"content": content,
+			"flags":   discordEphemeral,
+		},
+	})
+}
+
+// validDiscordSignature checks the Ed25519 signature Discord sends with each
+// interaction, made over the timestamp and body with the application's key.
+func validDiscordSignature(publicKey ed25519.PublicKey, timestamp string, payload []byte, signature string) bool {
+	if len(publicKey) != ed25519.PublicKeySize || timestamp == "" {
+		return false
+	}
+
+	decoded, err := hex.DecodeString(signature)
+	if err != nil || len(decoded) != ed25519.SignatureSize {
+		return false
+	}
+
+	return ed25519.Verify(publicKey, append([]byte(timestamp), payload...), decoded)
+}
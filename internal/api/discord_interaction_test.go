@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/discord"
+	"github.com/gin-gonic/gin"
+)
+
+type MockDiscordDispatcher struct {
+	ErrorToReturn   error
+	LastInteraction *discord.Interaction
+}
+
+func (m *MockDiscordDispatcher) Dispatch(ctx context.Context, interaction discord.Interaction) error {
+	m.LastInteraction = &interaction
+	return m.ErrorToReturn
+}
+
+func TestPostDiscordInteraction(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	command := `{"type":2,"application_id":"1234","token":"tok","data":{"name":"haiku","options":[{"name":"message","type":3,"value":"fix flaky test"}]},"member":{"user":{"id":"42"}}}`
+
+	tests := []struct {
+		name               string
+		payload            string
+		signature          string
+		dispatchError      error
+		expectedStatusCode int
+		expectedType       int
+		expectedContent    string
+		expectedDispatch   *discord.Interaction
+	}{
+		{
+			name:               "Ping",
+			payload:            `{"type":1}`,
+			expectedStatusCode: http.StatusOK,
+			expectedType:       1,
+		},
+		{
+			name:               "Command deferred",
+			payload:            command,
+			expectedStatusCode: http.StatusOK,
+			expectedType:       5,
+			expectedDispatch:   &discord.Interaction{ApplicationID: "1234", Token: "tok", Text: "fix flaky test", UserID: "42"},
+		},
+		{
+			name:               "Direct message command",
+			payload:            `{"type":2,"application_id":"1234","token":"tok","data":{"options":[{"type":3,"value":"fix flaky test"}]},"user":{"id":"7"}}`,
+			expectedStatusCode: http.StatusOK,
+			expectedType:       5,
+			expectedDispatch:   &discord.Interaction{ApplicationID: "1234", Token: "tok", Text: "fix flaky test", UserID: "7"},
+		},
+		{
+			name:               "Missing message shows usage",
+			payload:            `{"type":2,"application_id":"1234","token":"tok","data":{"options":[]}}`,
+			expectedStatusCode: http.StatusOK,
+			expectedType:       4,
+			expectedContent:    DiscordUsage,
+		},
+		{
+			name:               "Dispatch failure reported to the user",
+			payload:            command,
+			dispatchError:      errors.New("throttled"),
+			expectedStatusCode: http.StatusOK,
+			expectedType:       4,
+			expectedContent:    CommandUnavailable,
+		},
+		{
+			name:               "Unsupported interaction",
+			payload:            `{"type":3}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid signature",
+			payload:            command,
+			signature:          hex.EncodeToString(make([]byte, ed25519.SignatureSize)),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dispatcher := &MockDiscordDispatcher{ErrorToReturn: tc.dispatchError}
+
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				DiscordInteractions: dispatcher,
+				DiscordPublicKey:    publicKey,
+			})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			timestamp := "1700000000"
+			signature := tc.signature
+			if signature == "" {
+				signature = hex.EncodeToString(ed25519.Sign(privateKey, []byte(timestamp+tc.payload)))
+			}
+
+			req, _ := http.NewRequest("POST", "/integrations/discord", bytes.NewBufferString(tc.payload))
+			req.Header.Set("X-Signature-Timestamp", timestamp)
+			req.Header.Set("X-Signature-Ed25519", signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedType == 0 {
+				return
+			}
+
+			var response struct {
+				Type int `json:"type"`
+				Data struct {
+					Content string `json:"content"`
+					Flags   int    `json:"flags"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Type != tc.expectedType {
+				t.Fatalf("Expected response type %d, got %s", tc.expectedType, w.Body.String())
+			}
+			if tc.expectedContent != "" && (response.Data.Content != tc.expectedContent || response.Data.Flags != 64) {
+				t.Errorf("Expected ephemeral message %q, got %s", tc.expectedContent, w.Body.String())
+			}
+			if tc.expectedDispatch != nil && (dispatcher.LastInteraction == nil || *dispatcher.LastInteraction != *tc.expectedDispatch) {
+				t.Errorf("Expected interaction %+v to be dispatched, got %+v", tc.expectedDispatch, dispatcher.LastInteraction)
+			}
+		})
+	}
+}
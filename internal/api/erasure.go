@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestedByAdminToken names requests made with the admin token rather than
+// an admin key in audit records, such as those of erasures.
+const RequestedByAdminToken = "admin-token"
+
+// deleteAuthorHaiku erases every kept haiku written for the commit author
+// :id and returns the audit record of the erasure.
+func (api *HaikuAPI) deleteAuthorHaiku(c *gin.Context) {
+	requestedBy := RequestedByAdminToken
+	if key, ok := c.Get(apiKeyContextKey); ok {
+		requestedBy = "key:" + key.(keys.Key).ID
+	}
+
+	erasure, err := api.options.Erasure.Erase(c.Request.Context(), c.Param("id"), requestedBy)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, erasure)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/erasure"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+type MockErasureService struct {
+	ErrorToReturn   error
+	LastAuthor      string
+	LastRequestedBy string
+}
+
+func (m *MockErasureService) Erase(ctx context.Context, author string, requestedBy string) (erasure.Erasure, error) {
+	m.LastAuthor = author
+	m.LastRequestedBy = requestedBy
+	return erasure.Erasure{ID: "e1", RequestedBy: requestedBy, Haiku: 2}, m.ErrorToReturn
+}
+
+func TestDeleteAuthorHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name                string
+		authorization       string
+		mockError           error
+		expectedStatusCode  int
+		expectedRequestedBy string
+	}{
+		{
+			name:                "Admin token",
+			authorization:       "Bearer " + testAdminToken,
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestedBy: RequestedByAdminToken,
+		},
+		{
+			name:                "Admin key",
+			authorization:       "Bearer admin-key",
+			expectedStatusCode:  http.StatusOK,
+			expectedRequestedBy: "key:admin",
+		},
+		{
+			name:               "Key without the admin scope",
+			authorization:      "Bearer haiku-key",
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "No token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Service error",
+			authorization:      "Bearer " + testAdminToken,
+			mockError:          erasure.ErrErase,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockErasure := &MockErasureService{ErrorToReturn: tc.mockError}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Erasure: mockErasure, Keys: newTestKeyService(), AdminToken: testAdminToken})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("DELETE", "/authors/Mona%20Lisa/haiku", nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if mockErasure.LastAuthor != "Mona Lisa" || mockErasure.LastRequestedBy != tc.expectedRequestedBy {
+				t.Errorf("Expected Mona Lisa erased for %s, got %q for %q", tc.expectedRequestedBy, mockErasure.LastAuthor, mockErasure.LastRequestedBy)
+			}
+			var response erasure.Erasure
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.ID != "e1" || response.Haiku != 2 {
+				t.Errorf("Expected the audit record, got %+v", response)
+			}
+		})
+	}
+}
+
+func TestDeleteAuthorHaikuDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Erasure: &MockErasureService{ErrorToReturn: errors.New("unused")}})
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("DELETE", "/authors/Mona/haiku", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected erasure to be off without an admin token, got %d", w.Code)
+	}
+}
+
+func TestDeleteAuthorHaikuStaysInTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedKept  []string
+	}{
+		{
+			name:          "Default tenant's admin key",
+			authorization: "Bearer admin-key",
+			expectedKept:  []string{"acme"},
+		},
+		{
+			name:          "Tenant's admin key",
+			authorization: "Bearer acme-key",
+			expectedKept:  []string{""},
+		},
+		{
+			name:          "Operator key",
+			authorization: "Bearer ops-key",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := votes.NewMemoryStore()
+			voteService := votes.NewVoteService(store, nil)
+			for _, tenant := range []string{"", "acme"} {
+				if _, err := voteService.Save(context.Background(), haiku.Stored{Haiku: "leaves", Author: "Mona", Tenant: tenant}); err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+			}
+			erasureService := erasure.NewErasureService(voteService, store, nil)
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Erasure: erasureService, Keys: newTestKeyService(), AdminToken: testAdminToken})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, _ := http.NewRequest("DELETE", "/authors/Mona/haiku", nil)
+			req.Header.Set("Authorization", tc.authorization)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var kept []string
+			err := voteService.Each(context.Background(), func(saved votes.Saved) error {
+				kept = append(kept, saved.Tenant)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !slices.Equal(kept, tc.expectedKept) {
+				t.Errorf("Expected the haiku of tenants %q kept, got %q", tc.expectedKept, kept)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metrics"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/renga"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/styles"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// errorMapping is the problem returned for service errors wrapping target.
+type errorMapping struct {
+	target error
+	status int
+	code   string
+	title  string
+	detail bool          // Whether the error text is safe to return as the problem detail
+	class  metrics.Class // Error class the failure is counted under (default: not counted)
+}
+
+// errorMappings are checked in order, so more specific errors come first.
+// Errors matching none of them are internal server errors, counted as
+// metrics.ClassInternal.
+var errorMappings = []errorMapping{
+	{target: jobs.ErrJobNotFound, status: http.StatusNotFound, code: CodeNotFound, title: NotFound},
+	{target: keys.ErrKeyNotFound, status: http.StatusNotFound, code: CodeNotFound, title: NotFound},
+	{target: votes.ErrHaikuNotFound, status: http.StatusNotFound, code: CodeNotFound, title: NotFound},
+	{target: styles.ErrStyleNotFound, status: http.StatusNotFound, code: CodeNotFound, title: NotFound},
+	{target: renga.ErrRengaNotFound, status: http.StatusNotFound, code: CodeNotFound, title: NotFound},
+	{target: digest.ErrSubscriptionNotFound, status: http.StatusNotFound, code: CodeNotFound, title: NotFound},
+	{target: votes.ErrAlreadyVoted, status: http.StatusConflict, code: CodeAlreadyVoted, title: AlreadyVoted},
+	{target: votes.ErrNotPublished, status: http.StatusConflict, code: CodeNotPublished, title: NotPublished},
+	{target: renga.ErrRengaBusy, status: http.StatusConflict, code: CodeRengaBusy, title: RengaBusy},
+	{target: keys.ErrNotOperator, status: http.StatusForbidden, code: CodeForbidden, title: Forbidden, detail: true},
+	{target: keys.ErrBadKeyRequest, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: styles.ErrBadStyle, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: renga.ErrBadRenga, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: digest.ErrBadSubscription, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: haiku.ErrBadHaikuRequest, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: jobs.ErrBadCallback, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: webhook.ErrBadEvent, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, detail: true, class: metrics.ClassValidation},
+	{target: haiku.ErrContentBlocked, status: http.StatusUnprocessableEntity, code: CodeContentBlocked, title: ContentBlocked, class: metrics.ClassGuardrail},
+	{target: haiku.ErrInvalidStructure, status: http.StatusUnprocessableEntity, code: CodeInvalidHaiku, title: InvalidHaiku, detail: true, class: metrics.ClassStructure},
+	{target: bedrock.ErrThrottling, status: http.StatusTooManyRequests, code: CodeThrottled, title: Throttled, class: metrics.ClassThrottling},
+	{target: bedrock.ErrQuotaExceeded, status: http.StatusTooManyRequests, code: CodeQuotaExceeded, title: QuotaExceeded, class: metrics.ClassQuota},
+	{target: bedrock.ErrValidation, status: http.StatusBadRequest, code: CodeInvalidRequest, title: InvalidRequest, class: metrics.ClassValidation},
+	{target: quotas.ErrQuotaExceeded, status: http.StatusTooManyRequests, code: CodeKeyQuotaExceeded, title: KeyQuotaExceeded, detail: true, class: metrics.ClassQuota},
+	{target: bedrock.ErrModelUnavailable, status: http.StatusServiceUnavailable, code: CodeModelUnavailable, title: ModelUnavailable, class: metrics.ClassUnavailable},
+	{target: bedrock.ErrResponseParsing, status: http.StatusInternalServerError, code: CodeInternalError, title: InternalServerError, class: metrics.ClassParse},
+}
+
+// ServiceProblem returns the problem mapped from a service error, so that
+// clients can tell errors worth retrying from the rest. It is exported so
+// that failures reported outside a request, such as a failed job, read the
+// same as a failed request.
+func ServiceProblem(err error) Problem {
+	for _, mapping := range errorMappings {
+		if !errors.Is(err, mapping.target) {
+			continue
+		}
+
+		detail := ""
+		if mapping.detail {
+			detail = err.Error()
+		}
+		return newProblem(mapping.status, mapping.code, mapping.title, detail)
+	}
+
+	return newProblem(http.StatusInternalServerError, CodeInternalError, InternalServerError, "")
+}
+
+// errorClass returns the class a service error is counted under, if any.
+// Client mistakes such as an unknown haiku or a repeated vote aren't counted.
+func errorClass(err error) (metrics.Class, bool) {
+	for _, mapping := range errorMappings {
+		if errors.Is(err, mapping.target) {
+			return mapping.class, mapping.class != ""
+		}
+	}
+	return metrics.ClassInternal, true
+}
+
+// serviceError aborts the request with the problem mapped from a service
+// error.
+func serviceError(c *gin.Context, err error) {
+	_ = c.Error(err)
+
+	p := ServiceProblem(err)
+	if p.Status >= http.StatusInternalServerError {
+		logging.Errorf("[HAIKU API] %s: %v", p.Code, err)
+	} else {
+		logging.Warnf("[HAIKU API] %s: %v", p.Code, err)
+	}
+	abortWithProblem(c, p)
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestServiceError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// Service errors wrap the provider's, as in "creating haiku: invoking
+	// Claude: request was throttled: ...".
+	wrap := func(err error) error {
+		return fmt.Errorf("%w: invoking Claude: %w", haiku.ErrCreateHaiku, fmt.Errorf("%w: ThrottlingException", err))
+	}
+
+	tests := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+		expectDetail   bool
+	}{
+		{"Bad request", haiku.ErrBadHaikuRequest, http.StatusBadRequest, CodeInvalidRequest, true},
+		{"Bad webhook event", fmt.Errorf("%w: no commits", webhook.ErrBadEvent), http.StatusBadRequest, CodeInvalidRequest, true},
+		{"Content blocked", fmt.Errorf("%w: guardrail", haiku.ErrContentBlocked), http.StatusUnprocessableEntity, CodeContentBlocked, false},
+		{"Invalid structure", fmt.Errorf("%w: 5-8-5", haiku.ErrInvalidStructure), http.StatusUnprocessableEntity, CodeInvalidHaiku, true},
+		{"Throttled", wrap(bedrock.ErrThrottling), http.StatusTooManyRequests, CodeThrottled, false},
+		{"Quota exceeded", wrap(bedrock.ErrQuotaExceeded), http.StatusTooManyRequests, CodeQuotaExceeded, false},
+		{"Provider validation", wrap(bedrock.ErrValidation), http.StatusBadRequest, CodeInvalidRequest, false},
+		{"Model unavailable", wrap(bedrock.ErrModelUnavailable), http.StatusServiceUnavailable, CodeModelUnavailable, false},
+		{"Model invocation", wrap(bedrock.ErrModelInvocation), http.StatusInternalServerError, CodeInternalError, false},
+		{"Unknown", errors.New("some internal error"), http.StatusInternalServerError, CodeInternalError, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			serviceError(c, tc.err)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, w.Code)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if problem.Code != tc.expectedCode || problem.Status != tc.expectedStatus {
+				t.Errorf("Expected code %q and status %d, got %+v", tc.expectedCode, tc.expectedStatus, problem)
+			}
+			if (problem.Detail != "") != tc.expectDetail {
+				t.Errorf("Expected detail %t, got %q", tc.expectDetail, problem.Detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportFormat is how GET /export writes a key's haiku.
+type ExportFormat string
+
+const (
+	ExportJSONL ExportFormat = "jsonl"
+	ExportCSV   ExportFormat = "csv"
+)
+
+// ExportFormats lists every export format, the default first.
+var ExportFormats = []ExportFormat{ExportJSONL, ExportCSV}
+
+// exportColumns are the CSV header, in the order exportRow writes them.
+var exportColumns = []string{"id", "createdAt", "haiku", "mood", "repository", "author", "promptVersion", "model", "status"}
+
+// getExport streams every haiku kept for the caller's API key, oldest first,
+// as JSON Lines or CSV (?format=).
+func (api *HaikuAPI) getExport(c *gin.Context) {
+	format := ExportFormat(c.DefaultQuery("format", string(ExportJSONL)))
+	if !slices.Contains(ExportFormats, format) {
+		field := invalidValue("format", ExportFormats)
+		invalidRequest(c, field.Detail, field)
+		return
+	}
+	key := c.MustGet(apiKeyContextKey).(keys.Key)
+
+	// The response starts with the first haiku, so that a failure before
+	// then can still be answered with a problem.
+	encoder := json.NewEncoder(c.Writer)
+	writer := csv.NewWriter(c.Writer)
+	started := false
+	start := func() error {
+		if started {
+			return nil
+		}
+		started = true
+		c.Header("Content-Disposition", `attachment; filename="haiku.`+string(format)+`"`)
+		if format == ExportJSONL {
+			c.Header("Content-Type", "application/x-ndjson")
+			c.Status(http.StatusOK)
+			return nil
+		}
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(http.StatusOK)
+		return writer.Write(exportColumns)
+	}
+
+	err := api.options.Votes.History(c.Request.Context(), key.ID, func(saved votes.Saved) error {
+		if err := start(); err != nil {
+			return err
+		}
+		if format == ExportJSONL {
+			if err := encoder.Encode(saved); err != nil {
+				return err
+			}
+		} else {
+			if err := writer.Write(exportRow(saved)); err != nil {
+				return err
+			}
+			writer.Flush()
+		}
+		c.Writer.Flush()
+		return writer.Error()
+	})
+	if err == nil {
+		err = start()
+		writer.Flush()
+	}
+	if err != nil && !started {
+		serviceError(c, err)
+		return
+	}
+	if err != nil {
+		// The response has started, so the caller can only tell from the
+		// truncated body.
+		_ = c.Error(err)
+		logging.Errorf("[HAIKU API] error writing export for key %s: %v", key.ID, err)
+	}
+}
+
+func exportRow(saved votes.Saved) []string {
+	row := []string{
+		saved.ID,
+		saved.CreatedAt.UTC().Format(time.RFC3339),
+		saved.Haiku,
+		string(saved.Mood),
+		saved.Repository,
+		saved.Author,
+		saved.PromptVersion,
+		saved.Model,
+		string(saved.Status),
+	}
+	for i, cell := range row {
+		row[i] = escapeFormula(cell)
+	}
+	return row
+}
+
+// escapeFormula prefixes cells a spreadsheet would run as a formula with a
+// quote, so that a haiku or repository name written by a caller is shown as
+// written rather than evaluated when the export is opened.
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
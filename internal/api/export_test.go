@@ -0,0 +1,140 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetExport(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	history := []votes.Saved{
+		{ID: "a", Stored: haiku.Stored{Haiku: "Old cracks mended now\nthe login door swings open\nquiet in the logs", Mood: haiku.MoodTechnical, Repository: "octo/leaves", KeyID: "haiku", CreatedAt: time.Date(2025, 10, 9, 14, 3, 11, 0, time.UTC)}, Status: votes.StatusPublished},
+		{ID: "b", Stored: haiku.Stored{Haiku: "leaves fall", KeyID: "haiku", CreatedAt: time.Date(2025, 10, 10, 9, 0, 0, 0, time.UTC)}, Status: votes.StatusPending},
+	}
+
+	tests := []struct {
+		name                string
+		query               string
+		apiKey              string
+		history             []votes.Saved
+		mockError           error
+		expectedStatusCode  int
+		expectedContentType string
+		expectedBody        string
+		expectedCode        string
+	}{
+		{
+			name:                "JSON Lines by default",
+			apiKey:              "haiku-key",
+			history:             history,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "application/x-ndjson",
+			expectedBody: `{"id":"a","haiku":"Old cracks mended now\nthe login door swings open\nquiet in the logs","mood":"technical","repository":"octo/leaves","keyId":"haiku","createdAt":"2025-10-09T14:03:11Z","status":"published"}` + "\n" +
+				`{"id":"b","haiku":"leaves fall","mood":"","keyId":"haiku","createdAt":"2025-10-10T09:00:00Z","status":"pending"}` + "\n",
+		},
+		{
+			name:                "CSV",
+			query:               "?format=csv",
+			apiKey:              "haiku-key",
+			history:             history,
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "id,createdAt,haiku,mood,repository,author,promptVersion,model,status\n" +
+				"a,2025-10-09T14:03:11Z,\"Old cracks mended now\nthe login door swings open\nquiet in the logs\",technical,octo/leaves,,,,published\n" +
+				"b,2025-10-10T09:00:00Z,leaves fall,,,,,,pending\n",
+		},
+		{
+			name:   "CSV with formulas",
+			query:  "?format=csv",
+			apiKey: "haiku-key",
+			history: []votes.Saved{
+				{ID: "c", Stored: haiku.Stored{Haiku: "=HYPERLINK(\"https://example.com\")", Repository: "+octo", Author: "@leaf", PromptVersion: "-1", KeyID: "haiku", CreatedAt: time.Date(2025, 10, 11, 9, 0, 0, 0, time.UTC)}, Status: votes.StatusPublished},
+			},
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody: "id,createdAt,haiku,mood,repository,author,promptVersion,model,status\n" +
+				"c,2025-10-11T09:00:00Z,\"'=HYPERLINK(\"\"https://example.com\"\")\",,'+octo,'@leaf,'-1,,published\n",
+		},
+		{
+			name:                "Empty CSV",
+			query:               "?format=csv",
+			apiKey:              "haiku-key",
+			expectedStatusCode:  http.StatusOK,
+			expectedContentType: "text/csv; charset=utf-8",
+			expectedBody:        "id,createdAt,haiku,mood,repository,author,promptVersion,model,status\n",
+		},
+		{
+			name:               "Unknown format",
+			query:              "?format=xml",
+			apiKey:             "haiku-key",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "No API key",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       CodeUnauthorized,
+		},
+		{
+			name:               "Service error",
+			apiKey:             "haiku-key",
+			mockError:          errors.New("table not found"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       CodeInternalError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockVotes := &MockVoteService{HistoryToReturn: tc.history, ErrorToReturn: tc.mockError}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Votes: mockVotes, Keys: newTestKeyService()})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/export"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+				return
+			}
+
+			if got := w.Header().Get("Content-Type"); got != tc.expectedContentType {
+				t.Errorf("Expected content type %s, got %s", tc.expectedContentType, got)
+			}
+			if w.Body.String() != tc.expectedBody {
+				t.Errorf("Expected body:\n%s\ngot:\n%s", tc.expectedBody, w.Body.String())
+			}
+			if mockVotes.LastKeyID != "haiku" {
+				t.Errorf("Expected the export scoped to key haiku, got %q", mockVotes.LastKeyID)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlags answers whether a flag is on for the caller of ctx, or def
+// when the flag isn't defined.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string, def bool) bool
+}
+
+// requireFlag answers 404 Not Found, as if the route didn't exist, while the
+// feature flag name is off for the caller. It follows authentication, so that
+// a rollout sees the caller's key. Flagged routes are on unless a flag turns
+// them off.
+func (api *HaikuAPI) requireFlag(name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if api.options.Flags != nil && !api.options.Flags.Enabled(c.Request.Context(), name, true) {
+			problem(c, http.StatusNotFound, CodeNotFound, NotFound, "")
+			return
+		}
+		c.Next()
+	}
+}
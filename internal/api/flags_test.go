@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequireFlag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		flags              flags.Flags
+		path               string
+		header             string
+		value              string
+		expectedStatusCode int
+	}{
+		{
+			name:               "Export on without a flag",
+			path:               "/export",
+			header:             APIKeyHeader,
+			value:              "haiku-key",
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Export off",
+			flags:              flags.Flags{flags.Export: {Enabled: false}},
+			path:               "/export",
+			header:             APIKeyHeader,
+			value:              "haiku-key",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Export still needs a key",
+			flags:              flags.Flags{flags.Export: {Enabled: false}},
+			path:               "/export",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Style guides on for their stage",
+			flags:              flags.Flags{flags.StyleGuides: {Enabled: true, Stages: []string{"dev"}}},
+			path:               "/admin/style",
+			header:             "Authorization",
+			value:              "Bearer " + testAdminToken,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Style guides off in other stages",
+			flags:              flags.Flags{flags.StyleGuides: {Enabled: true, Stages: []string{"prod"}}},
+			path:               "/admin/style",
+			header:             "Authorization",
+			value:              "Bearer " + testAdminToken,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				Votes:      &MockVoteService{},
+				Keys:       newTestKeyService(),
+				AdminToken: testAdminToken,
+				Styles:     &MockStyleService{},
+				Flags:      flags.NewStore(tc.flags, &flags.Options{Stage: "dev"}),
+			})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", tc.path, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+type gitHubRepository struct {
+	FullName string `json:"full_name"`
+}
+
+type gitHubInstallation struct {
+	ID int64 `json:"id"`
+}
+
+type gitHubPushEvent struct {
+	Ref        string `json:"ref"`
+	Deleted    bool   `json:"deleted"`
+	HeadCommit *struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	} `json:"head_commit"`
+	Repository   gitHubRepository   `json:"repository"`
+	Installation gitHubInstallation `json:"installation"`
+}
+
+type gitHubPullRequestEvent struct {
+	Action      string `json:"action"`
+	Number      int    `json:"number"`
+	PullRequest struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+		Head  struct {
+			Ref string `json:"ref"`
+		} `json:"head"`
+	} `json:"pull_request"`
+	Repository   gitHubRepository   `json:"repository"`
+	Installation gitHubInstallation `json:"installation"`
+}
+
+func (api *HaikuAPI) postGitHubWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookPayload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error reading github webhook: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	if !validSignature(api.options.GitHubWebhookSecret, payload, c.GetHeader("X-Hub-Signature-256")) {
+		logging.Warnf("[HAIKU API] invalid github webhook signature")
+		problem(c, http.StatusUnauthorized, CodeInvalidSignature, InvalidSignature, "")
+		return
+	}
+
+	event, ok, err := parseGitHubEvent(c.GetHeader("X-GitHub-Event"), payload)
+	if err != nil {
+		logging.Errorf("[HAIKU API] error parsing github webhook: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ignored",
+		})
+		return
+	}
+
+	api.handleWebhookEvent(c, api.options.GitHubWebhooks, event)
+}
+
+// validSignature checks the "sha256=<hex>" HMAC GitHub and Bitbucket send with
+// each delivery.
+func validSignature(secret string, payload []byte, signature string) bool {
+	digest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
+
+// branchName returns the branch a "refs/heads/<branch>" ref points at, or ""
+// for other refs such as tags.
+func branchName(ref string) string {
+	branch, ok := strings.CutPrefix(ref, "refs/heads/")
+	if !ok {
+		return ""
+	}
+	return branch
+}
+
+// parseGitHubEvent converts push and newly opened pull request deliveries to a
+// webhook event. Other deliveries, including pings, are reported as not ok.
+func parseGitHubEvent(eventType string, payload []byte) (webhook.Event, bool, error) {
+	switch eventType {
+	case "push":
+		var push gitHubPushEvent
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return webhook.Event{}, false, err
+		}
+		if push.Deleted || push.HeadCommit == nil {
+			return webhook.Event{}, false, nil
+		}
+		return webhook.Event{
+			CommitMessage: push.HeadCommit.Message,
+			Target: webhook.Target{
+				Repository:     push.Repository.FullName,
+				CommitSHA:      push.HeadCommit.ID,
+				InstallationID: push.Installation.ID,
+			},
+			Branch: branchName(push.Ref),
+		}, true, nil
+
+	case "pull_request":
+		var pullRequest gitHubPullRequestEvent
+		if err := json.Unmarshal(payload, &pullRequest); err != nil {
+			return webhook.Event{}, false, err
+		}
+		if pullRequest.Action != "opened" {
+			return webhook.Event{}, false, nil
+		}
+		return webhook.Event{
+			CommitMessage: strings.TrimSpace(pullRequest.PullRequest.Title + "\n\n" + pullRequest.PullRequest.Body),
+			Target: webhook.Target{
+				Repository:     pullRequest.Repository.FullName,
+				PullRequest:    pullRequest.Number,
+				InstallationID: pullRequest.Installation.ID,
+			},
+			Branch: pullRequest.PullRequest.Head.Ref,
+		}, true, nil
+	}
+
+	return webhook.Event{}, false, nil
+}
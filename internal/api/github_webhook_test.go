@@ -0,0 +1,195 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+const testWebhookSecret = "s3cret"
+
+type MockWebhookService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+
+	LastEvent *webhook.Event
+}
+
+func (m *MockWebhookService) HandleEvent(ctx context.Context, event webhook.Event) (haiku.HaikuCommitResponse, error) {
+	m.LastEvent = &event
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+func signPayload(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestPostGitHubWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	push := `{"ref":"refs/heads/release/1.2","head_commit":{"id":"abc123","message":"fix: resolved login issue"},"repository":{"full_name":"octo/leaves"},"installation":{"id":42}}`
+	pullRequest := `{"action":"opened","number":7,"pull_request":{"title":"Add leaves","body":"They fall.","head":{"ref":"hotfix/leaves"}},"repository":{"full_name":"octo/leaves"},"installation":{"id":42}}`
+
+	tests := []struct {
+		name               string
+		eventType          string
+		payload            string
+		signature          string
+		mockError          error
+		expectedStatusCode int
+		expectedEvent      *webhook.Event
+	}{
+		{
+			name:               "Push",
+			eventType:          "push",
+			payload:            push,
+			expectedStatusCode: http.StatusOK,
+			expectedEvent: &webhook.Event{
+				CommitMessage: "fix: resolved login issue",
+				Target:        webhook.Target{Repository: "octo/leaves", CommitSHA: "abc123", InstallationID: 42},
+				Branch:        "release/1.2",
+			},
+		},
+		{
+			name:               "Tag push has no branch",
+			eventType:          "push",
+			payload:            strings.Replace(push, "refs/heads/release/1.2", "refs/tags/v1.2", 1),
+			expectedStatusCode: http.StatusOK,
+			expectedEvent: &webhook.Event{
+				CommitMessage: "fix: resolved login issue",
+				Target:        webhook.Target{Repository: "octo/leaves", CommitSHA: "abc123", InstallationID: 42},
+			},
+		},
+		{
+			name:               "Opened pull request",
+			eventType:          "pull_request",
+			payload:            pullRequest,
+			expectedStatusCode: http.StatusOK,
+			expectedEvent: &webhook.Event{
+				CommitMessage: "Add leaves\n\nThey fall.",
+				Target:        webhook.Target{Repository: "octo/leaves", PullRequest: 7, InstallationID: 42},
+				Branch:        "hotfix/leaves",
+			},
+		},
+		{
+			name:               "Closed pull request ignored",
+			eventType:          "pull_request",
+			payload:            strings.Replace(pullRequest, "opened", "closed", 1),
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Branch deletion ignored",
+			eventType:          "push",
+			payload:            `{"deleted":true,"head_commit":null,"repository":{"full_name":"octo/leaves"}}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Ping ignored",
+			eventType:          "ping",
+			payload:            `{"zen":"Keep it logically awesome."}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Invalid signature",
+			eventType:          "push",
+			payload:            push,
+			signature:          "sha256=00",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Malformed payload",
+			eventType:          "push",
+			payload:            `{"head_commit":`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Merge queue branch ignored",
+			eventType:          "push",
+			payload:            push,
+			mockError:          webhook.ErrIgnored,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Content blocked",
+			eventType:          "push",
+			payload:            push,
+			mockError:          haiku.ErrContentBlocked,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			webhooks := &MockWebhookService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"},
+				ErrorToReturn:    tc.mockError,
+			}
+
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				GitHubWebhooks:      webhooks,
+				GitHubWebhookSecret: testWebhookSecret,
+			})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			signature := tc.signature
+			if signature == "" {
+				signature = signPayload([]byte(tc.payload))
+			}
+
+			req, _ := http.NewRequest("POST", "/webhooks/github", bytes.NewBufferString(tc.payload))
+			req.Header.Set("X-GitHub-Event", tc.eventType)
+			req.Header.Set("X-Hub-Signature-256", signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+
+			if tc.expectedEvent == nil {
+				if tc.mockError == nil && webhooks.LastEvent != nil {
+					t.Errorf("Expected the event to be ignored, got %+v", webhooks.LastEvent)
+				}
+				return
+			}
+			if *webhooks.LastEvent != *tc.expectedEvent {
+				t.Errorf("Expected event %+v, got %+v", tc.expectedEvent, webhooks.LastEvent)
+			}
+
+			var response haiku.HaikuCommitResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Haiku != "leaves fall" {
+				t.Errorf("Expected the haiku in the response, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestGitHubWebhookDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{GitHubWebhooks: &MockWebhookService{}})
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("POST", "/webhooks/github", bytes.NewBufferString("{}"))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected the webhook to be disabled without a secret, got %d", w.Code)
+	}
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+type gitLabProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type gitLabPushEvent struct {
+	Ref         string `json:"ref"`
+	CheckoutSHA string `json:"checkout_sha"`
+	Commits     []struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	} `json:"commits"`
+	Project gitLabProject `json:"project"`
+}
+
+type gitLabMergeRequestEvent struct {
+	ObjectAttributes struct {
+		IID          int    `json:"iid"`
+		Title        string `json:"title"`
+		Description  string `json:"description"`
+		Action       string `json:"action"`
+		SourceBranch string `json:"source_branch"`
+	} `json:"object_attributes"`
+	Project gitLabProject `json:"project"`
+}
+
+func (api *HaikuAPI) postGitLabWebhook(c *gin.Context) {
+	token := c.GetHeader("X-Gitlab-Token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(api.options.GitLabWebhookToken)) != 1 {
+		logging.Warnf("[HAIKU API] invalid gitlab webhook token")
+		problem(c, http.StatusUnauthorized, CodeInvalidSignature, InvalidSignature, "")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookPayload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error reading gitlab webhook: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	event, ok, err := parseGitLabEvent(c.GetHeader("X-Gitlab-Event"), payload)
+	if err != nil {
+		logging.Errorf("[HAIKU API] error parsing gitlab webhook: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ignored",
+		})
+		return
+	}
+
+	api.handleWebhookEvent(c, api.options.GitLabWebhooks, event)
+}
+
+// parseGitLabEvent converts push and newly opened merge request deliveries to
+// a webhook event. Other deliveries are reported as not ok.
+func parseGitLabEvent(eventType string, payload []byte) (webhook.Event, bool, error) {
+	switch eventType {
+	case "Push Hook":
+		var push gitLabPushEvent
+		if err := json.Unmarshal(payload, &push); err != nil {
+			return webhook.Event{}, false, err
+		}
+		// Branch deletions have no checkout commit.
+		if push.CheckoutSHA == "" || len(push.Commits) == 0 {
+			return webhook.Event{}, false, nil
+		}
+
+		head := push.Commits[len(push.Commits)-1]
+		for _, commit := range push.Commits {
+			if commit.ID == push.CheckoutSHA {
+				head = commit
+				break
+			}
+		}
+
+		return webhook.Event{
+			CommitMessage: head.Message,
+			Target: webhook.Target{
+				Repository: push.Project.PathWithNamespace,
+				CommitSHA:  head.ID,
+			},
+			Branch: branchName(push.Ref),
+		}, true, nil
+
+	case "Merge Request Hook":
+		var mergeRequest gitLabMergeRequestEvent
+		if err := json.Unmarshal(payload, &mergeRequest); err != nil {
+			return webhook.Event{}, false, err
+		}
+		attributes := mergeRequest.ObjectAttributes
+		if attributes.Action != "open" {
+			return webhook.Event{}, false, nil
+		}
+		return webhook.Event{
+			CommitMessage: strings.TrimSpace(attributes.Title + "\n\n" + attributes.Description),
+			Target: webhook.Target{
+				Repository:  mergeRequest.Project.PathWithNamespace,
+				PullRequest: attributes.IID,
+			},
+			Branch: attributes.SourceBranch,
+		}, true, nil
+	}
+
+	return webhook.Event{}, false, nil
+}
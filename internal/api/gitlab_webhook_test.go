@@ -0,0 +1,117 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostGitLabWebhook(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	push := `{"object_kind":"push","ref":"refs/heads/main","checkout_sha":"def456","commits":[{"id":"def456","message":"fix: resolved login issue"},{"id":"abc123","message":"chore: older"}],"project":{"path_with_namespace":"group/sub/leaves"}}`
+	mergeRequest := `{"object_kind":"merge_request","object_attributes":{"iid":7,"title":"Add leaves","description":"They fall.","action":"open","source_branch":"hotfix/leaves"},"project":{"path_with_namespace":"group/leaves"}}`
+
+	tests := []struct {
+		name               string
+		eventType          string
+		payload            string
+		token              string
+		expectedStatusCode int
+		expectedEvent      *webhook.Event
+	}{
+		{
+			name:               "Push",
+			eventType:          "Push Hook",
+			payload:            push,
+			token:              testWebhookSecret,
+			expectedStatusCode: http.StatusOK,
+			expectedEvent: &webhook.Event{
+				CommitMessage: "fix: resolved login issue",
+				Target:        webhook.Target{Repository: "group/sub/leaves", CommitSHA: "def456"},
+				Branch:        "main",
+			},
+		},
+		{
+			name:               "Opened merge request",
+			eventType:          "Merge Request Hook",
+			payload:            mergeRequest,
+			token:              testWebhookSecret,
+			expectedStatusCode: http.StatusOK,
+			expectedEvent: &webhook.Event{
+				CommitMessage: "Add leaves\n\nThey fall.",
+				Target:        webhook.Target{Repository: "group/leaves", PullRequest: 7},
+				Branch:        "hotfix/leaves",
+			},
+		},
+		{
+			name:               "Merged merge request ignored",
+			eventType:          "Merge Request Hook",
+			payload:            strings.Replace(mergeRequest, `"open"`, `"merge"`, 1),
+			token:              testWebhookSecret,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Branch deletion ignored",
+			eventType:          "Push Hook",
+			payload:            `{"object_kind":"push","checkout_sha":null,"commits":[],"project":{"path_with_namespace":"group/leaves"}}`,
+			token:              testWebhookSecret,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Wrong token",
+			eventType:          "Push Hook",
+			payload:            push,
+			token:              "guess",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Malformed payload",
+			eventType:          "Push Hook",
+			payload:            `{"commits":`,
+			token:              testWebhookSecret,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			webhooks := &MockWebhookService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"},
+			}
+
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				GitLabWebhooks:     webhooks,
+				GitLabWebhookToken: testWebhookSecret,
+			})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, _ := http.NewRequest("POST", "/webhooks/gitlab", bytes.NewBufferString(tc.payload))
+			req.Header.Set("X-Gitlab-Event", tc.eventType)
+			req.Header.Set("X-Gitlab-Token", tc.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+
+			if tc.expectedEvent == nil {
+				if webhooks.LastEvent != nil {
+					t.Errorf("Expected the event to be ignored, got %+v", webhooks.LastEvent)
+				}
+				return
+			}
+			if *webhooks.LastEvent != *tc.expectedEvent {
+				t.Errorf("Expected event %+v, got %+v", tc.expectedEvent, webhooks.LastEvent)
+			}
+		})
+	}
+}
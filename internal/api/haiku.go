@@ -1,55 +1,148 @@
 package api
 
 import (
-	"fmt"
-	"log"
 	"net/http"
 
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/render"
 	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
 	"github.com/gin-gonic/gin"
 )
 
 func (api *HaikuAPI) postHaiku(c *gin.Context) {
-	var request haiku.HaikuCommitRequest
-
-	// Validate request format
-	if err := c.ShouldBindJSON(&request); err != nil {
-		log.Printf("[HAIKU API] error binding request: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   InvalidRequest,
-			"details": err.Error(),
-		})
+	endValidate := timing.Start(c.Request.Context(), timing.StageValidate)
+	request, ok := api.bindHaikuRequest(c)
+	if !ok {
 		return
 	}
+	endValidate()
+
+	response, err := api.haikuService.CreateHaiku(c.Request.Context(), request)
 
-	// Enforce max commit length
-	if len(request.CommitMessage) > MaxCommitLength {
-		log.Printf("[HAIKU API] commitMessage exceeds %d characters", MaxCommitLength)
-		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
-			"error":   InvalidRequest,
-			"details": fmt.Sprintf("commitMessage exceeds %d characters", MaxCommitLength),
-		})
+	if err != nil {
+		serviceError(c, err)
 		return
 	}
 
-	response, err := api.haikuService.CreateHaiku(c.Request.Context(), request)
+	api.respondHaiku(c, response)
+}
 
+// postRegenerateHaiku writes a new haiku for the commit of a kept haiku,
+// avoiding the drafts already written for it. The request describes the
+// commit again, as for postHaiku.
+func (api *HaikuAPI) postRegenerateHaiku(c *gin.Context) {
+	endValidate := timing.Start(c.Request.Context(), timing.StageValidate)
+	request, ok := api.bindHaikuRequest(c)
+	if !ok {
+		return
+	}
+	endValidate()
+
+	response, err := api.haikuService.RegenerateHaiku(c.Request.Context(), c.Param("id"), request)
 	if err != nil {
-		if err == haiku.ErrBadHaikuRequest {
-			log.Printf("[HAIKU API] bad haiku request: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   InvalidRequest,
-				"details": err.Error(),
-			})
+		serviceError(c, err)
+		return
+	}
+
+	api.respondHaiku(c, response)
+}
+
+// respondHaiku returns a commit haiku, rendered as an SVG card too when asked
+// for with ?svg=true, and with the time spent in each stage when asked for
+// with ?timings=true.
+func (api *HaikuAPI) respondHaiku(c *gin.Context, response haiku.HaikuCommitResponse) {
+	if c.Query("svg") == "true" {
+		endRender := timing.Start(c.Request.Context(), timing.StageRender)
+		svg, err := render.SVG(response.Haiku)
+		endRender()
+		if err != nil {
+			logging.Errorf("[HAIKU API] error rendering svg: %v", err)
+			_ = c.Error(err)
+			problem(c, http.StatusInternalServerError, CodeInternalError, InternalServerError, "")
 			return
 		}
-
-		log.Printf("[HAIKU API] internal server error: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": InternalServerError,
-		})
-		return
+		response.SVG = svg
+	}
+	if timings := timing.FromContext(c.Request.Context()); timings != nil && c.Query("timings") == "true" {
+		response.Timings = timings.Milliseconds(timings.Elapsed())
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+// canSetSampling reports whether the request may set sampling parameters.
+func canSetSampling(c *gin.Context) bool {
+	value, ok := c.Get(apiKeyContextKey)
+	return !ok || value.(keys.Key).HasScope(keys.ScopeSampling)
+}
+
+// bindHaikuRequest binds and validates a haiku request, truncating its commit
+// message if needed. When the request is invalid it aborts with a problem and
+// returns false.
+func (api *HaikuAPI) bindHaikuRequest(c *gin.Context) (haiku.HaikuCommitRequest, bool) {
+	var request haiku.HaikuCommitRequest
+
+	// Validate request format. The body is kept so that endpoints extending
+	// the request can bind their own fields from it too.
+	if err := c.ShouldBindBodyWithJSON(&request); err != nil {
+		logging.Errorf("[HAIKU API] error binding request: %v", err)
+		bindingError(c, err)
+		return request, false
+	}
+
+	// Reject unknown options by field before calling the service.
+	var fields []FieldError
+	if request.Mood != "" && request.Mood != haiku.MoodAuto && !request.Mood.IsValid() {
+		fields = append(fields, invalidValue("mood", append(haiku.Moods, haiku.MoodAuto)))
+	}
+	if request.Register != "" && !request.Register.IsValid() {
+		fields = append(fields, invalidValue("register", haiku.Registers))
+	}
+	if request.PairingStyle != "" && !request.PairingStyle.IsValid() {
+		fields = append(fields, invalidValue("pairingStyle", haiku.PairingStyles))
+	}
+	if request.Variants < 0 {
+		fields = append(fields, FieldError{Field: "variants", Code: FieldInvalidValue, Detail: "variants must not be negative"})
+	}
+	if request.MaxTokens < 0 || request.MaxTokens > haiku.MaxRequestTokens {
+		fields = append(fields, outOfRange("maxTokens", 0, haiku.MaxRequestTokens))
+	}
+	if request.Temperature < 0 || request.Temperature > haiku.MaxRequestTemperature {
+		fields = append(fields, outOfRange("temperature", 0, haiku.MaxRequestTemperature))
+	}
+	if len(fields) > 0 {
+		logging.Warnf("[HAIKU API] invalid request fields: %+v", fields)
+		invalidRequest(c, "", fields...)
+		return request, false
+	}
+
+	// Sampling is tuned per key: a key needs the sampling scope to set it.
+	// Without keys, anyone may.
+	if (request.MaxTokens != 0 || request.Temperature != 0) && !canSetSampling(c) {
+		logging.Warnf("[HAIKU API] key lacks the %s scope to set sampling parameters", keys.ScopeSampling)
+		problem(c, http.StatusForbidden, CodeForbidden, Forbidden, "setting maxTokens or temperature needs a key with the sampling scope")
+		return request, false
+	}
+
+	ok := api.limitCommitMessage(c, &request.CommitMessage)
+	return request, ok
+}
+
+// limitCommitMessage enforces the max commit length, rejecting or truncating
+// longer messages. Co-authored-by trailers are not sent to the model, so they
+// do not count.
+func (api *HaikuAPI) limitCommitMessage(c *gin.Context, commitMessage *string) bool {
+	if message, _ := haiku.SplitCoAuthors(*commitMessage); len(message) > api.options.MaxCommitLength {
+		if api.options.LengthStrategy == LengthStrategyReject {
+			logging.Warnf("[HAIKU API] commitMessage exceeds %d characters", api.options.MaxCommitLength)
+			invalidRequest(c, "", tooLong("commitMessage", api.options.MaxCommitLength))
+			return false
+		}
+
+		logging.Infof("[HAIKU API] truncating commitMessage of %d characters", len(*commitMessage))
+		*commitMessage = TruncateCommitMessage(*commitMessage, api.options.MaxCommitLength, api.options.TruncatedBodyLength)
+	}
+	return true
+}
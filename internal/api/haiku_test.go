@@ -5,23 +5,51 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
 	"github.com/gin-gonic/gin"
 )
 
 type MockHaikuService struct {
-	ResponseToReturn haiku.HaikuCommitResponse
-	ErrorToReturn    error
+	ResponseToReturn             haiku.HaikuCommitResponse
+	ReleaseNotesResponseToReturn haiku.ReleaseNotesResponse
+	ChangelogResponseToReturn    haiku.ChangelogResponse
+	PulseResponseToReturn        haiku.PulseResponse
+	ErrorToReturn                error
+
+	LastRequest haiku.HaikuCommitRequest
+	LastID      string
 }
 
 func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.LastRequest = request
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+func (m *MockHaikuService) RegenerateHaiku(ctx context.Context, id string, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.LastID = id
+	m.LastRequest = request
 	return m.ResponseToReturn, m.ErrorToReturn
 }
 
+func (m *MockHaikuService) CreateReleaseNotesHaiku(ctx context.Context, request haiku.ReleaseNotesRequest) (haiku.ReleaseNotesResponse, error) {
+	return m.ReleaseNotesResponseToReturn, m.ErrorToReturn
+}
+
+func (m *MockHaikuService) CreateChangelogHaiku(ctx context.Context, request haiku.ChangelogRequest) (haiku.ChangelogResponse, error) {
+	return m.ChangelogResponseToReturn, m.ErrorToReturn
+}
+
+func (m *MockHaikuService) CreatePulseHaiku(ctx context.Context, request haiku.PulseRequest) (haiku.PulseResponse, error) {
+	return m.PulseResponseToReturn, m.ErrorToReturn
+}
+
 func TestPostHaiku(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -45,6 +73,15 @@ func TestPostHaiku(t *testing.T) {
 			mockError:          nil,
 			expectedStatusCode: http.StatusOK,
 		},
+		{
+			name: "Automatic mood",
+			requestBody: haiku.HaikuCommitRequest{
+				CommitMessage: "fix: resolved login issue",
+				Mood:          haiku.MoodAuto,
+			},
+			mockResponse:       haiku.HaikuCommitResponse{Haiku: "haiku"},
+			expectedStatusCode: http.StatusOK,
+		},
 		{
 			name:               "Invalid JSON request",
 			requestBody:        "invalid json",
@@ -64,6 +101,58 @@ func TestPostHaiku(t *testing.T) {
 			expectedStatusCode: http.StatusBadRequest,
 			expectedError:      InvalidRequest,
 		},
+		{
+			name: "Negative variants",
+			requestBody: haiku.HaikuCommitRequest{
+				CommitMessage: "test commit",
+				Variants:      -1,
+			},
+			mockResponse:       haiku.HaikuCommitResponse{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Temperature out of range",
+			requestBody: haiku.HaikuCommitRequest{
+				CommitMessage: "test commit",
+				Temperature:   1.5,
+			},
+			mockResponse:       haiku.HaikuCommitResponse{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Max tokens out of range",
+			requestBody: haiku.HaikuCommitRequest{
+				CommitMessage: "test commit",
+				MaxTokens:     haiku.MaxRequestTokens + 1,
+			},
+			mockResponse:       haiku.HaikuCommitResponse{},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Service returns content blocked error",
+			requestBody: haiku.HaikuCommitRequest{
+				CommitMessage: "test commit",
+				Mood:          haiku.MoodReflective,
+			},
+			mockResponse:       haiku.HaikuCommitResponse{},
+			mockError:          fmt.Errorf("%w: blocked word list", haiku.ErrContentBlocked),
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedError:      ContentBlocked,
+		},
+		{
+			name: "Strict request without a valid haiku",
+			requestBody: haiku.HaikuCommitRequest{
+				CommitMessage: "test commit",
+				Strict:        true,
+			},
+			mockResponse:       haiku.HaikuCommitResponse{},
+			mockError:          fmt.Errorf("%w: 4 lines instead of 3", haiku.ErrInvalidStructure),
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedError:      InvalidHaiku,
+		},
 		{
 			name: "Service returns internal error",
 			requestBody: haiku.HaikuCommitRequest{
@@ -84,7 +173,7 @@ func TestPostHaiku(t *testing.T) {
 				ErrorToReturn:    tc.mockError,
 			}
 
-			api := NewHaikuAPI(mockService)
+			api := NewHaikuAPI(mockService, nil)
 
 			// Setup router
 			router := gin.New()
@@ -133,10 +222,141 @@ func TestPostHaiku(t *testing.T) {
 				}
 			} else {
 				// Check error response
-				if errorMsg, ok := response["error"].(string); !ok || errorMsg != tc.expectedError {
+				if errorMsg, ok := response["title"].(string); !ok || errorMsg != tc.expectedError {
 					t.Errorf("Expected error %q, got %q", tc.expectedError, errorMsg)
 				}
 			}
 		})
 	}
 }
+
+func TestPostHaikuSVG(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		query       string
+		expectedSVG bool
+	}{
+		{name: "SVG requested", query: "?svg=true", expectedSVG: true},
+		{name: "SVG not requested", query: "", expectedSVG: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{
+					Haiku: "Code changes merged in\nBugs squashed with precision now\nUsers rejoice, yay",
+				},
+			}
+
+			router := gin.New()
+			NewHaikuAPI(mockService, nil).SetupRoutes(router)
+
+			req, _ := http.NewRequest("POST", "/haiku"+tc.query, bytes.NewBufferString(`{"commitMessage": "fix: resolved login issue"}`))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+
+			var response haiku.HaikuCommitResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if hasSVG := strings.HasPrefix(response.SVG, "<svg"); hasSVG != tc.expectedSVG {
+				t.Errorf("Expected svg=%v, got %q", tc.expectedSVG, response.SVG)
+			}
+		})
+	}
+}
+
+func TestPostRegenerateHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestBody        string
+		votes              bool
+		mockError          error
+		expectedStatusCode int
+		expectedCode       string
+	}{
+		{
+			name:               "Regenerated",
+			requestBody:        `{"commitMessage": "fix: resolved login issue"}`,
+			votes:              true,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Unknown haiku",
+			requestBody:        `{"commitMessage": "fix: resolved login issue"}`,
+			votes:              true,
+			mockError:          votes.ErrHaikuNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       CodeNotFound,
+		},
+		{
+			name:               "Missing commit message",
+			requestBody:        `{}`,
+			votes:              true,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Haiku are not kept",
+			requestBody:        `{"commitMessage": "fix: resolved login issue"}`,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{ID: "def", Previous: "abc", Haiku: "leaves fall again"},
+				ErrorToReturn:    tc.mockError,
+			}
+			opts := &Options{}
+			if tc.votes {
+				opts.Votes = &MockVoteService{}
+			}
+
+			router := gin.New()
+			NewHaikuAPI(mockService, opts).SetupRoutes(router)
+
+			req, _ := http.NewRequest("POST", "/haiku/abc/regenerate", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if mockService.LastID != "abc" || mockService.LastRequest.CommitMessage != "fix: resolved login issue" {
+				t.Errorf("Expected haiku abc regenerated for the commit, got %s: %+v", mockService.LastID, mockService.LastRequest)
+			}
+			var response haiku.HaikuCommitResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.ID != "def" || response.Previous != "abc" {
+				t.Errorf("Expected the new haiku replacing abc, got %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HealthOK       = "ok"
+	HealthShedding = "shedding" // Bedrock is throttling, and requests are failing without calling it
+)
+
+// RetryBudget reports the retry budget of the model client.
+type RetryBudget interface {
+	State() bedrock.RetryBudgetState
+}
+
+type HealthResponse struct {
+	Status      string                    `json:"status"`                // HealthOK or HealthShedding
+	RetryBudget *bedrock.RetryBudgetState `json:"retryBudget,omitempty"` // The model client's retry budget, when it has one
+}
+
+// getHealth reports whether the instance is serving, and the state of its
+// retry budget. An instance shedding requests still answers 200: it recovers
+// on its own once Bedrock stops throttling.
+func (api *HaikuAPI) getHealth(c *gin.Context) {
+	response := HealthResponse{Status: HealthOK}
+	if api.options.RetryBudget != nil {
+		state := api.options.RetryBudget.State()
+		response.RetryBudget = &state
+		if state.Shedding {
+			response.Status = HealthShedding
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/gin-gonic/gin"
+)
+
+type MockRetryBudget struct {
+	StateToReturn bedrock.RetryBudgetState
+}
+
+func (m *MockRetryBudget) State() bedrock.RetryBudgetState {
+	return m.StateToReturn
+}
+
+func TestGetHealth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		budget         RetryBudget
+		expectedStatus string
+		expectedBudget bool
+	}{
+		{name: "No retry budget", expectedStatus: HealthOK},
+		{
+			name:           "Retrying",
+			budget:         &MockRetryBudget{StateToReturn: bedrock.RetryBudgetState{Tokens: 20, MaxTokens: 20, Retrying: true}},
+			expectedStatus: HealthOK,
+			expectedBudget: true,
+		},
+		{
+			name:           "Shedding",
+			budget:         &MockRetryBudget{StateToReturn: bedrock.RetryBudgetState{MaxTokens: 20, Shedding: true}},
+			expectedStatus: HealthShedding,
+			expectedBudget: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{RetryBudget: tc.budget})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, _ := http.NewRequest("GET", "/health", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+			}
+			var response HealthResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if response.Status != tc.expectedStatus {
+				t.Errorf("Expected status %q, got %q", tc.expectedStatus, response.Status)
+			}
+			if (response.RetryBudget != nil) != tc.expectedBudget {
+				t.Errorf("Expected retry budget reported: %t, got %+v", tc.expectedBudget, response.RetryBudget)
+			}
+		})
+	}
+}
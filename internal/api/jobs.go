@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+// HaikuJobRequest is a haiku request to run in the background.
+type HaikuJobRequest struct {
+	haiku.HaikuCommitRequest
+	CallbackURL string `json:"callbackUrl,omitempty"` // Receives the finished job, signed with the callback secret
+}
+
+// postHaikuJob accepts a haiku request to run in the background and returns
+// the pending job straight away, with its location to poll.
+func (api *HaikuAPI) postHaikuJob(c *gin.Context) {
+	request, ok := api.bindHaikuRequest(c)
+	if !ok {
+		return
+	}
+
+	// The haiku fields were bound and checked above; only the callback is new.
+	var jobRequest HaikuJobRequest
+	if err := c.ShouldBindBodyWithJSON(&jobRequest); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	job, err := api.options.Jobs.Submit(c.Request.Context(), request, &jobs.SubmitOptions{CallbackURL: jobRequest.CallbackURL})
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.Header("Location", "/haiku/jobs/"+job.ID)
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (api *HaikuAPI) getHaikuJob(c *gin.Context) {
+	job, err := api.options.Jobs.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
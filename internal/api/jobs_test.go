@@ -0,0 +1,204 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+const testJobID = "0123456789abcdef0123456789abcdef"
+
+type MockJobService struct {
+	JobToReturn   jobs.Job
+	ErrorToReturn error
+	LastRequest   haiku.HaikuCommitRequest
+	LastOptions   *jobs.SubmitOptions
+}
+
+func (m *MockJobService) Submit(ctx context.Context, request haiku.HaikuCommitRequest, opts *jobs.SubmitOptions) (jobs.Job, error) {
+	m.LastRequest = request
+	m.LastOptions = opts
+	return m.JobToReturn, m.ErrorToReturn
+}
+
+func (m *MockJobService) Get(ctx context.Context, id string) (jobs.Job, error) {
+	if m.ErrorToReturn != nil {
+		return jobs.Job{}, m.ErrorToReturn
+	}
+	if id != m.JobToReturn.ID {
+		return jobs.Job{}, jobs.ErrJobNotFound
+	}
+	return m.JobToReturn, nil
+}
+
+func TestPostHaikuJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestBody        any
+		mockError          error
+		expectedStatusCode int
+		expectedCallback   string
+	}{
+		{
+			name:               "Accepted",
+			requestBody:        haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"},
+			expectedStatusCode: http.StatusAccepted,
+		},
+		{
+			name:               "Accepted with callback",
+			requestBody:        map[string]any{"commitMessage": "fix: resolved login issue", "mood": "technical", "callbackUrl": "https://example.com/haiku"},
+			expectedStatusCode: http.StatusAccepted,
+			expectedCallback:   "https://example.com/haiku",
+		},
+		{
+			name:               "Callback refused",
+			requestBody:        map[string]any{"commitMessage": "fix: resolved login issue", "callbackUrl": "http://localhost/haiku"},
+			mockError:          jobs.ErrBadCallback,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid request",
+			requestBody:        haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue", Mood: "grumpy"},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Job could not be scheduled",
+			requestBody:        haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"},
+			mockError:          jobs.ErrScheduleJob,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockJobs := &MockJobService{
+				JobToReturn:   jobs.Job{ID: testJobID, Status: jobs.StatusPending},
+				ErrorToReturn: tc.mockError,
+			}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Jobs: mockJobs})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			requestBody, err := json.Marshal(tc.requestBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/haiku/jobs", bytes.NewBuffer(requestBody))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedStatusCode != http.StatusAccepted {
+				return
+			}
+
+			if mockJobs.LastRequest.CommitMessage != "fix: resolved login issue" || mockJobs.LastOptions.CallbackURL != tc.expectedCallback {
+				t.Errorf("Expected the request and callback %q to be submitted, got %+v and %+v", tc.expectedCallback, mockJobs.LastRequest, mockJobs.LastOptions)
+			}
+			if location := w.Header().Get("Location"); location != "/haiku/jobs/"+testJobID {
+				t.Errorf("Expected location /haiku/jobs/%s, got %q", testJobID, location)
+			}
+			var job jobs.Job
+			if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if job.ID != testJobID || job.Status != jobs.StatusPending {
+				t.Errorf("Expected pending job %s, got %+v", testJobID, job)
+			}
+		})
+	}
+}
+
+func TestGetHaikuJob(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		id                 string
+		mockError          error
+		expectedStatusCode int
+	}{
+		{
+			name:               "Found",
+			id:                 testJobID,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Not found",
+			id:                 "fedcba9876543210fedcba9876543210",
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "Store error",
+			id:                 testJobID,
+			mockError:          errors.Join(jobs.ErrStoreJob, errors.New("table not found")),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockJobs := &MockJobService{
+				JobToReturn: jobs.Job{
+					ID:     testJobID,
+					Status: jobs.StatusSucceeded,
+					Result: &haiku.HaikuCommitResponse{Haiku: "Old cracks mended now"},
+				},
+				ErrorToReturn: tc.mockError,
+			}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Jobs: mockJobs})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/haiku/jobs/"+tc.id, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedStatusCode == http.StatusNotFound && w.Header().Get("Content-Type") != ProblemContentType {
+				t.Errorf("Expected a problem response, got %q", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestJobRoutesDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	NewHaikuAPI(&MockHaikuService{}, nil).SetupRoutes(router)
+
+	req, _ := http.NewRequest("GET", "/haiku/jobs/"+testJobID, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status code %d without a job service, got %d", http.StatusNotFound, w.Code)
+	}
+}
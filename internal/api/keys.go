@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the API key on requests to the haiku endpoints.
+const APIKeyHeader = "X-Api-Key"
+
+// apiKeyContextKey holds the authenticated key in the gin context.
+const apiKeyContextKey = "apiKey"
+
+// requireAPIKey refuses requests without a valid API key granted scope.
+func (api *HaikuAPI) requireAPIKey(scope keys.Scope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := api.authenticate(c, c.GetHeader(APIKeyHeader))
+		if !ok {
+			return
+		}
+		if !key.HasScope(scope) {
+			logging.Warnf("[HAIKU API] key %s lacks the %s scope", key.ID, scope)
+			problem(c, http.StatusForbidden, CodeForbidden, Forbidden, "")
+			return
+		}
+
+		c.Set(apiKeyContextKey, key)
+		c.Request = c.Request.WithContext(keyContext(c.Request.Context(), key))
+		c.Next()
+	}
+}
+
+// requireAdmin refuses requests to the admin API unless they carry the admin
+// token, which creates the first keys, or a key with the admin scope. The
+// admin token, and keys also given the operator scope, act as the operator.
+func (api *HaikuAPI) requireAdmin(c *gin.Context) {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(api.options.AdminToken)) == 1 {
+		c.Set(adminTokenContextKey, true)
+		c.Request = c.Request.WithContext(keys.NewOperatorContext(c.Request.Context()))
+		c.Next()
+		return
+	}
+
+	key, ok := api.authenticate(c, token)
+	if !ok {
+		return
+	}
+	if !key.HasScope(keys.ScopeAdmin) {
+		logging.Warnf("[HAIKU API] key %s lacks the admin scope", key.ID)
+		problem(c, http.StatusForbidden, CodeForbidden, Forbidden, "")
+		return
+	}
+
+	ctx := keyContext(c.Request.Context(), key)
+	if key.HasScope(keys.ScopeOperator) && key.Tenant == "" {
+		ctx = keys.NewOperatorContext(ctx)
+	}
+	c.Set(apiKeyContextKey, key)
+	c.Request = c.Request.WithContext(ctx)
+	c.Next()
+}
+
+// keyContext scopes a request to its key and the key's tenant.
+func keyContext(ctx context.Context, key keys.Key) context.Context {
+	return keys.NewTenantContext(keys.NewContext(ctx, key.ID), key.Tenant)
+}
+
+// authenticate returns the key token belongs to, or aborts the request when
+// there is none.
+func (api *HaikuAPI) authenticate(c *gin.Context, token string) (keys.Key, bool) {
+	if token == "" || api.options.Keys == nil {
+		problem(c, http.StatusUnauthorized, CodeUnauthorized, InvalidAPIKey, "")
+		return keys.Key{}, false
+	}
+
+	key, err := api.options.Keys.Authenticate(c.Request.Context(), token)
+	if errors.Is(err, keys.ErrInvalidKey) {
+		logging.Warnf("[HAIKU API] invalid api key")
+		problem(c, http.StatusUnauthorized, CodeUnauthorized, InvalidAPIKey, "")
+		return keys.Key{}, false
+	}
+	if err != nil {
+		serviceError(c, err)
+		return keys.Key{}, false
+	}
+	return key, true
+}
+
+// postKey creates an API key. Its token is only ever returned here.
+func (api *HaikuAPI) postKey(c *gin.Context) {
+	var request keys.CreateKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	created, err := api.options.Keys.Create(c.Request.Context(), request)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+func (api *HaikuAPI) deleteKey(c *gin.Context) {
+	if err := api.options.Keys.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (api *HaikuAPI) patchKeyQuota(c *gin.Context) {
+	var quota keys.Quota
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	key, err := api.options.Keys.SetQuota(c.Request.Context(), c.Param("id"), quota)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	testAdminToken = "admin-token"
+	testKeyID      = "0123456789abcdef0123456789abcdef"
+)
+
+// MockKeyService knows one token per key, mapped to the key it authenticates.
+type MockKeyService struct {
+	Tokens        map[string]keys.Key
+	ErrorToReturn error
+	LastRequest   keys.CreateKeyRequest
+	LastQuota     keys.Quota
+	LastOperator  bool
+}
+
+func (m *MockKeyService) Create(ctx context.Context, request keys.CreateKeyRequest) (keys.CreatedKey, error) {
+	m.LastRequest = request
+	m.LastOperator = keys.IsOperator(ctx)
+	if m.ErrorToReturn != nil {
+		return keys.CreatedKey{}, m.ErrorToReturn
+	}
+	return keys.CreatedKey{Key: keys.Key{ID: testKeyID, Name: request.Name, Scopes: []keys.Scope{keys.ScopeHaiku}}, Token: testKeyID + ".secret"}, nil
+}
+
+func (m *MockKeyService) Delete(ctx context.Context, id string) error {
+	if id != testKeyID {
+		return keys.ErrKeyNotFound
+	}
+	return m.ErrorToReturn
+}
+
+func (m *MockKeyService) SetQuota(ctx context.Context, id string, quota keys.Quota) (keys.Key, error) {
+	m.LastQuota = quota
+	if id != testKeyID {
+		return keys.Key{}, keys.ErrKeyNotFound
+	}
+	if quota.MonthlyRequests > 5000 {
+		return keys.Key{}, fmt.Errorf("%w: raising quotas", keys.ErrNotOperator)
+	}
+	return keys.Key{ID: id, Quota: quota}, m.ErrorToReturn
+}
+
+func (m *MockKeyService) Authenticate(ctx context.Context, token string) (keys.Key, error) {
+	if m.ErrorToReturn != nil {
+		return keys.Key{}, m.ErrorToReturn
+	}
+	key, ok := m.Tokens[token]
+	if !ok {
+		return keys.Key{}, keys.ErrInvalidKey
+	}
+	return key, nil
+}
+
+func newTestKeyService() *MockKeyService {
+	return &MockKeyService{Tokens: map[string]keys.Key{
+		"haiku-key": {ID: "haiku", Scopes: []keys.Scope{keys.ScopeHaiku}},
+		"admin-key": {ID: "admin", Scopes: []keys.Scope{keys.ScopeAdmin}},
+		"acme-key":  {ID: "acme", Scopes: []keys.Scope{keys.ScopeHaiku, keys.ScopeAdmin}, Tenant: "acme"},
+		"tuner-key": {ID: "tuner", Scopes: []keys.Scope{keys.ScopeHaiku, keys.ScopeSampling}},
+		"ops-key":   {ID: "ops", Scopes: []keys.Scope{keys.ScopeAdmin, keys.ScopeOperator}},
+	}}
+}
+
+func TestAdminAuthorization(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		authorization      string
+		mockError          error
+		expectedStatusCode int
+		expectedOperator   bool
+	}{
+		{name: "Admin token", authorization: "Bearer " + testAdminToken, expectedStatusCode: http.StatusCreated, expectedOperator: true},
+		{name: "Admin key", authorization: "Bearer admin-key", expectedStatusCode: http.StatusCreated},
+		{name: "Operator key", authorization: "Bearer ops-key", expectedStatusCode: http.StatusCreated, expectedOperator: true},
+		{name: "Tenant admin key", authorization: "Bearer acme-key", expectedStatusCode: http.StatusCreated},
+		{name: "Key without the admin scope", authorization: "Bearer haiku-key", expectedStatusCode: http.StatusForbidden},
+		{name: "Unknown key", authorization: "Bearer guess", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Missing token", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Key store fails", authorization: "Bearer admin-key", mockError: keys.ErrStoreKey, expectedStatusCode: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockKeys := newTestKeyService()
+			mockKeys.ErrorToReturn = tc.mockError
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: mockKeys, AdminToken: testAdminToken})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(`{"name":"ci"}`))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if mockKeys.LastOperator != tc.expectedOperator {
+				t.Errorf("Expected operator %v, got %v", tc.expectedOperator, mockKeys.LastOperator)
+			}
+		})
+	}
+}
+
+func TestAdminKeyRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		body               string
+		expectedStatusCode int
+	}{
+		{name: "Create", method: "POST", path: "/admin/keys", body: `{"name":"ci","scopes":["haiku"],"quota":{"monthlyRequests":1000}}`, expectedStatusCode: http.StatusCreated},
+		{name: "Create without a name", method: "POST", path: "/admin/keys", body: `{"scopes":["haiku"]}`, expectedStatusCode: http.StatusBadRequest},
+		{name: "Delete", method: "DELETE", path: "/admin/keys/" + testKeyID, expectedStatusCode: http.StatusNoContent},
+		{name: "Delete unknown key", method: "DELETE", path: "/admin/keys/unknown", expectedStatusCode: http.StatusNotFound},
+		{name: "Set quota", method: "PATCH", path: "/admin/keys/" + testKeyID + "/quota", body: `{"monthlyRequests":500,"monthlyOutputTokens":20000}`, expectedStatusCode: http.StatusOK},
+		{name: "Negative quota", method: "PATCH", path: "/admin/keys/" + testKeyID + "/quota", body: `{"monthlyRequests":-1}`, expectedStatusCode: http.StatusBadRequest},
+		{name: "Quota raise refused", method: "PATCH", path: "/admin/keys/" + testKeyID + "/quota", body: `{"monthlyRequests":1000000}`, expectedStatusCode: http.StatusForbidden},
+		{name: "Quota for unknown key", method: "PATCH", path: "/admin/keys/unknown/quota", body: `{}`, expectedStatusCode: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockKeys := newTestKeyService()
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: mockKeys, AdminToken: testAdminToken})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Authorization", "Bearer "+testAdminToken)
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+
+			switch tc.name {
+			case "Create":
+				var created keys.CreatedKey
+				if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if created.Token == "" || mockKeys.LastRequest.Quota.MonthlyRequests != 1000 {
+					t.Errorf("Expected the key and its token, got %+v for %+v", created, mockKeys.LastRequest)
+				}
+			case "Set quota":
+				if mockKeys.LastQuota != (keys.Quota{MonthlyRequests: 500, MonthlyOutputTokens: 20000}) {
+					t.Errorf("Expected the quota to be set, got %+v", mockKeys.LastQuota)
+				}
+			}
+		})
+	}
+}
+
+func TestAdminRoutesDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: newTestKeyService()})
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("POST", "/admin/keys", bytes.NewBufferString(`{"name":"ci"}`))
+	req.Header.Set("Authorization", "Bearer admin-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected the admin API to be disabled without an admin token, got %d", w.Code)
+	}
+}
+
+func TestRequireAPIKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		keys               KeyService
+		apiKey             string
+		expectedStatusCode int
+	}{
+		{name: "Haiku key", keys: newTestKeyService(), apiKey: "haiku-key", expectedStatusCode: http.StatusOK},
+		{name: "Key without the haiku scope", keys: newTestKeyService(), apiKey: "admin-key", expectedStatusCode: http.StatusForbidden},
+		{name: "Unknown key", keys: newTestKeyService(), apiKey: "guess", expectedStatusCode: http.StatusUnauthorized},
+		{name: "Missing key", keys: newTestKeyService(), expectedStatusCode: http.StatusUnauthorized},
+		{name: "No key service", apiKey: "haiku-key", expectedStatusCode: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"}}
+			api := NewHaikuAPI(mockService, &Options{Keys: tc.keys, RequireAPIKey: true})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", "/haiku", bytes.NewBufferString(`{"commitMessage":"fix: resolved login issue"}`))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// Other routes verify requests their own way.
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: newTestKeyService(), RequireAPIKey: true})
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the OpenAPI spec without a key, got %d", w.Code)
+	}
+}
+
+func TestSamplingScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requireAPIKey      bool
+		apiKey             string
+		requestBody        string
+		expectedStatusCode int
+		expectedTemp       float64
+	}{
+		{
+			name:               "Key with the sampling scope",
+			requireAPIKey:      true,
+			apiKey:             "tuner-key",
+			requestBody:        `{"commitMessage":"fix: resolved login issue","temperature":0.3,"maxTokens":200}`,
+			expectedStatusCode: http.StatusOK,
+			expectedTemp:       0.3,
+		},
+		{
+			name:               "Key without the sampling scope",
+			requireAPIKey:      true,
+			apiKey:             "haiku-key",
+			requestBody:        `{"commitMessage":"fix: resolved login issue","temperature":0.3}`,
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name:               "Key without the sampling scope leaving sampling alone",
+			requireAPIKey:      true,
+			apiKey:             "haiku-key",
+			requestBody:        `{"commitMessage":"fix: resolved login issue"}`,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Keys not required",
+			requestBody:        `{"commitMessage":"fix: resolved login issue","maxTokens":200,"temperature":0.3}`,
+			expectedStatusCode: http.StatusOK,
+			expectedTemp:       0.3,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"}}
+			api := NewHaikuAPI(mockService, &Options{Keys: newTestKeyService(), RequireAPIKey: tc.requireAPIKey})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, _ := http.NewRequest("POST", "/haiku", bytes.NewBufferString(tc.requestBody))
+			req.Header.Set("Content-Type", "application/json")
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedStatusCode == http.StatusOK && mockService.LastRequest.Temperature != tc.expectedTemp {
+				t.Errorf("Expected temperature %g passed to the service, got %g", tc.expectedTemp, mockService.LastRequest.Temperature)
+			}
+		})
+	}
+}
+
+func TestKeysScopeRequestsToTheirTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		header         string
+		token          string
+		admin          bool
+		expectedKeyID  string
+		expectedTenant string
+	}{
+		{name: "Tenant's key", header: APIKeyHeader, token: "acme-key", expectedKeyID: "acme", expectedTenant: "acme"},
+		{name: "Default tenant's key", header: APIKeyHeader, token: "haiku-key", expectedKeyID: "haiku"},
+		{name: "Tenant's admin key", header: "Authorization", token: "Bearer acme-key", admin: true, expectedKeyID: "acme", expectedTenant: "acme"},
+		{name: "Admin token", header: "Authorization", token: "Bearer " + testAdminToken, admin: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: newTestKeyService(), AdminToken: testAdminToken})
+			var keyID, tenant string
+			record := func(c *gin.Context) {
+				keyID = keys.IDFromContext(c.Request.Context())
+				tenant = keys.TenantFromContext(c.Request.Context())
+				c.Status(http.StatusNoContent)
+			}
+
+			router := gin.New()
+			if tc.admin {
+				router.GET("/scoped", api.requireAdmin, record)
+			} else {
+				router.GET("/scoped", api.requireAPIKey(keys.ScopeHaiku), record)
+			}
+
+			req, _ := http.NewRequest("GET", "/scoped", nil)
+			req.Header.Set(tc.header, tc.token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("Expected status code %d, got %d: %s", http.StatusNoContent, w.Code, w.Body.String())
+			}
+			if keyID != tc.expectedKeyID || tenant != tc.expectedTenant {
+				t.Errorf("Expected key %q of tenant %q, got key %q of tenant %q", tc.expectedKeyID, tc.expectedTenant, keyID, tenant)
+			}
+		})
+	}
+}
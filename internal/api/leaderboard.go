@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/gin-gonic/gin"
+)
+
+// getLeaderboard ranks repositories or authors (?by=) by haiku written or
+// their longest daily streak (?rank=) over the last ?days= days.
+func (api *HaikuAPI) getLeaderboard(c *gin.Context) {
+	board := stats.Board(c.DefaultQuery("by", string(stats.BoardRepositories)))
+	if !board.IsValid() {
+		field := invalidValue("by", stats.Boards)
+		invalidRequest(c, field.Detail, field)
+		return
+	}
+
+	ranking := stats.Ranking(c.DefaultQuery("rank", string(stats.RankingHaiku)))
+	if !ranking.IsValid() {
+		field := invalidValue("rank", stats.Rankings)
+		invalidRequest(c, field.Detail, field)
+		return
+	}
+
+	days, ok := queryInt(c, "days", stats.DefaultDays, 1, stats.MaxDays)
+	if !ok {
+		return
+	}
+	limit, ok := queryInt(c, "limit", stats.DefaultLeaderboardSize, 1, stats.MaxLeaderboardSize)
+	if !ok {
+		return
+	}
+
+	leaderboard, err := api.options.Leaderboard.Leaderboard(c.Request.Context(), board, ranking, days, limit)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, leaderboard)
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/gin-gonic/gin"
+)
+
+type MockLeaderboardService struct {
+	LeaderboardToReturn stats.Leaderboard
+	ErrorToReturn       error
+	LastBoard           stats.Board
+	LastRanking         stats.Ranking
+	LastDays            int
+	LastLimit           int
+}
+
+func (m *MockLeaderboardService) Leaderboard(ctx context.Context, board stats.Board, ranking stats.Ranking, days int, limit int) (stats.Leaderboard, error) {
+	m.LastBoard = board
+	m.LastRanking = ranking
+	m.LastDays = days
+	m.LastLimit = limit
+	return m.LeaderboardToReturn, m.ErrorToReturn
+}
+
+func TestGetLeaderboard(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		query              string
+		mockError          error
+		expectedStatusCode int
+		expectedCode       string
+		expectedBoard      stats.Board
+		expectedRanking    stats.Ranking
+		expectedDays       int
+		expectedLimit      int
+	}{
+		{
+			name:               "Defaults",
+			expectedStatusCode: http.StatusOK,
+			expectedBoard:      stats.BoardRepositories,
+			expectedRanking:    stats.RankingHaiku,
+			expectedDays:       stats.DefaultDays,
+			expectedLimit:      stats.DefaultLeaderboardSize,
+		},
+		{
+			name:               "Authors by streak",
+			query:              "?by=authors&rank=streak&days=30&limit=3",
+			expectedStatusCode: http.StatusOK,
+			expectedBoard:      stats.BoardAuthors,
+			expectedRanking:    stats.RankingStreak,
+			expectedDays:       30,
+			expectedLimit:      3,
+		},
+		{
+			name:               "Unknown board",
+			query:              "?by=teams",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Unknown ranking",
+			query:              "?rank=likes",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Too many days",
+			query:              "?days=365",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Limit too large",
+			query:              "?limit=1000",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Store fails",
+			mockError:          errors.Join(stats.ErrGetStats, errors.New("table not found")),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       CodeInternalError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockLeaderboard := &MockLeaderboardService{
+				LeaderboardToReturn: stats.Leaderboard{Entries: []stats.Entry{{Rank: 1, Name: "octo/leaves", Haiku: 4, Streak: 2}}},
+				ErrorToReturn:       tc.mockError,
+			}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Leaderboard: mockLeaderboard})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/leaderboard"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+				return
+			}
+
+			if mockLeaderboard.LastBoard != tc.expectedBoard || mockLeaderboard.LastRanking != tc.expectedRanking {
+				t.Errorf("Expected %s by %s, got %s by %s", tc.expectedBoard, tc.expectedRanking, mockLeaderboard.LastBoard, mockLeaderboard.LastRanking)
+			}
+			if mockLeaderboard.LastDays != tc.expectedDays || mockLeaderboard.LastLimit != tc.expectedLimit {
+				t.Errorf("Expected %d days and %d entries, got %d and %d", tc.expectedDays, tc.expectedLimit, mockLeaderboard.LastDays, mockLeaderboard.LastLimit)
+			}
+			var leaderboard stats.Leaderboard
+			if err := json.Unmarshal(w.Body.Bytes(), &leaderboard); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(leaderboard.Entries) != 1 || leaderboard.Entries[0].Name != "octo/leaves" {
+				t.Errorf("Expected octo/leaves on the leaderboard, got %+v", leaderboard.Entries)
+			}
+		})
+	}
+}
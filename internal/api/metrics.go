@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorMetrics counts failed requests by error class, e.g. as CloudWatch
+// metrics.
+type ErrorMetrics interface {
+	CountError(class metrics.Class, route string) error
+}
+
+// countErrors counts each failed request under the class of its service
+// error. Requests refused before reaching a service are counted by status: a
+// malformed request as validation, and a recovered panic as internal.
+func (api *HaikuAPI) countErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		class, ok := requestErrorClass(c)
+		if !ok {
+			return
+		}
+		if err := api.options.Metrics.CountError(class, c.FullPath()); err != nil {
+			logging.Errorf("[HAIKU API] error counting %s error: %v", class, err)
+		}
+	}
+}
+
+func requestErrorClass(c *gin.Context) (metrics.Class, bool) {
+	if err := c.Errors.Last(); err != nil {
+		return errorClass(err.Err)
+	}
+
+	switch status := c.Writer.Status(); {
+	case status == http.StatusBadRequest:
+		return metrics.ClassValidation, true
+	case status >= http.StatusInternalServerError:
+		return metrics.ClassInternal, true
+	}
+	return "", false
+}
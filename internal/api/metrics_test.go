@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metrics"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+type MockErrorMetrics struct {
+	Counted []metrics.Class
+	Routes  []string
+}
+
+func (m *MockErrorMetrics) CountError(class metrics.Class, route string) error {
+	m.Counted = append(m.Counted, class)
+	m.Routes = append(m.Routes, route)
+	return nil
+}
+
+func TestCountErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		body           string
+		serviceError   error
+		expectedStatus int
+		expectedClass  metrics.Class
+	}{
+		{
+			name:           "Throttled",
+			serviceError:   fmt.Errorf("%w: ThrottlingException", bedrock.ErrThrottling),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedClass:  metrics.ClassThrottling,
+		},
+		{
+			name:           "Bedrock quota",
+			serviceError:   fmt.Errorf("%w: ServiceQuotaExceededException", bedrock.ErrQuotaExceeded),
+			expectedStatus: http.StatusTooManyRequests,
+			expectedClass:  metrics.ClassQuota,
+		},
+		{
+			name:           "Response parsing",
+			serviceError:   fmt.Errorf("%w: %w: unexpected end of JSON input", haiku.ErrCreateHaiku, bedrock.ErrResponseParsing),
+			expectedStatus: http.StatusInternalServerError,
+			expectedClass:  metrics.ClassParse,
+		},
+		{
+			name:           "Guardrail block",
+			serviceError:   fmt.Errorf("%w: guardrail", haiku.ErrContentBlocked),
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedClass:  metrics.ClassGuardrail,
+		},
+		{
+			name:           "Unmapped error",
+			serviceError:   fmt.Errorf("%w: connection reset", haiku.ErrCreateHaiku),
+			expectedStatus: http.StatusInternalServerError,
+			expectedClass:  metrics.ClassInternal,
+		},
+		{
+			name:           "Malformed request",
+			body:           `{"commitMessage": 42}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedClass:  metrics.ClassValidation,
+		},
+		{
+			name:           "Client mistake not counted",
+			serviceError:   votes.ErrHaikuNotFound,
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Success not counted",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			counter := &MockErrorMetrics{}
+			api := NewHaikuAPI(&MockHaikuService{ErrorToReturn: tc.serviceError}, &Options{Metrics: counter})
+
+			router := gin.New()
+			api.SetupMiddleware(router)
+			api.SetupRoutes(router)
+
+			body := tc.body
+			if body == "" {
+				body = `{"commitMessage": "fix: count errors by class"}`
+			}
+			req, err := http.NewRequest("POST", "/haiku", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, w.Code)
+			}
+			if tc.expectedClass == "" {
+				if len(counter.Counted) != 0 {
+					t.Errorf("Expected no errors counted, got %v", counter.Counted)
+				}
+				return
+			}
+			if len(counter.Counted) != 1 || counter.Counted[0] != tc.expectedClass || counter.Routes[0] != "/haiku" {
+				t.Errorf("Expected one %s error on /haiku, got %v on %v", tc.expectedClass, counter.Counted, counter.Routes)
+			}
+		})
+	}
+}
+
+func TestCountErrorsPanic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	counter := &MockErrorMetrics{}
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Metrics: counter})
+
+	router := gin.New()
+	api.SetupMiddleware(router)
+	router.GET("/panic", func(c *gin.Context) { panic("index out of range") })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/panic", nil))
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d, got %d", http.StatusInternalServerError, w.Code)
+	}
+	if len(counter.Counted) != 1 || counter.Counted[0] != metrics.ClassInternal {
+		t.Errorf("Expected one internal error, got %v", counter.Counted)
+	}
+}
@@ -0,0 +1,572 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/openapi"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/daily"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/erasure"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/renga"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/styles"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPIDocument describes the haiku endpoints. Schemas are derived from the
+// request and response types, so the spec follows them as they change.
+var OpenAPIDocument = sync.OnceValue(func() openapi.Document {
+	b := openapi.NewBuilder(openapi.Info{
+		Title:       "Commits Fall Like Leaves",
+		Description: "Turns commit messages, release notes and changelogs into haiku.",
+		Version:     "1.0.0",
+	})
+	openapi.Enum(b, append(haiku.Moods, haiku.MoodAuto)...)
+	openapi.Enum(b, haiku.Registers...)
+	openapi.Enum(b, haiku.PairingStyles...)
+	openapi.Enum(b, jobs.Statuses...)
+	openapi.Enum(b, keys.Scopes...)
+	openapi.Enum(b, stats.Boards...)
+	openapi.Enum(b, stats.Rankings...)
+	openapi.Enum(b, ExportFormats...)
+
+	badRequest := openapi.Response{Description: InvalidRequest, Content: b.Content(ProblemContentType, Problem{})}
+	serverError := openapi.Response{Description: InternalServerError, Content: b.Content(ProblemContentType, Problem{})}
+	throttled := openapi.Response{Description: "The model is throttled or out of quota, or the API key's monthly quota is used up; retry later", Content: b.Content(ProblemContentType, Problem{})}
+	unavailable := openapi.Response{Description: ModelUnavailable, Content: b.Content(ProblemContentType, Problem{})}
+	timingsParameter := openapi.Parameter{
+		Name:        "timings",
+		In:          "query",
+		Description: "Set to true to also return the milliseconds spent in each stage of the request",
+		Schema:      &openapi.Schema{Type: "boolean"},
+	}
+
+	b.Operation(http.MethodPost, "/haiku", openapi.Operation{
+		Summary:     "Write a haiku about a commit message",
+		OperationID: "createHaiku",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "svg",
+				In:          "query",
+				Description: "Set to true to also return the haiku rendered as an SVG card",
+				Schema:      &openapi.Schema{Type: "boolean"},
+			},
+			timingsParameter,
+		},
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(haiku.HaikuCommitRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The haiku", Content: b.JSON(haiku.HaikuCommitResponse{})},
+			"400": badRequest,
+			"422": {Description: "The haiku was blocked by the content filter, or was not 5-7-5 in strict mode", Content: b.Content(ProblemContentType, Problem{})},
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/health", openapi.Operation{
+		Summary:     "Report whether the service is serving, and the state of its retry budget",
+		OperationID: "getHealth",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The service's health", Content: b.JSON(HealthResponse{})},
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/release-notes", openapi.Operation{
+		Summary:     "Write a haiku for each theme in release notes",
+		OperationID: "createReleaseNotesHaiku",
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(haiku.ReleaseNotesRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "A haiku per theme", Content: b.JSON(haiku.ReleaseNotesResponse{})},
+			"400": badRequest,
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/changelog", openapi.Operation{
+		Summary:     "Write a haiku for each category of a changelog release",
+		OperationID: "createChangelogHaiku",
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(haiku.ChangelogRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "A haiku per category", Content: b.JSON(haiku.ChangelogResponse{})},
+			"400": badRequest,
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/pulse", openapi.Operation{
+		Summary:     "Weave commits from across repositories into one poem of linked verses",
+		OperationID: "createPulseHaiku",
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(haiku.PulseRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "A verse per repository, linked into one poem", Content: b.JSON(haiku.PulseResponse{})},
+			"400": badRequest,
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/jobs", openapi.Operation{
+		Summary:     "Write a haiku about a commit message in the background",
+		OperationID: "createHaikuJob",
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(HaikuJobRequest{})},
+		Responses: map[string]openapi.Response{
+			"202": {Description: "The pending job; poll the Location header for its result", Content: b.JSON(jobs.Job{})},
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/haiku/jobs/{id}", openapi.Operation{
+		Summary:     "Get a background haiku job and, once finished, its result",
+		OperationID: "getHaikuJob",
+		Parameters: []openapi.Parameter{{
+			Name:        "id",
+			In:          "path",
+			Description: "The job ID returned when it was submitted",
+			Required:    true,
+			Schema:      &openapi.Schema{Type: "string"},
+		}},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The job", Content: b.JSON(jobs.Job{})},
+			"404": {Description: "No such job, or it has expired", Content: b.Content(ProblemContentType, Problem{})},
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/haiku/daily", openapi.Operation{
+		Summary:     "Get the haiku of the day, the same for every caller until midnight UTC",
+		OperationID: "getDailyHaiku",
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The haiku of the day", Content: b.JSON(daily.Daily{})},
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/renga", openapi.Operation{
+		Summary:     "Add a commit's verse to its repository's renga, written to follow the verse before it",
+		OperationID: "continueRenga",
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(renga.ContinueRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The verse, and its place in the renga", Content: b.JSON(renga.Verse{})},
+			"400": badRequest,
+			"409": {Description: RengaBusy, Content: b.Content(ProblemContentType, Problem{})},
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/haiku/renga", openapi.Operation{
+		Summary:     "Get the latest verses of a repository's renga",
+		OperationID: "getRenga",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "repository",
+				In:          "query",
+				Description: "Repository identifier, e.g. octo/leaves",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:        "limit",
+				In:          "query",
+				Description: fmt.Sprintf("Latest verses to return, from 1 to %d (default: %d)", renga.MaxLimit, renga.DefaultLimit),
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The renga's length and its latest verses, oldest first", Content: b.JSON(renga.Renga{})},
+			"400": badRequest,
+			"404": {Description: "The repository has no renga", Content: b.Content(ProblemContentType, Problem{})},
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/{id}/regenerate", openapi.Operation{
+		Summary:     "Write a new haiku for the commit of a kept haiku, avoiding the drafts already written for it",
+		OperationID: "regenerateHaiku",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "id",
+				In:          "path",
+				Description: "The ID of the haiku to replace",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:        "svg",
+				In:          "query",
+				Description: "Set to true to also return the haiku rendered as an SVG card",
+				Schema:      &openapi.Schema{Type: "boolean"},
+			},
+			timingsParameter,
+		},
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(haiku.HaikuCommitRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The new haiku, under its own ID", Content: b.JSON(haiku.HaikuCommitResponse{})},
+			"400": badRequest,
+			"404": {Description: "No such haiku served to the caller, or it has expired", Content: b.Content(ProblemContentType, Problem{})},
+			"422": {Description: "The haiku was blocked by the content filter, or was not 5-7-5 in strict mode", Content: b.Content(ProblemContentType, Problem{})},
+			"429": throttled,
+			"500": serverError,
+			"503": unavailable,
+		},
+	})
+
+	b.Operation(http.MethodPost, "/haiku/{id}/vote", openapi.Operation{
+		Summary:     "Upvote a haiku, once per voter",
+		OperationID: "voteHaiku",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "id",
+				In:          "path",
+				Description: "The haiku ID returned with it",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:        VoterHeader,
+				In:          "header",
+				Description: "The user voting; required without an API key, and tells apart the users of one key",
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The haiku and its votes", Content: b.JSON(votes.Haiku{})},
+			"400": badRequest,
+			"404": {Description: "No such haiku, it has expired, or moderation unpublished it", Content: b.Content(ProblemContentType, Problem{})},
+			"409": {Description: "Already voted for this haiku, or it is awaiting moderation", Content: b.Content(ProblemContentType, Problem{})},
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/haiku/top", openapi.Operation{
+		Summary:     "List the most voted haiku of the last days",
+		OperationID: "getTopHaiku",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "days",
+				In:          "query",
+				Description: fmt.Sprintf("Days whose votes count, up to and including today, from 1 to %d (default: %d)", votes.MaxDays, votes.DefaultDays),
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+			{
+				Name:        "limit",
+				In:          "query",
+				Description: fmt.Sprintf("Haiku to return, from 1 to %d (default: %d)", votes.MaxTopSize, votes.DefaultTopSize),
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The most voted haiku, with the votes cast on the days covered", Content: b.JSON(votes.Top{})},
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/stats", openapi.Operation{
+		Summary:     "Get usage statistics for the last days",
+		OperationID: "getStats",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "Authorization",
+				In:          "header",
+				Description: "Bearer followed by the stats token",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:        "days",
+				In:          "query",
+				Description: fmt.Sprintf("Days to summarize, up to and including today, from 1 to %d (default: %d)", stats.MaxDays, stats.DefaultDays),
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+			{
+				Name:        "tenant",
+				In:          "query",
+				Description: "Tenant to summarize (default: the default tenant)",
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Counts per day, mood and repository, model latency and token totals", Content: b.JSON(stats.Stats{})},
+			"400": badRequest,
+			"401": {Description: Unauthorized, Content: b.Content(ProblemContentType, Problem{})},
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/leaderboard", openapi.Operation{
+		Summary:     "Rank repositories or authors by haiku written, daily streak or votes",
+		OperationID: "getLeaderboard",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        "by",
+				In:          "query",
+				Description: "What to rank (default: repositories)",
+				Schema:      b.Schema(stats.BoardRepositories),
+			},
+			{
+				Name:        "rank",
+				In:          "query",
+				Description: "Rank by haiku written, by the longest run of consecutive days with a haiku, or by votes for their haiku (default: haiku)",
+				Schema:      b.Schema(stats.RankingHaiku),
+			},
+			{
+				Name:        "days",
+				In:          "query",
+				Description: fmt.Sprintf("Days to rank, up to and including today, from 1 to %d (default: %d)", stats.MaxDays, stats.DefaultDays),
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+			{
+				Name:        "limit",
+				In:          "query",
+				Description: fmt.Sprintf("Entries to return, from 1 to %d (default: %d)", stats.MaxLeaderboardSize, stats.DefaultLeaderboardSize),
+				Schema:      &openapi.Schema{Type: "integer"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The leaderboard, highest ranked first", Content: b.JSON(stats.Leaderboard{})},
+			"400": badRequest,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/export", openapi.Operation{
+		Summary:     "Download every haiku kept for an API key",
+		OperationID: "exportHaiku",
+		Parameters: []openapi.Parameter{
+			{
+				Name:        APIKeyHeader,
+				In:          "header",
+				Description: "The API key whose haiku to export",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+			{
+				Name:        "format",
+				In:          "query",
+				Description: "JSON Lines, one haiku per line, or CSV with a header row (default: jsonl)",
+				Schema:      b.Schema(ExportJSONL),
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The key's haiku, oldest first", Content: map[string]openapi.MediaType{
+				"application/x-ndjson": {Schema: b.Schema(votes.Saved{})},
+				"text/csv":             {Schema: &openapi.Schema{Type: "string"}},
+			}},
+			"400": badRequest,
+			"401": {Description: InvalidAPIKey, Content: b.Content(ProblemContentType, Problem{})},
+			"403": {Description: Forbidden, Content: b.Content(ProblemContentType, Problem{})},
+			"500": serverError,
+		},
+	})
+
+	adminToken := openapi.Parameter{
+		Name:        "Authorization",
+		In:          "header",
+		Description: "Bearer followed by the admin token or an admin key",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	keyID := openapi.Parameter{
+		Name:        "id",
+		In:          "path",
+		Description: "The key ID",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+	unauthorized := openapi.Response{Description: InvalidAPIKey, Content: b.Content(ProblemContentType, Problem{})}
+	forbidden := openapi.Response{Description: Forbidden, Content: b.Content(ProblemContentType, Problem{})}
+	keyNotFound := openapi.Response{Description: "No such key", Content: b.Content(ProblemContentType, Problem{})}
+
+	b.Operation(http.MethodPost, "/admin/keys", openapi.Operation{
+		Summary:     "Create an API key",
+		OperationID: "createKey",
+		Parameters:  []openapi.Parameter{adminToken},
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(keys.CreateKeyRequest{})},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The key and its token, which is not shown again", Content: b.JSON(keys.CreatedKey{})},
+			"400": badRequest,
+			"401": unauthorized,
+			"403": forbidden,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodDelete, "/admin/keys/{id}", openapi.Operation{
+		Summary:     "Revoke an API key",
+		OperationID: "deleteKey",
+		Parameters:  []openapi.Parameter{adminToken, keyID},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The key was revoked"},
+			"401": unauthorized,
+			"403": forbidden,
+			"404": keyNotFound,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodPatch, "/admin/keys/{id}/quota", openapi.Operation{
+		Summary:     "Replace an API key's monthly quota",
+		OperationID: "setKeyQuota",
+		Parameters:  []openapi.Parameter{adminToken, keyID},
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(keys.Quota{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The key with its new quota", Content: b.JSON(keys.Key{})},
+			"400": badRequest,
+			"401": unauthorized,
+			"403": forbidden,
+			"404": keyNotFound,
+			"500": serverError,
+		},
+	})
+
+	styleNotFound := openapi.Response{Description: "The tenant has no style guide", Content: b.Content(ProblemContentType, Problem{})}
+
+	b.Operation(http.MethodGet, "/admin/style", openapi.Operation{
+		Summary:     "Get the style guide of the caller's tenant",
+		OperationID: "getStyle",
+		Parameters:  []openapi.Parameter{adminToken},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The style guide", Content: b.JSON(styles.Style{})},
+			"401": unauthorized,
+			"403": forbidden,
+			"404": styleNotFound,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodPut, "/admin/style", openapi.Operation{
+		Summary:     "Replace the style guide of the caller's tenant",
+		OperationID: "setStyle",
+		Parameters:  []openapi.Parameter{adminToken},
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(styles.SetStyleRequest{})},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The new style guide, merged into the prompt of the tenant's commit haiku", Content: b.JSON(styles.Style{})},
+			"400": {Description: fmt.Sprintf("The guide is empty, longer than %d characters, or holds instructions, prompt tags or control characters", styles.MaxGuideLength), Content: b.Content(ProblemContentType, Problem{})},
+			"401": unauthorized,
+			"403": forbidden,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodDelete, "/admin/style", openapi.Operation{
+		Summary:     "Remove the style guide of the caller's tenant",
+		OperationID: "deleteStyle",
+		Parameters:  []openapi.Parameter{adminToken},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The style guide was removed"},
+			"401": unauthorized,
+			"403": forbidden,
+			"404": styleNotFound,
+			"500": serverError,
+		},
+	})
+
+	subscriptionNotFound := openapi.Response{Description: "The tenant has no such subscription", Content: b.Content(ProblemContentType, Problem{})}
+	subscriptionID := openapi.Parameter{
+		Name:        "id",
+		In:          "path",
+		Description: "The subscription's ID",
+		Required:    true,
+		Schema:      &openapi.Schema{Type: "string"},
+	}
+
+	b.Operation(http.MethodPost, "/admin/digests", openapi.Operation{
+		Summary:     "Subscribe an address to the weekly digest of the caller's tenant's haiku, or of one repository's",
+		OperationID: "subscribeDigest",
+		Parameters:  []openapi.Parameter{adminToken},
+		RequestBody: &openapi.RequestBody{Required: true, Content: b.JSON(digest.SubscribeRequest{})},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "The subscription", Content: b.JSON(digest.Subscription{})},
+			"400": {Description: fmt.Sprintf("The email isn't a plain address, or the tenant already has %d subscriptions", digest.MaxSubscriptions), Content: b.Content(ProblemContentType, Problem{})},
+			"401": unauthorized,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/admin/digests", openapi.Operation{
+		Summary:     "List the digest subscriptions of the caller's tenant",
+		OperationID: "listDigests",
+		Parameters:  []openapi.Parameter{adminToken},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The subscriptions, oldest first", Content: b.JSON(digest.Subscriptions{})},
+			"401": unauthorized,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodDelete, "/admin/digests/{id}", openapi.Operation{
+		Summary:     "Remove a digest subscription of the caller's tenant",
+		OperationID: "deleteDigest",
+		Parameters:  []openapi.Parameter{adminToken, subscriptionID},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "The subscription was removed"},
+			"401": unauthorized,
+			"404": subscriptionNotFound,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodGet, "/digests/{id}/unsubscribe", openapi.Operation{
+		Summary:     "Unsubscribe from the weekly digest, as linked to from each digest",
+		OperationID: "unsubscribeDigest",
+		Parameters: []openapi.Parameter{
+			subscriptionID,
+			{
+				Name:        "token",
+				In:          "query",
+				Description: "The token the digest's link carries",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The subscription was removed", Content: b.Content("text/plain", "")},
+			"404": subscriptionNotFound,
+			"500": serverError,
+		},
+	})
+
+	b.Operation(http.MethodDelete, "/authors/{id}/haiku", openapi.Operation{
+		Summary:     "Erase every haiku kept for a commit author",
+		OperationID: "eraseAuthorHaiku",
+		Parameters: []openapi.Parameter{
+			adminToken,
+			{
+				Name:        "id",
+				In:          "path",
+				Description: "The author's name, exactly as requests gave it",
+				Required:    true,
+				Schema:      &openapi.Schema{Type: "string"},
+			},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The audit record of the erasure", Content: b.JSON(erasure.Erasure{})},
+			"401": unauthorized,
+			"403": forbidden,
+			"500": serverError,
+		},
+	})
+
+	return b.Document()
+})
+
+func (api *HaikuAPI) getOpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, OpenAPIDocument())
+}
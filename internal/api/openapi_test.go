@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/openapi"
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetOpenAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	api := NewHaikuAPI(&MockHaikuService{}, nil)
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, err := http.NewRequest("GET", "/openapi.json", nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, w.Code)
+	}
+
+	var document openapi.Document
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+
+	for _, path := range []string{"/haiku", "/haiku/release-notes", "/haiku/changelog", "/haiku/pulse"} {
+		if _, ok := document.Paths[path]["post"]; !ok {
+			t.Errorf("Expected an operation for POST %s", path)
+		}
+	}
+
+	schemas := document.Components.Schemas
+	request, ok := schemas["HaikuCommitRequest"]
+	if !ok {
+		t.Fatalf("Expected a HaikuCommitRequest schema, got %v", schemas)
+	}
+	if !slices.Equal(request.Required, []string{"commitMessage"}) {
+		t.Errorf("Expected only commitMessage to be required, got %v", request.Required)
+	}
+	if ref := request.Properties["mood"].Ref; ref != "#/components/schemas/Mood" {
+		t.Errorf("Expected mood to refer to the Mood schema, got %q", ref)
+	}
+	if mood := schemas["Mood"]; mood == nil || !slices.Equal(mood.Enum, []string{"humorous", "reflective", "technical", "auto"}) {
+		t.Errorf("Expected the Mood schema to list every mood, got %+v", mood)
+	}
+	if _, ok := document.Paths["/haiku"]["post"].Responses["400"].Content[ProblemContentType]; !ok {
+		t.Errorf("Expected errors to be described as %s", ProblemContentType)
+	}
+	if _, ok := schemas["FieldError"]; !ok {
+		t.Errorf("Expected a FieldError schema")
+	}
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ProblemContentType is the media type of error responses (RFC 7807).
+const ProblemContentType = "application/problem+json"
+
+// ProblemTypeBase prefixes each problem code to form its type URI.
+const ProblemTypeBase = "https://github.com/brianherrera/commits-fall-like-leaves#"
+
+// Problem codes identify what went wrong, independent of the title text.
+const (
+	CodeInvalidRequest   = "invalid_request"
+	CodeContentBlocked   = "content_blocked"
+	CodeInvalidHaiku     = "invalid_haiku"
+	CodeInvalidSignature = "invalid_signature"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeAlreadyVoted     = "already_voted"
+	CodeNotPublished     = "not_published"
+	CodeRengaBusy        = "renga_busy"
+	CodeThrottled        = "throttled"
+	CodeQuotaExceeded    = "quota_exceeded"
+	CodeKeyQuotaExceeded = "key_quota_exceeded"
+	CodeModelUnavailable = "model_unavailable"
+	CodeInternalError    = "internal_error"
+)
+
+// Field error codes identify what is wrong with a single request field.
+const (
+	FieldRequired     = "required"
+	FieldInvalidType  = "invalid_type"
+	FieldInvalidValue = "invalid_value"
+	FieldTooLong      = "too_long"
+)
+
+// Problem is the body of every error response, an RFC 7807 problem details
+// object extended with a machine-readable code and per-field errors.
+type Problem struct {
+	Type   string       `json:"type"`
+	Title  string       `json:"title"`
+	Status int          `json:"status"`
+	Detail string       `json:"detail,omitempty"`
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError describes a request field that failed validation. Field is the
+// field's JSON name, e.g. "commitMessage", or its path when nested, e.g.
+// "commits[0].message".
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Detail string `json:"detail"`
+}
+
+// registerFieldNames makes binding validation errors name fields by their
+// JSON names, which are the names clients send.
+var registerFieldNames = sync.OnceFunc(func() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+})
+
+func newProblem(status int, code string, title string, detail string, fields ...FieldError) Problem {
+	return Problem{
+		Type:   ProblemTypeBase + code,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+		Code:   code,
+		Errors: fields,
+	}
+}
+
+// problem aborts the request with a problem response.
+func problem(c *gin.Context, status int, code string, title string, detail string, fields ...FieldError) {
+	abortWithProblem(c, newProblem(status, code, title, detail, fields...))
+}
+
+func abortWithProblem(c *gin.Context, p Problem) {
+	c.Header("Content-Type", ProblemContentType)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// invalidRequest aborts the request with a 400 problem listing the fields
+// that failed validation, if any.
+func invalidRequest(c *gin.Context, detail string, fields ...FieldError) {
+	problem(c, http.StatusBadRequest, CodeInvalidRequest, InvalidRequest, detail, fields...)
+}
+
+// bindingError aborts the request with a 400 problem describing why the body
+// could not be bound, naming the offending fields where gin reports them.
+func bindingError(c *gin.Context, err error) {
+	invalidRequest(c, err.Error(), bindingFieldErrors(err)...)
+}
+
+func bindingFieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fieldError := range validationErrors {
+			// Namespaces start with the request type, e.g.
+			// "PulseRequest.commits[0].message".
+			_, field, _ := strings.Cut(fieldError.Namespace(), ".")
+			fields = append(fields, validationFieldError(field, fieldError))
+		}
+		return fields
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return []FieldError{{
+			Field:  typeError.Field,
+			Code:   FieldInvalidType,
+			Detail: typeError.Field + " must be a " + typeError.Type.Kind().String(),
+		}}
+	}
+
+	return nil
+}
+
+// validationFieldError describes a failed binding tag on field.
+func validationFieldError(field string, fieldError validator.FieldError) FieldError {
+	switch fieldError.Tag() {
+	case "required":
+		return FieldError{Field: field, Code: FieldRequired, Detail: field + " is required"}
+	case "max":
+		unit := "characters"
+		if fieldError.Kind() == reflect.Slice {
+			unit = "entries"
+		}
+		return FieldError{
+			Field:  field,
+			Code:   FieldTooLong,
+			Detail: fmt.Sprintf("%s exceeds %s %s", field, fieldError.Param(), unit),
+		}
+	}
+	return FieldError{Field: field, Code: FieldInvalidValue, Detail: fieldError.Error()}
+}
+
+// tooLong describes a field longer than max characters.
+func tooLong(field string, max int) FieldError {
+	return FieldError{
+		Field:  field,
+		Code:   FieldTooLong,
+		Detail: fmt.Sprintf("%s exceeds %d characters", field, max),
+	}
+}
+
+// outOfRange describes a number outside min to max.
+func outOfRange[T int | float64](field string, min T, max T) FieldError {
+	return FieldError{
+		Field:  field,
+		Code:   FieldInvalidValue,
+		Detail: fmt.Sprintf("%s must be from %v to %v", field, min, max),
+	}
+}
+
+// queryInt returns the integer query parameter name, or fallback when it is
+// absent. A value that is not a number from min to max aborts the request.
+func queryInt(c *gin.Context, name string, fallback int, min int, max int) (int, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, true
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < min || parsed > max {
+		detail := fmt.Sprintf("%s must be a number from %d to %d", name, min, max)
+		invalidRequest(c, detail, FieldError{Field: name, Code: FieldInvalidValue, Detail: detail})
+		return 0, false
+	}
+	return parsed, true
+}
+
+// invalidValue describes a field holding none of its allowed values.
+func invalidValue[T ~string](field string, allowed []T) FieldError {
+	values := make([]string, len(allowed))
+	for i, value := range allowed {
+		values[i] = string(value)
+	}
+	return FieldError{
+		Field:  field,
+		Code:   FieldInvalidValue,
+		Detail: fmt.Sprintf("%s must be one of %s", field, strings.Join(values, ", ")),
+	}
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostHaikuProblem(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		expectedFields []FieldError
+	}{
+		{
+			name:        "Missing commit message",
+			requestBody: `{"mood":"reflective"}`,
+			expectedFields: []FieldError{
+				{Field: "commitMessage", Code: FieldRequired},
+			},
+		},
+		{
+			name:        "Invalid mood and register",
+			requestBody: `{"commitMessage":"fix: resolved login issue","mood":"grumpy","register":"shouty"}`,
+			expectedFields: []FieldError{
+				{Field: "mood", Code: FieldInvalidValue},
+				{Field: "register", Code: FieldInvalidValue},
+			},
+		},
+		{
+			name:        "Wrong type",
+			requestBody: `{"commitMessage":"fix: resolved login issue","strict":"yes"}`,
+			expectedFields: []FieldError{
+				{Field: "strict", Code: FieldInvalidType},
+			},
+		},
+		{
+			name:        "Commit message too long",
+			requestBody: `{"commitMessage":"` + strings.Repeat("a", MaxCommitLength+1) + `"}`,
+			expectedFields: []FieldError{
+				{Field: "commitMessage", Code: FieldTooLong},
+			},
+		},
+		{
+			name:        "Malformed JSON",
+			requestBody: `{"commitMessage":`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{}
+			api := NewHaikuAPI(mockService, &Options{LengthStrategy: LengthStrategyReject})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", "/haiku", bytes.NewBufferString(tc.requestBody))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, w.Code)
+			}
+			if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, ProblemContentType) {
+				t.Errorf("Expected content type %q, got %q", ProblemContentType, contentType)
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if problem.Code != CodeInvalidRequest || problem.Status != http.StatusBadRequest || problem.Title != InvalidRequest {
+				t.Errorf("Expected an invalid request problem, got %+v", problem)
+			}
+			if problem.Type != ProblemTypeBase+CodeInvalidRequest {
+				t.Errorf("Expected type %q, got %q", ProblemTypeBase+CodeInvalidRequest, problem.Type)
+			}
+
+			var fields []FieldError
+			for _, field := range problem.Errors {
+				if field.Detail == "" {
+					t.Errorf("Expected a detail for field %q", field.Field)
+				}
+				fields = append(fields, FieldError{Field: field.Field, Code: field.Code})
+			}
+			if !reflect.DeepEqual(fields, tc.expectedFields) {
+				t.Errorf("Expected field errors %+v, got %+v", tc.expectedFields, fields)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func (api *HaikuAPI) postPulseHaiku(c *gin.Context) {
+	var request haiku.PulseRequest
+
+	// Validate request format
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logging.Errorf("[HAIKU API] error binding pulse request: %v", err)
+		bindingError(c, err)
+		return
+	}
+
+	if request.Mood != "" && !request.Mood.IsValid() {
+		logging.Warnf("[HAIKU API] invalid mood: %s", request.Mood)
+		invalidRequest(c, "", invalidValue("mood", haiku.Moods))
+		return
+	}
+
+	response, err := api.haikuService.CreatePulseHaiku(c.Request.Context(), request)
+
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostPulseHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	commits := []haiku.PulseCommit{
+		{Repository: "octo-org/api", Message: "fix: resolved login issue"},
+		{Repository: "octo-org/web", Message: "feat: new login page"},
+	}
+
+	tests := []struct {
+		name               string
+		requestBody        any
+		mockResponse       haiku.PulseResponse
+		mockError          error
+		expectedStatusCode int
+		expectedField      string
+	}{
+		{
+			name:        "Successful request",
+			requestBody: haiku.PulseRequest{Commits: commits},
+			mockResponse: haiku.PulseResponse{
+				Poem: "Old cracks mended now\n\nA new page unfolds",
+				Verses: []haiku.RepositoryHaiku{
+					{Repository: "octo-org/api", Haiku: "Old cracks mended now"},
+					{Repository: "octo-org/web", Haiku: "A new page unfolds"},
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Missing commits",
+			requestBody:        map[string]string{"mood": "reflective"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedField:      "commits",
+		},
+		{
+			name: "Commit without a repository",
+			requestBody: map[string]any{
+				"commits": []map[string]string{{"message": "fix: resolved login issue"}},
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedField:      "commits[0].repository",
+		},
+		{
+			name:               "Invalid mood",
+			requestBody:        haiku.PulseRequest{Commits: commits, Mood: "grumpy"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedField:      "mood",
+		},
+		{
+			name:               "Too many commits",
+			requestBody:        haiku.PulseRequest{Commits: slices.Repeat(commits, 501)},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedField:      "commits",
+		},
+		{
+			name:               "Service returns bad haiku request error",
+			requestBody:        haiku.PulseRequest{Commits: commits},
+			mockError:          haiku.ErrBadHaikuRequest,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Service returns internal error",
+			requestBody:        haiku.PulseRequest{Commits: commits},
+			mockError:          errors.New("some internal error"),
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{
+				PulseResponseToReturn: tc.mockResponse,
+				ErrorToReturn:         tc.mockError,
+			}
+
+			api := NewHaikuAPI(mockService, nil)
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			requestBody, err := json.Marshal(tc.requestBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/haiku/pulse", bytes.NewBuffer(requestBody))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var response haiku.PulseResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if response.Poem != tc.mockResponse.Poem || len(response.Verses) != len(tc.mockResponse.Verses) {
+					t.Errorf("Expected response %+v, got %+v", tc.mockResponse, response)
+				}
+				return
+			}
+
+			var problem Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if tc.expectedField != "" && (len(problem.Errors) == 0 || problem.Errors[0].Field != tc.expectedField) {
+				t.Errorf("Expected a field error for %q, got %+v", tc.expectedField, problem.Errors)
+			}
+		})
+	}
+}
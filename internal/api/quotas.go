@@ -0,0 +1,73 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metering"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+	"github.com/gin-gonic/gin"
+)
+
+// Quota headers tell callers how much of their API key's monthly quota is
+// left. Limits and remaining counts are only sent for limited quotas.
+const (
+	QuotaRequestsLimitHeader         = "X-Quota-Requests-Limit"
+	QuotaRequestsRemainingHeader     = "X-Quota-Requests-Remaining"
+	QuotaOutputTokensLimitHeader     = "X-Quota-Output-Tokens-Limit"
+	QuotaOutputTokensRemainingHeader = "X-Quota-Output-Tokens-Remaining"
+	QuotaResetHeader                 = "X-Quota-Reset" // Unix time the quota starts over
+)
+
+// enforceQuota counts the request against the caller's API key, refusing it
+// once the key's monthly quota is used up, and charges the key for the output
+// tokens the request consumed.
+func (api *HaikuAPI) enforceQuota(c *gin.Context) {
+	value, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		c.Next()
+		return
+	}
+	key := value.(keys.Key)
+
+	usage, err := api.options.Quotas.Admit(c.Request.Context(), key)
+	if errors.Is(err, quotas.ErrQuotaExceeded) {
+		setQuotaHeaders(c, usage)
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(usage.ResetAt).Seconds()))))
+		serviceError(c, err)
+		return
+	}
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+	setQuotaHeaders(c, usage)
+
+	ctx, meter := metering.NewContext(c.Request.Context())
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	// The response is written, so the tokens are charged even if the caller
+	// has gone; a failure can only be logged.
+	if err := api.options.Quotas.AddOutputTokens(context.WithoutCancel(ctx), key.ID, meter.OutputTokens()); err != nil {
+		logging.Errorf("[HAIKU API] error charging key %s for %d output tokens: %v", key.ID, meter.OutputTokens(), err)
+	}
+}
+
+func setQuotaHeaders(c *gin.Context, usage quotas.Usage) {
+	if usage.Quota.MonthlyRequests > 0 {
+		c.Header(QuotaRequestsLimitHeader, strconv.FormatInt(usage.Quota.MonthlyRequests, 10))
+		c.Header(QuotaRequestsRemainingHeader, strconv.FormatInt(usage.RemainingRequests(), 10))
+	}
+	if usage.Quota.MonthlyOutputTokens > 0 {
+		c.Header(QuotaOutputTokensLimitHeader, strconv.FormatInt(usage.Quota.MonthlyOutputTokens, 10))
+		c.Header(QuotaOutputTokensRemainingHeader, strconv.FormatInt(usage.RemainingOutputTokens(), 10))
+	}
+	c.Header(QuotaResetHeader, strconv.FormatInt(usage.ResetAt.Unix(), 10))
+}
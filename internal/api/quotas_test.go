@@ -0,0 +1,165 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metering"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+	"github.com/gin-gonic/gin"
+)
+
+type MockQuotaService struct {
+	UsageToReturn   quotas.Usage
+	ErrorToReturn   error
+	Admitted        []string
+	ChargedKey      string
+	ChargedTokens   int
+	ChargeCallCount int
+}
+
+func (m *MockQuotaService) Admit(ctx context.Context, key keys.Key) (quotas.Usage, error) {
+	m.Admitted = append(m.Admitted, key.ID)
+	return m.UsageToReturn, m.ErrorToReturn
+}
+
+func (m *MockQuotaService) AddOutputTokens(ctx context.Context, keyID string, tokens int) error {
+	m.ChargeCallCount++
+	m.ChargedKey = keyID
+	m.ChargedTokens = tokens
+	return nil
+}
+
+// meteredHaikuService consumes tokens the way the model clients do.
+type meteredHaikuService struct {
+	MockHaikuService
+}
+
+func (m *meteredHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	metering.Add(ctx, 120, 42)
+	return haiku.HaikuCommitResponse{Haiku: "leaves fall"}, nil
+}
+
+func TestEnforceQuota(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	resetAt := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name               string
+		usage              quotas.Usage
+		mockError          error
+		expectedStatusCode int
+		expectedHeaders    map[string]string
+		expectedCharge     int
+	}{
+		{
+			name:               "Admitted",
+			usage:              quotas.Usage{Quota: keys.Quota{MonthlyRequests: 100, MonthlyOutputTokens: 5000}, Requests: 10, OutputTokens: 1000, ResetAt: resetAt},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders: map[string]string{
+				QuotaRequestsLimitHeader:         "100",
+				QuotaRequestsRemainingHeader:     "90",
+				QuotaOutputTokensLimitHeader:     "5000",
+				QuotaOutputTokensRemainingHeader: "4000",
+			},
+			expectedCharge: 42,
+		},
+		{
+			name:               "Unlimited",
+			usage:              quotas.Usage{Requests: 10, ResetAt: resetAt},
+			expectedStatusCode: http.StatusOK,
+			expectedHeaders:    map[string]string{QuotaRequestsLimitHeader: "", QuotaOutputTokensLimitHeader: ""},
+			expectedCharge:     42,
+		},
+		{
+			name:               "Quota used up",
+			usage:              quotas.Usage{Quota: keys.Quota{MonthlyRequests: 100}, Requests: 100, ResetAt: resetAt},
+			mockError:          quotas.ErrQuotaExceeded,
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedHeaders:    map[string]string{QuotaRequestsRemainingHeader: "0", "Retry-After": "3600"},
+		},
+		{
+			name:               "Usage can't be tracked",
+			mockError:          quotas.ErrTrackUsage,
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuotas := &MockQuotaService{UsageToReturn: tc.usage, ErrorToReturn: tc.mockError}
+			api := NewHaikuAPI(&meteredHaikuService{}, &Options{Keys: newTestKeyService(), RequireAPIKey: true, Quotas: mockQuotas})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", "/haiku", bytes.NewBufferString(`{"commitMessage":"fix: resolved login issue"}`))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(APIKeyHeader, "haiku-key")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			for name, expected := range tc.expectedHeaders {
+				if got := w.Header().Get(name); got != expected {
+					t.Errorf("Expected %s %q, got %q", name, expected, got)
+				}
+			}
+			if len(mockQuotas.Admitted) != 1 || mockQuotas.Admitted[0] != "haiku" {
+				t.Errorf("Expected the request to be counted against key haiku, got %v", mockQuotas.Admitted)
+			}
+
+			if tc.expectedStatusCode == http.StatusTooManyRequests {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != CodeKeyQuotaExceeded {
+					t.Errorf("Expected code %s, got %s", CodeKeyQuotaExceeded, p.Code)
+				}
+			}
+			if tc.expectedCharge > 0 && (mockQuotas.ChargedKey != "haiku" || mockQuotas.ChargedTokens != tc.expectedCharge) {
+				t.Errorf("Expected key haiku to be charged %d tokens, got %q charged %d", tc.expectedCharge, mockQuotas.ChargedKey, mockQuotas.ChargedTokens)
+			}
+			if tc.expectedCharge == 0 && mockQuotas.ChargeCallCount != 0 {
+				t.Errorf("Expected a refused request not to be charged, got %d tokens", mockQuotas.ChargedTokens)
+			}
+		})
+	}
+}
+
+func TestPollingJobsIsNotCounted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	mockQuotas := &MockQuotaService{ErrorToReturn: quotas.ErrQuotaExceeded}
+	mockJobs := &MockJobService{JobToReturn: jobs.Job{ID: testJobID, Status: jobs.StatusSucceeded}}
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Keys: newTestKeyService(), RequireAPIKey: true, Quotas: mockQuotas, Jobs: mockJobs})
+
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("GET", "/haiku/jobs/"+testJobID, nil)
+	req.Header.Set(APIKeyHeader, "haiku-key")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// The key's quota is used up, but its jobs can still be fetched.
+	if w.Code != http.StatusOK || len(mockQuotas.Admitted) != 0 {
+		t.Errorf("Expected the poll to skip the quota, got %d after %v", w.Code, mockQuotas.Admitted)
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func (api *HaikuAPI) postReleaseNotesHaiku(c *gin.Context) {
+	var request haiku.ReleaseNotesRequest
+
+	// Validate request format
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logging.Errorf("[HAIKU API] error binding release notes request: %v", err)
+		bindingError(c, err)
+		return
+	}
+
+	if request.Mood != "" && !request.Mood.IsValid() {
+		logging.Warnf("[HAIKU API] invalid mood: %s", request.Mood)
+		invalidRequest(c, "", invalidValue("mood", haiku.Moods))
+		return
+	}
+
+	// Enforce max release notes length
+	if len(request.ReleaseNotes) > MaxReleaseNotesLength {
+		logging.Warnf("[HAIKU API] releaseNotes exceeds %d characters", MaxReleaseNotesLength)
+		invalidRequest(c, "", tooLong("releaseNotes", MaxReleaseNotesLength))
+		return
+	}
+
+	response, err := api.haikuService.CreateReleaseNotesHaiku(c.Request.Context(), request)
+
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func TestPostReleaseNotesHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requestBody        any
+		mockResponse       haiku.ReleaseNotesResponse
+		mockError          error
+		expectedStatusCode int
+		expectedError      string
+	}{
+		{
+			name: "Successful request",
+			requestBody: haiku.ReleaseNotesRequest{
+				ReleaseNotes: "- Faster queries\n- Fixed login bug",
+			},
+			mockResponse: haiku.ReleaseNotesResponse{
+				Haiku: []haiku.ThemeHaiku{
+					{Theme: "Performance", Haiku: "Queries slip like rain\nthrough channels carved anew\nthe pages load fast"},
+					{Theme: "Bug fixes", Haiku: "Old cracks mended now\nthe login door swings open\nquiet in the logs"},
+				},
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Missing release notes",
+			requestBody:        map[string]string{"mood": "reflective"},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Release notes too long",
+			requestBody: haiku.ReleaseNotesRequest{
+				ReleaseNotes: strings.Repeat("a", MaxReleaseNotesLength+1),
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Service returns bad haiku request error",
+			requestBody: haiku.ReleaseNotesRequest{
+				ReleaseNotes: "- Faster queries",
+				Mood:         "invalid_mood",
+			},
+			mockError:          haiku.ErrBadHaikuRequest,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedError:      InvalidRequest,
+		},
+		{
+			name: "Service returns internal error",
+			requestBody: haiku.ReleaseNotesRequest{
+				ReleaseNotes: "- Faster queries",
+			},
+			mockError:          errors.New("some internal error"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedError:      InternalServerError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{
+				ReleaseNotesResponseToReturn: tc.mockResponse,
+				ErrorToReturn:                tc.mockError,
+			}
+
+			api := NewHaikuAPI(mockService, nil)
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			requestBody, err := json.Marshal(tc.requestBody)
+			if err != nil {
+				t.Fatalf("Failed to marshal request body: %v", err)
+			}
+
+			req, err := http.NewRequest("POST", "/haiku/release-notes", bytes.NewBuffer(requestBody))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+
+			if tc.expectedStatusCode == http.StatusOK {
+				var response haiku.ReleaseNotesResponse
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if len(response.Haiku) != len(tc.mockResponse.Haiku) {
+					t.Fatalf("Expected %d haiku, got %d", len(tc.mockResponse.Haiku), len(response.Haiku))
+				}
+				for i, expected := range tc.mockResponse.Haiku {
+					if response.Haiku[i] != expected {
+						t.Errorf("Expected haiku %d to be %+v, got %+v", i, expected, response.Haiku[i])
+					}
+				}
+			} else {
+				var response map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("Failed to unmarshal response: %v", err)
+				}
+				if errorMsg, ok := response["title"].(string); !ok || errorMsg != tc.expectedError {
+					t.Errorf("Expected error %q, got %q", tc.expectedError, errorMsg)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/renga"
+	"github.com/gin-gonic/gin"
+)
+
+// postRenga adds a commit's verse to its repository's renga, written to
+// follow the verse before it.
+func (api *HaikuAPI) postRenga(c *gin.Context) {
+	var request renga.ContinueRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		logging.Errorf("[HAIKU API] error binding renga request: %v", err)
+		bindingError(c, err)
+		return
+	}
+
+	if request.Mood != "" && request.Mood != haiku.MoodAuto && !request.Mood.IsValid() {
+		logging.Warnf("[HAIKU API] invalid mood: %s", request.Mood)
+		invalidRequest(c, "", invalidValue("mood", append(haiku.Moods, haiku.MoodAuto)))
+		return
+	}
+	if !api.limitCommitMessage(c, &request.CommitMessage) {
+		return
+	}
+
+	verse, err := api.options.Renga.Continue(c.Request.Context(), request)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, verse)
+}
+
+// getRenga returns the latest ?limit= verses of the renga of ?repository=.
+func (api *HaikuAPI) getRenga(c *gin.Context) {
+	repository := c.Query("repository")
+	if repository == "" {
+		field := FieldError{Field: "repository", Code: FieldRequired, Detail: "repository is required"}
+		invalidRequest(c, field.Detail, field)
+		return
+	}
+	limit, ok := queryInt(c, "limit", renga.DefaultLimit, 1, renga.MaxLimit)
+	if !ok {
+		return
+	}
+
+	result, err := api.options.Renga.Get(c.Request.Context(), repository, limit)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/renga"
+	"github.com/gin-gonic/gin"
+)
+
+type MockRengaService struct {
+	ErrorToReturn error
+	Continued     []renga.ContinueRequest
+	Limit         int
+}
+
+func (m *MockRengaService) Continue(ctx context.Context, request renga.ContinueRequest) (renga.Verse, error) {
+	m.Continued = append(m.Continued, request)
+	return renga.Verse{Position: 2, Haiku: "frost on the build log"}, m.ErrorToReturn
+}
+
+func (m *MockRengaService) Get(ctx context.Context, repository string, limit int) (renga.Renga, error) {
+	m.Limit = limit
+	return renga.Renga{Repository: repository, Length: 2}, m.ErrorToReturn
+}
+
+func TestPostRenga(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		body               string
+		mockError          error
+		expectedStatusCode int
+		expectedCode       string
+		expectedMessage    string
+	}{
+		{
+			name:               "Verse added",
+			body:               `{"repository": "octo/leaves", "commitMessage": "fix typo", "mood": "auto"}`,
+			expectedStatusCode: http.StatusOK,
+			expectedMessage:    "fix typo",
+		},
+		{
+			name:               "Long commit message truncated",
+			body:               fmt.Sprintf(`{"repository": "octo/leaves", "commitMessage": %q}`, strings.Repeat("a", MaxCommitLength+10)),
+			expectedStatusCode: http.StatusOK,
+			expectedMessage:    strings.Repeat("a", MaxCommitLength),
+		},
+		{
+			name:               "Missing repository",
+			body:               `{"commitMessage": "fix typo"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Invalid mood",
+			body:               `{"repository": "octo/leaves", "commitMessage": "fix typo", "mood": "angry"}`,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Busy",
+			body:               `{"repository": "octo/leaves", "commitMessage": "fix typo"}`,
+			mockError:          renga.ErrRengaBusy,
+			expectedStatusCode: http.StatusConflict,
+			expectedCode:       CodeRengaBusy,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRenga := &MockRengaService{ErrorToReturn: tc.mockError}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Renga: mockRenga})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", "/haiku/renga", strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+				return
+			}
+
+			if len(mockRenga.Continued) != 1 || mockRenga.Continued[0].CommitMessage != tc.expectedMessage {
+				t.Errorf("Expected the verse written from %q, got %+v", tc.expectedMessage, mockRenga.Continued)
+			}
+			var verse renga.Verse
+			if err := json.Unmarshal(w.Body.Bytes(), &verse); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if verse.Position != 2 || verse.Haiku != "frost on the build log" {
+				t.Errorf("Expected the verse, got %+v", verse)
+			}
+		})
+	}
+}
+
+func TestGetRenga(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		query              string
+		mockError          error
+		expectedStatusCode int
+		expectedLimit      int
+	}{
+		{
+			name:               "Default limit",
+			query:              "?repository=octo/leaves",
+			expectedStatusCode: http.StatusOK,
+			expectedLimit:      renga.DefaultLimit,
+		},
+		{
+			name:               "Limit",
+			query:              "?repository=octo/leaves&limit=3",
+			expectedStatusCode: http.StatusOK,
+			expectedLimit:      3,
+		},
+		{
+			name:               "Missing repository",
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Limit too high",
+			query:              fmt.Sprintf("?repository=octo/leaves&limit=%d", renga.MaxLimit+1),
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "No renga",
+			query:              "?repository=octo/leaves",
+			mockError:          renga.ErrRengaNotFound,
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRenga := &MockRengaService{ErrorToReturn: tc.mockError}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Renga: mockRenga})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/haiku/renga"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedStatusCode == http.StatusOK && mockRenga.Limit != tc.expectedLimit {
+				t.Errorf("Expected limit %d, got %d", tc.expectedLimit, mockRenga.Limit)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/reporting"
+	"github.com/gin-gonic/gin"
+)
+
+// reportTimeout bounds how long a failed request waits for its report.
+const reportTimeout = 3 * time.Second
+
+// recovery turns a panic into a 500 problem response and reports it, along
+// with every other 5xx response, to the configured reporter. Reports are sent
+// before the response is returned, as a Lambda may be frozen straight after.
+func (api *HaikuAPI) recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			stack := string(debug.Stack())
+			logging.Errorf("[HAIKU API] recovered from panic: %v\n%s", recovered, stack)
+			problem(c, http.StatusInternalServerError, CodeInternalError, InternalServerError, "")
+			api.report(c, reporting.KindPanic, fmt.Sprint(recovered), stack)
+		}()
+
+		c.Next()
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			message := strings.Join(c.Errors.Errors(), "; ")
+			if message == "" {
+				message = http.StatusText(c.Writer.Status())
+			}
+			api.report(c, reporting.KindError, message, "")
+		}
+	}
+}
+
+func (api *HaikuAPI) report(c *gin.Context, kind reporting.Kind, message string, stack string) {
+	if api.options.Reporter == nil {
+		return
+	}
+
+	event := reporting.Event{
+		Time:      time.Now(),
+		Kind:      kind,
+		Message:   message,
+		Status:    c.Writer.Status(),
+		Method:    c.Request.Method,
+		Route:     c.FullPath(),
+		Path:      c.Request.URL.Path,
+		UserAgent: c.Request.UserAgent(),
+		Stack:     stack,
+	}
+	ids := requestIDs(c.Request.Context())
+	event.RequestID, event.APIRequestID = ids.ID, ids.APIRequestID
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), reportTimeout)
+	defer cancel()
+	if err := api.options.Reporter.Report(ctx, event); err != nil {
+		logging.Errorf("[HAIKU API] error reporting %s: %v", kind, err)
+	}
+}
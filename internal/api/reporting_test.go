@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/reporting"
+	"github.com/gin-gonic/gin"
+)
+
+type MockReporter struct {
+	Events []reporting.Event
+}
+
+func (m *MockReporter) Report(ctx context.Context, event reporting.Event) error {
+	m.Events = append(m.Events, event)
+	return nil
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name           string
+		handler        gin.HandlerFunc
+		expectedStatus int
+		expectedKind   reporting.Kind
+		expectedText   string
+	}{
+		{
+			name:           "Panic recovered and reported",
+			handler:        func(c *gin.Context) { panic("index out of range") },
+			expectedStatus: http.StatusInternalServerError,
+			expectedKind:   reporting.KindPanic,
+			expectedText:   "index out of range",
+		},
+		{
+			name:           "Server error reported",
+			handler:        func(c *gin.Context) { serviceError(c, errors.New("some internal error")) },
+			expectedStatus: http.StatusInternalServerError,
+			expectedKind:   reporting.KindError,
+			expectedText:   "some internal error",
+		},
+		{
+			name:           "Client error not reported",
+			handler:        func(c *gin.Context) { invalidRequest(c, "bad") },
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Success not reported",
+			handler:        func(c *gin.Context) { c.Status(http.StatusOK) },
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter := &MockReporter{}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Reporter: reporter})
+
+			router := gin.New()
+			router.Use(api.recovery())
+			router.POST("/haiku", tc.handler)
+
+			req, err := http.NewRequest("POST", "/haiku", nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatus {
+				t.Errorf("Expected status code %d, got %d", tc.expectedStatus, w.Code)
+			}
+
+			if tc.expectedKind == "" {
+				if len(reporter.Events) != 0 {
+					t.Errorf("Expected no reports, got %+v", reporter.Events)
+				}
+				return
+			}
+
+			if len(reporter.Events) != 1 {
+				t.Fatalf("Expected one report, got %+v", reporter.Events)
+			}
+			event := reporter.Events[0]
+			if event.Kind != tc.expectedKind || event.Route != "/haiku" || event.Status != tc.expectedStatus {
+				t.Errorf("Expected a %s report for /haiku, got %+v", tc.expectedKind, event)
+			}
+			if !strings.Contains(event.Message, tc.expectedText) {
+				t.Errorf("Expected message to contain %q, got %q", tc.expectedText, event.Message)
+			}
+			if tc.expectedKind == reporting.KindPanic && event.Stack == "" {
+				t.Errorf("Expected a stack for the panic")
+			}
+		})
+	}
+}
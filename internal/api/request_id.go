@@ -0,0 +1,52 @@
+package api
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/awslabs/aws-lambda-go-api-proxy/core"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader lists the API Gateway request ID, then the Lambda request
+// ID, of each response, so that a support ticket quoting them can be traced
+// to the function's log.
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDs returns the Lambda and API Gateway request IDs of ctx, which are
+// empty outside Lambda.
+func requestIDs(ctx context.Context) logging.Request {
+	var ids logging.Request
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		ids.ID = lc.AwsRequestID
+	}
+	if gateway, ok := core.GetAPIGatewayContextFromContext(ctx); ok {
+		ids.APIRequestID = gateway.RequestID
+	}
+	return ids
+}
+
+// identifyRequests sets the request ID header of every response.
+func identifyRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ids := requestIDs(c.Request.Context())
+		for _, id := range []string{ids.APIRequestID, ids.ID} {
+			if id != "" {
+				c.Writer.Header().Add(RequestIDHeader, id)
+			}
+		}
+		c.Next()
+	}
+}
+
+// accessLog logs each request once it has been answered. The request IDs are
+// attached to the line by the logging package, like every other.
+func accessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		started := time.Now()
+		c.Next()
+		logging.Infof("[HAIKU API] %s %s %d in %s", c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(started))
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/slack"
+	"github.com/gin-gonic/gin"
+)
+
+// postSlackCommand acknowledges a /haiku slash command within Slack's three
+// second timeout and dispatches the haiku to be posted to the command's
+// response URL.
+func (api *HaikuAPI) postSlackCommand(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookPayload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error reading slack command: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	timestamp := c.GetHeader("X-Slack-Request-Timestamp")
+	if !validSlackSignature(api.options.SlackSigningSecret, timestamp, payload, c.GetHeader("X-Slack-Signature"), time.Now()) {
+		logging.Warnf("[HAIKU API] invalid slack request signature")
+		problem(c, http.StatusUnauthorized, CodeInvalidSignature, InvalidSignature, "")
+		return
+	}
+
+	form, err := url.ParseQuery(string(payload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error parsing slack command: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	command := slack.Command{
+		Text:        strings.TrimSpace(form.Get("text")),
+		ResponseURL: form.Get("response_url"),
+		UserID:      form.Get("user_id"),
+	}
+	if command.Text == "" {
+		c.JSON(http.StatusOK, slack.Message{
+			ResponseType: "ephemeral",
+			Text:         SlackUsage,
+		})
+		return
+	}
+
+	if len(command.Text) > api.options.MaxCommitLength {
+		command.Text = TruncateCommitMessage(command.Text, api.options.MaxCommitLength, api.options.TruncatedBodyLength)
+	}
+
+	if err := api.options.SlackCommands.Dispatch(c.Request.Context(), command); err != nil {
+		if errors.Is(err, slack.ErrBadCommand) {
+			logging.Warnf("[HAIKU API] bad slack command: %v", err)
+			invalidRequest(c, err.Error())
+			return
+		}
+
+		// Slack shows non-200 responses as a generic failure, so the user is
+		// told in the acknowledgement instead.
+		logging.Errorf("[HAIKU API] error dispatching slack command: %v", err)
+		c.JSON(http.StatusOK, slack.Message{
+			ResponseType: "ephemeral",
+			Text:         CommandUnavailable,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, slack.Message{
+		ResponseType: "ephemeral",
+		Text:         SlackAcknowledged,
+	})
+}
+
+// validSlackSignature checks the "v0=<hex>" HMAC Slack sends with each request,
+// computed over the request timestamp and body. Requests older than
+// SlackRequestMaxAge are rejected so captured requests cannot be replayed.
+func validSlackSignature(secret string, timestamp string, payload []byte, signature string, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > SlackRequestMaxAge || age < -SlackRequestMaxAge {
+		return false
+	}
+
+	digest, ok := strings.CutPrefix(signature, "v0=")
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
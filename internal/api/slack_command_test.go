@@ -0,0 +1,147 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/slack"
+	"github.com/gin-gonic/gin"
+)
+
+type MockSlackDispatcher struct {
+	ErrorToReturn error
+	LastCommand   *slack.Command
+}
+
+func (m *MockSlackDispatcher) Dispatch(ctx context.Context, command slack.Command) error {
+	m.LastCommand = &command
+	return m.ErrorToReturn
+}
+
+func signSlackRequest(timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(payload)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestPostSlackCommand(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	form := url.Values{
+		"command":      {"/haiku"},
+		"text":         {"fix flaky test"},
+		"response_url": {"https://hooks.slack.com/commands/T1/2/abc"},
+		"user_id":      {"U123"},
+	}.Encode()
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+
+	tests := []struct {
+		name               string
+		payload            string
+		timestamp          string
+		signature          string
+		dispatchError      error
+		expectedStatusCode int
+		expectedText       string
+		expectedCommand    *slack.Command
+	}{
+		{
+			name:               "Command acknowledged",
+			payload:            form,
+			timestamp:          now,
+			expectedStatusCode: http.StatusOK,
+			expectedText:       SlackAcknowledged,
+			expectedCommand: &slack.Command{
+				Text:        "fix flaky test",
+				ResponseURL: "https://hooks.slack.com/commands/T1/2/abc",
+				UserID:      "U123",
+			},
+		},
+		{
+			name:               "Empty text shows usage",
+			payload:            url.Values{"text": {" "}, "response_url": {"https://hooks.slack.com/commands/T1/2/abc"}}.Encode(),
+			timestamp:          now,
+			expectedStatusCode: http.StatusOK,
+			expectedText:       SlackUsage,
+		},
+		{
+			name:               "Dispatch failure reported to the user",
+			payload:            form,
+			timestamp:          now,
+			dispatchError:      errors.New("throttled"),
+			expectedStatusCode: http.StatusOK,
+			expectedText:       CommandUnavailable,
+		},
+		{
+			name:               "Bad response url",
+			payload:            form,
+			timestamp:          now,
+			dispatchError:      slack.ErrBadCommand,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Invalid signature",
+			payload:            form,
+			timestamp:          now,
+			signature:          "v0=00",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Stale timestamp",
+			payload:            form,
+			timestamp:          strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10),
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dispatcher := &MockSlackDispatcher{ErrorToReturn: tc.dispatchError}
+
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				SlackCommands:      dispatcher,
+				SlackSigningSecret: testWebhookSecret,
+			})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			signature := tc.signature
+			if signature == "" {
+				signature = signSlackRequest(tc.timestamp, []byte(tc.payload))
+			}
+
+			req, _ := http.NewRequest("POST", "/integrations/slack", bytes.NewBufferString(tc.payload))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("X-Slack-Request-Timestamp", tc.timestamp)
+			req.Header.Set("X-Slack-Signature", signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+
+			if tc.expectedText != "" {
+				var message slack.Message
+				if err := json.Unmarshal(w.Body.Bytes(), &message); err != nil || message.Text != tc.expectedText || message.ResponseType != "ephemeral" {
+					t.Errorf("Expected ephemeral message %q, got %s", tc.expectedText, w.Body.String())
+				}
+			}
+			if tc.expectedCommand != nil && (dispatcher.LastCommand == nil || *dispatcher.LastCommand != *tc.expectedCommand) {
+				t.Errorf("Expected command %+v to be dispatched, got %+v", tc.expectedCommand, dispatcher.LastCommand)
+			}
+		})
+	}
+}
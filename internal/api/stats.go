@@ -0,0 +1,45 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/gin-gonic/gin"
+)
+
+// getStats returns usage statistics for the last ?days days (default: 7), of
+// the default tenant or ?tenant. Statistics name the repositories using the
+// service, so they are only served to callers holding the stats token.
+func (api *HaikuAPI) getStats(c *gin.Context) {
+	token, _ := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(api.options.StatsToken)) != 1 {
+		logging.Warnf("[HAIKU API] invalid stats token")
+		c.Header("WWW-Authenticate", "Bearer")
+		problem(c, http.StatusUnauthorized, CodeUnauthorized, Unauthorized, "")
+		return
+	}
+
+	days, ok := queryInt(c, "days", stats.DefaultDays, 1, stats.MaxDays)
+	if !ok {
+		return
+	}
+
+	tenant := c.Query("tenant")
+	if tenant != "" && !keys.ValidTenant(tenant) {
+		detail := "tenant must be lowercase letters, digits and hyphens"
+		invalidRequest(c, detail, FieldError{Field: "tenant", Code: FieldInvalidValue, Detail: detail})
+		return
+	}
+
+	summary, err := api.options.Stats.Summary(keys.NewTenantContext(c.Request.Context(), tenant), days)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/gin-gonic/gin"
+)
+
+const testStatsToken = "stats-token"
+
+type MockStatsService struct {
+	StatsToReturn stats.Stats
+	ErrorToReturn error
+	LastDays      int
+	LastTenant    string
+}
+
+func (m *MockStatsService) Summary(ctx context.Context, days int) (stats.Stats, error) {
+	m.LastDays = days
+	m.LastTenant = keys.TenantFromContext(ctx)
+	return m.StatsToReturn, m.ErrorToReturn
+}
+
+func TestGetStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		query              string
+		authorization      string
+		mockError          error
+		expectedStatusCode int
+		expectedCode       string
+		expectedDays       int
+		expectedTenant     string
+	}{
+		{
+			name:               "Default days",
+			authorization:      "Bearer " + testStatsToken,
+			expectedStatusCode: http.StatusOK,
+			expectedDays:       stats.DefaultDays,
+		},
+		{
+			name:               "Requested days",
+			query:              "?days=30",
+			authorization:      "Bearer " + testStatsToken,
+			expectedStatusCode: http.StatusOK,
+			expectedDays:       30,
+		},
+		{
+			name:               "Tenant",
+			query:              "?tenant=acme",
+			authorization:      "Bearer " + testStatsToken,
+			expectedStatusCode: http.StatusOK,
+			expectedDays:       stats.DefaultDays,
+			expectedTenant:     "acme",
+		},
+		{
+			name:               "Invalid tenant",
+			query:              "?tenant=Acme%3Aci",
+			authorization:      "Bearer " + testStatsToken,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Too many days",
+			query:              "?days=365",
+			authorization:      "Bearer " + testStatsToken,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Days not a number",
+			query:              "?days=week",
+			authorization:      "Bearer " + testStatsToken,
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Missing token",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       CodeUnauthorized,
+		},
+		{
+			name:               "Wrong token",
+			authorization:      "Bearer guess",
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedCode:       CodeUnauthorized,
+		},
+		{
+			name:               "Store fails",
+			authorization:      "Bearer " + testStatsToken,
+			mockError:          errors.Join(stats.ErrGetStats, errors.New("table not found")),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedCode:       CodeInternalError,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockStats := &MockStatsService{
+				StatsToReturn: stats.Stats{From: "2025-09-27", To: "2025-10-03", Haiku: 12},
+				ErrorToReturn: tc.mockError,
+			}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Stats: mockStats, StatsToken: testStatsToken})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/stats"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+				return
+			}
+
+			if mockStats.LastDays != tc.expectedDays {
+				t.Errorf("Expected %d days, got %d", tc.expectedDays, mockStats.LastDays)
+			}
+			if mockStats.LastTenant != tc.expectedTenant {
+				t.Errorf("Expected tenant %q, got %q", tc.expectedTenant, mockStats.LastTenant)
+			}
+			var summary stats.Stats
+			if err := json.Unmarshal(w.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if summary.Haiku != 12 {
+				t.Errorf("Expected 12 haiku, got %d", summary.Haiku)
+			}
+		})
+	}
+}
+
+func TestStatsRequiresToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	api := NewHaikuAPI(&MockHaikuService{}, &Options{Stats: &MockStatsService{}})
+	router := gin.New()
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("GET", "/stats", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected stats to be disabled without a token, got %d", w.Code)
+	}
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/styles"
+	"github.com/gin-gonic/gin"
+)
+
+// getStyle returns the style guide of the caller's tenant.
+func (api *HaikuAPI) getStyle(c *gin.Context) {
+	style, err := api.options.Styles.Get(c.Request.Context())
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, style)
+}
+
+// putStyle replaces the style guide of the caller's tenant, merged into the
+// prompt of every commit haiku written for it from then on.
+func (api *HaikuAPI) putStyle(c *gin.Context) {
+	var request styles.SetStyleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		bindingError(c, err)
+		return
+	}
+
+	style, err := api.options.Styles.Set(c.Request.Context(), request)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, style)
+}
+
+func (api *HaikuAPI) deleteStyle(c *gin.Context) {
+	if err := api.options.Styles.Delete(c.Request.Context()); err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
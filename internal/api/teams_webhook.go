@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/teams"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	teamsMention = regexp.MustCompile(`(?s)<at>.*?</at>`)
+	teamsBreak   = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>`)
+	teamsTag     = regexp.MustCompile(`<[^>]*>`)
+)
+
+type teamsActivity struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+	From struct {
+		Name string `json:"name"`
+	} `json:"from"`
+}
+
+// text returns the message Teams sent as plain text, without the mention of
+// the webhook that every message starts with.
+func (a teamsActivity) text() string {
+	text := teamsMention.ReplaceAllString(a.Text, "")
+	text = teamsBreak.ReplaceAllString(text, "\n")
+	text = html.UnescapeString(teamsTag.ReplaceAllString(text, ""))
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	return strings.TrimSpace(text)
+}
+
+// postTeamsMessage replies to a message mentioning the outgoing webhook. Teams
+// has no way to deliver a reply later, so the haiku is generated within
+// TeamsReplyTimeout and failures are explained in the reply.
+func (api *HaikuAPI) postTeamsMessage(c *gin.Context) {
+	payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxWebhookPayload))
+	if err != nil {
+		logging.Errorf("[HAIKU API] error reading teams message: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+
+	if !validTeamsSignature(api.options.TeamsWebhookSecret, payload, c.GetHeader("Authorization")) {
+		logging.Warnf("[HAIKU API] invalid teams message signature")
+		problem(c, http.StatusUnauthorized, CodeInvalidSignature, InvalidSignature, "")
+		return
+	}
+
+	var activity teamsActivity
+	if err := json.Unmarshal(payload, &activity); err != nil {
+		logging.Errorf("[HAIKU API] error parsing teams message: %v", err)
+		invalidRequest(c, err.Error())
+		return
+	}
+	if activity.Type != "message" {
+		logging.Warnf("[HAIKU API] unsupported teams activity type %q", activity.Type)
+		invalidRequest(c, "")
+		return
+	}
+
+	message := teams.Message{
+		Text:     activity.text(),
+		UserName: activity.From.Name,
+	}
+	if message.Text == "" {
+		c.JSON(http.StatusOK, teams.TextReply(TeamsUsage))
+		return
+	}
+
+	if len(message.Text) > api.options.MaxCommitLength {
+		message.Text = TruncateCommitMessage(message.Text, api.options.MaxCommitLength, api.options.TruncatedBodyLength)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), TeamsReplyTimeout)
+	defer cancel()
+
+	reply, err := api.options.TeamsMessages.Reply(ctx, message)
+	if err != nil {
+		// Teams shows non-200 responses as a generic failure, so the reply
+		// explains the error instead.
+		logging.Errorf("[HAIKU API] error replying to teams message: %v", err)
+		if reply.Type == "" {
+			reply = teams.TextReply(CommandUnavailable)
+		}
+	}
+
+	c.JSON(http.StatusOK, reply)
+}
+
+// validTeamsSignature checks the "HMAC <base64>" authorization Teams sends
+// with each message, computed over the body with the webhook's decoded
+// security token.
+func validTeamsSignature(secret []byte, payload []byte, authorization string) bool {
+	if len(secret) == 0 {
+		return false
+	}
+
+	digest, ok := strings.CutPrefix(authorization, "HMAC ")
+	if !ok {
+		return false
+	}
+
+	expected, err := base64.StdEncoding.DecodeString(strings.TrimSpace(digest))
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(payload)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/teams"
+	"github.com/gin-gonic/gin"
+)
+
+type MockTeamsResponder struct {
+	ReplyToReturn teams.Activity
+	ErrorToReturn error
+	LastMessage   *teams.Message
+}
+
+func (m *MockTeamsResponder) Reply(ctx context.Context, message teams.Message) (teams.Activity, error) {
+	m.LastMessage = &message
+	return m.ReplyToReturn, m.ErrorToReturn
+}
+
+func signTeamsMessage(payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write(payload)
+	return "HMAC " + base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestPostTeamsMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	message := `{"type":"message","text":"<at>Haiku</at>&nbsp;fix flaky test<br>","from":{"name":"Ada Lovelace"}}`
+	card := teams.Activity{Type: "message", Attachments: []teams.Attachment{{ContentType: "application/vnd.microsoft.card.adaptive"}}}
+
+	tests := []struct {
+		name               string
+		payload            string
+		signature          string
+		reply              teams.Activity
+		replyError         error
+		expectedStatusCode int
+		expectedText       string
+		expectedMessage    *teams.Message
+	}{
+		{
+			name:               "Haiku card",
+			payload:            message,
+			reply:              card,
+			expectedStatusCode: http.StatusOK,
+			expectedMessage:    &teams.Message{Text: "fix flaky test", UserName: "Ada Lovelace"},
+		},
+		{
+			name:               "Mention only shows usage",
+			payload:            `{"type":"message","text":"<at>Haiku</at> "}`,
+			expectedStatusCode: http.StatusOK,
+			expectedText:       TeamsUsage,
+		},
+		{
+			name:               "Failure explained in the reply",
+			payload:            message,
+			reply:              teams.TextReply("Sorry, that haiku was blocked by the content filter."),
+			replyError:         errors.New("blocked"),
+			expectedStatusCode: http.StatusOK,
+			expectedText:       "Sorry, that haiku was blocked by the content filter.",
+		},
+		{
+			name:               "Failure without a reply",
+			payload:            message,
+			replyError:         context.DeadlineExceeded,
+			expectedStatusCode: http.StatusOK,
+			expectedText:       CommandUnavailable,
+		},
+		{
+			name:               "Invalid signature",
+			payload:            message,
+			signature:          "HMAC AAAA",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Missing signature",
+			payload:            message,
+			signature:          "Bearer token",
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "Unsupported activity",
+			payload:            `{"type":"conversationUpdate"}`,
+			expectedStatusCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			responder := &MockTeamsResponder{ReplyToReturn: tc.reply, ErrorToReturn: tc.replyError}
+
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				TeamsMessages:      responder,
+				TeamsWebhookSecret: []byte(testWebhookSecret),
+			})
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			signature := tc.signature
+			if signature == "" {
+				signature = signTeamsMessage([]byte(tc.payload))
+			}
+
+			req, _ := http.NewRequest("POST", "/integrations/teams", bytes.NewBufferString(tc.payload))
+			req.Header.Set("Authorization", signature)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			var reply teams.Activity
+			if err := json.Unmarshal(w.Body.Bytes(), &reply); err != nil || reply.Type != "message" {
+				t.Fatalf("Expected a message activity, got %s", w.Body.String())
+			}
+			if tc.expectedText != "" && reply.Text != tc.expectedText {
+				t.Errorf("Expected reply %q, got %q", tc.expectedText, reply.Text)
+			}
+			if tc.expectedMessage != nil {
+				if responder.LastMessage == nil || *responder.LastMessage != *tc.expectedMessage {
+					t.Errorf("Expected message %+v, got %+v", tc.expectedMessage, responder.LastMessage)
+				}
+				if len(reply.Attachments) != 1 {
+					t.Errorf("Expected the card to be returned, got %s", w.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
+	"github.com/gin-gonic/gin"
+)
+
+// serverTiming records stage durations for each request and reports them in
+// a Server-Timing header, so a slow request can be traced to its stage from
+// the client's developer tools or a curl -i. With a tracer, each stage is
+// traced too.
+func serverTiming(tracer timing.Tracer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, timings := timing.NewContext(c.Request.Context(), tracer)
+		c.Request = c.Request.WithContext(ctx)
+		c.Writer = &timingWriter{
+			ResponseWriter: c.Writer,
+			timings:        timings,
+		}
+		c.Next()
+	}
+}
+
+// timingWriter adds the Server-Timing header just before the response is
+// written, once every stage has finished.
+type timingWriter struct {
+	gin.ResponseWriter
+	timings *timing.Timings
+	written bool
+}
+
+func (w *timingWriter) setHeader() {
+	if w.written || w.ResponseWriter.Written() {
+		return
+	}
+	w.written = true
+	w.Header().Set("Server-Timing", w.timings.Header(w.timings.Elapsed()))
+}
+
+func (w *timingWriter) WriteHeaderNow() {
+	w.setHeader()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timingWriter) Write(data []byte) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timingWriter) WriteString(s string) (int, error) {
+	w.setHeader()
+	return w.ResponseWriter.WriteString(s)
+}
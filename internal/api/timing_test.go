@@ -0,0 +1,115 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
+	"github.com/gin-gonic/gin"
+)
+
+// TimedHaikuService records a provider stage, as the real service does.
+type TimedHaikuService struct {
+	MockHaikuService
+}
+
+func (m *TimedHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	defer timing.Start(ctx, timing.StageProvider)()
+	return m.MockHaikuService.CreateHaiku(ctx, request)
+}
+
+func TestServerTiming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &TimedHaikuService{MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"}}}
+	api := NewHaikuAPI(service, nil)
+	router := gin.New()
+	api.SetupMiddleware(router)
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("POST", "/haiku?svg=true", bytes.NewBufferString(`{"commitMessage":"fix: resolved login issue"}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	expected := regexp.MustCompile(`^validate;dur=[0-9.]+, provider;dur=[0-9.]+, render;dur=[0-9.]+, total;dur=[0-9.]+$`)
+	if header := w.Header().Get("Server-Timing"); !expected.MatchString(header) {
+		t.Errorf("Expected a Server-Timing header with each stage, got %q", header)
+	}
+}
+
+func TestTimingsBlock(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := &TimedHaikuService{MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"}}}
+	api := NewHaikuAPI(service, nil)
+	router := gin.New()
+	api.SetupMiddleware(router)
+	api.SetupRoutes(router)
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedTimings bool
+	}{
+		{name: "Requested", query: "?timings=true", expectedTimings: true},
+		{name: "Not requested"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req, _ := http.NewRequest("POST", "/haiku"+tc.query, bytes.NewBufferString(`{"commitMessage":"fix: resolved login issue"}`))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			var response haiku.HaikuCommitResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			_, provider := response.Timings[timing.StageProvider]
+			_, total := response.Timings["total"]
+			if tc.expectedTimings && (!provider || !total) {
+				t.Errorf("Expected provider and total timings, got %v", response.Timings)
+			}
+			if !tc.expectedTimings && response.Timings != nil {
+				t.Errorf("Expected no timings, got %v", response.Timings)
+			}
+		})
+	}
+}
+
+type MockTracer struct {
+	Names []string
+}
+
+func (m *MockTracer) Trace(name string, start time.Time, end time.Time) {
+	m.Names = append(m.Names, name)
+}
+
+func TestServerTimingTraces(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tracer := &MockTracer{}
+	service := &TimedHaikuService{MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "leaves fall"}}}
+	api := NewHaikuAPI(service, &Options{Tracer: tracer})
+	router := gin.New()
+	api.SetupMiddleware(router)
+	api.SetupRoutes(router)
+
+	req, _ := http.NewRequest("POST", "/haiku", bytes.NewBufferString(`{"commitMessage":"fix: resolved login issue"}`))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(tracer.Names) != 2 || tracer.Names[0] != timing.StageValidate || tracer.Names[1] != timing.StageProvider {
+		t.Errorf("Expected the validate and provider stages traced, got %v", tracer.Names)
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+// LengthStrategy controls how commit messages longer than the maximum length
+// are handled.
+type LengthStrategy string
+
+const (
+	LengthStrategyReject   LengthStrategy = "reject"
+	LengthStrategyTruncate LengthStrategy = "truncate"
+)
+
+func (s LengthStrategy) IsValid() bool {
+	switch s {
+	case LengthStrategyReject, LengthStrategyTruncate:
+		return true
+	}
+	return false
+}
+
+// TruncateCommitMessage shortens a commit message to at most maxLength bytes,
+// keeping the subject line and up to bodyLength bytes of the body, which is
+// where squash commits put the detail worth keeping. Co-authored-by trailers
+// are kept in full and do not count towards maxLength, so co-authors are still
+// credited.
+func TruncateCommitMessage(message string, maxLength int, bodyLength int) string {
+	message, trailers := haiku.SplitCoAuthors(message)
+	truncated := truncateMessage(strings.TrimSpace(message), maxLength, bodyLength)
+	if len(trailers) == 0 {
+		return truncated
+	}
+	return strings.TrimSpace(truncated + "\n\n" + strings.Join(trailers, "\n"))
+}
+
+func truncateMessage(message string, maxLength int, bodyLength int) string {
+	if len(message) <= maxLength {
+		return message
+	}
+
+	subject, body, _ := strings.Cut(message, "\n")
+	subject = truncateRunes(strings.TrimSpace(subject), maxLength)
+
+	const separator = "\n\n"
+	remaining := min(bodyLength, maxLength-len(subject)-len(separator))
+	body = strings.Join(strings.Fields(body), " ")
+	if remaining <= 0 || body == "" {
+		return subject
+	}
+
+	return subject + separator + truncateRunes(body, remaining)
+}
+
+// truncateRunes cuts s to at most n bytes without splitting a UTF-8 sequence,
+// preferring to break at a word boundary.
+func truncateRunes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	cut := n
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	truncated := s[:cut]
+
+	if i := strings.LastIndexByte(truncated, ' '); i > len(truncated)/2 {
+		truncated = truncated[:i]
+	}
+	return strings.TrimSpace(truncated)
+}
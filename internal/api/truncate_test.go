@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/gin-gonic/gin"
+)
+
+func TestTruncateCommitMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		message    string
+		maxLength  int
+		bodyLength int
+		expected   string
+	}{
+		{
+			name:       "Short message unchanged",
+			message:    "fix: resolved login issue",
+			maxLength:  100,
+			bodyLength: 50,
+			expected:   "fix: resolved login issue",
+		},
+		{
+			name:       "Subject and start of body kept",
+			message:    "feat: squash merge of the billing work\n\n* add invoices\n* add receipts\n* add refunds and many other things",
+			maxLength:  80,
+			bodyLength: 30,
+			expected:   "feat: squash merge of the billing work\n\n* add invoices * add receipts",
+		},
+		{
+			name:       "Body limited by remaining length",
+			message:    "feat: squash merge\n\nthe quick brown fox jumps over the lazy dog",
+			maxLength:  40,
+			bodyLength: 100,
+			expected:   "feat: squash merge\n\nthe quick brown fox",
+		},
+		{
+			name:       "Long subject only",
+			message:    strings.Repeat("word ", 30) + "\n\nbody",
+			maxLength:  20,
+			bodyLength: 50,
+			expected:   "word word word word",
+		},
+		{
+			name:       "Multi-byte characters are not split",
+			message:    "docs: " + strings.Repeat("é", 20),
+			maxLength:  11,
+			bodyLength: 50,
+			expected:   "docs: éé",
+		},
+		{
+			name:       "Co-author trailers kept",
+			message:    "feat: squash merge\n\nthe quick brown fox jumps over the lazy dog\n\nCo-authored-by: Ada <ada@example.com>",
+			maxLength:  40,
+			bodyLength: 100,
+			expected:   "feat: squash merge\n\nthe quick brown fox\n\nCo-authored-by: Ada <ada@example.com>",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			truncated := TruncateCommitMessage(tc.message, tc.maxLength, tc.bodyLength)
+			if truncated != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, truncated)
+			}
+			if message, _ := haiku.SplitCoAuthors(truncated); len(message) > tc.maxLength {
+				t.Errorf("Expected at most %d bytes before trailers, got %d", tc.maxLength, len(message))
+			}
+		})
+	}
+}
+
+func TestPostHaikuLongCommitMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	longMessage := "feat: squash merge\n\n" + strings.Repeat("details ", 40)
+
+	tests := []struct {
+		name               string
+		options            *Options
+		expectedStatusCode int
+	}{
+		{
+			name:               "Truncated by default",
+			options:            nil,
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name:               "Rejected when configured",
+			options:            &Options{LengthStrategy: LengthStrategyReject},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "Accepted under a raised limit",
+			options:            &Options{MaxCommitLength: 1000, LengthStrategy: LengthStrategyReject},
+			expectedStatusCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "haiku"},
+			}
+
+			api := NewHaikuAPI(mockService, tc.options)
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			requestBody, _ := json.Marshal(haiku.HaikuCommitRequest{CommitMessage: longMessage})
+			req, _ := http.NewRequest("POST", "/haiku", bytes.NewBuffer(requestBody))
+			req.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+
+			if w.Code == http.StatusOK {
+				maxLength := MaxCommitLength
+				if tc.options != nil && tc.options.MaxCommitLength > 0 {
+					maxLength = tc.options.MaxCommitLength
+				}
+				if len(mockService.LastRequest.CommitMessage) > maxLength {
+					t.Errorf("Expected commit message of at most %d characters, got %d", maxLength, len(mockService.LastRequest.CommitMessage))
+				}
+				if !strings.HasPrefix(mockService.LastRequest.CommitMessage, "feat: squash merge") {
+					t.Errorf("Expected subject line to be kept, got %q", mockService.LastRequest.CommitMessage)
+				}
+			}
+		})
+	}
+}
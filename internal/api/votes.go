@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+// VoterHeader names the user casting a vote. With an API key it tells apart
+// the users voting through one integration; without one it is the only
+// thing telling voters apart.
+const VoterHeader = "X-Haiku-User"
+
+// postVote upvotes a stored haiku, once per voter, and returns it with its
+// votes.
+func (api *HaikuAPI) postVote(c *gin.Context) {
+	voter, ok := voterOf(c)
+	if !ok {
+		return
+	}
+
+	voted, err := api.options.Votes.Vote(c.Request.Context(), c.Param("id"), voter)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, voted)
+}
+
+// getTopHaiku lists the most voted haiku of the last ?days= days.
+func (api *HaikuAPI) getTopHaiku(c *gin.Context) {
+	days, ok := queryInt(c, "days", votes.DefaultDays, 1, votes.MaxDays)
+	if !ok {
+		return
+	}
+	limit, ok := queryInt(c, "limit", votes.DefaultTopSize, 1, votes.MaxTopSize)
+	if !ok {
+		return
+	}
+
+	top, err := api.options.Votes.Top(c.Request.Context(), days, limit)
+	if err != nil {
+		serviceError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, top)
+}
+
+// voterOf identifies who is voting by their API key and, when given, the
+// user claimed in VoterHeader, aborting the request when there is neither.
+func voterOf(c *gin.Context) (string, bool) {
+	user := c.GetHeader(VoterHeader)
+	if value, ok := c.Get(apiKeyContextKey); ok {
+		voter := "key:" + value.(keys.Key).ID
+		if user != "" {
+			voter += "/user:" + user
+		}
+		return voter, true
+	}
+	if user != "" {
+		return "user:" + user, true
+	}
+
+	detail := "votes need an API key or the " + VoterHeader + " header"
+	invalidRequest(c, detail, FieldError{Field: VoterHeader, Code: FieldRequired, Detail: detail})
+	return "", false
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/gin-gonic/gin"
+)
+
+const testHaikuID = "0123456789abcdef0123456789abcdef"
+
+type MockVoteService struct {
+	HaikuToReturn   votes.Haiku
+	TopToReturn     votes.Top
+	HistoryToReturn []votes.Saved
+	ErrorToReturn   error
+	LastID          string
+	LastVoter       string
+	LastDays        int
+	LastLimit       int
+	LastKeyID       string
+}
+
+func (m *MockVoteService) Vote(ctx context.Context, id string, voter string) (votes.Haiku, error) {
+	m.LastID = id
+	m.LastVoter = voter
+	return m.HaikuToReturn, m.ErrorToReturn
+}
+
+func (m *MockVoteService) Top(ctx context.Context, days int, limit int) (votes.Top, error) {
+	m.LastDays = days
+	m.LastLimit = limit
+	return m.TopToReturn, m.ErrorToReturn
+}
+
+func (m *MockVoteService) History(ctx context.Context, keyID string, fn func(saved votes.Saved) error) error {
+	m.LastKeyID = keyID
+	for _, saved := range m.HistoryToReturn {
+		if err := fn(saved); err != nil {
+			return err
+		}
+	}
+	return m.ErrorToReturn
+}
+
+func TestPostVote(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		requireAPIKey      bool
+		apiKey             string
+		user               string
+		mockError          error
+		expectedStatusCode int
+		expectedCode       string
+		expectedVoter      string
+	}{
+		{
+			name:               "User claim",
+			user:               "mona",
+			expectedStatusCode: http.StatusOK,
+			expectedVoter:      "user:mona",
+		},
+		{
+			name:               "API key",
+			requireAPIKey:      true,
+			apiKey:             "haiku-key",
+			expectedStatusCode: http.StatusOK,
+			expectedVoter:      "key:haiku",
+		},
+		{
+			name:               "User of an API key",
+			requireAPIKey:      true,
+			apiKey:             "haiku-key",
+			user:               "mona",
+			expectedStatusCode: http.StatusOK,
+			expectedVoter:      "key:haiku/user:mona",
+		},
+		{
+			name:               "No voter",
+			expectedStatusCode: http.StatusBadRequest,
+			expectedCode:       CodeInvalidRequest,
+		},
+		{
+			name:               "Already voted",
+			user:               "mona",
+			mockError:          votes.ErrAlreadyVoted,
+			expectedStatusCode: http.StatusConflict,
+			expectedCode:       CodeAlreadyVoted,
+		},
+		{
+			name:               "Awaiting moderation",
+			user:               "mona",
+			mockError:          votes.ErrNotPublished,
+			expectedStatusCode: http.StatusConflict,
+			expectedCode:       CodeNotPublished,
+		},
+		{
+			name:               "Unknown haiku",
+			user:               "mona",
+			mockError:          votes.ErrHaikuNotFound,
+			expectedStatusCode: http.StatusNotFound,
+			expectedCode:       CodeNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockVotes := &MockVoteService{
+				HaikuToReturn: votes.Haiku{ID: testHaikuID, Votes: 3},
+				ErrorToReturn: tc.mockError,
+			}
+			// The haiku routes around the vote must still resolve.
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{
+				Votes:         mockVotes,
+				Jobs:          &MockJobService{},
+				Daily:         &MockDailyService{},
+				Keys:          newTestKeyService(),
+				RequireAPIKey: tc.requireAPIKey,
+			})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("POST", "/haiku/"+testHaikuID+"/vote", nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+			if tc.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tc.apiKey)
+			}
+			if tc.user != "" {
+				req.Header.Set(VoterHeader, tc.user)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d: %s", tc.expectedStatusCode, w.Code, w.Body.String())
+			}
+			if tc.expectedCode != "" {
+				var p Problem
+				if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+					t.Fatalf("Failed to unmarshal problem: %v", err)
+				}
+				if p.Code != tc.expectedCode {
+					t.Errorf("Expected code %s, got %s", tc.expectedCode, p.Code)
+				}
+				return
+			}
+
+			if mockVotes.LastID != testHaikuID || mockVotes.LastVoter != tc.expectedVoter {
+				t.Errorf("Expected a vote by %q for %s, got %q for %s", tc.expectedVoter, testHaikuID, mockVotes.LastVoter, mockVotes.LastID)
+			}
+			var voted votes.Haiku
+			if err := json.Unmarshal(w.Body.Bytes(), &voted); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if voted.Votes != 3 {
+				t.Errorf("Expected 3 votes, got %d", voted.Votes)
+			}
+		})
+	}
+}
+
+func TestGetTopHaiku(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedStatusCode int
+		expectedDays       int
+		expectedLimit      int
+	}{
+		{name: "Defaults", expectedStatusCode: http.StatusOK, expectedDays: votes.DefaultDays, expectedLimit: votes.DefaultTopSize},
+		{name: "Requested", query: "?days=30&limit=5", expectedStatusCode: http.StatusOK, expectedDays: 30, expectedLimit: 5},
+		{name: "Too many days", query: "?days=365", expectedStatusCode: http.StatusBadRequest},
+		{name: "Limit too large", query: "?limit=1000", expectedStatusCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockVotes := &MockVoteService{TopToReturn: votes.Top{Haiku: []votes.Haiku{{ID: testHaikuID, Votes: 3}}}}
+			api := NewHaikuAPI(&MockHaikuService{}, &Options{Votes: mockVotes, Daily: &MockDailyService{}})
+
+			router := gin.New()
+			api.SetupRoutes(router)
+
+			req, err := http.NewRequest("GET", "/haiku/top"+tc.query, nil)
+			if err != nil {
+				t.Fatalf("Failed to create request: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tc.expectedStatusCode {
+				t.Fatalf("Expected status code %d, got %d", tc.expectedStatusCode, w.Code)
+			}
+			if tc.expectedStatusCode != http.StatusOK {
+				return
+			}
+
+			if mockVotes.LastDays != tc.expectedDays || mockVotes.LastLimit != tc.expectedLimit {
+				t.Errorf("Expected %d days and %d haiku, got %d and %d", tc.expectedDays, tc.expectedLimit, mockVotes.LastDays, mockVotes.LastLimit)
+			}
+			var top votes.Top
+			if err := json.Unmarshal(w.Body.Bytes(), &top); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(top.Haiku) != 1 || top.Haiku[0].ID != testHaikuID {
+				t.Errorf("Expected the top haiku, got %+v", top.Haiku)
+			}
+		})
+	}
+}
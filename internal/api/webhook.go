@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// handleWebhookEvent generates and posts the haiku for a verified delivery.
+func (api *HaikuAPI) handleWebhookEvent(c *gin.Context, webhooks WebhookService, event webhook.Event) {
+	// Webhook senders cannot shorten their commits, so long messages are
+	// always truncated rather than rejected.
+	if len(event.CommitMessage) > api.options.MaxCommitLength {
+		event.CommitMessage = TruncateCommitMessage(event.CommitMessage, api.options.MaxCommitLength, api.options.TruncatedBodyLength)
+	}
+
+	response, err := webhooks.HandleEvent(c.Request.Context(), event)
+	if err != nil {
+		api.webhookError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// webhookError maps a webhook service error to a response.
+func (api *HaikuAPI) webhookError(c *gin.Context, err error) {
+	if errors.Is(err, webhook.ErrIgnored) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ignored",
+		})
+		return
+	}
+
+	serviceError(c, err)
+}
@@ -0,0 +1,1468 @@
+// Package app wires the service's clients, stores and handlers together from
+// configuration. Each entry point (the Lambda handler today, later a server,
+// worker or CLI) builds one App and takes what it needs from it, so the
+// construction order and config mapping live in a single place.
+package app
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/api"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/audit"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bitbucket"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/cloudwatchlogs"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/comprehend"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/dynamo"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/eventbridge"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/github"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/gitlab"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/jira"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/lambda"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/polly"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/ses"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/sns"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/storage"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/config"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metrics"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/pii"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/redact"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/reporting"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/daily"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/discord"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/erasure"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/events"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/export"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/issues"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/jobs"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/notify"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/renga"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/shadow"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/slack"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/styles"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/teams"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/workflow"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension/plugin"
+	"github.com/gin-gonic/gin"
+)
+
+// App builds each dependency on first use and reuses it afterwards, so two
+// consumers of the Bedrock client or the artifact store share one instance.
+// App is meant to be assembled during startup and is not safe for concurrent
+// construction.
+type App struct {
+	aws    aws.Config
+	config config.Config
+
+	bedrockClient     *bedrock.BedrockClient
+	artifacts         *storage.S3Client
+	speech            *polly.PollyClient
+	gitHubClient      *github.GitHubClient
+	gitLabClient      *gitlab.GitLabClient
+	bitbucketClient   *bitbucket.BitbucketClient
+	lambdaClient      *lambda.LambdaClient
+	prompts           haiku.PromptProvider
+	moderator         moderation.Moderator
+	haikuService      *haiku.HaikuService
+	gitHubWebhooks    *webhook.WebhookService
+	gitLabWebhooks    *webhook.WebhookService
+	bitbucketWebhooks *webhook.WebhookService
+	slackService      *slack.SlackService
+	discordService    *discord.DiscordService
+	teamsService      *teams.TeamsService
+	scheduler         *scheduler
+	reporter          reporting.Reporter
+	reporterLoaded    bool
+	tracer            *timing.XRayTracer
+	tracerLoaded      bool
+	audit             *audit.Log
+	auditLoaded       bool
+	redactor          *redact.Writer
+	redactorLoaded    bool
+	pii               *pii.Redactor
+	piiLoaded         bool
+	haikuAPI          *api.HaikuAPI
+	router            *gin.Engine
+	routerOnce        sync.Once
+
+	responseCache       haiku.ResponseCache
+	responseCacheLoaded bool
+	jobs                *jobs.JobService
+	jobsLoaded          bool
+	workflows           *workflow.WorkflowService
+	daily               *daily.DailyService
+	votes               *votes.VoteService
+	voteStore           votes.Store
+	votesLoaded         bool
+	erasure             *erasure.ErasureService
+	dailyLoaded         bool
+	renga               *renga.RengaService
+	rengaLoaded         bool
+	exports             *export.ExportService
+	stats               *stats.StatsService
+	statsLoaded         bool
+	keys                *keys.KeyService
+	keysLoaded          bool
+	quotas              *quotas.QuotaService
+	styles              *styles.StyleService
+	digests             *digest.DigestService
+	notifications       *notify.NotifyService
+	notificationsLoaded bool
+	events              *events.EventService
+	eventsLoaded        bool
+	featureFlags        *flags.Store
+	featureFlagsLoaded  bool
+	shadow              *shadow.ShadowService
+	shadowLoaded        bool
+	issues              *issues.IssueService
+
+	provider        extension.Provider
+	providerLoaded  bool
+	notifiers       []extension.Notifier
+	notifiersLoaded bool
+
+	closers []func(context.Context) error
+}
+
+func New(cfg aws.Config, appConfig config.Config) *App {
+	return &App{
+		aws:    cfg,
+		config: appConfig,
+	}
+}
+
+// Config returns the application configuration the App was built from.
+func (a *App) Config() config.Config {
+	return a.config
+}
+
+// OnClose registers a cleanup function, run in reverse registration order by
+// Close. Subsystems with background work register their shutdown here.
+func (a *App) OnClose(closer func(context.Context) error) {
+	a.closers = append(a.closers, closer)
+}
+
+// Close runs every registered cleanup function and returns their errors joined.
+func (a *App) Close(ctx context.Context) error {
+	var errs []error
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		if err := a.closers[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	a.closers = nil
+	return errors.Join(errs...)
+}
+
+// BedrockClient returns the Bedrock client. With a canary model configured,
+// it sends that share of the configured model's requests to the canary.
+func (a *App) BedrockClient() *bedrock.BedrockClient {
+	if a.bedrockClient != nil {
+		return a.bedrockClient
+	}
+
+	opts := &bedrock.Options{}
+	if a.config.CanaryModelID != "" && a.config.CanaryPercent > 0 {
+		opts.Canary = &bedrock.Canary{
+			ModelID:       a.config.ModelID,
+			CanaryModelID: a.config.CanaryModelID,
+			Percent:       min(a.config.CanaryPercent, 100),
+		}
+	}
+
+	a.bedrockClient = bedrock.NewDefaultBedrockClient(a.aws, opts)
+	return a.bedrockClient
+}
+
+// Artifacts returns the S3 store for rendered artifacts, or nil when no
+// artifact bucket is configured.
+func (a *App) Artifacts() *storage.S3Client {
+	if a.artifacts == nil && a.config.ArtifactBucket != "" {
+		a.artifacts = storage.NewDefaultS3Client(a.aws, a.config.ArtifactBucket)
+	}
+	return a.artifacts
+}
+
+// ResponseCache returns the cache shared by equivalent haiku requests: in
+// memory, in DynamoDB, or in memory backed by DynamoDB. Cached haiku are
+// shared across callers, so it is nil unless either is configured.
+func (a *App) ResponseCache() haiku.ResponseCache {
+	if a.responseCacheLoaded {
+		return a.responseCache
+	}
+	a.responseCacheLoaded = true
+
+	var tiers []haiku.ResponseCache
+	if a.config.ResponseCacheSize > 0 {
+		tiers = append(tiers, haiku.NewMemoryResponseCache(a.config.ResponseCacheSize, a.config.ResponseCacheTTL))
+	}
+	if a.config.ResponseCacheTable != "" {
+		store := dynamo.NewDefaultDynamoClient(a.aws, a.config.ResponseCacheTable)
+		tiers = append(tiers, haiku.NewStoreResponseCache(store, a.config.ResponseCacheTTL))
+	}
+
+	switch len(tiers) {
+	case 0:
+	case 1:
+		a.responseCache = tiers[0]
+	default:
+		a.responseCache = haiku.NewTieredResponseCache(tiers...)
+	}
+	return a.responseCache
+}
+
+func (a *App) Speech() *polly.PollyClient {
+	if a.speech == nil {
+		a.speech = polly.NewDefaultPollyClient(a.aws)
+	}
+	return a.speech
+}
+
+// GitHubClient returns the GitHub App client, or nil when the app is not
+// configured or its private key cannot be parsed.
+func (a *App) GitHubClient() *github.GitHubClient {
+	if a.gitHubClient != nil || a.config.GitHubAppID == "" || a.config.GitHubAppPrivateKey == "" {
+		return a.gitHubClient
+	}
+
+	client, err := github.NewDefaultGitHubClient(a.config.GitHubAppID, a.config.GitHubAppPrivateKey)
+	if err != nil {
+		logging.Errorf("[APP] error configuring github app, haiku will not be posted: %v\n", err)
+		return nil
+	}
+
+	a.gitHubClient = client
+	return a.gitHubClient
+}
+
+// Issues returns the service looking up the issues commits reference, or nil
+// when neither Jira nor GitHub issue lookups are configured.
+func (a *App) Issues() *issues.IssueService {
+	if a.issues != nil {
+		return a.issues
+	}
+
+	opts := &issues.Options{}
+	if a.config.JiraURL != "" && a.config.JiraEmail != "" && a.config.JiraAPIToken != "" {
+		opts.Jira = jira.NewDefaultJiraClient(a.config.JiraURL, a.config.JiraEmail, a.config.JiraAPIToken)
+	}
+	if a.config.GitHubIssueLookup {
+		if client := a.GitHubClient(); client != nil {
+			opts.GitHub = client
+		}
+	}
+	if opts.Jira == nil && opts.GitHub == nil {
+		return nil
+	}
+	if a.config.IssueBindings == "" {
+		logging.Warnf("[APP] issue lookups are configured without ISSUE_BINDINGS, looking none up\n")
+		return nil
+	}
+	bindings, err := issues.ParseBindings(a.config.IssueBindings)
+	if err != nil {
+		logging.Errorf("[APP] error parsing issue bindings, looking no issues up: %v\n", err)
+		return nil
+	}
+	opts.Bindings = bindings
+
+	a.issues = issues.NewIssueService(opts)
+	return a.issues
+}
+
+// Moderator screens output with the default and configured word lists, and
+// with a Bedrock guardrail when one is configured.
+func (a *App) Moderator() moderation.Moderator {
+	if a.moderator != nil {
+		return a.moderator
+	}
+
+	words := append(slices.Clone(moderation.DefaultBlockedWords), a.config.ModerationBlockedWords...)
+	moderators := []moderation.Moderator{moderation.NewWordListModerator(words)}
+
+	if a.config.ModerationGuardrailID != "" {
+		moderators = append(moderators, moderation.NewGuardrailModerator(
+			a.BedrockClient(),
+			a.config.ModerationGuardrailID,
+			a.config.ModerationGuardrailVersion,
+		))
+	}
+
+	a.moderator = moderation.Chain(moderators...)
+	return a.moderator
+}
+
+// Prompts returns the SSM backed prompt templates, or nil when no parameter
+// path is configured or the experiment definition is invalid, in which case
+// the service falls back to its compiled-in prompts.
+func (a *App) Prompts() haiku.PromptProvider {
+	if a.prompts != nil || a.config.PromptParameterPath == "" {
+		return a.prompts
+	}
+
+	prompts, err := a.newPromptProvider()
+	if err != nil {
+		logging.Errorf("[APP] error configuring prompt templates, using defaults: %v\n", err)
+		return nil
+	}
+
+	a.prompts = prompts
+	return a.prompts
+}
+
+// newPromptProvider loads prompts from SSM. With an experiment configured, each
+// version is read from its own sub-path, e.g. "<path>/v2/system".
+func (a *App) newPromptProvider() (haiku.PromptProvider, error) {
+	if a.config.PromptExperiment == "" {
+		return a.newPromptStore(a.config.PromptParameterPath, prompt.DefaultVersion), nil
+	}
+
+	allocations, err := prompt.ParseAllocations(a.config.PromptExperiment)
+	if err != nil {
+		return nil, err
+	}
+
+	variants := make([]prompt.Variant, 0, len(allocations))
+	for _, allocation := range allocations {
+		variants = append(variants, prompt.Variant{
+			Allocation: allocation,
+			Prompts:    a.newPromptStore(a.versionPath(allocation.Version), allocation.Version),
+		})
+	}
+
+	return prompt.NewRegistry(variants...)
+}
+
+// newPromptStore loads the prompt templates at path from SSM, labelled with
+// version.
+func (a *App) newPromptStore(path string, version string) *prompt.Store {
+	defaults := haiku.DefaultPromptDefinitions()
+	defaults.Version = version
+
+	store := prompt.NewStore(prompt.NewDefaultSSMSource(a.aws, path), defaults, a.config.PromptRefreshInterval)
+	if err := store.Refresh(context.TODO()); err != nil {
+		logging.Errorf("[APP] error loading prompt templates from %s, using defaults: %v\n", path, err)
+	}
+	return store
+}
+
+// versionPath is where the prompt templates of version are stored.
+func (a *App) versionPath(version string) string {
+	return strings.TrimSuffix(a.config.PromptParameterPath, "/") + "/" + version
+}
+
+func (a *App) HaikuService() *haiku.HaikuService {
+	if a.haikuService != nil {
+		return a.haikuService
+	}
+
+	opts := &haiku.Options{
+		ModelID:           a.config.ModelID,
+		Moderator:         a.Moderator(),
+		ModerationRetries: a.config.ModerationRetries,
+		StructureRetries:  a.config.StructureRetries,
+		CommitContent:     a.commitContentMode(),
+		Fallback:          a.config.FallbackHaiku,
+	}
+
+	if redactor := a.Redactor(); redactor != nil {
+		opts.Redactor = redactor
+	}
+
+	if redactor := a.PIIRedactor(); redactor != nil {
+		opts.PII = redactor
+	}
+
+	if prompts := a.Prompts(); prompts != nil {
+		opts.Prompts = prompts
+	}
+
+	if responseCache := a.ResponseCache(); responseCache != nil {
+		opts.ResponseCache = responseCache
+	}
+
+	if service := a.Stats(); service != nil {
+		opts.Usage = service
+	}
+
+	if service := a.Votes(); service != nil {
+		opts.Archive = service
+	}
+
+	if service := a.Styles(); service != nil {
+		opts.Styles = service
+	}
+
+	if store := a.Flags(); store != nil {
+		opts.Flags = store
+	}
+
+	if service := a.Shadow(); service != nil {
+		opts.Shadow = service
+	}
+
+	if service := a.Notifications(); service != nil {
+		opts.Notifier = service
+	}
+
+	if service := a.Events(); service != nil {
+		opts.Events = service
+	}
+
+	if service := a.Issues(); service != nil {
+		opts.Issues = service
+	}
+
+	if artifacts := a.Artifacts(); artifacts != nil {
+		opts.Artifacts = artifacts
+		opts.ArtifactURLTTL = a.config.ArtifactURLTTL
+		opts.Speech = a.Speech()
+		opts.VoiceID = a.config.VoiceID
+	}
+
+	if a.config.IllustrationModelID != "" {
+		opts.Images = a.BedrockClient()
+		opts.ImageModelID = a.config.IllustrationModelID
+	}
+
+	var modelClient haiku.BedrockClient = a.BedrockClient()
+	if provider := a.Provider(); provider != nil {
+		modelClient = haiku.NewProviderClient(provider)
+	}
+
+	a.haikuService = haiku.NewHaikuService(modelClient, opts)
+	return a.haikuService
+}
+
+// Provider returns the configured provider plugin, or nil when none is
+// configured or it fails to start, in which case haiku are generated with
+// Bedrock.
+func (a *App) Provider() extension.Provider {
+	if a.providerLoaded {
+		return a.provider
+	}
+	a.providerLoaded = true
+
+	if a.config.PluginProvider == "" {
+		return nil
+	}
+
+	client := a.startPlugin(a.config.PluginProvider)
+	if client == nil {
+		return nil
+	}
+	if a.provider = client.Provider(); a.provider == nil {
+		logging.Warnf("[APP] plugin %s does not offer a provider, using bedrock\n", a.config.PluginProvider)
+	}
+	return a.provider
+}
+
+// Notifiers returns the configured notifier plugins. Plugins that fail to start
+// or do not offer a notifier are skipped.
+func (a *App) Notifiers() []extension.Notifier {
+	if a.notifiersLoaded {
+		return a.notifiers
+	}
+	a.notifiersLoaded = true
+
+	for _, path := range a.config.PluginNotifiers {
+		client := a.startPlugin(path)
+		if client == nil {
+			continue
+		}
+
+		notifier := client.Notifier()
+		if notifier == nil {
+			logging.Warnf("[APP] plugin %s does not offer a notifier, skipping\n", path)
+			continue
+		}
+		a.notifiers = append(a.notifiers, notifier)
+	}
+	return a.notifiers
+}
+
+// startPlugin launches a plugin and stops it on Close. Plugins only see the
+// host environment variables listed in PluginEnv.
+func (a *App) startPlugin(path string) *plugin.Client {
+	var env []string
+	for _, key := range a.config.PluginEnv {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+
+	client, err := plugin.Start(context.TODO(), plugin.Config{Path: path, Env: env})
+	if err != nil {
+		logging.Errorf("[APP] error starting plugin %s: %v\n", path, err)
+		return nil
+	}
+
+	a.OnClose(client.Close)
+	return client
+}
+
+// GitLabClient returns the GitLab client, or nil when no access token is
+// configured.
+func (a *App) GitLabClient() *gitlab.GitLabClient {
+	if a.gitLabClient == nil && a.config.GitLabToken != "" {
+		a.gitLabClient = gitlab.NewDefaultGitLabClient(a.config.GitLabURL, a.config.GitLabToken)
+	}
+	return a.gitLabClient
+}
+
+// GitHubWebhooks returns the service handling GitHub webhooks. Haiku are
+// posted back to GitHub when the GitHub App is configured.
+func (a *App) GitHubWebhooks() *webhook.WebhookService {
+	if a.gitHubWebhooks != nil {
+		return a.gitHubWebhooks
+	}
+
+	var commenter webhook.Commenter
+	if client := a.GitHubClient(); client != nil {
+		commenter = webhook.NewGitHubCommenter(client)
+	}
+
+	a.gitHubWebhooks = webhook.NewWebhookService(a.HaikuService(), &webhook.Options{
+		Commenter: commenter,
+		Notifiers: a.Notifiers(),
+		MoodRules: a.MoodRules(),
+
+		MergeQueueBranches: a.config.MergeQueueBranches,
+		IncludeMergeQueues: a.config.MergeQueueHaiku,
+	})
+	return a.gitHubWebhooks
+}
+
+// GitLabWebhooks returns the service handling GitLab webhooks. Haiku are
+// posted back to GitLab when an access token is configured.
+func (a *App) GitLabWebhooks() *webhook.WebhookService {
+	if a.gitLabWebhooks != nil {
+		return a.gitLabWebhooks
+	}
+
+	var commenter webhook.Commenter
+	if client := a.GitLabClient(); client != nil {
+		commenter = webhook.NewGitLabCommenter(client)
+	}
+
+	a.gitLabWebhooks = webhook.NewWebhookService(a.HaikuService(), &webhook.Options{
+		Commenter: commenter,
+		Notifiers: a.Notifiers(),
+		MoodRules: a.MoodRules(),
+
+		MergeQueueBranches: a.config.MergeQueueBranches,
+		IncludeMergeQueues: a.config.MergeQueueHaiku,
+	})
+	return a.gitLabWebhooks
+}
+
+// MoodRules returns the configured webhook mood rules. Invalid rules are
+// logged and none are applied.
+func (a *App) MoodRules() []webhook.MoodRule {
+	if a.config.MoodRules == "" {
+		return nil
+	}
+
+	rules, err := webhook.ParseMoodRules(a.config.MoodRules)
+	if err != nil {
+		logging.Errorf("[APP] error parsing mood rules, using the default mood: %v\n", err)
+		return nil
+	}
+	return rules
+}
+
+// BitbucketClient returns the Bitbucket client, or nil when no access token is
+// configured.
+func (a *App) BitbucketClient() *bitbucket.BitbucketClient {
+	if a.bitbucketClient == nil && a.config.BitbucketToken != "" {
+		a.bitbucketClient = bitbucket.NewDefaultBitbucketClient(a.config.BitbucketToken)
+	}
+	return a.bitbucketClient
+}
+
+// BitbucketWebhooks returns the service handling Bitbucket webhooks. Haiku are
+// posted back to Bitbucket when an access token is configured.
+func (a *App) BitbucketWebhooks() *webhook.WebhookService {
+	if a.bitbucketWebhooks != nil {
+		return a.bitbucketWebhooks
+	}
+
+	var commenter webhook.Commenter
+	if client := a.BitbucketClient(); client != nil {
+		commenter = webhook.NewBitbucketCommenter(client)
+	}
+
+	a.bitbucketWebhooks = webhook.NewWebhookService(a.HaikuService(), &webhook.Options{
+		Commenter: commenter,
+		Notifiers: a.Notifiers(),
+		MoodRules: a.MoodRules(),
+
+		MergeQueueBranches: a.config.MergeQueueBranches,
+		IncludeMergeQueues: a.config.MergeQueueHaiku,
+	})
+	return a.bitbucketWebhooks
+}
+
+// LambdaClient returns a client for invoking this function asynchronously, or
+// nil when not running on Lambda.
+func (a *App) LambdaClient() *lambda.LambdaClient {
+	if a.lambdaClient == nil && a.config.LambdaFunctionName != "" {
+		a.lambdaClient = lambda.NewDefaultLambdaClient(a.aws, a.config.LambdaFunctionName)
+	}
+	return a.lambdaClient
+}
+
+func (a *App) SlackService() *slack.SlackService {
+	if a.slackService == nil {
+		a.slackService = slack.NewDefaultSlackService(a.HaikuService())
+	}
+	return a.slackService
+}
+
+func (a *App) DiscordService() *discord.DiscordService {
+	if a.discordService == nil {
+		a.discordService = discord.NewDefaultDiscordService(a.HaikuService())
+	}
+	return a.discordService
+}
+
+func (a *App) TeamsService() *teams.TeamsService {
+	if a.teamsService == nil {
+		a.teamsService = teams.NewTeamsService(a.HaikuService())
+	}
+	return a.teamsService
+}
+
+// SlackCommands returns the dispatcher that responds to acknowledged Slack
+// slash commands.
+func (a *App) SlackCommands() api.SlackDispatcher {
+	return &slackDispatcher{scheduler: a.deferredScheduler()}
+}
+
+// DiscordInteractions returns the dispatcher that responds to deferred Discord
+// interactions.
+func (a *App) DiscordInteractions() api.DiscordDispatcher {
+	return &discordDispatcher{scheduler: a.deferredScheduler()}
+}
+
+// deferredScheduler hands deferred work to an asynchronous invocation on
+// Lambda, and to a goroutine elsewhere.
+func (a *App) deferredScheduler() *scheduler {
+	if a.scheduler != nil {
+		return a.scheduler
+	}
+
+	a.scheduler = &scheduler{handle: a.HandleDeferred}
+	if client := a.LambdaClient(); client != nil {
+		a.scheduler.invoker = client
+	}
+	return a.scheduler
+}
+
+// Jobs returns the service running haiku requests in the background, or nil
+// when there is nowhere to keep jobs. Lambda instances don't share memory, so
+// there a job table is required. Finished jobs are posted to callback URLs
+// only when a callback secret is configured to sign them.
+func (a *App) Jobs() *jobs.JobService {
+	if a.jobsLoaded {
+		return a.jobs
+	}
+	a.jobsLoaded = true
+
+	var store jobs.Store
+	switch {
+	case a.config.JobTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.JobTable)
+	case a.config.LambdaFunctionName == "":
+		store = jobs.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	opts := &jobs.Options{DescribeError: describeJobError, TTL: a.config.JobTTL}
+	if a.config.CallbackSecret != "" {
+		opts.Callbacks = jobs.NewDefaultCallbackSender([]byte(a.config.CallbackSecret))
+	}
+	if service := a.Quotas(); service != nil {
+		opts.Quotas = service
+	}
+
+	a.jobs = jobs.NewJobService(a.HaikuService(), store, &jobDispatcher{scheduler: a.deferredScheduler()}, opts)
+	return a.jobs
+}
+
+// Daily returns the service writing the haiku of the day, or nil when it
+// can't be shared. Lambda instances don't share memory, so there a table is
+// required for every caller to get the same haiku.
+func (a *App) Daily() *daily.DailyService {
+	if a.dailyLoaded {
+		return a.daily
+	}
+	a.dailyLoaded = true
+
+	var store daily.Store
+	switch {
+	case a.config.DailyHaikuTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.DailyHaikuTable)
+	case a.config.LambdaFunctionName == "":
+		store = daily.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	a.daily = daily.NewDailyService(a.HaikuService(), store, nil)
+	return a.daily
+}
+
+// Renga returns the service chaining repositories' commit haiku into renga,
+// or nil when they can't be shared. Lambda instances don't share memory, so
+// there a table is required for every verse to join the same chain. Verses
+// are kept as long as served haiku.
+func (a *App) Renga() *renga.RengaService {
+	if a.rengaLoaded {
+		return a.renga
+	}
+	a.rengaLoaded = true
+
+	var store renga.Store
+	switch {
+	case a.config.RengaTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.RengaTable)
+	case a.config.LambdaFunctionName == "":
+		store = renga.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	a.renga = renga.NewRengaService(a.HaikuService(), store, &renga.Options{Retention: a.config.HaikuRetention})
+	return a.renga
+}
+
+// Votes returns the service keeping served haiku and their votes, or nil when
+// they can't be shared. Lambda instances don't share memory, so there a vote
+// table is required. Votes are credited in usage statistics when kept, and
+// haiku are screened with the publication guardrail, when one is configured,
+// before they are published.
+func (a *App) Votes() *votes.VoteService {
+	if a.votesLoaded {
+		return a.votes
+	}
+	a.votesLoaded = true
+
+	var store votes.Store
+	switch {
+	case a.config.VoteTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.VoteTable)
+	case a.config.LambdaFunctionName == "":
+		store = votes.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	opts := &votes.Options{Retention: a.config.HaikuRetention}
+	if service := a.Stats(); service != nil {
+		opts.Recorder = service
+	}
+	if a.config.PublicationGuardrailID != "" {
+		opts.Moderator = moderation.NewGuardrailModerator(
+			a.BedrockClient(),
+			a.config.PublicationGuardrailID,
+			a.config.PublicationGuardrailVersion,
+		)
+		opts.Scheduler = &moderationDispatcher{scheduler: a.deferredScheduler()}
+	}
+
+	a.voteStore = store
+	a.votes = votes.NewVoteService(store, opts)
+	return a.votes
+}
+
+// Erasure returns the service erasing the haiku kept for a commit author, or
+// nil when haiku aren't kept or no admin token is configured to ask for it.
+// Audit records are kept alongside the haiku, and erased haiku are removed
+// from the response cache and rewritten out of exports when those are on, as
+// the author is from renga, shadow results and statistics.
+func (a *App) Erasure() *erasure.ErasureService {
+	if a.erasure != nil || a.config.AdminToken == "" {
+		return a.erasure
+	}
+
+	service := a.Votes()
+	if service == nil {
+		return nil
+	}
+	opts := &erasure.Options{}
+	if cache := a.ResponseCache(); cache != nil {
+		opts.Cache = cache
+	}
+	if exports := a.Exports(); exports != nil {
+		opts.Exporter = exports
+	}
+	if renga := a.Renga(); renga != nil {
+		opts.Renga = renga
+	}
+	if shadow := a.Shadow(); shadow != nil {
+		opts.Shadow = shadow
+	}
+	if stats := a.Stats(); stats != nil {
+		opts.Stats = stats
+	}
+	a.erasure = erasure.NewErasureService(service, a.voteStore, opts)
+	return a.erasure
+}
+
+// Exports returns the service exporting kept haiku to S3, or nil when no
+// export bucket is configured or haiku aren't kept.
+func (a *App) Exports() *export.ExportService {
+	if a.exports != nil || a.config.ExportBucket == "" {
+		return a.exports
+	}
+
+	source := a.Votes()
+	if source == nil {
+		return nil
+	}
+	a.exports = export.NewExportService(source, storage.NewDefaultS3Client(a.aws, a.config.ExportBucket), nil)
+	return a.exports
+}
+
+// Stats returns the service keeping usage statistics, or nil when no stats
+// token is configured to read them. Lambda instances don't share memory, so
+// there a stats table is required.
+func (a *App) Stats() *stats.StatsService {
+	if a.statsLoaded {
+		return a.stats
+	}
+	a.statsLoaded = true
+
+	var store stats.Store
+	switch {
+	case a.config.StatsToken == "":
+		return nil
+	case a.config.StatsTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.StatsTable)
+	case a.config.LambdaFunctionName == "":
+		store = stats.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	a.stats = stats.NewStatsService(store, &stats.Options{Aggregated: a.config.StatsFromStream})
+	return a.stats
+}
+
+// Keys returns the service issuing and checking API keys, or nil when keys
+// are neither managed nor required. Lambda instances don't share memory, so
+// there a key table is required.
+func (a *App) Keys() *keys.KeyService {
+	if a.keysLoaded {
+		return a.keys
+	}
+	a.keysLoaded = true
+
+	var store keys.Store
+	switch {
+	case a.config.AdminToken == "" && !a.config.RequireAPIKey:
+		return nil
+	case a.config.KeyTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.KeyTable)
+	case a.config.LambdaFunctionName == "":
+		store = keys.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	a.keys = keys.NewKeyService(store)
+	return a.keys
+}
+
+// Quotas returns the service enforcing API key quotas, or nil when keys aren't
+// required. Usage is counted in the key table, or in memory alongside keys
+// kept in memory off Lambda; on Lambda, Validate refuses required keys
+// without the table.
+func (a *App) Quotas() *quotas.QuotaService {
+	if a.quotas != nil || !a.config.RequireAPIKey || a.Keys() == nil {
+		return a.quotas
+	}
+
+	var store quotas.Store
+	switch {
+	case a.config.KeyTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.KeyTable)
+	case a.config.LambdaFunctionName == "":
+		store = quotas.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	opts := &quotas.Options{}
+	if service := a.Events(); service != nil {
+		opts.Events = service
+	}
+
+	a.quotas = quotas.NewQuotaService(store, opts)
+	return a.quotas
+}
+
+// Styles returns the service keeping tenants' style guides, or nil when the
+// admin API that manages them is off. Guides are kept in the key table, or in
+// memory alongside keys kept in memory.
+func (a *App) Styles() *styles.StyleService {
+	if a.styles != nil || a.config.AdminToken == "" || a.Keys() == nil {
+		return a.styles
+	}
+
+	var store styles.Store = styles.NewMemoryStore()
+	if a.config.KeyTable != "" {
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.KeyTable)
+	}
+
+	a.styles = styles.NewStyleService(store)
+	return a.styles
+}
+
+// Digests returns the service emailing weekly haiku digests, or nil when no
+// sender is configured, haiku aren't kept, or the admin API that manages
+// subscriptions is off. Subscriptions are kept in the key table, or in memory
+// alongside keys kept in memory.
+func (a *App) Digests() *digest.DigestService {
+	if a.digests != nil || a.config.DigestSender == "" || a.config.AdminToken == "" || a.Keys() == nil {
+		return a.digests
+	}
+
+	source := a.Votes()
+	if source == nil {
+		return nil
+	}
+	var store digest.Store = digest.NewMemoryStore()
+	if a.config.KeyTable != "" {
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.KeyTable)
+	}
+
+	a.digests = digest.NewDigestService(store, source, ses.NewDefaultSESClient(a.aws, a.config.DigestSender), &digest.Options{
+		PublicURL: a.config.PublicURL,
+	})
+	return a.digests
+}
+
+// Flags returns the feature flags, or nil when none are set in the
+// environment and no AppConfig profile is configured, in which case every
+// flagged behaviour is on. Flags in the environment that can't be parsed are
+// logged and ignored.
+func (a *App) Flags() *flags.Store {
+	if a.featureFlagsLoaded {
+		return a.featureFlags
+	}
+	a.featureFlagsLoaded = true
+
+	var source flags.Source
+	if a.config.AppConfigApplication != "" && a.config.AppConfigEnvironment != "" && a.config.AppConfigProfile != "" {
+		source = flags.NewDefaultAppConfigSource(a.config.AppConfigApplication, a.config.AppConfigEnvironment, a.config.AppConfigProfile)
+	}
+	if source == nil && a.config.FeatureFlags == "" {
+		return nil
+	}
+
+	var fallback flags.Flags
+	if a.config.FeatureFlags != "" {
+		parsed, err := flags.Parse([]byte(a.config.FeatureFlags))
+		if err != nil {
+			logging.Errorf("[APP] error parsing feature flags, ignoring them: %v\n", err)
+		}
+		fallback = parsed
+	}
+
+	a.featureFlags = flags.NewStore(fallback, &flags.Options{
+		Source:   source,
+		Stage:    a.config.Stage,
+		Interval: a.config.FeatureFlagRefreshInterval,
+	})
+	return a.featureFlags
+}
+
+// Shadow returns the service writing a share of haiku again with an alternate
+// prompt version or model, or nil when shadowing is off or there is nowhere to
+// keep its results. Lambda instances don't share memory, so there a shadow
+// table is required. Without an alternate prompt version the served prompt is
+// used, and without an alternate model the served model.
+func (a *App) Shadow() *shadow.ShadowService {
+	if a.shadowLoaded {
+		return a.shadow
+	}
+	a.shadowLoaded = true
+
+	if a.config.ShadowPercent <= 0 || (a.config.ShadowPromptVersion == "" && a.config.ShadowModelID == "") {
+		return nil
+	}
+
+	var store shadow.Store
+	switch {
+	case a.config.ShadowTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.ShadowTable)
+	case a.config.LambdaFunctionName == "":
+		store = shadow.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	// Results hold the served haiku, so they are kept no longer than it is.
+	opts := &shadow.Options{
+		ModelID:     a.config.ModelID,
+		Percent:     min(a.config.ShadowPercent, 100),
+		DailyTokens: int64(a.config.ShadowDailyTokens),
+		Retention:   min(shadow.DefaultRetention, a.config.HaikuRetention),
+	}
+	if a.config.ShadowModelID != "" {
+		opts.ModelID = a.config.ShadowModelID
+	}
+	if a.config.ShadowPromptVersion != "" && a.config.PromptParameterPath == "" {
+		logging.Warnf("[APP] shadow prompt version %s needs a prompt parameter path, using the served prompt\n", a.config.ShadowPromptVersion)
+	}
+	if a.config.ShadowPromptVersion != "" && a.config.PromptParameterPath != "" {
+		opts.Prompts = a.newPromptStore(a.versionPath(a.config.ShadowPromptVersion), a.config.ShadowPromptVersion)
+	} else if prompts := a.Prompts(); prompts != nil {
+		opts.Prompts = prompts
+	}
+
+	a.shadow = shadow.NewShadowService(a.BedrockClient(), store, &shadowDispatcher{scheduler: a.deferredScheduler()}, opts)
+	return a.shadow
+}
+
+// Notifications returns the service pushing newly written haiku to Slack
+// incoming webhooks, or nil when none are configured or there is nowhere to
+// queue haiku. Lambda instances don't share memory, so there a table is
+// required for the scheduled flush to see every queued haiku. Webhooks that
+// can't be parsed are logged and none are used.
+func (a *App) Notifications() *notify.NotifyService {
+	if a.notificationsLoaded {
+		return a.notifications
+	}
+	a.notificationsLoaded = true
+
+	if a.config.SlackNotifyWebhooks == "" {
+		return nil
+	}
+	webhooks, err := notify.ParseWebhooks(a.config.SlackNotifyWebhooks)
+	if err != nil {
+		logging.Errorf("[APP] error parsing slack notification webhooks, pushing none: %v\n", err)
+		return nil
+	}
+
+	var store notify.Store
+	switch {
+	case a.config.NotifyTable != "":
+		store = dynamo.NewDefaultDynamoClient(a.aws, a.config.NotifyTable)
+	case a.config.LambdaFunctionName == "":
+		store = notify.NewMemoryStore()
+	default:
+		return nil
+	}
+
+	a.notifications = notify.NewDefaultNotifyService(webhooks, store, nil)
+	return a.notifications
+}
+
+// Events returns the service publishing domain events to SNS and EventBridge,
+// or nil when neither a topic nor a bus is configured. A topic or bus that
+// can't be used is logged, and no events are published to it.
+func (a *App) Events() *events.EventService {
+	if a.eventsLoaded {
+		return a.events
+	}
+	a.eventsLoaded = true
+
+	var publishers []events.Publisher
+	if a.config.HaikuEventsTopicARN != "" {
+		client, err := sns.NewDefaultSNSClient(a.aws, a.config.HaikuEventsTopicARN)
+		if err != nil {
+			logging.Errorf("[APP] error configuring the events topic, publishing none to it: %v\n", err)
+		} else {
+			publishers = append(publishers, events.NewSNSPublisher(client))
+		}
+	}
+	if a.config.EventBusName != "" {
+		client, err := eventbridge.NewDefaultEventBridgeClient(a.aws, a.config.EventBusName)
+		if err != nil {
+			logging.Errorf("[APP] error configuring the event bus, putting none on it: %v\n", err)
+		} else {
+			publishers = append(publishers, events.NewEventBridgePublisher(client))
+		}
+	}
+	if len(publishers) == 0 {
+		return nil
+	}
+
+	a.events = events.NewEventService(publishers...)
+	return a.events
+}
+
+// runJob runs a scheduled haiku job.
+func (a *App) runJob(ctx context.Context, id string) error {
+	service := a.Jobs()
+	if service == nil {
+		return errors.New("background jobs are not configured")
+	}
+	return service.Run(ctx, id)
+}
+
+// moderateHaiku runs the scheduled moderation of a kept haiku. A haiku whose
+// moderation fails stays unpublished.
+func (a *App) moderateHaiku(ctx context.Context, id string) error {
+	service := a.Votes()
+	if service == nil {
+		return errors.New("votes are not configured")
+	}
+	return service.Moderate(ctx, id)
+}
+
+// runShadow writes the shadow of a served haiku.
+func (a *App) runShadow(ctx context.Context, request haiku.ShadowRequest) error {
+	service := a.Shadow()
+	if service == nil {
+		return errors.New("shadow haiku are not configured")
+	}
+	return service.Run(ctx, request)
+}
+
+// Workflows returns the handlers for Step Functions tasks. Posting to GitHub
+// fails unless the GitHub App is configured.
+func (a *App) Workflows() *workflow.WorkflowService {
+	if a.workflows != nil {
+		return a.workflows
+	}
+
+	var github webhook.Commenter
+	if client := a.GitHubClient(); client != nil {
+		github = webhook.NewGitHubCommenter(client)
+	}
+
+	a.workflows = workflow.NewWorkflowService(a.HaikuService(), a.Moderator(), github)
+	return a.workflows
+}
+
+// describeJobError describes a failed job as the API would have described the
+// failed request.
+func describeJobError(err error) jobs.Error {
+	p := api.ServiceProblem(err)
+	return jobs.Error{Status: p.Status, Code: p.Code, Title: p.Title, Detail: p.Detail}
+}
+
+// DiscordPublicKey returns the Discord application's public key, or nil when
+// none is configured or it is not a hex encoded Ed25519 key.
+func (a *App) DiscordPublicKey() ed25519.PublicKey {
+	if a.config.DiscordPublicKey == "" {
+		return nil
+	}
+
+	key, err := hex.DecodeString(a.config.DiscordPublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		logging.Warnf("[APP] invalid discord public key, discord interactions disabled\n")
+		return nil
+	}
+	return ed25519.PublicKey(key)
+}
+
+// TeamsWebhookSecret returns the decoded Teams security token, or nil when none
+// is configured or it is not base64 encoded.
+func (a *App) TeamsWebhookSecret() []byte {
+	if a.config.TeamsWebhookSecret == "" {
+		return nil
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(a.config.TeamsWebhookSecret)
+	if err != nil || len(secret) == 0 {
+		logging.Warnf("[APP] invalid teams webhook secret, teams webhook disabled\n")
+		return nil
+	}
+	return secret
+}
+
+func (a *App) HaikuAPI() *api.HaikuAPI {
+	if a.haikuAPI != nil {
+		return a.haikuAPI
+	}
+
+	opts := &api.Options{
+		MaxCommitLength:     a.config.MaxCommitLength,
+		LengthStrategy:      api.LengthStrategy(a.config.CommitLengthStrategy),
+		TruncatedBodyLength: a.config.TruncatedBodyLength,
+	}
+
+	if a.config.GitHubWebhookSecret != "" {
+		opts.GitHubWebhooks = a.GitHubWebhooks()
+		opts.GitHubWebhookSecret = a.config.GitHubWebhookSecret
+	}
+	if a.config.GitLabWebhookToken != "" {
+		opts.GitLabWebhooks = a.GitLabWebhooks()
+		opts.GitLabWebhookToken = a.config.GitLabWebhookToken
+	}
+	if a.config.BitbucketWebhookSecret != "" {
+		opts.BitbucketWebhooks = a.BitbucketWebhooks()
+		opts.BitbucketWebhookSecret = a.config.BitbucketWebhookSecret
+	}
+	if a.config.SlackSigningSecret != "" {
+		opts.SlackCommands = a.SlackCommands()
+		opts.SlackSigningSecret = a.config.SlackSigningSecret
+	}
+	if key := a.DiscordPublicKey(); key != nil {
+		opts.DiscordInteractions = a.DiscordInteractions()
+		opts.DiscordPublicKey = key
+	}
+	if secret := a.TeamsWebhookSecret(); secret != nil {
+		opts.TeamsMessages = a.TeamsService()
+		opts.TeamsWebhookSecret = secret
+	}
+	if service := a.Jobs(); service != nil {
+		opts.Jobs = service
+	}
+	if service := a.Daily(); service != nil {
+		opts.Daily = service
+	}
+	if service := a.Renga(); service != nil {
+		opts.Renga = service
+	}
+	if service := a.Votes(); service != nil {
+		opts.Votes = service
+	}
+	if service := a.Stats(); service != nil {
+		opts.Stats = service
+		opts.StatsToken = a.config.StatsToken
+		opts.Leaderboard = service
+	}
+	// Without a key service, required keys can't be checked and every haiku
+	// request is refused.
+	if service := a.Keys(); service != nil {
+		opts.Keys = service
+		opts.AdminToken = a.config.AdminToken
+	}
+	if service := a.Styles(); service != nil {
+		opts.Styles = service
+	}
+	if service := a.Digests(); service != nil {
+		opts.Digests = service
+	}
+	if service := a.Erasure(); service != nil {
+		opts.Erasure = service
+		opts.AdminToken = a.config.AdminToken
+	}
+	opts.RequireAPIKey = a.config.RequireAPIKey
+	if service := a.Quotas(); service != nil {
+		opts.Quotas = service
+	}
+	if store := a.Flags(); store != nil {
+		opts.Flags = store
+	}
+	opts.Reporter = a.Reporter()
+	if a.config.ErrorMetrics {
+		opts.Metrics = metrics.NewEmitter(os.Stdout)
+	}
+	if log := a.Audit(); log != nil {
+		opts.Audit = log
+	}
+	if tracer := a.Tracer(); tracer != nil {
+		opts.Tracer = tracer
+	}
+	opts.RetryBudget = a.BedrockClient().RetryBudget()
+
+	a.haikuAPI = api.NewHaikuAPI(a.HaikuService(), opts)
+	return a.haikuAPI
+}
+
+// Tracer returns the tracer sending each stage of a request to X-Ray, or nil
+// when active tracing is off.
+func (a *App) Tracer() *timing.XRayTracer {
+	if a.tracerLoaded {
+		return a.tracer
+	}
+	a.tracerLoaded = true
+
+	if a.config.XRayDaemonAddress == "" {
+		return nil
+	}
+	tracer, err := timing.NewXRayTracer(a.config.XRayDaemonAddress)
+	if err != nil {
+		logging.Errorf("[APP] error configuring X-Ray, tracing nothing: %v\n", err)
+		return nil
+	}
+	a.tracer = tracer
+	return a.tracer
+}
+
+// Reporter returns the sink for panics and 5xx responses, or nil when error
+// reporting is off. A Sentry reporter without a usable DSN falls back to
+// structured log lines, so errors are never silently dropped.
+func (a *App) Reporter() reporting.Reporter {
+	if a.reporterLoaded {
+		return a.reporter
+	}
+	a.reporterLoaded = true
+
+	switch a.config.ErrorReporting {
+	case "off":
+		return nil
+	case "sentry":
+		reporter, err := reporting.NewDefaultSentryReporter(a.config.SentryDSN)
+		if err == nil {
+			a.reporter = reporter
+			return a.reporter
+		}
+		logging.Errorf("[APP] error configuring sentry, reporting errors to the log instead: %v\n", err)
+	case "log", "":
+	default:
+		logging.Warnf("[APP] unknown ERROR_REPORTING %q, reporting errors to the log\n", a.config.ErrorReporting)
+	}
+
+	a.reporter = reporting.NewLogReporter(os.Stdout)
+	return a.reporter
+}
+
+// Audit returns the audit log, kept in a stream of its own in the
+// AUDIT_LOG_GROUP log group, or in the function's log when no group is
+// configured. Each instance of the function chains its records in a stream
+// named for the day it started.
+func (a *App) Audit() *audit.Log {
+	if a.auditLoaded {
+		return a.audit
+	}
+	a.auditLoaded = true
+
+	stream, err := audit.NewStreamName(time.Now())
+	if err != nil {
+		logging.Errorf("[APP] error naming the audit stream, auditing nothing: %v\n", err)
+		return nil
+	}
+
+	var sink audit.Sink = audit.NewWriterSink(os.Stdout)
+	if a.config.AuditLogGroup != "" {
+		client, err := cloudwatchlogs.NewDefaultLogsClient(a.aws, a.config.AuditLogGroup)
+		if err != nil {
+			logging.Errorf("[APP] error configuring the audit log group, auditing to the log instead: %v\n", err)
+		} else {
+			sink = audit.NewCloudWatchSink(client, stream)
+		}
+	}
+
+	a.audit = audit.NewLog(sink, stream)
+	return a.audit
+}
+
+// commitContentMode returns how commit messages appear in logs. An unknown
+// LOG_COMMIT_CONTENT hashes them, rather than risk logging them in full.
+func (a *App) commitContentMode() redact.Mode {
+	mode := redact.Mode(a.config.LogCommitContent)
+	switch {
+	case mode.IsValid():
+		return mode
+	case mode != "":
+		logging.Warnf("[APP] unknown LOG_COMMIT_CONTENT %q, hashing commit content\n", a.config.LogCommitContent)
+	case a.config.LogFullPrompts:
+		return redact.ModeFull
+	}
+	return redact.ModeHash
+}
+
+// Redactor returns the log output that hides the commit messages being
+// written about from every log line, or nil when they are logged in full.
+func (a *App) Redactor() *redact.Writer {
+	if a.redactorLoaded {
+		return a.redactor
+	}
+	a.redactorLoaded = true
+
+	if mode := a.commitContentMode(); mode != redact.ModeFull {
+		a.redactor = redact.NewWriter(os.Stderr, mode)
+	}
+	return a.redactor
+}
+
+// SetupLogging sends log lines at or above LOG_LEVEL to w, formatted as
+// LOG_FORMAT says, with the commit messages being written about hidden as
+// LOG_COMMIT_CONTENT says. An unknown level logs at info, and an unknown
+// format as text.
+func (a *App) SetupLogging(w io.Writer) {
+	if redactor := a.Redactor(); redactor != nil {
+		redactor.SetOutput(w)
+		w = redactor
+	}
+
+	level, levelOK := logging.ParseLevel(a.config.LogLevel)
+	format := a.config.LogFormat
+	if format != logging.FormatJSON && format != logging.FormatText {
+		format = logging.FormatText
+	}
+	logging.Setup(w, &logging.Options{Level: level, Format: format})
+
+	if !levelOK && a.config.LogLevel != "" {
+		logging.Warnf("[APP] unknown LOG_LEVEL %q, logging at info\n", a.config.LogLevel)
+	}
+	if format != a.config.LogFormat && a.config.LogFormat != "" {
+		logging.Warnf("[APP] unknown LOG_FORMAT %q, logging text\n", a.config.LogFormat)
+	}
+}
+
+// PIIRedactor returns the redactor removing personal data from commit text
+// before it is sent to the model, or nil when PII_REDACTION is off. Without a
+// usable Comprehend client, only the regex pack is used; an unknown setting
+// uses the pack too, rather than send personal data on.
+func (a *App) PIIRedactor() *pii.Redactor {
+	if a.piiLoaded {
+		return a.pii
+	}
+	a.piiLoaded = true
+
+	detectors := []pii.Detector{pii.NewPatternDetector()}
+	switch a.config.PIIRedaction {
+	case "off", "":
+		return nil
+	case "comprehend":
+		client, err := comprehend.NewDefaultComprehendClient(a.aws)
+		if err != nil {
+			logging.Errorf("[APP] error configuring comprehend, redacting with patterns only: %v\n", err)
+			break
+		}
+		detectors = append(detectors, pii.NewComprehendDetector(client))
+	case "patterns":
+	default:
+		logging.Warnf("[APP] unknown PII_REDACTION %q, redacting with patterns\n", a.config.PIIRedaction)
+	}
+
+	a.pii = pii.NewRedactor(detectors...)
+	return a.pii
+}
+
+// Router returns a gin engine with the API's middleware and routes installed.
+// It is built once, along with every dependency it needs, and safe to call
+// concurrently.
+func (a *App) Router() *gin.Engine {
+	a.routerOnce.Do(func() {
+		a.router = gin.New()
+
+		haikuAPI := a.HaikuAPI()
+		haikuAPI.SetupMiddleware(a.router)
+		haikuAPI.SetupRoutes(a.router)
+	})
+	return a.router
+}
@@ -0,0 +1,791 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/config"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/redact"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+)
+
+func testConfig() config.Config {
+	return config.Config{
+		MaxCommitLength:     100,
+		TruncatedBodyLength: 50,
+		ModerationRetries:   2,
+	}
+}
+
+func TestAppReusesDependencies(t *testing.T) {
+	app := New(aws.Config{Region: "us-east-1"}, testConfig())
+
+	if app.BedrockClient() != app.BedrockClient() {
+		t.Error("Expected the Bedrock client to be built once")
+	}
+	if app.HaikuService() != app.HaikuService() {
+		t.Error("Expected the haiku service to be built once")
+	}
+	if app.HaikuAPI() != app.HaikuAPI() {
+		t.Error("Expected the haiku API to be built once")
+	}
+}
+
+func TestAppOptionalDependencies(t *testing.T) {
+	tests := []struct {
+		name              string
+		artifactBucket    string
+		expectedArtifacts bool
+	}{
+		{
+			name: "Nothing configured",
+		},
+		{
+			name:              "Artifact bucket configured",
+			artifactBucket:    "haiku-artifacts",
+			expectedArtifacts: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ArtifactBucket = tc.artifactBucket
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Artifacts() != nil; got != tc.expectedArtifacts {
+				t.Errorf("Expected artifacts configured %v, got %v", tc.expectedArtifacts, got)
+			}
+			if app.Prompts() != nil {
+				t.Error("Expected no prompt provider without a parameter path")
+			}
+		})
+	}
+}
+
+func TestAppPluginsFailingToStart(t *testing.T) {
+	cfg := testConfig()
+	cfg.PluginProvider = "/nonexistent/provider"
+	cfg.PluginNotifiers = []string{"/nonexistent/notifier"}
+	app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+	if app.Provider() != nil {
+		t.Error("Expected no provider when the plugin cannot start")
+	}
+	if len(app.Notifiers()) != 0 {
+		t.Errorf("Expected no notifiers when the plugin cannot start, got %d", len(app.Notifiers()))
+	}
+	if app.HaikuService() == nil {
+		t.Error("Expected the haiku service to fall back to bedrock")
+	}
+}
+
+func TestAppClose(t *testing.T) {
+	app := New(aws.Config{}, testConfig())
+
+	var order []string
+	errFirst := errors.New("first failed")
+	app.OnClose(func(ctx context.Context) error {
+		order = append(order, "first")
+		return errFirst
+	})
+	app.OnClose(func(ctx context.Context) error {
+		order = append(order, "second")
+		return nil
+	})
+
+	err := app.Close(context.Background())
+	if !errors.Is(err, errFirst) {
+		t.Errorf("Expected close error to wrap %v, got %v", errFirst, err)
+	}
+	if !slices.Equal(order, []string{"second", "first"}) {
+		t.Errorf("Expected closers to run in reverse order, got %v", order)
+	}
+
+	if err := app.Close(context.Background()); err != nil {
+		t.Errorf("Expected a second close to be a no-op, got %v", err)
+	}
+}
+
+func TestAppReporter(t *testing.T) {
+	tests := []struct {
+		name           string
+		errorReporting string
+		sentryDSN      string
+		expected       string
+	}{
+		{
+			name:     "Log by default",
+			expected: "*reporting.LogReporter",
+		},
+		{
+			name:           "Sentry",
+			errorReporting: "sentry",
+			sentryDSN:      "https://abc123@o1.ingest.sentry.io/42",
+			expected:       "*reporting.SentryReporter",
+		},
+		{
+			name:           "Sentry without a DSN falls back to the log",
+			errorReporting: "sentry",
+			expected:       "*reporting.LogReporter",
+		},
+		{
+			name:           "Off",
+			errorReporting: "off",
+			expected:       "<nil>",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ErrorReporting = tc.errorReporting
+			cfg.SentryDSN = tc.sentryDSN
+			app := New(aws.Config{}, cfg)
+
+			if got := fmt.Sprintf("%T", app.Reporter()); got != tc.expected {
+				t.Errorf("Expected reporter %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppResponseCache(t *testing.T) {
+	tests := []struct {
+		name     string
+		size     int
+		table    string
+		expected string
+	}{
+		{
+			name:     "Disabled by default",
+			expected: "<nil>",
+		},
+		{
+			name:     "Memory",
+			size:     100,
+			expected: "haiku.memoryResponseCache",
+		},
+		{
+			name:     "DynamoDB",
+			table:    "haiku-cache",
+			expected: "haiku.storeResponseCache",
+		},
+		{
+			name:     "Memory backed by DynamoDB",
+			size:     100,
+			table:    "haiku-cache",
+			expected: "haiku.tieredResponseCache",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ResponseCacheSize = tc.size
+			cfg.ResponseCacheTable = tc.table
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := fmt.Sprintf("%T", app.ResponseCache()); got != tc.expected {
+				t.Errorf("Expected response cache %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppFlags(t *testing.T) {
+	tests := []struct {
+		name         string
+		featureFlags string
+		appConfig    bool
+		expectStore  bool
+		expectExport bool
+	}{
+		{
+			name:         "None configured",
+			expectExport: true,
+		},
+		{
+			name:         "Environment",
+			featureFlags: `{"export": {"enabled": false}}`,
+			expectStore:  true,
+		},
+		{
+			name:         "Invalid flags are ignored",
+			featureFlags: `{"export": {"enabled": false, "percent": 150}}`,
+			expectStore:  true,
+			expectExport: true,
+		},
+		{
+			name:        "AppConfig",
+			appConfig:   true,
+			expectStore: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.FeatureFlags = tc.featureFlags
+			if tc.appConfig {
+				cfg.AppConfigApplication = "haiku"
+				cfg.AppConfigEnvironment = "test"
+				cfg.AppConfigProfile = "flags"
+			}
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			store := app.Flags()
+			if (store != nil) != tc.expectStore {
+				t.Fatalf("Expected a flag store %v, got %v", tc.expectStore, store != nil)
+			}
+			if store == nil {
+				return
+			}
+			if !tc.appConfig {
+				if got := store.Enabled(context.Background(), "export", true); got != tc.expectExport {
+					t.Errorf("Expected export %v, got %v", tc.expectExport, got)
+				}
+			}
+			if app.Flags() != store {
+				t.Errorf("Expected the flag store to be reused")
+			}
+		})
+	}
+}
+
+func TestAppDaily(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name:     "Memory",
+			expected: true,
+		},
+		{
+			name:   "Lambda without a table",
+			lambda: "haiku",
+		},
+		{
+			name:     "Lambda with a table",
+			table:    "haiku-daily",
+			lambda:   "haiku",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DailyHaikuTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Daily() != nil; got != tc.expected {
+				t.Errorf("Expected daily haiku %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppRenga(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name:     "Memory",
+			expected: true,
+		},
+		{
+			name:   "Lambda without a table",
+			lambda: "haiku",
+		},
+		{
+			name:     "Lambda with a table",
+			table:    "haiku-renga",
+			lambda:   "haiku",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.RengaTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Renga() != nil; got != tc.expected {
+				t.Errorf("Expected renga %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		topicARN string
+		bus      string
+		expected bool
+	}{
+		{
+			name: "Neither topic nor bus",
+		},
+		{
+			name:     "Topic",
+			topicARN: "arn:aws:sns:us-east-1:123456789012:haiku-events",
+			expected: true,
+		},
+		{
+			name:     "Bus",
+			bus:      "haiku-events",
+			expected: true,
+		},
+		{
+			name:     "Invalid topic",
+			topicARN: "haiku-events",
+		},
+		{
+			name:     "Invalid topic with a bus",
+			topicARN: "haiku-events",
+			bus:      "arn:aws:events:us-east-1:123456789012:event-bus/haiku-events",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.HaikuEventsTopicARN = tc.topicARN
+			cfg.EventBusName = tc.bus
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Events() != nil; got != tc.expected {
+				t.Errorf("Expected events %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppAudit(t *testing.T) {
+	tests := []struct {
+		name   string
+		group  string
+		region string
+	}{
+		{
+			name: "Function log",
+		},
+		{
+			name:   "Log group",
+			group:  "haiku-audit",
+			region: "us-east-1",
+		},
+		{
+			name:  "Log group without a region falls back to the function log",
+			group: "haiku-audit",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AuditLogGroup = tc.group
+			app := New(aws.Config{Region: tc.region}, cfg)
+
+			if app.Audit() == nil {
+				t.Errorf("Expected requests to be audited")
+			}
+		})
+	}
+}
+
+func TestAppVotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name:     "Memory",
+			expected: true,
+		},
+		{
+			name:   "Lambda without a table",
+			lambda: "haiku",
+		},
+		{
+			name:     "Lambda with a table",
+			table:    "haiku-votes",
+			lambda:   "haiku",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.VoteTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Votes() != nil; got != tc.expected {
+				t.Errorf("Expected votes %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppShadow(t *testing.T) {
+	tests := []struct {
+		name     string
+		percent  int
+		model    string
+		table    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name:  "Off by default",
+			model: "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+		},
+		{
+			name:    "Nothing to compare",
+			percent: 10,
+		},
+		{
+			name:     "Memory",
+			percent:  10,
+			model:    "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+			expected: true,
+		},
+		{
+			name:    "Lambda without a table",
+			percent: 10,
+			model:   "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+			lambda:  "haiku",
+		},
+		{
+			name:     "Lambda with a table",
+			percent:  10,
+			model:    "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+			table:    "haiku-shadow",
+			lambda:   "haiku",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ShadowPercent = tc.percent
+			cfg.ShadowModelID = tc.model
+			cfg.ShadowTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Shadow() != nil; got != tc.expected {
+				t.Errorf("Expected shadow %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppErasure(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name:     "Admin token",
+			token:    "admin",
+			expected: true,
+		},
+		{
+			name: "No admin token",
+		},
+		{
+			name:   "Haiku not kept",
+			token:  "admin",
+			lambda: "haiku",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AdminToken = tc.token
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Erasure() != nil; got != tc.expected {
+				t.Errorf("Expected erasure %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppErasureErasesStats(t *testing.T) {
+	cfg := testConfig()
+	cfg.AdminToken = "admin"
+	cfg.StatsToken = "stats-token"
+	app := New(aws.Config{Region: "us-east-1"}, cfg)
+	ctx := context.Background()
+
+	for _, author := range []string{"Mona", "Hubot"} {
+		if err := app.Stats().Record(ctx, haiku.Usage{Mood: haiku.MoodTechnical, Author: author}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if err := app.Stats().RecordVote(ctx, haiku.Stored{Author: author}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	erased, err := app.Erasure().Erase(ctx, "Mona", "admin")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if erased.StatsDays != 1 {
+		t.Errorf("Expected one day of statistics erased, got %+v", erased)
+	}
+
+	summary, err := app.Stats().Summary(ctx, stats.DefaultDays)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, ok := summary.Authors["Mona"]; ok || summary.Authors["Hubot"] != 1 {
+		t.Errorf("Expected only Hubot in /stats, got %v", summary.Authors)
+	}
+	for _, ranking := range stats.Rankings {
+		board, err := app.Stats().Leaderboard(ctx, stats.BoardAuthors, ranking, stats.DefaultDays, 0)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if len(board.Entries) != 1 || board.Entries[0].Name != "Hubot" {
+			t.Errorf("Expected only Hubot on the %s leaderboard, got %+v", ranking, board.Entries)
+		}
+	}
+}
+
+func TestAppStats(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		table    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name: "Disabled by default",
+		},
+		{
+			name:     "Memory",
+			token:    "stats-token",
+			expected: true,
+		},
+		{
+			name:   "Lambda without a table",
+			token:  "stats-token",
+			lambda: "haiku",
+		},
+		{
+			name:     "Lambda with a table",
+			token:    "stats-token",
+			table:    "haiku-stats",
+			lambda:   "haiku",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.StatsToken = tc.token
+			cfg.StatsTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Stats() != nil; got != tc.expected {
+				t.Errorf("Expected stats %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		adminToken string
+		require    bool
+		table      string
+		lambda     string
+		expected   bool
+		styles     bool
+	}{
+		{
+			name: "Disabled by default",
+		},
+		{
+			name:       "Admin API",
+			adminToken: "admin-token",
+			expected:   true,
+			styles:     true,
+		},
+		{
+			name:     "Keys required",
+			require:  true,
+			expected: true,
+		},
+		{
+			name:       "Lambda without a table",
+			adminToken: "admin-token",
+			lambda:     "haiku",
+		},
+		{
+			name:       "Lambda with a table",
+			adminToken: "admin-token",
+			table:      "haiku-keys",
+			lambda:     "haiku",
+			expected:   true,
+			styles:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.AdminToken = tc.adminToken
+			cfg.RequireAPIKey = tc.require
+			cfg.KeyTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Keys() != nil; got != tc.expected {
+				t.Errorf("Expected keys %v, got %v", tc.expected, got)
+			}
+			// Style guides are managed with the admin API, so they need it.
+			if got := app.Styles() != nil; got != tc.styles {
+				t.Errorf("Expected style guides %v, got %v", tc.styles, got)
+			}
+		})
+	}
+}
+
+func TestAppRedactor(t *testing.T) {
+	tests := []struct {
+		name           string
+		commitContent  string
+		logFullPrompts bool
+		expectedMode   redact.Mode
+	}{
+		{name: "Hashed by default", expectedMode: redact.ModeHash},
+		{name: "Redacted", commitContent: "redact", expectedMode: redact.ModeRedact},
+		{name: "Full prompts", logFullPrompts: true, expectedMode: redact.ModeFull},
+		{name: "Commit content overrides full prompts", commitContent: "hash", logFullPrompts: true, expectedMode: redact.ModeHash},
+		{name: "Unknown mode hashes", commitContent: "verbose", expectedMode: redact.ModeHash},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.LogCommitContent = tc.commitContent
+			cfg.LogFullPrompts = tc.logFullPrompts
+			app := New(aws.Config{}, cfg)
+
+			if mode := app.commitContentMode(); mode != tc.expectedMode {
+				t.Errorf("Expected mode %q, got %q", tc.expectedMode, mode)
+			}
+			if redactor := app.Redactor(); (redactor == nil) != (tc.expectedMode == redact.ModeFull) {
+				t.Errorf("Expected a redactor unless logging in full, got %v", redactor)
+			}
+		})
+	}
+}
+
+func TestAppPIIRedactor(t *testing.T) {
+	tests := []struct {
+		name             string
+		setting          string
+		region           string
+		expectedRedactor bool
+	}{
+		{name: "Off by default"},
+		{name: "Off", setting: "off"},
+		{name: "Patterns", setting: "patterns", expectedRedactor: true},
+		{name: "Comprehend", setting: "comprehend", region: "us-east-1", expectedRedactor: true},
+		{name: "Comprehend without a region falls back to patterns", setting: "comprehend", expectedRedactor: true},
+		{name: "Unknown setting redacts with patterns", setting: "strict", expectedRedactor: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.PIIRedaction = tc.setting
+			app := New(aws.Config{Region: tc.region}, cfg)
+
+			if redactor := app.PIIRedactor(); (redactor != nil) != tc.expectedRedactor {
+				t.Errorf("Expected redactor %t, got %v", tc.expectedRedactor, redactor)
+			}
+		})
+	}
+}
+
+func TestAppSetupLogging(t *testing.T) {
+	previous := slog.Default()
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	})
+
+	const message = "fix: rotate the key Acme Corp leaked"
+	cfg := testConfig()
+	cfg.LogLevel = "warn"
+	cfg.LogFormat = "json"
+	cfg.LogCommitContent = "redact"
+	app := New(aws.Config{}, cfg)
+
+	var out bytes.Buffer
+	app.SetupLogging(&out)
+	release := app.Redactor().Hide(message)
+	defer release()
+
+	logging.Infof("[HAIKU SERVICE] writing a haiku for %s", message)
+	logging.Warnf("[HAIKU SERVICE] model refused %s", message)
+
+	var line struct {
+		Level     string `json:"level"`
+		Msg       string `json:"msg"`
+		Component string `json:"component"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+		t.Fatalf("Expected a single JSON line, got %q: %v", out.String(), err)
+	}
+	if line.Level != "WARN" || line.Component != "HAIKU SERVICE" {
+		t.Errorf("Expected a warning of the haiku service, got %+v", line)
+	}
+	if strings.Contains(line.Msg, "Acme") {
+		t.Errorf("Expected the commit message hidden, got %q", line.Msg)
+	}
+}
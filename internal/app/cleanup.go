@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/dynamo"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// cleanupEvent is the payload of a scheduled cleanup, {"cleanup": true}.
+type cleanupEvent struct {
+	Cleanup bool `json:"cleanup"`
+}
+
+// ParseCleanup reports whether a Lambda payload asks for expired items to be
+// deleted rather than to serve a request.
+func ParseCleanup(payload []byte) bool {
+	var event cleanupEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return false
+	}
+	return event.Cleanup
+}
+
+// Cleanup deletes expired items from every table keeping commit derived
+// content: cached responses, jobs, the haiku of the day, served haiku with
+// their votes, and haiku queued for Slack. Each table is cleaned even when
+// another fails.
+func (a *App) Cleanup(ctx context.Context) error {
+	var errs []error
+	for _, table := range a.cleanupTables() {
+		deleted, err := dynamo.NewDefaultDynamoClient(a.aws, table).DeleteExpired(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("cleaning up %s: %w", table, err))
+		}
+		logging.Infof("[APP] deleted %d expired items from %s\n", deleted, table)
+	}
+	return errors.Join(errs...)
+}
+
+func (a *App) cleanupTables() []string {
+	var tables []string
+	for _, table := range []string{
+		a.config.ResponseCacheTable,
+		a.config.JobTable,
+		a.config.DailyHaikuTable,
+		a.config.VoteTable,
+		a.config.NotifyTable,
+	} {
+		if table != "" {
+			tables = append(tables, table)
+		}
+	}
+	return tables
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseCleanup(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Cleanup",
+			payload:  `{"cleanup": true}`,
+			expected: true,
+		},
+		{
+			name:    "Warm-up",
+			payload: `{"warmUp": true}`,
+		},
+		{
+			name:    "Scheduled event",
+			payload: `{"version": "0", "source": "aws.events", "detail-type": "Scheduled Event", "detail": {}}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `cleanup`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseCleanup([]byte(tc.payload)); got != tc.expected {
+				t.Errorf("Expected cleanup %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppCleanupTables(t *testing.T) {
+	cfg := testConfig()
+	app := New(aws.Config{Region: "us-east-1"}, cfg)
+	if err := app.Cleanup(context.Background()); err != nil {
+		t.Errorf("Expected nothing to clean up without tables, got %v", err)
+	}
+
+	cfg.ResponseCacheTable = "haiku-cache"
+	cfg.VoteTable = "haiku-votes"
+	cfg.StatsTable = "haiku-stats"
+	cfg.NotifyTable = "haiku-notify"
+	app = New(aws.Config{Region: "us-east-1"}, cfg)
+
+	// Statistics hold no commit content and expire on their own.
+	expected := []string{"haiku-cache", "haiku-votes", "haiku-notify"}
+	if tables := app.cleanupTables(); !slices.Equal(tables, expected) {
+		t.Errorf("Expected tables %v, got %v", expected, tables)
+	}
+}
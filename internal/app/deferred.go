@@ -0,0 +1,142 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/discord"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/slack"
+)
+
+// deferredTimeout bounds work finished after its request was answered.
+const deferredTimeout = 30 * time.Second
+
+// Deferred is work the API acknowledged but could not finish within the
+// request, such as a slash command response. On Lambda it is the payload of
+// an asynchronous invocation of the same function.
+type Deferred struct {
+	SlackCommand       *slack.Command       `json:"slackCommand,omitempty"`
+	DiscordInteraction *discord.Interaction `json:"discordInteraction,omitempty"`
+	HaikuJob           string               `json:"haikuJob,omitempty"`
+	ModerateHaiku      string               `json:"moderateHaiku,omitempty"`
+	Shadow             *haiku.ShadowRequest `json:"shadow,omitempty"`
+}
+
+// ParseDeferred reports whether a Lambda payload carries deferred work rather
+// than an API Gateway request.
+func ParseDeferred(payload []byte) (Deferred, bool) {
+	var deferred Deferred
+	if err := json.Unmarshal(payload, &deferred); err != nil {
+		return Deferred{}, false
+	}
+	return deferred, deferred.SlackCommand != nil || deferred.DiscordInteraction != nil || deferred.HaikuJob != "" || deferred.ModerateHaiku != "" || deferred.Shadow != nil
+}
+
+// HandleDeferred finishes deferred work. Failures are logged rather than
+// returned: Lambda retries failed asynchronous invocations, and the user has
+// already been told the request failed.
+func (a *App) HandleDeferred(ctx context.Context, deferred Deferred) {
+	ctx, cancel := context.WithTimeout(ctx, deferredTimeout)
+	defer cancel()
+
+	if deferred.SlackCommand != nil {
+		if err := a.SlackService().Respond(ctx, *deferred.SlackCommand); err != nil {
+			logging.Errorf("[APP] error responding to slack command: %v\n", err)
+		}
+	}
+	if deferred.DiscordInteraction != nil {
+		if err := a.DiscordService().Respond(ctx, *deferred.DiscordInteraction); err != nil {
+			logging.Errorf("[APP] error responding to discord interaction: %v\n", err)
+		}
+	}
+	if deferred.HaikuJob != "" {
+		if err := a.runJob(ctx, deferred.HaikuJob); err != nil {
+			logging.Errorf("[APP] error running haiku job %s: %v\n", deferred.HaikuJob, err)
+		}
+	}
+	if deferred.ModerateHaiku != "" {
+		if err := a.moderateHaiku(ctx, deferred.ModerateHaiku); err != nil {
+			logging.Errorf("[APP] error moderating haiku %s: %v\n", deferred.ModerateHaiku, err)
+		}
+	}
+	if deferred.Shadow != nil {
+		if err := a.runShadow(ctx, *deferred.Shadow); err != nil {
+			logging.Errorf("[APP] error writing shadow haiku: %v\n", err)
+		}
+	}
+}
+
+type invoker interface {
+	InvokeAsync(ctx context.Context, payload []byte) error
+}
+
+// scheduler runs deferred work. A Lambda is frozen as soon as it returns its
+// response, so there the work is handed to an asynchronous invocation of the
+// function; elsewhere it runs in a goroutine.
+type scheduler struct {
+	invoker invoker
+	handle  func(ctx context.Context, deferred Deferred)
+}
+
+func (s *scheduler) schedule(ctx context.Context, deferred Deferred) error {
+	if s.invoker != nil {
+		payload, err := json.Marshal(deferred)
+		if err != nil {
+			return err
+		}
+		return s.invoker.InvokeAsync(ctx, payload)
+	}
+
+	// The work outlives the request, so it must not be canceled with it.
+	go s.handle(context.WithoutCancel(ctx), deferred)
+	return nil
+}
+
+type slackDispatcher struct {
+	scheduler *scheduler
+}
+
+func (d *slackDispatcher) Dispatch(ctx context.Context, command slack.Command) error {
+	if err := command.Validate(); err != nil {
+		return err
+	}
+	return d.scheduler.schedule(ctx, Deferred{SlackCommand: &command})
+}
+
+type discordDispatcher struct {
+	scheduler *scheduler
+}
+
+func (d *discordDispatcher) Dispatch(ctx context.Context, interaction discord.Interaction) error {
+	if err := interaction.Validate(); err != nil {
+		return err
+	}
+	return d.scheduler.schedule(ctx, Deferred{DiscordInteraction: &interaction})
+}
+
+type jobDispatcher struct {
+	scheduler *scheduler
+}
+
+func (d *jobDispatcher) Schedule(ctx context.Context, id string) error {
+	return d.scheduler.schedule(ctx, Deferred{HaikuJob: id})
+}
+
+type moderationDispatcher struct {
+	scheduler *scheduler
+}
+
+func (d *moderationDispatcher) Schedule(ctx context.Context, id string) error {
+	return d.scheduler.schedule(ctx, Deferred{ModerateHaiku: id})
+}
+
+type shadowDispatcher struct {
+	scheduler *scheduler
+}
+
+func (d *shadowDispatcher) Schedule(ctx context.Context, request haiku.ShadowRequest) error {
+	return d.scheduler.schedule(ctx, Deferred{Shadow: &request})
+}
@@ -0,0 +1,145 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/discord"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/slack"
+)
+
+type MockInvoker struct {
+	LastPayload []byte
+}
+
+func (m *MockInvoker) InvokeAsync(ctx context.Context, payload []byte) error {
+	m.LastPayload = payload
+	return nil
+}
+
+func TestDeferredDispatchers(t *testing.T) {
+	command := slack.Command{Text: "fix flaky test", ResponseURL: "https://hooks.slack.com/commands/T1/2/abc", UserID: "U123"}
+	interaction := discord.Interaction{ApplicationID: "1234", Token: "tok", Text: "fix flaky test", UserID: "42"}
+
+	invoker := &MockInvoker{}
+	scheduler := &scheduler{invoker: invoker}
+
+	if err := (&slackDispatcher{scheduler: scheduler}).Dispatch(context.Background(), command); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	deferred, ok := ParseDeferred(invoker.LastPayload)
+	if !ok || deferred.SlackCommand == nil || *deferred.SlackCommand != command {
+		t.Errorf("Expected the slack command to round trip through the payload, got %s", invoker.LastPayload)
+	}
+
+	if err := (&discordDispatcher{scheduler: scheduler}).Dispatch(context.Background(), interaction); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	deferred, ok = ParseDeferred(invoker.LastPayload)
+	if !ok || deferred.DiscordInteraction == nil || *deferred.DiscordInteraction != interaction {
+		t.Errorf("Expected the discord interaction to round trip through the payload, got %s", invoker.LastPayload)
+	}
+
+	if err := (&jobDispatcher{scheduler: scheduler}).Schedule(context.Background(), "0123456789abcdef0123456789abcdef"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	deferred, ok = ParseDeferred(invoker.LastPayload)
+	if !ok || deferred.HaikuJob != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("Expected the haiku job to round trip through the payload, got %s", invoker.LastPayload)
+	}
+
+	if err := (&moderationDispatcher{scheduler: scheduler}).Schedule(context.Background(), "0123456789abcdef0123456789abcdef"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	deferred, ok = ParseDeferred(invoker.LastPayload)
+	if !ok || deferred.ModerateHaiku != "0123456789abcdef0123456789abcdef" {
+		t.Errorf("Expected the haiku to moderate to round trip through the payload, got %s", invoker.LastPayload)
+	}
+
+	shadow := haiku.ShadowRequest{HaikuID: "abc123", Mood: haiku.MoodTechnical, CommitMessage: "fix flaky test", Haiku: "leaves fall"}
+	if err := (&shadowDispatcher{scheduler: scheduler}).Schedule(context.Background(), shadow); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	deferred, ok = ParseDeferred(invoker.LastPayload)
+	if !ok || deferred.Shadow == nil || !reflect.DeepEqual(*deferred.Shadow, shadow) {
+		t.Errorf("Expected the shadow request to round trip through the payload, got %s", invoker.LastPayload)
+	}
+
+	err := (&slackDispatcher{scheduler: scheduler}).Dispatch(context.Background(), slack.Command{Text: "fix flaky test", ResponseURL: "https://example.com/hook"})
+	if !errors.Is(err, slack.ErrBadCommand) {
+		t.Errorf("Expected a non-slack response url to be refused, got %v", err)
+	}
+}
+
+func TestSchedulerWithoutLambda(t *testing.T) {
+	handled := make(chan error, 1)
+	scheduler := &scheduler{handle: func(ctx context.Context, deferred Deferred) {
+		handled <- ctx.Err()
+	}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := scheduler.schedule(ctx, Deferred{SlackCommand: &slack.Command{}})
+	// The request that scheduled the work finishing must not cancel the work.
+	cancel()
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	select {
+	case err := <-handled:
+		if err != nil {
+			t.Errorf("Expected the work to run with an uncanceled context, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the work to run in the background")
+	}
+}
+
+func TestParseDeferred(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Slack command",
+			payload:  `{"slackCommand":{"text":"fix flaky test","responseUrl":"https://hooks.slack.com/commands/T1/2/abc"}}`,
+			expected: true,
+		},
+		{
+			name:     "Haiku job",
+			payload:  `{"haikuJob":"0123456789abcdef0123456789abcdef"}`,
+			expected: true,
+		},
+		{
+			name:     "Discord interaction",
+			payload:  `{"discordInteraction":{"applicationId":"1234","token":"tok","text":"fix flaky test"}}`,
+			expected: true,
+		},
+		{
+			name:     "Shadow haiku",
+			payload:  `{"shadow":{"mood":"technical","commitMessage":"fix flaky test","haiku":"leaves fall"}}`,
+			expected: true,
+		},
+		{
+			name:    "API Gateway request",
+			payload: `{"resource":"/haiku","path":"/haiku","httpMethod":"POST","body":"{}"}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `leaves`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := ParseDeferred([]byte(tc.payload)); ok != tc.expected {
+				t.Errorf("Expected deferred %v, got %v", tc.expected, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/digest"
+)
+
+// digestEvent is the payload of the weekly digest schedule, {"digest": true}.
+type digestEvent struct {
+	Digest bool `json:"digest"`
+}
+
+// ParseDigest reports whether a Lambda payload asks for the weekly digests to
+// be sent rather than to serve a request.
+func ParseDigest(payload []byte) bool {
+	var event digestEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return false
+	}
+	return event.Digest
+}
+
+// SendDigests emails every subscription its digest of the last week's haiku.
+func (a *App) SendDigests(ctx context.Context) (digest.Run, error) {
+	service := a.Digests()
+	if service == nil {
+		return digest.Run{}, errors.New("digests are not configured")
+	}
+	return service.SendWeekly(ctx)
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseDigest(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Scheduled digest",
+			payload:  `{"digest": true}`,
+			expected: true,
+		},
+		{
+			name:    "Export",
+			payload: `{"export": true}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `digest`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseDigest([]byte(tc.payload)); got != tc.expected {
+				t.Errorf("Expected digest %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppDigests(t *testing.T) {
+	tests := []struct {
+		name       string
+		sender     string
+		adminToken string
+		expected   bool
+	}{
+		{
+			name:       "Without a sender",
+			adminToken: "secret",
+		},
+		{
+			name:   "Without an admin token",
+			sender: "haiku@example.com",
+		},
+		{
+			name:       "Memory",
+			sender:     "haiku@example.com",
+			adminToken: "secret",
+			expected:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DigestSender = tc.sender
+			cfg.AdminToken = tc.adminToken
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Digests() != nil; got != tc.expected {
+				t.Errorf("Expected digests %v, got %v", tc.expected, got)
+			}
+			if !tc.expected {
+				if _, err := app.SendDigests(context.Background()); err == nil {
+					t.Error("Expected an error sending digests that aren't configured")
+				}
+			}
+		})
+	}
+}
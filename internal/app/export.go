@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/export"
+)
+
+// exportEvent is the payload of a scheduled export, {"export": true}, which
+// exports yesterday. Day, as YYYY-MM-DD, exports another day instead, e.g. to
+// backfill one the schedule missed.
+type exportEvent struct {
+	Export bool   `json:"export"`
+	Day    string `json:"day"`
+}
+
+// ParseExport reports whether a Lambda payload asks for kept haiku to be
+// exported rather than to serve a request, and which day, if any, it names.
+func ParseExport(payload []byte) (string, bool) {
+	var event exportEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return "", false
+	}
+	return event.Day, event.Export
+}
+
+// Export writes the haiku kept on day, or yesterday when day is empty, to the
+// export bucket.
+func (a *App) Export(ctx context.Context, day string) (export.Export, error) {
+	service := a.Exports()
+	if service == nil {
+		return export.Export{}, errors.New("exports are not configured")
+	}
+	if day == "" {
+		return service.ExportYesterday(ctx)
+	}
+
+	parsed, err := time.Parse(time.DateOnly, day)
+	if err != nil {
+		return export.Export{}, fmt.Errorf("invalid export day %q: %w", day, err)
+	}
+	return service.Export(ctx, parsed)
+}
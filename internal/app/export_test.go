@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseExport(t *testing.T) {
+	tests := []struct {
+		name        string
+		payload     string
+		expectedDay string
+		expected    bool
+	}{
+		{
+			name:     "Scheduled export",
+			payload:  `{"export": true}`,
+			expected: true,
+		},
+		{
+			name:        "Backfill",
+			payload:     `{"export": true, "day": "2025-10-09"}`,
+			expectedDay: "2025-10-09",
+			expected:    true,
+		},
+		{
+			name:    "Cleanup",
+			payload: `{"cleanup": true}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `export`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			day, ok := ParseExport([]byte(tc.payload))
+			if ok != tc.expected || day != tc.expectedDay {
+				t.Errorf("Expected export %v of %q, got %v of %q", tc.expected, tc.expectedDay, ok, day)
+			}
+		})
+	}
+}
+
+// Exports always reach the bucket, even for a day without haiku, whose
+// earlier export is deleted, so only requests refused before then are run
+// here.
+func TestAppExport(t *testing.T) {
+	tests := []struct {
+		name   string
+		bucket string
+		day    string
+	}{
+		{
+			name: "Not configured",
+			day:  "2025-10-09",
+		},
+		{
+			name:   "Invalid day",
+			bucket: "haiku-exports",
+			day:    "yesterday",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.ExportBucket = tc.bucket
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if _, err := app.Export(context.Background(), tc.day); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	ginadapter "github.com/awslabs/aws-lambda-go-api-proxy/gin"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/config"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// ColdStartEventType marks cold start timings among other log lines, e.g.
+// filter type = "cold_start" in CloudWatch Logs Insights.
+const ColdStartEventType = "cold_start"
+
+// ColdStart is how long each step of building the App took, in milliseconds.
+type ColdStart struct {
+	Type               string  `json:"type"`                         // Always ColdStartEventType
+	InitializationType string  `json:"initializationType,omitempty"` // "on-demand" or "provisioned-concurrency"
+	AWSConfigMs        float64 `json:"awsConfigMs"`                  // Loading AWS config and credentials settings
+	ConfigMs           float64 `json:"configMs"`                     // Reading the environment
+	RouterMs           float64 `json:"routerMs"`                     // Constructing clients, services and routes
+	TotalMs            float64 `json:"totalMs"`
+}
+
+// AWSConfigLoader loads the AWS config, e.g. from the Lambda environment.
+type AWSConfigLoader func(ctx context.Context) (aws.Config, error)
+
+// Lambda handles the function's invocations with an App built on first use.
+// A failure while building it fails only the invocation that hit it, and the
+// next invocation tries again.
+type Lambda struct {
+	loadAWS    AWSConfigLoader
+	loadConfig func() config.Config
+	out        io.Writer
+
+	mu        sync.Mutex
+	app       *App
+	proxy     *ginadapter.GinLambda
+	coldStart *ColdStart
+}
+
+// NewLambda builds the App from the given loaders, so that tests can serve
+// invocations through the full stack without AWS.
+func NewLambda(loadAWS AWSConfigLoader, loadConfig func() config.Config) *Lambda {
+	return &Lambda{
+		loadAWS:    loadAWS,
+		loadConfig: loadConfig,
+		out:        os.Stdout,
+	}
+}
+
+func NewDefaultLambda() *Lambda {
+	return NewLambda(func(ctx context.Context) (aws.Config, error) {
+		return awsconfig.LoadDefaultConfig(ctx)
+	}, config.Load)
+}
+
+// Init builds the App, if it hasn't been built yet, and returns it.
+func (l *Lambda) Init(ctx context.Context) (*App, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.app != nil {
+		return l.app, nil
+	}
+
+	start := time.Now()
+	cfg, err := l.loadAWS(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config: %w", err)
+	}
+	awsConfigDone := time.Now()
+
+	appConfig := l.loadConfig()
+	if err := appConfig.Validate(); err != nil {
+		return nil, err
+	}
+	configDone := time.Now()
+
+	app := New(cfg, appConfig)
+	app.SetupLogging(os.Stderr)
+	proxy := ginadapter.New(app.Router())
+	routerDone := time.Now()
+
+	l.app, l.proxy = app, proxy
+	l.coldStart = &ColdStart{
+		Type:               ColdStartEventType,
+		InitializationType: os.Getenv("AWS_LAMBDA_INITIALIZATION_TYPE"),
+		AWSConfigMs:        milliseconds(awsConfigDone.Sub(start)),
+		ConfigMs:           milliseconds(configDone.Sub(awsConfigDone)),
+		RouterMs:           milliseconds(routerDone.Sub(configDone)),
+		TotalMs:            milliseconds(routerDone.Sub(start)),
+	}
+	return l.app, nil
+}
+
+// Handle serves API Gateway requests, deferred work the function queued for
+// itself while answering one, Step Functions tasks, scheduled cleanups,
+// exports, digests and notifications, the vote table's stream, and warm-up
+// invocations. The first invocation after the App is built logs how
+// long building it took. Every line logged while handling an invocation
+// carries its Lambda request ID, and for API Gateway requests, the API
+// Gateway request ID too.
+func (l *Lambda) Handle(ctx context.Context, payload json.RawMessage) (any, error) {
+	ids := logging.Request{}
+	if lc, ok := lambdacontext.FromContext(ctx); ok {
+		ids.ID = lc.AwsRequestID
+	}
+	defer logging.SetRequest(ids)()
+
+	app, err := l.Init(ctx)
+	if err != nil {
+		logging.Errorf("[APP] error initializing: %v\n", err)
+		return nil, err
+	}
+	l.reportColdStart()
+
+	if ParseCleanup(payload) {
+		return nil, app.Cleanup(ctx)
+	}
+
+	if day, ok := ParseExport(payload); ok {
+		return app.Export(ctx, day)
+	}
+
+	if ParseDigest(payload) {
+		return app.SendDigests(ctx)
+	}
+
+	if ParseNotify(payload) {
+		return app.FlushNotifications(ctx)
+	}
+
+	if event, ok := ParseStream(payload); ok {
+		return app.AggregateStats(ctx, event)
+	}
+
+	if ParseWarmUp(payload) {
+		return nil, app.WarmUp(ctx)
+	}
+
+	if deferred, ok := ParseDeferred(payload); ok {
+		app.HandleDeferred(ctx, deferred)
+		return nil, nil
+	}
+
+	if task, ok := ParseTask(payload); ok {
+		return app.HandleTask(ctx, task)
+	}
+
+	var req events.APIGatewayProxyRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return nil, err
+	}
+	ids.APIRequestID = req.RequestContext.RequestID
+	defer logging.SetRequest(ids)()
+	return l.proxy.ProxyWithContext(ctx, req)
+}
+
+// reportColdStart writes the cold start timings as a single JSON line, once.
+func (l *Lambda) reportColdStart() {
+	l.mu.Lock()
+	coldStart := l.coldStart
+	l.coldStart = nil
+	l.mu.Unlock()
+
+	if coldStart == nil {
+		return
+	}
+
+	line, err := json.Marshal(coldStart)
+	if err != nil {
+		logging.Errorf("[APP] error encoding cold start timings: %v\n", err)
+		return
+	}
+	if _, err := l.out.Write(append(line, '\n')); err != nil {
+		logging.Errorf("[APP] error writing cold start timings: %v\n", err)
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
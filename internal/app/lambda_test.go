@@ -0,0 +1,181 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-lambda-go/lambdacontext"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/api"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/config"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+func newTestLambda(loadAWS AWSConfigLoader) (*Lambda, *bytes.Buffer) {
+	if loadAWS == nil {
+		loadAWS = func(ctx context.Context) (aws.Config, error) {
+			return aws.Config{Region: "us-east-1"}, nil
+		}
+	}
+
+	var out bytes.Buffer
+	lambda := NewLambda(loadAWS, func() config.Config { return testConfig() })
+	lambda.out = &out
+	return lambda, &out
+}
+
+func apiGatewayPayload(t *testing.T, method string, path string, body string) json.RawMessage {
+	t.Helper()
+	payload, err := json.Marshal(events.APIGatewayProxyRequest{
+		HTTPMethod: method,
+		Path:       path,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       body,
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	return payload
+}
+
+func TestLambdaServesRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "OpenAPI document",
+			method:         http.MethodGet,
+			path:           "/openapi.json",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid haiku request",
+			method:         http.MethodPost,
+			path:           "/haiku",
+			body:           `{"mood": "reflective"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lambda, _ := newTestLambda(nil)
+
+			response, err := lambda.Handle(context.Background(), apiGatewayPayload(t, tc.method, tc.path, tc.body))
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			proxyResponse, ok := response.(events.APIGatewayProxyResponse)
+			if !ok {
+				t.Fatalf("Expected an API Gateway response, got %T", response)
+			}
+			if proxyResponse.StatusCode != tc.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tc.expectedStatus, proxyResponse.StatusCode, proxyResponse.Body)
+			}
+		})
+	}
+}
+
+func TestLambdaRetriesFailedInit(t *testing.T) {
+	calls := 0
+	lambda, _ := newTestLambda(func(ctx context.Context) (aws.Config, error) {
+		calls++
+		if calls == 1 {
+			return aws.Config{}, errors.New("credentials endpoint timed out")
+		}
+		return aws.Config{Region: "us-east-1"}, nil
+	})
+
+	if _, err := lambda.Handle(context.Background(), json.RawMessage(`{"warmUp": true}`)); err == nil {
+		t.Fatal("Expected the first invocation to fail")
+	}
+	if _, err := lambda.Handle(context.Background(), json.RawMessage(`{"warmUp": true}`)); err != nil {
+		t.Fatalf("Expected the second invocation to succeed, got: %v", err)
+	}
+	if _, err := lambda.Handle(context.Background(), json.RawMessage(`{"warmUp": true}`)); err != nil {
+		t.Fatalf("Expected the third invocation to succeed, got: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected the AWS config to be loaded twice, got %d", calls)
+	}
+}
+
+func TestLambdaRefusesInvalidConfig(t *testing.T) {
+	lambda := NewLambda(func(ctx context.Context) (aws.Config, error) {
+		return aws.Config{Region: "us-east-1"}, nil
+	}, func() config.Config {
+		cfg := testConfig()
+		cfg.LambdaFunctionName, cfg.RequireAPIKey, cfg.KeyTable = "haiku", true, ""
+		return cfg
+	})
+
+	if _, err := lambda.Handle(context.Background(), json.RawMessage(`{"warmUp": true}`)); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("Expected ErrInvalidConfig, got %v", err)
+	}
+}
+
+func TestLambdaReportsColdStartOnce(t *testing.T) {
+	lambda, out := newTestLambda(nil)
+
+	for range 2 {
+		if _, err := lambda.Handle(context.Background(), apiGatewayPayload(t, http.MethodGet, "/openapi.json", "")); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one cold start line, got %q", out.String())
+	}
+
+	var coldStart ColdStart
+	if err := json.Unmarshal([]byte(lines[0]), &coldStart); err != nil {
+		t.Fatalf("Expected a JSON line, got %q: %v", lines[0], err)
+	}
+	if coldStart.Type != ColdStartEventType {
+		t.Errorf("Expected type %q, got %q", ColdStartEventType, coldStart.Type)
+	}
+	if coldStart.TotalMs < coldStart.RouterMs {
+		t.Errorf("Expected the total to include the router, got %+v", coldStart)
+	}
+}
+
+func TestLambdaRequestIDs(t *testing.T) {
+	lambda, _ := newTestLambda(nil)
+
+	payload, err := json.Marshal(events.APIGatewayProxyRequest{
+		HTTPMethod:     http.MethodGet,
+		Path:           "/openapi.json",
+		RequestContext: events.APIGatewayProxyRequestContext{RequestID: "Ab1xYHJ-IAMEQ3A="},
+	})
+	if err != nil {
+		t.Fatalf("Failed to encode request: %v", err)
+	}
+	ctx := lambdacontext.NewContext(context.Background(), &lambdacontext.LambdaContext{AwsRequestID: "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"})
+
+	response, err := lambda.Handle(ctx, payload)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	expected := []string{"Ab1xYHJ-IAMEQ3A=", "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"}
+	if ids := response.(events.APIGatewayProxyResponse).MultiValueHeaders[api.RequestIDHeader]; !slices.Equal(ids, expected) {
+		t.Errorf("Expected request IDs %v, got %v", expected, ids)
+	}
+	if ids := logging.CurrentRequest(); ids != (logging.Request{}) {
+		t.Errorf("Expected the request IDs cleared after the invocation, got %+v", ids)
+	}
+}
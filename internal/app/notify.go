@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/notify"
+)
+
+// notifyEvent is the payload of the notification schedule, {"notify": true}.
+type notifyEvent struct {
+	Notify bool `json:"notify"`
+}
+
+// ParseNotify reports whether a Lambda payload asks for queued haiku to be
+// posted to their Slack webhooks rather than to serve a request.
+func ParseNotify(payload []byte) bool {
+	var event notifyEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return false
+	}
+	return event.Notify
+}
+
+// FlushNotifications posts the haiku queued for each Slack webhook.
+func (a *App) FlushNotifications(ctx context.Context) (notify.Run, error) {
+	service := a.Notifications()
+	if service == nil {
+		return notify.Run{}, errors.New("slack notifications are not configured")
+	}
+	return service.Flush(ctx)
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseNotify(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Scheduled flush",
+			payload:  `{"notify": true}`,
+			expected: true,
+		},
+		{
+			name:    "Digest",
+			payload: `{"digest": true}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `notify`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseNotify([]byte(tc.payload)); got != tc.expected {
+				t.Errorf("Expected notify %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppNotifications(t *testing.T) {
+	tests := []struct {
+		name     string
+		webhooks string
+		table    string
+		lambda   string
+		expected bool
+	}{
+		{
+			name: "No webhooks",
+		},
+		{
+			name:     "Memory",
+			webhooks: `[{"url": "https://hooks.slack.com/services/T0/B0/team"}]`,
+			expected: true,
+		},
+		{
+			name:     "Invalid webhooks",
+			webhooks: `[{"url": "https://example.com/hook"}]`,
+		},
+		{
+			name:     "Lambda without a table",
+			webhooks: `[{"url": "https://hooks.slack.com/services/T0/B0/team"}]`,
+			lambda:   "haiku",
+		},
+		{
+			name:     "Lambda with a table",
+			webhooks: `[{"url": "https://hooks.slack.com/services/T0/B0/team"}]`,
+			table:    "haiku-notify",
+			lambda:   "haiku",
+			expected: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.SlackNotifyWebhooks = tc.webhooks
+			cfg.NotifyTable = tc.table
+			cfg.LambdaFunctionName = tc.lambda
+			app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+			if got := app.Notifications() != nil; got != tc.expected {
+				t.Errorf("Expected notifications %v, got %v", tc.expected, got)
+			}
+			if !tc.expected {
+				if _, err := app.FlushNotifications(context.Background()); err == nil {
+					t.Error("Expected an error flushing notifications that aren't configured")
+				}
+			}
+		})
+	}
+}
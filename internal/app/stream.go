@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/dynamo"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/stats"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+// dynamoDBEventSource is the event source of DynamoDB stream records.
+const dynamoDBEventSource = "aws:dynamodb"
+
+// ParseStream reports whether a Lambda payload is a batch of DynamoDB stream
+// records rather than a request, and returns it.
+func ParseStream(payload []byte) (events.DynamoDBEvent, bool) {
+	var event events.DynamoDBEvent
+	if err := json.Unmarshal(payload, &event); err != nil || len(event.Records) == 0 {
+		return events.DynamoDBEvent{}, false
+	}
+	return event, event.Records[0].EventSource == dynamoDBEventSource
+}
+
+// AggregateStats counts the haiku newly kept in a batch of the vote table's
+// stream records, in order. When a haiku can't be counted, the batch is
+// reported failed from its record on, so that the stream delivers only the
+// haiku not yet counted again. A record delivered again anyway, e.g. when the
+// function times out, is recognized by its haiku's ID and not counted twice.
+func (a *App) AggregateStats(ctx context.Context, event events.DynamoDBEvent) (events.DynamoDBEventResponse, error) {
+	service := a.Stats()
+	if service == nil || !a.config.StatsFromStream {
+		return events.DynamoDBEventResponse{}, errors.New("stats are not aggregated from the stream")
+	}
+
+	var response events.DynamoDBEventResponse
+	for _, record := range event.Records {
+		if err := aggregateRecord(ctx, service, record); err != nil {
+			logging.Errorf("[APP] error counting stream record %s: %v\n", record.EventID, err)
+			response.BatchItemFailures = []events.DynamoDBBatchItemFailure{{ItemIdentifier: record.Change.SequenceNumber}}
+			return response, nil
+		}
+	}
+	return response, nil
+}
+
+// aggregateRecord counts the haiku a stream record inserted, if any. Haiku
+// written again, e.g. once moderated, were counted when first kept.
+func aggregateRecord(ctx context.Context, service *stats.StatsService, record events.DynamoDBEventRecord) error {
+	if record.EventName != string(events.DynamoDBOperationTypeInsert) {
+		return nil
+	}
+
+	key, value := record.Change.NewImage[dynamo.KeyAttribute], record.Change.NewImage[dynamo.ValueAttribute]
+	if key.DataType() != events.DataTypeString || value.DataType() != events.DataTypeBinary {
+		return nil
+	}
+	saved, ok, err := votes.ParseItem(key.String(), value.Binary())
+	if err != nil {
+		// Delivering it again wouldn't make it readable.
+		logging.Warnf("[APP] skipping unreadable haiku %s: %v\n", key.String(), err)
+		return nil
+	}
+	if !ok {
+		return nil
+	}
+	return service.RecordKept(ctx, saved.ID, saved.Stored)
+}
@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+// streamRecord is a stream record of a haiku kept in the vote table, as
+// Lambda delivers it.
+func streamRecord(t *testing.T, eventName, key string, stored haiku.Stored) string {
+	value, err := json.Marshal(stored)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	return fmt.Sprintf(`{"eventID": "1", "eventName": %q, "eventSource": "aws:dynamodb", "dynamodb": {"SequenceNumber": %q, "NewImage": {"key": {"S": %q}, "value": {"B": %q}}}}`,
+		eventName, eventName+key, key, base64.StdEncoding.EncodeToString(value))
+}
+
+func TestParseStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Stream records",
+			payload:  `{"Records": [{"eventSource": "aws:dynamodb", "eventName": "INSERT"}]}`,
+			expected: true,
+		},
+		{
+			name:    "Other records",
+			payload: `{"Records": [{"eventSource": "aws:sqs"}]}`,
+		},
+		{
+			name:    "Notify",
+			payload: `{"notify": true}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `stream`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, got := ParseStream([]byte(tc.payload)); got != tc.expected {
+				t.Errorf("Expected stream %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppAggregateStats(t *testing.T) {
+	cfg := testConfig()
+	cfg.StatsToken = "stats-token"
+	cfg.StatsFromStream = true
+	app := New(aws.Config{Region: "us-east-1"}, cfg)
+
+	kept := haiku.Stored{Haiku: "frost", Mood: haiku.MoodTechnical, Repository: "octo/leaves", Tenant: "acme", CreatedAt: time.Now().UTC()}
+	id := "haiku:0123456789abcdef0123456789abcdef"
+	payload := fmt.Sprintf(`{"Records": [%s, %s, %s]}`,
+		streamRecord(t, "INSERT", id, kept),
+		streamRecord(t, "MODIFY", id, kept),
+		streamRecord(t, "INSERT", "votes:0123456789abcdef0123456789abcdef", kept),
+	)
+	event, ok := ParseStream([]byte(payload))
+	if !ok {
+		t.Fatalf("Expected stream records in %s", payload)
+	}
+
+	// A batch delivered again, e.g. after the function timed out, isn't
+	// counted again.
+	for range 2 {
+		response, err := app.AggregateStats(context.Background(), event)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if len(response.BatchItemFailures) != 0 {
+			t.Errorf("Expected no failures, got %+v", response.BatchItemFailures)
+		}
+	}
+
+	summary, err := app.Stats().Summary(keys.NewTenantContext(context.Background(), "acme"), 1)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if summary.Haiku != 1 || summary.Repositories["octo/leaves"] != 1 {
+		t.Errorf("Expected only the inserted haiku counted once, got %+v", summary)
+	}
+
+	// A haiku served again from the response cache is kept again under an ID
+	// of its own, so it counts again, as it did when counted as served.
+	payload = fmt.Sprintf(`{"Records": [%s]}`, streamRecord(t, "INSERT", "haiku:fedcba9876543210fedcba9876543210", kept))
+	event, _ = ParseStream([]byte(payload))
+	if _, err := app.AggregateStats(context.Background(), event); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	summary, err = app.Stats().Summary(keys.NewTenantContext(context.Background(), "acme"), 1)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if summary.Haiku != 2 {
+		t.Errorf("Expected the haiku served from the cache counted too, got %+v", summary)
+	}
+
+	cfg.StatsFromStream = false
+	if _, err := New(aws.Config{Region: "us-east-1"}, cfg).AggregateStats(context.Background(), event); err == nil {
+		t.Error("Expected an error aggregating stats that aren't aggregated from the stream")
+	}
+}
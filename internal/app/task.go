@@ -0,0 +1,62 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/api"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/workflow"
+)
+
+// Task is the payload of a Step Functions task state, e.g.
+// {"task": "generate", "input.$": "$"} in the state's Parameters.
+type Task struct {
+	Task  string          `json:"task"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ParseTask reports whether a Lambda payload is a Step Functions task rather
+// than an API Gateway request.
+func ParseTask(payload []byte) (Task, bool) {
+	var task Task
+	if err := json.Unmarshal(payload, &task); err != nil {
+		return Task{}, false
+	}
+	return task, task.Task != ""
+}
+
+// HandleTask runs a Step Functions task and returns its output. Failures are
+// named after the API's problem codes, e.g. "throttled" or "content_blocked",
+// so that states can retry or catch them by name.
+func (a *App) HandleTask(ctx context.Context, task Task) (any, error) {
+	output, err := a.Workflows().Run(ctx, task.Task, task.Input)
+	if err != nil {
+		logging.Errorf("[APP] error running %s task: %v\n", task.Task, err)
+		return nil, taskError(err)
+	}
+	return output, nil
+}
+
+// Task error names for failures the API never returns.
+const (
+	TaskErrorUnknownTask   = "unknown_task"
+	TaskErrorNotConfigured = "not_configured"
+)
+
+func taskError(err error) error {
+	var name string
+	switch {
+	case errors.Is(err, workflow.ErrBadTaskInput):
+		name = api.CodeInvalidRequest
+	case errors.Is(err, workflow.ErrUnknownTask):
+		name = TaskErrorUnknownTask
+	case errors.Is(err, workflow.ErrNotConfigured):
+		name = TaskErrorNotConfigured
+	default:
+		name = api.ServiceProblem(err).Code
+	}
+	return messages.InvokeResponse_Error{Type: name, Message: err.Error()}
+}
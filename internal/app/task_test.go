@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/lambda/messages"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/workflow"
+)
+
+func TestParseTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Step Functions task",
+			payload:  `{"task": "validateSyllables", "input": {"haiku": "leaves fall"}}`,
+			expected: true,
+		},
+		{
+			name:    "API Gateway request",
+			payload: `{"httpMethod": "POST", "path": "/haiku", "body": "{\"commitMessage\": \"fix\"}"}`,
+		},
+		{
+			name:    "Warm-up",
+			payload: `{"warmUp": true}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `task`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, got := ParseTask([]byte(tc.payload)); got != tc.expected {
+				t.Errorf("Expected task %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestLambdaRunsTasks(t *testing.T) {
+	tests := []struct {
+		name          string
+		payload       string
+		expected      string
+		expectedError string
+	}{
+		{
+			name:     "Validate syllables",
+			payload:  `{"task": "validateSyllables", "input": {"haiku": "Old cracks mended now\nthe login door swings open\nquiet in the logs"}}`,
+			expected: `{"valid":true,"syllables":[5,7,5]}`,
+		},
+		{
+			name:     "Moderate",
+			payload:  `{"task": "moderate", "input": {"text": "what the fuck"}}`,
+			expected: `{"blocked":true,"reason":"blocked word list"}`,
+		},
+		{
+			name:          "Invalid input",
+			payload:       `{"task": "generate", "input": {}}`,
+			expectedError: "invalid_request",
+		},
+		{
+			name:          "Unknown task",
+			payload:       `{"task": "translate"}`,
+			expectedError: TaskErrorUnknownTask,
+		},
+		{
+			name:          "GitHub App not configured",
+			payload:       `{"task": "postToGitHub", "input": {"haiku": "leaves fall", "repository": "octo/leaves", "commitSha": "abc123"}}`,
+			expectedError: TaskErrorNotConfigured,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			lambda, _ := newTestLambda(nil)
+
+			output, err := lambda.Handle(context.Background(), json.RawMessage(tc.payload))
+			if tc.expectedError != "" {
+				var invokeError messages.InvokeResponse_Error
+				if !errors.As(err, &invokeError) || invokeError.Type != tc.expectedError {
+					t.Fatalf("Expected a %s error, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			encoded, err := json.Marshal(output)
+			if err != nil {
+				t.Fatalf("Failed to marshal output: %v", err)
+			}
+			if string(encoded) != tc.expected {
+				t.Errorf("Expected output %s, got %s", tc.expected, encoded)
+			}
+		})
+	}
+}
+
+func TestTaskErrorUsesProblemCodes(t *testing.T) {
+	err := taskError(errors.Join(workflow.ErrPostHaiku, errors.New("502 bad gateway")))
+
+	var invokeError messages.InvokeResponse_Error
+	if !errors.As(err, &invokeError) || invokeError.Type != "internal_error" {
+		t.Errorf("Expected an internal_error, got %v", err)
+	}
+}
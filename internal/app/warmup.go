@@ -0,0 +1,42 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// warmUpEvent is the payload of a warm-up invocation: either an EventBridge
+// scheduled event or an explicit {"warmUp": true}.
+type warmUpEvent struct {
+	WarmUp     bool   `json:"warmUp"`
+	Source     string `json:"source"`
+	DetailType string `json:"detail-type"`
+}
+
+// ParseWarmUp reports whether a Lambda payload is a warm-up invocation, sent
+// to keep an instance ready rather than to serve a request.
+func ParseWarmUp(payload []byte) bool {
+	var event warmUpEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return false
+	}
+	return event.WarmUp || (event.Source == "aws.events" && event.DetailType == "Scheduled Event")
+}
+
+// WarmUp builds every dependency the API needs and fetches AWS credentials,
+// so that the next request doesn't pay for either. It never calls Bedrock.
+func (a *App) WarmUp(ctx context.Context) error {
+	a.HaikuAPI()
+
+	if a.aws.Credentials != nil {
+		if _, err := a.aws.Credentials.Retrieve(ctx); err != nil {
+			return fmt.Errorf("retrieving aws credentials: %w", err)
+		}
+	}
+
+	logging.Infof("[APP] warmed up\n")
+	return nil
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestParseWarmUp(t *testing.T) {
+	tests := []struct {
+		name     string
+		payload  string
+		expected bool
+	}{
+		{
+			name:     "Explicit warm-up",
+			payload:  `{"warmUp": true}`,
+			expected: true,
+		},
+		{
+			name:     "Scheduled event",
+			payload:  `{"version": "0", "source": "aws.events", "detail-type": "Scheduled Event", "detail": {}}`,
+			expected: true,
+		},
+		{
+			name:    "API Gateway request",
+			payload: `{"httpMethod": "POST", "path": "/haiku", "body": "{\"commitMessage\": \"fix\"}"}`,
+		},
+		{
+			name:    "Other EventBridge event",
+			payload: `{"source": "aws.s3", "detail-type": "Object Created"}`,
+		},
+		{
+			name:    "Not JSON",
+			payload: `warm`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ParseWarmUp([]byte(tc.payload)); got != tc.expected {
+				t.Errorf("Expected warm-up %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestAppWarmUp(t *testing.T) {
+	tests := []struct {
+		name        string
+		credentials aws.CredentialsProvider
+		expectError bool
+	}{
+		{
+			name:        "Credentials fetched",
+			credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) { return aws.Credentials{}, nil }),
+		},
+		{
+			name: "Credentials unavailable",
+			credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+				return aws.Credentials{}, errors.New("no credentials")
+			}),
+			expectError: true,
+		},
+		{
+			name: "No credentials provider",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			app := New(aws.Config{Region: "us-east-1", Credentials: tc.credentials}, testConfig())
+
+			err := app.WarmUp(context.Background())
+			if (err != nil) != tc.expectError {
+				t.Errorf("Expected error %v, got %v", tc.expectError, err)
+			}
+			if app.haikuAPI == nil {
+				t.Error("Expected the API to be built")
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+// Package audit keeps a log of who requested what: the caller, the route,
+// the model and prompt version that served each haiku, and the moderation
+// outcomes along the way. Records are chained within their stream: each
+// carries the next sequence number and the hash of the record before it, so
+// a record removed, reordered or altered breaks the chain, which Verify
+// detects.
+//
+// Moderation outcomes and served haiku are collected in a Trail carried by the
+// request context by whichever service produces them.
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRecord      = errors.New("error writing audit record")
+	ErrBrokenChain = errors.New("audit record chain is broken")
+)
+
+// EventType marks audit records among other log lines, e.g.
+// filter type = "audit" in CloudWatch Logs Insights.
+const EventType = "audit"
+
+// Haiku is a haiku served to the request.
+type Haiku struct {
+	ID            string `json:"id,omitempty"` // Empty when the haiku isn't kept
+	Model         string `json:"model"`
+	PromptVersion string `json:"promptVersion,omitempty"` // Empty for fallback haiku
+	Cached        bool   `json:"cached,omitempty"`
+}
+
+// Moderation is the outcome of screening one generated haiku.
+type Moderation struct {
+	Blocked bool   `json:"blocked"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// Record describes one request.
+type Record struct {
+	Type         string       `json:"type"`     // Always EventType
+	Stream       string       `json:"stream"`   // The chain the record belongs to
+	Sequence     uint64       `json:"sequence"` // Position in the stream, from 1
+	Time         time.Time    `json:"time"`
+	RequestID    string       `json:"requestId,omitempty"`    // Lambda request ID, when running in Lambda
+	APIRequestID string       `json:"apiRequestId,omitempty"` // API Gateway request ID, when running behind API Gateway
+	Caller       string       `json:"caller,omitempty"`       // "key:<id>" or "admin-token"; empty for unauthenticated requests
+	Tenant       string       `json:"tenant,omitempty"`
+	SourceIP     string       `json:"sourceIp,omitempty"`
+	UserAgent    string       `json:"userAgent,omitempty"`
+	Method       string       `json:"method"`
+	Route        string       `json:"route,omitempty"` // Matched route pattern, e.g. "/haiku"
+	Path         string       `json:"path"`
+	Status       int          `json:"status"`
+	Haiku        []Haiku      `json:"haiku,omitempty"`
+	Moderation   []Moderation `json:"moderation,omitempty"`
+	Previous     string       `json:"previous,omitempty"` // Hash of the record before it in the stream
+	Hash         string       `json:"hash"`               // SHA-256 of the record with Hash empty
+}
+
+// digest returns the hash of the record, which covers every field but Hash.
+func (r Record) digest() (string, error) {
+	r.Hash = ""
+	encoded, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sink stores records, e.g. in a CloudWatch Logs stream.
+type Sink interface {
+	Write(ctx context.Context, at time.Time, line []byte) error
+}
+
+// Log chains records and writes them to a sink, one at a time, so that
+// records reach the sink in sequence.
+type Log struct {
+	mu       sync.Mutex
+	sink     Sink
+	stream   string
+	sequence uint64
+	previous string
+}
+
+// NewLog starts the chain stream, which names the sink's stream too.
+func NewLog(sink Sink, stream string) *Log {
+	return &Log{sink: sink, stream: stream}
+}
+
+// NewStreamName names a new stream, e.g. "2026/10/15/3f9a0c1d2b4e5f60", so
+// that each instance of the function keeps a chain of its own.
+func NewStreamName(now time.Time) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return now.UTC().Format("2006/01/02") + "/" + hex.EncodeToString(b), nil
+}
+
+// Record chains record to the stream and writes it, returning it as written.
+// A record that can't be written takes no place in the chain.
+func (l *Log) Record(ctx context.Context, record Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.Type = EventType
+	record.Stream = l.stream
+	record.Sequence = l.sequence + 1
+	record.Time = record.Time.UTC()
+	record.Previous = l.previous
+	hash, err := record.digest()
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: %v", ErrRecord, err)
+	}
+	record.Hash = hash
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return Record{}, fmt.Errorf("%w: encoding record: %v", ErrRecord, err)
+	}
+	if err := l.sink.Write(ctx, record.Time, line); err != nil {
+		return Record{}, fmt.Errorf("%w: %w", ErrRecord, err)
+	}
+
+	l.sequence, l.previous = record.Sequence, record.Hash
+	return record, nil
+}
+
+// Verify checks that records, in the order written, form an unbroken chain
+// of one stream from its first record.
+func Verify(records []Record) error {
+	var previous string
+	for i, record := range records {
+		if record.Stream != records[0].Stream {
+			return fmt.Errorf("%w: record %d is from stream %s, not %s", ErrBrokenChain, record.Sequence, record.Stream, records[0].Stream)
+		}
+		if record.Sequence != uint64(i+1) {
+			return fmt.Errorf("%w: expected record %d, got %d", ErrBrokenChain, i+1, record.Sequence)
+		}
+		if record.Previous != previous {
+			return fmt.Errorf("%w: record %d doesn't follow the record before it", ErrBrokenChain, record.Sequence)
+		}
+		hash, err := record.digest()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrBrokenChain, err)
+		}
+		if record.Hash != hash {
+			return fmt.Errorf("%w: record %d was altered", ErrBrokenChain, record.Sequence)
+		}
+		previous = record.Hash
+	}
+	return nil
+}
+
+// WriterSink writes each record as a single JSON line, e.g. to the function's
+// own log when no separate stream is configured.
+type WriterSink struct {
+	w io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Write(ctx context.Context, at time.Time, line []byte) error {
+	_, err := s.w.Write(append(line, '\n'))
+	return err
+}
+
+type contextKey struct{}
+
+// Trail collects what a request was served. It is safe for concurrent use,
+// since some model calls run in parallel.
+type Trail struct {
+	mu         sync.Mutex
+	haiku      []Haiku
+	moderation []Moderation
+}
+
+// NewContext returns a context carrying a new Trail.
+func NewContext(ctx context.Context) (context.Context, *Trail) {
+	trail := &Trail{}
+	return context.WithValue(ctx, contextKey{}, trail), trail
+}
+
+// FromContext returns the context's Trail, or nil when it has none.
+func FromContext(ctx context.Context) *Trail {
+	trail, _ := ctx.Value(contextKey{}).(*Trail)
+	return trail
+}
+
+// Served records a haiku served to the request. Without a Trail in the
+// context it does nothing, so callers need not check.
+func Served(ctx context.Context, haiku Haiku) {
+	trail := FromContext(ctx)
+	if trail == nil {
+		return
+	}
+
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+	trail.haiku = append(trail.haiku, haiku)
+}
+
+// Moderated records the outcome of screening a generated haiku. Without a
+// Trail in the context it does nothing.
+func Moderated(ctx context.Context, moderation Moderation) {
+	trail := FromContext(ctx)
+	if trail == nil {
+		return
+	}
+
+	trail.mu.Lock()
+	defer trail.mu.Unlock()
+	trail.moderation = append(trail.moderation, moderation)
+}
+
+// Haiku returns the haiku served so far.
+func (t *Trail) Haiku() []Haiku {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.haiku)
+}
+
+// Moderation returns the moderation outcomes recorded so far.
+func (t *Trail) Moderation() []Moderation {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.moderation)
+}
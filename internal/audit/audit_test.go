@@ -0,0 +1,165 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/cloudwatchlogs"
+)
+
+type MockSink struct {
+	ErrorToReturn error
+	Lines         [][]byte
+}
+
+func (m *MockSink) Write(ctx context.Context, at time.Time, line []byte) error {
+	if m.ErrorToReturn != nil {
+		return m.ErrorToReturn
+	}
+	m.Lines = append(m.Lines, line)
+	return nil
+}
+
+func TestLogRecord(t *testing.T) {
+	var out bytes.Buffer
+	log := NewLog(NewWriterSink(&out), "2026/10/15/abc123")
+	when := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+
+	for _, route := range []string{"/haiku", "/haiku/:id/vote", "/admin/keys"} {
+		if _, err := log.Record(context.Background(), Record{Time: when, Method: "POST", Route: route, Path: route, Status: 200}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	var records []Record
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("Failed to unmarshal log line: %v", err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[0].Type != EventType || records[0].Stream != "2026/10/15/abc123" || records[2].Sequence != 3 {
+		t.Errorf("Expected typed, sequenced records of the stream, got %+v", records)
+	}
+	if err := Verify(records); err != nil {
+		t.Errorf("Expected an unbroken chain, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func([]Record) []Record
+	}{
+		{
+			name:   "Record removed",
+			tamper: func(r []Record) []Record { return []Record{r[0], r[2]} },
+		},
+		{
+			name:   "Records reordered",
+			tamper: func(r []Record) []Record { return []Record{r[0], r[2], r[1]} },
+		},
+		{
+			name: "Record altered",
+			tamper: func(r []Record) []Record {
+				altered := append([]Record{}, r...)
+				altered[1].Status = 404
+				return altered
+			},
+		},
+		{
+			name: "Record altered and rehashed",
+			tamper: func(r []Record) []Record {
+				altered := append([]Record{}, r...)
+				altered[1].Caller = "key:someone-else"
+				altered[1].Hash, _ = altered[1].digest()
+				return altered
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := Verify(tc.tamper(records)); !errors.Is(err, ErrBrokenChain) {
+				t.Errorf("Expected error %v, got %v", ErrBrokenChain, err)
+			}
+		})
+	}
+}
+
+func TestLogRecordFailure(t *testing.T) {
+	sink := &MockSink{ErrorToReturn: errors.New("stream unavailable")}
+	log := NewLog(sink, "2026/10/15/abc123")
+
+	if _, err := log.Record(context.Background(), Record{Method: "POST", Path: "/haiku"}); !errors.Is(err, ErrRecord) {
+		t.Fatalf("Expected error %v, got %v", ErrRecord, err)
+	}
+
+	// The failed record takes no place in the chain.
+	sink.ErrorToReturn = nil
+	record, err := log.Record(context.Background(), Record{Method: "POST", Path: "/haiku"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if record.Sequence != 1 || record.Previous != "" {
+		t.Errorf("Expected the first record of the chain, got %+v", record)
+	}
+}
+
+func TestTrail(t *testing.T) {
+	// Without a trail, recording does nothing.
+	Served(context.Background(), Haiku{ID: "abc123"})
+
+	ctx, trail := NewContext(context.Background())
+	Moderated(ctx, Moderation{Blocked: true, Reason: "guardrail"})
+	Moderated(ctx, Moderation{})
+	Served(ctx, Haiku{ID: "abc123", Model: "claude", PromptVersion: "v2"})
+
+	if moderation := trail.Moderation(); len(moderation) != 2 || !moderation[0].Blocked || moderation[1].Blocked {
+		t.Errorf("Expected a blocked then an allowed haiku, got %+v", moderation)
+	}
+	if haiku := trail.Haiku(); len(haiku) != 1 || haiku[0].ID != "abc123" {
+		t.Errorf("Expected haiku abc123 served, got %+v", haiku)
+	}
+}
+
+type MockLogsClient struct {
+	Created []string
+	Events  []cloudwatchlogs.Event
+}
+
+func (m *MockLogsClient) CreateStream(ctx context.Context, name string) error {
+	m.Created = append(m.Created, name)
+	return nil
+}
+
+func (m *MockLogsClient) PutEvents(ctx context.Context, name string, events []cloudwatchlogs.Event) error {
+	m.Events = append(m.Events, events...)
+	return nil
+}
+
+func TestCloudWatchSink(t *testing.T) {
+	client := &MockLogsClient{}
+	log := NewLog(NewCloudWatchSink(client, "2026/10/15/abc123"), "2026/10/15/abc123")
+
+	for range 2 {
+		if _, err := log.Record(context.Background(), Record{Method: "POST", Path: "/haiku"}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	if len(client.Created) != 1 || client.Created[0] != "2026/10/15/abc123" {
+		t.Errorf("Expected the stream created once, got %v", client.Created)
+	}
+	if len(client.Events) != 2 {
+		t.Errorf("Expected 2 events, got %d", len(client.Events))
+	}
+}
@@ -0,0 +1,40 @@
+package audit
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/cloudwatchlogs"
+)
+
+type LogsClient interface {
+	CreateStream(ctx context.Context, name string) error
+	PutEvents(ctx context.Context, name string, events []cloudwatchlogs.Event) error
+}
+
+// CloudWatchSink writes records to a stream of their own in a CloudWatch Logs
+// log group, apart from the function's log, so that the group can be kept
+// longer and read by fewer people. The stream is created with the first
+// record.
+type CloudWatchSink struct {
+	client  LogsClient
+	stream  string
+	created bool
+}
+
+// NewCloudWatchSink writes to stream, which should be the name the Log
+// chains records under. Writes aren't safe for concurrent use, which the Log
+// ensures.
+func NewCloudWatchSink(client LogsClient, stream string) *CloudWatchSink {
+	return &CloudWatchSink{client: client, stream: stream}
+}
+
+func (s *CloudWatchSink) Write(ctx context.Context, at time.Time, line []byte) error {
+	if !s.created {
+		if err := s.client.CreateStream(ctx, s.stream); err != nil {
+			return err
+		}
+		s.created = true
+	}
+	return s.client.PutEvents(ctx, s.stream, []cloudwatchlogs.Event{{Time: at, Message: string(line)}})
+}
@@ -0,0 +1,91 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUEviction(t *testing.T) {
+	c := New[string, int](2, 0)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Get("a") // "b" is now least recently used
+	c.Add("c", 3)
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("Expected least recently used entry to be evicted")
+	}
+	if value, ok := c.Get("a"); !ok || value != 1 {
+		t.Errorf("Expected a=1, got %d (found=%v)", value, ok)
+	}
+	if value, ok := c.Get("c"); !ok || value != 3 {
+		t.Errorf("Expected c=3, got %d (found=%v)", value, ok)
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestLRUReplace(t *testing.T) {
+	c := New[string, int](2, 0)
+
+	c.Add("a", 1)
+	c.Add("a", 2)
+
+	if value, _ := c.Get("a"); value != 2 {
+		t.Errorf("Expected replaced value 2, got %d", value)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", c.Len())
+	}
+}
+
+func TestLRURemove(t *testing.T) {
+	c := New[string, int](2, 0)
+
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Remove("a")
+	c.Remove("missing")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected a to be removed")
+	}
+	if value, _ := c.Get("b"); value != 2 {
+		t.Errorf("Expected b to be kept, got %d", value)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", c.Len())
+	}
+}
+
+func TestLRUExpiry(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	c := New[string, int](2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("a", 1)
+
+	now = now.Add(59 * time.Second)
+	if _, ok := c.Get("a"); !ok {
+		t.Errorf("Expected entry before expiry")
+	}
+
+	now = now.Add(time.Second)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Expected entry to expire")
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected expired entry to be removed, got %d entries", c.Len())
+	}
+}
+
+func TestLRUDisabled(t *testing.T) {
+	c := New[string, int](0, 0)
+
+	c.Add("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Errorf("Expected zero-size cache to store nothing")
+	}
+}
@@ -0,0 +1,95 @@
+// Package canonical reduces commit messages to a canonical form, so messages
+// that differ only in ticket references, commit SHAs, case, or whitespace
+// compare equal.
+//
+// The rules are versioned: any change to what Canonicalize produces must bump
+// Version, so that keys derived from canonical text are not silently reused
+// for different inputs.
+package canonical
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Version identifies the current canonicalization rules.
+const Version = 1
+
+var (
+	// ticketPattern matches issue tracker keys such as JIRA-123 and GH-42,
+	// along with the colon that often follows a key used as a prefix.
+	ticketPattern = regexp.MustCompile(`\b([A-Za-z][A-Za-z0-9]+)-\d+\b:?`)
+	// issuePattern matches issue and pull request references such as #123.
+	issuePattern = regexp.MustCompile(`(^|[\s(\[])#\d+\b`)
+	// shaPattern matches abbreviated and full commit SHAs.
+	shaPattern = regexp.MustCompile(`\b[0-9a-fA-F]{7,40}\b`)
+	// emptyBracketsPattern matches brackets left empty by the rules above.
+	emptyBracketsPattern = regexp.MustCompile(`\(\s*\)|\[\s*\]`)
+)
+
+// standardPrefixes look like ticket keys but name standards, e.g. UTF-8.
+var standardPrefixes = map[string]bool{
+	"utf": true, "sha": true, "iso": true, "rfc": true, "md": true, "es": true, "x": true,
+}
+
+// Canonicalize lowercases message, removes ticket IDs, issue references, and
+// commit SHAs, and collapses whitespace.
+func Canonicalize(message string) string {
+	canonical := ticketPattern.ReplaceAllStringFunc(message, func(match string) string {
+		prefix := match[:strings.IndexByte(match, '-')]
+		if standardPrefixes[strings.ToLower(prefix)] {
+			return match
+		}
+		return ""
+	})
+
+	canonical = issuePattern.ReplaceAllString(canonical, "$1")
+
+	canonical = shaPattern.ReplaceAllStringFunc(canonical, func(match string) string {
+		// Words spelled only with the letters a-f, like "defaced", are not SHAs.
+		if strings.IndexFunc(match, unicode.IsDigit) < 0 {
+			return match
+		}
+		return ""
+	})
+
+	canonical = emptyBracketsPattern.ReplaceAllString(canonical, "")
+
+	return strings.Join(strings.Fields(strings.ToLower(canonical)), " ")
+}
+
+// Key returns the canonical form of message prefixed with the rules version,
+// for use in cache and deduplication keys.
+func Key(message string) string {
+	return "v" + strconv.Itoa(Version) + ":" + Canonicalize(message)
+}
+
+// Similarity returns the Jaccard similarity of the canonical words of a and b,
+// from 0 (no words in common) to 1 (the same set of words).
+func Similarity(a, b string) float64 {
+	wordsA := wordSet(Canonicalize(a))
+	wordsB := wordSet(Canonicalize(b))
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+
+	shared := 0
+	for word := range wordsA {
+		if wordsB[word] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wordsA)+len(wordsB)-shared)
+}
+
+func wordSet(text string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		words[word] = true
+	}
+	return words
+}
@@ -0,0 +1,65 @@
+package canonical
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{name: "Lowercase", message: "Fix Typo", expected: "fix typo"},
+		{name: "Collapse whitespace", message: "  fix \t typo\n\nin readme ", expected: "fix typo in readme"},
+		{name: "Ticket prefix", message: "JIRA-123: fix typo", expected: "fix typo"},
+		{name: "Ticket suffix", message: "fix typo [PROJ-42]", expected: "fix typo"},
+		{name: "Issue reference", message: "fix typo (#123)", expected: "fix typo"},
+		{name: "Issue reference mid-sentence", message: "fix typo, closes #9", expected: "fix typo, closes"},
+		{name: "Short SHA", message: "revert 3f2c9a1", expected: "revert"},
+		{name: "Full SHA", message: "revert 3f2c9a1e5b7d4c6a8f0e2d4b6a8c0e2f4a6b8c0d", expected: "revert"},
+		{name: "Hex-only word kept", message: "remove defaced assets", expected: "remove defaced assets"},
+		{name: "Standard kept", message: "Encode as UTF-8 and hash with SHA-256", expected: "encode as utf-8 and hash with sha-256"},
+		{name: "Empty", message: "", expected: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Canonicalize(tc.message); got != tc.expected {
+				t.Errorf("Canonicalize(%q) = %q, expected %q", tc.message, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	if Key("Fix typo (#12)") != Key("fix  typo (#34)") {
+		t.Errorf("Expected equivalent messages to share a key")
+	}
+	// Keys are part of cache entries; changing this output requires bumping Version.
+	if got := Key("Fix typo"); got != "v1:fix typo" {
+		t.Errorf("Expected stable key %q, got %q", "v1:fix typo", got)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		expected float64
+	}{
+		{name: "Identical after canonicalization", a: "Fix typo (#1)", b: "fix typo", expected: 1},
+		{name: "Disjoint", a: "fix typo", b: "add logging", expected: 0},
+		{name: "Partial overlap", a: "fix login bug", b: "fix signup bug", expected: 0.5},
+		{name: "Both empty", a: "", b: "", expected: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Similarity(tc.a, tc.b); math.Abs(got-tc.expected) > 1e-9 {
+				t.Errorf("Similarity(%q, %q) = %g, expected %g", tc.a, tc.b, got, tc.expected)
+			}
+		})
+	}
+}
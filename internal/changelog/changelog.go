@@ -0,0 +1,159 @@
+// Package changelog parses changelogs written in the Keep a Changelog format.
+//
+// See https://keepachangelog.com for the format. The parser is lenient: it accepts
+// a full changelog document or a single pasted release section, and ignores the
+// surrounding prose and link reference definitions.
+package changelog
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+)
+
+var ErrNoEntries = errors.New("changelog contains no entries")
+
+type Category string
+
+const (
+	CategoryAdded      Category = "Added"
+	CategoryChanged    Category = "Changed"
+	CategoryDeprecated Category = "Deprecated"
+	CategoryRemoved    Category = "Removed"
+	CategoryFixed      Category = "Fixed"
+	CategorySecurity   Category = "Security"
+)
+
+type Section struct {
+	Category Category
+	Entries  []string
+}
+
+type Release struct {
+	Version  string // e.g. "1.2.0" or "Unreleased"; empty for a pasted section without a heading
+	Date     string // e.g. "2024-01-31"; empty when not provided
+	Sections []Section
+}
+
+type Changelog struct {
+	Releases []Release
+}
+
+// Parse reads Keep a Changelog formatted text. Releases without any entries are
+// dropped, and ErrNoEntries is returned if nothing remains.
+func Parse(text string) (Changelog, error) {
+	var (
+		releases []Release
+		release  *Release
+		section  *Section
+	)
+
+	startRelease := func(version, date string) {
+		releases = append(releases, Release{Version: version, Date: date})
+		release = &releases[len(releases)-1]
+		section = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+
+		switch {
+		case line == "" || isLinkReference(line):
+			continue
+		case strings.HasPrefix(line, "### "):
+			if release == nil {
+				startRelease("", "")
+			}
+			release.Sections = append(release.Sections, Section{
+				Category: normalizeCategory(strings.TrimPrefix(line, "### ")),
+			})
+			section = &release.Sections[len(release.Sections)-1]
+		case strings.HasPrefix(line, "## "):
+			startRelease(parseReleaseHeading(strings.TrimPrefix(line, "## ")))
+		case strings.HasPrefix(line, "# "):
+			// Document title
+			continue
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			if section == nil {
+				continue
+			}
+			section.Entries = append(section.Entries, strings.TrimSpace(line[2:]))
+		default:
+			// Wrapped continuation of the previous entry; anything else is prose.
+			if section != nil && len(section.Entries) > 0 && raw != line {
+				last := len(section.Entries) - 1
+				section.Entries[last] += " " + line
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Changelog{}, err
+	}
+
+	changelog := Changelog{}
+	for _, r := range releases {
+		sections := make([]Section, 0, len(r.Sections))
+		for _, s := range r.Sections {
+			if len(s.Entries) > 0 {
+				sections = append(sections, s)
+			}
+		}
+		if len(sections) == 0 {
+			continue
+		}
+		r.Sections = sections
+		changelog.Releases = append(changelog.Releases, r)
+	}
+
+	if len(changelog.Releases) == 0 {
+		return Changelog{}, ErrNoEntries
+	}
+
+	return changelog, nil
+}
+
+// Release returns the release matching version, or the first (most recent)
+// release when version is empty.
+func (c Changelog) Release(version string) (Release, bool) {
+	if version == "" && len(c.Releases) > 0 {
+		return c.Releases[0], true
+	}
+	for _, r := range c.Releases {
+		if strings.EqualFold(r.Version, version) {
+			return r, true
+		}
+	}
+	return Release{}, false
+}
+
+// parseReleaseHeading splits headings such as "[1.0.0] - 2017-06-20" into
+// their version and date.
+func parseReleaseHeading(heading string) (string, string) {
+	version, date, _ := strings.Cut(heading, " - ")
+	version = strings.TrimSpace(version)
+	version = strings.TrimPrefix(version, "[")
+	if i := strings.Index(version, "]"); i >= 0 {
+		version = version[:i]
+	}
+	return strings.TrimSpace(version), strings.TrimSpace(date)
+}
+
+func normalizeCategory(name string) Category {
+	name = strings.TrimSpace(name)
+	for _, c := range []Category{CategoryAdded, CategoryChanged, CategoryDeprecated, CategoryRemoved, CategoryFixed, CategorySecurity} {
+		if strings.EqualFold(name, string(c)) {
+			return c
+		}
+	}
+	return Category(name)
+}
+
+func isLinkReference(line string) bool {
+	if !strings.HasPrefix(line, "[") {
+		return false
+	}
+	end := strings.Index(line, "]:")
+	return end > 0
+}
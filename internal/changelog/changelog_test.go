@@ -0,0 +1,124 @@
+package changelog
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+const sampleChangelog = `# Changelog
+
+All notable changes to this project will be documented in this file.
+
+## [Unreleased]
+
+## [1.1.0] - 2024-03-05
+
+### Added
+- Release notes haiku endpoint
+- Changelog anthology that spans
+  multiple lines
+
+### fixed
+* Login timeout
+
+## [1.0.0] - 2024-01-31
+
+### Removed
+- Legacy XML output
+
+[1.1.0]: https://example.com/compare/v1.0.0...v1.1.0
+[1.0.0]: https://example.com/releases/v1.0.0
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Changelog
+		errorIs  error
+	}{
+		{
+			name:  "Full document",
+			input: sampleChangelog,
+			expected: Changelog{Releases: []Release{
+				{
+					Version: "1.1.0",
+					Date:    "2024-03-05",
+					Sections: []Section{
+						{Category: CategoryAdded, Entries: []string{"Release notes haiku endpoint", "Changelog anthology that spans multiple lines"}},
+						{Category: CategoryFixed, Entries: []string{"Login timeout"}},
+					},
+				},
+				{
+					Version: "1.0.0",
+					Date:    "2024-01-31",
+					Sections: []Section{
+						{Category: CategoryRemoved, Entries: []string{"Legacy XML output"}},
+					},
+				},
+			}},
+		},
+		{
+			name:  "Pasted section without release heading",
+			input: "### Changed\n- Faster builds\n",
+			expected: Changelog{Releases: []Release{
+				{Sections: []Section{{Category: CategoryChanged, Entries: []string{"Faster builds"}}}},
+			}},
+		},
+		{
+			name:  "Unbracketed version and custom category",
+			input: "## 2.0.0\n### Performance\n- Cached responses\n",
+			expected: Changelog{Releases: []Release{
+				{Version: "2.0.0", Sections: []Section{{Category: Category("Performance"), Entries: []string{"Cached responses"}}}},
+			}},
+		},
+		{
+			name:    "No entries",
+			input:   "# Changelog\n\n## [Unreleased]\n### Added\n",
+			errorIs: ErrNoEntries,
+		},
+		{
+			name:    "Plain prose",
+			input:   "Fixed some bugs and added a feature.",
+			errorIs: ErrNoEntries,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			changelog, err := Parse(tc.input)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(changelog, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, changelog)
+			}
+		})
+	}
+}
+
+func TestChangelogRelease(t *testing.T) {
+	changelog, err := Parse(sampleChangelog)
+	if err != nil {
+		t.Fatalf("Failed to parse changelog: %v", err)
+	}
+
+	if release, ok := changelog.Release(""); !ok || release.Version != "1.1.0" {
+		t.Errorf("Expected latest release 1.1.0, got %q", release.Version)
+	}
+	if release, ok := changelog.Release("1.0.0"); !ok || release.Version != "1.0.0" {
+		t.Errorf("Expected release 1.0.0, got %q", release.Version)
+	}
+	if _, ok := changelog.Release("9.9.9"); ok {
+		t.Errorf("Expected missing release to not be found")
+	}
+}
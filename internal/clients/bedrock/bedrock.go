@@ -9,52 +9,142 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metering"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
 )
 
 type BedrockRuntime interface {
 	InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
+	ApplyGuardrail(ctx context.Context, params *bedrockruntime.ApplyGuardrailInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ApplyGuardrailOutput, error)
 }
 
 type BedrockClient struct {
 	runtimeClient BedrockRuntime
+	canary        *Canary
+	budget        *RetryBudget
+	roll          func(n int) int
 }
 
-func NewBedrockClient(runtimeClient BedrockRuntime) *BedrockClient {
-	return &BedrockClient{
+type Options struct {
+	Canary      *Canary                   // Sends a share of one model's requests to another (default: none)
+	HTTPClient  bedrockruntime.HTTPClient // Sends requests to Bedrock, for NewDefaultBedrockClient (default: NewHTTPClient(nil))
+	RetryBudget *RetryBudget              // Limits retries, and sheds requests, while Bedrock throttles (default: NewRetryBudget(nil))
+}
+
+func NewBedrockClient(runtimeClient BedrockRuntime, opts *Options) *BedrockClient {
+	client := &BedrockClient{
 		runtimeClient: runtimeClient,
+		roll:          roll,
+	}
+
+	if opts != nil {
+		client.canary = opts.Canary
+		client.budget = opts.RetryBudget
 	}
+	if client.budget == nil {
+		client.budget = NewRetryBudget(nil)
+	}
+
+	return client
+}
+
+// NewDefaultBedrockClient sends requests to Bedrock with the SDK, which
+// retries them within the client's retry budget. Keep the client for the life
+// of the instance, so that its connections and budget are reused.
+func NewDefaultBedrockClient(cfg aws.Config, opts *Options) *BedrockClient {
+	options := Options{}
+	if opts != nil {
+		options = *opts
+	}
+	if options.HTTPClient == nil {
+		options.HTTPClient = NewHTTPClient(nil)
+	}
+	if options.RetryBudget == nil {
+		options.RetryBudget = NewRetryBudget(nil)
+	}
+
+	runtimeClient := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		o.HTTPClient = options.HTTPClient
+		o.Retryer = options.RetryBudget.Retryer(o.Retryer)
+	})
+	return NewBedrockClient(runtimeClient, &options)
+}
+
+// RetryBudget returns the budget the client's requests are retried within.
+func (c *BedrockClient) RetryBudget() *RetryBudget {
+	return c.budget
 }
 
-func NewDefaultBedrockClient(cfg aws.Config) *BedrockClient {
-	return NewBedrockClient(bedrockruntime.NewFromConfig(cfg))
+// admit fails a request without calling Bedrock while the retry budget sheds
+// load.
+func (c *BedrockClient) admit() error {
+	if c.budget.Admit() {
+		return nil
+	}
+	logging.Warnf("[BEDROCK CLIENT] shedding request while Bedrock throttles")
+	return fmt.Errorf("%w: shedding requests until Bedrock recovers", ErrThrottling)
 }
 
-func (c *BedrockClient) InvokeClaude(ctx context.Context, prompt string, opts *ClaudeOptions) (string, error) {
+// InvokeClaude sends prompt to a Claude model. Options outside the model's
+// limits are clamped, and each adjustment is reported in the result warnings.
+// With a canary configured, the model that served the request, which may not
+// be the one asked for, is reported in the result.
+func (c *BedrockClient) InvokeClaude(ctx context.Context, prompt string, opts *ClaudeOptions) (ClaudeResult, error) {
 	// Validate prompt
 	if prompt == "" {
-		log.Printf("[BEDROCK CLIENT] prompt is empty")
-		return "", fmt.Errorf("%w: prompt cannot be empty", ErrInvalidRequest)
+		logging.Warnf("[BEDROCK CLIENT] prompt is empty")
+		return ClaudeResult{}, fmt.Errorf("%w: prompt cannot be empty", ErrInvalidRequest)
 	}
 
-	modelID := ClaudeModelID
-
 	options := DefaultClaudeOptions()
 	if opts != nil {
+		if opts.ModelID != "" {
+			options.ModelID = opts.ModelID
+		}
 		// Validate and apply MaxTokens (must be positive)
 		if opts.MaxTokens > 0 {
 			options.MaxTokens = opts.MaxTokens
 		}
-		// Validate and apply Temperature (must be between 0.0 and 1.0)
-		if opts.Temperature > 0 && opts.Temperature <= 1.0 {
+		// Validate and apply Temperature (must be positive; clamped to the model limit below)
+		if opts.Temperature > 0 {
 			options.Temperature = opts.Temperature
 		}
 		if opts.System != "" {
 			options.System = opts.System
 		}
+		if opts.Deterministic {
+			options.Temperature = 0
+			options.Deterministic = true
+		}
+	}
+	if !options.Deterministic {
+		options.ModelID = c.route(options.ModelID)
+	}
+
+	var warnings []string
+	sendTemperature := options.Temperature > 0 || options.Deterministic
+	capabilities, ok := LookupCapabilities(options.ModelID)
+	if ok {
+		sendTemperature = sendTemperature && capabilities.SupportsTemperature
+		options, warnings = capabilities.Clamp(options)
+		if !capabilities.SupportsSystem && options.System != "" {
+			prompt = options.System + "\n\n" + prompt
+			options.System = ""
+		}
+	} else {
+		logging.Warnf("[BEDROCK CLIENT] no capabilities registered for model %s, sending options unchanged", options.ModelID)
+	}
+	for _, warning := range warnings {
+		logging.Warnf("[BEDROCK CLIENT] %s", warning)
+	}
+
+	var temperature *float64
+	if sendTemperature {
+		temperature = &options.Temperature
 	}
 
 	request := &ClaudeRequest{
@@ -71,31 +161,55 @@ func (c *BedrockClient) InvokeClaude(ctx context.Context, prompt string, opts *C
 				},
 			},
 		},
-		Temperature: options.Temperature,
+		Temperature: temperature,
 		System:      options.System,
 	}
 
-	body, err := json.Marshal(request)
+	if err := c.admit(); err != nil {
+		return ClaudeResult{}, err
+	}
+	buf, err := encodeRequest(request)
 	if err != nil {
-		log.Printf("[BEDROCK CLIENT] error encountered marshalling request: %v", err)
-		return "", fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+		logging.Errorf("[BEDROCK CLIENT] error encountered marshalling request: %v", err)
+		return ClaudeResult{}, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
 	}
 
+	endBedrock := timing.Start(ctx, timing.StageBedrock)
 	output, err := c.runtimeClient.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
-		ModelId:     aws.String(modelID),
-		ContentType: aws.String("application/json"),
-		Body:        body,
+		ModelId:     aws.String(options.ModelID),
+		ContentType: jsonContentType,
+		Body:        buf.body(),
 	})
+	endBedrock()
+	buf.release()
+	if err != nil {
+		logging.Errorf("[BEDROCK CLIENT] error encountered invoking model: %v", err)
+		err = c.handleBedrockError(err)
+	}
+	c.budget.Record(err)
 	if err != nil {
-		log.Printf("[BEDROCK CLIENT] error encountered invoking model: %v", err)
-		return "", c.handleBedrockError(err)
+		return ClaudeResult{}, err
 	}
 
+	endParse := timing.Start(ctx, timing.StageParse)
+	defer endParse()
 	var response ClaudeResponse
 	if err := json.Unmarshal(output.Body, &response); err != nil {
-		log.Printf("[BEDROCK CLIENT] error encountered parsing response: %v", err)
-		return "", fmt.Errorf("%w: %v", ErrResponseParsing, err)
+		logging.Errorf("[BEDROCK CLIENT] error encountered parsing response: %v", err)
+		return ClaudeResult{}, fmt.Errorf("%w: %v", ErrResponseParsing, err)
+	}
+
+	if len(response.Content) == 0 {
+		logging.Errorf("[BEDROCK CLIENT] response contained no content")
+		return ClaudeResult{}, fmt.Errorf("%w: response contained no content", ErrResponseParsing)
 	}
 
-	return response.Content[0].Text, nil
+	metering.Add(ctx, response.Usage.InputTokens, response.Usage.OutputTokens)
+
+	return ClaudeResult{
+		Text:     response.Content[0].Text,
+		ModelID:  options.ModelID,
+		Usage:    response.Usage,
+		Warnings: warnings,
+	}, nil
 }
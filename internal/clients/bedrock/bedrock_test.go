@@ -4,15 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
 	"github.com/aws/smithy-go"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
 )
 
 type MockBedrockRuntime struct {
-	InvokeModelFunc func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
+	InvokeModelFunc    func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error)
+	ApplyGuardrailFunc func(ctx context.Context, params *bedrockruntime.ApplyGuardrailInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ApplyGuardrailOutput, error)
 }
 
 func (m *MockBedrockRuntime) InvokeModel(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
@@ -22,6 +25,13 @@ func (m *MockBedrockRuntime) InvokeModel(ctx context.Context, params *bedrockrun
 	return nil, errors.New("InvokeModelFunc not implemented")
 }
 
+func (m *MockBedrockRuntime) ApplyGuardrail(ctx context.Context, params *bedrockruntime.ApplyGuardrailInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ApplyGuardrailOutput, error) {
+	if m.ApplyGuardrailFunc != nil {
+		return m.ApplyGuardrailFunc(ctx, params, optFns...)
+	}
+	return nil, errors.New("ApplyGuardrailFunc not implemented")
+}
+
 func TestInvokeClaudeValidation(t *testing.T) {
 	mock := &MockBedrockRuntime{
 		InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
@@ -38,7 +48,7 @@ func TestInvokeClaudeValidation(t *testing.T) {
 		},
 	}
 
-	client := NewBedrockClient(mock)
+	client := NewBedrockClient(mock, nil)
 
 	tests := []struct {
 		name          string
@@ -83,9 +93,9 @@ func TestInvokeClaudeValidation(t *testing.T) {
 			prompt: "Hello, world!",
 			opts: &ClaudeOptions{
 				MaxTokens:   100,
-				Temperature: 1.5, // Invalid value - will be ignored
+				Temperature: 1.5, // Above the model limit - will be clamped
 			},
-			expectError: false, // Not an error, just uses default
+			expectError: false, // Not an error, just a warning
 		},
 	}
 
@@ -179,7 +189,7 @@ func TestInvokeClaudeErrors(t *testing.T) {
 				},
 			}
 
-			client := NewBedrockClient(mock)
+			client := NewBedrockClient(mock, nil)
 			_, err := client.InvokeClaude(context.Background(), "Test prompt", nil)
 
 			if err == nil {
@@ -193,3 +203,59 @@ func TestInvokeClaudeErrors(t *testing.T) {
 		})
 	}
 }
+
+func TestInvokeClaudeStageTimings(t *testing.T) {
+	mock := &MockBedrockRuntime{
+		InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+			return &bedrockruntime.InvokeModelOutput{Body: []byte(`{"content":[{"type":"text","text":"leaves fall"}]}`)}, nil
+		},
+	}
+
+	ctx, timings := timing.NewContext(context.Background(), nil)
+	if _, err := NewBedrockClient(mock, nil).InvokeClaude(ctx, "Write a haiku", nil); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	var names []string
+	for _, stage := range timings.Stages() {
+		names = append(names, stage.Name)
+	}
+	if expected := []string{timing.StageBedrock, timing.StageParse}; !slices.Equal(names, expected) {
+		t.Errorf("Expected stages %v, got %v", expected, names)
+	}
+}
+
+func BenchmarkInvokeClaude(b *testing.B) {
+	body := []byte(`{"content":[{"type":"text","text":"Login fixed at last\nthe gate swings open for all\nautumn leaves drift in"}],"usage":{"input_tokens":412,"output_tokens":24}}`)
+	mock := &MockBedrockRuntime{
+		InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+			return &bedrockruntime.InvokeModelOutput{Body: body}, nil
+		},
+	}
+	client := NewBedrockClient(mock, nil)
+	prompt := "Write a haiku about this commit message: " + strings.Repeat("fix: resolved login issue for users with <special> & unusual characters in passwords\n", 8)
+	options := &ClaudeOptions{System: strings.Repeat("You are a poet who writes haiku about software changes.\n", 20)}
+
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := client.InvokeClaude(context.Background(), prompt, options); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestEncodeRequest(t *testing.T) {
+	for range 2 {
+		buf, err := encodeRequest(ContentBlock{Type: "text", Text: "fix: <b>bold</b> & italics"})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		expected := `{"text":"fix: <b>bold</b> & italics","type":"text"}`
+		if body := string(buf.body()); body != expected {
+			t.Errorf("Expected body %s, got %s", expected, body)
+		}
+		buf.release()
+	}
+}
@@ -0,0 +1,59 @@
+package bedrock
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// maxPooledBuffer is the largest request buffer kept for reuse, so that one
+// unusually long prompt doesn't hold its memory for the life of the instance.
+const maxPooledBuffer = 64 * 1024
+
+// jsonContentType is shared by every request; the SDK only reads it.
+var jsonContentType = aws.String("application/json")
+
+// requestBuffer holds a request body while it's sent, with the encoder
+// writing into it.
+type requestBuffer struct {
+	bytes.Buffer
+	encoder *json.Encoder
+}
+
+// requestBuffers are reused by concurrent invocations, rather than each
+// allocating a body of its own.
+var requestBuffers = sync.Pool{
+	New: func() any {
+		buf := &requestBuffer{}
+		buf.encoder = json.NewEncoder(&buf.Buffer)
+		// Prompts are sent as they are, so HTML characters needn't be escaped.
+		buf.encoder.SetEscapeHTML(false)
+		return buf
+	},
+}
+
+// encodeRequest encodes request as JSON into a pooled buffer, which must be
+// released once the SDK is done with its body, i.e. once InvokeModel returns.
+func encodeRequest(request any) (*requestBuffer, error) {
+	buf := requestBuffers.Get().(*requestBuffer)
+	buf.Reset()
+	if err := buf.encoder.Encode(request); err != nil {
+		buf.release()
+		return nil, err
+	}
+	return buf, nil
+}
+
+// body returns the encoded request, without the newline the encoder ends it
+// with. It's only valid until the buffer is released.
+func (buf *requestBuffer) body() []byte {
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
+}
+
+func (buf *requestBuffer) release() {
+	if buf.Cap() <= maxPooledBuffer {
+		requestBuffers.Put(buf)
+	}
+}
@@ -0,0 +1,39 @@
+package bedrock
+
+import (
+	"math/rand/v2"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// Canary sends a share of the requests for one model to another, so that a
+// new model version serves a little traffic before it replaces the old one.
+type Canary struct {
+	ModelID       string // Model whose requests are shared (default: Claude Haiku 4.5)
+	CanaryModelID string // Model serving the canary's share
+	Percent       int    // Share of ModelID's requests sent to CanaryModelID, 0 to 100
+}
+
+// route returns the model to invoke for a request asking for modelID. Only
+// requests for the canary's model are shared; any other model is invoked as
+// asked.
+func (c *BedrockClient) route(modelID string) string {
+	if c.canary == nil || c.canary.CanaryModelID == "" || c.canary.Percent <= 0 {
+		return modelID
+	}
+
+	primary := c.canary.ModelID
+	if primary == "" {
+		primary = ClaudeModelID
+	}
+	if modelID != primary || c.roll(100) >= c.canary.Percent {
+		return modelID
+	}
+
+	logging.Debugf("[BEDROCK CLIENT] routing request for %s to canary model %s", modelID, c.canary.CanaryModelID)
+	return c.canary.CanaryModelID
+}
+
+func roll(n int) int {
+	return rand.IntN(n) // #nosec G404 -- canary routing is not security sensitive
+}
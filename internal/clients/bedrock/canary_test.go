@@ -0,0 +1,93 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+func TestInvokeClaudeCanary(t *testing.T) {
+	const canaryModelID = "global.anthropic.claude-sonnet-4-5-20250929-v1:0"
+
+	tests := []struct {
+		name          string
+		canary        *Canary
+		roll          int
+		modelID       string
+		deterministic bool
+		expectedModel string
+	}{
+		{
+			name:          "No canary",
+			roll:          0,
+			expectedModel: ClaudeModelID,
+		},
+		{
+			name:          "Within the canary's share",
+			canary:        &Canary{CanaryModelID: canaryModelID, Percent: 5},
+			roll:          4,
+			expectedModel: canaryModelID,
+		},
+		{
+			name:          "Outside the canary's share",
+			canary:        &Canary{CanaryModelID: canaryModelID, Percent: 5},
+			roll:          5,
+			expectedModel: ClaudeModelID,
+		},
+		{
+			name:          "Configured model",
+			canary:        &Canary{ModelID: "us.anthropic.claude-haiku-4-5-20251001-v1:0", CanaryModelID: canaryModelID, Percent: 5},
+			roll:          0,
+			modelID:       "us.anthropic.claude-haiku-4-5-20251001-v1:0",
+			expectedModel: canaryModelID,
+		},
+		{
+			name:          "Other models are not shared",
+			canary:        &Canary{CanaryModelID: canaryModelID, Percent: 100},
+			roll:          0,
+			modelID:       "us.anthropic.claude-3-5-haiku-20241022-v1:0",
+			expectedModel: "us.anthropic.claude-3-5-haiku-20241022-v1:0",
+		},
+		{
+			name:          "Deterministic requests skip the canary",
+			canary:        &Canary{CanaryModelID: canaryModelID, Percent: 100},
+			roll:          0,
+			deterministic: true,
+			expectedModel: ClaudeModelID,
+		},
+		{
+			name:          "Canary off",
+			canary:        &Canary{CanaryModelID: canaryModelID},
+			roll:          0,
+			expectedModel: ClaudeModelID,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var invoked string
+			mock := &MockBedrockRuntime{
+				InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+					invoked = *params.ModelId
+					body, _ := json.Marshal(ClaudeResponse{Content: []ContentBlock{{Text: "leaves fall", Type: "text"}}})
+					return &bedrockruntime.InvokeModelOutput{Body: body}, nil
+				},
+			}
+			client := NewBedrockClient(mock, &Options{Canary: tc.canary})
+			client.roll = func(n int) int { return tc.roll }
+
+			result, err := client.InvokeClaude(context.Background(), "fix: resolved login issue", &ClaudeOptions{ModelID: tc.modelID, Deterministic: tc.deterministic})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if invoked != tc.expectedModel {
+				t.Errorf("Expected %s to be invoked, got %s", tc.expectedModel, invoked)
+			}
+			if result.ModelID != tc.expectedModel {
+				t.Errorf("Expected the result to name %s, got %s", tc.expectedModel, result.ModelID)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package bedrock
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelCapabilities describes the limits and pricing of a Bedrock text model.
+type ModelCapabilities struct {
+	MaxOutputTokens     int     // Largest max_tokens the model accepts
+	MaxTemperature      float64 // Largest temperature the model accepts
+	SupportsSystem      bool    // Whether a system prompt may be sent separately
+	SupportsTemperature bool    // Whether temperature may be set
+	SupportsStreaming   bool    // Whether InvokeModelWithResponseStream is available
+	InputCostPerToken   float64 // USD per input token
+	OutputCostPerToken  float64 // USD per output token
+}
+
+// Capabilities lists the models InvokeClaude knows how to clamp options for.
+// Cross-region inference profile IDs ("global.", "us.", ...) resolve to the
+// underlying model.
+var Capabilities = map[string]ModelCapabilities{
+	"anthropic.claude-haiku-4-5-20251001-v1:0": {
+		MaxOutputTokens:     64000,
+		MaxTemperature:      1.0,
+		SupportsSystem:      true,
+		SupportsTemperature: true,
+		SupportsStreaming:   true,
+		InputCostPerToken:   0.000001,
+		OutputCostPerToken:  0.000005,
+	},
+	"anthropic.claude-sonnet-4-5-20250929-v1:0": {
+		MaxOutputTokens:     64000,
+		MaxTemperature:      1.0,
+		SupportsSystem:      true,
+		SupportsTemperature: true,
+		SupportsStreaming:   true,
+		InputCostPerToken:   0.000003,
+		OutputCostPerToken:  0.000015,
+	},
+	"anthropic.claude-3-5-haiku-20241022-v1:0": {
+		MaxOutputTokens:     8192,
+		MaxTemperature:      1.0,
+		SupportsSystem:      true,
+		SupportsTemperature: true,
+		SupportsStreaming:   true,
+		InputCostPerToken:   0.0000008,
+		OutputCostPerToken:  0.000004,
+	},
+	"anthropic.claude-3-haiku-20240307-v1:0": {
+		MaxOutputTokens:     4096,
+		MaxTemperature:      1.0,
+		SupportsSystem:      true,
+		SupportsTemperature: true,
+		SupportsStreaming:   true,
+		InputCostPerToken:   0.00000025,
+		OutputCostPerToken:  0.00000125,
+	},
+}
+
+// inferenceProfilePrefixes are the geography prefixes of cross-region
+// inference profiles.
+var inferenceProfilePrefixes = []string{"global.", "us.", "eu.", "apac.", "jp.", "au."}
+
+// LookupCapabilities returns the capabilities of modelID, which may be a
+// foundation model ID or a cross-region inference profile ID.
+func LookupCapabilities(modelID string) (ModelCapabilities, bool) {
+	for _, prefix := range inferenceProfilePrefixes {
+		if trimmed, ok := strings.CutPrefix(modelID, prefix); ok {
+			modelID = trimmed
+			break
+		}
+	}
+
+	capabilities, ok := Capabilities[modelID]
+	return capabilities, ok
+}
+
+// Clamp adjusts options to fit within the model's limits, returning the
+// adjusted options and a warning for each change made.
+func (c ModelCapabilities) Clamp(options ClaudeOptions) (ClaudeOptions, []string) {
+	var warnings []string
+
+	if c.MaxOutputTokens > 0 && options.MaxTokens > c.MaxOutputTokens {
+		warnings = append(warnings, fmt.Sprintf("maxTokens reduced from %d to the model limit of %d", options.MaxTokens, c.MaxOutputTokens))
+		options.MaxTokens = c.MaxOutputTokens
+	}
+
+	if !c.SupportsTemperature {
+		if options.Temperature != 0 {
+			warnings = append(warnings, "temperature is not supported by the model and was ignored")
+		}
+		options.Temperature = 0
+	} else if c.MaxTemperature > 0 && options.Temperature > c.MaxTemperature {
+		warnings = append(warnings, fmt.Sprintf("temperature reduced from %g to the model limit of %g", options.Temperature, c.MaxTemperature))
+		options.Temperature = c.MaxTemperature
+	}
+
+	return options, warnings
+}
+
+// Cost returns the estimated USD cost of usage.
+func (c ModelCapabilities) Cost(usage Usage) float64 {
+	return float64(usage.InputTokens)*c.InputCostPerToken + float64(usage.OutputTokens)*c.OutputCostPerToken
+}
@@ -0,0 +1,169 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+func TestLookupCapabilities(t *testing.T) {
+	tests := []struct {
+		name     string
+		modelID  string
+		expected bool
+	}{
+		{name: "Foundation model", modelID: "anthropic.claude-3-haiku-20240307-v1:0", expected: true},
+		{name: "Global inference profile", modelID: ClaudeModelID, expected: true},
+		{name: "Regional inference profile", modelID: "eu.anthropic.claude-sonnet-4-5-20250929-v1:0", expected: true},
+		{name: "Unknown model", modelID: "meta.llama3-70b-instruct-v1:0", expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, ok := LookupCapabilities(tc.modelID); ok != tc.expected {
+				t.Errorf("Expected found=%v for %q, got %v", tc.expected, tc.modelID, ok)
+			}
+		})
+	}
+}
+
+func TestClamp(t *testing.T) {
+	capabilities := ModelCapabilities{
+		MaxOutputTokens:     4096,
+		MaxTemperature:      1.0,
+		SupportsTemperature: true,
+	}
+
+	tests := []struct {
+		name             string
+		capabilities     ModelCapabilities
+		options          ClaudeOptions
+		expected         ClaudeOptions
+		expectedWarnings int
+	}{
+		{
+			name:         "Within limits",
+			capabilities: capabilities,
+			options:      ClaudeOptions{MaxTokens: 500, Temperature: 0.7},
+			expected:     ClaudeOptions{MaxTokens: 500, Temperature: 0.7},
+		},
+		{
+			name:             "Max tokens above limit",
+			capabilities:     capabilities,
+			options:          ClaudeOptions{MaxTokens: 10000, Temperature: 0.7},
+			expected:         ClaudeOptions{MaxTokens: 4096, Temperature: 0.7},
+			expectedWarnings: 1,
+		},
+		{
+			name:             "Temperature above limit",
+			capabilities:     capabilities,
+			options:          ClaudeOptions{MaxTokens: 500, Temperature: 1.5},
+			expected:         ClaudeOptions{MaxTokens: 500, Temperature: 1.0},
+			expectedWarnings: 1,
+		},
+		{
+			name:             "Temperature unsupported",
+			capabilities:     ModelCapabilities{MaxOutputTokens: 4096},
+			options:          ClaudeOptions{MaxTokens: 500, Temperature: 0.7},
+			expected:         ClaudeOptions{MaxTokens: 500},
+			expectedWarnings: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			options, warnings := tc.capabilities.Clamp(tc.options)
+			if !reflect.DeepEqual(options, tc.expected) {
+				t.Errorf("Expected options %+v, got %+v", tc.expected, options)
+			}
+			if len(warnings) != tc.expectedWarnings {
+				t.Errorf("Expected %d warnings, got %v", tc.expectedWarnings, warnings)
+			}
+		})
+	}
+}
+
+func TestCost(t *testing.T) {
+	capabilities := ModelCapabilities{InputCostPerToken: 0.000001, OutputCostPerToken: 0.000005}
+
+	cost := capabilities.Cost(Usage{InputTokens: 1000, OutputTokens: 200})
+	if math.Abs(cost-0.002) > 1e-12 {
+		t.Errorf("Expected cost 0.002, got %g", cost)
+	}
+}
+
+func TestInvokeClaudeClampsOptions(t *testing.T) {
+	var request ClaudeRequest
+	mock := &MockBedrockRuntime{
+		InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+			if err := json.Unmarshal(params.Body, &request); err != nil {
+				t.Fatalf("Failed to unmarshal request: %v", err)
+			}
+			return &bedrockruntime.InvokeModelOutput{
+				Body: []byte(`{"content": [{"type": "text", "text": "leaves"}], "usage": {"input_tokens": 12, "output_tokens": 8}}`),
+			}, nil
+		},
+	}
+
+	result, err := NewBedrockClient(mock, nil).InvokeClaude(context.Background(), "Hello, world!", &ClaudeOptions{
+		ModelID:     "anthropic.claude-3-haiku-20240307-v1:0",
+		MaxTokens:   10000,
+		Temperature: 1.5,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if request.MaxTokens != 4096 || request.Temperature == nil || *request.Temperature != 1.0 {
+		t.Errorf("Expected options clamped to 4096 tokens and temperature 1, got %d and %v", request.MaxTokens, request.Temperature)
+	}
+	if len(result.Warnings) != 2 {
+		t.Errorf("Expected 2 warnings, got %v", result.Warnings)
+	}
+	if result.Text != "leaves" || result.Usage.OutputTokens != 8 {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+}
+
+func TestInvokeClaudeDeterministic(t *testing.T) {
+	tests := []struct {
+		name                string
+		deterministic       bool
+		expectedTemperature float64
+	}{
+		// Temperature 0 has to be sent, or the model samples at its default.
+		{name: "Deterministic", deterministic: true, expectedTemperature: 0},
+		{name: "Not deterministic", expectedTemperature: 0.9},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var request ClaudeRequest
+			mock := &MockBedrockRuntime{
+				InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+					if err := json.Unmarshal(params.Body, &request); err != nil {
+						t.Fatalf("Failed to unmarshal request: %v", err)
+					}
+					return &bedrockruntime.InvokeModelOutput{
+						Body: []byte(`{"content": [{"type": "text", "text": "leaves"}]}`),
+					}, nil
+				},
+			}
+
+			_, err := NewBedrockClient(mock, nil).InvokeClaude(context.Background(), "Hello, world!", &ClaudeOptions{
+				Temperature:   0.9,
+				Deterministic: tc.deterministic,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if request.Temperature == nil || *request.Temperature != tc.expectedTemperature {
+				t.Errorf("Expected temperature %g sent, got %v", tc.expectedTemperature, request.Temperature)
+			}
+		})
+	}
+}
@@ -7,6 +7,11 @@ const (
 	DefaultMaxTokens   = 500
 	DefaultTemperature = 0.7
 
+	TitanImageModelID          = "amazon.titan-image-generator-v2:0"
+	DefaultImageSize           = 512
+	DefaultNegativeImagePrompt = "text, letters, words, watermark, signature"
+	MaxImagePromptLength       = 512
+
 	// AWS Bedrock error codes
 	ValidationExceptionCode           = "ValidationException"
 	ResourceNotFoundExceptionCode     = "ResourceNotFoundException"
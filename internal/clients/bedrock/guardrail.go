@@ -0,0 +1,46 @@
+package bedrock
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// ApplyGuardrail evaluates model output against a Bedrock guardrail and reports
+// whether the guardrail intervened.
+func (c *BedrockClient) ApplyGuardrail(ctx context.Context, guardrailID string, guardrailVersion string, text string) (bool, error) {
+	if guardrailID == "" || guardrailVersion == "" {
+		logging.Warnf("[BEDROCK CLIENT] guardrail identifier or version is empty")
+		return false, fmt.Errorf("%w: guardrail identifier and version are required", ErrInvalidRequest)
+	}
+
+	if err := c.admit(); err != nil {
+		return false, err
+	}
+	output, err := c.runtimeClient.ApplyGuardrail(ctx, &bedrockruntime.ApplyGuardrailInput{
+		GuardrailIdentifier: aws.String(guardrailID),
+		GuardrailVersion:    aws.String(guardrailVersion),
+		Source:              types.GuardrailContentSourceOutput,
+		Content: []types.GuardrailContentBlock{
+			&types.GuardrailContentBlockMemberText{
+				Value: types.GuardrailTextBlock{
+					Text: aws.String(text),
+				},
+			},
+		},
+	})
+	if err != nil {
+		logging.Errorf("[BEDROCK CLIENT] error encountered applying guardrail: %v", err)
+		err = c.handleBedrockError(err)
+	}
+	c.budget.Record(err)
+	if err != nil {
+		return false, err
+	}
+
+	return output.Action == types.GuardrailActionGuardrailIntervened, nil
+}
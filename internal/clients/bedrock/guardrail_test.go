@@ -0,0 +1,90 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go"
+)
+
+func TestApplyGuardrail(t *testing.T) {
+	tests := []struct {
+		name             string
+		guardrailID      string
+		action           types.GuardrailAction
+		mockError        error
+		expectedBlocked  bool
+		expectedErrorIs  error
+		expectInvocation bool
+	}{
+		{
+			name:             "Guardrail intervened",
+			guardrailID:      "gr-123",
+			action:           types.GuardrailActionGuardrailIntervened,
+			expectedBlocked:  true,
+			expectInvocation: true,
+		},
+		{
+			name:             "Guardrail passed",
+			guardrailID:      "gr-123",
+			action:           types.GuardrailActionNone,
+			expectedBlocked:  false,
+			expectInvocation: true,
+		},
+		{
+			name:            "Missing guardrail identifier",
+			guardrailID:     "",
+			expectedErrorIs: ErrInvalidRequest,
+		},
+		{
+			name:        "Throttled",
+			guardrailID: "gr-123",
+			mockError: &smithy.GenericAPIError{
+				Code:    ThrottlingExceptionCode,
+				Message: "Request was throttled",
+			},
+			expectedErrorIs:  ErrThrottling,
+			expectInvocation: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			invoked := false
+			mock := &MockBedrockRuntime{
+				ApplyGuardrailFunc: func(ctx context.Context, params *bedrockruntime.ApplyGuardrailInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.ApplyGuardrailOutput, error) {
+					invoked = true
+					if params.Source != types.GuardrailContentSourceOutput {
+						t.Errorf("Expected output source, got %q", params.Source)
+					}
+					if tc.mockError != nil {
+						return nil, tc.mockError
+					}
+					return &bedrockruntime.ApplyGuardrailOutput{Action: tc.action}, nil
+				},
+			}
+
+			client := NewBedrockClient(mock, nil)
+			blocked, err := client.ApplyGuardrail(context.Background(), tc.guardrailID, "1", "a haiku")
+
+			if invoked != tc.expectInvocation {
+				t.Errorf("Expected invocation %t, got %t", tc.expectInvocation, invoked)
+			}
+			if tc.expectedErrorIs != nil {
+				if !errors.Is(err, tc.expectedErrorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.expectedErrorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if blocked != tc.expectedBlocked {
+				t.Errorf("Expected blocked %t, got %t", tc.expectedBlocked, blocked)
+			}
+		})
+	}
+}
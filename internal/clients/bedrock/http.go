@@ -0,0 +1,67 @@
+package bedrock
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// HTTPOptions tune the connections the Bedrock client keeps to Bedrock.
+type HTTPOptions struct {
+	MaxIdleConnsPerHost int           // Idle connections kept to Bedrock between requests (default: 32)
+	IdleConnTimeout     time.Duration // How long an idle connection is kept (default: 5 minutes)
+	DialTimeout         time.Duration // How long connecting may take (default: 2 seconds)
+	TLSHandshakeTimeout time.Duration // How long the TLS handshake may take (default: 3 seconds)
+	KeepAlive           time.Duration // Interval between TCP keep-alive probes (default: 30 seconds)
+}
+
+func DefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		MaxIdleConnsPerHost: 32,
+		IdleConnTimeout:     5 * time.Minute,
+		DialTimeout:         2 * time.Second,
+		TLSHandshakeTimeout: 3 * time.Second,
+		KeepAlive:           30 * time.Second,
+	}
+}
+
+// NewHTTPClient returns an HTTP client for the Bedrock SDK, built on the
+// SDK's own transport. Every request of an instance goes to the same Bedrock
+// endpoint, so the client keeps more idle connections to it than the SDK
+// does, and keeps them across warm invocations; a dead endpoint fails fast
+// rather than after the SDK's 30 second dial timeout. Options left zero take
+// their defaults.
+func NewHTTPClient(opts *HTTPOptions) *awshttp.BuildableClient {
+	options := DefaultHTTPOptions()
+	if opts != nil {
+		if opts.MaxIdleConnsPerHost > 0 {
+			options.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+		}
+		if opts.IdleConnTimeout > 0 {
+			options.IdleConnTimeout = opts.IdleConnTimeout
+		}
+		if opts.DialTimeout > 0 {
+			options.DialTimeout = opts.DialTimeout
+		}
+		if opts.TLSHandshakeTimeout > 0 {
+			options.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+		}
+		if opts.KeepAlive > 0 {
+			options.KeepAlive = opts.KeepAlive
+		}
+	}
+
+	return awshttp.NewBuildableClient().
+		WithTransportOptions(func(transport *http.Transport) {
+			transport.MaxIdleConns = max(transport.MaxIdleConns, options.MaxIdleConnsPerHost)
+			transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+			transport.IdleConnTimeout = options.IdleConnTimeout
+			transport.TLSHandshakeTimeout = options.TLSHandshakeTimeout
+		}).
+		WithDialerOptions(func(dialer *net.Dialer) {
+			dialer.Timeout = options.DialTimeout
+			dialer.KeepAlive = options.KeepAlive
+		})
+}
@@ -0,0 +1,89 @@
+package bedrock
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type MockHTTPClient struct {
+	Requests []*http.Request
+	Body     string
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.Requests = append(m.Requests, req)
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(m.Body)),
+		Request:    req,
+	}, nil
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	tests := []struct {
+		name                string
+		opts                *HTTPOptions
+		expectedIdlePerHost int
+		expectedDialTimeout time.Duration
+		expectedTLSTimeout  time.Duration
+	}{
+		{
+			name:                "Defaults",
+			expectedIdlePerHost: 32,
+			expectedDialTimeout: 2 * time.Second,
+			expectedTLSTimeout:  3 * time.Second,
+		},
+		{
+			name:                "Overrides",
+			opts:                &HTTPOptions{MaxIdleConnsPerHost: 64, DialTimeout: time.Second},
+			expectedIdlePerHost: 64,
+			expectedDialTimeout: time.Second,
+			expectedTLSTimeout:  3 * time.Second,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := NewHTTPClient(tc.opts)
+			transport, dialer := client.GetTransport(), client.GetDialer()
+			if transport.MaxIdleConnsPerHost != tc.expectedIdlePerHost || transport.MaxIdleConns < tc.expectedIdlePerHost {
+				t.Errorf("Expected %d idle connections per host, got %d of %d", tc.expectedIdlePerHost, transport.MaxIdleConnsPerHost, transport.MaxIdleConns)
+			}
+			if dialer.Timeout != tc.expectedDialTimeout {
+				t.Errorf("Expected dial timeout %v, got %v", tc.expectedDialTimeout, dialer.Timeout)
+			}
+			if transport.TLSHandshakeTimeout != tc.expectedTLSTimeout {
+				t.Errorf("Expected TLS handshake timeout %v, got %v", tc.expectedTLSTimeout, transport.TLSHandshakeTimeout)
+			}
+		})
+	}
+}
+
+func TestNewDefaultBedrockClientHTTPClient(t *testing.T) {
+	httpClient := &MockHTTPClient{Body: `{"content":[{"type":"text","text":"leaves fall"}]}`}
+	cfg := aws.Config{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}
+
+	client := NewDefaultBedrockClient(cfg, &Options{HTTPClient: httpClient})
+	result, err := client.InvokeClaude(context.Background(), "Write a haiku", nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if result.Text != "leaves fall" {
+		t.Errorf("Expected %q, got %q", "leaves fall", result.Text)
+	}
+	if len(httpClient.Requests) != 1 || !strings.HasPrefix(httpClient.Requests[0].URL.Host, "bedrock-runtime.us-east-1.") {
+		t.Errorf("Expected one request to Bedrock through the injected client, got %d", len(httpClient.Requests))
+	}
+}
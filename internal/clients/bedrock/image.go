@@ -0,0 +1,93 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// GenerateImage renders prompt with an image model that accepts the Titan
+// Image Generator request format (Titan Image Generator and Nova Canvas) and
+// returns the image as a base64-encoded PNG.
+func (c *BedrockClient) GenerateImage(ctx context.Context, prompt string, opts *ImageOptions) (string, error) {
+	if prompt == "" {
+		logging.Warnf("[BEDROCK CLIENT] image prompt is empty")
+		return "", fmt.Errorf("%w: prompt cannot be empty", ErrInvalidRequest)
+	}
+
+	options := DefaultImageOptions()
+	if opts != nil {
+		if opts.ModelID != "" {
+			options.ModelID = opts.ModelID
+		}
+		if opts.Width > 0 {
+			options.Width = opts.Width
+		}
+		if opts.Height > 0 {
+			options.Height = opts.Height
+		}
+		if opts.NegativePrompt != "" {
+			options.NegativePrompt = opts.NegativePrompt
+		}
+	}
+
+	// Titan rejects prompts longer than its limit rather than truncating them.
+	if len(prompt) > MaxImagePromptLength {
+		prompt = prompt[:MaxImagePromptLength]
+	}
+
+	request := &ImageRequest{
+		TaskType: "TEXT_IMAGE",
+		TextToImageParams: TextToImageParams{
+			Text:         prompt,
+			NegativeText: options.NegativePrompt,
+		},
+		ImageGenerationConfig: ImageGenerationConfig{
+			NumberOfImages: 1,
+			Width:          options.Width,
+			Height:         options.Height,
+		},
+	}
+
+	if err := c.admit(); err != nil {
+		return "", err
+	}
+	buf, err := encodeRequest(request)
+	if err != nil {
+		logging.Errorf("[BEDROCK CLIENT] error encountered marshalling image request: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+
+	output, err := c.runtimeClient.InvokeModel(ctx, &bedrockruntime.InvokeModelInput{
+		ModelId:     aws.String(options.ModelID),
+		ContentType: jsonContentType,
+		Accept:      jsonContentType,
+		Body:        buf.body(),
+	})
+	buf.release()
+	if err != nil {
+		logging.Errorf("[BEDROCK CLIENT] error encountered invoking image model: %v", err)
+		err = c.handleBedrockError(err)
+	}
+	c.budget.Record(err)
+	if err != nil {
+		return "", err
+	}
+
+	var response ImageResponse
+	if err := json.Unmarshal(output.Body, &response); err != nil {
+		logging.Errorf("[BEDROCK CLIENT] error encountered parsing image response: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrResponseParsing, err)
+	}
+
+	if response.Error != "" || len(response.Images) == 0 {
+		logging.Errorf("[BEDROCK CLIENT] image model returned no image: %s", response.Error)
+		return "", fmt.Errorf("%w: no image returned: %s", ErrModelInvocation, response.Error)
+	}
+
+	return response.Images[0], nil
+}
@@ -0,0 +1,107 @@
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+func TestGenerateImage(t *testing.T) {
+	tests := []struct {
+		name            string
+		prompt          string
+		opts            *ImageOptions
+		response        ImageResponse
+		expectedModelID string
+		expectedImage   string
+		errorIs         error
+	}{
+		{
+			name:            "Default options",
+			prompt:          "maple leaves drifting over a quiet pond",
+			response:        ImageResponse{Images: []string{"aW1hZ2U="}},
+			expectedModelID: TitanImageModelID,
+			expectedImage:   "aW1hZ2U=",
+		},
+		{
+			name:            "Custom model",
+			prompt:          "maple leaves drifting over a quiet pond",
+			opts:            &ImageOptions{ModelID: "amazon.nova-canvas-v1:0"},
+			response:        ImageResponse{Images: []string{"aW1hZ2U="}},
+			expectedModelID: "amazon.nova-canvas-v1:0",
+			expectedImage:   "aW1hZ2U=",
+		},
+		{
+			name:    "Empty prompt",
+			prompt:  "",
+			errorIs: ErrInvalidRequest,
+		},
+		{
+			name:            "Model error",
+			prompt:          "maple leaves",
+			response:        ImageResponse{Error: "content blocked"},
+			expectedModelID: TitanImageModelID,
+			errorIs:         ErrModelInvocation,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockBedrockRuntime{
+				InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+					if got := aws.ToString(params.ModelId); got != tc.expectedModelID {
+						t.Errorf("Expected model %q, got %q", tc.expectedModelID, got)
+					}
+
+					var request ImageRequest
+					if err := json.Unmarshal(params.Body, &request); err != nil {
+						t.Fatalf("Failed to unmarshal request: %v", err)
+					}
+					if request.TaskType != "TEXT_IMAGE" || request.ImageGenerationConfig.Width != DefaultImageSize {
+						t.Errorf("Unexpected image request: %+v", request)
+					}
+
+					body, _ := json.Marshal(tc.response)
+					return &bedrockruntime.InvokeModelOutput{Body: body}, nil
+				},
+			}
+
+			image, err := NewBedrockClient(mock, nil).GenerateImage(context.Background(), tc.prompt, tc.opts)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if image != tc.expectedImage {
+				t.Errorf("Expected image %q, got %q", tc.expectedImage, image)
+			}
+		})
+	}
+}
+
+func TestGenerateImageTruncatesPrompt(t *testing.T) {
+	mock := &MockBedrockRuntime{
+		InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+			var request ImageRequest
+			_ = json.Unmarshal(params.Body, &request)
+			if len(request.TextToImageParams.Text) != MaxImagePromptLength {
+				t.Errorf("Expected prompt of %d characters, got %d", MaxImagePromptLength, len(request.TextToImageParams.Text))
+			}
+			return &bedrockruntime.InvokeModelOutput{Body: []byte(`{"images": ["aW1hZ2U="]}`)}, nil
+		},
+	}
+
+	if _, err := NewBedrockClient(mock, nil).GenerateImage(context.Background(), strings.Repeat("a", 1000), nil); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+}
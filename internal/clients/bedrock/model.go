@@ -5,7 +5,7 @@ type ClaudeRequest struct {
 	MaxTokens        int       `json:"max_tokens"`
 	Messages         []Message `json:"messages"`
 	System           string    `json:"system,omitempty"`
-	Temperature      float64   `json:"temperature,omitempty"`
+	Temperature      *float64  `json:"temperature,omitempty"` // Omitted for models without one
 }
 
 type ContentBlock struct {
@@ -20,17 +20,75 @@ type Message struct {
 
 type ClaudeResponse struct {
 	Content []ContentBlock `json:"content"`
+	Usage   Usage          `json:"usage"`
+}
+
+type Usage struct {
+	InputTokens  int `json:"input_tokens"`
+	OutputTokens int `json:"output_tokens"`
+}
+
+// ClaudeResult is the text generated by InvokeClaude along with how it was produced.
+type ClaudeResult struct {
+	Text     string
+	ModelID  string   // Model that generated Text
+	Usage    Usage    // Tokens consumed by the request
+	Warnings []string // Adjustments made to the requested options
 }
 
 type ClaudeOptions struct {
+	ModelID     string  // Model to invoke (default: Claude Haiku 4.5)
 	MaxTokens   int     // Maximum number of tokens to generate (default: 500)
-	Temperature float64 // Controls randomness (0.0-1.0, default: 0.7)
+	Temperature float64 // Controls randomness (0.0-1.0, default: 0.7; clamped to the model limit)
 	System      string  // Defines the bounds of your task’s specific requirements.
+	// Deterministic samples at temperature 0, in place of Temperature, and
+	// skips any canary, so that the same prompt gets the same reply from the
+	// same model as nearly as the model allows.
+	Deterministic bool
 }
 
 func DefaultClaudeOptions() ClaudeOptions {
 	return ClaudeOptions{
+		ModelID:     ClaudeModelID,
 		MaxTokens:   DefaultMaxTokens,
 		Temperature: DefaultTemperature,
 	}
 }
+
+type ImageRequest struct {
+	TaskType              string                `json:"taskType"`
+	TextToImageParams     TextToImageParams     `json:"textToImageParams"`
+	ImageGenerationConfig ImageGenerationConfig `json:"imageGenerationConfig"`
+}
+
+type TextToImageParams struct {
+	Text         string `json:"text"`
+	NegativeText string `json:"negativeText,omitempty"`
+}
+
+type ImageGenerationConfig struct {
+	NumberOfImages int `json:"numberOfImages"`
+	Width          int `json:"width"`
+	Height         int `json:"height"`
+}
+
+type ImageResponse struct {
+	Images []string `json:"images"`
+	Error  string   `json:"error,omitempty"`
+}
+
+type ImageOptions struct {
+	ModelID        string // Image model to invoke (default: Titan Image Generator v2)
+	Width          int    // Image width in pixels (default: 512)
+	Height         int    // Image height in pixels (default: 512)
+	NegativePrompt string // Content to keep out of the image (default: text and watermarks)
+}
+
+func DefaultImageOptions() ImageOptions {
+	return ImageOptions{
+		ModelID:        TitanImageModelID,
+		Width:          DefaultImageSize,
+		Height:         DefaultImageSize,
+		NegativePrompt: DefaultNegativeImagePrompt,
+	}
+}
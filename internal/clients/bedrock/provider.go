@@ -0,0 +1,28 @@
+package bedrock
+
+import (
+	"context"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// Generate implements extension.Provider with Claude.
+func (c *BedrockClient) Generate(ctx context.Context, prompt string, opts extension.GenerateOptions) (extension.Generation, error) {
+	result, err := c.InvokeClaude(ctx, prompt, &ClaudeOptions{
+		ModelID:     opts.ModelID,
+		MaxTokens:   opts.MaxTokens,
+		Temperature: opts.Temperature,
+		System:      opts.System,
+	})
+	if err != nil {
+		return extension.Generation{}, err
+	}
+
+	return extension.Generation{
+		Text:         result.Text,
+		ModelID:      result.ModelID,
+		InputTokens:  result.Usage.InputTokens,
+		OutputTokens: result.Usage.OutputTokens,
+		Warnings:     result.Warnings,
+	}, nil
+}
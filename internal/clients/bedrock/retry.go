@@ -0,0 +1,171 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+)
+
+// ErrRetryBudgetSpent stops the SDK retrying once the budget can't afford it.
+var ErrRetryBudgetSpent = errors.New("retry budget spent")
+
+type RetryBudgetOptions struct {
+	MaxTokens  float64       // Tokens the budget holds when full (default: 20)
+	TokenRatio float64       // Tokens each successful request earns back (default: 0.1)
+	Cooldown   time.Duration // How long requests are shed once the budget runs out (default: 5 seconds)
+}
+
+func DefaultRetryBudgetOptions() RetryBudgetOptions {
+	return RetryBudgetOptions{
+		MaxTokens:  20,
+		TokenRatio: 0.1,
+		Cooldown:   5 * time.Second,
+	}
+}
+
+// RetryBudget limits the retries of every request of a client together, so
+// that while Bedrock throttles, retries don't multiply the load on it. Each
+// retry and each throttled request spends a token, and each successful
+// request earns back a fraction of one. Requests are retried only while the
+// budget is over half full. Once it runs out, requests are shed, failing
+// without calling Bedrock, for a cooldown; after it, requests are let through
+// again, and the first one throttled starts another.
+type RetryBudget struct {
+	mu         sync.Mutex
+	tokens     float64
+	maxTokens  float64
+	tokenRatio float64
+	cooldown   time.Duration
+	shedUntil  time.Time
+	now        func() time.Time
+}
+
+func NewRetryBudget(opts *RetryBudgetOptions) *RetryBudget {
+	options := DefaultRetryBudgetOptions()
+	if opts != nil {
+		if opts.MaxTokens > 0 {
+			options.MaxTokens = opts.MaxTokens
+		}
+		if opts.TokenRatio > 0 {
+			options.TokenRatio = opts.TokenRatio
+		}
+		if opts.Cooldown > 0 {
+			options.Cooldown = opts.Cooldown
+		}
+	}
+
+	return &RetryBudget{
+		tokens:     options.MaxTokens,
+		maxTokens:  options.MaxTokens,
+		tokenRatio: options.TokenRatio,
+		cooldown:   options.Cooldown,
+		now:        time.Now,
+	}
+}
+
+// RetryBudgetState is a snapshot of a RetryBudget, as reported by /health.
+type RetryBudgetState struct {
+	Tokens        float64    `json:"tokens"`
+	MaxTokens     float64    `json:"maxTokens"`
+	Retrying      bool       `json:"retrying"`                // Whether failed requests are retried
+	Shedding      bool       `json:"shedding"`                // Whether requests fail without calling Bedrock
+	SheddingUntil *time.Time `json:"sheddingUntil,omitempty"` // When requests are let through again
+}
+
+func (b *RetryBudget) State() RetryBudgetState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := RetryBudgetState{
+		Tokens:    b.tokens,
+		MaxTokens: b.maxTokens,
+		Retrying:  b.canRetry(),
+	}
+	if now := b.now(); now.Before(b.shedUntil) {
+		until := b.shedUntil.UTC()
+		state.Shedding, state.SheddingUntil = true, &until
+	}
+	return state
+}
+
+// Admit reports whether a request may call Bedrock, i.e. whether requests
+// aren't being shed.
+func (b *RetryBudget) Admit() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return !b.now().Before(b.shedUntil)
+}
+
+// Record spends a token for a throttled request, or earns a fraction of one
+// back for a successful one. Other failures, e.g. invalid requests, say
+// nothing about Bedrock's load and leave the budget as it is.
+func (b *RetryBudget) Record(err error) {
+	switch {
+	case err == nil:
+		b.mu.Lock()
+		b.tokens = min(b.maxTokens, b.tokens+b.tokenRatio)
+		b.mu.Unlock()
+	case errors.Is(err, ErrThrottling):
+		b.spend()
+	}
+}
+
+// spendRetry spends a token for a retry, when the budget can afford one.
+func (b *RetryBudget) spendRetry() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.canRetry() {
+		return false
+	}
+	b.spendLocked()
+	return true
+}
+
+func (b *RetryBudget) spend() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.spendLocked()
+}
+
+// spendLocked spends a token, starting a cooldown if it was the last. The
+// caller holds b.mu.
+func (b *RetryBudget) spendLocked() {
+	b.tokens = max(0, b.tokens-1)
+	if b.tokens == 0 && !b.now().Before(b.shedUntil) {
+		b.shedUntil = b.now().Add(b.cooldown)
+	}
+}
+
+func (b *RetryBudget) canRetry() bool {
+	return b.tokens > b.maxTokens/2
+}
+
+// Retryer makes retryer, the SDK's, retry only when the budget affords it.
+// Without a retryer of its own, the SDK's standard one is used.
+func (b *RetryBudget) Retryer(retryer aws.Retryer) aws.RetryerV2 {
+	v2, ok := retryer.(aws.RetryerV2)
+	if !ok {
+		v2 = retry.NewStandard()
+	}
+	return budgetRetryer{RetryerV2: v2, budget: b}
+}
+
+type budgetRetryer struct {
+	aws.RetryerV2
+	budget *RetryBudget
+}
+
+func (r budgetRetryer) GetRetryToken(ctx context.Context, opErr error) (func(error) error, error) {
+	if !r.budget.spendRetry() {
+		return nil, fmt.Errorf("%w: not retrying %v", ErrRetryBudgetSpent, opErr)
+	}
+	return r.RetryerV2.GetRetryToken(ctx, opErr)
+}
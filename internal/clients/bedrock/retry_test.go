@@ -0,0 +1,94 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/smithy-go"
+)
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	budget := NewRetryBudget(&RetryBudgetOptions{MaxTokens: 4, TokenRatio: 0.5, Cooldown: 5 * time.Second})
+	budget.now = func() time.Time { return now }
+
+	// A full budget retries, until it's half spent.
+	if !budget.spendRetry() || !budget.spendRetry() {
+		t.Fatal("Expected a full budget to afford retries")
+	}
+	if budget.spendRetry() {
+		t.Error("Expected no retries once the budget is half spent")
+	}
+
+	// Successes earn retries back.
+	budget.Record(nil)
+	if state := budget.State(); state.Tokens != 2.5 || !state.Retrying {
+		t.Errorf("Expected 2.5 tokens and retries, got %+v", state)
+	}
+
+	// Other failures leave the budget as it is.
+	budget.Record(ErrValidation)
+	if state := budget.State(); state.Tokens != 2.5 {
+		t.Errorf("Expected 2.5 tokens, got %+v", state)
+	}
+
+	// Throttled requests spend it, and requests are shed once it runs out.
+	for range 3 {
+		budget.Record(ErrThrottling)
+	}
+	state := budget.State()
+	if budget.Admit() || !state.Shedding || state.Tokens != 0 || state.SheddingUntil == nil || !state.SheddingUntil.Equal(now.Add(5*time.Second)) {
+		t.Errorf("Expected requests shed for the cooldown, got %+v", state)
+	}
+
+	// Requests are let through after the cooldown, and shed again when
+	// throttled.
+	now = now.Add(5 * time.Second)
+	if !budget.Admit() || budget.State().Shedding {
+		t.Error("Expected requests let through after the cooldown")
+	}
+	budget.Record(ErrThrottling)
+	if budget.Admit() {
+		t.Error("Expected requests shed again once throttled")
+	}
+}
+
+func TestBudgetRetryer(t *testing.T) {
+	budget := NewRetryBudget(&RetryBudgetOptions{MaxTokens: 2})
+	retryer := budget.Retryer(retry.NewStandard())
+	throttled := &smithy.GenericAPIError{Code: ThrottlingExceptionCode}
+
+	if _, err := retryer.GetRetryToken(context.Background(), throttled); err != nil {
+		t.Fatalf("Expected a retry within the budget, got: %v", err)
+	}
+	if _, err := retryer.GetRetryToken(context.Background(), throttled); !errors.Is(err, ErrRetryBudgetSpent) {
+		t.Errorf("Expected error %v, got %v", ErrRetryBudgetSpent, err)
+	}
+}
+
+func TestInvokeClaudeShedsLoad(t *testing.T) {
+	calls := 0
+	mock := &MockBedrockRuntime{
+		InvokeModelFunc: func(ctx context.Context, params *bedrockruntime.InvokeModelInput, optFns ...func(*bedrockruntime.Options)) (*bedrockruntime.InvokeModelOutput, error) {
+			calls++
+			return nil, &smithy.GenericAPIError{Code: ThrottlingExceptionCode, Message: "Request was throttled"}
+		},
+	}
+	client := NewBedrockClient(mock, &Options{RetryBudget: NewRetryBudget(&RetryBudgetOptions{MaxTokens: 2})})
+
+	for range 3 {
+		if _, err := client.InvokeClaude(context.Background(), "Write a haiku", nil); !errors.Is(err, ErrThrottling) {
+			t.Errorf("Expected error %v, got %v", ErrThrottling, err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("Expected Bedrock called until the budget ran out, 2 times, got %d", calls)
+	}
+	if state := client.RetryBudget().State(); !state.Shedding {
+		t.Errorf("Expected the client to be shedding requests, got %+v", state)
+	}
+}
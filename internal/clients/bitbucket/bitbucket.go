@@ -0,0 +1,91 @@
+// Package bitbucket posts haiku back to Bitbucket Cloud.
+package bitbucket
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const DefaultBaseURL = "https://api.bitbucket.org/2.0"
+
+var (
+	ErrInvalidRequest = errors.New("invalid bitbucket request")
+	ErrComment        = errors.New("bitbucket comment failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type BitbucketClient struct {
+	httpClient HTTPClient
+	baseURL    string
+	token      string
+}
+
+// NewBitbucketClient authenticates to the Bitbucket API at baseURL with a
+// repository, project or workspace access token.
+func NewBitbucketClient(httpClient HTTPClient, baseURL string, token string) *BitbucketClient {
+	return &BitbucketClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+	}
+}
+
+func NewDefaultBitbucketClient(token string) *BitbucketClient {
+	return NewBitbucketClient(&http.Client{Timeout: 10 * time.Second}, DefaultBaseURL, token)
+}
+
+// CreateCommitComment comments on a commit in repository, given by its full
+// name such as "workspace/repo-slug".
+func (c *BitbucketClient) CreateCommitComment(ctx context.Context, repository, sha, body string) error {
+	workspace, slug, ok := strings.Cut(repository, "/")
+	if !ok || workspace == "" || slug == "" || sha == "" {
+		return fmt.Errorf("%w: repository %q and sha are required", ErrInvalidRequest, repository)
+	}
+
+	path := fmt.Sprintf("/repositories/%s/%s/commit/%s/comments", url.PathEscape(workspace), url.PathEscape(slug), url.PathEscape(sha))
+	return c.post(ctx, path, body)
+}
+
+func (c *BitbucketClient) post(ctx context.Context, path string, body string) error {
+	payload, err := json.Marshal(map[string]any{
+		"content": map[string]string{"raw": body},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: encoding comment: %v", ErrComment, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrComment, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[BITBUCKET CLIENT] error encountered creating comment: %v", err)
+		return fmt.Errorf("%w: %v", ErrComment, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[BITBUCKET CLIENT] comment on %s returned %d", path, resp.StatusCode)
+		return fmt.Errorf("%w: POST %s returned %d: %s", ErrComment, path, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
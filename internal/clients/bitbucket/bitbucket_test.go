@@ -0,0 +1,78 @@
+package bitbucket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateCommitComment(t *testing.T) {
+	tests := []struct {
+		name         string
+		repository   string
+		status       int
+		expectedPath string
+		errorIs      error
+	}{
+		{
+			name:         "Commit comment",
+			repository:   "leafy/leaves",
+			status:       http.StatusCreated,
+			expectedPath: "/2.0/repositories/leafy/leaves/commit/abc123/comments",
+		},
+		{
+			name:       "Comment rejected",
+			repository: "leafy/leaves",
+			status:     http.StatusForbidden,
+			errorIs:    ErrComment,
+		},
+		{
+			name:       "Missing workspace",
+			repository: "leaves",
+			errorIs:    ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			var body struct {
+				Content struct {
+					Raw string `json:"raw"`
+				} `json:"content"`
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != "Bearer bb-token" {
+					t.Errorf("Expected the access token header, got %q", got)
+				}
+				path = r.URL.EscapedPath()
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client := NewBitbucketClient(server.Client(), server.URL+"/2.0/", "bb-token")
+			err := client.CreateCommitComment(context.Background(), tc.repository, "abc123", "falling")
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if path != tc.expectedPath {
+				t.Errorf("Expected path %q, got %q", tc.expectedPath, path)
+			}
+			if body.Content.Raw != "falling" {
+				t.Errorf("Expected the comment as raw content, got %q", body.Content.Raw)
+			}
+		})
+	}
+}
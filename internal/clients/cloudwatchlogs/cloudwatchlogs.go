@@ -0,0 +1,206 @@
+// Package cloudwatchlogs writes log events to a log stream of an Amazon
+// CloudWatch Logs log group, through its JSON API. Requests are signed with
+// the SDK's credentials, so the function's role needs logs:CreateLogStream and
+// logs:PutLogEvents on the group.
+package cloudwatchlogs
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	// signingName is the service name CloudWatch Logs requests are signed for.
+	signingName = "logs"
+
+	createLogStreamTarget = "Logs_20140328.CreateLogStream"
+	putLogEventsTarget    = "Logs_20140328.PutLogEvents"
+
+	// alreadyExists is the error type CreateLogStream returns for a stream
+	// that was created before.
+	alreadyExists = "ResourceAlreadyExistsException"
+)
+
+var (
+	ErrInvalidGroup = errors.New("invalid cloudwatch logs log group")
+	ErrCreateStream = errors.New("creating cloudwatch logs stream failed")
+	ErrPutEvents    = errors.New("putting cloudwatch logs events failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Event is a log event, written to the stream in the order given.
+type Event struct {
+	Time    time.Time
+	Message string
+}
+
+type LogsClient struct {
+	httpClient  HTTPClient
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+	endpoint    string
+	group       string
+	now         func() time.Time
+}
+
+// NewLogsClient writes to streams of the log group named group, in cfg's
+// region.
+func NewLogsClient(httpClient HTTPClient, cfg aws.Config, group string) (*LogsClient, error) {
+	if group == "" || cfg.Region == "" {
+		return nil, fmt.Errorf("%w: no log group or region for %q", ErrInvalidGroup, group)
+	}
+
+	return &LogsClient{
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+		credentials: cfg.Credentials,
+		region:      cfg.Region,
+		endpoint:    fmt.Sprintf("https://logs.%s.amazonaws.com/", cfg.Region),
+		group:       group,
+		now:         time.Now,
+	}, nil
+}
+
+func NewDefaultLogsClient(cfg aws.Config, group string) (*LogsClient, error) {
+	return NewLogsClient(&http.Client{Timeout: 5 * time.Second}, cfg, group)
+}
+
+type createLogStreamRequest struct {
+	LogGroupName  string `json:"logGroupName"`
+	LogStreamName string `json:"logStreamName"`
+}
+
+type inputLogEvent struct {
+	Timestamp int64  `json:"timestamp"`
+	Message   string `json:"message"`
+}
+
+type putLogEventsRequest struct {
+	LogGroupName  string          `json:"logGroupName"`
+	LogStreamName string          `json:"logStreamName"`
+	LogEvents     []inputLogEvent `json:"logEvents"`
+}
+
+type putLogEventsResponse struct {
+	RejectedLogEventsInfo *struct {
+		TooNewLogEventStartIndex *int `json:"tooNewLogEventStartIndex"`
+		TooOldLogEventEndIndex   *int `json:"tooOldLogEventEndIndex"`
+		ExpiredLogEventEndIndex  *int `json:"expiredLogEventEndIndex"`
+	} `json:"rejectedLogEventsInfo"`
+}
+
+// CreateStream creates the stream name in the client's log group. A stream
+// that already exists is left as it is.
+func (c *LogsClient) CreateStream(ctx context.Context, name string) error {
+	status, body, err := c.call(ctx, createLogStreamTarget, createLogStreamRequest{
+		LogGroupName:  c.group,
+		LogStreamName: name,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCreateStream, err)
+	}
+	if status == http.StatusBadRequest && strings.Contains(string(body), alreadyExists) {
+		return nil
+	}
+	if status < 200 || status > 299 {
+		logging.Errorf("[CLOUDWATCH LOGS CLIENT] creating log stream returned %d", status)
+		return fmt.Errorf("%w: CreateLogStream returned %d: %s", ErrCreateStream, status, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// PutEvents writes events to the stream name of the client's log group.
+func (c *LogsClient) PutEvents(ctx context.Context, name string, events []Event) error {
+	request := putLogEventsRequest{
+		LogGroupName:  c.group,
+		LogStreamName: name,
+		LogEvents:     make([]inputLogEvent, 0, len(events)),
+	}
+	for _, event := range events {
+		when := event.Time
+		if when.IsZero() {
+			when = c.now()
+		}
+		request.LogEvents = append(request.LogEvents, inputLogEvent{Timestamp: when.UnixMilli(), Message: event.Message})
+	}
+
+	status, body, err := c.call(ctx, putLogEventsTarget, request)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPutEvents, err)
+	}
+	if status < 200 || status > 299 {
+		logging.Errorf("[CLOUDWATCH LOGS CLIENT] putting log events returned %d", status)
+		return fmt.Errorf("%w: PutLogEvents returned %d: %s", ErrPutEvents, status, bytes.TrimSpace(body))
+	}
+
+	// PutLogEvents succeeds as a whole even when events are too old or too
+	// new to be kept.
+	var result putLogEventsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("%w: decoding response: %v", ErrPutEvents, err)
+	}
+	if result.RejectedLogEventsInfo != nil {
+		logging.Errorf("[CLOUDWATCH LOGS CLIENT] log events rejected")
+		return fmt.Errorf("%w: log events rejected: %s", ErrPutEvents, bytes.TrimSpace(body))
+	}
+	return nil
+}
+
+// call sends payload to the action target, returning the response status
+// and up to 4 KB of its body.
+func (c *LogsClient) call(ctx context.Context, target string, payload any) (int, []byte, error) {
+	if c.credentials == nil {
+		return 0, nil, errors.New("no AWS credentials")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return 0, nil, fmt.Errorf("encoding request: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	credentials, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return 0, nil, fmt.Errorf("retrieving credentials: %v", err)
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, c.now()); err != nil {
+		return 0, nil, fmt.Errorf("signing request: %v", err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[CLOUDWATCH LOGS CLIENT] error encountered calling %s: %v", target, err)
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+
+	detail, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return 0, nil, fmt.Errorf("reading response: %v", err)
+	}
+	return resp.StatusCode, detail, nil
+}
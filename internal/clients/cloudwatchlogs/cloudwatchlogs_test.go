@@ -0,0 +1,139 @@
+package cloudwatchlogs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *LogsClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewLogsClient(server.Client(), aws.Config{
+		Region: "eu-west-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}, "haiku-audit")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	client.endpoint = server.URL
+	return client
+}
+
+func TestCreateStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		errorIs  error
+	}{
+		{
+			name:     "Created",
+			status:   http.StatusOK,
+			response: `{}`,
+		},
+		{
+			name:     "Already exists",
+			status:   http.StatusBadRequest,
+			response: `{"__type": "ResourceAlreadyExistsException"}`,
+		},
+		{
+			name:     "Rejected",
+			status:   http.StatusBadRequest,
+			response: `{"__type": "AccessDeniedException"}`,
+			errorIs:  ErrCreateStream,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var request createLogStreamRequest
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Amz-Target") != createLogStreamTarget {
+					t.Errorf("Expected target %s, got %q", createLogStreamTarget, r.Header.Get("X-Amz-Target"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			})
+
+			err := client.CreateStream(context.Background(), "2026/10/15/abc123")
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			expected := createLogStreamRequest{LogGroupName: "haiku-audit", LogStreamName: "2026/10/15/abc123"}
+			if request != expected {
+				t.Errorf("Expected %+v, got %+v", expected, request)
+			}
+		})
+	}
+}
+
+func TestPutEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		errorIs  error
+	}{
+		{
+			name:     "Put",
+			status:   http.StatusOK,
+			response: `{"nextSequenceToken": "49590"}`,
+		},
+		{
+			name:     "Events rejected",
+			status:   http.StatusOK,
+			response: `{"rejectedLogEventsInfo": {"tooOldLogEventEndIndex": 1}}`,
+			errorIs:  ErrPutEvents,
+		},
+		{
+			name:     "Stream missing",
+			status:   http.StatusBadRequest,
+			response: `{"__type": "ResourceNotFoundException"}`,
+			errorIs:  ErrPutEvents,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var request putLogEventsRequest
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/eu-west-1/logs/aws4_request") {
+					t.Errorf("Expected a request signed for CloudWatch Logs, got %q", auth)
+				}
+				if r.Header.Get("X-Amz-Target") != putLogEventsTarget {
+					t.Errorf("Expected target %s, got %q", putLogEventsTarget, r.Header.Get("X-Amz-Target"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			})
+
+			when := time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)
+			err := client.PutEvents(context.Background(), "2026/10/15/abc123", []Event{{Time: when, Message: `{"sequence": 1}`}})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+
+			if request.LogGroupName != "haiku-audit" || request.LogStreamName != "2026/10/15/abc123" {
+				t.Errorf("Expected the audit group's stream, got %+v", request)
+			}
+			expected := inputLogEvent{Timestamp: when.UnixMilli(), Message: `{"sequence": 1}`}
+			if len(request.LogEvents) != 1 || request.LogEvents[0] != expected {
+				t.Errorf("Expected %+v, got %+v", expected, request.LogEvents)
+			}
+		})
+	}
+}
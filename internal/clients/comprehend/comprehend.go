@@ -0,0 +1,140 @@
+// Package comprehend detects personal data in text with Amazon Comprehend,
+// through its JSON API. Requests are signed with the SDK's credentials, so
+// the function's role needs comprehend:DetectPiiEntities.
+package comprehend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	// signingName is the service name Comprehend requests are signed for.
+	signingName = "comprehend"
+
+	detectPiiEntitiesTarget = "Comprehend_20171127.DetectPiiEntities"
+
+	// MaxTextBytes is the most UTF-8 text DetectPiiEntities accepts.
+	MaxTextBytes = 100 * 1024
+)
+
+var (
+	ErrInvalidRegion = errors.New("invalid comprehend region")
+	ErrDetect        = errors.New("detecting personal data failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Entity is personal data found in text. Offsets count characters, not
+// bytes, from the start of the text.
+type Entity struct {
+	Type        string  `json:"Type"` // e.g. "EMAIL", "NAME" or "AWS_ACCESS_KEY"
+	Score       float64 `json:"Score"`
+	BeginOffset int     `json:"BeginOffset"`
+	EndOffset   int     `json:"EndOffset"`
+}
+
+type ComprehendClient struct {
+	httpClient   HTTPClient
+	signer       *v4.Signer
+	credentials  aws.CredentialsProvider
+	region       string
+	endpoint     string
+	languageCode string
+	now          func() time.Time
+}
+
+// NewComprehendClient detects personal data in English text, in cfg's
+// region.
+func NewComprehendClient(httpClient HTTPClient, cfg aws.Config) (*ComprehendClient, error) {
+	if cfg.Region == "" {
+		return nil, fmt.Errorf("%w: no region", ErrInvalidRegion)
+	}
+
+	return &ComprehendClient{
+		httpClient:   httpClient,
+		signer:       v4.NewSigner(),
+		credentials:  cfg.Credentials,
+		region:       cfg.Region,
+		endpoint:     fmt.Sprintf("https://comprehend.%s.amazonaws.com/", cfg.Region),
+		languageCode: "en",
+		now:          time.Now,
+	}, nil
+}
+
+func NewDefaultComprehendClient(cfg aws.Config) (*ComprehendClient, error) {
+	return NewComprehendClient(&http.Client{Timeout: 5 * time.Second}, cfg)
+}
+
+type detectPiiEntitiesRequest struct {
+	Text         string `json:"Text"`
+	LanguageCode string `json:"LanguageCode"`
+}
+
+type detectPiiEntitiesResponse struct {
+	Entities []Entity `json:"Entities"`
+}
+
+// DetectPiiEntities returns the personal data Comprehend finds in text.
+func (c *ComprehendClient) DetectPiiEntities(ctx context.Context, text string) ([]Entity, error) {
+	if len(text) > MaxTextBytes {
+		return nil, fmt.Errorf("%w: text of %d bytes is over the limit of %d", ErrDetect, len(text), MaxTextBytes)
+	}
+	if c.credentials == nil {
+		return nil, fmt.Errorf("%w: no AWS credentials", ErrDetect)
+	}
+
+	body, err := json.Marshal(detectPiiEntitiesRequest{Text: text, LanguageCode: c.languageCode})
+	if err != nil {
+		return nil, fmt.Errorf("%w: encoding request: %v", ErrDetect, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDetect, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", detectPiiEntitiesTarget)
+
+	credentials, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%w: retrieving credentials: %v", ErrDetect, err)
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, c.now()); err != nil {
+		return nil, fmt.Errorf("%w: signing request: %v", ErrDetect, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[COMPREHEND CLIENT] error encountered calling DetectPiiEntities: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrDetect, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		logging.Errorf("[COMPREHEND CLIENT] detecting personal data returned %d", resp.StatusCode)
+		return nil, fmt.Errorf("%w: DetectPiiEntities returned %d: %s", ErrDetect, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	var result detectPiiEntitiesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: decoding response: %v", ErrDetect, err)
+	}
+	return result.Entities, nil
+}
@@ -0,0 +1,109 @@
+package comprehend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *ComprehendClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewComprehendClient(server.Client(), aws.Config{
+		Region: "eu-west-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	client.endpoint = server.URL
+	return client
+}
+
+func TestDetectPiiEntities(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		status           int
+		response         string
+		expectedEntities []Entity
+		errorIs          error
+	}{
+		{
+			name:     "Entities found",
+			text:     "fix: login for Jane Doe",
+			status:   http.StatusOK,
+			response: `{"Entities": [{"Type": "NAME", "Score": 0.99, "BeginOffset": 15, "EndOffset": 23}]}`,
+			expectedEntities: []Entity{
+				{Type: "NAME", Score: 0.99, BeginOffset: 15, EndOffset: 23},
+			},
+		},
+		{
+			name:     "Nothing found",
+			text:     "fix: resolved login issue",
+			status:   http.StatusOK,
+			response: `{"Entities": []}`,
+		},
+		{
+			name:     "Throttled",
+			text:     "fix: resolved login issue",
+			status:   http.StatusBadRequest,
+			response: `{"__type": "ThrottlingException"}`,
+			errorIs:  ErrDetect,
+		},
+		{
+			name:    "Text too long",
+			text:    strings.Repeat("a", MaxTextBytes+1),
+			errorIs: ErrDetect,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("X-Amz-Target") != detectPiiEntitiesTarget {
+					t.Errorf("Expected target %s, got %q", detectPiiEntitiesTarget, r.Header.Get("X-Amz-Target"))
+				}
+				if !strings.Contains(r.Header.Get("Authorization"), "/comprehend/aws4_request") {
+					t.Errorf("Expected a request signed for comprehend, got %q", r.Header.Get("Authorization"))
+				}
+				var request detectPiiEntitiesRequest
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				if request.Text != tc.text || request.LanguageCode != "en" {
+					t.Errorf("Expected English text %q, got %+v", tc.text, request)
+				}
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			})
+
+			entities, err := client.DetectPiiEntities(context.Background(), tc.text)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if len(entities) != len(tc.expectedEntities) {
+				t.Fatalf("Expected %d entities, got %+v", len(tc.expectedEntities), entities)
+			}
+			for i, expected := range tc.expectedEntities {
+				if entities[i] != expected {
+					t.Errorf("Expected %+v, got %+v", expected, entities[i])
+				}
+			}
+		})
+	}
+}
+
+func TestNewComprehendClientWithoutRegion(t *testing.T) {
+	if _, err := NewComprehendClient(http.DefaultClient, aws.Config{}); !errors.Is(err, ErrInvalidRegion) {
+		t.Errorf("Expected error %v, got %v", ErrInvalidRegion, err)
+	}
+}
@@ -0,0 +1,423 @@
+// Package dynamo stores cached values and counters in a DynamoDB table, so
+// that every Lambda instance shares them.
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+var (
+	ErrGetItem    = errors.New("failed to get cached item")
+	ErrPutItem    = errors.New("failed to store cached item")
+	ErrAddItem    = errors.New("failed to add to counters")
+	ErrDeleteItem = errors.New("failed to delete item")
+)
+
+// Attribute names in the cache table. ExpiresAtAttribute should be the
+// table's TTL attribute, so DynamoDB deletes expired items.
+const (
+	KeyAttribute       = "key"
+	ValueAttribute     = "value"
+	ExpiresAtAttribute = "expiresAt"
+)
+
+type DynamoDBAPI interface {
+	GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+}
+
+type DynamoClient struct {
+	dynamoClient DynamoDBAPI
+	table        string
+	now          func() time.Time
+}
+
+func NewDynamoClient(dynamoClient DynamoDBAPI, table string) *DynamoClient {
+	return &DynamoClient{
+		dynamoClient: dynamoClient,
+		table:        table,
+		now:          time.Now,
+	}
+}
+
+func NewDefaultDynamoClient(cfg aws.Config, table string) *DynamoClient {
+	return NewDynamoClient(dynamodb.NewFromConfig(cfg), table)
+}
+
+// Get returns the value stored under key. Expired items are treated as
+// missing, since DynamoDB may take a while to delete them.
+func (c *DynamoClient) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	output, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			KeyAttribute: &types.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error getting %s: %v", key, err)
+		return nil, false, fmt.Errorf("%w: %v", ErrGetItem, err)
+	}
+
+	value, ok := output.Item[ValueAttribute].(*types.AttributeValueMemberB)
+	if !ok {
+		return nil, false, nil
+	}
+
+	if expiresAt, ok := output.Item[ExpiresAtAttribute].(*types.AttributeValueMemberN); ok {
+		seconds, err := strconv.ParseInt(expiresAt.Value, 10, 64)
+		if err == nil && !c.now().Before(time.Unix(seconds, 0)) {
+			return nil, false, nil
+		}
+	}
+
+	return value.Value, true, nil
+}
+
+// Put stores value under key, replacing any existing value. A zero ttl keeps
+// the item until it is replaced.
+func (c *DynamoClient) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := c.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.table),
+		Item:      c.item(key, value, ttl),
+	})
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error storing %s: %v", key, err)
+		return fmt.Errorf("%w: %v", ErrPutItem, err)
+	}
+	return nil
+}
+
+// PutIfAbsent stores value under key unless an unexpired value is already
+// stored there, and reports whether it was stored. Of several callers racing
+// to store the same key, exactly one succeeds.
+func (c *DynamoClient) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	_, err := c.dynamoClient.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           aws.String(c.table),
+		Item:                c.item(key, value, ttl),
+		ConditionExpression: aws.String("attribute_not_exists(#key) OR #expiresAt <= :now"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":       KeyAttribute,
+			"#expiresAt": ExpiresAtAttribute,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(c.now().Unix(), 10)},
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return false, nil
+	}
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error storing %s: %v", key, err)
+		return false, fmt.Errorf("%w: %v", ErrPutItem, err)
+	}
+	return true, nil
+}
+
+// item builds the item storing value under key, expiring after ttl when it
+// is above zero.
+func (c *DynamoClient) item(key string, value []byte, ttl time.Duration) map[string]types.AttributeValue {
+	item := map[string]types.AttributeValue{
+		KeyAttribute:   &types.AttributeValueMemberS{Value: key},
+		ValueAttribute: &types.AttributeValueMemberB{Value: value},
+	}
+	if ttl > 0 {
+		expiresAt := c.now().Add(ttl).Unix()
+		item[ExpiresAtAttribute] = &types.AttributeValueMemberN{Value: strconv.FormatInt(expiresAt, 10)}
+	}
+	return item
+}
+
+// Delete removes the value stored under key, if any.
+func (c *DynamoClient) Delete(ctx context.Context, key string) error {
+	_, err := c.dynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			KeyAttribute: &types.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error deleting %s: %v", key, err)
+		return fmt.Errorf("%w: %v", ErrDeleteItem, err)
+	}
+	return nil
+}
+
+// ScanPrefix calls fn with the key and value of every unexpired item whose
+// key starts with prefix, in no particular order, until fn returns an error.
+// It reads the whole table, so it suits scheduled work rather than requests.
+func (c *DynamoClient) ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	return c.scan(ctx, prefix, func(key string, item map[string]types.AttributeValue) error {
+		value, ok := item[ValueAttribute].(*types.AttributeValueMemberB)
+		if !ok {
+			return nil
+		}
+		return fn(key, value.Value)
+	})
+}
+
+// ScanCounters calls fn with the key and counters of every unexpired item
+// whose key starts with prefix, as ScanPrefix does with values.
+func (c *DynamoClient) ScanCounters(ctx context.Context, prefix string, fn func(key string, counters map[string]int64) error) error {
+	return c.scan(ctx, prefix, func(key string, item map[string]types.AttributeValue) error {
+		return fn(key, counters(item))
+	})
+}
+
+// scan calls fn with every unexpired item whose key starts with prefix.
+func (c *DynamoClient) scan(ctx context.Context, prefix string, fn func(key string, item map[string]types.AttributeValue) error) error {
+	input := &dynamodb.ScanInput{
+		TableName:        aws.String(c.table),
+		FilterExpression: aws.String("begins_with(#key, :prefix) AND (attribute_not_exists(#expiresAt) OR #expiresAt > :now)"),
+		ExpressionAttributeNames: map[string]string{
+			"#key":       KeyAttribute,
+			"#expiresAt": ExpiresAtAttribute,
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":prefix": &types.AttributeValueMemberS{Value: prefix},
+			":now":    &types.AttributeValueMemberN{Value: strconv.FormatInt(c.now().Unix(), 10)},
+		},
+	}
+
+	for {
+		output, err := c.dynamoClient.Scan(ctx, input)
+		if err != nil {
+			logging.Errorf("[DYNAMO CLIENT] error scanning %s for %s: %v", c.table, prefix, err)
+			return fmt.Errorf("%w: %v", ErrGetItem, err)
+		}
+
+		for _, item := range output.Items {
+			key, ok := item[KeyAttribute].(*types.AttributeValueMemberS)
+			if !ok {
+				continue
+			}
+			if err := fn(key.Value, item); err != nil {
+				return err
+			}
+		}
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// DeleteExpired deletes every item that has expired and returns how many it
+// deleted. DynamoDB deletes expired items itself, but only within a few days
+// of their expiry, so this bounds how long they are kept. Items stored again
+// since the scan found them are left alone.
+func (c *DynamoClient) DeleteExpired(ctx context.Context) (int, error) {
+	names := map[string]string{
+		"#key":       KeyAttribute,
+		"#expiresAt": ExpiresAtAttribute,
+	}
+	values := map[string]types.AttributeValue{
+		":now": &types.AttributeValueMemberN{Value: strconv.FormatInt(c.now().Unix(), 10)},
+	}
+	input := &dynamodb.ScanInput{
+		TableName:                 aws.String(c.table),
+		FilterExpression:          aws.String("#expiresAt <= :now"),
+		ProjectionExpression:      aws.String("#key"),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+	}
+
+	deleted := 0
+	for {
+		output, err := c.dynamoClient.Scan(ctx, input)
+		if err != nil {
+			logging.Errorf("[DYNAMO CLIENT] error scanning %s for expired items: %v", c.table, err)
+			return deleted, fmt.Errorf("%w: %v", ErrDeleteItem, err)
+		}
+
+		for _, item := range output.Items {
+			_, err := c.dynamoClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+				TableName:                 aws.String(c.table),
+				Key:                       map[string]types.AttributeValue{KeyAttribute: item[KeyAttribute]},
+				ConditionExpression:       aws.String("#expiresAt <= :now"),
+				ExpressionAttributeNames:  map[string]string{"#expiresAt": ExpiresAtAttribute},
+				ExpressionAttributeValues: values,
+			})
+			var conditionFailed *types.ConditionalCheckFailedException
+			if errors.As(err, &conditionFailed) {
+				continue
+			}
+			if err != nil {
+				logging.Errorf("[DYNAMO CLIENT] error deleting expired item from %s: %v", c.table, err)
+				return deleted, fmt.Errorf("%w: %v", ErrDeleteItem, err)
+			}
+			deleted++
+		}
+
+		if len(output.LastEvaluatedKey) == 0 {
+			return deleted, nil
+		}
+		input.ExclusiveStartKey = output.LastEvaluatedKey
+	}
+}
+
+// AddCounters atomically adds each delta to the named counter in the item
+// stored under key, creating the item and counters as needed, and returns
+// every counter in the item once added to. Each counter is a number attribute
+// of its own. A ttl above zero moves the item's expiry to ttl from now.
+func (c *DynamoClient) AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error) {
+	if len(deltas) == 0 {
+		return c.GetCounters(ctx, key)
+	}
+
+	names := make(map[string]string, len(deltas)+1)
+	values := make(map[string]types.AttributeValue, len(deltas)+1)
+	adds := make([]string, 0, len(deltas))
+	for _, name := range slices.Sorted(maps.Keys(deltas)) {
+		i := len(adds)
+		names[fmt.Sprintf("#c%d", i)] = name
+		values[fmt.Sprintf(":c%d", i)] = &types.AttributeValueMemberN{Value: strconv.FormatInt(deltas[name], 10)}
+		adds = append(adds, fmt.Sprintf("#c%d :c%d", i, i))
+	}
+
+	expression := "ADD " + strings.Join(adds, ", ")
+	if ttl > 0 {
+		names["#expiresAt"] = ExpiresAtAttribute
+		values[":expiresAt"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(c.now().Add(ttl).Unix(), 10)}
+		expression += " SET #expiresAt = :expiresAt"
+	}
+
+	output, err := c.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			KeyAttribute: &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression:          aws.String(expression),
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		ReturnValues:              types.ReturnValueAllNew,
+	})
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error adding to %s: %v", key, err)
+		return nil, fmt.Errorf("%w: %v", ErrAddItem, err)
+	}
+	return counters(output.Attributes), nil
+}
+
+// RemoveCounters removes the named counters from the item stored under key,
+// leaving its other counters and expiry as they are. A missing item or
+// counter is left missing.
+func (c *DynamoClient) RemoveCounters(ctx context.Context, key string, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	attributes := map[string]string{"#key": KeyAttribute}
+	removes := make([]string, 0, len(names))
+	for i, name := range names {
+		attributes[fmt.Sprintf("#c%d", i)] = name
+		removes = append(removes, fmt.Sprintf("#c%d", i))
+	}
+
+	// Without the condition, removing from a missing item would create it.
+	_, err := c.dynamoClient.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			KeyAttribute: &types.AttributeValueMemberS{Value: key},
+		},
+		UpdateExpression:         aws.String("REMOVE " + strings.Join(removes, ", ")),
+		ConditionExpression:      aws.String("attribute_exists(#key)"),
+		ExpressionAttributeNames: attributes,
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error removing counters from %s: %v", key, err)
+		return fmt.Errorf("%w: %v", ErrDeleteItem, err)
+	}
+	return nil
+}
+
+// GetCounters returns the counters stored under key by AddCounters, or none
+// when there is no such item.
+func (c *DynamoClient) GetCounters(ctx context.Context, key string) (map[string]int64, error) {
+	output, err := c.dynamoClient.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			KeyAttribute: &types.AttributeValueMemberS{Value: key},
+		},
+	})
+	if err != nil {
+		logging.Errorf("[DYNAMO CLIENT] error getting %s: %v", key, err)
+		return nil, fmt.Errorf("%w: %v", ErrGetItem, err)
+	}
+
+	return counters(output.Item), nil
+}
+
+// maxBatchGet is the most keys BatchGetItem reads at once.
+const maxBatchGet = 100
+
+// BatchGetCounters returns the counters stored under each of keys by
+// AddCounters, keyed by key, reading up to 100 items a request. Keys without
+// an item are left out.
+func (c *DynamoClient) BatchGetCounters(ctx context.Context, keys []string) (map[string]map[string]int64, error) {
+	found := make(map[string]map[string]int64, len(keys))
+	for batch := range slices.Chunk(keys, maxBatchGet) {
+		requested := make([]map[string]types.AttributeValue, 0, len(batch))
+		for _, key := range batch {
+			requested = append(requested, map[string]types.AttributeValue{
+				KeyAttribute: &types.AttributeValueMemberS{Value: key},
+			})
+		}
+		pending := map[string]types.KeysAndAttributes{c.table: {Keys: requested}}
+
+		// Keys DynamoDB couldn't read, e.g. when throttled, are asked for
+		// again until every one is read.
+		for len(pending) > 0 {
+			output, err := c.dynamoClient.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				logging.Errorf("[DYNAMO CLIENT] error batch getting from %s: %v", c.table, err)
+				return nil, fmt.Errorf("%w: %v", ErrGetItem, err)
+			}
+			for _, item := range output.Responses[c.table] {
+				if key, ok := item[KeyAttribute].(*types.AttributeValueMemberS); ok {
+					found[key.Value] = counters(item)
+				}
+			}
+			pending = output.UnprocessedKeys
+		}
+	}
+	return found, nil
+}
+
+// counters returns the number attributes of an item, other than its expiry.
+func counters(item map[string]types.AttributeValue) map[string]int64 {
+	counters := make(map[string]int64, len(item))
+	for name, attribute := range item {
+		number, ok := attribute.(*types.AttributeValueMemberN)
+		if !ok || name == ExpiresAtAttribute {
+			continue
+		}
+		if value, err := strconv.ParseInt(number.Value, 10, 64); err == nil {
+			counters[name] = value
+		}
+	}
+	return counters
+}
@@ -0,0 +1,552 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+type MockDynamoDBAPI struct {
+	GetItemFunc    func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error)
+	PutItemFunc    func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error)
+	UpdateItemFunc func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error)
+	DeleteItemFunc func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error)
+	ScanFunc       func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error)
+	BatchGetFunc   func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+}
+
+func (m *MockDynamoDBAPI) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	return m.GetItemFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBAPI) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	return m.PutItemFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBAPI) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	return m.UpdateItemFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBAPI) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	return m.DeleteItemFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBAPI) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	return m.ScanFunc(ctx, params, optFns...)
+}
+
+func (m *MockDynamoDBAPI) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	return m.BatchGetFunc(ctx, params, optFns...)
+}
+
+func TestGet(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		item          map[string]types.AttributeValue
+		mockError     error
+		expectedValue string
+		expectedFound bool
+		errorIs       error
+	}{
+		{
+			name: "Found",
+			item: map[string]types.AttributeValue{
+				KeyAttribute:       &types.AttributeValueMemberS{Value: "abc"},
+				ValueAttribute:     &types.AttributeValueMemberB{Value: []byte("haiku")},
+				ExpiresAtAttribute: &types.AttributeValueMemberN{Value: "1759323600"}, // 13:00
+			},
+			expectedValue: "haiku",
+			expectedFound: true,
+		},
+		{
+			name: "Expired but not yet deleted",
+			item: map[string]types.AttributeValue{
+				KeyAttribute:       &types.AttributeValueMemberS{Value: "abc"},
+				ValueAttribute:     &types.AttributeValueMemberB{Value: []byte("haiku")},
+				ExpiresAtAttribute: &types.AttributeValueMemberN{Value: "1759316400"}, // 11:00
+			},
+		},
+		{
+			name: "No expiry",
+			item: map[string]types.AttributeValue{
+				KeyAttribute:   &types.AttributeValueMemberS{Value: "abc"},
+				ValueAttribute: &types.AttributeValueMemberB{Value: []byte("haiku")},
+			},
+			expectedValue: "haiku",
+			expectedFound: true,
+		},
+		{
+			name: "Missing",
+		},
+		{
+			name:      "DynamoDB error",
+			mockError: errors.New("access denied"),
+			errorIs:   ErrGetItem,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockDynamoDBAPI{
+				GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+					key, _ := params.Key[KeyAttribute].(*types.AttributeValueMemberS)
+					if aws.ToString(params.TableName) != "cache" || key == nil || key.Value != "abc" {
+						t.Errorf("Unexpected get input: %+v", params)
+					}
+					return &dynamodb.GetItemOutput{Item: tc.item}, tc.mockError
+				},
+			}
+
+			client := NewDynamoClient(mock, "cache")
+			client.now = func() time.Time { return now }
+
+			value, found, err := client.Get(context.Background(), "abc")
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if found != tc.expectedFound || string(value) != tc.expectedValue {
+				t.Errorf("Expected %q (found %v), got %q (found %v)", tc.expectedValue, tc.expectedFound, value, found)
+			}
+		})
+	}
+}
+
+func TestPut(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name              string
+		ttl               time.Duration
+		mockError         error
+		expectedExpiresAt string
+		errorIs           error
+	}{
+		{name: "Stored with expiry", ttl: time.Hour, expectedExpiresAt: "1759323600"},
+		{name: "Stored without expiry"},
+		{name: "DynamoDB error", mockError: errors.New("access denied"), errorIs: ErrPutItem},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockDynamoDBAPI{
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					value, _ := params.Item[ValueAttribute].(*types.AttributeValueMemberB)
+					if aws.ToString(params.TableName) != "cache" || value == nil || string(value.Value) != "haiku" {
+						t.Errorf("Unexpected put input: %+v", params)
+					}
+
+					expiresAt := ""
+					if attribute, ok := params.Item[ExpiresAtAttribute].(*types.AttributeValueMemberN); ok {
+						expiresAt = attribute.Value
+					}
+					if expiresAt != tc.expectedExpiresAt {
+						t.Errorf("Expected expiresAt %q, got %q", tc.expectedExpiresAt, expiresAt)
+					}
+					return &dynamodb.PutItemOutput{}, tc.mockError
+				},
+			}
+
+			client := NewDynamoClient(mock, "cache")
+			client.now = func() time.Time { return now }
+
+			err := client.Put(context.Background(), "abc", []byte("haiku"), tc.ttl)
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestPutIfAbsent(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		mockError      error
+		expectedStored bool
+		errorIs        error
+	}{
+		{name: "Stored", expectedStored: true},
+		{name: "Already stored", mockError: &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}},
+		{name: "DynamoDB error", mockError: errors.New("access denied"), errorIs: ErrPutItem},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockDynamoDBAPI{
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if aws.ToString(params.ConditionExpression) == "" {
+						t.Error("Expected a condition on the put")
+					}
+					nowValue, _ := params.ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN)
+					if nowValue == nil || nowValue.Value != "1759320000" {
+						t.Errorf("Expected :now 1759320000, got %+v", params.ExpressionAttributeValues)
+					}
+					return &dynamodb.PutItemOutput{}, tc.mockError
+				},
+			}
+
+			client := NewDynamoClient(mock, "cache")
+			client.now = func() time.Time { return now }
+
+			stored, err := client.PutIfAbsent(context.Background(), "abc", []byte("haiku"), time.Hour)
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if stored != tc.expectedStored {
+				t.Errorf("Expected stored %v, got %v", tc.expectedStored, stored)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		errorIs   error
+	}{
+		{name: "Deleted"},
+		{name: "DynamoDB error", mockError: errors.New("access denied"), errorIs: ErrDeleteItem},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockDynamoDBAPI{
+				DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+					key, _ := params.Key[KeyAttribute].(*types.AttributeValueMemberS)
+					if aws.ToString(params.TableName) != "cache" || key == nil || key.Value != "abc" {
+						t.Errorf("Unexpected delete input: %+v", params)
+					}
+					return &dynamodb.DeleteItemOutput{}, tc.mockError
+				},
+			}
+
+			err := NewDynamoClient(mock, "cache").Delete(context.Background(), "abc")
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestAddCounters(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name               string
+		ttl                time.Duration
+		mockError          error
+		expectedExpression string
+		errorIs            error
+	}{
+		{name: "Added with expiry", ttl: time.Hour, expectedExpression: "ADD #c0 :c0, #c1 :c1 SET #expiresAt = :expiresAt"},
+		{name: "Added without expiry", expectedExpression: "ADD #c0 :c0, #c1 :c1"},
+		{name: "DynamoDB error", mockError: errors.New("access denied"), expectedExpression: "ADD #c0 :c0, #c1 :c1", errorIs: ErrAddItem},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockDynamoDBAPI{
+				UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					if aws.ToString(params.UpdateExpression) != tc.expectedExpression {
+						t.Errorf("Expected expression %q, got %q", tc.expectedExpression, aws.ToString(params.UpdateExpression))
+					}
+					// Counters are added in name order.
+					if params.ExpressionAttributeNames["#c0"] != "haiku" || params.ExpressionAttributeNames["#c1"] != "mood:technical" {
+						t.Errorf("Unexpected counter names: %v", params.ExpressionAttributeNames)
+					}
+					if delta, _ := params.ExpressionAttributeValues[":c1"].(*types.AttributeValueMemberN); delta == nil || delta.Value != "2" {
+						t.Errorf("Expected a delta of 2, got %+v", params.ExpressionAttributeValues[":c1"])
+					}
+					if expiresAt, ok := params.ExpressionAttributeValues[":expiresAt"].(*types.AttributeValueMemberN); ok && expiresAt.Value != "1759323600" {
+						t.Errorf("Expected expiresAt 1759323600, got %s", expiresAt.Value)
+					}
+					if params.ReturnValues != types.ReturnValueAllNew {
+						t.Errorf("Expected every counter to be returned, got %s", params.ReturnValues)
+					}
+					return &dynamodb.UpdateItemOutput{Attributes: map[string]types.AttributeValue{
+						KeyAttribute:     &types.AttributeValueMemberS{Value: "2025-10-01"},
+						"haiku":          &types.AttributeValueMemberN{Value: "7"},
+						"mood:technical": &types.AttributeValueMemberN{Value: "2"},
+					}}, tc.mockError
+				},
+			}
+
+			client := NewDynamoClient(mock, "stats")
+			client.now = func() time.Time { return now }
+
+			counters, err := client.AddCounters(context.Background(), "2025-10-01", map[string]int64{"mood:technical": 2, "haiku": 1}, tc.ttl)
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if err == nil && !maps.Equal(counters, map[string]int64{"haiku": 7, "mood:technical": 2}) {
+				t.Errorf("Expected the added counters, got %v", counters)
+			}
+		})
+	}
+}
+
+func TestRemoveCounters(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		errorIs   error
+	}{
+		{name: "Removed"},
+		{name: "Missing item", mockError: &types.ConditionalCheckFailedException{}},
+		{name: "DynamoDB error", mockError: errors.New("access denied"), errorIs: ErrDeleteItem},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockDynamoDBAPI{
+				UpdateItemFunc: func(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+					if expression := aws.ToString(params.UpdateExpression); expression != "REMOVE #c0, #c1" {
+						t.Errorf("Expected expression %q, got %q", "REMOVE #c0, #c1", expression)
+					}
+					if aws.ToString(params.ConditionExpression) != "attribute_exists(#key)" {
+						t.Errorf("Expected a missing item left missing, got condition %q", aws.ToString(params.ConditionExpression))
+					}
+					if params.ExpressionAttributeNames["#c0"] != "author:Mona" || params.ExpressionAttributeNames["#c1"] != "authorVotes:Mona" {
+						t.Errorf("Unexpected counter names: %v", params.ExpressionAttributeNames)
+					}
+					return &dynamodb.UpdateItemOutput{}, tc.mockError
+				},
+			}
+
+			err := NewDynamoClient(mock, "stats").RemoveCounters(context.Background(), "stats:2025-10-01", []string{"author:Mona", "authorVotes:Mona"})
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestGetCounters(t *testing.T) {
+	mock := &MockDynamoDBAPI{
+		GetItemFunc: func(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+			return &dynamodb.GetItemOutput{Item: map[string]types.AttributeValue{
+				KeyAttribute:       &types.AttributeValueMemberS{Value: "2025-10-01"},
+				ExpiresAtAttribute: &types.AttributeValueMemberN{Value: "1759323600"},
+				"haiku":            &types.AttributeValueMemberN{Value: "12"},
+				"mood:technical":   &types.AttributeValueMemberN{Value: "5"},
+			}}, nil
+		},
+	}
+
+	counters, err := NewDynamoClient(mock, "stats").GetCounters(context.Background(), "2025-10-01")
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	expected := map[string]int64{"haiku": 12, "mood:technical": 5}
+	if !maps.Equal(counters, expected) {
+		t.Errorf("Expected counters %v, got %v", expected, counters)
+	}
+}
+
+func TestBatchGetCounters(t *testing.T) {
+	var requests int
+	mock := &MockDynamoDBAPI{
+		BatchGetFunc: func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+			requests++
+			requested := params.RequestItems["stats"].Keys
+			// The first request leaves its last key unprocessed.
+			var unprocessed map[string]types.KeysAndAttributes
+			if requests == 1 {
+				last := len(requested) - 1
+				requested, unprocessed = requested[:last], map[string]types.KeysAndAttributes{"stats": {Keys: requested[last:]}}
+			}
+
+			var items []map[string]types.AttributeValue
+			for _, key := range requested {
+				if key[KeyAttribute].(*types.AttributeValueMemberS).Value == "2025-10-02" {
+					continue
+				}
+				items = append(items, map[string]types.AttributeValue{
+					KeyAttribute:       key[KeyAttribute],
+					ExpiresAtAttribute: &types.AttributeValueMemberN{Value: "1759323600"},
+					"haiku":            &types.AttributeValueMemberN{Value: "3"},
+				})
+			}
+			return &dynamodb.BatchGetItemOutput{
+				Responses:       map[string][]map[string]types.AttributeValue{"stats": items},
+				UnprocessedKeys: unprocessed,
+			}, nil
+		},
+	}
+
+	found, err := NewDynamoClient(mock, "stats").BatchGetCounters(context.Background(), []string{"2025-10-01", "2025-10-02", "2025-10-03"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected the unprocessed key asked for again, got %d requests", requests)
+	}
+	expected := map[string]int64{"haiku": 3}
+	if len(found) != 2 || !maps.Equal(found["2025-10-01"], expected) || !maps.Equal(found["2025-10-03"], expected) {
+		t.Errorf("Expected the counters of the days with an item, got %v", found)
+	}
+
+	mock.BatchGetFunc = func(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+		return nil, errors.New("throttled")
+	}
+	if _, err := NewDynamoClient(mock, "stats").BatchGetCounters(context.Background(), []string{"2025-10-01"}); !errors.Is(err, ErrGetItem) {
+		t.Errorf("Expected ErrGetItem, got %v", err)
+	}
+}
+
+func TestDeleteExpired(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	keyItem := func(key string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{KeyAttribute: &types.AttributeValueMemberS{Value: key}}
+	}
+	pages := []*dynamodb.ScanOutput{
+		{Items: []map[string]types.AttributeValue{keyItem("a"), keyItem("b")}, LastEvaluatedKey: keyItem("b")},
+		{Items: []map[string]types.AttributeValue{keyItem("c")}},
+	}
+
+	tests := []struct {
+		name            string
+		deleteError     error
+		expectedDeleted int
+		errorIs         error
+	}{
+		{
+			name:            "Deleted across pages",
+			expectedDeleted: 3,
+		},
+		{
+			name:            "Stored again since the scan",
+			deleteError:     &types.ConditionalCheckFailedException{},
+			expectedDeleted: 0,
+		},
+		{
+			name:        "Delete fails",
+			deleteError: errors.New("access denied"),
+			errorIs:     ErrDeleteItem,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var scans []*dynamodb.ScanInput
+			var deletes []string
+			mock := &MockDynamoDBAPI{
+				ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+					scans = append(scans, params)
+					return pages[len(scans)-1], nil
+				},
+				DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+					deletes = append(deletes, params.Key[KeyAttribute].(*types.AttributeValueMemberS).Value)
+					if aws.ToString(params.ConditionExpression) != "#expiresAt <= :now" {
+						t.Errorf("Expected the delete to be conditional on expiry, got %q", aws.ToString(params.ConditionExpression))
+					}
+					return &dynamodb.DeleteItemOutput{}, tc.deleteError
+				},
+			}
+			client := NewDynamoClient(mock, "cache")
+			client.now = func() time.Time { return now }
+
+			deleted, err := client.DeleteExpired(context.Background())
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if deleted != tc.expectedDeleted {
+				t.Errorf("Expected %d deleted, got %d", tc.expectedDeleted, deleted)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if len(scans) != 2 || scans[1].ExclusiveStartKey == nil {
+				t.Errorf("Expected the scan to continue from the first page, got %d scans", len(scans))
+			}
+			if now := scans[0].ExpressionAttributeValues[":now"].(*types.AttributeValueMemberN).Value; now != "1759320000" {
+				t.Errorf("Expected items expired by 1759320000, got %s", now)
+			}
+			if len(deletes) != 3 {
+				t.Errorf("Expected every expired item to be deleted, got %v", deletes)
+			}
+		})
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	item := func(key string, value string) map[string]types.AttributeValue {
+		return map[string]types.AttributeValue{
+			KeyAttribute:   &types.AttributeValueMemberS{Value: key},
+			ValueAttribute: &types.AttributeValueMemberB{Value: []byte(value)},
+		}
+	}
+	pages := []*dynamodb.ScanOutput{
+		{Items: []map[string]types.AttributeValue{item("haiku:a", "leaves"), item("haiku:b", "roots")}, LastEvaluatedKey: item("haiku:b", "")},
+		{Items: []map[string]types.AttributeValue{item("haiku:c", "bark")}},
+	}
+
+	var scans []*dynamodb.ScanInput
+	mock := &MockDynamoDBAPI{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			scans = append(scans, params)
+			return pages[len(scans)-1], nil
+		},
+	}
+
+	scanned := make(map[string]string)
+	err := NewDynamoClient(mock, "votes").ScanPrefix(context.Background(), "haiku:", func(key string, value []byte) error {
+		scanned[key] = string(value)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	expected := map[string]string{"haiku:a": "leaves", "haiku:b": "roots", "haiku:c": "bark"}
+	if !maps.Equal(scanned, expected) {
+		t.Errorf("Expected %v, got %v", expected, scanned)
+	}
+	if prefix := scans[0].ExpressionAttributeValues[":prefix"].(*types.AttributeValueMemberS).Value; prefix != "haiku:" {
+		t.Errorf("Expected the scan filtered by haiku:, got %q", prefix)
+	}
+
+	// An error from fn stops the scan.
+	stop := errors.New("stop")
+	scans = nil
+	err = NewDynamoClient(mock, "votes").ScanPrefix(context.Background(), "haiku:", func(key string, value []byte) error {
+		return stop
+	})
+	if !errors.Is(err, stop) || len(scans) != 1 {
+		t.Errorf("Expected the scan to stop after the first item, got %v after %d scans", err, len(scans))
+	}
+}
+
+func TestScanCounters(t *testing.T) {
+	mock := &MockDynamoDBAPI{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			return &dynamodb.ScanOutput{Items: []map[string]types.AttributeValue{{
+				KeyAttribute:       &types.AttributeValueMemberS{Value: "stats:2025-10-01"},
+				ExpiresAtAttribute: &types.AttributeValueMemberN{Value: "1759323600"},
+				"haiku":            &types.AttributeValueMemberN{Value: "7"},
+			}}}, nil
+		},
+	}
+
+	scanned := make(map[string]map[string]int64)
+	err := NewDynamoClient(mock, "stats").ScanCounters(context.Background(), "stats:", func(key string, counters map[string]int64) error {
+		scanned[key] = counters
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(scanned) != 1 || !maps.Equal(scanned["stats:2025-10-01"], map[string]int64{"haiku": 7}) {
+		t.Errorf("Expected the day's counters, got %v", scanned)
+	}
+}
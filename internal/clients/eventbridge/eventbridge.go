@@ -0,0 +1,172 @@
+// Package eventbridge puts events on an Amazon EventBridge event bus, through
+// its JSON API. Requests are signed with the SDK's credentials, so the
+// function's role needs events:PutEvents on the bus.
+package eventbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	// signingName is the service name EventBridge requests are signed for.
+	signingName = "events"
+	target      = "AWSEvents.PutEvents"
+)
+
+var (
+	ErrInvalidBus = errors.New("invalid eventbridge event bus")
+	ErrPutEvent   = errors.New("putting event on eventbridge failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Entry is an event put on the bus. Rules match on its Source and DetailType,
+// and on the fields of Detail, a JSON object.
+type Entry struct {
+	Source     string
+	DetailType string
+	Detail     string
+	Time       time.Time // When the event happened (default: when it is put)
+}
+
+type EventBridgeClient struct {
+	httpClient  HTTPClient
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+	endpoint    string
+	bus         string
+	now         func() time.Time
+}
+
+// NewEventBridgeClient puts events on bus, given by name or ARN. A bus given
+// by ARN is put on in its own region, and one given by name in cfg's.
+func NewEventBridgeClient(httpClient HTTPClient, cfg aws.Config, bus string) (*EventBridgeClient, error) {
+	region := cfg.Region
+	if arn.IsARN(bus) {
+		parsed, err := arn.Parse(bus)
+		if err != nil || parsed.Service != "events" {
+			return nil, fmt.Errorf("%w: %q is not an event bus arn", ErrInvalidBus, bus)
+		}
+		region = parsed.Region
+	}
+	if bus == "" || region == "" {
+		return nil, fmt.Errorf("%w: no bus or region for %q", ErrInvalidBus, bus)
+	}
+
+	return &EventBridgeClient{
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+		credentials: cfg.Credentials,
+		region:      region,
+		endpoint:    fmt.Sprintf("https://events.%s.amazonaws.com/", region),
+		bus:         bus,
+		now:         time.Now,
+	}, nil
+}
+
+func NewDefaultEventBridgeClient(cfg aws.Config, bus string) (*EventBridgeClient, error) {
+	return NewEventBridgeClient(&http.Client{Timeout: 5 * time.Second}, cfg, bus)
+}
+
+type putEventsEntry struct {
+	Source       string `json:"Source"`
+	DetailType   string `json:"DetailType"`
+	Detail       string `json:"Detail"`
+	EventBusName string `json:"EventBusName"`
+	Time         int64  `json:"Time"`
+}
+
+type putEventsRequest struct {
+	Entries []putEventsEntry `json:"Entries"`
+}
+
+type putEventsResponse struct {
+	FailedEntryCount int `json:"FailedEntryCount"`
+	Entries          []struct {
+		ErrorCode    string `json:"ErrorCode"`
+		ErrorMessage string `json:"ErrorMessage"`
+	} `json:"Entries"`
+}
+
+// PutEvent puts entry on the client's bus.
+func (c *EventBridgeClient) PutEvent(ctx context.Context, entry Entry) error {
+	if c.credentials == nil {
+		return fmt.Errorf("%w: no AWS credentials", ErrPutEvent)
+	}
+
+	when := entry.Time
+	if when.IsZero() {
+		when = c.now()
+	}
+	body, err := json.Marshal(putEventsRequest{Entries: []putEventsEntry{{
+		Source:       entry.Source,
+		DetailType:   entry.DetailType,
+		Detail:       entry.Detail,
+		EventBusName: c.bus,
+		Time:         when.Unix(),
+	}}})
+	if err != nil {
+		return fmt.Errorf("%w: encoding request: %v", ErrPutEvent, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPutEvent, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	credentials, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: retrieving credentials: %v", ErrPutEvent, err)
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, c.now()); err != nil {
+		return fmt.Errorf("%w: signing request: %v", ErrPutEvent, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[EVENTBRIDGE CLIENT] error encountered putting event: %v", err)
+		return fmt.Errorf("%w: %v", ErrPutEvent, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[EVENTBRIDGE CLIENT] putting event returned %d", resp.StatusCode)
+		return fmt.Errorf("%w: PutEvents returned %d: %s", ErrPutEvent, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	// PutEvents succeeds as a whole even when its entries fail.
+	var result putEventsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%w: decoding response: %v", ErrPutEvent, err)
+	}
+	if result.FailedEntryCount > 0 {
+		var code, message string
+		if len(result.Entries) > 0 {
+			code, message = result.Entries[0].ErrorCode, result.Entries[0].ErrorMessage
+		}
+		logging.Errorf("[EVENTBRIDGE CLIENT] event refused: %s", code)
+		return fmt.Errorf("%w: event refused: %s: %s", ErrPutEvent, code, message)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package eventbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const testBus = "arn:aws:events:eu-west-1:123456789012:event-bus/haiku"
+
+func TestNewEventBridgeClient(t *testing.T) {
+	tests := []struct {
+		name           string
+		bus            string
+		region         string
+		expectedRegion string
+		errorIs        error
+	}{
+		{
+			name:           "Bus ARN",
+			bus:            testBus,
+			region:         "us-east-1",
+			expectedRegion: "eu-west-1",
+		},
+		{
+			name:           "Bus name",
+			bus:            "default",
+			region:         "us-east-1",
+			expectedRegion: "us-east-1",
+		},
+		{
+			name:    "Not a bus",
+			bus:     "arn:aws:sns:eu-west-1:123456789012:haiku-events",
+			errorIs: ErrInvalidBus,
+		},
+		{
+			name:    "No region",
+			bus:     "default",
+			errorIs: ErrInvalidBus,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := NewDefaultEventBridgeClient(aws.Config{Region: tc.region}, tc.bus)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs == nil && client.region != tc.expectedRegion {
+				t.Errorf("Expected region %s, got %s", tc.expectedRegion, client.region)
+			}
+		})
+	}
+}
+
+func TestPutEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		response string
+		errorIs  error
+	}{
+		{
+			name:     "Put",
+			status:   http.StatusOK,
+			response: `{"FailedEntryCount": 0, "Entries": [{"EventId": "e1"}]}`,
+		},
+		{
+			name:     "Entry refused",
+			status:   http.StatusOK,
+			response: `{"FailedEntryCount": 1, "Entries": [{"ErrorCode": "InternalFailure", "ErrorMessage": "try again"}]}`,
+			errorIs:  ErrPutEvent,
+		},
+		{
+			name:     "Rejected",
+			status:   http.StatusBadRequest,
+			response: `{"__type": "AccessDeniedException"}`,
+			errorIs:  ErrPutEvent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var request putEventsRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/eu-west-1/events/aws4_request") {
+					t.Errorf("Expected a request signed for EventBridge in the bus's region, got %q", auth)
+				}
+				if r.Header.Get("X-Amz-Target") != target {
+					t.Errorf("Expected target %s, got %q", target, r.Header.Get("X-Amz-Target"))
+				}
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+
+			client, err := NewEventBridgeClient(server.Client(), aws.Config{
+				Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+				}),
+			}, testBus)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			client.endpoint = server.URL
+
+			when := time.Date(2025, 10, 10, 9, 0, 0, 0, time.UTC)
+			err = client.PutEvent(context.Background(), Entry{
+				Source:     "commits-fall-like-leaves",
+				DetailType: "haiku.created",
+				Detail:     `{"haiku": "frost"}`,
+				Time:       when,
+			})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+
+			expected := putEventsEntry{
+				Source:       "commits-fall-like-leaves",
+				DetailType:   "haiku.created",
+				Detail:       `{"haiku": "frost"}`,
+				EventBusName: testBus,
+				Time:         when.Unix(),
+			}
+			if len(request.Entries) != 1 || request.Entries[0] != expected {
+				t.Errorf("Expected %+v, got %+v", expected, request.Entries)
+			}
+		})
+	}
+}
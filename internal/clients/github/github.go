@@ -0,0 +1,310 @@
+// Package github posts haiku back to GitHub as a GitHub App, and looks up the
+// issues commits reference.
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	DefaultBaseURL = "https://api.github.com"
+
+	apiVersion = "2022-11-28"
+
+	// appTokenTTL is how long an app JWT is valid; GitHub allows at most ten
+	// minutes.
+	appTokenTTL = 9 * time.Minute
+	// clockSkew backdates app JWTs to tolerate drift between our clock and
+	// GitHub's.
+	clockSkew = time.Minute
+	// tokenRefreshMargin renews installation tokens shortly before they expire
+	// so that a request never starts with a token about to lapse.
+	tokenRefreshMargin = time.Minute
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid github request")
+	ErrInvalidKey     = errors.New("invalid github app private key")
+	ErrAuthentication = errors.New("github app authentication failed")
+	ErrComment        = errors.New("github comment failed")
+	ErrIssue          = errors.New("github issue lookup failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type installationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+type GitHubClient struct {
+	httpClient HTTPClient
+	baseURL    string
+	appID      string
+	privateKey *rsa.PrivateKey
+
+	mu            sync.Mutex
+	tokens        map[int64]installationToken
+	installations map[string]int64 // Installation IDs by "owner/repo"
+	now           func() time.Time
+}
+
+// NewGitHubClient authenticates as the GitHub App appID, which may be the
+// app's numeric ID or its client ID.
+func NewGitHubClient(httpClient HTTPClient, baseURL string, appID string, privateKey *rsa.PrivateKey) *GitHubClient {
+	return &GitHubClient{
+		httpClient:    httpClient,
+		baseURL:       baseURL,
+		appID:         appID,
+		privateKey:    privateKey,
+		tokens:        make(map[int64]installationToken),
+		installations: make(map[string]int64),
+		now:           time.Now,
+	}
+}
+
+// NewDefaultGitHubClient authenticates against github.com with the app's PEM
+// encoded private key.
+func NewDefaultGitHubClient(appID string, privateKeyPEM string) (*GitHubClient, error) {
+	privateKey, err := ParsePrivateKey([]byte(privateKeyPEM))
+	if err != nil {
+		return nil, err
+	}
+	return NewGitHubClient(&http.Client{Timeout: 10 * time.Second}, DefaultBaseURL, appID, privateKey), nil
+}
+
+// ParsePrivateKey decodes an RSA private key in the PKCS#1 format GitHub
+// issues, or PKCS#8.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM block found", ErrInvalidKey)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: key is not RSA", ErrInvalidKey)
+	}
+	return key, nil
+}
+
+// CreateCommitComment comments on a commit in owner/repo.
+func (c *GitHubClient) CreateCommitComment(ctx context.Context, installationID int64, owner, repo, sha, body string) error {
+	if owner == "" || repo == "" || sha == "" {
+		return fmt.Errorf("%w: owner, repo and sha are required", ErrInvalidRequest)
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/commits/%s/comments", url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(sha))
+	return c.createComment(ctx, installationID, path, body)
+}
+
+// CreateIssueComment comments on an issue or pull request in owner/repo.
+func (c *GitHubClient) CreateIssueComment(ctx context.Context, installationID int64, owner, repo string, number int, body string) error {
+	if owner == "" || repo == "" || number <= 0 {
+		return fmt.Errorf("%w: owner, repo and number are required", ErrInvalidRequest)
+	}
+
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d/comments", url.PathEscape(owner), url.PathEscape(repo), number)
+	return c.createComment(ctx, installationID, path, body)
+}
+
+func (c *GitHubClient) createComment(ctx context.Context, installationID int64, path string, body string) error {
+	token, err := c.InstallationToken(ctx, installationID)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]string{"body": body})
+	if err != nil {
+		return fmt.Errorf("%w: encoding comment: %v", ErrComment, err)
+	}
+
+	if err := c.do(ctx, http.MethodPost, path, "token "+token, payload, nil); err != nil {
+		logging.Errorf("[GITHUB CLIENT] error encountered creating comment: %v", err)
+		return fmt.Errorf("%w: %v", ErrComment, err)
+	}
+
+	return nil
+}
+
+// IssueTitle returns the title of issue or pull request number in
+// owner/repo, which must have the app installed with read access to issues.
+func (c *GitHubClient) IssueTitle(ctx context.Context, owner, repo string, number int) (string, error) {
+	if owner == "" || repo == "" || number <= 0 {
+		return "", fmt.Errorf("%w: owner, repo and number are required", ErrInvalidRequest)
+	}
+
+	installationID, err := c.RepositoryInstallation(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	token, err := c.InstallationToken(ctx, installationID)
+	if err != nil {
+		return "", err
+	}
+
+	var issue struct {
+		Title string `json:"title"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/issues/%d", url.PathEscape(owner), url.PathEscape(repo), number)
+	if err := c.do(ctx, http.MethodGet, path, "token "+token, nil, &issue); err != nil {
+		logging.Errorf("[GITHUB CLIENT] error encountered looking up issue: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrIssue, err)
+	}
+	return issue.Title, nil
+}
+
+// RepositoryInstallation returns the ID of the app's installation on
+// owner/repo. Installations rarely change, so IDs are cached for the life of
+// the client.
+func (c *GitHubClient) RepositoryInstallation(ctx context.Context, owner, repo string) (int64, error) {
+	name := owner + "/" + repo
+	c.mu.Lock()
+	installationID, ok := c.installations[name]
+	c.mu.Unlock()
+	if ok {
+		return installationID, nil
+	}
+
+	jwt, err := c.appToken()
+	if err != nil {
+		return 0, err
+	}
+
+	var installation struct {
+		ID int64 `json:"id"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/installation", url.PathEscape(owner), url.PathEscape(repo))
+	if err := c.do(ctx, http.MethodGet, path, "Bearer "+jwt, nil, &installation); err != nil {
+		logging.Errorf("[GITHUB CLIENT] error encountered finding installation: %v", err)
+		return 0, fmt.Errorf("%w: %v", ErrAuthentication, err)
+	}
+
+	c.mu.Lock()
+	c.installations[name] = installation.ID
+	c.mu.Unlock()
+	return installation.ID, nil
+}
+
+// InstallationToken returns an access token for the app installation,
+// exchanging a fresh app JWT when no unexpired token is cached.
+func (c *GitHubClient) InstallationToken(ctx context.Context, installationID int64) (string, error) {
+	if installationID <= 0 {
+		return "", fmt.Errorf("%w: installation id is required", ErrInvalidRequest)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if cached, ok := c.tokens[installationID]; ok && c.now().Add(tokenRefreshMargin).Before(cached.expiresAt) {
+		return cached.token, nil
+	}
+
+	jwt, err := c.appToken()
+	if err != nil {
+		return "", err
+	}
+
+	var response struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	path := "/app/installations/" + strconv.FormatInt(installationID, 10) + "/access_tokens"
+	if err := c.do(ctx, http.MethodPost, path, "Bearer "+jwt, nil, &response); err != nil {
+		logging.Errorf("[GITHUB CLIENT] error encountered exchanging installation token: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrAuthentication, err)
+	}
+	if response.Token == "" {
+		return "", fmt.Errorf("%w: empty installation token", ErrAuthentication)
+	}
+
+	c.tokens[installationID] = installationToken{
+		token:     response.Token,
+		expiresAt: response.ExpiresAt,
+	}
+
+	return response.Token, nil
+}
+
+// appToken signs a short-lived RS256 JWT identifying the app itself.
+func (c *GitHubClient) appToken() (string, error) {
+	now := c.now()
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]any{
+		"iat": now.Add(-clockSkew).Unix(),
+		"exp": now.Add(appTokenTTL).Unix(),
+		"iss": c.appID,
+	})
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+
+	signature, err := rsa.SignPKCS1v15(rand.Reader, c.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("%w: signing app token: %v", ErrAuthentication, err)
+	}
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// do sends a GitHub REST request and decodes a JSON response into out when
+// out is non-nil.
+func (c *GitHubClient) do(ctx context.Context, method, path, authorization string, body []byte, out any) error {
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("X-GitHub-Api-Version", apiVersion)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s %s returned %d: %s", method, path, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,257 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	return key
+}
+
+// verifyAppToken checks an app JWT was signed by key for the given app.
+func verifyAppToken(t *testing.T, key *rsa.PrivateKey, authorization string, appID string) {
+	t.Helper()
+
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	if len(parts) != 3 {
+		t.Fatalf("Expected a bearer JWT, got %q", authorization)
+	}
+
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature); err != nil {
+		t.Errorf("Expected a valid RS256 signature: %v", err)
+	}
+
+	var claims struct {
+		Issuer string `json:"iss"`
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Issuer != appID {
+		t.Errorf("Expected issuer %q, got %q (%v)", appID, claims.Issuer, err)
+	}
+}
+
+func TestCreateComment(t *testing.T) {
+	key := testKey(t)
+
+	tests := []struct {
+		name          string
+		comment       func(c *GitHubClient) error
+		commentStatus int
+		expectedPath  string
+		errorIs       error
+	}{
+		{
+			name: "Commit comment",
+			comment: func(c *GitHubClient) error {
+				return c.CreateCommitComment(context.Background(), 42, "octo", "leaves", "abc123", "falling")
+			},
+			commentStatus: http.StatusCreated,
+			expectedPath:  "/repos/octo/leaves/commits/abc123/comments",
+		},
+		{
+			name: "Pull request comment",
+			comment: func(c *GitHubClient) error {
+				return c.CreateIssueComment(context.Background(), 42, "octo", "leaves", 7, "falling")
+			},
+			commentStatus: http.StatusCreated,
+			expectedPath:  "/repos/octo/leaves/issues/7/comments",
+		},
+		{
+			name: "Comment rejected",
+			comment: func(c *GitHubClient) error {
+				return c.CreateIssueComment(context.Background(), 42, "octo", "leaves", 7, "falling")
+			},
+			commentStatus: http.StatusForbidden,
+			expectedPath:  "/repos/octo/leaves/issues/7/comments",
+			errorIs:       ErrComment,
+		},
+		{
+			name: "Missing sha",
+			comment: func(c *GitHubClient) error {
+				return c.CreateCommitComment(context.Background(), 42, "octo", "leaves", "", "falling")
+			},
+			errorIs: ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var commentPath, commentBody string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/app/installations/42/access_tokens" {
+					verifyAppToken(t, key, r.Header.Get("Authorization"), "1234")
+					w.WriteHeader(http.StatusCreated)
+					_ = json.NewEncoder(w).Encode(map[string]any{
+						"token":      "installation-token",
+						"expires_at": time.Now().Add(time.Hour),
+					})
+					return
+				}
+
+				if got := r.Header.Get("Authorization"); got != "token installation-token" {
+					t.Errorf("Expected installation token authorization, got %q", got)
+				}
+				var payload map[string]string
+				_ = json.NewDecoder(r.Body).Decode(&payload)
+				commentPath, commentBody = r.URL.Path, payload["body"]
+				w.WriteHeader(tc.commentStatus)
+			}))
+			defer server.Close()
+
+			err := tc.comment(NewGitHubClient(server.Client(), server.URL, "1234", key))
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if commentPath != tc.expectedPath || commentBody != "falling" {
+				t.Errorf("Expected comment %q on %s, got %q on %s", "falling", tc.expectedPath, commentBody, commentPath)
+			}
+		})
+	}
+}
+
+func TestInstallationTokenCaching(t *testing.T) {
+	exchanges := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token":      "installation-token",
+			"expires_at": time.Date(2025, 1, 1, 1, 0, 0, 0, time.UTC),
+		})
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	client := NewGitHubClient(server.Client(), server.URL, "1234", testKey(t))
+	client.now = func() time.Time { return now }
+
+	for range 2 {
+		if _, err := client.InstallationToken(context.Background(), 42); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	if exchanges != 1 {
+		t.Errorf("Expected a cached token to be reused, got %d exchanges", exchanges)
+	}
+
+	now = now.Add(59*time.Minute + 30*time.Second)
+	if _, err := client.InstallationToken(context.Background(), 42); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if exchanges != 2 {
+		t.Errorf("Expected a token near expiry to be renewed, got %d exchanges", exchanges)
+	}
+}
+
+func TestIssueTitle(t *testing.T) {
+	key := testKey(t)
+	lookups := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/octo/leaves/installation":
+			lookups++
+			verifyAppToken(t, key, r.Header.Get("Authorization"), "1234")
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": 42})
+		case "/app/installations/42/access_tokens":
+			_ = json.NewEncoder(w).Encode(map[string]any{"token": "installation-token", "expires_at": time.Now().Add(time.Hour)})
+		case "/repos/octo/leaves/issues/456":
+			if got := r.Header.Get("Authorization"); got != "token installation-token" {
+				t.Errorf("Expected the installation token, got %q", got)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]any{"number": 456, "title": "Leaves fall out of order"})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewGitHubClient(server.Client(), server.URL, "1234", key)
+	for range 2 {
+		title, err := client.IssueTitle(context.Background(), "octo", "leaves", 456)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if title != "Leaves fall out of order" {
+			t.Errorf("Expected the issue title, got %q", title)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("Expected the installation to be cached, got %d lookups", lookups)
+	}
+
+	if _, err := client.IssueTitle(context.Background(), "octo", "leaves", 7); !errors.Is(err, ErrIssue) {
+		t.Errorf("Expected error to wrap %v, got %v", ErrIssue, err)
+	}
+	if _, err := client.IssueTitle(context.Background(), "octo", "other", 7); !errors.Is(err, ErrAuthentication) {
+		t.Errorf("Expected error to wrap %v, got %v", ErrAuthentication, err)
+	}
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := testKey(t)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	tests := []struct {
+		name    string
+		pem     []byte
+		errorIs error
+	}{
+		{
+			name: "PKCS1",
+			pem:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+		},
+		{
+			name: "PKCS8",
+			pem:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+		},
+		{
+			name:    "Not PEM",
+			pem:     []byte("not a key"),
+			errorIs: ErrInvalidKey,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			parsed, err := ParsePrivateKey(tc.pem)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil || !parsed.Equal(key) {
+				t.Errorf("Expected the original key, got error %v", err)
+			}
+		})
+	}
+}
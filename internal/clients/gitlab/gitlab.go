@@ -0,0 +1,101 @@
+// Package gitlab posts haiku back to GitLab, including self-managed instances.
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const DefaultBaseURL = "https://gitlab.com"
+
+var (
+	ErrInvalidRequest = errors.New("invalid gitlab request")
+	ErrComment        = errors.New("gitlab comment failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type GitLabClient struct {
+	httpClient HTTPClient
+	baseURL    string
+	token      string
+}
+
+// NewGitLabClient authenticates to the GitLab instance at baseURL with a
+// personal, group or project access token.
+func NewGitLabClient(httpClient HTTPClient, baseURL string, token string) *GitLabClient {
+	return &GitLabClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+	}
+}
+
+func NewDefaultGitLabClient(baseURL string, token string) *GitLabClient {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return NewGitLabClient(&http.Client{Timeout: 10 * time.Second}, baseURL, token)
+}
+
+// CreateCommitComment comments on a commit in project, given by its full
+// path such as "group/subgroup/project".
+func (c *GitLabClient) CreateCommitComment(ctx context.Context, project, sha, note string) error {
+	if project == "" || sha == "" {
+		return fmt.Errorf("%w: project and sha are required", ErrInvalidRequest)
+	}
+
+	path := fmt.Sprintf("/projects/%s/repository/commits/%s/comments", url.PathEscape(project), url.PathEscape(sha))
+	return c.post(ctx, path, map[string]string{"note": note})
+}
+
+// CreateMergeRequestNote comments on the merge request iid in project.
+func (c *GitLabClient) CreateMergeRequestNote(ctx context.Context, project string, iid int, body string) error {
+	if project == "" || iid <= 0 {
+		return fmt.Errorf("%w: project and merge request iid are required", ErrInvalidRequest)
+	}
+
+	path := fmt.Sprintf("/projects/%s/merge_requests/%d/notes", url.PathEscape(project), iid)
+	return c.post(ctx, path, map[string]string{"body": body})
+}
+
+func (c *GitLabClient) post(ctx context.Context, path string, body map[string]string) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("%w: encoding comment: %v", ErrComment, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/v4"+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrComment, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("PRIVATE-TOKEN", c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[GITLAB CLIENT] error encountered creating comment: %v", err)
+		return fmt.Errorf("%w: %v", ErrComment, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[GITLAB CLIENT] comment on %s returned %d", path, resp.StatusCode)
+		return fmt.Errorf("%w: POST %s returned %d: %s", ErrComment, path, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
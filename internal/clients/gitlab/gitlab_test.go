@@ -0,0 +1,91 @@
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateComment(t *testing.T) {
+	tests := []struct {
+		name         string
+		comment      func(c *GitLabClient) error
+		status       int
+		expectedPath string
+		expectedBody map[string]string
+		errorIs      error
+	}{
+		{
+			name: "Commit comment",
+			comment: func(c *GitLabClient) error {
+				return c.CreateCommitComment(context.Background(), "group/sub/leaves", "abc123", "falling")
+			},
+			status:       http.StatusCreated,
+			expectedPath: "/gitlab/api/v4/projects/group%2Fsub%2Fleaves/repository/commits/abc123/comments",
+			expectedBody: map[string]string{"note": "falling"},
+		},
+		{
+			name: "Merge request note",
+			comment: func(c *GitLabClient) error {
+				return c.CreateMergeRequestNote(context.Background(), "group/leaves", 7, "falling")
+			},
+			status:       http.StatusCreated,
+			expectedPath: "/gitlab/api/v4/projects/group%2Fleaves/merge_requests/7/notes",
+			expectedBody: map[string]string{"body": "falling"},
+		},
+		{
+			name: "Comment rejected",
+			comment: func(c *GitLabClient) error {
+				return c.CreateMergeRequestNote(context.Background(), "group/leaves", 7, "falling")
+			},
+			status:  http.StatusForbidden,
+			errorIs: ErrComment,
+		},
+		{
+			name: "Missing merge request",
+			comment: func(c *GitLabClient) error {
+				return c.CreateMergeRequestNote(context.Background(), "group/leaves", 0, "falling")
+			},
+			errorIs: ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			var body map[string]string
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("PRIVATE-TOKEN"); got != "glpat-test" {
+					t.Errorf("Expected the access token header, got %q", got)
+				}
+				path = r.URL.EscapedPath()
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			err := tc.comment(NewGitLabClient(server.Client(), server.URL+"/gitlab/", "glpat-test"))
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if path != tc.expectedPath {
+				t.Errorf("Expected path %q, got %q", tc.expectedPath, path)
+			}
+			if !maps.Equal(body, tc.expectedBody) {
+				t.Errorf("Expected body %v, got %v", tc.expectedBody, body)
+			}
+		})
+	}
+}
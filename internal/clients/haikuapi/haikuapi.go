@@ -0,0 +1,115 @@
+// Package haikuapi calls a deployed haiku API, for clients such as the CLI
+// that run away from the service.
+package haikuapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid haiku api request")
+	ErrRequest        = errors.New("haiku api request failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type HaikuAPIClient struct {
+	httpClient HTTPClient
+	baseURL    string
+}
+
+// NewHaikuAPIClient calls the haiku API deployed at baseURL, e.g. the stage URL
+// API Gateway prints on deploy.
+func NewHaikuAPIClient(httpClient HTTPClient, baseURL string) *HaikuAPIClient {
+	return &HaikuAPIClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func NewDefaultHaikuAPIClient(baseURL string) *HaikuAPIClient {
+	return NewHaikuAPIClient(&http.Client{Timeout: 30 * time.Second}, baseURL)
+}
+
+// CreateHaiku requests a haiku for a commit message. Errors reported by the
+// API are returned with the API's explanation.
+func (c *HaikuAPIClient) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	if strings.TrimSpace(request.CommitMessage) == "" {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: commit message is required", ErrInvalidRequest)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: encoding request: %v", ErrInvalidRequest, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/haiku", bytes.NewReader(payload))
+	if err != nil {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: %v", ErrInvalidRequest, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: %v", ErrRequest, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: reading response: %v", ErrRequest, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: %s", ErrRequest, errorDetail(resp.StatusCode, body))
+	}
+
+	var response haiku.HaikuCommitResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: decoding response: %v", ErrRequest, err)
+	}
+	return response, nil
+}
+
+// errorDetail describes an error response, preferring the API's own
+// problem details body.
+func errorDetail(status int, body []byte) string {
+	var problem struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+		Errors []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &problem) != nil || problem.Title == "" {
+		detail := strings.TrimSpace(string(body))
+		if len(detail) > 200 {
+			detail = detail[:200]
+		}
+		return fmt.Sprintf("api returned %d: %s", status, detail)
+	}
+
+	details := make([]string, 0, len(problem.Errors)+1)
+	if problem.Detail != "" {
+		details = append(details, problem.Detail)
+	}
+	for _, field := range problem.Errors {
+		details = append(details, field.Detail)
+	}
+	if len(details) > 0 {
+		return fmt.Sprintf("api returned %d: %s: %s", status, problem.Title, strings.Join(details, "; "))
+	}
+	return fmt.Sprintf("api returned %d: %s", status, problem.Title)
+}
@@ -0,0 +1,93 @@
+package haikuapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+func TestCreateHaiku(t *testing.T) {
+	tests := []struct {
+		name          string
+		commitMessage string
+		status        int
+		body          string
+		expectedHaiku string
+		errorContains string
+		errorIs       error
+	}{
+		{
+			name:          "Haiku",
+			commitMessage: "fix: resolved login issue",
+			status:        http.StatusOK,
+			body:          `{"haiku":"leaves fall","metadata":{}}`,
+			expectedHaiku: "leaves fall",
+		},
+		{
+			name:          "API error explained",
+			commitMessage: "fix: resolved login issue",
+			status:        http.StatusBadRequest,
+			body:          `{"title":"Invalid request format","status":400,"code":"invalid_request","errors":[{"field":"commitMessage","code":"too_long","detail":"commitMessage exceeds 100 characters"}]}`,
+			errorContains: "commitMessage exceeds 100 characters",
+			errorIs:       ErrRequest,
+		},
+		{
+			name:          "Gateway error",
+			commitMessage: "fix: resolved login issue",
+			status:        http.StatusForbidden,
+			body:          `Forbidden`,
+			errorContains: "api returned 403: Forbidden",
+			errorIs:       ErrRequest,
+		},
+		{
+			name:    "Empty commit message",
+			errorIs: ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			var request haiku.HaikuCommitRequest
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				_ = json.NewDecoder(r.Body).Decode(&request)
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			client := NewHaikuAPIClient(server.Client(), server.URL+"/prod/")
+			response, err := client.CreateHaiku(context.Background(), haiku.HaikuCommitRequest{CommitMessage: tc.commitMessage, Mood: haiku.MoodHumerous})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				if err != nil && !strings.Contains(err.Error(), tc.errorContains) {
+					t.Errorf("Expected error to contain %q, got %v", tc.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if path != "/prod/haiku" {
+				t.Errorf("Expected path /prod/haiku, got %q", path)
+			}
+			if request.CommitMessage != tc.commitMessage || request.Mood != haiku.MoodHumerous {
+				t.Errorf("Expected the request to be sent, got %+v", request)
+			}
+			if response.Haiku != tc.expectedHaiku {
+				t.Errorf("Expected haiku %q, got %q", tc.expectedHaiku, response.Haiku)
+			}
+		})
+	}
+}
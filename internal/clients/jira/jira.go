@@ -0,0 +1,92 @@
+// Package jira looks up Jira issues, so that commits referencing them can be
+// described by their summaries.
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid jira request")
+	ErrIssue          = errors.New("jira issue lookup failed")
+)
+
+// keyPattern matches Jira issue keys, e.g. PROJ-123.
+var keyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]+-[0-9]+$`)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+type JiraClient struct {
+	httpClient HTTPClient
+	baseURL    string
+	email      string
+	token      string
+}
+
+// NewJiraClient authenticates to the Jira site at baseURL, e.g.
+// "https://example.atlassian.net", as email with an API token.
+func NewJiraClient(httpClient HTTPClient, baseURL string, email string, token string) *JiraClient {
+	return &JiraClient{
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		email:      email,
+		token:      token,
+	}
+}
+
+func NewDefaultJiraClient(baseURL string, email string, token string) *JiraClient {
+	return NewJiraClient(&http.Client{Timeout: 5 * time.Second}, baseURL, email, token)
+}
+
+// IssueSummary returns the summary of the issue key, e.g. "PROJ-123".
+func (c *JiraClient) IssueSummary(ctx context.Context, key string) (string, error) {
+	key = strings.ToUpper(key)
+	if !keyPattern.MatchString(key) {
+		return "", fmt.Errorf("%w: %q is not an issue key", ErrInvalidRequest, key)
+	}
+
+	path := "/rest/api/3/issue/" + url.PathEscape(key) + "?fields=summary"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrIssue, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(c.email, c.token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[JIRA CLIENT] error encountered looking up issue: %v", err)
+		return "", fmt.Errorf("%w: %v", ErrIssue, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[JIRA CLIENT] lookup of %s returned %d", key, resp.StatusCode)
+		return "", fmt.Errorf("%w: GET %s returned %d: %s", ErrIssue, path, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	var issue struct {
+		Fields struct {
+			Summary string `json:"summary"`
+		} `json:"fields"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&issue); err != nil {
+		return "", fmt.Errorf("%w: decoding issue: %v", ErrIssue, err)
+	}
+	return issue.Fields.Summary, nil
+}
@@ -0,0 +1,72 @@
+package jira
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIssueSummary(t *testing.T) {
+	tests := []struct {
+		name         string
+		key          string
+		status       int
+		response     string
+		expectedPath string
+		expected     string
+		errorIs      error
+	}{
+		{
+			name:         "Summary",
+			key:          "leaf-4521",
+			status:       http.StatusOK,
+			response:     `{"key":"LEAF-4521","fields":{"summary":"Login times out behind the proxy"}}`,
+			expectedPath: "/rest/api/3/issue/LEAF-4521",
+			expected:     "Login times out behind the proxy",
+		},
+		{
+			name:    "Unknown issue",
+			key:     "LEAF-1",
+			status:  http.StatusNotFound,
+			errorIs: ErrIssue,
+		},
+		{
+			name:    "Not an issue key",
+			key:     "LEAF/../1",
+			errorIs: ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if email, token, ok := r.BasicAuth(); !ok || email != "bot@example.com" || token != "jira-token" {
+					t.Errorf("Expected basic auth with the API token, got %q %q", email, token)
+				}
+				path = r.URL.EscapedPath()
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.response))
+			}))
+			defer server.Close()
+
+			client := NewJiraClient(server.Client(), server.URL+"/", "bot@example.com", "jira-token")
+			summary, err := client.IssueSummary(context.Background(), tc.key)
+
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+			if path != tc.expectedPath {
+				t.Errorf("Expected path %q, got %q", tc.expectedPath, path)
+			}
+			if summary != tc.expected {
+				t.Errorf("Expected summary %q, got %q", tc.expected, summary)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package lambda invokes Lambda functions asynchronously, so work that outlives
+// an API Gateway request can continue in a separate invocation.
+package lambda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid lambda request")
+	ErrInvoke         = errors.New("lambda invocation failed")
+)
+
+type LambdaAPI interface {
+	Invoke(ctx context.Context, params *awslambda.InvokeInput, optFns ...func(*awslambda.Options)) (*awslambda.InvokeOutput, error)
+}
+
+type LambdaClient struct {
+	lambdaClient LambdaAPI
+	functionName string
+}
+
+func NewLambdaClient(lambdaClient LambdaAPI, functionName string) *LambdaClient {
+	return &LambdaClient{
+		lambdaClient: lambdaClient,
+		functionName: functionName,
+	}
+}
+
+func NewDefaultLambdaClient(cfg aws.Config, functionName string) *LambdaClient {
+	return NewLambdaClient(awslambda.NewFromConfig(cfg), functionName)
+}
+
+// InvokeAsync queues payload for the function and returns once Lambda has
+// accepted it, without waiting for the function to run.
+func (c *LambdaClient) InvokeAsync(ctx context.Context, payload []byte) error {
+	if c.functionName == "" {
+		return fmt.Errorf("%w: function name is required", ErrInvalidRequest)
+	}
+
+	_, err := c.lambdaClient.Invoke(ctx, &awslambda.InvokeInput{
+		FunctionName:   aws.String(c.functionName),
+		InvocationType: types.InvocationTypeEvent,
+		Payload:        payload,
+	})
+	if err != nil {
+		logging.Errorf("[LAMBDA CLIENT] error encountered invoking %s: %v", c.functionName, err)
+		return fmt.Errorf("%w: %v", ErrInvoke, err)
+	}
+
+	return nil
+}
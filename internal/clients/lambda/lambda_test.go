@@ -0,0 +1,73 @@
+package lambda
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awslambda "github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type MockLambdaAPI struct {
+	ErrorToReturn error
+	LastInput     *awslambda.InvokeInput
+}
+
+func (m *MockLambdaAPI) Invoke(ctx context.Context, params *awslambda.InvokeInput, optFns ...func(*awslambda.Options)) (*awslambda.InvokeOutput, error) {
+	m.LastInput = params
+	if m.ErrorToReturn != nil {
+		return nil, m.ErrorToReturn
+	}
+	return &awslambda.InvokeOutput{StatusCode: 202}, nil
+}
+
+func TestInvokeAsync(t *testing.T) {
+	tests := []struct {
+		name         string
+		functionName string
+		mockError    error
+		errorIs      error
+	}{
+		{
+			name:         "Queued",
+			functionName: "haiku",
+		},
+		{
+			name:         "Lambda error",
+			functionName: "haiku",
+			mockError:    errors.New("throttled"),
+			errorIs:      ErrInvoke,
+		},
+		{
+			name:    "Missing function name",
+			errorIs: ErrInvalidRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockLambdaAPI{ErrorToReturn: tc.mockError}
+			err := NewLambdaClient(mock, tc.functionName).InvokeAsync(context.Background(), []byte(`{"work":true}`))
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			input := mock.LastInput
+			if aws.ToString(input.FunctionName) != tc.functionName || input.InvocationType != types.InvocationTypeEvent {
+				t.Errorf("Expected an event invocation of %s, got %s invocation of %s", tc.functionName, input.InvocationType, aws.ToString(input.FunctionName))
+			}
+			if string(input.Payload) != `{"work":true}` {
+				t.Errorf("Expected the payload to be passed through, got %s", input.Payload)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Package polly synthesizes spoken haiku with Amazon Polly.
+package polly
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awspolly "github.com/aws/aws-sdk-go-v2/service/polly"
+	"github.com/aws/aws-sdk-go-v2/service/polly/types"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	DefaultVoiceID = "Joanna"
+
+	// lineBreak is the pause between lines, long enough to hear the form.
+	lineBreak = "800ms"
+)
+
+var (
+	ErrInvalidRequest = errors.New("invalid speech request")
+	ErrSynthesis      = errors.New("speech synthesis failed")
+)
+
+type PollyAPI interface {
+	SynthesizeSpeech(ctx context.Context, params *awspolly.SynthesizeSpeechInput, optFns ...func(*awspolly.Options)) (*awspolly.SynthesizeSpeechOutput, error)
+}
+
+type SpeechOptions struct {
+	VoiceID string // Polly voice to read the haiku (default: Joanna)
+}
+
+type PollyClient struct {
+	pollyClient PollyAPI
+}
+
+func NewPollyClient(pollyClient PollyAPI) *PollyClient {
+	return &PollyClient{
+		pollyClient: pollyClient,
+	}
+}
+
+func NewDefaultPollyClient(cfg aws.Config) *PollyClient {
+	return NewPollyClient(awspolly.NewFromConfig(cfg))
+}
+
+// Synthesize reads text aloud, one line at a time with a pause between lines,
+// and returns the audio as MP3.
+func (c *PollyClient) Synthesize(ctx context.Context, text string, opts *SpeechOptions) ([]byte, error) {
+	ssml, err := toSSML(text)
+	if err != nil {
+		logging.Warnf("[POLLY CLIENT] %v", err)
+		return nil, err
+	}
+
+	voiceID := DefaultVoiceID
+	if opts != nil && opts.VoiceID != "" {
+		voiceID = opts.VoiceID
+	}
+
+	output, err := c.pollyClient.SynthesizeSpeech(ctx, &awspolly.SynthesizeSpeechInput{
+		Engine:       types.EngineNeural,
+		OutputFormat: types.OutputFormatMp3,
+		Text:         aws.String(ssml),
+		TextType:     types.TextTypeSsml,
+		VoiceId:      types.VoiceId(voiceID),
+	})
+	if err != nil {
+		logging.Errorf("[POLLY CLIENT] error encountered synthesizing speech: %v", err)
+		return nil, fmt.Errorf("%w: %v", ErrSynthesis, err)
+	}
+	defer output.AudioStream.Close()
+
+	audio, err := io.ReadAll(output.AudioStream)
+	if err != nil {
+		logging.Errorf("[POLLY CLIENT] error encountered reading audio stream: %v", err)
+		return nil, fmt.Errorf("%w: reading audio: %v", ErrSynthesis, err)
+	}
+
+	return audio, nil
+}
+
+// toSSML wraps each non-empty line of text in SSML, separated by breaks.
+func toSSML(text string) (string, error) {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			var escaped bytes.Buffer
+			_ = xml.EscapeText(&escaped, []byte(line))
+			lines = append(lines, escaped.String())
+		}
+	}
+	if len(lines) == 0 {
+		return "", fmt.Errorf("%w: text cannot be empty", ErrInvalidRequest)
+	}
+
+	return `<speak><prosody rate="90%">` +
+		strings.Join(lines, `<break time="`+lineBreak+`"/>`) +
+		`</prosody></speak>`, nil
+}
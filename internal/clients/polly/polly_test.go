@@ -0,0 +1,94 @@
+package polly
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awspolly "github.com/aws/aws-sdk-go-v2/service/polly"
+	"github.com/aws/aws-sdk-go-v2/service/polly/types"
+)
+
+type MockPollyAPI struct {
+	SynthesizeSpeechFunc func(ctx context.Context, params *awspolly.SynthesizeSpeechInput, optFns ...func(*awspolly.Options)) (*awspolly.SynthesizeSpeechOutput, error)
+}
+
+func (m *MockPollyAPI) SynthesizeSpeech(ctx context.Context, params *awspolly.SynthesizeSpeechInput, optFns ...func(*awspolly.Options)) (*awspolly.SynthesizeSpeechOutput, error) {
+	return m.SynthesizeSpeechFunc(ctx, params, optFns...)
+}
+
+func TestSynthesize(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		opts          *SpeechOptions
+		mockError     error
+		expectedVoice types.VoiceId
+		expectedSSML  string
+		errorIs       error
+	}{
+		{
+			name:          "Default voice",
+			text:          "Old cracks mended now\nthe <login> door swings open\n\nquiet in the logs",
+			expectedVoice: DefaultVoiceID,
+			expectedSSML:  `<speak><prosody rate="90%">Old cracks mended now<break time="800ms"/>the &lt;login&gt; door swings open<break time="800ms"/>quiet in the logs</prosody></speak>`,
+		},
+		{
+			name:          "Custom voice",
+			text:          "leaves fall",
+			opts:          &SpeechOptions{VoiceID: "Kazuha"},
+			expectedVoice: "Kazuha",
+			expectedSSML:  `<speak><prosody rate="90%">leaves fall</prosody></speak>`,
+		},
+		{
+			name:    "Empty text",
+			text:    " \n ",
+			errorIs: ErrInvalidRequest,
+		},
+		{
+			name:          "Polly error",
+			text:          "leaves fall",
+			mockError:     errors.New("throttled"),
+			expectedVoice: DefaultVoiceID,
+			expectedSSML:  `<speak><prosody rate="90%">leaves fall</prosody></speak>`,
+			errorIs:       ErrSynthesis,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockPollyAPI{
+				SynthesizeSpeechFunc: func(ctx context.Context, params *awspolly.SynthesizeSpeechInput, optFns ...func(*awspolly.Options)) (*awspolly.SynthesizeSpeechOutput, error) {
+					if params.VoiceId != tc.expectedVoice {
+						t.Errorf("Expected voice %q, got %q", tc.expectedVoice, params.VoiceId)
+					}
+					if got := aws.ToString(params.Text); got != tc.expectedSSML {
+						t.Errorf("Expected SSML %q, got %q", tc.expectedSSML, got)
+					}
+					if tc.mockError != nil {
+						return nil, tc.mockError
+					}
+					return &awspolly.SynthesizeSpeechOutput{AudioStream: io.NopCloser(strings.NewReader("mp3"))}, nil
+				},
+			}
+
+			audio, err := NewPollyClient(mock).Synthesize(context.Background(), tc.text, tc.opts)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if string(audio) != "mp3" {
+				t.Errorf("Expected audio %q, got %q", "mp3", audio)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Package ses sends email with Amazon SES, through its v2 API. Requests are
+// signed with the SDK's credentials, so the function's role needs
+// ses:SendEmail on the sender's identity.
+package ses
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// signingName is the service name SES v2 requests are signed for.
+const signingName = "ses"
+
+var (
+	ErrInvalidRequest = errors.New("invalid email request")
+	ErrSend           = errors.New("sending email failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Message is a single email, sent as plain text with an HTML alternative.
+type Message struct {
+	To             string
+	Subject        string
+	Text           string
+	HTML           string
+	UnsubscribeURL string // Sent as the List-Unsubscribe header, when set
+}
+
+type SESClient struct {
+	httpClient  HTTPClient
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+	endpoint    string
+	from        string
+	now         func() time.Time
+}
+
+// NewSESClient sends email from the verified identity from, in cfg's region.
+func NewSESClient(httpClient HTTPClient, cfg aws.Config, from string) *SESClient {
+	return &SESClient{
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+		credentials: cfg.Credentials,
+		region:      cfg.Region,
+		endpoint:    fmt.Sprintf("https://email.%s.amazonaws.com", cfg.Region),
+		from:        from,
+		now:         time.Now,
+	}
+}
+
+func NewDefaultSESClient(cfg aws.Config, from string) *SESClient {
+	return NewSESClient(&http.Client{Timeout: 10 * time.Second}, cfg, from)
+}
+
+// Send sends message from the client's identity.
+func (c *SESClient) Send(ctx context.Context, message Message) error {
+	if _, err := mail.ParseAddress(message.To); err != nil {
+		return fmt.Errorf("%w: %q is not an email address", ErrInvalidRequest, message.To)
+	}
+	if c.credentials == nil {
+		return fmt.Errorf("%w: no AWS credentials", ErrSend)
+	}
+
+	type content struct {
+		Data    string `json:"Data"`
+		Charset string `json:"Charset"`
+	}
+	type header struct {
+		Name  string `json:"Name"`
+		Value string `json:"Value"`
+	}
+	var simple struct {
+		Subject content `json:"Subject"`
+		Body    struct {
+			Text content  `json:"Text"`
+			HTML *content `json:"Html,omitempty"`
+		} `json:"Body"`
+		Headers []header `json:"Headers,omitempty"`
+	}
+	simple.Subject = content{Data: message.Subject, Charset: "UTF-8"}
+	simple.Body.Text = content{Data: message.Text, Charset: "UTF-8"}
+	if message.HTML != "" {
+		simple.Body.HTML = &content{Data: message.HTML, Charset: "UTF-8"}
+	}
+	if message.UnsubscribeURL != "" {
+		simple.Headers = []header{{Name: "List-Unsubscribe", Value: "<" + message.UnsubscribeURL + ">"}}
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"FromEmailAddress": c.from,
+		"Destination":      map[string][]string{"ToAddresses": {message.To}},
+		"Content":          map[string]any{"Simple": simple},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	credentials, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: retrieving credentials: %v", ErrSend, err)
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, c.now()); err != nil {
+		return fmt.Errorf("%w: signing request: %v", ErrSend, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[SES CLIENT] error encountered sending email: %v", err)
+		return fmt.Errorf("%w: %v", ErrSend, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[SES CLIENT] sending email returned %d", resp.StatusCode)
+		return fmt.Errorf("%w: SendEmail returned %d: %s", ErrSend, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
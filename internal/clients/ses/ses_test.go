@@ -0,0 +1,86 @@
+package ses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestSend(t *testing.T) {
+	tests := []struct {
+		name           string
+		message        Message
+		status         int
+		expectedHeader string
+		errorIs        error
+	}{
+		{
+			name:           "Sent",
+			message:        Message{To: "mona@example.com", Subject: "Haiku", Text: "frost", HTML: "<p>frost</p>", UnsubscribeURL: "https://haiku.example.com/unsubscribe"},
+			status:         http.StatusOK,
+			expectedHeader: "<https://haiku.example.com/unsubscribe>",
+		},
+		{
+			name:    "Not an address",
+			message: Message{To: "mona", Subject: "Haiku", Text: "frost"},
+			errorIs: ErrInvalidRequest,
+		},
+		{
+			name:    "Rejected",
+			message: Message{To: "mona@example.com", Subject: "Haiku", Text: "frost"},
+			status:  http.StatusBadRequest,
+			errorIs: ErrSend,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var request map[string]any
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v2/email/outbound-emails" {
+					t.Errorf("Expected a SendEmail request, got %s", r.URL.Path)
+				}
+				if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/us-east-1/ses/aws4_request") {
+					t.Errorf("Expected a request signed for SES, got %q", auth)
+				}
+				body, _ := io.ReadAll(r.Body)
+				json.Unmarshal(body, &request)
+				w.WriteHeader(tc.status)
+				w.Write([]byte(`{}`))
+			}))
+			defer server.Close()
+
+			client := NewSESClient(server.Client(), aws.Config{
+				Region: "us-east-1",
+				Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+				}),
+			}, "haiku@example.com")
+			client.endpoint = server.URL
+
+			err := client.Send(context.Background(), tc.message)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if request["FromEmailAddress"] != "haiku@example.com" {
+				t.Errorf("Expected the client's sender, got %v", request["FromEmailAddress"])
+			}
+			simple := request["Content"].(map[string]any)["Simple"].(map[string]any)
+			headers, _ := simple["Headers"].([]any)
+			if len(headers) != 1 || headers[0].(map[string]any)["Value"] != tc.expectedHeader {
+				t.Errorf("Expected a List-Unsubscribe header %q, got %v", tc.expectedHeader, headers)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Package sns publishes messages to an Amazon SNS topic, through its query
+// API. Requests are signed with the SDK's credentials, so the function's role
+// needs sns:Publish on the topic.
+package sns
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	// signingName is the service name SNS requests are signed for.
+	signingName = "sns"
+	apiVersion  = "2010-03-31"
+)
+
+var (
+	ErrInvalidTopic = errors.New("invalid sns topic")
+	ErrPublish      = errors.New("publishing to sns failed")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Message is published as Body, with Attributes as string message attributes
+// subscribers can filter on. Attributes with empty values are left out, as
+// SNS refuses them.
+type Message struct {
+	Body       string
+	Attributes map[string]string
+}
+
+type SNSClient struct {
+	httpClient  HTTPClient
+	signer      *v4.Signer
+	credentials aws.CredentialsProvider
+	region      string
+	endpoint    string
+	topicARN    string
+	now         func() time.Time
+}
+
+// NewSNSClient publishes to topicARN, in the topic's own region.
+func NewSNSClient(httpClient HTTPClient, cfg aws.Config, topicARN string) (*SNSClient, error) {
+	topic, err := arn.Parse(topicARN)
+	if err != nil || topic.Service != "sns" || topic.Region == "" {
+		return nil, fmt.Errorf("%w: %q is not an sns topic arn", ErrInvalidTopic, topicARN)
+	}
+
+	return &SNSClient{
+		httpClient:  httpClient,
+		signer:      v4.NewSigner(),
+		credentials: cfg.Credentials,
+		region:      topic.Region,
+		endpoint:    fmt.Sprintf("https://sns.%s.amazonaws.com/", topic.Region),
+		topicARN:    topicARN,
+		now:         time.Now,
+	}, nil
+}
+
+func NewDefaultSNSClient(cfg aws.Config, topicARN string) (*SNSClient, error) {
+	return NewSNSClient(&http.Client{Timeout: 5 * time.Second}, cfg, topicARN)
+}
+
+// Publish publishes message to the client's topic.
+func (c *SNSClient) Publish(ctx context.Context, message Message) error {
+	if c.credentials == nil {
+		return fmt.Errorf("%w: no AWS credentials", ErrPublish)
+	}
+
+	form := url.Values{
+		"Action":   {"Publish"},
+		"Version":  {apiVersion},
+		"TopicArn": {c.topicARN},
+		"Message":  {message.Body},
+	}
+	names := make([]string, 0, len(message.Attributes))
+	for name, value := range message.Attributes {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	for i, name := range names {
+		entry := "MessageAttributes.entry." + strconv.Itoa(i+1)
+		form.Set(entry+".Name", name)
+		form.Set(entry+".Value.DataType", "String")
+		form.Set(entry+".Value.StringValue", message.Attributes[name])
+	}
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPublish, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	credentials, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: retrieving credentials: %v", ErrPublish, err)
+	}
+	hash := sha256.Sum256(body)
+	if err := c.signer.SignHTTP(ctx, credentials, req, hex.EncodeToString(hash[:]), signingName, c.region, c.now()); err != nil {
+		return fmt.Errorf("%w: signing request: %v", ErrPublish, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[SNS CLIENT] error encountered publishing message: %v", err)
+		return fmt.Errorf("%w: %v", ErrPublish, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[SNS CLIENT] publishing message returned %d", resp.StatusCode)
+		return fmt.Errorf("%w: Publish returned %d: %s", ErrPublish, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package sns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+const testTopic = "arn:aws:sns:eu-west-1:123456789012:haiku-events"
+
+func TestNewSNSClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		topicARN string
+		errorIs  error
+	}{
+		{
+			name:     "Topic",
+			topicARN: testTopic,
+		},
+		{
+			name:     "Not an ARN",
+			topicARN: "haiku-events",
+			errorIs:  ErrInvalidTopic,
+		},
+		{
+			name:     "Not a topic",
+			topicARN: "arn:aws:sqs:eu-west-1:123456789012:haiku-events",
+			errorIs:  ErrInvalidTopic,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client, err := NewDefaultSNSClient(aws.Config{Region: "us-east-1"}, tc.topicARN)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs == nil && client.region != "eu-west-1" {
+				t.Errorf("Expected the topic's region, got %s", client.region)
+			}
+		})
+	}
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		errorIs error
+	}{
+		{
+			name:   "Published",
+			status: http.StatusOK,
+		},
+		{
+			name:    "Rejected",
+			status:  http.StatusForbidden,
+			errorIs: ErrPublish,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var form url.Values
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if auth := r.Header.Get("Authorization"); !strings.Contains(auth, "/eu-west-1/sns/aws4_request") {
+					t.Errorf("Expected a request signed for SNS in the topic's region, got %q", auth)
+				}
+				body, _ := io.ReadAll(r.Body)
+				form, _ = url.ParseQuery(string(body))
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			client, err := NewSNSClient(server.Client(), aws.Config{
+				Region: "us-east-1",
+				Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+					return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+				}),
+			}, testTopic)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			client.endpoint = server.URL
+
+			err = client.Publish(context.Background(), Message{
+				Body:       `{"haiku": "frost"}`,
+				Attributes: map[string]string{"mood": "technical", "tenant": ""},
+			})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			expected := url.Values{
+				"Action":                         {"Publish"},
+				"Version":                        {apiVersion},
+				"TopicArn":                       {testTopic},
+				"Message":                        {`{"haiku": "frost"}`},
+				"MessageAttributes.entry.1.Name": {"mood"},
+				"MessageAttributes.entry.1.Value.DataType":    {"String"},
+				"MessageAttributes.entry.1.Value.StringValue": {"technical"},
+			}
+			if form.Encode() != expected.Encode() {
+				t.Errorf("Expected %v, got %v", expected, form)
+			}
+		})
+	}
+}
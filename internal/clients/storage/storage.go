@@ -0,0 +1,92 @@
+// Package storage stores generated artifacts, such as share cards, in S3.
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+var (
+	ErrPutObject    = errors.New("failed to store object")
+	ErrDeleteObject = errors.New("failed to delete object")
+	ErrPresign      = errors.New("failed to presign object url")
+)
+
+type S3API interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+type PresignAPI interface {
+	PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+type S3Client struct {
+	s3Client  S3API
+	presigner PresignAPI
+	bucket    string
+}
+
+func NewS3Client(s3Client S3API, presigner PresignAPI, bucket string) *S3Client {
+	return &S3Client{
+		s3Client:  s3Client,
+		presigner: presigner,
+		bucket:    bucket,
+	}
+}
+
+func NewDefaultS3Client(cfg aws.Config, bucket string) *S3Client {
+	client := s3.NewFromConfig(cfg)
+	return NewS3Client(client, s3.NewPresignClient(client), bucket)
+}
+
+// Put stores body under key.
+func (c *S3Client) Put(ctx context.Context, key string, contentType string, body []byte) error {
+	_, err := c.s3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(c.bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+		Body:        bytes.NewReader(body),
+	})
+	if err != nil {
+		logging.Errorf("[STORAGE CLIENT] error storing %s: %v", key, err)
+		return fmt.Errorf("%w: %v", ErrPutObject, err)
+	}
+	return nil
+}
+
+// Delete removes the object stored under key. Deleting a key that holds no
+// object succeeds.
+func (c *S3Client) Delete(ctx context.Context, key string) error {
+	_, err := c.s3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		logging.Errorf("[STORAGE CLIENT] error deleting %s: %v", key, err)
+		return fmt.Errorf("%w: %v", ErrDeleteObject, err)
+	}
+	return nil
+}
+
+// PresignGet returns a URL that downloads key without credentials until ttl
+// elapses. The URL stops working earlier if the signing credentials expire.
+func (c *S3Client) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	request, err := c.presigner.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		logging.Errorf("[STORAGE CLIENT] error presigning %s: %v", key, err)
+		return "", fmt.Errorf("%w: %v", ErrPresign, err)
+	}
+	return request.URL, nil
+}
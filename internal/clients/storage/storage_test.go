@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+type MockS3API struct {
+	PutObjectFunc    func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObjectFunc func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+}
+
+func (m *MockS3API) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return m.PutObjectFunc(ctx, params, optFns...)
+}
+
+func (m *MockS3API) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	return m.DeleteObjectFunc(ctx, params, optFns...)
+}
+
+type MockPresignAPI struct {
+	PresignGetObjectFunc func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error)
+}
+
+func (m *MockPresignAPI) PresignGetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+	return m.PresignGetObjectFunc(ctx, params, optFns...)
+}
+
+func TestPut(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		errorIs   error
+	}{
+		{name: "Stored"},
+		{name: "S3 error", mockError: errors.New("access denied"), errorIs: ErrPutObject},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockS3API{
+				PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+					if aws.ToString(params.Bucket) != "cards" || aws.ToString(params.Key) != "cards/a.png" || aws.ToString(params.ContentType) != "image/png" {
+						t.Errorf("Unexpected put input: %+v", params)
+					}
+					body, _ := io.ReadAll(params.Body)
+					if string(body) != "png" {
+						t.Errorf("Expected body %q, got %q", "png", body)
+					}
+					return &s3.PutObjectOutput{}, tc.mockError
+				},
+			}
+
+			err := NewS3Client(mock, nil, "cards").Put(context.Background(), "cards/a.png", "image/png", []byte("png"))
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		name      string
+		mockError error
+		errorIs   error
+	}{
+		{name: "Deleted"},
+		{name: "S3 error", mockError: errors.New("access denied"), errorIs: ErrDeleteObject},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mock := &MockS3API{
+				DeleteObjectFunc: func(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+					if aws.ToString(params.Bucket) != "exports" || aws.ToString(params.Key) != "haiku/dt=2025-10-09/haiku.jsonl" {
+						t.Errorf("Unexpected delete input: %+v", params)
+					}
+					return &s3.DeleteObjectOutput{}, tc.mockError
+				},
+			}
+
+			err := NewS3Client(mock, nil, "exports").Delete(context.Background(), "haiku/dt=2025-10-09/haiku.jsonl")
+			if !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestPresignGet(t *testing.T) {
+	mock := &MockPresignAPI{
+		PresignGetObjectFunc: func(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.PresignOptions)) (*v4.PresignedHTTPRequest, error) {
+			options := &s3.PresignOptions{}
+			for _, fn := range optFns {
+				fn(options)
+			}
+			if options.Expires != time.Hour {
+				t.Errorf("Expected expiry of %v, got %v", time.Hour, options.Expires)
+			}
+			return &v4.PresignedHTTPRequest{URL: "https://cards.s3.amazonaws.com/cards/a.png?X-Amz-Signature=abc"}, nil
+		},
+	}
+
+	url, err := NewS3Client(nil, mock, "cards").PresignGet(context.Background(), "cards/a.png", time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if url != "https://cards.s3.amazonaws.com/cards/a.png?X-Amz-Signature=abc" {
+		t.Errorf("Unexpected url %q", url)
+	}
+}
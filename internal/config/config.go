@@ -0,0 +1,546 @@
+// Package config loads application configuration from the environment.
+//
+// Every setting has a default so that the service runs without any
+// configuration; invalid values are logged and replaced with their default.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	DefaultMaxCommitLength       = 100
+	DefaultCommitLengthStrategy  = "truncate"
+	DefaultErrorReporting        = "log"
+	DefaultLogLevel              = "info"
+	DefaultTruncatedBodyLength   = 50
+	DefaultPromptRefreshInterval = 5 * time.Minute
+	DefaultGuardrailVersion      = "DRAFT"
+	DefaultModerationRetries     = 2
+	DefaultStructureRetries      = 2
+	DefaultResponseCacheTTL      = time.Hour
+	DefaultJobTTL                = 24 * time.Hour
+	DefaultHaikuRetention        = 90 * 24 * time.Hour
+	DefaultArtifactURLTTL        = time.Hour
+	DefaultVoiceID               = "Joanna"
+	DefaultGitLabURL             = "https://gitlab.com"
+
+	DefaultFeatureFlagRefreshInterval = 30 * time.Second
+	DefaultShadowDailyTokens          = 100_000
+)
+
+type Config struct {
+	// ModelID is the Bedrock text model, or inference profile, used to
+	// generate haiku. When empty the client default is used.
+	ModelID string
+	// CanaryModelID is a second text model that serves CanaryPercent of the
+	// requests for ModelID, so that a new model version can be tried on a
+	// little traffic before switching to it.
+	CanaryModelID string
+	CanaryPercent int
+
+	// MaxCommitLength is the longest commit message sent to the model.
+	MaxCommitLength int
+	// CommitLengthStrategy is "truncate" to shorten longer commit messages to
+	// their subject line and the start of the body, or "reject" to refuse them.
+	CommitLengthStrategy string
+	// TruncatedBodyLength is how much of the body is kept when truncating.
+	TruncatedBodyLength int
+
+	// PromptParameterPath is the SSM Parameter Store path prompt templates are
+	// loaded from. When empty the compiled-in prompts are used.
+	PromptParameterPath string
+	// PromptRefreshInterval is how long loaded prompt templates are served
+	// before being reloaded.
+	PromptRefreshInterval time.Duration
+	// PromptExperiment splits traffic between prompt versions stored under
+	// PromptParameterPath, e.g. "v1:90,v2:10". When empty a single version is
+	// read from PromptParameterPath itself.
+	PromptExperiment string
+
+	// ShadowPercent is the share of served haiku written again after the
+	// response, for offline comparison, with the prompt version
+	// ShadowPromptVersion stored under PromptParameterPath, the model
+	// ShadowModelID, or both. Shadow haiku are kept in ShadowTable, and stop
+	// for the day once ShadowDailyTokens are spent.
+	ShadowPercent       int
+	ShadowPromptVersion string
+	ShadowModelID       string
+	ShadowDailyTokens   int
+	ShadowTable         string
+
+	// ModerationBlockedWords extends the built-in list of words that block a
+	// generated haiku.
+	ModerationBlockedWords []string
+	// ModerationGuardrailID and ModerationGuardrailVersion select an optional
+	// Bedrock guardrail applied to every generated haiku.
+	ModerationGuardrailID      string
+	ModerationGuardrailVersion string
+	// ModerationRetries is how many times a blocked haiku is regenerated.
+	ModerationRetries int
+
+	// StructureRetries is how many times a haiku that is not 5-7-5 is
+	// regenerated for strict requests.
+	StructureRetries int
+
+	// FallbackHaiku writes a haiku locally, rather than failing, while the
+	// text model is unavailable or throttled.
+	FallbackHaiku bool
+
+	// IllustrationModelID is the Bedrock image model used to render requested
+	// illustrations. When empty only the image prompt is returned.
+	IllustrationModelID string
+
+	// ResponseCacheSize is how many generated haiku are kept for reuse by
+	// equivalent requests from any caller. Zero disables the cache; only enable
+	// it where sharing responses between callers is acceptable.
+	ResponseCacheSize int
+	// ResponseCacheTTL is how long a cached haiku is reused.
+	ResponseCacheTTL time.Duration
+	// ResponseCacheTable is a DynamoDB table sharing cached haiku between
+	// Lambda instances, checked after the in-memory cache when both are set.
+	ResponseCacheTable string
+
+	// JobTable is the DynamoDB table background haiku jobs are kept in. On
+	// Lambda the jobs API is only available when it is set.
+	JobTable string
+	// JobTTL is how long a background job and its result are kept.
+	JobTTL time.Duration
+	// CallbackSecret signs the callbacks posted when background jobs finish.
+	// When empty, jobs with a callback URL are refused.
+	CallbackSecret string
+
+	// DailyHaikuTable is the DynamoDB table the haiku of the day is kept in,
+	// so every Lambda instance serves the same one. On Lambda GET /haiku/daily
+	// is only available when it is set.
+	DailyHaikuTable string
+
+	// RengaTable is the DynamoDB table repositories' renga are kept in, so
+	// every Lambda instance adds to the same chain of verses. On Lambda
+	// /haiku/renga is only available when it is set.
+	RengaTable string
+
+	// VoteTable is the DynamoDB table served haiku and their votes are kept
+	// in. On Lambda haiku can only be voted on when it is set.
+	VoteTable string
+	// HaikuRetention is how long a served haiku, who voted for it, and its
+	// vote counters are kept.
+	HaikuRetention time.Duration
+	// PublicationGuardrailID and PublicationGuardrailVersion select an
+	// optional Bedrock guardrail kept haiku are screened with in the
+	// background before they can be voted for or listed. When empty they are
+	// published as they are kept.
+	PublicationGuardrailID      string
+	PublicationGuardrailVersion string
+	// ExportBucket is the S3 bucket kept haiku are exported to each day for
+	// analysis. When empty nothing is exported.
+	ExportBucket string
+
+	// StatsToken is the bearer token GET /stats requires. When empty usage
+	// statistics are neither kept nor served.
+	StatsToken string
+	// StatsTable is the DynamoDB table daily usage counters are kept in. On
+	// Lambda usage statistics are only kept when it is set.
+	StatsTable string
+	// StatsFromStream counts haiku as the vote table's stream delivers them,
+	// rather than as they are served, so that serving a haiku doesn't wait on
+	// its counters. The function must be subscribed to the stream.
+	StatsFromStream bool
+
+	// AdminToken is the bearer token of the admin API, which manages API
+	// keys. When empty the admin API is off.
+	AdminToken string
+	// KeyTable is the DynamoDB table API keys are kept in. On Lambda API keys
+	// are only available when it is set.
+	KeyTable string
+	// RequireAPIKey makes the haiku endpoints refuse requests without a valid
+	// API key.
+	RequireAPIKey bool
+
+	// DigestSender is the SES identity weekly haiku digests are emailed from.
+	// When empty, or when haiku aren't kept or the admin API that manages
+	// subscriptions is off, digests are off.
+	DigestSender string
+	// PublicURL is the base URL the API is reached at, for the unsubscribe
+	// links in digests. When empty digests carry no link.
+	PublicURL string
+
+	// SlackNotifyWebhooks are the Slack incoming webhooks newly written haiku
+	// are pushed to, as JSON, e.g.
+	// [{"url": "https://hooks.slack.com/services/...", "repository": "octo/leaves"}].
+	// When empty nothing is pushed.
+	SlackNotifyWebhooks string
+	// NotifyTable is the DynamoDB table haiku are queued in until they are
+	// posted. On Lambda haiku are only pushed when it is set.
+	NotifyTable string
+
+	// HaikuEventsTopicARN is the SNS topic domain events are published to,
+	// such as one for each haiku newly written by the model. When empty no
+	// events are published to SNS.
+	HaikuEventsTopicARN string
+	// EventBusName is the EventBridge event bus, by name or ARN, domain events
+	// are put on. When empty no events are put on EventBridge.
+	EventBusName string
+
+	// ArtifactBucket is the S3 bucket share cards and audio are stored in.
+	// When empty both are unavailable.
+	ArtifactBucket string
+	// ArtifactURLTTL is how long artifact links remain valid. Links also
+	// stop working when the credentials that signed them expire.
+	ArtifactURLTTL time.Duration
+	// VoiceID is the Amazon Polly voice that reads haiku aloud.
+	VoiceID string
+
+	// GitHubAppID and GitHubAppPrivateKey authenticate the GitHub App that
+	// posts haiku back as commit and pull request comments. GitHubAppID may be
+	// the app's numeric ID or client ID, and the key is PEM encoded. When
+	// either is empty haiku are generated for webhooks but not posted.
+	GitHubAppID         string
+	GitHubAppPrivateKey string
+	// GitHubWebhookSecret verifies GitHub webhook deliveries. When empty the
+	// GitHub webhook endpoint is disabled.
+	GitHubWebhookSecret string
+	// GitHubIssueLookup has the GitHub App look up the titles of issues
+	// commits reference as #456, for the prompt. The app needs read access
+	// to issues.
+	GitHubIssueLookup bool
+
+	// JiraURL, JiraEmail and JiraAPIToken look up the summaries of Jira
+	// issues commits reference as JIRA-4521, for the prompt. When any is
+	// empty Jira issues are not looked up.
+	JiraURL      string
+	JiraEmail    string
+	JiraAPIToken string
+	// IssueBindings are the Jira projects and GitHub repositories whose
+	// issues each tenant's commits are described with, as JSON. When empty
+	// no issues are looked up.
+	IssueBindings string
+
+	// GitLabURL is the GitLab instance haiku are posted back to, for
+	// self-managed GitLab. GitLabToken is an access token with API scope; when
+	// empty haiku are generated for webhooks but not posted.
+	GitLabURL   string
+	GitLabToken string
+	// GitLabWebhookToken verifies GitLab webhook deliveries. When empty the
+	// GitLab webhook endpoint is disabled.
+	GitLabWebhookToken string
+
+	// BitbucketToken is a Bitbucket Cloud repository or workspace access token
+	// used to post haiku back as commit comments; when empty haiku are
+	// generated for webhooks but not posted.
+	BitbucketToken string
+	// BitbucketWebhookSecret verifies Bitbucket webhook deliveries. When empty
+	// the Bitbucket webhook endpoint is disabled.
+	BitbucketWebhookSecret string
+
+	// SlackSigningSecret verifies Slack slash command requests. When empty the
+	// Slack command endpoint is disabled.
+	SlackSigningSecret string
+	// DiscordPublicKey is the hex encoded public key of the Discord application
+	// whose interactions are answered. When empty the Discord interactions
+	// endpoint is disabled.
+	DiscordPublicKey string
+	// TeamsWebhookSecret is the base64 encoded security token Teams shows when
+	// an outgoing webhook is created. When empty the Teams webhook endpoint is
+	// disabled.
+	TeamsWebhookSecret string
+
+	// MoodRules picks the mood of webhook haiku by branch pattern or
+	// repository path prefix, e.g. "branch:release/*=humorous,path:octo/=technical".
+	// The first matching rule wins; when none match the default mood is used.
+	MoodRules string
+	// MergeQueueBranches are prefixes of temporary merge queue branches whose
+	// webhook events are ignored, replacing the built-in GitHub and Mergify
+	// prefixes. Set MergeQueueHaiku to generate haiku for them anyway.
+	MergeQueueBranches []string
+	MergeQueueHaiku    bool
+
+	// PluginProvider is a provider plugin executable that generates haiku in
+	// place of Bedrock. PluginNotifiers are notifier plugin executables told
+	// about every haiku generated for a webhook. PluginEnv names the host
+	// environment variables passed to plugins; nothing else is inherited.
+	PluginProvider  string
+	PluginNotifiers []string
+	PluginEnv       []string
+
+	// FeatureFlags turns flagged behaviour on or off, as JSON in the shape of
+	// an AppConfig feature flag profile, e.g.
+	// {"responseCache": {"enabled": true, "percent": 10, "stages": ["dev"]}}.
+	// Flags loaded from AppConfig take precedence.
+	FeatureFlags string
+	// AppConfigApplication, AppConfigEnvironment and AppConfigProfile name the
+	// AppConfig feature flag profile read through the AppConfig Lambda
+	// extension. When any is empty only FeatureFlags are used.
+	AppConfigApplication string
+	AppConfigEnvironment string
+	AppConfigProfile     string
+	// FeatureFlagRefreshInterval is how long flags loaded from AppConfig are
+	// used before being reloaded.
+	FeatureFlagRefreshInterval time.Duration
+	// Stage is the stage the service is deployed to, e.g. "prod", which flags
+	// can be limited to.
+	Stage string
+
+	// LambdaFunctionName is set by the Lambda runtime. When present, work that
+	// outlives a request is handed to an asynchronous invocation of the
+	// function instead of a background goroutine.
+	LambdaFunctionName string
+
+	// XRayDaemonAddress is set by the Lambda runtime when active tracing is
+	// on. When present, each stage of a request is traced as an X-Ray
+	// subsegment.
+	XRayDaemonAddress string
+
+	// LogFullPrompts logs prompts, which contain commit content, in full.
+	// By default only a hash of each prompt is logged.
+	LogFullPrompts bool
+
+	// LogLevel is the least severe level logged: "debug", "info", "warn" or
+	// "error".
+	LogLevel string
+
+	// LogFormat is "json" for a JSON object per log line, the default in
+	// Lambda, or "text" for readable lines, the default elsewhere.
+	LogFormat string
+
+	// LogCommitContent is how commit messages appear in every log line:
+	// "hash" for a short digest, "redact" for only their length, or "full".
+	// When unset, commit messages are hashed, or logged in full when
+	// LogFullPrompts is set. Messages and lines shorter than
+	// redact.MinFragmentLength (12 bytes) are left as written, outside the
+	// prompts logged at debug.
+	LogCommitContent string
+
+	// PIIRedaction removes personal data from commit text before it is sent
+	// to the model: "patterns" with the built-in regex pack, "comprehend"
+	// with the pack and Amazon Comprehend, or "off" (the default).
+	PIIRedaction string
+
+	// ErrorReporting is where panics and 5xx responses are reported: "log"
+	// for structured log lines, "sentry" for the Sentry project at SentryDSN,
+	// or "off".
+	ErrorReporting string
+	SentryDSN      string
+
+	// ErrorMetrics counts failed requests by error class as CloudWatch
+	// metrics, written as Embedded Metric Format log lines.
+	ErrorMetrics bool
+
+	// AuditLogGroup is the CloudWatch Logs log group audit records are kept
+	// in. Without it they are written to the function's log.
+	AuditLogGroup string
+}
+
+func Load() Config {
+	return Config{
+		ModelID:       os.Getenv("MODEL_ID"),
+		CanaryModelID: os.Getenv("CANARY_MODEL_ID"),
+		CanaryPercent: getInt("CANARY_PERCENT", 0),
+
+		MaxCommitLength:      getInt("MAX_COMMIT_LENGTH", DefaultMaxCommitLength),
+		CommitLengthStrategy: getString("COMMIT_LENGTH_STRATEGY", DefaultCommitLengthStrategy),
+		TruncatedBodyLength:  getInt("TRUNCATED_BODY_LENGTH", DefaultTruncatedBodyLength),
+
+		PromptParameterPath:   os.Getenv("PROMPT_PARAMETER_PATH"),
+		PromptRefreshInterval: getDuration("PROMPT_REFRESH_INTERVAL", DefaultPromptRefreshInterval),
+		PromptExperiment:      os.Getenv("PROMPT_EXPERIMENT"),
+
+		ShadowPercent:       getInt("SHADOW_PERCENT", 0),
+		ShadowPromptVersion: os.Getenv("SHADOW_PROMPT_VERSION"),
+		ShadowModelID:       os.Getenv("SHADOW_MODEL_ID"),
+		ShadowDailyTokens:   getInt("SHADOW_DAILY_TOKENS", DefaultShadowDailyTokens),
+		ShadowTable:         os.Getenv("SHADOW_TABLE"),
+
+		ModerationBlockedWords:     getList("MODERATION_BLOCKED_WORDS"),
+		ModerationGuardrailID:      os.Getenv("MODERATION_GUARDRAIL_ID"),
+		ModerationGuardrailVersion: getString("MODERATION_GUARDRAIL_VERSION", DefaultGuardrailVersion),
+		ModerationRetries:          getInt("MODERATION_RETRIES", DefaultModerationRetries),
+
+		StructureRetries: getInt("STRUCTURE_RETRIES", DefaultStructureRetries),
+
+		FallbackHaiku: getBool("FALLBACK_HAIKU", false),
+
+		IllustrationModelID: os.Getenv("ILLUSTRATION_MODEL_ID"),
+
+		ResponseCacheSize:  getInt("RESPONSE_CACHE_SIZE", 0),
+		ResponseCacheTTL:   getDuration("RESPONSE_CACHE_TTL", DefaultResponseCacheTTL),
+		ResponseCacheTable: os.Getenv("RESPONSE_CACHE_TABLE"),
+
+		JobTable:       os.Getenv("JOB_TABLE"),
+		JobTTL:         getDuration("JOB_TTL", DefaultJobTTL),
+		CallbackSecret: os.Getenv("CALLBACK_SECRET"),
+
+		DailyHaikuTable: os.Getenv("DAILY_HAIKU_TABLE"),
+
+		RengaTable: os.Getenv("RENGA_TABLE"),
+
+		VoteTable:                   os.Getenv("VOTE_TABLE"),
+		HaikuRetention:              getDuration("HAIKU_RETENTION", DefaultHaikuRetention),
+		PublicationGuardrailID:      os.Getenv("PUBLICATION_GUARDRAIL_ID"),
+		PublicationGuardrailVersion: getString("PUBLICATION_GUARDRAIL_VERSION", DefaultGuardrailVersion),
+		ExportBucket:                os.Getenv("EXPORT_BUCKET"),
+
+		StatsToken:      os.Getenv("STATS_TOKEN"),
+		StatsTable:      os.Getenv("STATS_TABLE"),
+		StatsFromStream: getBool("STATS_FROM_STREAM", false),
+
+		AdminToken:    os.Getenv("ADMIN_TOKEN"),
+		KeyTable:      os.Getenv("KEY_TABLE"),
+		RequireAPIKey: getBool("REQUIRE_API_KEY", false),
+
+		DigestSender: os.Getenv("DIGEST_SENDER"),
+		PublicURL:    os.Getenv("PUBLIC_URL"),
+
+		SlackNotifyWebhooks: os.Getenv("SLACK_NOTIFY_WEBHOOKS"),
+		NotifyTable:         os.Getenv("NOTIFY_TABLE"),
+
+		HaikuEventsTopicARN: os.Getenv("HAIKU_EVENTS_TOPIC_ARN"),
+		EventBusName:        os.Getenv("EVENT_BUS_NAME"),
+
+		ArtifactBucket: os.Getenv("ARTIFACT_BUCKET"),
+		ArtifactURLTTL: getDuration("ARTIFACT_URL_TTL", DefaultArtifactURLTTL),
+		VoiceID:        getString("VOICE_ID", DefaultVoiceID),
+
+		GitHubAppID:         os.Getenv("GITHUB_APP_ID"),
+		GitHubAppPrivateKey: os.Getenv("GITHUB_APP_PRIVATE_KEY"),
+		GitHubWebhookSecret: os.Getenv("GITHUB_WEBHOOK_SECRET"),
+		GitHubIssueLookup:   getBool("GITHUB_ISSUE_LOOKUP", false),
+
+		JiraURL:      os.Getenv("JIRA_URL"),
+		JiraEmail:    os.Getenv("JIRA_EMAIL"),
+		JiraAPIToken: os.Getenv("JIRA_API_TOKEN"),
+
+		IssueBindings: os.Getenv("ISSUE_BINDINGS"),
+
+		GitLabURL:          getString("GITLAB_URL", DefaultGitLabURL),
+		GitLabToken:        os.Getenv("GITLAB_TOKEN"),
+		GitLabWebhookToken: os.Getenv("GITLAB_WEBHOOK_TOKEN"),
+
+		BitbucketToken:         os.Getenv("BITBUCKET_TOKEN"),
+		BitbucketWebhookSecret: os.Getenv("BITBUCKET_WEBHOOK_SECRET"),
+
+		SlackSigningSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+		DiscordPublicKey:   os.Getenv("DISCORD_PUBLIC_KEY"),
+		TeamsWebhookSecret: os.Getenv("TEAMS_WEBHOOK_SECRET"),
+
+		MoodRules:          os.Getenv("MOOD_RULES"),
+		MergeQueueBranches: getList("MERGE_QUEUE_BRANCHES"),
+		MergeQueueHaiku:    getBool("MERGE_QUEUE_HAIKU", false),
+
+		PluginProvider:  os.Getenv("PLUGIN_PROVIDER"),
+		PluginNotifiers: getList("PLUGIN_NOTIFIERS"),
+		PluginEnv:       getList("PLUGIN_ENV"),
+
+		FeatureFlags:               os.Getenv("FEATURE_FLAGS"),
+		AppConfigApplication:       os.Getenv("APPCONFIG_APPLICATION"),
+		AppConfigEnvironment:       os.Getenv("APPCONFIG_ENVIRONMENT"),
+		AppConfigProfile:           os.Getenv("APPCONFIG_PROFILE"),
+		FeatureFlagRefreshInterval: getDuration("FEATURE_FLAG_REFRESH_INTERVAL", DefaultFeatureFlagRefreshInterval),
+		Stage:                      os.Getenv("STAGE"),
+
+		LambdaFunctionName: os.Getenv("AWS_LAMBDA_FUNCTION_NAME"),
+		XRayDaemonAddress:  os.Getenv("AWS_XRAY_DAEMON_ADDRESS"),
+
+		LogFullPrompts:   getBool("LOG_FULL_PROMPTS", false),
+		LogLevel:         getString("LOG_LEVEL", DefaultLogLevel),
+		LogFormat:        getString("LOG_FORMAT", defaultLogFormat()),
+		LogCommitContent: os.Getenv("LOG_COMMIT_CONTENT"),
+		PIIRedaction:     os.Getenv("PII_REDACTION"),
+
+		ErrorReporting: getString("ERROR_REPORTING", DefaultErrorReporting),
+		SentryDSN:      os.Getenv("SENTRY_DSN"),
+
+		ErrorMetrics: getBool("ERROR_METRICS", true),
+
+		AuditLogGroup: os.Getenv("AUDIT_LOG_GROUP"),
+	}
+}
+
+// ErrInvalidConfig reports settings that load but can't work together.
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// Validate reports settings that can't work together. Lambda instances don't
+// share memory, so there required API keys need the key table to count their
+// quotas in.
+func (c Config) Validate() error {
+	if c.LambdaFunctionName != "" && c.RequireAPIKey && c.KeyTable == "" {
+		return fmt.Errorf("%w: REQUIRE_API_KEY on Lambda needs KEY_TABLE", ErrInvalidConfig)
+	}
+	return nil
+}
+
+// defaultLogFormat is JSON in Lambda, where CloudWatch Logs Insights queries
+// it, and text elsewhere, e.g. running the CLI locally.
+func defaultLogFormat() string {
+	if os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "" {
+		return "json"
+	}
+	return "text"
+}
+
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getList splits a comma-separated value, dropping empty entries.
+func getList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func getBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logging.Warnf("[CONFIG] invalid boolean for %s: %q, using default %t", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func getInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logging.Warnf("[CONFIG] invalid integer for %s: %q, using default %d", key, value, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logging.Warnf("[CONFIG] invalid duration for %s: %q, using default %s", key, value, fallback)
+		return fallback
+	}
+	return duration
+}
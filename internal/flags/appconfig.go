@@ -0,0 +1,66 @@
+package flags
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultAppConfigEndpoint is where the AppConfig Lambda extension serves
+// configuration.
+const DefaultAppConfigEndpoint = "http://localhost:2772"
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// AppConfigSource loads flags from an AppConfig feature flag profile through
+// the AppConfig Lambda extension, which polls AppConfig and caches the
+// profile, so that loading flags never leaves the instance.
+type AppConfigSource struct {
+	httpClient HTTPClient
+	url        string
+}
+
+func NewAppConfigSource(httpClient HTTPClient, endpoint string, application string, environment string, profile string) *AppConfigSource {
+	return &AppConfigSource{
+		httpClient: httpClient,
+		url: fmt.Sprintf("%s/applications/%s/environments/%s/configurations/%s",
+			endpoint, url.PathEscape(application), url.PathEscape(environment), url.PathEscape(profile)),
+	}
+}
+
+func NewDefaultAppConfigSource(application string, environment string, profile string) *AppConfigSource {
+	return NewAppConfigSource(&http.Client{Timeout: 2 * time.Second}, DefaultAppConfigEndpoint, application, environment, profile)
+}
+
+func (s *AppConfigSource) Load(ctx context.Context) (Flags, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadFlags, err)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadFlags, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadFlags, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: appconfig extension returned %d: %s", ErrLoadFlags, resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	flags, err := Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrLoadFlags, err)
+	}
+	return flags, nil
+}
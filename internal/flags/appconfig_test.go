@@ -0,0 +1,55 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type MockHTTPClient struct {
+	StatusCode  int
+	Body        string
+	LastRequest *http.Request
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.LastRequest = req
+	return &http.Response{StatusCode: m.StatusCode, Body: io.NopCloser(strings.NewReader(m.Body))}, nil
+}
+
+func TestAppConfigSourceLoad(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		expected   bool
+		errorIs    error
+	}{
+		{name: "Loaded", statusCode: http.StatusOK, body: `{"export": {"enabled": true}}`, expected: true},
+		{name: "Extension error", statusCode: http.StatusBadRequest, body: "unknown profile", errorIs: ErrLoadFlags},
+		{name: "Invalid flags", statusCode: http.StatusOK, body: `{"export": {"enabled": true, "percent": -1}}`, errorIs: ErrInvalidFlags},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			httpClient := &MockHTTPClient{StatusCode: tc.statusCode, Body: tc.body}
+			source := NewAppConfigSource(httpClient, DefaultAppConfigEndpoint, "haiku", "prod", "flags")
+
+			flags, err := source.Load(context.Background())
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if flags[Export].Enabled != tc.expected {
+				t.Errorf("Expected export enabled %v, got %+v", tc.expected, flags)
+			}
+
+			expectedURL := "http://localhost:2772/applications/haiku/environments/prod/configurations/flags"
+			if httpClient.LastRequest.URL.String() != expectedURL {
+				t.Errorf("Expected a request to %s, got %s", expectedURL, httpClient.LastRequest.URL)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package flags
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+type MockSource struct {
+	FlagsToReturn Flags
+	ErrorToReturn error
+	Calls         int
+}
+
+func (m *MockSource) Load(ctx context.Context) (Flags, error) {
+	m.Calls++
+	return m.FlagsToReturn, m.ErrorToReturn
+}
+
+func percent(p int) *int {
+	return &p
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		errorIs error
+	}{
+		{name: "Flags", data: `{"responseCache": {"enabled": true, "percent": 10, "stages": ["dev"]}, "export": {"enabled": false}}`},
+		{name: "No flags", data: `{}`},
+		{name: "Percent out of range", data: `{"export": {"enabled": true, "percent": 150}}`, errorIs: ErrInvalidFlags},
+		{name: "Not JSON", data: `export=on`, errorIs: ErrInvalidFlags},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tc.data)); !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	flags := Flags{
+		"on":         {Enabled: true},
+		"off":        {Enabled: false},
+		"dev":        {Enabled: true, Stages: []string{"dev"}},
+		"nobody":     {Enabled: true, Percent: percent(0)},
+		"everybody":  {Enabled: true, Percent: percent(100)},
+		"off in dev": {Enabled: false, Stages: []string{"dev"}},
+	}
+
+	tests := []struct {
+		name     string
+		flag     string
+		stage    string
+		def      bool
+		expected bool
+	}{
+		{name: "On", flag: "on", expected: true},
+		{name: "Off", flag: "off", def: true},
+		{name: "Undefined uses the default", flag: "unknown", def: true, expected: true},
+		{name: "Undefined off by default", flag: "unknown"},
+		{name: "In its stage", flag: "dev", stage: "dev", expected: true},
+		{name: "Outside its stage", flag: "dev", stage: "prod", def: true},
+		{name: "No caller", flag: "nobody", def: true},
+		{name: "Every caller", flag: "everybody", expected: true},
+		{name: "Off in its stage", flag: "off in dev", stage: "dev", def: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := NewStore(flags, &Options{Stage: tc.stage})
+			if got := store.Enabled(context.Background(), tc.flag, tc.def); got != tc.expected {
+				t.Errorf("Expected %s to be %v, got %v", tc.flag, tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestEnabledRollout(t *testing.T) {
+	store := NewStore(Flags{"rollout": {Enabled: true, Percent: percent(25)}}, nil)
+
+	on := 0
+	for i := range 1000 {
+		ctx := keys.NewContext(context.Background(), fmt.Sprintf("key-%d", i))
+		enabled := store.Enabled(ctx, "rollout", false)
+		for range 3 {
+			if store.Enabled(ctx, "rollout", false) != enabled {
+				t.Fatalf("Expected key-%d to see the same result on every request", i)
+			}
+		}
+		if enabled {
+			on++
+		}
+	}
+
+	// The hash spreads callers roughly evenly; allow for its unevenness.
+	if on < 200 || on > 300 {
+		t.Errorf("Expected about a quarter of 1000 callers, got %d", on)
+	}
+}
+
+func TestStoreRefresh(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	source := &MockSource{FlagsToReturn: Flags{"export": {Enabled: true}}}
+	store := NewStore(Flags{"export": {Enabled: false}, "responseCache": {Enabled: true}}, &Options{Source: source, Interval: time.Minute})
+	store.now = func() time.Time { return now }
+
+	if !store.Enabled(context.Background(), Export, false) {
+		t.Errorf("Expected the loaded flag over the fallback")
+	}
+	if !store.Enabled(context.Background(), ResponseCache, false) {
+		t.Errorf("Expected fallback flags the source leaves out to be kept")
+	}
+	if source.Calls != 1 {
+		t.Errorf("Expected one load, got %d", source.Calls)
+	}
+
+	// A failed load keeps the flags last loaded.
+	source.FlagsToReturn, source.ErrorToReturn = nil, ErrLoadFlags
+	now = now.Add(time.Minute)
+	if !store.Enabled(context.Background(), Export, false) || source.Calls != 2 {
+		t.Errorf("Expected the last loaded flags after a failed reload, got %d loads", source.Calls)
+	}
+	if store.Enabled(context.Background(), Export, false); source.Calls != 2 {
+		t.Errorf("Expected no reload before the interval, got %d loads", source.Calls)
+	}
+}
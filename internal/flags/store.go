@@ -0,0 +1,126 @@
+package flags
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// DefaultRefreshInterval is how long loaded flags are used before they are
+// loaded again.
+const DefaultRefreshInterval = 30 * time.Second
+
+// Source loads flags from an external location, e.g. AppConfig.
+type Source interface {
+	Load(ctx context.Context) (Flags, error)
+}
+
+// Store answers whether flags are on, from flags loaded from a source over
+// flags it was given, such as those set in the environment.
+type Store struct {
+	source   Source
+	fallback Flags
+	stage    string
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	current   Flags
+	checkedAt time.Time
+
+	refreshing sync.Mutex
+}
+
+type Options struct {
+	Source   Source        // Loads flags over the fallback (default: none, only the fallback is used)
+	Stage    string        // Stage the service runs in, matched against flags' stages (default: none)
+	Interval time.Duration // How long loaded flags are used before they are loaded again (default: 30 seconds)
+}
+
+// NewStore creates a store that uses the fallback flags until the first
+// successful load from the source, and then reloads whenever the loaded flags
+// are older than the interval.
+func NewStore(fallback Flags, opts *Options) *Store {
+	store := &Store{
+		fallback: fallback,
+		current:  fallback,
+		interval: DefaultRefreshInterval,
+		now:      time.Now,
+	}
+
+	if opts != nil {
+		store.source = opts.Source
+		store.stage = opts.Stage
+		if opts.Interval > 0 {
+			store.interval = opts.Interval
+		}
+	}
+
+	return store
+}
+
+// Refresh loads the latest flags from the source. On error the previously
+// loaded flags remain in use.
+func (s *Store) Refresh(ctx context.Context) error {
+	if s.source == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.checkedAt = s.now()
+	s.mu.Unlock()
+
+	loaded, err := s.source.Load(ctx)
+	if err != nil {
+		return err
+	}
+
+	merged := maps.Clone(s.fallback)
+	if merged == nil {
+		merged = Flags{}
+	}
+	maps.Copy(merged, loaded)
+
+	s.mu.Lock()
+	s.current = merged
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Enabled reports whether the flag name is on for the caller of ctx, or def
+// when the flag isn't defined. Stale flags are refreshed first; only one
+// caller refreshes at a time, and concurrent callers use the current flags
+// rather than waiting.
+func (s *Store) Enabled(ctx context.Context, name string, def bool) bool {
+	if s.stale() && s.refreshing.TryLock() {
+		if s.stale() {
+			if err := s.Refresh(ctx); err != nil {
+				logging.Errorf("[FLAG STORE] error refreshing feature flags: %v\n", err)
+			}
+		}
+		s.refreshing.Unlock()
+	}
+
+	s.mu.RLock()
+	flag, ok := s.current[name]
+	s.mu.RUnlock()
+
+	if !ok {
+		return def
+	}
+	return flag.on(ctx, name, s.stage)
+}
+
+func (s *Store) stale() bool {
+	if s.source == nil {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.now().Sub(s.checkedAt) >= s.interval
+}
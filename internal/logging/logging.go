@@ -0,0 +1,166 @@
+// Package logging writes leveled log lines. In Lambda each line is a JSON
+// object, which CloudWatch Logs Insights queries without any parsing, e.g.
+// filter level = "ERROR" | stats count() by component; elsewhere lines are
+// the standard library's readable text.
+//
+// Messages keep this repository's "[COMPONENT] message" form. JSON lines also
+// carry the component as a field of its own, and every line carries the IDs
+// of the request being handled, so that a request can be traced through the
+// log.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+type Options struct {
+	Level  slog.Level // Least severe level logged (default: info)
+	Format string     // FormatJSON or FormatText (default: text)
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error", in any case.
+func ParseLevel(level string) (slog.Level, bool) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, true
+	case "info":
+		return slog.LevelInfo, true
+	case "warn", "warning":
+		return slog.LevelWarn, true
+	case "error":
+		return slog.LevelError, true
+	}
+	return slog.LevelInfo, false
+}
+
+// Setup sends log lines at or above the level to w, in the format given.
+// Lines written with the log package, e.g. by plugins or dependencies, are
+// logged at info.
+func Setup(w io.Writer, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	if opts.Format == FormatJSON {
+		slog.SetDefault(slog.New(NewJSONHandler(w, opts.Level)))
+		return
+	}
+
+	// Until slog.SetDefault is called, slog writes through the log package,
+	// which prints readable text.
+	log.SetOutput(w)
+	slog.SetLogLoggerLevel(opts.Level)
+}
+
+// NewJSONHandler writes a JSON object per line to w, with the component of
+// each message as a field.
+func NewJSONHandler(w io.Writer, level slog.Leveler) slog.Handler {
+	return componentHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})}
+}
+
+type componentHandler struct {
+	slog.Handler
+}
+
+// Handle adds the component, and the request IDs to lines written with the
+// log package rather than this one, which carry no attributes of their own.
+func (h componentHandler) Handle(ctx context.Context, r slog.Record) error {
+	r = r.Clone()
+	if r.NumAttrs() == 0 {
+		r.AddAttrs(CurrentRequest().attrs()...)
+	}
+	if component, ok := Component(r.Message); ok {
+		r.AddAttrs(slog.String("component", component))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return componentHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h componentHandler) WithGroup(name string) slog.Handler {
+	return componentHandler{h.Handler.WithGroup(name)}
+}
+
+// Component returns the component a message starts with, e.g. "HAIKU
+// SERVICE" for "[HAIKU SERVICE] error invoking Claude".
+func Component(message string) (string, bool) {
+	if !strings.HasPrefix(message, "[") {
+		return "", false
+	}
+	component, _, ok := strings.Cut(message[1:], "] ")
+	if !ok || component == "" {
+		return "", false
+	}
+	return component, true
+}
+
+// Request identifies the request being handled.
+type Request struct {
+	ID           string // Lambda request ID, as CloudWatch Logs reports it
+	APIRequestID string // API Gateway request ID, for requests through the API
+}
+
+func (r Request) attrs() []slog.Attr {
+	var attrs []slog.Attr
+	if r.ID != "" {
+		attrs = append(attrs, slog.String("requestId", r.ID))
+	}
+	if r.APIRequestID != "" {
+		attrs = append(attrs, slog.String("apiRequestId", r.APIRequestID))
+	}
+	return attrs
+}
+
+var current atomic.Pointer[Request]
+
+// SetRequest attaches the IDs of request to every log line until restore is
+// called, which restores those of the request before. A Lambda instance
+// handles one invocation at a time, so the IDs are the process's rather
+// than carried by a context; work outliving the invocation logs without
+// them once it's restored.
+func SetRequest(request Request) (restore func()) {
+	previous := current.Swap(&request)
+	return func() {
+		current.Store(previous)
+	}
+}
+
+// CurrentRequest returns the request being handled, if any.
+func CurrentRequest() Request {
+	if request := current.Load(); request != nil {
+		return *request
+	}
+	return Request{}
+}
+
+func Debugf(format string, args ...any) { logf(slog.LevelDebug, format, args...) }
+func Infof(format string, args ...any)  { logf(slog.LevelInfo, format, args...) }
+func Warnf(format string, args ...any)  { logf(slog.LevelWarn, format, args...) }
+func Errorf(format string, args ...any) { logf(slog.LevelError, format, args...) }
+
+func logf(level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	logger := slog.Default()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+
+	message := strings.TrimSuffix(fmt.Sprintf(format, args...), "\n")
+	record := slog.NewRecord(time.Now(), level, message, 0)
+	record.AddAttrs(CurrentRequest().attrs()...)
+	_ = logger.Handler().Handle(ctx, record)
+}
@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		level         string
+		expectedLevel slog.Level
+		expectedOK    bool
+	}{
+		{level: "debug", expectedLevel: slog.LevelDebug, expectedOK: true},
+		{level: "INFO", expectedLevel: slog.LevelInfo, expectedOK: true},
+		{level: "warn", expectedLevel: slog.LevelWarn, expectedOK: true},
+		{level: "warning", expectedLevel: slog.LevelWarn, expectedOK: true},
+		{level: "error", expectedLevel: slog.LevelError, expectedOK: true},
+		{level: "verbose", expectedLevel: slog.LevelInfo},
+		{level: "", expectedLevel: slog.LevelInfo},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.level, func(t *testing.T) {
+			level, ok := ParseLevel(tc.level)
+			if level != tc.expectedLevel || ok != tc.expectedOK {
+				t.Errorf("Expected %v (%t), got %v (%t)", tc.expectedLevel, tc.expectedOK, level, ok)
+			}
+		})
+	}
+}
+
+func TestJSONHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewJSONHandler(&out, slog.LevelInfo))
+
+	logger.Debug("[HAIKU SERVICE] sending request to Bedrock")
+	logger.Error("[HAIKU SERVICE] error invoking Claude: throttled")
+	logger.Info("plugin started")
+
+	lines := bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n"))
+	if len(lines) != 2 {
+		t.Fatalf("Expected debug left out, got %q", out.String())
+	}
+
+	var line struct {
+		Level     string `json:"level"`
+		Msg       string `json:"msg"`
+		Component string `json:"component"`
+	}
+	if err := json.Unmarshal(lines[0], &line); err != nil {
+		t.Fatalf("Failed to unmarshal log line: %v", err)
+	}
+	if line.Level != "ERROR" || line.Component != "HAIKU SERVICE" || line.Msg != "[HAIKU SERVICE] error invoking Claude: throttled" {
+		t.Errorf("Expected an error of the haiku service, got %+v", line)
+	}
+
+	line.Component = ""
+	if err := json.Unmarshal(lines[1], &line); err != nil {
+		t.Fatalf("Failed to unmarshal log line: %v", err)
+	}
+	if line.Component != "" {
+		t.Errorf("Expected no component, got %q", line.Component)
+	}
+}
+
+func TestComponent(t *testing.T) {
+	tests := []struct {
+		message           string
+		expectedComponent string
+		expectedOK        bool
+	}{
+		{message: "[APP] error initializing", expectedComponent: "APP", expectedOK: true},
+		{message: "[PLUGIN styles] restarting plugin", expectedComponent: "PLUGIN styles", expectedOK: true},
+		{message: "[] nothing"},
+		{message: "[APP]"},
+		{message: "no component"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.message, func(t *testing.T) {
+			component, ok := Component(tc.message)
+			if component != tc.expectedComponent || ok != tc.expectedOK {
+				t.Errorf("Expected %q (%t), got %q (%t)", tc.expectedComponent, tc.expectedOK, component, ok)
+			}
+		})
+	}
+}
+
+func TestSetRequest(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(NewJSONHandler(&out, slog.LevelInfo))
+
+	restoreInvocation := SetRequest(Request{ID: "c6af9ac6-7b61-11e6-9a41-93e8deadbeef"})
+	restoreAPI := SetRequest(Request{ID: "c6af9ac6-7b61-11e6-9a41-93e8deadbeef", APIRequestID: "Ab1xYHJ-IAMEQ3A="})
+	logger.Info("[PLUGIN styles] started")
+	restoreAPI()
+	logger.Info("[APP] stream aggregated")
+	restoreInvocation()
+	logger.Info("[APP] warmed up")
+
+	type requestIDs struct {
+		RequestID    string `json:"requestId"`
+		APIRequestID string `json:"apiRequestId"`
+	}
+	var lines []requestIDs
+	for _, data := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		var line requestIDs
+		if err := json.Unmarshal(data, &line); err != nil {
+			t.Fatalf("Failed to unmarshal log line: %v", err)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %q", out.String())
+	}
+	if lines[0].RequestID == "" || lines[0].APIRequestID != "Ab1xYHJ-IAMEQ3A=" {
+		t.Errorf("Expected both request IDs, got %+v", lines[0])
+	}
+	if lines[1].RequestID == "" || lines[1].APIRequestID != "" {
+		t.Errorf("Expected only the Lambda request ID, got %+v", lines[1])
+	}
+	if lines[2].RequestID != "" {
+		t.Errorf("Expected no request IDs outside a request, got %+v", lines[2])
+	}
+}
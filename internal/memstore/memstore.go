@@ -0,0 +1,228 @@
+// Package memstore keeps the services' values and counters in memory, for
+// running them as a single server. Lambda instances don't share memory, so
+// there the services keep them in DynamoDB instead.
+package memstore
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	value     []byte
+	expiresAt time.Time // Zero when the value doesn't expire
+}
+
+type counters struct {
+	values    map[string]int64
+	expiresAt time.Time // Zero when the counters don't expire
+}
+
+// Store implements the stores of the services that keep their data in
+// DynamoDB on Lambda: values and named counters by key, each kept until its
+// ttl passes, or until the process exits for a zero ttl.
+type Store struct {
+	mu       sync.Mutex
+	entries  map[string]entry
+	counters map[string]counters
+	now      func() time.Time
+}
+
+func New() *Store {
+	return &Store{
+		entries:  make(map[string]entry),
+		counters: make(map[string]counters),
+		now:      time.Now,
+	}
+}
+
+// SetClock replaces the clock values and counters expire by, for tests.
+func (s *Store) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+func (s *Store) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.live(key, s.now())
+	return stored.value, ok, nil
+}
+
+func (s *Store) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.put(key, value, ttl)
+	return nil
+}
+
+func (s *Store) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.live(key, s.now()); ok {
+		return false, nil
+	}
+	s.put(key, value, ttl)
+	return true, nil
+}
+
+// Delete removes the value and the counters stored under key.
+func (s *Store) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	delete(s.counters, key)
+	return nil
+}
+
+// ScanPrefix calls fn with every unexpired value whose key starts with
+// prefix, in key order.
+func (s *Store) ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	s.mu.Lock()
+	now := s.now()
+	values := make(map[string][]byte)
+	for key := range s.entries {
+		if stored, ok := s.live(key, now); ok && strings.HasPrefix(key, prefix) {
+			values[key] = stored.value
+		}
+	}
+	s.mu.Unlock()
+
+	// fn runs unlocked, so that it can use the store.
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if err := fn(key, values[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ScanCounters calls fn with the unexpired counters of every key starting
+// with prefix, in key order.
+func (s *Store) ScanCounters(ctx context.Context, prefix string, fn func(key string, counters map[string]int64) error) error {
+	s.mu.Lock()
+	now := s.now()
+	found := make(map[string]map[string]int64)
+	for key := range s.counters {
+		if current, ok := s.liveCounters(key, now); ok && strings.HasPrefix(key, prefix) {
+			found[key] = maps.Clone(current.values)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, key := range slices.Sorted(maps.Keys(found)) {
+		if err := fn(key, found[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddCounters adds deltas to the counters under key and returns their new
+// values. Every add keeps the counters for another ttl.
+func (s *Store) AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	current, ok := s.liveCounters(key, now)
+	if !ok {
+		current = counters{values: make(map[string]int64, len(deltas))}
+	}
+	for name, delta := range deltas {
+		current.values[name] += delta
+	}
+	current.expiresAt = expiry(now, ttl)
+	s.counters[key] = current
+	return maps.Clone(current.values), nil
+}
+
+func (s *Store) GetCounters(ctx context.Context, key string) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, _ := s.liveCounters(key, s.now())
+	return maps.Clone(current.values), nil
+}
+
+func (s *Store) BatchGetCounters(ctx context.Context, keys []string) (map[string]map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	found := make(map[string]map[string]int64, len(keys))
+	for _, key := range keys {
+		if current, ok := s.liveCounters(key, now); ok {
+			found[key] = maps.Clone(current.values)
+		}
+	}
+	return found, nil
+}
+
+// RemoveCounters removes the named counters under key, leaving the rest and
+// their expiry as they are.
+func (s *Store) RemoveCounters(ctx context.Context, key string, names []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, ok := s.liveCounters(key, s.now()); ok {
+		for _, name := range names {
+			delete(current.values, name)
+		}
+	}
+	return nil
+}
+
+// put stores value under key. Expired values and counters are dropped as new
+// values arrive, so they don't pile up. The caller holds s.mu.
+func (s *Store) put(key string, value []byte, ttl time.Duration) {
+	now := s.now()
+	for k := range s.entries {
+		if _, ok := s.live(k, now); !ok {
+			delete(s.entries, k)
+		}
+	}
+	for k := range s.counters {
+		if _, ok := s.liveCounters(k, now); !ok {
+			delete(s.counters, k)
+		}
+	}
+	s.entries[key] = entry{value: value, expiresAt: expiry(now, ttl)}
+}
+
+func (s *Store) live(key string, now time.Time) (entry, bool) {
+	stored, ok := s.entries[key]
+	if !ok || expired(stored.expiresAt, now) {
+		return entry{}, false
+	}
+	return stored, true
+}
+
+func (s *Store) liveCounters(key string, now time.Time) (counters, bool) {
+	current, ok := s.counters[key]
+	if !ok || expired(current.expiresAt, now) {
+		return counters{}, false
+	}
+	return current, true
+}
+
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+func expired(expiresAt time.Time, now time.Time) bool {
+	return !expiresAt.IsZero() && !now.Before(expiresAt)
+}
@@ -0,0 +1,116 @@
+package memstore
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestExpiry(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := New()
+	store.SetClock(func() time.Time { return now })
+	ctx := context.Background()
+
+	_ = store.Put(ctx, "day", []byte("haiku"), 24*time.Hour)
+	_ = store.Put(ctx, "key", []byte("secret"), 0)
+	_, _ = store.AddCounters(ctx, "votes", map[string]int64{"up": 1}, time.Hour)
+
+	now = now.Add(2 * time.Hour)
+	if value, ok, _ := store.Get(ctx, "day"); !ok || string(value) != "haiku" {
+		t.Errorf("Expected the value kept for its ttl, got %q, %v", value, ok)
+	}
+	if counters, _ := store.GetCounters(ctx, "votes"); counters != nil {
+		t.Errorf("Expected the counters to have expired, got %v", counters)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if _, ok, _ := store.Get(ctx, "day"); ok {
+		t.Errorf("Expected the value to have expired")
+	}
+	if stored, _ := store.PutIfAbsent(ctx, "day", []byte("next"), time.Hour); !stored {
+		t.Errorf("Expected an expired value to be replaced")
+	}
+	if _, ok, _ := store.Get(ctx, "key"); !ok {
+		t.Errorf("Expected a value without a ttl to be kept")
+	}
+}
+
+func TestPutIfAbsent(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+
+	if stored, _ := store.PutIfAbsent(ctx, "mark", []byte("first"), time.Hour); !stored {
+		t.Errorf("Expected the first value to be stored")
+	}
+	if stored, _ := store.PutIfAbsent(ctx, "mark", []byte("second"), time.Hour); stored {
+		t.Errorf("Expected the second value to be refused")
+	}
+	if value, _, _ := store.Get(ctx, "mark"); string(value) != "first" {
+		t.Errorf("Expected the first value kept, got %q", value)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+
+	_, _ = store.AddCounters(ctx, "day:1", map[string]int64{"served": 2}, 0)
+	counters, _ := store.AddCounters(ctx, "day:1", map[string]int64{"served": 1, "cached": 1}, 0)
+	if !reflect.DeepEqual(counters, map[string]int64{"served": 3, "cached": 1}) {
+		t.Errorf("Expected the counters added to, got %v", counters)
+	}
+
+	found, _ := store.BatchGetCounters(ctx, []string{"day:1", "day:2"})
+	if len(found) != 1 || found["day:1"]["served"] != 3 {
+		t.Errorf("Expected only day:1's counters, got %v", found)
+	}
+
+	var scanned []string
+	_, _ = store.AddCounters(ctx, "night:1", map[string]int64{"served": 1}, 0)
+	_ = store.ScanCounters(ctx, "day:", func(key string, counters map[string]int64) error {
+		scanned = append(scanned, key)
+		return nil
+	})
+	if !reflect.DeepEqual(scanned, []string{"day:1"}) {
+		t.Errorf("Expected day:1's counters scanned, got %v", scanned)
+	}
+
+	_ = store.RemoveCounters(ctx, "day:1", []string{"cached", "missing"})
+	if counters, _ := store.GetCounters(ctx, "day:1"); !reflect.DeepEqual(counters, map[string]int64{"served": 3}) {
+		t.Errorf("Expected cached removed, got %v", counters)
+	}
+
+	_ = store.Delete(ctx, "day:1")
+	if counters, _ := store.GetCounters(ctx, "day:1"); counters != nil {
+		t.Errorf("Expected deleted counters, got %v", counters)
+	}
+}
+
+func TestScanPrefix(t *testing.T) {
+	store := New()
+	ctx := context.Background()
+	for _, key := range []string{"haiku:b", "haiku:a", "voter:a"} {
+		_ = store.Put(ctx, key, []byte(key), time.Hour)
+	}
+
+	var keys []string
+	err := store.ScanPrefix(ctx, "haiku:", func(key string, value []byte) error {
+		keys = append(keys, key)
+		// The store can be used while it is scanned.
+		return store.Delete(ctx, key)
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !reflect.DeepEqual(keys, []string{"haiku:a", "haiku:b"}) {
+		t.Errorf("Expected haiku keys in order, got %v", keys)
+	}
+
+	stop := errors.New("stop")
+	if err := store.ScanPrefix(ctx, "", func(key string, value []byte) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("Expected the scan to stop with fn's error, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+// Package metering counts the model tokens a request consumes, so they can be
+// charged to the caller. Tokens are added to a Meter carried by the request
+// context by whichever client calls the model.
+package metering
+
+import (
+	"context"
+	"sync"
+)
+
+type contextKey struct{}
+
+// Meter totals the tokens consumed by one request. It is safe for concurrent
+// use, since some model calls run in parallel.
+type Meter struct {
+	mu           sync.Mutex
+	inputTokens  int
+	outputTokens int
+}
+
+// NewContext returns a context carrying a new Meter.
+func NewContext(ctx context.Context) (context.Context, *Meter) {
+	meter := &Meter{}
+	return context.WithValue(ctx, contextKey{}, meter), meter
+}
+
+// FromContext returns the context's Meter, or nil when it has none.
+func FromContext(ctx context.Context) *Meter {
+	meter, _ := ctx.Value(contextKey{}).(*Meter)
+	return meter
+}
+
+// Add records the tokens of one model call. Without a Meter in the context it
+// does nothing, so callers need not check.
+func Add(ctx context.Context, inputTokens int, outputTokens int) {
+	meter := FromContext(ctx)
+	if meter == nil {
+		return
+	}
+
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	meter.inputTokens += inputTokens
+	meter.outputTokens += outputTokens
+}
+
+// InputTokens returns the prompt tokens recorded so far.
+func (m *Meter) InputTokens() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inputTokens
+}
+
+// OutputTokens returns the generated tokens recorded so far.
+func (m *Meter) OutputTokens() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.outputTokens
+}
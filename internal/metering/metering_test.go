@@ -0,0 +1,35 @@
+package metering
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+func TestAdd(t *testing.T) {
+	ctx, meter := NewContext(context.Background())
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Go(func() {
+			Add(ctx, 100, 20)
+		})
+	}
+	wg.Wait()
+
+	if meter.InputTokens() != 1000 || meter.OutputTokens() != 200 {
+		t.Errorf("Expected 1000 input and 200 output tokens, got %d and %d", meter.InputTokens(), meter.OutputTokens())
+	}
+	if FromContext(ctx) != meter {
+		t.Error("Expected the context to carry the meter")
+	}
+}
+
+func TestAddWithoutMeter(t *testing.T) {
+	// Nothing to record to, and nothing to fail.
+	Add(context.Background(), 100, 20)
+
+	if FromContext(context.Background()) != nil {
+		t.Error("Expected no meter")
+	}
+}
@@ -0,0 +1,100 @@
+// Package metrics counts failed requests by error class as CloudWatch custom
+// metrics. Each count is written as a CloudWatch Embedded Metric Format log
+// line, which CloudWatch turns into a metric without any API call, so
+// counting never slows a request down or fails it.
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var ErrEmit = errors.New("error metric emission failed")
+
+const (
+	// Namespace is the CloudWatch namespace the metrics are published under.
+	Namespace = "CommitsFallLikeLeaves"
+	// ErrorsMetric counts failed requests, by ErrorClassDimension alone and
+	// by ErrorClassDimension and RouteDimension.
+	ErrorsMetric = "Errors"
+
+	ErrorClassDimension = "ErrorClass"
+	RouteDimension      = "Route"
+)
+
+// Class is the kind of failure a request ended with, coarse enough to alarm
+// on each.
+type Class string
+
+const (
+	ClassThrottling  Class = "throttling"  // Bedrock throttled the model invocation
+	ClassQuota       Class = "quota"       // A Bedrock service quota or the caller's API key quota was used up
+	ClassValidation  Class = "validation"  // The request was refused as invalid, by the API or by Bedrock
+	ClassParse       Class = "parse"       // The model's response couldn't be read
+	ClassGuardrail   Class = "guardrail"   // Moderation or a guardrail blocked the content
+	ClassStructure   Class = "structure"   // The model kept writing haiku that failed structure validation
+	ClassUnavailable Class = "unavailable" // The model was unavailable
+	ClassInternal    Class = "internal"    // Any other server error, including panics
+)
+
+// Emitter writes each count as a single log line. Lines are written whole, so
+// concurrent requests can share an Emitter.
+type Emitter struct {
+	mu  sync.Mutex
+	w   io.Writer
+	now func() time.Time
+}
+
+func NewEmitter(w io.Writer) *Emitter {
+	return &Emitter{w: w, now: time.Now}
+}
+
+type directive struct {
+	Namespace  string     `json:"Namespace"`
+	Dimensions [][]string `json:"Dimensions"`
+	Metrics    []metric   `json:"Metrics"`
+}
+
+type metric struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// CountError counts one failed request of the given class on route, the
+// matched route pattern, e.g. "/haiku". Requests matching no route are
+// counted by class only.
+func (e *Emitter) CountError(class Class, route string) error {
+	dimensions := [][]string{{ErrorClassDimension}}
+	line := map[string]any{
+		ErrorClassDimension: class,
+		ErrorsMetric:        1,
+	}
+	if route != "" {
+		dimensions = append(dimensions, []string{ErrorClassDimension, RouteDimension})
+		line[RouteDimension] = route
+	}
+	line["_aws"] = map[string]any{
+		"Timestamp": e.now().UnixMilli(),
+		"CloudWatchMetrics": []directive{{
+			Namespace:  Namespace,
+			Dimensions: dimensions,
+			Metrics:    []metric{{Name: ErrorsMetric, Unit: "Count"}},
+		}},
+	}
+
+	encoded, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("%w: encoding metric: %v", ErrEmit, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.w.Write(append(encoded, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrEmit, err)
+	}
+	return nil
+}
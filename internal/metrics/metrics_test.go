@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCountError(t *testing.T) {
+	tests := []struct {
+		name               string
+		route              string
+		expectedDimensions []any
+	}{
+		{
+			name:               "By class and route",
+			route:              "/haiku",
+			expectedDimensions: []any{[]any{"ErrorClass"}, []any{"ErrorClass", "Route"}},
+		},
+		{
+			name:               "Unmatched route by class only",
+			expectedDimensions: []any{[]any{"ErrorClass"}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			emitter := NewEmitter(&out)
+			now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+			emitter.now = func() time.Time { return now }
+
+			if err := emitter.CountError(ClassThrottling, tc.route); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if strings.Count(out.String(), "\n") != 1 {
+				t.Fatalf("Expected a single log line, got %q", out.String())
+			}
+
+			var logged map[string]any
+			if err := json.Unmarshal(out.Bytes(), &logged); err != nil {
+				t.Fatalf("Failed to unmarshal log line: %v", err)
+			}
+			if logged["ErrorClass"] != "throttling" || logged["Errors"] != float64(1) {
+				t.Errorf("Expected one throttling error, got %v", logged)
+			}
+			if route, _ := logged["Route"].(string); route != tc.route {
+				t.Errorf("Expected route %q, got %q", tc.route, route)
+			}
+
+			aws := logged["_aws"].(map[string]any)
+			if aws["Timestamp"] != float64(now.UnixMilli()) {
+				t.Errorf("Expected timestamp %d, got %v", now.UnixMilli(), aws["Timestamp"])
+			}
+			directive := aws["CloudWatchMetrics"].([]any)[0].(map[string]any)
+			if directive["Namespace"] != Namespace {
+				t.Errorf("Expected namespace %s, got %v", Namespace, directive["Namespace"])
+			}
+			if !reflect.DeepEqual(directive["Dimensions"], tc.expectedDimensions) {
+				t.Errorf("Expected dimensions %v, got %v", tc.expectedDimensions, directive["Dimensions"])
+			}
+			expectedMetrics := []any{map[string]any{"Name": "Errors", "Unit": "Count"}}
+			if !reflect.DeepEqual(directive["Metrics"], expectedMetrics) {
+				t.Errorf("Expected metrics %v, got %v", expectedMetrics, directive["Metrics"])
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+// Package moderation screens generated text before it is returned or posted
+// publicly.
+//
+// A Moderator reports whether text should be blocked. The word list moderator
+// runs locally and cheaply; the guardrail moderator delegates to a Bedrock
+// guardrail. Moderators can be combined with Chain.
+package moderation
+
+import (
+	"context"
+	"regexp"
+	"strings"
+)
+
+// Result is the outcome of moderating a piece of text.
+type Result struct {
+	Blocked bool
+	Reason  string // Why the text was blocked; empty when it was allowed
+}
+
+type Moderator interface {
+	Moderate(ctx context.Context, text string) (Result, error)
+}
+
+// DefaultBlockedWords is a deliberately short list of unambiguous profanity.
+// Deployments extend it through configuration.
+var DefaultBlockedWords = []string{
+	"fuck",
+	"fucking",
+	"shit",
+	"bitch",
+	"bastard",
+	"asshole",
+	"cunt",
+	"dickhead",
+	"motherfucker",
+}
+
+// WordListModerator blocks text containing any of a list of words, matched
+// case-insensitively on word boundaries.
+type WordListModerator struct {
+	pattern *regexp.Regexp
+}
+
+func NewWordListModerator(words []string) *WordListModerator {
+	quoted := make([]string, 0, len(words))
+	for _, word := range words {
+		word = strings.TrimSpace(word)
+		if word != "" {
+			quoted = append(quoted, regexp.QuoteMeta(word))
+		}
+	}
+
+	moderator := &WordListModerator{}
+	if len(quoted) > 0 {
+		moderator.pattern = regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+	}
+	return moderator
+}
+
+func (m *WordListModerator) Moderate(ctx context.Context, text string) (Result, error) {
+	if m.pattern == nil {
+		return Result{}, nil
+	}
+
+	if match := m.pattern.FindString(text); match != "" {
+		return Result{
+			Blocked: true,
+			Reason:  "blocked word list",
+		}, nil
+	}
+	return Result{}, nil
+}
+
+type GuardrailClient interface {
+	ApplyGuardrail(ctx context.Context, guardrailID string, guardrailVersion string, text string) (bool, error)
+}
+
+// GuardrailModerator blocks text that a Bedrock guardrail intervenes on.
+type GuardrailModerator struct {
+	client           GuardrailClient
+	guardrailID      string
+	guardrailVersion string
+}
+
+func NewGuardrailModerator(client GuardrailClient, guardrailID string, guardrailVersion string) *GuardrailModerator {
+	return &GuardrailModerator{
+		client:           client,
+		guardrailID:      guardrailID,
+		guardrailVersion: guardrailVersion,
+	}
+}
+
+func (m *GuardrailModerator) Moderate(ctx context.Context, text string) (Result, error) {
+	intervened, err := m.client.ApplyGuardrail(ctx, m.guardrailID, m.guardrailVersion, text)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if intervened {
+		return Result{
+			Blocked: true,
+			Reason:  "guardrail " + m.guardrailID,
+		}, nil
+	}
+	return Result{}, nil
+}
+
+type chain []Moderator
+
+// Chain runs moderators in order and returns the first blocking result.
+func Chain(moderators ...Moderator) Moderator {
+	return chain(moderators)
+}
+
+func (c chain) Moderate(ctx context.Context, text string) (Result, error) {
+	for _, moderator := range c {
+		result, err := moderator.Moderate(ctx, text)
+		if err != nil || result.Blocked {
+			return result, err
+		}
+	}
+	return Result{}, nil
+}
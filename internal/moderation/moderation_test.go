@@ -0,0 +1,113 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type MockGuardrailClient struct {
+	IntervenedToReturn bool
+	ErrorToReturn      error
+	Calls              int
+}
+
+func (m *MockGuardrailClient) ApplyGuardrail(ctx context.Context, guardrailID string, guardrailVersion string, text string) (bool, error) {
+	m.Calls++
+	return m.IntervenedToReturn, m.ErrorToReturn
+}
+
+func TestWordListModerator(t *testing.T) {
+	moderator := NewWordListModerator([]string{"darn", " heck ", ""})
+
+	tests := []struct {
+		name            string
+		text            string
+		expectedBlocked bool
+	}{
+		{name: "Clean text", text: "Old cracks mended now\nthe login door swings open\nquiet in the logs"},
+		{name: "Blocked word", text: "Darn the flaky test", expectedBlocked: true},
+		{name: "Trimmed word", text: "what the heck", expectedBlocked: true},
+		{name: "Word boundary", text: "checking the darnedest heckle", expectedBlocked: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := moderator.Moderate(context.Background(), tc.text)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if result.Blocked != tc.expectedBlocked {
+				t.Errorf("Expected blocked %t, got %t", tc.expectedBlocked, result.Blocked)
+			}
+		})
+	}
+}
+
+func TestEmptyWordListModerator(t *testing.T) {
+	result, err := NewWordListModerator(nil).Moderate(context.Background(), "anything")
+	if err != nil || result.Blocked {
+		t.Errorf("Expected empty word list to allow text, got %+v (%v)", result, err)
+	}
+}
+
+func TestChain(t *testing.T) {
+	guardrailErr := errors.New("guardrail unavailable")
+
+	tests := []struct {
+		name              string
+		text              string
+		guardrail         *MockGuardrailClient
+		expectedBlocked   bool
+		expectedErr       error
+		expectedGuardrail int
+	}{
+		{
+			name:              "Word list blocks before guardrail",
+			text:              "darn",
+			guardrail:         &MockGuardrailClient{},
+			expectedBlocked:   true,
+			expectedGuardrail: 0,
+		},
+		{
+			name:              "Guardrail blocks",
+			text:              "clean",
+			guardrail:         &MockGuardrailClient{IntervenedToReturn: true},
+			expectedBlocked:   true,
+			expectedGuardrail: 1,
+		},
+		{
+			name:              "Both allow",
+			text:              "clean",
+			guardrail:         &MockGuardrailClient{},
+			expectedGuardrail: 1,
+		},
+		{
+			name:              "Guardrail error",
+			text:              "clean",
+			guardrail:         &MockGuardrailClient{ErrorToReturn: guardrailErr},
+			expectedErr:       guardrailErr,
+			expectedGuardrail: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			moderator := Chain(
+				NewWordListModerator([]string{"darn"}),
+				NewGuardrailModerator(tc.guardrail, "gr-123", "1"),
+			)
+
+			result, err := moderator.Moderate(context.Background(), tc.text)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if result.Blocked != tc.expectedBlocked {
+				t.Errorf("Expected blocked %t, got %t", tc.expectedBlocked, result.Blocked)
+			}
+			if tc.guardrail.Calls != tc.expectedGuardrail {
+				t.Errorf("Expected %d guardrail calls, got %d", tc.expectedGuardrail, tc.guardrail.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+// Package openapi builds OpenAPI 3 documents whose schemas are derived from
+// Go types, so the published spec cannot drift from the types the API binds
+// and returns.
+//
+// Struct fields are described by their json tags: renamed and omitted fields
+// follow encoding/json, and fields without omitempty, or with a gin
+// binding:"required" tag, are required. Untagged embedded structs are
+// flattened into the embedding struct. Named string types listed with Enum
+// are published with their allowed values.
+package openapi
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Version is the OpenAPI version documents are written in.
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps lower-case HTTP methods to operations.
+type PathItem map[string]Operation
+
+type Operation struct {
+	Summary     string              `json:"summary"`
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Builder collects the schemas of the types used by a document's operations
+// as named components.
+type Builder struct {
+	doc   Document
+	enums map[reflect.Type][]string
+}
+
+func NewBuilder(info Info) *Builder {
+	return &Builder{
+		doc: Document{
+			OpenAPI:    Version,
+			Info:       info,
+			Paths:      make(map[string]PathItem),
+			Components: Components{Schemas: make(map[string]*Schema)},
+		},
+		enums: make(map[reflect.Type][]string),
+	}
+}
+
+// Enum records the allowed values of a named string type, e.g.
+// Enum(b, haiku.Moods...).
+func Enum[T ~string](b *Builder, values ...T) {
+	var zero T
+	names := make([]string, len(values))
+	for i, value := range values {
+		names[i] = string(value)
+	}
+	b.enums[reflect.TypeOf(zero)] = names
+}
+
+// Schema returns a reference to the component schema of value's type,
+// registering it and every named type it refers to.
+func (b *Builder) Schema(value any) *Schema {
+	return b.schema(reflect.TypeOf(value))
+}
+
+// JSON describes a JSON body holding the schema of value's type.
+func (b *Builder) JSON(value any) map[string]MediaType {
+	return b.Content("application/json", value)
+}
+
+// Content describes a body of the given media type holding the schema of
+// value's type.
+func (b *Builder) Content(mediaType string, value any) map[string]MediaType {
+	return map[string]MediaType{
+		mediaType: {Schema: b.Schema(value)},
+	}
+}
+
+// Operation adds an operation on a path.
+func (b *Builder) Operation(method string, path string, operation Operation) {
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(method)] = operation
+}
+
+// Document returns the document built so far.
+func (b *Builder) Document() Document {
+	return b.doc
+}
+
+func (b *Builder) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := b.enums[t]; ok {
+		return b.component(t, func() *Schema {
+			return &Schema{Type: "string", Enum: values}
+		})
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct:
+		return b.component(t, func() *Schema {
+			return b.object(t)
+		})
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: b.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: b.schema(t.Elem())}
+	}
+	return &Schema{}
+}
+
+// component registers a named type's schema once and returns a reference to
+// it. Anonymous types are inlined.
+func (b *Builder) component(t reflect.Type, build func() *Schema) *Schema {
+	if t.Name() == "" {
+		return build()
+	}
+
+	ref := &Schema{Ref: "#/components/schemas/" + t.Name()}
+	if _, ok := b.doc.Components.Schemas[t.Name()]; !ok {
+		// Register before building so recursive types terminate.
+		schema := &Schema{}
+		b.doc.Components.Schemas[t.Name()] = schema
+		*schema = *build()
+	}
+	return ref
+}
+
+func (b *Builder) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		// Untagged embedded structs are flattened, as encoding/json does.
+		if field.Anonymous && field.Type.Kind() == reflect.Struct && field.Tag.Get("json") == "" {
+			embedded := b.object(field.Type)
+			maps.Copy(schema.Properties, embedded.Properties)
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		name, omitEmpty, ok := jsonName(field)
+		if !ok {
+			continue
+		}
+
+		schema.Properties[name] = b.schema(field.Type)
+		if !omitEmpty || slices.Contains(strings.Split(field.Tag.Get("binding"), ","), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+
+	return schema
+}
+
+// jsonName returns the name encoding/json gives a struct field and whether it
+// is omitted when empty. Unexported fields and fields tagged "-" are not ok.
+func jsonName(field reflect.StructField) (string, bool, bool) {
+	if !field.IsExported() {
+		return "", false, false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, false
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, slices.Contains(strings.Split(options, ","), "omitempty"), true
+}
@@ -0,0 +1,80 @@
+package openapi
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type color string
+
+type node struct {
+	Name     string            `json:"name" binding:"required"`
+	Color    color             `json:"color,omitempty"`
+	Count    int               `json:"count,omitempty"`
+	Created  time.Time         `json:"created"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Children []*node           `json:"children,omitempty"`
+	Skipped  string            `json:"-"`
+	hidden   string
+}
+
+func TestBuilderSchema(t *testing.T) {
+	b := NewBuilder(Info{Title: "Test", Version: "1"})
+	Enum(b, color("red"), color("green"))
+
+	ref := b.Schema(node{})
+	if ref.Ref != "#/components/schemas/node" {
+		t.Fatalf("Expected a reference to the node schema, got %+v", ref)
+	}
+
+	schemas := b.Document().Components.Schemas
+	expected := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"name":     {Type: "string"},
+			"color":    {Ref: "#/components/schemas/color"},
+			"count":    {Type: "integer"},
+			"created":  {Type: "string", Format: "date-time"},
+			"labels":   {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"children": {Type: "array", Items: &Schema{Ref: "#/components/schemas/node"}},
+		},
+		Required: []string{"name", "created"},
+	}
+	if !reflect.DeepEqual(schemas["node"], expected) {
+		t.Errorf("Expected node schema %+v, got %+v", expected, schemas["node"])
+	}
+
+	if enum := schemas["color"]; enum == nil || !reflect.DeepEqual(enum.Enum, []string{"red", "green"}) {
+		t.Errorf("Expected the color schema to list its values, got %+v", enum)
+	}
+}
+
+type labeledNode struct {
+	node
+	Label string `json:"label"`
+}
+
+func TestBuilderSchemaFlattensEmbeddedStructs(t *testing.T) {
+	b := NewBuilder(Info{Title: "Test", Version: "1"})
+	b.Schema(labeledNode{})
+
+	schema := b.Document().Components.Schemas["labeledNode"]
+	if schema == nil || schema.Properties["name"] == nil || schema.Properties["label"] == nil || schema.Properties["node"] != nil {
+		t.Fatalf("Expected the node fields alongside label, got %+v", schema)
+	}
+	if !reflect.DeepEqual(schema.Required, []string{"name", "created", "label"}) {
+		t.Errorf("Expected the embedded required fields, got %v", schema.Required)
+	}
+}
+
+func TestBuilderOperation(t *testing.T) {
+	b := NewBuilder(Info{Title: "Test", Version: "1"})
+	b.Operation("POST", "/nodes", Operation{OperationID: "createNode"})
+	b.Operation("GET", "/nodes", Operation{OperationID: "listNodes"})
+
+	item := b.Document().Paths["/nodes"]
+	if item["post"].OperationID != "createNode" || item["get"].OperationID != "listNodes" {
+		t.Errorf("Expected both operations on /nodes, got %+v", item)
+	}
+}
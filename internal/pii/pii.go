@@ -0,0 +1,191 @@
+// Package pii removes personal data from text before it is sent to a model.
+//
+// A Detector finds personal data in text. The pattern detector runs locally
+// and cheaply, matching email addresses, well-known token formats and the
+// names in commit trailers; the Comprehend detector delegates to Amazon
+// Comprehend, which also finds names in prose. A Redactor replaces what its
+// detectors find with placeholders such as [EMAIL].
+package pii
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/comprehend"
+)
+
+// Kinds of personal data, as named in placeholders and responses.
+const (
+	KindEmail = "email"
+	KindToken = "token"
+	KindName  = "name"
+)
+
+// Entity is personal data found in text, from byte Start up to End.
+type Entity struct {
+	Kind  string
+	Start int
+	End   int
+}
+
+type Detector interface {
+	Detect(ctx context.Context, text string) ([]Entity, error)
+}
+
+type pattern struct {
+	kind   string
+	regexp *regexp.Regexp // Its "value" group, when it has one, is what's redacted
+}
+
+// patterns is the regex pack. Tokens are matched by their well-known
+// prefixes, or as the value assigned to a secret-sounding name, so that
+// commit SHAs and other IDs are left alone.
+var patterns = []pattern{
+	{KindEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{KindToken, regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)},
+	{KindToken, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{KindToken, regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})\b`)},
+	{KindToken, regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{KindToken, regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{KindToken, regexp.MustCompile(`\b[sr]k_(?:live|test)_[A-Za-z0-9]{16,}\b`)},
+	{KindToken, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	{KindToken, regexp.MustCompile(`(?i)\bbearer\s+(?P<value>[A-Za-z0-9._~+/-]{16,}=*)`)},
+	{KindToken, regexp.MustCompile(`(?i)\b(?:password|passwd|secret|token|api[_-]?key)\b["']?\s*[:=]\s*["']?(?P<value>[^\s"']{6,})`)},
+	{KindName, regexp.MustCompile(`(?im)^[ \t]*(?:signed-off-by|co-authored-by|reviewed-by|acked-by|reported-by|tested-by|suggested-by|helped-by)[ \t]*:[ \t]*(?P<value>[^<\n]*[^<\s])`)},
+}
+
+// PatternDetector finds personal data with the regex pack.
+type PatternDetector struct{}
+
+func NewPatternDetector() *PatternDetector {
+	return &PatternDetector{}
+}
+
+func (d *PatternDetector) Detect(ctx context.Context, text string) ([]Entity, error) {
+	var entities []Entity
+	for _, p := range patterns {
+		group := max(p.regexp.SubexpIndex("value"), 0)
+		for _, match := range p.regexp.FindAllStringSubmatchIndex(text, -1) {
+			if start, end := match[2*group], match[2*group+1]; start >= 0 && end > start {
+				entities = append(entities, Entity{Kind: p.kind, Start: start, End: end})
+			}
+		}
+	}
+	return entities, nil
+}
+
+// MinComprehendScore is the least confidence Comprehend needs for its
+// finding to be redacted.
+const MinComprehendScore = 0.5
+
+type ComprehendClient interface {
+	DetectPiiEntities(ctx context.Context, text string) ([]comprehend.Entity, error)
+}
+
+// ComprehendDetector finds personal data with Amazon Comprehend. Emails and
+// names keep their kinds; credentials such as passwords and access keys are
+// tokens, and anything else, e.g. a phone number, is named for its
+// Comprehend type.
+type ComprehendDetector struct {
+	client ComprehendClient
+}
+
+func NewComprehendDetector(client ComprehendClient) *ComprehendDetector {
+	return &ComprehendDetector{client: client}
+}
+
+func (d *ComprehendDetector) Detect(ctx context.Context, text string) ([]Entity, error) {
+	found, err := d.client.DetectPiiEntities(ctx, text)
+	if err != nil {
+		return nil, err
+	}
+
+	// Comprehend counts characters, so map its offsets to bytes.
+	offsets := make([]int, 0, len(text)+1)
+	for i := range text {
+		offsets = append(offsets, i)
+	}
+	offsets = append(offsets, len(text))
+
+	var entities []Entity
+	for _, entity := range found {
+		if entity.Score < MinComprehendScore || entity.BeginOffset < 0 || entity.EndOffset <= entity.BeginOffset || entity.EndOffset >= len(offsets) {
+			continue
+		}
+		entities = append(entities, Entity{
+			Kind:  comprehendKind(entity.Type),
+			Start: offsets[entity.BeginOffset],
+			End:   offsets[entity.EndOffset],
+		})
+	}
+	return entities, nil
+}
+
+func comprehendKind(entityType string) string {
+	switch entityType {
+	case "EMAIL":
+		return KindEmail
+	case "NAME":
+		return KindName
+	case "PASSWORD", "PIN", "AWS_ACCESS_KEY", "AWS_SECRET_KEY", "USERNAME":
+		return KindToken
+	}
+	return strings.ToLower(entityType)
+}
+
+// Redactor replaces the personal data its detectors find.
+type Redactor struct {
+	detectors []Detector
+}
+
+func NewRedactor(detectors ...Detector) *Redactor {
+	return &Redactor{detectors: detectors}
+}
+
+// Redact returns text with each piece of personal data replaced by a
+// placeholder naming its kind, e.g. [EMAIL], and the kinds it replaced, in
+// order. Text is never returned partly redacted: if a detector fails, so
+// does Redact.
+func (r *Redactor) Redact(ctx context.Context, text string) (string, []string, error) {
+	var entities []Entity
+	for _, detector := range r.detectors {
+		found, err := detector.Detect(ctx, text)
+		if err != nil {
+			return "", nil, err
+		}
+		entities = append(entities, found...)
+	}
+	if len(entities) == 0 {
+		return text, nil, nil
+	}
+
+	// Overlapping entities are redacted as one, under the kind of the
+	// earliest, longest of them.
+	slices.SortFunc(entities, func(a, b Entity) int {
+		if a.Start != b.Start {
+			return a.Start - b.Start
+		}
+		return b.End - a.End
+	})
+
+	var redacted strings.Builder
+	var kinds []string
+	last := 0
+	for i := 0; i < len(entities); {
+		first, end := entities[i], entities[i].End
+		for i++; i < len(entities) && entities[i].Start < end; i++ {
+			end = max(end, entities[i].End)
+		}
+
+		redacted.WriteString(text[last:first.Start])
+		redacted.WriteString("[" + strings.ToUpper(first.Kind) + "]")
+		if !slices.Contains(kinds, first.Kind) {
+			kinds = append(kinds, first.Kind)
+		}
+		last = end
+	}
+	redacted.WriteString(text[last:])
+	return redacted.String(), kinds, nil
+}
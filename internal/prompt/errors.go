@@ -0,0 +1,10 @@
+package prompt
+
+import "errors"
+
+var (
+	ErrInvalidTemplate   = errors.New("invalid prompt template")
+	ErrRender            = errors.New("failed to render prompt")
+	ErrSourceLoad        = errors.New("failed to load prompt templates")
+	ErrInvalidExperiment = errors.New("invalid prompt experiment")
+)
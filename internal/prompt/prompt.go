@@ -0,0 +1,115 @@
+// Package prompt manages the prompt templates used to generate haiku.
+//
+// Templates are loaded from an external Source (SSM Parameter Store in
+// production) so that prompts can be tuned without a deploy. A Store keeps the
+// most recently loaded templates in memory, refreshes them once they are older
+// than the configured interval, and falls back to compiled-in defaults when the
+// source is unavailable.
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultVersion labels prompt sets that do not declare a version.
+const DefaultVersion = "default"
+
+// Definitions holds the raw, unparsed template text for a prompt set.
+type Definitions struct {
+	Version string            // Label recorded against every haiku generated with this set
+	System  string            // System prompt sent with every request
+	Prompt  string            // Default user prompt framing
+	Moods   map[string]string // Per-mood user prompt framing, keyed by mood
+}
+
+// Set is a parsed, ready to render prompt set.
+type Set struct {
+	Version string
+	System  string
+
+	prompt *template.Template
+	moods  map[string]*template.Template
+}
+
+// PromptData is made available to user prompt templates.
+type PromptData struct {
+	Mood          string
+	Register      string // Empty when no register was requested
+	CommitMessage string
+	Repository    *Repository // Nil when the request described no repository
+	Issues        []Issue     // Issues the commit references whose titles could be looked up
+}
+
+// Issue is an issue a commit references.
+type Issue struct {
+	Reference string // As written in the commit message, e.g. "JIRA-4521" or "#456"
+	Title     string
+}
+
+// Repository describes the repository a commit belongs to, so that the
+// imagery can reflect its domain.
+type Repository struct {
+	Name        string
+	Language    string // Empty when not given
+	Description string // Empty when not given
+}
+
+// Parse compiles definitions into a Set. The default prompt framing is required;
+// the system prompt and mood framings are optional.
+func Parse(defs Definitions) (*Set, error) {
+	if strings.TrimSpace(defs.Prompt) == "" {
+		return nil, fmt.Errorf("%w: prompt template cannot be empty", ErrInvalidTemplate)
+	}
+
+	prompt, err := template.New("prompt").Option("missingkey=error").Parse(defs.Prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: prompt: %v", ErrInvalidTemplate, err)
+	}
+
+	moods := make(map[string]*template.Template, len(defs.Moods))
+	for mood, text := range defs.Moods {
+		tmpl, err := template.New(mood).Option("missingkey=error").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: mood %s: %v", ErrInvalidTemplate, mood, err)
+		}
+		moods[mood] = tmpl
+	}
+
+	version := defs.Version
+	if version == "" {
+		version = DefaultVersion
+	}
+
+	return &Set{
+		Version: version,
+		System:  defs.System,
+		prompt:  prompt,
+		moods:   moods,
+	}, nil
+}
+
+// MustParse is like Parse but panics on error. It is intended for compiled-in defaults.
+func MustParse(defs Definitions) *Set {
+	set, err := Parse(defs)
+	if err != nil {
+		panic(err)
+	}
+	return set
+}
+
+// Render renders the user prompt for data.Mood, using the mood specific framing
+// when one is defined and the default framing otherwise.
+func (s *Set) Render(data PromptData) (string, error) {
+	tmpl, ok := s.moods[data.Mood]
+	if !ok {
+		tmpl = s.prompt
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrRender, err)
+	}
+	return b.String(), nil
+}
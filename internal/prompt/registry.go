@@ -0,0 +1,107 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+)
+
+// Allocation assigns a percentage of traffic to a prompt version.
+type Allocation struct {
+	Version string
+	Weight  int // Percentage of requests, 1-100
+}
+
+// Variant is a prompt version taking part in an experiment.
+type Variant struct {
+	Allocation
+	Prompts *Store
+}
+
+// Registry assigns each request to one of several prompt versions according to
+// their weights, so that a new prompt can be measured against the current one
+// before it is rolled out.
+type Registry struct {
+	variants []Variant
+}
+
+func NewRegistry(variants ...Variant) (*Registry, error) {
+	if len(variants) == 0 {
+		return nil, fmt.Errorf("%w: registry requires at least one variant", ErrInvalidExperiment)
+	}
+
+	total := 0
+	for _, variant := range variants {
+		if variant.Weight <= 0 {
+			return nil, fmt.Errorf("%w: version %s has non-positive weight %d", ErrInvalidExperiment, variant.Version, variant.Weight)
+		}
+		if variant.Prompts == nil {
+			return nil, fmt.Errorf("%w: version %s has no prompts", ErrInvalidExperiment, variant.Version)
+		}
+		total += variant.Weight
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("%w: weights sum to %d, expected 100", ErrInvalidExperiment, total)
+	}
+
+	return &Registry{
+		variants: variants,
+	}, nil
+}
+
+// Select returns the prompt set assigned to key. The same key is always
+// assigned the same version; an empty key is assigned at random.
+func (r *Registry) Select(ctx context.Context, key string) *Set {
+	bucket := rand.IntN(100) // #nosec G404 -- experiment assignment is not security sensitive
+	if key != "" {
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		bucket = int(hash.Sum32() % 100)
+	}
+
+	for _, variant := range r.variants {
+		if bucket < variant.Weight {
+			return variant.Prompts.Get(ctx)
+		}
+		bucket -= variant.Weight
+	}
+
+	// Unreachable while weights sum to 100.
+	return r.variants[len(r.variants)-1].Prompts.Get(ctx)
+}
+
+// ParseAllocations parses an experiment definition such as "v1:90,v2:10".
+func ParseAllocations(definition string) ([]Allocation, error) {
+	var allocations []Allocation
+
+	for _, part := range strings.Split(definition, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		version, weight, ok := strings.Cut(part, ":")
+		if !ok || strings.TrimSpace(version) == "" {
+			return nil, fmt.Errorf("%w: expected version:weight, got %q", ErrInvalidExperiment, part)
+		}
+
+		parsed, err := strconv.Atoi(strings.TrimSpace(weight))
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid weight in %q", ErrInvalidExperiment, part)
+		}
+
+		allocations = append(allocations, Allocation{
+			Version: strings.TrimSpace(version),
+			Weight:  parsed,
+		})
+	}
+
+	if len(allocations) == 0 {
+		return nil, fmt.Errorf("%w: no versions defined", ErrInvalidExperiment)
+	}
+
+	return allocations, nil
+}
@@ -0,0 +1,117 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func staticVersion(version string) *Store {
+	return NewStaticStore(Definitions{
+		Version: version,
+		Prompt:  "{{.CommitMessage}}",
+	})
+}
+
+func TestNewRegistryValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []Variant
+	}{
+		{name: "No variants"},
+		{
+			name: "Weights below 100",
+			variants: []Variant{
+				{Allocation: Allocation{Version: "v1", Weight: 50}, Prompts: staticVersion("v1")},
+			},
+		},
+		{
+			name: "Non-positive weight",
+			variants: []Variant{
+				{Allocation: Allocation{Version: "v1", Weight: 100}, Prompts: staticVersion("v1")},
+				{Allocation: Allocation{Version: "v2", Weight: 0}, Prompts: staticVersion("v2")},
+			},
+		},
+		{
+			name: "Missing prompts",
+			variants: []Variant{
+				{Allocation: Allocation{Version: "v1", Weight: 100}},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := NewRegistry(tc.variants...); !errors.Is(err, ErrInvalidExperiment) {
+				t.Errorf("Expected ErrInvalidExperiment, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRegistrySelect(t *testing.T) {
+	registry, err := NewRegistry(
+		Variant{Allocation: Allocation{Version: "v1", Weight: 80}, Prompts: staticVersion("v1")},
+		Variant{Allocation: Allocation{Version: "v2", Weight: 20}, Prompts: staticVersion("v2")},
+	)
+	if err != nil {
+		t.Fatalf("Failed to create registry: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// Assignment is sticky per key.
+	first := registry.Select(ctx, "fix: resolved login issue").Version
+	for i := 0; i < 10; i++ {
+		if got := registry.Select(ctx, "fix: resolved login issue").Version; got != first {
+			t.Fatalf("Expected sticky assignment %q, got %q", first, got)
+		}
+	}
+
+	// Traffic splits roughly according to weights.
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[registry.Select(ctx, fmt.Sprintf("commit %d", i)).Version]++
+	}
+	if counts["v2"] < 1500 || counts["v2"] > 2500 {
+		t.Errorf("Expected roughly 20%% of traffic on v2, got %d of 10000", counts["v2"])
+	}
+}
+
+func TestParseAllocations(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		expected   []Allocation
+		expectErr  bool
+	}{
+		{
+			name:       "Two versions",
+			definition: "v1:90, v2:10",
+			expected:   []Allocation{{Version: "v1", Weight: 90}, {Version: "v2", Weight: 10}},
+		},
+		{name: "Empty", definition: "", expectErr: true},
+		{name: "Missing weight", definition: "v1", expectErr: true},
+		{name: "Invalid weight", definition: "v1:ninety", expectErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			allocations, err := ParseAllocations(tc.definition)
+			if tc.expectErr {
+				if !errors.Is(err, ErrInvalidExperiment) {
+					t.Errorf("Expected ErrInvalidExperiment, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(allocations, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, allocations)
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package prompt
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ssm"
+)
+
+// Parameter names, relative to the source path.
+const (
+	VersionParameter    = "version"
+	SystemParameter     = "system"
+	PromptParameter     = "prompt"
+	MoodParameterPrefix = "moods/"
+)
+
+type SSMAPI interface {
+	GetParametersByPath(ctx context.Context, params *ssm.GetParametersByPathInput, optFns ...func(*ssm.Options)) (*ssm.GetParametersByPathOutput, error)
+}
+
+// SSMSource loads prompt definitions from SSM Parameter Store. Given the path
+// "/haiku/prompts" it reads "/haiku/prompts/version", "/haiku/prompts/system",
+// "/haiku/prompts/prompt" and "/haiku/prompts/moods/<mood>".
+type SSMSource struct {
+	client SSMAPI
+	path   string
+}
+
+func NewSSMSource(client SSMAPI, path string) *SSMSource {
+	return &SSMSource{
+		client: client,
+		path:   strings.TrimSuffix(path, "/"),
+	}
+}
+
+func NewDefaultSSMSource(cfg aws.Config, path string) *SSMSource {
+	return NewSSMSource(ssm.NewFromConfig(cfg), path)
+}
+
+func (s *SSMSource) Load(ctx context.Context) (Definitions, error) {
+	defs := Definitions{
+		Moods: map[string]string{},
+	}
+
+	paginator := ssm.NewGetParametersByPathPaginator(s.client, &ssm.GetParametersByPathInput{
+		Path:           aws.String(s.path),
+		Recursive:      aws.Bool(true),
+		WithDecryption: aws.Bool(true),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return Definitions{}, err
+		}
+
+		for _, parameter := range page.Parameters {
+			name := strings.TrimPrefix(aws.ToString(parameter.Name), s.path+"/")
+			value := aws.ToString(parameter.Value)
+
+			switch {
+			case name == VersionParameter:
+				defs.Version = value
+			case name == SystemParameter:
+				defs.System = value
+			case name == PromptParameter:
+				defs.Prompt = value
+			case strings.HasPrefix(name, MoodParameterPrefix):
+				defs.Moods[strings.TrimPrefix(name, MoodParameterPrefix)] = value
+			}
+		}
+	}
+
+	return defs, nil
+}
@@ -0,0 +1,139 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// Source loads prompt definitions from an external location. Fields left empty
+// by a source fall back to the store's compiled-in definitions.
+type Source interface {
+	Load(ctx context.Context) (Definitions, error)
+}
+
+type Store struct {
+	source   Source
+	fallback Definitions
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.RWMutex
+	current   *Set
+	checkedAt time.Time
+
+	refreshing sync.Mutex
+}
+
+// NewStore creates a store that serves the fallback definitions until the first
+// successful load from source, and then reloads whenever the loaded templates
+// are older than interval. A zero interval disables periodic refresh.
+func NewStore(source Source, fallback Definitions, interval time.Duration) *Store {
+	return &Store{
+		source:   source,
+		fallback: fallback,
+		interval: interval,
+		now:      time.Now,
+		current:  MustParse(fallback),
+	}
+}
+
+// NewStaticStore creates a store that always serves defs.
+func NewStaticStore(defs Definitions) *Store {
+	return NewStore(nil, defs, 0)
+}
+
+// Refresh loads the latest definitions from the source. On error the previously
+// loaded templates remain in use.
+func (s *Store) Refresh(ctx context.Context) error {
+	if s.source == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.checkedAt = s.now()
+	s.mu.Unlock()
+
+	loaded, err := s.source.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSourceLoad, err)
+	}
+
+	set, err := Parse(merge(s.fallback, loaded))
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.current = set
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Get returns the current prompt set, refreshing it first when it is stale.
+// Only one caller refreshes at a time; concurrent callers are served the
+// current templates rather than waiting.
+func (s *Store) Get(ctx context.Context) *Set {
+	if s.stale() && s.refreshing.TryLock() {
+		if s.stale() {
+			if err := s.Refresh(ctx); err != nil {
+				logging.Errorf("[PROMPT STORE] error refreshing prompt templates: %v\n", err)
+			}
+		}
+		s.refreshing.Unlock()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current
+}
+
+// Select returns the current prompt set. A store holds a single version, so the
+// assignment key is ignored.
+func (s *Store) Select(ctx context.Context, key string) *Set {
+	return s.Get(ctx)
+}
+
+func (s *Store) stale() bool {
+	if s.source == nil || s.interval <= 0 {
+		return false
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.now().Sub(s.checkedAt) >= s.interval
+}
+
+func merge(base, override Definitions) Definitions {
+	merged := Definitions{
+		Version: base.Version,
+		System:  base.System,
+		Prompt:  base.Prompt,
+		Moods:   maps.Clone(base.Moods),
+	}
+	if merged.Moods == nil {
+		merged.Moods = map[string]string{}
+	}
+
+	if override.Version != "" {
+		merged.Version = override.Version
+	}
+	if override.System != "" {
+		merged.System = override.System
+	}
+	if override.Prompt != "" {
+		merged.Prompt = override.Prompt
+	}
+	for mood, text := range override.Moods {
+		if text != "" {
+			merged.Moods[mood] = text
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,179 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type MockSource struct {
+	DefinitionsToReturn Definitions
+	ErrorToReturn       error
+	Calls               int
+}
+
+func (m *MockSource) Load(ctx context.Context) (Definitions, error) {
+	m.Calls++
+	return m.DefinitionsToReturn, m.ErrorToReturn
+}
+
+var testDefaults = Definitions{
+	System: "default system",
+	Prompt: "Create a {{.Mood}} haiku: {{.CommitMessage}}",
+}
+
+func TestSetRender(t *testing.T) {
+	set, err := Parse(Definitions{
+		Prompt: "Create a {{.Mood}} haiku: {{.CommitMessage}}",
+		Moods: map[string]string{
+			"humorous": "Make me laugh about: {{.CommitMessage}}",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to parse definitions: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		data     PromptData
+		expected string
+	}{
+		{
+			name:     "Default framing",
+			data:     PromptData{Mood: "reflective", CommitMessage: "fix: login"},
+			expected: "Create a reflective haiku: fix: login",
+		},
+		{
+			name:     "Mood framing",
+			data:     PromptData{Mood: "humorous", CommitMessage: "fix: login"},
+			expected: "Make me laugh about: fix: login",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rendered, err := set.Render(tc.data)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if rendered != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, rendered)
+			}
+		})
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		defs Definitions
+	}{
+		{name: "Empty prompt", defs: Definitions{}},
+		{name: "Malformed prompt", defs: Definitions{Prompt: "{{.Mood"}},
+		{name: "Malformed mood", defs: Definitions{Prompt: "ok", Moods: map[string]string{"technical": "{{end}}"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Parse(tc.defs); !errors.Is(err, ErrInvalidTemplate) {
+				t.Errorf("Expected ErrInvalidTemplate, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreRefresh(t *testing.T) {
+	source := &MockSource{
+		DefinitionsToReturn: Definitions{
+			System: "tuned system",
+			Moods:  map[string]string{"technical": "Be precise: {{.CommitMessage}}"},
+		},
+	}
+
+	store := NewStore(source, testDefaults, time.Minute)
+	if got := store.Get(context.Background()).System; got != "tuned system" {
+		t.Errorf("Expected loaded system prompt, got %q", got)
+	}
+
+	// Fields missing from the source fall back to the defaults.
+	rendered, err := store.Get(context.Background()).Render(PromptData{Mood: "reflective", CommitMessage: "docs"})
+	if err != nil || rendered != "Create a reflective haiku: docs" {
+		t.Errorf("Expected default framing, got %q (%v)", rendered, err)
+	}
+
+	if source.Calls != 1 {
+		t.Errorf("Expected 1 load within the refresh interval, got %d", source.Calls)
+	}
+}
+
+func TestStoreRefreshInterval(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &MockSource{DefinitionsToReturn: Definitions{System: "v1"}}
+
+	store := NewStore(source, testDefaults, time.Minute)
+	store.now = func() time.Time { return now }
+
+	store.Get(context.Background())
+	source.DefinitionsToReturn = Definitions{System: "v2"}
+
+	now = now.Add(30 * time.Second)
+	if got := store.Get(context.Background()).System; got != "v1" {
+		t.Errorf("Expected cached templates before the interval elapsed, got %q", got)
+	}
+
+	now = now.Add(time.Minute)
+	if got := store.Get(context.Background()).System; got != "v2" {
+		t.Errorf("Expected refreshed templates after the interval elapsed, got %q", got)
+	}
+}
+
+func TestStoreKeepsLastGoodTemplates(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	source := &MockSource{DefinitionsToReturn: Definitions{System: "v1"}}
+
+	store := NewStore(source, testDefaults, time.Minute)
+	store.now = func() time.Time { return now }
+	store.Get(context.Background())
+
+	tests := []struct {
+		name    string
+		defs    Definitions
+		err     error
+		errorIs error
+	}{
+		{
+			name:    "Source error",
+			err:     errors.New("ssm unavailable"),
+			errorIs: ErrSourceLoad,
+		},
+		{
+			name:    "Invalid template",
+			defs:    Definitions{Prompt: "{{.Mood"},
+			errorIs: ErrInvalidTemplate,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			source.DefinitionsToReturn = tc.defs
+			source.ErrorToReturn = tc.err
+
+			if err := store.Refresh(context.Background()); !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+			}
+
+			now = now.Add(2 * time.Minute)
+			if got := store.Get(context.Background()).System; got != "v1" {
+				t.Errorf("Expected last good templates, got %q", got)
+			}
+		})
+	}
+}
+
+func TestStaticStore(t *testing.T) {
+	store := NewStaticStore(testDefaults)
+	if got := store.Get(context.Background()).System; got != testDefaults.System {
+		t.Errorf("Expected default system prompt, got %q", got)
+	}
+}
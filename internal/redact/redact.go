@@ -0,0 +1,180 @@
+// Package redact keeps commit content out of logs. Commit messages can carry
+// secrets or customer names, so prompts built from them are logged hashed or
+// redacted, and a Writer installed as the log output hides the commit
+// messages being written about from every line, whichever package logs it.
+// IDs such as request, key and haiku IDs and commit SHAs are left alone, so
+// that log lines can still be correlated. Only messages and lines of at least
+// MinFragmentLength bytes, once trimmed, are hidden by the Writer: a shorter
+// commit message, such as "wip", appears in log lines as written unless it is
+// logged through Mode.Text.
+package redact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MinFragmentLength is the shortest commit message, or line of one, the
+// Writer hides. Shorter ones, such as "fix" or "wip", would hide common words
+// in unrelated log lines, and reveal little, so they are left as written.
+const MinFragmentLength = 12
+
+type Mode string
+
+const (
+	ModeHash   Mode = "hash"   // A short SHA-256 digest and the length, which still correlates repeated content
+	ModeRedact Mode = "redact" // Only the length
+	ModeFull   Mode = "full"   // In full, e.g. while debugging
+)
+
+func (m Mode) IsValid() bool {
+	switch m {
+	case ModeHash, ModeRedact, ModeFull:
+		return true
+	}
+	return false
+}
+
+// Text returns text as it may appear in logs. Modes other than full and
+// redact hash it.
+func (m Mode) Text(text string) string {
+	switch m {
+	case ModeFull:
+		return text
+	case ModeRedact:
+		return fmt.Sprintf("[redacted] (%d bytes)", len(text))
+	}
+	sum := sha256.Sum256([]byte(text))
+	return fmt.Sprintf("sha256:%s (%d bytes)", hex.EncodeToString(sum[:8]), len(text))
+}
+
+// Writer hides the text it is given from everything written through it,
+// for as long as the text is in use. It is safe for concurrent use, so that
+// it can be the log output of a server handling many requests.
+type Writer struct {
+	w    io.Writer
+	mode Mode
+
+	mu      sync.Mutex
+	hidden  map[string]*hidden // Fragments hidden, as written
+	ordered []string           // Hidden fragments, longest first, so that a line is hidden before the words within it
+}
+
+type hidden struct {
+	requests    int    // Requests using the fragment
+	replacement string // The fragment as mode logs it
+}
+
+// NewWriter writes to w, hiding text as mode logs it.
+func NewWriter(w io.Writer, mode Mode) *Writer {
+	return &Writer{w: w, mode: mode, hidden: make(map[string]*hidden)}
+}
+
+// SetOutput sets where the Writer writes to.
+func (w *Writer) SetOutput(out io.Writer) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.w = out
+}
+
+// Hide hides text, as a whole and line by line, until release is called.
+// Fragments are hidden both as they are and as escaped in a JSON log line.
+func (w *Writer) Hide(text string) (release func()) {
+	fragments := fragmentsOf(text)
+	if w.mode == ModeFull || len(fragments) == 0 {
+		return func() {}
+	}
+
+	var written []string
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, fragment := range fragments {
+		replacement := w.mode.Text(fragment)
+		for _, form := range []string{fragment, escapeJSON(fragment)} {
+			if slices.Contains(written, form) {
+				continue
+			}
+			written = append(written, form)
+			if w.hidden[form] == nil {
+				w.hidden[form] = &hidden{replacement: replacement}
+			}
+			w.hidden[form].requests++
+		}
+	}
+	w.reorder()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			w.mu.Lock()
+			defer w.mu.Unlock()
+			for _, form := range written {
+				if w.hidden[form].requests--; w.hidden[form].requests <= 0 {
+					delete(w.hidden, form)
+				}
+			}
+			w.reorder()
+		})
+	}
+}
+
+func (w *Writer) reorder() {
+	w.ordered = w.ordered[:0]
+	for fragment := range w.hidden {
+		w.ordered = append(w.ordered, fragment)
+	}
+	slices.SortFunc(w.ordered, func(a, b string) int { return len(b) - len(a) })
+}
+
+// Write writes p with every hidden fragment replaced. The log package and
+// slog handlers write each line in a single call, so fragments are never
+// split across writes.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	out, line := w.w, p
+	for _, fragment := range w.ordered {
+		if bytes.Contains(line, []byte(fragment)) {
+			line = bytes.ReplaceAll(line, []byte(fragment), []byte(w.hidden[fragment].replacement))
+		}
+	}
+	w.mu.Unlock()
+
+	if _, err := out.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// fragmentsOf returns text and its lines long enough to hide on their own.
+func fragmentsOf(text string) []string {
+	var fragments []string
+	if trimmed := strings.TrimSpace(text); len(trimmed) >= MinFragmentLength {
+		fragments = append(fragments, trimmed)
+	}
+	for line := range strings.Lines(text) {
+		line = strings.TrimSpace(line)
+		if len(line) >= MinFragmentLength && !slices.Contains(fragments, line) {
+			fragments = append(fragments, line)
+		}
+	}
+	return fragments
+}
+
+// escapeJSON returns text as escaped within a JSON string, without HTML
+// escaping, as slog's JSON handler writes it.
+func escapeJSON(text string) string {
+	var escaped bytes.Buffer
+	encoder := json.NewEncoder(&escaped)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(text); err != nil {
+		return text
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(escaped.String()), `"`), `"`)
+}
@@ -0,0 +1,161 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+)
+
+// Share cards use the 1200x630 size social networks expect for link previews.
+const (
+	cardWidth      = 1200
+	cardHeight     = 630
+	cardHaikuSize  = 44
+	cardDetailSize = 26
+	cardLineHeight = 70
+	cardMaxLine    = 1100
+)
+
+var (
+	cardBackgroundTop    = color.RGBA{R: 0xfd, G: 0xf2, B: 0xe9, A: 0xff}
+	cardBackgroundBottom = color.RGBA{R: 0xf6, G: 0xdd, B: 0xcc, A: 0xff}
+	cardTextColor        = color.RGBA{R: 0x4a, G: 0x2c, B: 0x1a, A: 0xff}
+	cardDetailColor      = color.RGBA{R: 0x8a, G: 0x5a, B: 0x3c, A: 0xff}
+)
+
+// Card is the content of a share card. The subject and repository are shown
+// beneath the haiku.
+type Card = extension.Card
+
+// PNG renders card as a PNG image with the same falling-leaves motif as SVG.
+func PNG(card Card) ([]byte, error) {
+	lines := splitLines(card.Haiku)
+	if len(lines) == 0 {
+		return nil, ErrEmptyHaiku
+	}
+
+	haikuFace, err := newFace(goitalic.TTF, cardHaikuSize)
+	if err != nil {
+		return nil, err
+	}
+	defer haikuFace.Close()
+
+	detailFace, err := newFace(goregular.TTF, cardDetailSize)
+	if err != nil {
+		return nil, err
+	}
+	defer detailFace.Close()
+
+	img := image.NewRGBA(image.Rect(0, 0, cardWidth, cardHeight))
+	drawGradient(img)
+	for _, l := range scatterLeaves(card.Haiku, cardWidth, cardHeight) {
+		drawLeaf(img, l)
+	}
+
+	top := (cardHeight - (len(lines)-1)*cardLineHeight) / 2
+	if card.Subject != "" || card.Repository != "" {
+		top -= cardLineHeight / 2
+	}
+	for i, line := range lines {
+		drawCentered(img, haikuFace, cardTextColor, line, top+i*cardLineHeight)
+	}
+
+	detail := card.Subject
+	if card.Repository != "" {
+		if detail != "" {
+			detail = card.Repository + " · " + detail
+		} else {
+			detail = card.Repository
+		}
+	}
+	if detail != "" {
+		drawCentered(img, detailFace, cardDetailColor, detail, top+len(lines)*cardLineHeight+cardLineHeight/2)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newFace(ttf []byte, size float64) (font.Face, error) {
+	parsed, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, errors.New("parsing font: " + err.Error())
+	}
+	return opentype.NewFace(parsed, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+}
+
+func drawGradient(img *image.RGBA) {
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		t := float64(y) / float64(bounds.Dy())
+		row := color.RGBA{
+			R: lerp(cardBackgroundTop.R, cardBackgroundBottom.R, t),
+			G: lerp(cardBackgroundTop.G, cardBackgroundBottom.G, t),
+			B: lerp(cardBackgroundTop.B, cardBackgroundBottom.B, t),
+			A: 0xff,
+		}
+		draw.Draw(img, image.Rect(bounds.Min.X, y, bounds.Max.X, y+1), image.NewUniform(row), image.Point{}, draw.Src)
+	}
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(float64(a) + (float64(b)-float64(a))*t) // #nosec G115 -- result stays between a and b
+}
+
+// drawLeaf rasterizes the same leaf outline used by the SVG template.
+func drawLeaf(img *image.RGBA, l leaf) {
+	scale := l.Scale * 2.5
+	angle := float64(l.Rotation) * math.Pi / 180
+	sin, cos := math.Sincos(angle)
+	point := func(x, y float64) (float32, float32) {
+		x, y = x*scale, y*scale
+		return float32(float64(l.X) + x*cos - y*sin), float32(float64(l.Y) + x*sin + y*cos)
+	}
+
+	r := vector.NewRasterizer(cardWidth, cardHeight)
+	r.MoveTo(point(0, -12))
+	c1x, c1y := point(8, -8)
+	c2x, c2y := point(10, 4)
+	x, y := point(0, 12)
+	r.CubeTo(c1x, c1y, c2x, c2y, x, y)
+	c1x, c1y = point(-10, 4)
+	c2x, c2y = point(-8, -8)
+	x, y = point(0, -12)
+	r.CubeTo(c1x, c1y, c2x, c2y, x, y)
+	r.ClosePath()
+
+	fill := l.Color
+	fill.A = 0xb3
+	r.Draw(img, img.Bounds(), image.NewUniform(fill), image.Point{})
+}
+
+// drawCentered draws text centered horizontally with its baseline at y,
+// shortening it with an ellipsis when it does not fit on the card.
+func drawCentered(img *image.RGBA, face font.Face, c color.Color, text string, y int) {
+	drawer := &font.Drawer{Dst: img, Src: image.NewUniform(c), Face: face}
+
+	for runes := []rune(text); drawer.MeasureString(text) > fixed.I(cardMaxLine) && len(runes) > 1; {
+		runes = runes[:len(runes)-1]
+		text = string(runes) + "…"
+	}
+
+	width := drawer.MeasureString(text)
+	drawer.Dot = fixed.Point26_6{X: (fixed.I(cardWidth) - width) / 2, Y: fixed.I(y)}
+	drawer.DrawString(text)
+}
@@ -0,0 +1,64 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestPNG(t *testing.T) {
+	tests := []struct {
+		name string
+		card Card
+	}{
+		{
+			name: "Haiku only",
+			card: Card{Haiku: "Old cracks mended now\nthe login door swings open\nquiet in the logs"},
+		},
+		{
+			name: "Subject and repository",
+			card: Card{
+				Haiku:      "Old cracks mended now\nthe login door swings open\nquiet in the logs",
+				Subject:    "fix: resolved login issue",
+				Repository: "octo-org/octo-repo",
+			},
+		},
+		{
+			name: "Long subject",
+			card: Card{
+				Haiku:   "Old cracks mended now\nthe login door swings open\nquiet in the logs",
+				Subject: strings.Repeat("refactor everything ", 20),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := PNG(tc.card)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Expected a valid PNG, got %v", err)
+			}
+			if bounds := img.Bounds(); bounds.Dx() != cardWidth || bounds.Dy() != cardHeight {
+				t.Errorf("Expected %dx%d card, got %dx%d", cardWidth, cardHeight, bounds.Dx(), bounds.Dy())
+			}
+
+			again, _ := PNG(tc.card)
+			if !bytes.Equal(again, data) {
+				t.Errorf("Expected the same card to render the same PNG")
+			}
+		})
+	}
+}
+
+func TestPNGEmpty(t *testing.T) {
+	if _, err := PNG(Card{Haiku: "\n"}); !errors.Is(err, ErrEmptyHaiku) {
+		t.Errorf("Expected ErrEmptyHaiku, got %v", err)
+	}
+}
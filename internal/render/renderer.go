@@ -0,0 +1,27 @@
+package render
+
+// SVGRenderer renders cards with SVG. Only the haiku is drawn.
+type SVGRenderer struct{}
+
+func (SVGRenderer) ContentType() string {
+	return "image/svg+xml"
+}
+
+func (SVGRenderer) Render(card Card) ([]byte, error) {
+	svg, err := SVG(card.Haiku)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(svg), nil
+}
+
+// PNGRenderer renders cards with PNG.
+type PNGRenderer struct{}
+
+func (PNGRenderer) ContentType() string {
+	return "image/png"
+}
+
+func (PNGRenderer) Render(card Card) ([]byte, error) {
+	return PNG(card)
+}
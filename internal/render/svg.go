@@ -0,0 +1,151 @@
+// Package render draws haiku as images suitable for embedding outside the API.
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"image/color"
+	"strings"
+	"text/template"
+)
+
+var ErrEmptyHaiku = errors.New("haiku has no lines to render")
+
+const (
+	svgWidth      = 600
+	svgLineHeight = 40
+	svgPadding    = 60
+	svgLeafCount  = 7
+)
+
+// leafColors is the autumn palette leaves are drawn from.
+var leafColors = []color.NRGBA{
+	{R: 0xc0, G: 0x39, B: 0x2b, A: 0xff},
+	{R: 0xd3, G: 0x54, B: 0x00, A: 0xff},
+	{R: 0xe6, G: 0x7e, B: 0x22, A: 0xff},
+	{R: 0xb9, G: 0x77, B: 0x0e, A: 0xff},
+	{R: 0xa0, G: 0x40, B: 0x00, A: 0xff},
+}
+
+type leaf struct {
+	X, Y     int
+	Rotation int
+	Scale    float64
+	Color    color.NRGBA
+}
+
+// Fill returns the leaf color in SVG hex notation.
+func (l leaf) Fill() string {
+	return fmt.Sprintf("#%02x%02x%02x", l.Color.R, l.Color.G, l.Color.B)
+}
+
+type svgData struct {
+	Width, Height int
+	CenterX       int
+	Lines         []svgLine
+	Leaves        []leaf
+}
+
+type svgLine struct {
+	Y    int
+	Text string
+}
+
+var svgTemplate = template.Must(template.New("haiku.svg").Funcs(template.FuncMap{
+	"xml": escapeXML,
+}).Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" viewBox="0 0 {{.Width}} {{.Height}}" role="img" aria-label="{{range $i, $l := .Lines}}{{if $i}} / {{end}}{{xml $l.Text}}{{end}}">
+  <defs>
+    <linearGradient id="sky" x1="0" y1="0" x2="0" y2="1">
+      <stop offset="0%" stop-color="#fdf2e9"/>
+      <stop offset="100%" stop-color="#f6ddcc"/>
+    </linearGradient>
+    <path id="leaf" d="M0,-12 C8,-8 10,4 0,12 C-10,4 -8,-8 0,-12 Z M0,-12 L0,16"/>
+  </defs>
+  <rect width="100%" height="100%" rx="12" fill="url(#sky)"/>
+{{- range .Leaves}}
+  <use href="#leaf" fill="{{.Fill}}" stroke="{{.Fill}}" stroke-width="1" opacity="0.7" transform="translate({{.X}} {{.Y}}) rotate({{.Rotation}}) scale({{printf "%.2f" .Scale}})"/>
+{{- end}}
+  <g font-family="Georgia, 'Times New Roman', serif" font-size="22" fill="#4a2c1a" text-anchor="middle">
+{{- range .Lines}}
+    <text x="{{$.CenterX}}" y="{{.Y}}">{{xml .Text}}</text>
+{{- end}}
+  </g>
+</svg>
+`))
+
+// SVG renders haiku, one line per row, on a card scattered with falling
+// leaves. The leaf layout is derived from the haiku text, so the same haiku
+// always renders the same image.
+func SVG(haiku string) (string, error) {
+	lines := splitLines(haiku)
+	if len(lines) == 0 {
+		return "", ErrEmptyHaiku
+	}
+
+	height := 2*svgPadding + (len(lines)-1)*svgLineHeight
+	data := svgData{
+		Width:   svgWidth,
+		Height:  height,
+		CenterX: svgWidth / 2,
+		Leaves:  scatterLeaves(haiku, svgWidth, height),
+	}
+	for i, line := range lines {
+		data.Lines = append(data.Lines, svgLine{
+			Y:    svgPadding + i*svgLineHeight + 8,
+			Text: line,
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := svgTemplate.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// splitLines returns the non-empty, trimmed lines of haiku.
+func splitLines(haiku string) []string {
+	var lines []string
+	for _, line := range strings.Split(haiku, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// scatterLeaves places leaves pseudo-randomly, seeded by the haiku text.
+func scatterLeaves(haiku string, width, height int) []leaf {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(haiku))
+	seed := hash.Sum64()
+
+	next := func(n int) int {
+		// xorshift keeps the layout stable without depending on math/rand's sequence.
+		seed ^= seed << 13
+		seed ^= seed >> 7
+		seed ^= seed << 17
+		return int(seed % uint64(n)) // #nosec G115 -- n is a small positive constant
+	}
+
+	leaves := make([]leaf, svgLeafCount)
+	for i := range leaves {
+		leaves[i] = leaf{
+			X:        next(width),
+			Y:        next(height),
+			Rotation: next(360),
+			Scale:    0.6 + float64(next(80))/100,
+			Color:    leafColors[next(len(leafColors))],
+		}
+	}
+	return leaves
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
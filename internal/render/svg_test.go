@@ -0,0 +1,51 @@
+package render
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSVG(t *testing.T) {
+	haiku := "Old cracks mended now\nthe <login> door swings open\nquiet in the logs"
+
+	svg, err := SVG(haiku)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// The output must be well-formed XML even when the haiku contains markup.
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		_, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				t.Fatalf("Expected well-formed SVG, got %v", err)
+			}
+			break
+		}
+	}
+
+	if !strings.Contains(svg, "the &lt;login&gt; door swings open") {
+		t.Errorf("Expected escaped haiku line in SVG")
+	}
+	if strings.Count(svg, "<text ") != 3 {
+		t.Errorf("Expected one text element per line, got %d", strings.Count(svg, "<text "))
+	}
+	if strings.Count(svg, `<use href="#leaf"`) != svgLeafCount {
+		t.Errorf("Expected %d leaves, got %d", svgLeafCount, strings.Count(svg, `<use href="#leaf"`))
+	}
+
+	again, _ := SVG(haiku)
+	if again != svg {
+		t.Errorf("Expected the same haiku to render the same SVG")
+	}
+}
+
+func TestSVGEmpty(t *testing.T) {
+	if _, err := SVG(" \n \n"); !errors.Is(err, ErrEmptyHaiku) {
+		t.Errorf("Expected ErrEmptyHaiku, got %v", err)
+	}
+}
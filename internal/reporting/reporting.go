@@ -0,0 +1,75 @@
+// Package reporting ships panics and server errors to an error reporting
+// sink: structured log lines for CloudWatch Logs Insights, or Sentry.
+package reporting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidDSN = errors.New("invalid sentry dsn")
+	ErrReport     = errors.New("error report failed")
+)
+
+// EventType marks error reports among other log lines, e.g.
+// filter type = "error_report" in CloudWatch Logs Insights.
+const EventType = "error_report"
+
+type Kind string
+
+const (
+	KindPanic Kind = "panic" // A handler panicked and was recovered
+	KindError Kind = "error" // A request failed with a 5xx response
+)
+
+// Event describes one failed request.
+type Event struct {
+	Type         string    `json:"type"` // Always EventType
+	Time         time.Time `json:"time"`
+	Kind         Kind      `json:"kind"`
+	Message      string    `json:"message"`
+	Status       int       `json:"status"`
+	Method       string    `json:"method"`
+	Route        string    `json:"route,omitempty"` // Matched route pattern, e.g. "/haiku"
+	Path         string    `json:"path"`
+	RequestID    string    `json:"requestId,omitempty"`    // Lambda request ID, when running in Lambda
+	APIRequestID string    `json:"apiRequestId,omitempty"` // API Gateway request ID, when running behind API Gateway
+	UserAgent    string    `json:"userAgent,omitempty"`
+	Stack        string    `json:"stack,omitempty"` // Goroutine stack, for panics
+}
+
+type Reporter interface {
+	Report(ctx context.Context, event Event) error
+}
+
+// LogReporter writes each event as a single JSON line, which CloudWatch Logs
+// Insights discovers fields from without any parsing.
+type LogReporter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogReporter(w io.Writer) *LogReporter {
+	return &LogReporter{w: w}
+}
+
+func (r *LogReporter) Report(ctx context.Context, event Event) error {
+	event.Type = EventType
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("%w: encoding event: %v", ErrReport, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrReport, err)
+	}
+	return nil
+}
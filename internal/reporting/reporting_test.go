@@ -0,0 +1,187 @@
+package reporting
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testEvent = Event{
+	Time:      time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC),
+	Kind:      KindPanic,
+	Message:   "runtime error: index out of range",
+	Status:    http.StatusInternalServerError,
+	Method:    http.MethodPost,
+	Route:     "/haiku",
+	Path:      "/haiku",
+	RequestID: "c6af9ac6-7b61-11e6-9a41-93e8deadbeef",
+	Stack:     "goroutine 1 [running]:",
+}
+
+func TestLogReporter(t *testing.T) {
+	var out bytes.Buffer
+	reporter := NewLogReporter(&out)
+
+	if err := reporter.Report(context.Background(), testEvent); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if strings.Count(out.String(), "\n") != 1 {
+		t.Fatalf("Expected a single log line, got %q", out.String())
+	}
+
+	var logged map[string]any
+	if err := json.Unmarshal(out.Bytes(), &logged); err != nil {
+		t.Fatalf("Failed to unmarshal log line: %v", err)
+	}
+	if logged["type"] != EventType || logged["kind"] != "panic" || logged["route"] != "/haiku" || logged["status"] != float64(500) {
+		t.Errorf("Expected the event fields in the log line, got %v", logged)
+	}
+}
+
+type MockHTTPClient struct {
+	StatusToReturn int
+	ErrorToReturn  error
+	LastRequest    *http.Request
+	LastBody       string
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.LastRequest = req
+	body, _ := io.ReadAll(req.Body)
+	m.LastBody = string(body)
+	if m.ErrorToReturn != nil {
+		return nil, m.ErrorToReturn
+	}
+
+	status := m.StatusToReturn
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(`{}`))}, nil
+}
+
+func TestNewSentryReporter(t *testing.T) {
+	tests := []struct {
+		name             string
+		dsn              string
+		expectedEndpoint string
+		expectError      bool
+	}{
+		{
+			name:             "Sentry hosted",
+			dsn:              "https://abc123@o1.ingest.sentry.io/42",
+			expectedEndpoint: "https://o1.ingest.sentry.io/api/42/envelope/",
+		},
+		{
+			name:             "Self-hosted under a path",
+			dsn:              "http://abc123@sentry.internal:9000/errors/7",
+			expectedEndpoint: "http://sentry.internal:9000/errors/api/7/envelope/",
+		},
+		{
+			name:        "Missing key",
+			dsn:         "https://o1.ingest.sentry.io/42",
+			expectError: true,
+		},
+		{
+			name:        "Missing project",
+			dsn:         "https://abc123@o1.ingest.sentry.io",
+			expectError: true,
+		},
+		{
+			name:        "Not a URL",
+			dsn:         "abc123",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reporter, err := NewSentryReporter(&MockHTTPClient{}, tc.dsn)
+			if tc.expectError {
+				if !errors.Is(err, ErrInvalidDSN) {
+					t.Errorf("Expected error to wrap %v, got %v", ErrInvalidDSN, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if reporter.endpoint != tc.expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", tc.expectedEndpoint, reporter.endpoint)
+			}
+		})
+	}
+}
+
+func TestSentryReporter(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		httpError  error
+		expectSent bool
+	}{
+		{
+			name:       "Event accepted",
+			expectSent: true,
+		},
+		{
+			name:   "Sentry rejects the event",
+			status: http.StatusTooManyRequests,
+		},
+		{
+			name:      "Sentry unreachable",
+			httpError: errors.New("connection refused"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &MockHTTPClient{StatusToReturn: tc.status, ErrorToReturn: tc.httpError}
+			reporter, err := NewSentryReporter(client, "https://abc123@o1.ingest.sentry.io/42")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			err = reporter.Report(context.Background(), testEvent)
+			if !tc.expectSent {
+				if !errors.Is(err, ErrReport) {
+					t.Errorf("Expected error to wrap %v, got %v", ErrReport, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if auth := client.LastRequest.Header.Get("X-Sentry-Auth"); !strings.Contains(auth, "sentry_key=abc123") {
+				t.Errorf("Expected the public key in the auth header, got %q", auth)
+			}
+
+			var lines []string
+			scanner := bufio.NewScanner(strings.NewReader(client.LastBody))
+			for scanner.Scan() {
+				lines = append(lines, scanner.Text())
+			}
+			if len(lines) != 3 {
+				t.Fatalf("Expected an envelope header, item header and event, got %q", client.LastBody)
+			}
+
+			var event sentryEvent
+			if err := json.Unmarshal([]byte(lines[2]), &event); err != nil {
+				t.Fatalf("Failed to unmarshal event: %v", err)
+			}
+			if event.Level != "fatal" || event.Transaction != "/haiku" || event.Extra["requestId"] != testEvent.RequestID || len(event.EventID) != 32 {
+				t.Errorf("Expected the panic described in the event, got %+v", event)
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package reporting
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const sentryClient = "commits-fall-like-leaves/1.0"
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// SentryReporter sends events to a Sentry project as envelopes.
+type SentryReporter struct {
+	httpClient HTTPClient
+	endpoint   string
+	publicKey  string
+	dsn        string
+}
+
+// NewSentryReporter parses a DSN of the form
+// https://<public key>@<host>/<project id>.
+func NewSentryReporter(httpClient HTTPClient, dsn string) (*SentryReporter, error) {
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDSN, err)
+	}
+
+	// Self-hosted Sentry may be served under a path, so the project ID is the
+	// last path segment.
+	path := strings.TrimSuffix(parsed.Path, "/")
+	index := strings.LastIndex(path, "/")
+	publicKey := parsed.User.Username()
+	if (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || publicKey == "" || index < 0 {
+		return nil, fmt.Errorf("%w: expected https://<public key>@<host>/<project id>", ErrInvalidDSN)
+	}
+
+	prefix, projectID := path[:index], path[index+1:]
+	if _, err := strconv.Atoi(projectID); err != nil {
+		return nil, fmt.Errorf("%w: project id %q is not a number", ErrInvalidDSN, projectID)
+	}
+
+	return &SentryReporter{
+		httpClient: httpClient,
+		endpoint:   fmt.Sprintf("%s://%s%s/api/%s/envelope/", parsed.Scheme, parsed.Host, prefix, projectID),
+		publicKey:  publicKey,
+		dsn:        dsn,
+	}, nil
+}
+
+func NewDefaultSentryReporter(dsn string) (*SentryReporter, error) {
+	return NewSentryReporter(&http.Client{Timeout: 5 * time.Second}, dsn)
+}
+
+// sentryEvent is the subset of the Sentry event payload the service fills in.
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Platform    string            `json:"platform"`
+	Level       string            `json:"level"`
+	Transaction string            `json:"transaction,omitempty"`
+	Message     string            `json:"message"`
+	Tags        map[string]string `json:"tags"`
+	Extra       map[string]string `json:"extra,omitempty"`
+	Request     sentryRequest     `json:"request"`
+}
+
+type sentryRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+func (r *SentryReporter) Report(ctx context.Context, event Event) error {
+	eventID, err := newEventID()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrReport, err)
+	}
+
+	level := "error"
+	if event.Kind == KindPanic {
+		level = "fatal"
+	}
+
+	payload := sentryEvent{
+		EventID:     eventID,
+		Timestamp:   event.Time.UTC(),
+		Platform:    "go",
+		Level:       level,
+		Transaction: event.Route,
+		Message:     event.Message,
+		Tags: map[string]string{
+			"kind":   string(event.Kind),
+			"status": strconv.Itoa(event.Status),
+		},
+		Extra: make(map[string]string),
+		Request: sentryRequest{
+			Method: event.Method,
+			URL:    event.Path,
+		},
+	}
+	if event.RequestID != "" {
+		payload.Extra["requestId"] = event.RequestID
+	}
+	if event.APIRequestID != "" {
+		payload.Extra["apiRequestId"] = event.APIRequestID
+	}
+	if event.Stack != "" {
+		payload.Extra["stack"] = event.Stack
+	}
+	if event.UserAgent != "" {
+		payload.Request.Headers = map[string]string{"User-Agent": event.UserAgent}
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, part := range []any{
+		map[string]string{"event_id": eventID, "dsn": r.dsn, "sent_at": time.Now().UTC().Format(time.RFC3339)},
+		map[string]string{"type": "event"},
+		payload,
+	} {
+		if err := encoder.Encode(part); err != nil {
+			return fmt.Errorf("%w: encoding envelope: %v", ErrReport, err)
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.endpoint, &body)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrReport, err)
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, r.publicKey))
+
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrReport, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%w: sentry returned %d: %s", ErrReport, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func newEventID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
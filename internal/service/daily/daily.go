@@ -0,0 +1,200 @@
+// Package daily writes one haiku per UTC day, shared by every caller, for
+// dashboards and office displays.
+package daily
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	dayFormat = "2006-01-02"
+	keyPrefix = "daily:"
+	// retention keeps yesterday's haiku a while, for callers whose day
+	// hasn't ended yet.
+	retention = 48 * time.Hour
+)
+
+var (
+	ErrGetDaily   = errors.New("error getting the haiku of the day")
+	ErrStoreDaily = errors.New("error storing the haiku of the day")
+)
+
+// Theme is a prompt the haiku of the day can be written from, phrased as a
+// commit message.
+type Theme struct {
+	CommitMessage string
+	Mood          haiku.Mood
+}
+
+// DefaultThemes are taken in turn, one per day.
+var DefaultThemes = []Theme{
+	{CommitMessage: "chore: first commit of the day, coffee still warming", Mood: haiku.MoodReflective},
+	{CommitMessage: "fix: off-by-one error at the end of a long loop", Mood: haiku.MoodHumerous},
+	{CommitMessage: "docs: explain why this works, for whoever reads it next", Mood: haiku.MoodReflective},
+	{CommitMessage: "refactor: untangle the module nobody wanted to touch", Mood: haiku.MoodTechnical},
+	{CommitMessage: "feat: ship the small feature users waited all year for", Mood: haiku.MoodReflective},
+	{CommitMessage: "revert: undo yesterday's confident change", Mood: haiku.MoodHumerous},
+	{CommitMessage: "test: the flaky test finally holds steady", Mood: haiku.MoodTechnical},
+	{CommitMessage: "chore: delete dead code, quietly", Mood: haiku.MoodReflective},
+	{CommitMessage: "perf: the slow query answers in a blink", Mood: haiku.MoodTechnical},
+	{CommitMessage: "ci: the build turns green after a long red night", Mood: haiku.MoodReflective},
+	{CommitMessage: "build: bump every dependency one more time", Mood: haiku.MoodHumerous},
+	{CommitMessage: "fix: the missing semicolon, found at midnight", Mood: haiku.MoodHumerous},
+}
+
+// Daily is the haiku of one UTC day.
+type Daily struct {
+	Date      string     `json:"date"` // As YYYY-MM-DD in UTC
+	Haiku     string     `json:"haiku"`
+	Theme     string     `json:"theme"` // The commit message it was written from
+	Mood      haiku.Mood `json:"mood"`
+	ExpiresAt time.Time  `json:"expiresAt"` // Midnight UTC, when the next day's haiku replaces it
+}
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+// Store keeps each day's haiku, e.g. in DynamoDB. PutIfAbsent stores a value
+// only when the key holds none, so that instances racing to write the same
+// day's haiku agree on one.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+}
+
+type DailyService struct {
+	haikuService HaikuService
+	store        Store
+	themes       []Theme
+	now          func() time.Time
+
+	// mu serializes lookups, so an instance writes each day's haiku once,
+	// and today is the last haiku it returned.
+	mu    sync.Mutex
+	today Daily
+}
+
+type Options struct {
+	Themes []Theme // Prompts taken in turn, one per day (default: DefaultThemes)
+}
+
+func NewDailyService(haikuService HaikuService, store Store, opts *Options) *DailyService {
+	service := &DailyService{
+		haikuService: haikuService,
+		store:        store,
+		themes:       DefaultThemes,
+		now:          time.Now,
+	}
+
+	if opts != nil && len(opts.Themes) > 0 {
+		service.themes = opts.Themes
+	}
+
+	return service
+}
+
+// Get returns today's haiku, writing it when no caller has yet today. A haiku
+// written while the model is unavailable is returned but not kept, so a later
+// caller can replace it.
+func (s *DailyService) Get(ctx context.Context) (Daily, error) {
+	now := s.now().UTC()
+	date := now.Format(dayFormat)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.today.Date == date {
+		return s.today, nil
+	}
+
+	key := keyPrefix + date
+	daily, found, err := s.load(ctx, key)
+	if err != nil {
+		return Daily{}, err
+	}
+	if found {
+		s.today = daily
+		return daily, nil
+	}
+
+	// Every tenant shares the haiku, so it is written and archived for the
+	// default tenant without an API key, whichever caller asks first, rather
+	// than kept as theirs.
+	shared := keys.NewTenantContext(keys.NewContext(ctx, ""), "")
+	theme := s.theme(now)
+	response, err := s.haikuService.CreateHaiku(shared, haiku.HaikuCommitRequest{
+		CommitMessage: theme.CommitMessage,
+		Mood:          theme.Mood,
+		NoCache:       true,
+	})
+	if err != nil {
+		return Daily{}, err
+	}
+
+	daily = Daily{
+		Date:      date,
+		Haiku:     response.Haiku,
+		Theme:     theme.CommitMessage,
+		Mood:      theme.Mood,
+		ExpiresAt: time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC),
+	}
+	if response.Degraded {
+		return daily, nil
+	}
+
+	value, err := json.Marshal(daily)
+	if err != nil {
+		return Daily{}, fmt.Errorf("%w: %w", ErrStoreDaily, err)
+	}
+	stored, err := s.store.PutIfAbsent(ctx, key, value, retention)
+	if err != nil {
+		return Daily{}, fmt.Errorf("%w: %w", ErrStoreDaily, err)
+	}
+	if !stored {
+		// Another instance wrote today's haiku first; return theirs.
+		logging.Infof("[DAILY SERVICE] haiku for %s was already written, discarding ours\n", date)
+		daily, found, err = s.load(ctx, key)
+		if err != nil {
+			return Daily{}, err
+		}
+		if !found {
+			return Daily{}, fmt.Errorf("%w: %s is missing after it was stored", ErrGetDaily, key)
+		}
+	}
+
+	s.today = daily
+	return daily, nil
+}
+
+func (s *DailyService) load(ctx context.Context, key string) (Daily, bool, error) {
+	value, found, err := s.store.Get(ctx, key)
+	if err != nil {
+		return Daily{}, false, fmt.Errorf("%w: %w", ErrGetDaily, err)
+	}
+	if !found {
+		return Daily{}, false, nil
+	}
+
+	var daily Daily
+	if err := json.Unmarshal(value, &daily); err != nil {
+		return Daily{}, false, fmt.Errorf("%w: %w", ErrGetDaily, err)
+	}
+	return daily, true, nil
+}
+
+// theme picks the day's theme, counting days since the Unix epoch so that
+// every instance picks the same one.
+func (s *DailyService) theme(now time.Time) Theme {
+	day := now.Unix() / int64(24*time.Hour/time.Second)
+	return s.themes[day%int64(len(s.themes))]
+}
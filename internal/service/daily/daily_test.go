@@ -0,0 +1,196 @@
+package daily
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+var testThemes = []Theme{
+	{CommitMessage: "fix: resolved login issue", Mood: haiku.MoodTechnical},
+	{CommitMessage: "docs: explain the retry budget", Mood: haiku.MoodReflective},
+}
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+	Requests         []haiku.HaikuCommitRequest
+	LastKeyID        string
+	LastTenant       string
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.Requests = append(m.Requests, request)
+	m.LastKeyID = keys.IDFromContext(ctx)
+	m.LastTenant = keys.TenantFromContext(ctx)
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+// MockStore loses every race to store a haiku to Winner, as if another
+// instance stored it between the lookup and the put.
+type MockStore struct {
+	Winner        *Daily
+	ErrorToReturn error
+	puts          int
+}
+
+func (m *MockStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if m.ErrorToReturn != nil || m.Winner == nil || m.puts == 0 {
+		return nil, false, m.ErrorToReturn
+	}
+	value, _ := json.Marshal(m.Winner)
+	return value, true, nil
+}
+
+func (m *MockStore) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	m.puts++
+	return m.Winner == nil, nil
+}
+
+func TestGet(t *testing.T) {
+	// 2025-10-01 is day 20362 since the Unix epoch, so it takes the first theme.
+	now := time.Date(2025, 10, 1, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		store         Store
+		response      haiku.HaikuCommitResponse
+		mockError     error
+		expected      Daily
+		expectedCalls int
+		errorIs       error
+	}{
+		{
+			name:     "Written and stored",
+			store:    NewMemoryStore(),
+			response: haiku.HaikuCommitResponse{Haiku: testHaiku},
+			expected: Daily{
+				Date:      "2025-10-01",
+				Haiku:     testHaiku,
+				Theme:     "fix: resolved login issue",
+				Mood:      haiku.MoodTechnical,
+				ExpiresAt: time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC),
+			},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Another instance wrote it first",
+			store:         &MockStore{Winner: &Daily{Date: "2025-10-01", Haiku: "Theirs"}},
+			response:      haiku.HaikuCommitResponse{Haiku: testHaiku},
+			expected:      Daily{Date: "2025-10-01", Haiku: "Theirs"},
+			expectedCalls: 1,
+		},
+		{
+			name:     "Degraded haiku are not kept",
+			store:    NewMemoryStore(),
+			response: haiku.HaikuCommitResponse{Haiku: testHaiku, Degraded: true},
+			expected: Daily{
+				Date:      "2025-10-01",
+				Haiku:     testHaiku,
+				Theme:     "fix: resolved login issue",
+				Mood:      haiku.MoodTechnical,
+				ExpiresAt: time.Date(2025, 10, 2, 0, 0, 0, 0, time.UTC),
+			},
+			expectedCalls: 2,
+		},
+		{
+			name:          "Model fails",
+			store:         NewMemoryStore(),
+			mockError:     errors.New("throttled"),
+			expectedCalls: 2,
+		},
+		{
+			name:    "Store fails",
+			store:   &MockStore{ErrorToReturn: errors.New("access denied")},
+			errorIs: ErrGetDaily,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{ResponseToReturn: tc.response, ErrorToReturn: tc.mockError}
+			service := NewDailyService(haikuService, tc.store, &Options{Themes: testThemes})
+			service.now = func() time.Time { return now }
+
+			// Asked twice, as by two callers on the same instance.
+			for range 2 {
+				daily, err := service.Get(context.Background())
+				if tc.mockError != nil {
+					if !errors.Is(err, tc.mockError) {
+						t.Fatalf("Expected the model's error, got %v", err)
+					}
+					continue
+				}
+				if !errors.Is(err, tc.errorIs) {
+					t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+				}
+				if daily != tc.expected {
+					t.Errorf("Expected %+v, got %+v", tc.expected, daily)
+				}
+			}
+
+			if len(haikuService.Requests) != tc.expectedCalls {
+				t.Errorf("Expected %d haiku written, got %d", tc.expectedCalls, len(haikuService.Requests))
+			}
+		})
+	}
+}
+
+func TestGetSharesStoredHaiku(t *testing.T) {
+	now := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.SetClock(func() time.Time { return now })
+
+	first := NewDailyService(&MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}, store, &Options{Themes: testThemes})
+	first.now = func() time.Time { return now }
+	written, err := first.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// A second instance, started later in the day, reads the same haiku.
+	haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: "Another verse"}}
+	second := NewDailyService(haikuService, store, &Options{Themes: testThemes})
+	second.now = func() time.Time { return now.Add(8 * time.Hour) }
+	read, err := second.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if read != written || len(haikuService.Requests) != 0 {
+		t.Errorf("Expected the stored haiku %+v, got %+v after %d writes", written, read, len(haikuService.Requests))
+	}
+
+	// The next day has a haiku of its own, from the next theme.
+	second.now = func() time.Time { return now.Add(24 * time.Hour) }
+	next, err := second.Get(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if next.Date != "2025-10-02" || next.Haiku != "Another verse" || next.Theme != testThemes[1].CommitMessage {
+		t.Errorf("Expected the next day's haiku from the next theme, got %+v", next)
+	}
+	if !haikuService.Requests[0].NoCache {
+		t.Error("Expected the daily haiku to bypass the response cache")
+	}
+}
+
+func TestGetWritesSharedHaiku(t *testing.T) {
+	haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+	service := NewDailyService(haikuService, NewMemoryStore(), &Options{Themes: testThemes})
+
+	// The first caller's key and tenant are not the haiku's.
+	ctx := keys.NewTenantContext(keys.NewContext(context.Background(), "key-1"), "acme")
+	if _, err := service.Get(ctx); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if haikuService.LastKeyID != "" || haikuService.LastTenant != "" {
+		t.Errorf("Expected the haiku written without a key in the default tenant, got key %q in tenant %q", haikuService.LastKeyID, haikuService.LastTenant)
+	}
+}
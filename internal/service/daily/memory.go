@@ -0,0 +1,11 @@
+package daily
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps each day's haiku in memory for a single server, which
+// writes its own haiku of the day.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
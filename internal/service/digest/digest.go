@@ -0,0 +1,395 @@
+// Package digest emails subscribers a weekly digest of the haiku written for
+// their team or repository, so that the poetry reaches people who never open
+// a pull request.
+package digest
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/ses"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+const (
+	// Period is how far back a digest reaches.
+	Period = 7 * 24 * time.Hour
+	// MaxHaiku is the most haiku a digest lists; the rest are counted.
+	MaxHaiku = 20
+	// MaxSubscriptions is the most subscriptions a tenant can hold.
+	MaxSubscriptions = 100
+
+	maxEmailLength      = 254
+	maxRepositoryLength = 100
+
+	subscriptionPrefix = "digest:"
+	sentPrefix         = "digest-sent:"
+	// sentRetention keeps a record of each digest sent for longer than the
+	// schedule could be retried.
+	sentRetention = 14 * 24 * time.Hour
+)
+
+var (
+	ErrBadSubscription      = errors.New("bad digest subscription")
+	ErrSubscriptionNotFound = errors.New("digest subscription not found")
+	ErrStoreSubscription    = errors.New("error storing digest subscription")
+	ErrSendDigest           = errors.New("error sending digests")
+)
+
+// Subscription asks for a weekly digest of a tenant's haiku, or of one of
+// its repositories', by email.
+type Subscription struct {
+	ID         string    `json:"id"`
+	Tenant     string    `json:"tenant,omitempty"`     // The tenant whose haiku are sent, empty for the default tenant
+	Email      string    `json:"email"`                // Address the digest is sent to
+	Repository string    `json:"repository,omitempty"` // Only this repository's haiku, when set
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Subscriptions lists a tenant's subscriptions.
+type Subscriptions struct {
+	Subscriptions []Subscription `json:"subscriptions"` // Oldest first
+}
+
+// SubscribeRequest subscribes an address to the weekly digest of the
+// caller's tenant.
+type SubscribeRequest struct {
+	Email      string `json:"email" binding:"required"`
+	Repository string `json:"repository,omitempty"` // Only this repository's haiku (default: every haiku of the tenant)
+}
+
+// Run is the outcome of sending a week's digests.
+type Run struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Sent    int       `json:"sent"`    // Digests sent
+	Skipped int       `json:"skipped"` // Subscriptions without haiku that week, or already sent theirs
+}
+
+// entry is a subscription as it is stored, with the token its unsubscribe
+// link carries.
+type entry struct {
+	Subscription
+	Token string `json:"token"`
+}
+
+// Source lists every kept haiku, e.g. the vote service.
+type Source interface {
+	Each(ctx context.Context, fn func(saved votes.Saved) error) error
+}
+
+// Sender sends email, e.g. through SES.
+type Sender interface {
+	Send(ctx context.Context, message ses.Message) error
+}
+
+// Store keeps subscriptions, e.g. in DynamoDB alongside keys. PutIfAbsent
+// records each digest as it is sent, so that a retried schedule doesn't send
+// it twice.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+	ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+}
+
+type DigestService struct {
+	store     Store
+	source    Source
+	sender    Sender
+	publicURL string
+	now       func() time.Time
+}
+
+type Options struct {
+	PublicURL string // Base URL of the API, linked to from digests to unsubscribe (default: none, no link)
+}
+
+func NewDigestService(store Store, source Source, sender Sender, opts *Options) *DigestService {
+	service := &DigestService{
+		store:  store,
+		source: source,
+		sender: sender,
+		now:    time.Now,
+	}
+
+	if opts != nil {
+		service.publicURL = strings.TrimSuffix(opts.PublicURL, "/")
+	}
+
+	return service
+}
+
+// Subscribe adds a subscription to the weekly digest of the context's tenant.
+func (s *DigestService) Subscribe(ctx context.Context, request SubscribeRequest) (Subscription, error) {
+	email := strings.TrimSpace(request.Email)
+	if address, err := mail.ParseAddress(email); err != nil || address.Address != email || len(email) > maxEmailLength {
+		return Subscription{}, fmt.Errorf("%w: email must be a plain address, at most %d characters", ErrBadSubscription, maxEmailLength)
+	}
+	repository := strings.TrimSpace(request.Repository)
+	if len(repository) > maxRepositoryLength || strings.ContainsFunc(repository, isControl) {
+		return Subscription{}, fmt.Errorf("%w: repository must be at most %d characters", ErrBadSubscription, maxRepositoryLength)
+	}
+
+	existing, err := s.List(ctx)
+	if err != nil {
+		return Subscription{}, err
+	}
+	if len(existing) >= MaxSubscriptions {
+		return Subscription{}, fmt.Errorf("%w: the tenant already has %d subscriptions", ErrBadSubscription, MaxSubscriptions)
+	}
+
+	id, err := randomHex()
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	token, err := randomHex()
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	subscription := Subscription{
+		ID:         id,
+		Tenant:     keys.TenantFromContext(ctx),
+		Email:      email,
+		Repository: repository,
+		CreatedAt:  s.now(),
+	}
+	value, err := json.Marshal(entry{Subscription: subscription, Token: token})
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	if err := s.store.Put(ctx, subscriptionKey(id), value, 0); err != nil {
+		return Subscription{}, fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	return subscription, nil
+}
+
+// List returns the subscriptions of the context's tenant, oldest first.
+func (s *DigestService) List(ctx context.Context) ([]Subscription, error) {
+	tenant := keys.TenantFromContext(ctx)
+	subscriptions := []Subscription{}
+	err := s.each(ctx, func(subscribed entry) {
+		if subscribed.Tenant == tenant {
+			subscriptions = append(subscriptions, subscribed.Subscription)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(subscriptions, func(a, b Subscription) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	return subscriptions, nil
+}
+
+// Unsubscribe removes the subscription id of the context's tenant.
+func (s *DigestService) Unsubscribe(ctx context.Context, id string) error {
+	subscribed, err := s.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if subscribed.Tenant != keys.TenantFromContext(ctx) {
+		return ErrSubscriptionNotFound
+	}
+	return s.delete(ctx, id)
+}
+
+// UnsubscribeWithToken removes the subscription id for whoever follows the
+// unsubscribe link of one of its digests, which carries token.
+func (s *DigestService) UnsubscribeWithToken(ctx context.Context, id string, token string) error {
+	subscribed, err := s.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(subscribed.Token)) != 1 {
+		return ErrSubscriptionNotFound
+	}
+	return s.delete(ctx, id)
+}
+
+// SendWeekly sends each subscription the haiku published over the last week
+// for its tenant, or its repository. Subscriptions without any are skipped,
+// as are those whose digest for the week was already sent, so the schedule
+// can be retried. A digest that fails to send is left for a retry, and the
+// rest are still sent.
+func (s *DigestService) SendWeekly(ctx context.Context) (Run, error) {
+	run := Run{To: s.now().UTC().Truncate(time.Hour)}
+	run.From = run.To.Add(-Period)
+
+	var subscriptions []entry
+	if err := s.each(ctx, func(subscribed entry) {
+		subscriptions = append(subscriptions, subscribed)
+	}); err != nil {
+		return run, err
+	}
+	if len(subscriptions) == 0 {
+		return run, nil
+	}
+
+	var published []votes.Saved
+	err := s.source.Each(ctx, func(saved votes.Saved) error {
+		if saved.Status == votes.StatusPublished && !saved.CreatedAt.Before(run.From) && saved.CreatedAt.Before(run.To) {
+			published = append(published, saved)
+		}
+		return nil
+	})
+	if err != nil {
+		return run, fmt.Errorf("%w: %w", ErrSendDigest, err)
+	}
+	slices.SortFunc(published, func(a, b votes.Saved) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+
+	var errs []error
+	for _, subscribed := range subscriptions {
+		var matched []votes.Saved
+		for _, saved := range published {
+			if subscribed.matches(saved) {
+				matched = append(matched, saved)
+			}
+		}
+		if len(matched) == 0 {
+			run.Skipped++
+			continue
+		}
+
+		sent, err := s.send(ctx, subscribed, run, matched)
+		if err != nil {
+			logging.Errorf("[DIGEST SERVICE] error sending digest %s: %v\n", subscribed.ID, err)
+			errs = append(errs, err)
+			continue
+		}
+		if !sent {
+			run.Skipped++
+			continue
+		}
+		run.Sent++
+	}
+
+	logging.Infof("[DIGEST SERVICE] sent %d digests, skipped %d\n", run.Sent, run.Skipped)
+	if len(errs) > 0 {
+		return run, fmt.Errorf("%w: %w", ErrSendDigest, errors.Join(errs...))
+	}
+	return run, nil
+}
+
+// send emails one subscription its digest, unless it was already sent this
+// week. The digest is recorded as sent before it is sent, so that runs
+// overlapping don't both send it, and forgotten if sending fails.
+func (s *DigestService) send(ctx context.Context, subscribed entry, run Run, haiku []votes.Saved) (bool, error) {
+	key := sentPrefix + subscribed.ID + ":" + run.To.Format(time.DateOnly)
+	recorded, err := s.store.PutIfAbsent(ctx, key, []byte(`{}`), sentRetention)
+	if err != nil {
+		return false, err
+	}
+	if !recorded {
+		return false, nil
+	}
+
+	message, err := render(subscribed.Subscription, s.unsubscribeURL(subscribed), run, haiku)
+	if err == nil {
+		err = s.sender.Send(ctx, message)
+	}
+	if err != nil {
+		if deleteErr := s.store.Delete(ctx, key); deleteErr != nil {
+			logging.Errorf("[DIGEST SERVICE] error forgetting digest %s: %v\n", key, deleteErr)
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// unsubscribeURL is the link removing subscribed, or "" without a public URL.
+func (s *DigestService) unsubscribeURL(subscribed entry) string {
+	if s.publicURL == "" {
+		return ""
+	}
+	return s.publicURL + "/digests/" + url.PathEscape(subscribed.ID) + "/unsubscribe?token=" + url.QueryEscape(subscribed.Token)
+}
+
+// matches reports whether a digest for the subscription includes saved.
+func (e entry) matches(saved votes.Saved) bool {
+	if saved.Tenant != e.Tenant {
+		return false
+	}
+	return e.Repository == "" || strings.EqualFold(saved.Repository, e.Repository)
+}
+
+func (s *DigestService) each(ctx context.Context, fn func(subscribed entry)) error {
+	err := s.store.ScanPrefix(ctx, subscriptionPrefix, func(key string, value []byte) error {
+		var subscribed entry
+		if err := json.Unmarshal(value, &subscribed); err != nil {
+			logging.Warnf("[DIGEST SERVICE] skipping unreadable %s: %v\n", key, err)
+			return nil
+		}
+		fn(subscribed)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	return nil
+}
+
+func (s *DigestService) load(ctx context.Context, id string) (entry, error) {
+	if !validID(id) {
+		return entry{}, ErrSubscriptionNotFound
+	}
+	value, found, err := s.store.Get(ctx, subscriptionKey(id))
+	if err != nil {
+		return entry{}, fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	if !found {
+		return entry{}, ErrSubscriptionNotFound
+	}
+
+	var subscribed entry
+	if err := json.Unmarshal(value, &subscribed); err != nil {
+		return entry{}, fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	return subscribed, nil
+}
+
+func (s *DigestService) delete(ctx context.Context, id string) error {
+	if err := s.store.Delete(ctx, subscriptionKey(id)); err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreSubscription, err)
+	}
+	return nil
+}
+
+// subscriptionKey names the item holding a subscription. Subscriptions of
+// every tenant share the prefix, so that the schedule finds them all.
+func subscriptionKey(id string) string {
+	return subscriptionPrefix + id
+}
+
+func randomHex() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validID(id string) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == 16
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
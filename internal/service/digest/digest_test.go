@@ -0,0 +1,244 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/ses"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+var now = time.Date(2025, 10, 13, 9, 0, 0, 0, time.UTC)
+
+type MockSource struct {
+	Kept []votes.Saved
+}
+
+func (m *MockSource) Each(ctx context.Context, fn func(saved votes.Saved) error) error {
+	for _, saved := range m.Kept {
+		if err := fn(saved); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type MockSender struct {
+	ErrorToReturn error
+	Sent          []ses.Message
+}
+
+func (m *MockSender) Send(ctx context.Context, message ses.Message) error {
+	if m.ErrorToReturn != nil {
+		return m.ErrorToReturn
+	}
+	m.Sent = append(m.Sent, message)
+	return nil
+}
+
+func kept(id string, tenant string, repository string, age time.Duration, status votes.Status) votes.Saved {
+	return votes.Saved{
+		ID:     id,
+		Stored: haiku.Stored{Haiku: "haiku " + id, Tenant: tenant, Repository: repository, CreatedAt: now.Add(-age)},
+		Status: status,
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	tests := []struct {
+		name    string
+		request SubscribeRequest
+		errorIs error
+	}{
+		{
+			name:    "Team",
+			request: SubscribeRequest{Email: "mona@example.com"},
+		},
+		{
+			name:    "Repository",
+			request: SubscribeRequest{Email: "mona@example.com", Repository: "octo/leaves"},
+		},
+		{
+			name:    "Not an address",
+			request: SubscribeRequest{Email: "mona"},
+			errorIs: ErrBadSubscription,
+		},
+		{
+			name:    "Display name",
+			request: SubscribeRequest{Email: "Mona <mona@example.com>"},
+			errorIs: ErrBadSubscription,
+		},
+		{
+			name:    "Control characters",
+			request: SubscribeRequest{Email: "mona@example.com", Repository: "octo/leaves\nBcc: eve@example.com"},
+			errorIs: ErrBadSubscription,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewDigestService(NewMemoryStore(), &MockSource{}, &MockSender{}, nil)
+			service.now = func() time.Time { return now }
+			ctx := keys.NewTenantContext(context.Background(), "acme")
+
+			subscription, err := service.Subscribe(ctx, tc.request)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if subscription.Tenant != "acme" || subscription.Email != tc.request.Email || subscription.Repository != tc.request.Repository || !validID(subscription.ID) {
+				t.Errorf("Expected a subscription for %+v, got %+v", tc.request, subscription)
+			}
+			listed, err := service.List(ctx)
+			if err != nil || len(listed) != 1 || listed[0] != subscription {
+				t.Errorf("Expected the subscription listed, got %+v, %v", listed, err)
+			}
+			if listed, _ := service.List(context.Background()); len(listed) != 0 {
+				t.Errorf("Expected no subscriptions for another tenant, got %+v", listed)
+			}
+		})
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewDigestService(store, &MockSource{}, &MockSender{}, nil)
+	ctx := keys.NewTenantContext(context.Background(), "acme")
+
+	subscription, err := service.Subscribe(ctx, SubscribeRequest{Email: "mona@example.com"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	subscribed, _ := service.load(ctx, subscription.ID)
+
+	if err := service.Unsubscribe(context.Background(), subscription.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Expected another tenant's subscription to be not found, got %v", err)
+	}
+	if err := service.UnsubscribeWithToken(context.Background(), subscription.ID, "wrong"); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Expected a wrong token to be refused, got %v", err)
+	}
+	if err := service.UnsubscribeWithToken(context.Background(), subscription.ID, subscribed.Token); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := service.Unsubscribe(ctx, subscription.ID); !errors.Is(err, ErrSubscriptionNotFound) {
+		t.Errorf("Expected the subscription removed, got %v", err)
+	}
+}
+
+func TestSendWeekly(t *testing.T) {
+	source := &MockSource{Kept: []votes.Saved{
+		kept("a1", "acme", "octo/leaves", time.Hour, votes.StatusPublished),
+		kept("a2", "acme", "octo/roots", 2*24*time.Hour, votes.StatusPublished),
+		kept("a3", "acme", "octo/leaves", 3*time.Hour, votes.StatusUnpublished),
+		kept("a4", "acme", "octo/leaves", 8*24*time.Hour, votes.StatusPublished),
+		kept("b1", "", "octo/leaves", time.Hour, votes.StatusPublished),
+	}}
+	store := NewMemoryStore()
+	sender := &MockSender{}
+	service := NewDigestService(store, source, sender, &Options{PublicURL: "https://haiku.example.com/"})
+	service.now = func() time.Time { return now }
+	store.SetClock(service.now)
+
+	ctx := keys.NewTenantContext(context.Background(), "acme")
+	for _, request := range []SubscribeRequest{
+		{Email: "team@example.com"},
+		{Email: "leaves@example.com", Repository: "Octo/Leaves"},
+		{Email: "quiet@example.com", Repository: "octo/quiet"},
+	} {
+		if _, err := service.Subscribe(ctx, request); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	run, err := service.SendWeekly(context.Background())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if run.Sent != 2 || run.Skipped != 1 {
+		t.Errorf("Expected 2 digests sent and 1 skipped, got %+v", run)
+	}
+
+	sent := map[string]string{}
+	for _, message := range sender.Sent {
+		sent[message.To] = message.Text
+		if !strings.HasPrefix(message.UnsubscribeURL, "https://haiku.example.com/digests/") || !strings.Contains(message.HTML, "Unsubscribe") {
+			t.Errorf("Expected an unsubscribe link, got %q", message.UnsubscribeURL)
+		}
+	}
+	expected := map[string][]string{
+		"team@example.com":   {"haiku a1", "haiku a2"},
+		"leaves@example.com": {"haiku a1"},
+	}
+	for to, included := range expected {
+		text, ok := sent[to]
+		if !ok {
+			t.Errorf("Expected a digest sent to %s", to)
+			continue
+		}
+		for _, id := range []string{"a1", "a2", "a3", "a4", "b1"} {
+			want := strings.Contains(fmt.Sprint(included), "haiku "+id)
+			if got := strings.Contains(text, "haiku "+id); got != want {
+				t.Errorf("Expected haiku %s in the digest to %s: %v, got %q", id, to, want, text)
+			}
+		}
+	}
+
+	// The schedule retried sends nothing twice.
+	run, err = service.SendWeekly(context.Background())
+	if err != nil || run.Sent != 0 || len(sender.Sent) != 2 {
+		t.Errorf("Expected no digests sent again, got %+v, %v", run, err)
+	}
+}
+
+func TestSendWeeklyFailureIsRetried(t *testing.T) {
+	source := &MockSource{Kept: []votes.Saved{kept("b1", "", "octo/leaves", time.Hour, votes.StatusPublished)}}
+	sender := &MockSender{ErrorToReturn: ses.ErrSend}
+	service := NewDigestService(NewMemoryStore(), source, sender, nil)
+	service.now = func() time.Time { return now }
+
+	if _, err := service.Subscribe(context.Background(), SubscribeRequest{Email: "mona@example.com"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.SendWeekly(context.Background()); !errors.Is(err, ErrSendDigest) || !errors.Is(err, ses.ErrSend) {
+		t.Fatalf("Expected the send error, got %v", err)
+	}
+
+	sender.ErrorToReturn = nil
+	run, err := service.SendWeekly(context.Background())
+	if err != nil || run.Sent != 1 {
+		t.Errorf("Expected the digest sent on retry, got %+v, %v", run, err)
+	}
+	if !strings.Contains(sender.Sent[0].Text, "Ask your administrator") {
+		t.Errorf("Expected no unsubscribe link without a public URL, got %q", sender.Sent[0].Text)
+	}
+}
+
+func TestRender(t *testing.T) {
+	var haiku []votes.Saved
+	for i := range MaxHaiku + 2 {
+		haiku = append(haiku, kept(fmt.Sprint(i), "", "<script>octo</script>", time.Hour, votes.StatusPublished))
+	}
+
+	message, err := render(Subscription{Email: "mona@example.com"}, "", Run{To: now}, haiku)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if message.Subject != "Commit haiku for your team, the week to October 13" {
+		t.Errorf("Expected the team's digest, got subject %q", message.Subject)
+	}
+	if strings.Contains(message.HTML, "<script>") {
+		t.Errorf("Expected repositories escaped in HTML, got %q", message.HTML)
+	}
+	if !strings.Contains(message.Text, "and 2 more") || strings.Contains(message.Text, fmt.Sprintf("haiku %d\n", MaxHaiku)) {
+		t.Errorf("Expected %d haiku listed and the rest counted, got %q", MaxHaiku, message.Text)
+	}
+}
@@ -0,0 +1,11 @@
+package digest
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps subscriptions, and which digests were sent, in memory for
+// trying digests out locally. Subscriptions are lost when the server exits.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
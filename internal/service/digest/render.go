@@ -0,0 +1,71 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/ses"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+// digestData is what the digest templates are rendered from.
+type digestData struct {
+	Title          string
+	Haiku          []votes.Saved
+	More           int // Haiku over MaxHaiku, counted rather than listed
+	UnsubscribeURL string
+}
+
+var textTemplate = texttemplate.Must(texttemplate.New("text").Parse(`{{.Title}}
+{{range .Haiku}}
+{{.Haiku}}
+  — {{with .Repository}}{{.}}, {{end}}{{.CreatedAt.Format "Mon Jan 2"}}
+{{end}}{{if .More}}
+…and {{.More}} more.
+{{end}}
+{{if .UnsubscribeURL}}Unsubscribe: {{.UnsubscribeURL}}{{else}}Ask your administrator to unsubscribe you.{{end}}
+`))
+
+var htmlTemplate = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html>
+<body style="font-family: Georgia, serif; color: #333; max-width: 36em; margin: 0 auto;">
+<h1 style="font-size: 1.4em; font-weight: normal;">{{.Title}}</h1>
+{{range .Haiku}}<blockquote style="margin: 1.5em 0; white-space: pre-line;">{{.Haiku}}
+<small style="color: #888;">— {{with .Repository}}{{.}}, {{end}}{{.CreatedAt.Format "Mon Jan 2"}}</small></blockquote>
+{{end}}{{if .More}}<p>…and {{.More}} more.</p>
+{{end}}<p style="font-size: 0.8em; color: #888;">{{if .UnsubscribeURL}}<a href="{{.UnsubscribeURL}}">Unsubscribe</a>{{else}}Ask your administrator to unsubscribe you.{{end}}</p>
+</body>
+</html>
+`))
+
+// render writes the digest of haiku, newest first, for subscription.
+func render(subscription Subscription, unsubscribeURL string, run Run, haiku []votes.Saved) (ses.Message, error) {
+	scope := "your team"
+	if subscription.Repository != "" {
+		scope = subscription.Repository
+	}
+	data := digestData{
+		Title:          fmt.Sprintf("Commit haiku for %s, the week to %s", scope, run.To.Format("January 2")),
+		Haiku:          haiku[:min(len(haiku), MaxHaiku)],
+		More:           max(len(haiku)-MaxHaiku, 0),
+		UnsubscribeURL: unsubscribeURL,
+	}
+
+	var text, html bytes.Buffer
+	if err := textTemplate.Execute(&text, data); err != nil {
+		return ses.Message{}, fmt.Errorf("rendering digest: %w", err)
+	}
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return ses.Message{}, fmt.Errorf("rendering digest: %w", err)
+	}
+
+	return ses.Message{
+		To:             subscription.Email,
+		Subject:        data.Title,
+		Text:           text.String(),
+		HTML:           html.String(),
+		UnsubscribeURL: unsubscribeURL,
+	}, nil
+}
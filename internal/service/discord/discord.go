@@ -0,0 +1,157 @@
+// Package discord answers the /haiku application command. Discord waits only
+// three seconds for an interaction response, so commands are deferred and the
+// haiku replaces the "thinking" message once it is ready.
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const DefaultBaseURL = "https://discord.com/api/v10"
+
+var (
+	ErrBadInteraction = errors.New("bad discord interaction received")
+	ErrRespond        = errors.New("error posting discord response")
+)
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Interaction is a verified /haiku command. Its token authorizes editing the
+// deferred response for fifteen minutes. It is serialized when the response is
+// deferred to another invocation.
+type Interaction struct {
+	ApplicationID string `json:"applicationId"`
+	Token         string `json:"token"`
+	Text          string `json:"text"`
+	UserID        string `json:"userId,omitempty"`
+}
+
+// Validate checks that the interaction has text and can be responded to.
+func (i Interaction) Validate() error {
+	if strings.TrimSpace(i.Text) == "" {
+		return fmt.Errorf("%w: text is required", ErrBadInteraction)
+	}
+	if i.ApplicationID == "" || i.Token == "" {
+		return fmt.Errorf("%w: application id and token are required", ErrBadInteraction)
+	}
+	return nil
+}
+
+type DiscordService struct {
+	haikuService HaikuService
+	httpClient   HTTPClient
+	baseURL      string
+}
+
+func NewDiscordService(haikuService HaikuService, httpClient HTTPClient, baseURL string) *DiscordService {
+	return &DiscordService{
+		haikuService: haikuService,
+		httpClient:   httpClient,
+		baseURL:      strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func NewDefaultDiscordService(haikuService HaikuService) *DiscordService {
+	return NewDiscordService(haikuService, &http.Client{Timeout: 10 * time.Second}, DefaultBaseURL)
+}
+
+// Respond generates a haiku for the interaction and replaces its deferred
+// response with it. When generation fails the response explains why and the
+// generation error is returned.
+func (s *DiscordService) Respond(ctx context.Context, interaction Interaction) error {
+	if err := interaction.Validate(); err != nil {
+		logging.Warnf("[DISCORD SERVICE] %v\n", err)
+		return err
+	}
+
+	response, err := s.haikuService.CreateHaiku(ctx, haiku.HaikuCommitRequest{
+		CommitMessage: interaction.Text,
+	})
+	if err != nil {
+		logging.Errorf("[DISCORD SERVICE] error generating haiku: %v\n", err)
+		return errors.Join(err, s.editResponse(ctx, interaction, errorMessage(err)))
+	}
+
+	return s.editResponse(ctx, interaction, FormatMessage(response.Haiku, interaction))
+}
+
+// editResponse replaces the deferred response. Mentions are never parsed, so
+// a haiku or commit message cannot ping anyone.
+func (s *DiscordService) editResponse(ctx context.Context, interaction Interaction, content string) error {
+	payload, err := json.Marshal(map[string]any{
+		"content":          content,
+		"allowed_mentions": map[string][]string{"parse": {}},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: encoding message: %v", ErrRespond, err)
+	}
+
+	path := fmt.Sprintf("/webhooks/%s/%s/messages/@original", url.PathEscape(interaction.ApplicationID), url.PathEscape(interaction.Token))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, s.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRespond, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[DISCORD SERVICE] error editing response: %v\n", err)
+		return fmt.Errorf("%w: %v", ErrRespond, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[DISCORD SERVICE] editing response returned %d\n", resp.StatusCode)
+		return fmt.Errorf("%w: editing response returned %d: %s", ErrRespond, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
+
+func errorMessage(err error) string {
+	if errors.Is(err, haiku.ErrContentBlocked) {
+		return "Sorry, that haiku was blocked by the content filter."
+	}
+	if errors.Is(err, haiku.ErrBadHaikuRequest) {
+		return "Sorry, a haiku cannot be written for that message."
+	}
+	return "Sorry, the haiku could not be written right now. Please try again."
+}
+
+// FormatMessage renders a haiku as a Discord quote, followed by who asked and
+// for which message.
+func FormatMessage(text string, interaction Interaction) string {
+	var message strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		message.WriteString("> ")
+		message.WriteString(strings.TrimSpace(line))
+		message.WriteString("\n")
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(interaction.Text), "\n")
+	if interaction.UserID != "" {
+		fmt.Fprintf(&message, "🍂 <@%s>: %s", interaction.UserID, subject)
+	} else {
+		fmt.Fprintf(&message, "🍂 %s", subject)
+	}
+	return message.String()
+}
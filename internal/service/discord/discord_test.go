@@ -0,0 +1,114 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+func TestRespond(t *testing.T) {
+	interaction := Interaction{ApplicationID: "1234", Token: "tok/en", Text: "fix flaky test", UserID: "42"}
+
+	tests := []struct {
+		name            string
+		interaction     Interaction
+		haikuError      error
+		status          int
+		expectedContent string
+		errorIs         error
+	}{
+		{
+			name:            "Haiku replaces deferred response",
+			interaction:     interaction,
+			status:          http.StatusOK,
+			expectedContent: FormatMessage(testHaiku, interaction),
+		},
+		{
+			name:            "Blocked haiku explained",
+			interaction:     interaction,
+			haikuError:      haiku.ErrContentBlocked,
+			status:          http.StatusOK,
+			expectedContent: "Sorry, that haiku was blocked by the content filter.",
+			errorIs:         haiku.ErrContentBlocked,
+		},
+		{
+			name:        "Expired token",
+			interaction: interaction,
+			status:      http.StatusNotFound,
+			errorIs:     ErrRespond,
+		},
+		{
+			name:        "Missing token",
+			interaction: Interaction{ApplicationID: "1234", Text: "fix flaky test"},
+			errorIs:     ErrBadInteraction,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var method, path string
+			var body struct {
+				Content         string              `json:"content"`
+				AllowedMentions map[string][]string `json:"allowed_mentions"`
+			}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method = r.Method
+				path = r.URL.EscapedPath()
+				_ = json.NewDecoder(r.Body).Decode(&body)
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			service := NewDiscordService(&MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku},
+				ErrorToReturn:    tc.haikuError,
+			}, server.Client(), server.URL+"/api/v10/")
+			err := service.Respond(context.Background(), tc.interaction)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if tc.expectedContent == "" {
+				return
+			}
+			if method != http.MethodPatch || path != "/api/v10/webhooks/1234/tok%2Fen/messages/@original" {
+				t.Errorf("Expected the original response to be edited, got %s %s", method, path)
+			}
+			if body.Content != tc.expectedContent {
+				t.Errorf("Expected content %q, got %q", tc.expectedContent, body.Content)
+			}
+			if parse, ok := body.AllowedMentions["parse"]; !ok || len(parse) != 0 {
+				t.Errorf("Expected mentions to be disabled, got %v", body.AllowedMentions)
+			}
+		})
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	expected := "> Old cracks mended now\n> the login door swings open\n> quiet in the logs\n🍂 <@42>: fix flaky test"
+	if got := FormatMessage(testHaiku, Interaction{Text: "fix flaky test\n\nbody", UserID: "42"}); got != expected {
+		t.Errorf("Expected message %q, got %q", expected, got)
+	}
+}
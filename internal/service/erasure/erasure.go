@@ -0,0 +1,250 @@
+// Package erasure removes every kept haiku written for a commit author, along
+// with the cached responses and exported files holding them and whatever else
+// credits the author, and records an audit trail of each erasure.
+package erasure
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/export"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+const (
+	// DefaultAuditRetention is how long audit records are kept, unless
+	// configured otherwise.
+	DefaultAuditRetention = 3 * 365 * 24 * time.Hour
+
+	dayFormat = "2006-01-02"
+)
+
+var (
+	ErrErase = errors.New("error erasing haiku")
+	ErrAudit = errors.New("error recording erasure")
+)
+
+// Haiku lists and deletes kept haiku, e.g. the vote service.
+type Haiku interface {
+	Each(ctx context.Context, fn func(saved votes.Saved) error) error
+	Delete(ctx context.Context, id string) error
+}
+
+// Cache removes cached responses, e.g. the response cache.
+type Cache interface {
+	Remove(ctx context.Context, key string) error
+}
+
+// Exporter rewrites a day's export from the haiku still kept, e.g. the export
+// service.
+type Exporter interface {
+	Export(ctx context.Context, day time.Time) (export.Export, error)
+}
+
+// Eraser removes what else is kept crediting an author, e.g. the renga,
+// shadow or stats service, and returns how many items it changed. Only the
+// context's tenant's items are changed, unless keys.EveryTenant holds for it.
+type Eraser interface {
+	EraseAuthor(ctx context.Context, author string) (int, error)
+}
+
+// AuditLog keeps audit records, e.g. in DynamoDB.
+type AuditLog interface {
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Erasure is the audit record of one erasure. It names the author by hash
+// only, so that the record doesn't keep what was erased.
+type Erasure struct {
+	ID           string    `json:"id"`
+	Author       string    `json:"author"`                 // SHA-256 of the author, hex encoded
+	RequestedBy  string    `json:"requestedBy"`            // Who asked, e.g. an admin key ID
+	Tenant       string    `json:"tenant,omitempty"`       // Tenant whose haiku were erased, empty for the default tenant
+	AllTenants   bool      `json:"allTenants,omitempty"`   // Whether every tenant's haiku were erased, as only the operator can
+	RequestedAt  time.Time `json:"requestedAt"`            // When the erasure ran
+	Haiku        int       `json:"haiku"`                  // Kept haiku deleted
+	CacheEntries int       `json:"cacheEntries"`           // Cached responses removed
+	RengaVerses  int       `json:"rengaVerses"`            // Renga verses emptied
+	ShadowRuns   int       `json:"shadowRuns"`             // Shadow results removed
+	StatsDays    int       `json:"statsDays"`              // Days whose statistics no longer name the author
+	ExportDays   []string  `json:"exportDays,omitempty"`   // Days whose export was rewritten, as YYYY-MM-DD
+	ExportErrors []string  `json:"exportErrors,omitempty"` // Days whose export couldn't be rewritten and should be exported again
+}
+
+type ErasureService struct {
+	haiku     Haiku
+	audit     AuditLog
+	cache     Cache
+	exporter  Exporter
+	renga     Eraser
+	shadow    Eraser
+	stats     Eraser
+	retention time.Duration
+	now       func() time.Time
+}
+
+type Options struct {
+	Cache          Cache         // Removes cached responses holding erased haiku (default: none)
+	Exporter       Exporter      // Rewrites exports holding erased haiku (default: none)
+	Renga          Eraser        // Empties renga verses credited to the author (default: none)
+	Shadow         Eraser        // Removes shadow results of the author's commits (default: none)
+	Stats          Eraser        // Removes the author's statistics (default: none)
+	AuditRetention time.Duration // How long audit records are kept (default: 3 years)
+}
+
+func NewErasureService(haiku Haiku, audit AuditLog, opts *Options) *ErasureService {
+	service := &ErasureService{
+		haiku:     haiku,
+		audit:     audit,
+		retention: DefaultAuditRetention,
+		now:       time.Now,
+	}
+
+	if opts != nil {
+		service.cache = opts.Cache
+		service.exporter = opts.Exporter
+		service.renga = opts.Renga
+		service.shadow = opts.Shadow
+		service.stats = opts.Stats
+		if opts.AuditRetention > 0 {
+			service.retention = opts.AuditRetention
+		}
+	}
+
+	return service
+}
+
+// Erase deletes every kept haiku written for author, matched exactly, and
+// records who asked for it. Only the haiku of the context's tenant are
+// erased, unless the operator asks outside any tenant, when every tenant's
+// are. Cached responses, and the renga
+// verses, shadow results and statistics crediting the author, are removed
+// before the haiku are deleted, so that a failed erasure can be retried until
+// it succeeds.
+// Exports are rewritten once the haiku are gone; a day that can't be is
+// named in the audit record rather than failing an erasure that has already
+// deleted the haiku.
+func (s *ErasureService) Erase(ctx context.Context, author string, requestedBy string) (Erasure, error) {
+	if author == "" {
+		return Erasure{}, fmt.Errorf("%w: no author", ErrErase)
+	}
+
+	tenant, every := keys.TenantFromContext(ctx), keys.EveryTenant(ctx)
+	var found []votes.Saved
+	err := s.haiku.Each(ctx, func(saved votes.Saved) error {
+		if saved.Author == author && (every || saved.Tenant == tenant) {
+			found = append(found, saved)
+		}
+		return nil
+	})
+	if err != nil {
+		return Erasure{}, fmt.Errorf("%w: %w", ErrErase, err)
+	}
+
+	id, err := newErasureID()
+	if err != nil {
+		return Erasure{}, fmt.Errorf("%w: %w", ErrAudit, err)
+	}
+	hash := sha256.Sum256([]byte(author))
+	erasure := Erasure{
+		ID:          id,
+		Author:      hex.EncodeToString(hash[:]),
+		RequestedBy: requestedBy,
+		Tenant:      tenant,
+		AllTenants:  every,
+		RequestedAt: s.now().UTC(),
+	}
+
+	// Equivalent requests share a cached response, so several haiku may
+	// name the same entry.
+	var cacheKeys, days []string
+	for _, saved := range found {
+		if saved.CacheKey != "" && !slices.Contains(cacheKeys, saved.CacheKey) {
+			cacheKeys = append(cacheKeys, saved.CacheKey)
+		}
+		if day := saved.CreatedAt.UTC().Format(dayFormat); !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	if s.cache != nil {
+		for _, key := range cacheKeys {
+			if err := s.cache.Remove(ctx, key); err != nil {
+				return Erasure{}, fmt.Errorf("%w: removing cached response: %w", ErrErase, err)
+			}
+			erasure.CacheEntries++
+		}
+	}
+
+	for _, hook := range []struct {
+		name   string
+		eraser Eraser
+		erased *int
+	}{
+		{"renga verses", s.renga, &erasure.RengaVerses},
+		{"shadow results", s.shadow, &erasure.ShadowRuns},
+		{"statistics", s.stats, &erasure.StatsDays},
+	} {
+		if hook.eraser == nil {
+			continue
+		}
+		erased, err := hook.eraser.EraseAuthor(ctx, author)
+		if err != nil {
+			return Erasure{}, fmt.Errorf("%w: erasing %s: %w", ErrErase, hook.name, err)
+		}
+		*hook.erased = erased
+	}
+
+	for _, saved := range found {
+		if err := s.haiku.Delete(ctx, saved.ID); err != nil {
+			return Erasure{}, fmt.Errorf("%w: %w", ErrErase, err)
+		}
+		erasure.Haiku++
+	}
+
+	if s.exporter != nil {
+		slices.Sort(days)
+		for _, day := range days {
+			parsed, _ := time.Parse(dayFormat, day)
+			if _, err := s.exporter.Export(ctx, parsed); err != nil {
+				logging.Errorf("[ERASURE SERVICE] error rewriting the export of %s for erasure %s: %v\n", day, erasure.ID, err)
+				erasure.ExportErrors = append(erasure.ExportErrors, day)
+				continue
+			}
+			erasure.ExportDays = append(erasure.ExportDays, day)
+		}
+	}
+
+	if err := s.record(ctx, erasure); err != nil {
+		return Erasure{}, err
+	}
+	logging.Infof("[ERASURE SERVICE] erasure %s deleted %d haiku and %d cached responses for %s\n", erasure.ID, erasure.Haiku, erasure.CacheEntries, erasure.RequestedBy)
+	return erasure, nil
+}
+
+func (s *ErasureService) record(ctx context.Context, erasure Erasure) error {
+	value, err := json.Marshal(erasure)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrAudit, err)
+	}
+	if err := s.audit.Put(ctx, "erasure:"+erasure.ID, value, s.retention); err != nil {
+		return fmt.Errorf("%w: %w", ErrAudit, err)
+	}
+	return nil
+}
+
+func newErasureID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
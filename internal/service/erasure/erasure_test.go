@@ -0,0 +1,322 @@
+package erasure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/export"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+type MockHaiku struct {
+	Saved         []votes.Saved
+	ErrorToReturn error
+	Deleted       []string
+}
+
+func (m *MockHaiku) Each(ctx context.Context, fn func(saved votes.Saved) error) error {
+	for _, saved := range m.Saved {
+		if slices.Contains(m.Deleted, saved.ID) {
+			continue
+		}
+		if err := fn(saved); err != nil {
+			return err
+		}
+	}
+	return m.ErrorToReturn
+}
+
+func (m *MockHaiku) Delete(ctx context.Context, id string) error {
+	m.Deleted = append(m.Deleted, id)
+	return nil
+}
+
+type MockCache struct {
+	ErrorToReturn error
+	Removed       []string
+}
+
+func (m *MockCache) Remove(ctx context.Context, key string) error {
+	if m.ErrorToReturn != nil {
+		return m.ErrorToReturn
+	}
+	m.Removed = append(m.Removed, key)
+	return nil
+}
+
+type MockExporter struct {
+	ErrorToReturn error
+	Exported      []string
+}
+
+func (m *MockExporter) Export(ctx context.Context, day time.Time) (export.Export, error) {
+	m.Exported = append(m.Exported, day.Format(dayFormat))
+	return export.Export{}, m.ErrorToReturn
+}
+
+type MockEraser struct {
+	Erased        int
+	ErrorToReturn error
+	Authors       []string
+}
+
+func (m *MockEraser) EraseAuthor(ctx context.Context, author string) (int, error) {
+	m.Authors = append(m.Authors, author)
+	return m.Erased, m.ErrorToReturn
+}
+
+type MockAuditLog struct {
+	ErrorToReturn error
+	Records       map[string][]byte
+	LastTTL       time.Duration
+}
+
+func (m *MockAuditLog) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if m.ErrorToReturn != nil {
+		return m.ErrorToReturn
+	}
+	if m.Records == nil {
+		m.Records = make(map[string][]byte)
+	}
+	m.Records[key] = value
+	m.LastTTL = ttl
+	return nil
+}
+
+func TestErase(t *testing.T) {
+	day := time.Date(2025, 10, 9, 14, 0, 0, 0, time.UTC)
+	saved := func(id string, author string, cacheKey string, createdAt time.Time) votes.Saved {
+		return votes.Saved{ID: id, Stored: haiku.Stored{Haiku: "leaves " + id, Author: author, CacheKey: cacheKey, CreatedAt: createdAt}}
+	}
+	kept := []votes.Saved{
+		saved("a", "Mona", "cache-1", day),
+		saved("b", "Mona", "cache-1", day.Add(time.Hour)),
+		saved("c", "Hubot", "cache-2", day),
+		saved("d", "Mona", "", day.AddDate(0, 0, -3)),
+		saved("e", "mona", "cache-3", day),
+	}
+
+	tests := []struct {
+		name            string
+		author          string
+		haiku           *MockHaiku
+		cacheError      error
+		exportError     error
+		auditError      error
+		expected        Erasure
+		expectedDeleted []string
+		expectedRemoved []string
+		errorIs         error
+	}{
+		{
+			name:            "Erased",
+			author:          "Mona",
+			haiku:           &MockHaiku{Saved: kept},
+			expected:        Erasure{Haiku: 3, CacheEntries: 1, ExportDays: []string{"2025-10-06", "2025-10-09"}},
+			expectedDeleted: []string{"a", "b", "d"},
+			expectedRemoved: []string{"cache-1"},
+		},
+		{
+			name:     "No haiku for the author",
+			author:   "Octocat",
+			haiku:    &MockHaiku{Saved: kept},
+			expected: Erasure{},
+		},
+		{
+			name:            "Export fails",
+			author:          "Hubot",
+			haiku:           &MockHaiku{Saved: kept},
+			exportError:     errors.New("access denied"),
+			expected:        Erasure{Haiku: 1, CacheEntries: 1, ExportErrors: []string{"2025-10-09"}},
+			expectedDeleted: []string{"c"},
+			expectedRemoved: []string{"cache-2"},
+		},
+		{
+			name:       "Cache fails before anything is deleted",
+			author:     "Mona",
+			haiku:      &MockHaiku{Saved: kept},
+			cacheError: errors.New("table not found"),
+			errorIs:    ErrErase,
+		},
+		{
+			name:    "Haiku can't be listed",
+			author:  "Mona",
+			haiku:   &MockHaiku{ErrorToReturn: errors.New("throttled")},
+			errorIs: ErrErase,
+		},
+		{
+			name:            "Audit fails",
+			author:          "Hubot",
+			haiku:           &MockHaiku{Saved: kept},
+			auditError:      errors.New("table not found"),
+			expectedDeleted: []string{"c"},
+			expectedRemoved: []string{"cache-2"},
+			errorIs:         ErrAudit,
+		},
+		{
+			name:    "No author",
+			haiku:   &MockHaiku{Saved: kept},
+			errorIs: ErrErase,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := &MockCache{ErrorToReturn: tc.cacheError}
+			exporter := &MockExporter{ErrorToReturn: tc.exportError}
+			audit := &MockAuditLog{ErrorToReturn: tc.auditError}
+			service := NewErasureService(tc.haiku, audit, &Options{Cache: cache, Exporter: exporter})
+			service.now = func() time.Time { return day.AddDate(0, 0, 1) }
+
+			erasure, err := service.Erase(context.Background(), tc.author, "admin-key")
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if !slices.Equal(tc.haiku.Deleted, tc.expectedDeleted) {
+				t.Errorf("Expected %v deleted, got %v", tc.expectedDeleted, tc.haiku.Deleted)
+			}
+			if !slices.Equal(cache.Removed, tc.expectedRemoved) {
+				t.Errorf("Expected cached responses %v removed, got %v", tc.expectedRemoved, cache.Removed)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if erasure.Haiku != tc.expected.Haiku || erasure.CacheEntries != tc.expected.CacheEntries ||
+				!slices.Equal(erasure.ExportDays, tc.expected.ExportDays) || !slices.Equal(erasure.ExportErrors, tc.expected.ExportErrors) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, erasure)
+			}
+			if erasure.RequestedBy != "admin-key" || !erasure.RequestedAt.Equal(day.AddDate(0, 0, 1)) {
+				t.Errorf("Expected the erasure requested by admin-key a day later, got %+v", erasure)
+			}
+
+			record, ok := audit.Records["erasure:"+erasure.ID]
+			if !ok {
+				t.Fatalf("Expected an audit record for %s, got %v", erasure.ID, audit.Records)
+			}
+			if audit.LastTTL != DefaultAuditRetention {
+				t.Errorf("Expected the record kept for %v, got %v", DefaultAuditRetention, audit.LastTTL)
+			}
+			if strings.Contains(string(record), tc.author) {
+				t.Errorf("Expected the record not to name the author, got %s", record)
+			}
+			var recorded Erasure
+			if err := json.Unmarshal(record, &recorded); err != nil || recorded.Author != erasure.Author || len(recorded.Author) != 64 {
+				t.Errorf("Expected the record to hold the hashed author, got %s: %v", record, err)
+			}
+		})
+	}
+}
+
+func TestEraseHooks(t *testing.T) {
+	kept := []votes.Saved{{ID: "a", Stored: haiku.Stored{Author: "Mona"}}}
+
+	tests := []struct {
+		name            string
+		statsError      error
+		expected        Erasure
+		expectedDeleted []string
+		errorIs         error
+	}{
+		{
+			name:            "Erased everywhere",
+			expected:        Erasure{Haiku: 1, RengaVerses: 2, ShadowRuns: 1, StatsDays: 3},
+			expectedDeleted: []string{"a"},
+		},
+		{
+			name:       "Statistics fail before the haiku are deleted",
+			statsError: errors.New("throttled"),
+			errorIs:    ErrErase,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaiku{Saved: kept}
+			renga := &MockEraser{Erased: 2}
+			shadow := &MockEraser{Erased: 1}
+			stats := &MockEraser{Erased: 3, ErrorToReturn: tc.statsError}
+			service := NewErasureService(haikuService, &MockAuditLog{}, &Options{Renga: renga, Shadow: shadow, Stats: stats})
+
+			erasure, err := service.Erase(context.Background(), "Mona", "admin-key")
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if !slices.Equal(haikuService.Deleted, tc.expectedDeleted) {
+				t.Errorf("Expected %v deleted, got %v", tc.expectedDeleted, haikuService.Deleted)
+			}
+			for _, eraser := range []*MockEraser{renga, shadow, stats} {
+				if !slices.Equal(eraser.Authors, []string{"Mona"}) {
+					t.Errorf("Expected Mona erased once, got %v", eraser.Authors)
+				}
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if erasure.Haiku != tc.expected.Haiku || erasure.RengaVerses != tc.expected.RengaVerses ||
+				erasure.ShadowRuns != tc.expected.ShadowRuns || erasure.StatsDays != tc.expected.StatsDays {
+				t.Errorf("Expected %+v, got %+v", tc.expected, erasure)
+			}
+		})
+	}
+}
+
+func TestEraseInTenant(t *testing.T) {
+	saved := []votes.Saved{
+		{ID: "a", Stored: haiku.Stored{Author: "Mona", Tenant: "acme"}},
+		{ID: "b", Stored: haiku.Stored{Author: "Mona", Tenant: "globex"}},
+		{ID: "c", Stored: haiku.Stored{Author: "Mona"}},
+	}
+
+	tests := []struct {
+		name            string
+		ctx             context.Context
+		expectedDeleted []string
+		expectedTenant  string
+		expectedAll     bool
+	}{
+		{
+			name:            "Tenant's admin key",
+			ctx:             keys.NewTenantContext(context.Background(), "acme"),
+			expectedDeleted: []string{"a"},
+			expectedTenant:  "acme",
+		},
+		{
+			name:            "Default tenant's admin key",
+			ctx:             keys.NewTenantContext(context.Background(), ""),
+			expectedDeleted: []string{"c"},
+		},
+		{
+			name:            "Operator",
+			ctx:             keys.NewOperatorContext(context.Background()),
+			expectedDeleted: []string{"a", "b", "c"},
+			expectedAll:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaiku{Saved: saved}
+			service := NewErasureService(haikuService, &MockAuditLog{}, nil)
+
+			erasure, err := service.Erase(tc.ctx, "Mona", "key:admin")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !slices.Equal(haikuService.Deleted, tc.expectedDeleted) {
+				t.Errorf("Expected %v deleted, got %v", tc.expectedDeleted, haikuService.Deleted)
+			}
+			if erasure.Tenant != tc.expectedTenant || erasure.AllTenants != tc.expectedAll || erasure.Haiku != len(tc.expectedDeleted) {
+				t.Errorf("Expected %d haiku erased in tenant %q, every tenant %v, got %+v", len(tc.expectedDeleted), tc.expectedTenant, tc.expectedAll, erasure)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// Package events publishes domain events, such as each haiku newly written by
+// the model or a key using up its quota, so that other services can react to
+// them without polling. Each event is handed to every configured publisher,
+// e.g. an SNS topic and an EventBridge bus.
+package events
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+)
+
+// Types of the events published.
+const (
+	TypeHaikuCreated  = "haiku.created"
+	TypeHaikuFailed   = "haiku.failed"
+	TypeQuotaExceeded = "quota.exceeded"
+)
+
+// Source is the source of every event, as EventBridge rules match it.
+const Source = "commits-fall-like-leaves"
+
+// publishTimeout bounds how long a request waits for its event to be
+// published.
+const publishTimeout = 2 * time.Second
+
+// Event is a domain event, as handed to publishers.
+type Event struct {
+	Type       string            // One of the Type* types
+	Time       time.Time         // When the event happened
+	Detail     any               // The event's detail, encoded as a JSON object, e.g. a haiku.HaikuEvent
+	Attributes map[string]string // Fields of Detail worth filtering on without decoding it
+}
+
+// Publisher delivers events, e.g. to an SNS topic or an EventBridge bus.
+type Publisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+type EventService struct {
+	publishers []Publisher
+	now        func() time.Time
+}
+
+func NewEventService(publishers ...Publisher) *EventService {
+	return &EventService{publishers: publishers, now: time.Now}
+}
+
+// Publish publishes a haiku.created event for a new haiku, with its tenant,
+// mood, repository and model as attributes.
+func (s *EventService) Publish(ctx context.Context, event haiku.HaikuEvent) {
+	s.publish(ctx, Event{
+		Type:   TypeHaikuCreated,
+		Time:   event.CreatedAt,
+		Detail: event,
+		Attributes: map[string]string{
+			"tenant":     event.Tenant,
+			"mood":       string(event.Mood),
+			"repository": event.Repository,
+			"model":      event.Model,
+		},
+	})
+}
+
+// Failed publishes a haiku.failed event for a haiku that couldn't be
+// written, with its tenant, repository and reason as attributes.
+func (s *EventService) Failed(ctx context.Context, failure haiku.HaikuFailure) {
+	s.publish(ctx, Event{
+		Type:   TypeHaikuFailed,
+		Time:   failure.FailedAt,
+		Detail: failure,
+		Attributes: map[string]string{
+			"tenant":     failure.Tenant,
+			"repository": failure.Repository,
+			"reason":     failure.Reason,
+		},
+	})
+}
+
+// QuotaExceeded publishes a quota.exceeded event for a key that used up its
+// quota, with its tenant, key and limit as attributes.
+func (s *EventService) QuotaExceeded(ctx context.Context, event quotas.ExceededEvent) {
+	s.publish(ctx, Event{
+		Type:   TypeQuotaExceeded,
+		Time:   s.now().UTC(),
+		Detail: event,
+		Attributes: map[string]string{
+			"tenant": event.Tenant,
+			"keyId":  event.KeyID,
+			"limit":  event.Limit,
+			"quota":  strconv.FormatInt(event.Quota, 10),
+		},
+	})
+}
+
+// publish hands event to every publisher at once. Publishing is best effort:
+// a failure is logged, and never reaches the caller.
+func (s *EventService) publish(ctx context.Context, event Event) {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, publisher := range s.publishers {
+		wg.Go(func() {
+			if err := publisher.Publish(ctx, event); err != nil {
+				logging.Errorf("[EVENT SERVICE] error publishing %s event: %v\n", event.Type, err)
+			}
+		})
+	}
+	wg.Wait()
+}
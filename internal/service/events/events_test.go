@@ -0,0 +1,111 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/quotas"
+)
+
+type MockPublisher struct {
+	ErrorToReturn error
+	Published     []Event
+}
+
+func (m *MockPublisher) Publish(ctx context.Context, event Event) error {
+	m.Published = append(m.Published, event)
+	return m.ErrorToReturn
+}
+
+func TestPublish(t *testing.T) {
+	now := time.Date(2025, 10, 10, 9, 0, 0, 0, time.UTC)
+	created := haiku.HaikuEvent{
+		ID:         "abc123",
+		Tenant:     "acme",
+		Repository: "octo/leaves",
+		Mood:       haiku.MoodTechnical,
+		Haiku:      "frost on the servers",
+		Model:      "claude",
+		CreatedAt:  now,
+	}
+	failure := haiku.HaikuFailure{
+		Tenant:   "acme",
+		Reason:   haiku.FailureModelUnavailable,
+		Error:    "model is currently unavailable",
+		FailedAt: now,
+	}
+	exceeded := quotas.ExceededEvent{KeyID: "ci", Tenant: "acme", Limit: quotas.LimitRequests, Quota: 100, Used: 100}
+
+	tests := []struct {
+		name               string
+		publish            func(service *EventService)
+		publishError       error
+		expectedType       string
+		expectedDetail     any
+		expectedAttributes map[string]string
+	}{
+		{
+			name:               "Haiku created",
+			publish:            func(service *EventService) { service.Publish(context.Background(), created) },
+			expectedType:       TypeHaikuCreated,
+			expectedDetail:     created,
+			expectedAttributes: map[string]string{"tenant": "acme", "mood": "technical", "repository": "octo/leaves", "model": "claude"},
+		},
+		{
+			name:               "Haiku failed",
+			publish:            func(service *EventService) { service.Failed(context.Background(), failure) },
+			expectedType:       TypeHaikuFailed,
+			expectedDetail:     failure,
+			expectedAttributes: map[string]string{"tenant": "acme", "repository": "", "reason": haiku.FailureModelUnavailable},
+		},
+		{
+			name:               "Quota exceeded",
+			publish:            func(service *EventService) { service.QuotaExceeded(context.Background(), exceeded) },
+			expectedType:       TypeQuotaExceeded,
+			expectedDetail:     exceeded,
+			expectedAttributes: map[string]string{"tenant": "acme", "keyId": "ci", "limit": "requests", "quota": "100"},
+		},
+		{
+			name:               "Failures are only logged",
+			publish:            func(service *EventService) { service.Publish(context.Background(), created) },
+			publishError:       errors.New("throttled"),
+			expectedType:       TypeHaikuCreated,
+			expectedDetail:     created,
+			expectedAttributes: map[string]string{"tenant": "acme", "mood": "technical", "repository": "octo/leaves", "model": "claude"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			failing := &MockPublisher{ErrorToReturn: tc.publishError}
+			publisher := &MockPublisher{}
+			service := NewEventService(failing, publisher)
+			service.now = func() time.Time { return now }
+
+			tc.publish(service)
+
+			// Every publisher is handed the event, whether or not another
+			// fails.
+			for _, p := range []*MockPublisher{failing, publisher} {
+				if len(p.Published) != 1 {
+					t.Fatalf("Expected one event published, got %d", len(p.Published))
+				}
+				event := p.Published[0]
+				if event.Type != tc.expectedType || event.Detail != tc.expectedDetail || !event.Time.Equal(now) {
+					t.Errorf("Expected a %s event of %+v, got %+v", tc.expectedType, tc.expectedDetail, event)
+				}
+				if len(event.Attributes) != len(tc.expectedAttributes) {
+					t.Fatalf("Expected attributes %v, got %v", tc.expectedAttributes, event.Attributes)
+				}
+				for name, value := range tc.expectedAttributes {
+					if event.Attributes[name] != value {
+						t.Errorf("Expected attribute %s to be %q, got %q", name, value, event.Attributes[name])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/eventbridge"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/sns"
+)
+
+// SNSClient publishes messages to a topic, e.g. the SNS client.
+type SNSClient interface {
+	Publish(ctx context.Context, message sns.Message) error
+}
+
+// SNSPublisher publishes events to an SNS topic. Each message is the event's
+// detail with its type added, and carries the event's type and attributes as
+// message attributes, for subscription filter policies.
+type SNSPublisher struct {
+	client SNSClient
+}
+
+func NewSNSPublisher(client SNSClient) *SNSPublisher {
+	return &SNSPublisher{client: client}
+}
+
+func (p *SNSPublisher) Publish(ctx context.Context, event Event) error {
+	detail, err := json.Marshal(event.Detail)
+	if err != nil {
+		return fmt.Errorf("encoding event: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(detail, &fields); err != nil {
+		return fmt.Errorf("event detail is not an object: %v", err)
+	}
+	fields["type"], _ = json.Marshal(event.Type)
+	body, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Errorf("encoding event: %v", err)
+	}
+
+	attributes := map[string]string{"type": event.Type}
+	maps.Copy(attributes, event.Attributes)
+	return p.client.Publish(ctx, sns.Message{Body: string(body), Attributes: attributes})
+}
+
+// EventBridgeClient puts events on a bus, e.g. the EventBridge client.
+type EventBridgeClient interface {
+	PutEvent(ctx context.Context, entry eventbridge.Entry) error
+}
+
+// EventBridgePublisher puts events on an EventBridge bus, from Source, with
+// the event's type as its detail type and its detail as is. Rules match on
+// the detail's fields, so attributes aren't needed.
+type EventBridgePublisher struct {
+	client EventBridgeClient
+}
+
+func NewEventBridgePublisher(client EventBridgeClient) *EventBridgePublisher {
+	return &EventBridgePublisher{client: client}
+}
+
+func (p *EventBridgePublisher) Publish(ctx context.Context, event Event) error {
+	detail, err := json.Marshal(event.Detail)
+	if err != nil {
+		return fmt.Errorf("encoding event: %v", err)
+	}
+	return p.client.PutEvent(ctx, eventbridge.Entry{
+		Source:     Source,
+		DetailType: event.Type,
+		Detail:     string(detail),
+		Time:       event.Time,
+	})
+}
@@ -0,0 +1,87 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/eventbridge"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/sns"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+type MockSNSClient struct {
+	Published []sns.Message
+}
+
+func (m *MockSNSClient) Publish(ctx context.Context, message sns.Message) error {
+	m.Published = append(m.Published, message)
+	return nil
+}
+
+type MockEventBridgeClient struct {
+	Put []eventbridge.Entry
+}
+
+func (m *MockEventBridgeClient) PutEvent(ctx context.Context, entry eventbridge.Entry) error {
+	m.Put = append(m.Put, entry)
+	return nil
+}
+
+var testEvent = Event{
+	Type: TypeHaikuCreated,
+	Time: time.Date(2025, 10, 10, 9, 0, 0, 0, time.UTC),
+	Detail: haiku.HaikuEvent{
+		ID:        "abc123",
+		Tenant:    "acme",
+		Mood:      haiku.MoodTechnical,
+		Haiku:     "frost on the servers",
+		CreatedAt: time.Date(2025, 10, 10, 9, 0, 0, 0, time.UTC),
+	},
+	Attributes: map[string]string{"tenant": "acme", "mood": "technical"},
+}
+
+func TestSNSPublisher(t *testing.T) {
+	client := &MockSNSClient{}
+	if err := NewSNSPublisher(client).Publish(context.Background(), testEvent); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(client.Published) != 1 {
+		t.Fatalf("Expected one message published, got %d", len(client.Published))
+	}
+
+	message := client.Published[0]
+	var published struct {
+		Type string `json:"type"`
+		haiku.HaikuEvent
+	}
+	if err := json.Unmarshal([]byte(message.Body), &published); err != nil {
+		t.Fatalf("Expected a JSON body, got %q", message.Body)
+	}
+	if published.Type != TypeHaikuCreated || published.HaikuEvent != testEvent.Detail {
+		t.Errorf("Expected the event's detail with its type, got %q", message.Body)
+	}
+	if message.Attributes["type"] != TypeHaikuCreated || message.Attributes["mood"] != "technical" || message.Attributes["tenant"] != "acme" {
+		t.Errorf("Expected the event's type and attributes, got %v", message.Attributes)
+	}
+}
+
+func TestEventBridgePublisher(t *testing.T) {
+	client := &MockEventBridgeClient{}
+	if err := NewEventBridgePublisher(client).Publish(context.Background(), testEvent); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(client.Put) != 1 {
+		t.Fatalf("Expected one event put, got %d", len(client.Put))
+	}
+
+	entry := client.Put[0]
+	if entry.Source != Source || entry.DetailType != TypeHaikuCreated || !entry.Time.Equal(testEvent.Time) {
+		t.Errorf("Expected a %s event from %s, got %+v", TypeHaikuCreated, Source, entry)
+	}
+	var detail haiku.HaikuEvent
+	if err := json.Unmarshal([]byte(entry.Detail), &detail); err != nil || detail != testEvent.Detail {
+		t.Errorf("Expected the event's detail, got %q", entry.Detail)
+	}
+}
@@ -0,0 +1,128 @@
+// Package export writes the haiku served each UTC day to S3 as JSON Lines,
+// partitioned by day, so that they can be queried with Athena without
+// reading the table that serves them.
+package export
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+const (
+	// DefaultPrefix is the key prefix exported files are written under.
+	DefaultPrefix = "haiku"
+
+	dayFormat   = "2006-01-02"
+	contentType = "application/x-ndjson"
+)
+
+var ErrExport = errors.New("error exporting haiku")
+
+// Source lists every kept haiku, e.g. the vote service.
+type Source interface {
+	Each(ctx context.Context, fn func(saved votes.Saved) error) error
+}
+
+// Uploader stores exported files, e.g. in S3.
+type Uploader interface {
+	Put(ctx context.Context, key string, contentType string, body []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Export is the outcome of exporting one day.
+type Export struct {
+	Day   string `json:"day"`   // As YYYY-MM-DD in UTC
+	Key   string `json:"key"`   // The file written, empty when no haiku were kept
+	Haiku int    `json:"haiku"` // How many haiku were written
+}
+
+type ExportService struct {
+	source   Source
+	uploader Uploader
+	prefix   string
+	now      func() time.Time
+}
+
+type Options struct {
+	Prefix string // Key prefix exported files are written under (default: "haiku")
+}
+
+func NewExportService(source Source, uploader Uploader, opts *Options) *ExportService {
+	service := &ExportService{
+		source:   source,
+		uploader: uploader,
+		prefix:   DefaultPrefix,
+		now:      time.Now,
+	}
+
+	if opts != nil && opts.Prefix != "" {
+		service.prefix = strings.Trim(opts.Prefix, "/")
+	}
+
+	return service
+}
+
+// Export writes every haiku served on day, in UTC, to a single file at
+// <prefix>/dt=YYYY-MM-DD/haiku.jsonl, oldest first, replacing any earlier
+// export of the day. Haiku are written whatever their publication status,
+// which each line carries. A day without haiku has no file, so any earlier
+// export of it, e.g. of haiku since erased, is deleted.
+func (s *ExportService) Export(ctx context.Context, day time.Time) (Export, error) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1)
+	export := Export{Day: start.Format(dayFormat)}
+	key := fmt.Sprintf("%s/dt=%s/haiku.jsonl", s.prefix, export.Day)
+
+	var served []votes.Saved
+	err := s.source.Each(ctx, func(saved votes.Saved) error {
+		if !saved.CreatedAt.Before(start) && saved.CreatedAt.Before(end) {
+			served = append(served, saved)
+		}
+		return nil
+	})
+	if err != nil {
+		return Export{}, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+	if len(served) == 0 {
+		logging.Infof("[EXPORT SERVICE] no haiku served on %s\n", export.Day)
+		if err := s.uploader.Delete(ctx, key); err != nil {
+			return Export{}, fmt.Errorf("%w: %w", ErrExport, err)
+		}
+		return export, nil
+	}
+
+	slices.SortFunc(served, func(a, b votes.Saved) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+	})
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, saved := range served {
+		if err := encoder.Encode(saved); err != nil {
+			return Export{}, fmt.Errorf("%w: %w", ErrExport, err)
+		}
+	}
+
+	export.Key = key
+	if err := s.uploader.Put(ctx, export.Key, contentType, body.Bytes()); err != nil {
+		return Export{}, fmt.Errorf("%w: %w", ErrExport, err)
+	}
+	export.Haiku = len(served)
+
+	logging.Infof("[EXPORT SERVICE] exported %d haiku to %s\n", export.Haiku, export.Key)
+	return export, nil
+}
+
+// ExportYesterday exports the last full UTC day, for a daily schedule.
+func (s *ExportService) ExportYesterday(ctx context.Context) (Export, error) {
+	return s.Export(ctx, s.now().UTC().AddDate(0, 0, -1))
+}
@@ -0,0 +1,137 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/votes"
+)
+
+type MockSource struct {
+	Saved         []votes.Saved
+	ErrorToReturn error
+}
+
+func (m *MockSource) Each(ctx context.Context, fn func(saved votes.Saved) error) error {
+	for _, saved := range m.Saved {
+		if err := fn(saved); err != nil {
+			return err
+		}
+	}
+	return m.ErrorToReturn
+}
+
+type MockUploader struct {
+	ErrorToReturn   error
+	LastKey         string
+	LastContentType string
+	LastBody        string
+	Calls           int
+	Deleted         []string
+}
+
+func (m *MockUploader) Put(ctx context.Context, key string, contentType string, body []byte) error {
+	m.Calls++
+	m.LastKey = key
+	m.LastContentType = contentType
+	m.LastBody = string(body)
+	return m.ErrorToReturn
+}
+
+func (m *MockUploader) Delete(ctx context.Context, key string) error {
+	m.Deleted = append(m.Deleted, key)
+	return m.ErrorToReturn
+}
+
+func TestExport(t *testing.T) {
+	day := time.Date(2025, 10, 9, 0, 0, 0, 0, time.UTC)
+	saved := func(id string, createdAt time.Time) votes.Saved {
+		return votes.Saved{ID: id, Stored: haiku.Stored{Haiku: "leaves " + id, Repository: "octo/leaves", CreatedAt: createdAt}, Status: votes.StatusPublished}
+	}
+	source := []votes.Saved{
+		saved("b", day.Add(18*time.Hour)),
+		saved("before", day.Add(-time.Second)),
+		saved("a", day.Add(time.Hour)),
+		saved("after", day.AddDate(0, 0, 1)),
+	}
+
+	tests := []struct {
+		name          string
+		source        *MockSource
+		uploaderError error
+		expected      Export
+		expectedLines []string
+		errorIs       error
+	}{
+		{
+			name:          "Exported",
+			source:        &MockSource{Saved: source},
+			expected:      Export{Day: "2025-10-09", Key: "exports/dt=2025-10-09/haiku.jsonl", Haiku: 2},
+			expectedLines: []string{`{"id":"a","haiku":"leaves a"`, `{"id":"b","haiku":"leaves b"`},
+		},
+		{
+			name:     "No haiku served",
+			source:   &MockSource{Saved: source[1:2]},
+			expected: Export{Day: "2025-10-09"},
+		},
+		{
+			name:    "Source fails",
+			source:  &MockSource{ErrorToReturn: errors.New("throttled")},
+			errorIs: ErrExport,
+		},
+		{
+			name:          "Upload fails",
+			source:        &MockSource{Saved: source},
+			uploaderError: errors.New("access denied"),
+			errorIs:       ErrExport,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			uploader := &MockUploader{ErrorToReturn: tc.uploaderError}
+			service := NewExportService(tc.source, uploader, &Options{Prefix: "/exports/"})
+			service.now = func() time.Time { return day.AddDate(0, 0, 1).Add(30 * time.Minute) }
+
+			export, err := service.ExportYesterday(context.Background())
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if export != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, export)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if tc.expectedLines == nil {
+				if uploader.Calls != 0 {
+					t.Errorf("Expected nothing to be written, got %q", uploader.LastBody)
+				}
+				if len(uploader.Deleted) != 1 || uploader.Deleted[0] != "exports/dt=2025-10-09/haiku.jsonl" {
+					t.Errorf("Expected the day's earlier export to be deleted, got %v", uploader.Deleted)
+				}
+				return
+			}
+			if len(uploader.Deleted) != 0 {
+				t.Errorf("Expected nothing to be deleted, got %v", uploader.Deleted)
+			}
+			if uploader.LastKey != tc.expected.Key || uploader.LastContentType != "application/x-ndjson" {
+				t.Errorf("Expected %s as JSON Lines, got %s as %s", tc.expected.Key, uploader.LastKey, uploader.LastContentType)
+			}
+			lines := strings.Split(strings.TrimSuffix(uploader.LastBody, "\n"), "\n")
+			if len(lines) != len(tc.expectedLines) {
+				t.Fatalf("Expected %d lines, got %q", len(tc.expectedLines), uploader.LastBody)
+			}
+			for i, line := range lines {
+				if !strings.HasPrefix(line, tc.expectedLines[i]) || !strings.Contains(line, `"status":"published"`) {
+					t.Errorf("Expected line %d to start %s with its status, got %s", i+1, tc.expectedLines[i], line)
+				}
+			}
+		})
+	}
+}
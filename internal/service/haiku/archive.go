@@ -0,0 +1,46 @@
+package haiku
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// Stored is a commit haiku kept after it was served, so that it can be voted
+// on and its votes credited to its repository, author and prompt version.
+type Stored struct {
+	Haiku         string    `json:"haiku"`
+	Mood          Mood      `json:"mood"`
+	Repository    string    `json:"repository,omitempty"`
+	Author        string    `json:"author,omitempty"`
+	PromptVersion string    `json:"promptVersion,omitempty"`
+	Model         string    `json:"model,omitempty"`
+	KeyID         string    `json:"keyId,omitempty"`    // The API key the haiku was written for, if any
+	Tenant        string    `json:"tenant,omitempty"`   // The tenant the haiku was written for, empty for the default tenant
+	CacheKey      string    `json:"cacheKey,omitempty"` // The response cache entry holding the haiku, if any, so that it can be erased with it
+	Previous      string    `json:"previous,omitempty"` // The haiku this one was regenerated from, if any
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Archive keeps served haiku, e.g. in DynamoDB, under an ID of its choosing.
+// Load returns a kept haiku served to the caller of ctx, so that it can be
+// written again.
+type Archive interface {
+	Save(ctx context.Context, haiku Stored) (string, error)
+	Load(ctx context.Context, id string) (Stored, error)
+}
+
+// archiveHaiku hands haiku to the archive and returns its ID. Keeping haiku
+// is best effort, so a failure is logged and the haiku served without an ID.
+func (h *HaikuService) archiveHaiku(ctx context.Context, haiku Stored) string {
+	if h.archive == nil {
+		return ""
+	}
+	id, err := h.archive.Save(ctx, haiku)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error archiving haiku: %v\n", err)
+		return ""
+	}
+	return id
+}
@@ -0,0 +1,116 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+// errNotKept stands in for the archive's error for haiku it doesn't keep.
+var errNotKept = errors.New("haiku not found")
+
+type MockArchive struct {
+	IDToReturn    string
+	ErrorToReturn error
+	Saved         []Stored
+	Kept          map[string]Stored
+}
+
+func (m *MockArchive) Save(ctx context.Context, haiku Stored) (string, error) {
+	m.Saved = append(m.Saved, haiku)
+	return m.IDToReturn, m.ErrorToReturn
+}
+
+func (m *MockArchive) Load(ctx context.Context, id string) (Stored, error) {
+	stored, ok := m.Kept[id]
+	if !ok {
+		return Stored{}, errNotKept
+	}
+	return stored, nil
+}
+
+func TestCreateHaikuArchivesHaiku(t *testing.T) {
+	tests := []struct {
+		name          string
+		archiveError  error
+		modelError    error
+		expectedID    string
+		expectedSaved int
+	}{
+		{
+			name:          "Archived",
+			expectedID:    "abc123",
+			expectedSaved: 1,
+		},
+		{
+			name:          "Archive fails",
+			archiveError:  errors.New("table not found"),
+			expectedSaved: 1,
+		},
+		{
+			name:       "Fallback haiku are not archived",
+			modelError: fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			archive := &MockArchive{IDToReturn: "abc123", ErrorToReturn: tc.archiveError}
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku", ErrorToReturn: tc.modelError}
+			service := NewHaikuService(mockClient, &Options{Archive: archive, Fallback: true})
+
+			request := HaikuCommitRequest{CommitMessage: "fix typo", Mood: MoodTechnical, Repository: &Repository{Name: "octo/leaves"}, Author: &Author{Name: "Mona"}}
+			response, err := service.CreateHaiku(context.Background(), request)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if response.ID != tc.expectedID {
+				t.Errorf("Expected ID %q, got %q", tc.expectedID, response.ID)
+			}
+			if len(archive.Saved) != tc.expectedSaved {
+				t.Fatalf("Expected %d haiku archived, got %d", tc.expectedSaved, len(archive.Saved))
+			}
+			if tc.expectedSaved == 0 {
+				return
+			}
+
+			saved := archive.Saved[0]
+			if saved.Haiku != "haiku" || saved.Repository != "octo/leaves" || saved.Author != "Mona" || saved.PromptVersion == "" {
+				t.Errorf("Expected the haiku with its repository, author and prompt version, got %+v", saved)
+			}
+			if saved.CacheKey != "" {
+				t.Errorf("Expected no cache key without a response cache, got %q", saved.CacheKey)
+			}
+		})
+	}
+}
+
+func TestCreateHaikuArchivesCacheKey(t *testing.T) {
+	archive := &MockArchive{IDToReturn: "abc123"}
+	responseCache := NewMemoryResponseCache(10, 0)
+	service := NewHaikuService(&MockBedrockClient{ResponseToReturn: "haiku"}, &Options{Archive: archive, ResponseCache: responseCache})
+
+	if _, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: "fix typo"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if len(archive.Saved) != 1 {
+		t.Fatalf("Expected 1 haiku archived, got %d", len(archive.Saved))
+	}
+	if _, ok := responseCache.Get(context.Background(), archive.Saved[0].CacheKey); !ok {
+		t.Errorf("Expected the cache key of the cached response, got %q", archive.Saved[0].CacheKey)
+	}
+
+	// Served again from the cache, the haiku is kept again, so that it can be
+	// voted for and counted as served.
+	if _, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: "fix typo"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(archive.Saved) != 2 || archive.Saved[1].CacheKey != archive.Saved[0].CacheKey {
+		t.Errorf("Expected the cached haiku archived again, got %+v", archive.Saved)
+	}
+}
@@ -0,0 +1,45 @@
+package haiku
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// DefaultArtifactURLTTL is how long links to stored artifacts remain valid.
+const DefaultArtifactURLTTL = time.Hour
+
+// Warnings returned when an artifact is requested but no object store is configured.
+const (
+	ShareCardsNotConfigured = "share cards are not configured"
+	AudioNotConfigured      = "audio is not configured"
+)
+
+// ObjectStore stores generated artifacts, such as share cards and audio, and
+// issues links to them.
+type ObjectStore = extension.Storage
+
+// storeArtifact stores body under prefix and returns a presigned link to it.
+// Artifacts are keyed by their content, so storing the same artifact again
+// overwrites the earlier copy.
+func (h *HaikuService) storeArtifact(ctx context.Context, prefix string, extension string, contentType string, body []byte) (*Artifact, error) {
+	sum := sha256.Sum256(body)
+	key := prefix + "/" + hex.EncodeToString(sum[:16]) + "." + extension
+
+	if err := h.artifacts.Put(ctx, key, contentType, body); err != nil {
+		return nil, err
+	}
+
+	url, err := h.artifacts.PresignGet(ctx, key, h.artifactURLTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Artifact{
+		URL:       url,
+		ExpiresAt: time.Now().Add(h.artifactURLTTL).UTC(),
+	}, nil
+}
@@ -0,0 +1,24 @@
+package haiku
+
+import (
+	"context"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/polly"
+)
+
+// SpeechSynthesizer reads text aloud as MP3 audio.
+type SpeechSynthesizer interface {
+	Synthesize(ctx context.Context, text string, opts *polly.SpeechOptions) ([]byte, error)
+}
+
+// createAudio reads the haiku aloud and stores the recording.
+func (h *HaikuService) createAudio(ctx context.Context, haiku string) (*Artifact, error) {
+	audio, err := h.speech.Synthesize(ctx, haiku, &polly.SpeechOptions{
+		VoiceID: h.voiceID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h.storeArtifact(ctx, "audio", "mp3", "audio/mpeg", audio)
+}
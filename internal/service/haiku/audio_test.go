@@ -0,0 +1,99 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/polly"
+)
+
+type MockSpeechSynthesizer struct {
+	AudioToReturn []byte
+	ErrorToReturn error
+	LastText      string
+	LastOptions   *polly.SpeechOptions
+}
+
+func (m *MockSpeechSynthesizer) Synthesize(ctx context.Context, text string, opts *polly.SpeechOptions) ([]byte, error) {
+	m.LastText = text
+	m.LastOptions = opts
+	return m.AudioToReturn, m.ErrorToReturn
+}
+
+func TestCreateHaikuWithAudio(t *testing.T) {
+	const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+	tests := []struct {
+		name            string
+		speech          *MockSpeechSynthesizer
+		store           *MockObjectStore
+		expectedURL     string
+		expectedWarning string
+		errorIs         error
+	}{
+		{
+			name:        "Audio stored",
+			speech:      &MockSpeechSynthesizer{AudioToReturn: []byte("mp3")},
+			store:       &MockObjectStore{URLToReturn: "https://artifacts.example/a.mp3?sig"},
+			expectedURL: "https://artifacts.example/a.mp3?sig",
+		},
+		{
+			name:            "No object store",
+			speech:          &MockSpeechSynthesizer{AudioToReturn: []byte("mp3")},
+			expectedWarning: AudioNotConfigured,
+		},
+		{
+			name:    "Synthesis error",
+			speech:  &MockSpeechSynthesizer{ErrorToReturn: errors.New("throttled")},
+			store:   &MockObjectStore{},
+			errorIs: ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &Options{Speech: tc.speech, VoiceID: "Matthew"}
+			if tc.store != nil {
+				opts.Artifacts = tc.store
+			}
+			service := NewHaikuService(&MockBedrockClient{ResponseToReturn: testHaiku}, opts)
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage: "fix: resolved login issue",
+				IncludeAudio:  true,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if tc.expectedWarning != "" {
+				if response.Audio != nil {
+					t.Errorf("Expected no audio, got %+v", response.Audio)
+				}
+				if len(response.Metadata.Warnings) != 1 || response.Metadata.Warnings[0] != tc.expectedWarning {
+					t.Errorf("Expected warning %q, got %v", tc.expectedWarning, response.Metadata.Warnings)
+				}
+				return
+			}
+
+			if response.Audio == nil || response.Audio.URL != tc.expectedURL {
+				t.Fatalf("Expected audio url %q, got %+v", tc.expectedURL, response.Audio)
+			}
+			if tc.speech.LastText != testHaiku || tc.speech.LastOptions.VoiceID != "Matthew" {
+				t.Errorf("Expected the haiku to be read by Matthew, got %q with %+v", tc.speech.LastText, tc.speech.LastOptions)
+			}
+			if !strings.HasPrefix(tc.store.LastKey, "audio/") || !strings.HasSuffix(tc.store.LastKey, ".mp3") {
+				t.Errorf("Unexpected audio key %q", tc.store.LastKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package haiku
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxAuthorLength caps the author name or handle shared with the model.
+const maxAuthorLength = 64
+
+// normalizeAuthor trims the author's name and handle, dropping the "@" a
+// handle is often written with. It returns nil for an author with neither.
+func normalizeAuthor(author *Author) *Author {
+	if author == nil {
+		return nil
+	}
+	normalized := *author
+	normalized.Name = strings.TrimSpace(normalized.Name)
+	normalized.Handle = strings.TrimPrefix(strings.TrimSpace(normalized.Handle), "@")
+	if normalized.Name == "" && normalized.Handle == "" {
+		return nil
+	}
+	return &normalized
+}
+
+// Key identifies the author in stored haiku, statistics and erasure requests:
+// their name, or their handle when no name was given.
+func (a *Author) Key() string {
+	if a.Name != "" {
+		return a.Name
+	}
+	return a.Handle
+}
+
+// Attribution credits the author of the commit a haiku was written for.
+func (a *Author) Attribution() string {
+	switch {
+	case a.Name != "" && a.Handle != "":
+		return fmt.Sprintf("after a commit by %s (@%s)", a.Name, a.Handle)
+	case a.Handle != "":
+		return fmt.Sprintf("after a commit by @%s", a.Handle)
+	}
+	return fmt.Sprintf("after a commit by %s", a.Name)
+}
+
+// authorGuidance asks the model to allude to the commit's author. The handle
+// is shared in preference to the name, shortened and fenced, since both are
+// untrusted.
+func authorGuidance(author *Author) string {
+	name := author.Handle
+	if name == "" {
+		name = author.Name
+	}
+	return fmt.Sprintf(AuthorGuidanceTemplate, promptDetail(name, maxAuthorLength))
+}
@@ -0,0 +1,94 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/changelog"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// CreateChangelogHaiku parses a Keep a Changelog document and writes one haiku
+// per category of the selected release. Categories are generated concurrently
+// and returned in changelog order.
+func (h *HaikuService) CreateChangelogHaiku(ctx context.Context, request ChangelogRequest) (ChangelogResponse, error) {
+	mood := request.Mood
+	if mood != "" && !mood.IsValid() {
+		logging.Warnf("[HAIKU SERVICE] invalid mood: %s\n", mood)
+		return ChangelogResponse{}, ErrBadHaikuRequest
+	}
+
+	if request.Mood == "" {
+		mood = MoodReflective
+	}
+
+	parsed, err := changelog.Parse(request.Changelog)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error parsing changelog: %v\n", err)
+		return ChangelogResponse{}, ErrBadHaikuRequest
+	}
+
+	release, ok := parsed.Release(request.Version)
+	if !ok {
+		logging.Warnf("[HAIKU SERVICE] changelog version not found: %s\n", request.Version)
+		return ChangelogResponse{}, ErrBadHaikuRequest
+	}
+
+	sections := release.Sections
+	if len(sections) > MaxChangelogCategories {
+		sections = sections[:MaxChangelogCategories]
+	}
+
+	options := &bedrock.ClaudeOptions{
+		ModelID: h.modelID,
+		System:  ChangelogSystemPrompt,
+	}
+
+	haiku := make([]CategoryHaiku, len(sections))
+	errs := make([]error, len(sections))
+
+	var wg sync.WaitGroup
+	for i, section := range sections {
+		wg.Add(1)
+		go func(i int, section changelog.Section) {
+			defer wg.Done()
+
+			entries, neutralized := sanitizeInput("- " + strings.Join(section.Entries, "\n- "))
+			if neutralized {
+				logging.Warnf("[HAIKU SERVICE] neutralized instruction-like content in changelog entries\n")
+			}
+
+			prompt := fmt.Sprintf("Create a %s haiku about what was %s in this release:\n<changelog_entries>\n%s\n</changelog_entries>",
+				mood, strings.ToLower(string(section.Category)), entries)
+
+			logging.Debugf("[HAIKU SERVICE] sending changelog request to Bedrock: %s\n", h.loggablePrompt(prompt))
+			response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+
+			haiku[i] = CategoryHaiku{
+				Category: string(section.Category),
+				Haiku:    response.Text,
+			}
+		}(i, section)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			logging.Errorf("[HAIKU SERVICE] error invoking Claude: %v\n", err)
+			return ChangelogResponse{}, fmt.Errorf("%w: invoking Claude: %w", ErrCreateHaiku, err)
+		}
+	}
+
+	return ChangelogResponse{
+		Version: release.Version,
+		Date:    release.Date,
+		Haiku:   haiku,
+	}, nil
+}
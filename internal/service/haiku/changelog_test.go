@@ -0,0 +1,112 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+const testChangelog = `# Changelog
+
+## [1.1.0] - 2024-03-05
+### Added
+- Release notes haiku endpoint
+### Fixed
+- Login timeout
+
+## [1.0.0] - 2024-01-31
+### Removed
+- Legacy XML output
+`
+
+func TestCreateChangelogHaiku(t *testing.T) {
+	tests := []struct {
+		name               string
+		changelog          string
+		version            string
+		mood               Mood
+		mockError          error
+		expectedVersion    string
+		expectedCategories []string
+		errorIs            error
+	}{
+		{
+			name:               "Latest release by default",
+			changelog:          testChangelog,
+			expectedVersion:    "1.1.0",
+			expectedCategories: []string{"Added", "Fixed"},
+		},
+		{
+			name:               "Selected release",
+			changelog:          testChangelog,
+			version:            "1.0.0",
+			expectedVersion:    "1.0.0",
+			expectedCategories: []string{"Removed"},
+		},
+		{
+			name:      "Unknown release",
+			changelog: testChangelog,
+			version:   "2.0.0",
+			errorIs:   ErrBadHaikuRequest,
+		},
+		{
+			name:      "Not a changelog",
+			changelog: "fixed some things",
+			errorIs:   ErrBadHaikuRequest,
+		},
+		{
+			name:      "Invalid mood",
+			changelog: testChangelog,
+			mood:      Mood("silly"),
+			errorIs:   ErrBadHaikuRequest,
+		},
+		{
+			name:      "Bedrock error",
+			changelog: testChangelog,
+			mockError: errors.New("bedrock API error"),
+			errorIs:   ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{
+				ResponseToReturn: "Seeds in fresh soil\nnew branches reach for the light\nthe garden expands",
+				ErrorToReturn:    tc.mockError,
+			}
+
+			service := NewHaikuService(mockClient, nil)
+			response, err := service.CreateChangelogHaiku(context.Background(), ChangelogRequest{
+				Changelog: tc.changelog,
+				Version:   tc.version,
+				Mood:      tc.mood,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if response.Version != tc.expectedVersion {
+				t.Errorf("Expected version %q, got %q", tc.expectedVersion, response.Version)
+			}
+			if len(response.Haiku) != len(tc.expectedCategories) {
+				t.Fatalf("Expected %d haiku, got %d", len(tc.expectedCategories), len(response.Haiku))
+			}
+			for i, category := range tc.expectedCategories {
+				if response.Haiku[i].Category != category {
+					t.Errorf("Expected category %d to be %q, got %q", i, category, response.Haiku[i].Category)
+				}
+				if response.Haiku[i].Haiku != mockClient.ResponseToReturn {
+					t.Errorf("Expected haiku %q, got %q", mockClient.ResponseToReturn, response.Haiku[i].Haiku)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package haiku
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+)
+
+// coAuthorTrailer is the git trailer crediting additional authors of a commit.
+const coAuthorTrailer = "co-authored-by:"
+
+// SplitCoAuthors separates the Co-authored-by trailers from a commit message,
+// returning the message without them and the trailer lines. Trailers credit
+// people rather than describe the change, so they are kept out of prompts.
+func SplitCoAuthors(commitMessage string) (string, []string) {
+	var kept, trailers []string
+
+	for _, line := range strings.Split(commitMessage, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if len(trimmed) > len(coAuthorTrailer) && strings.EqualFold(trimmed[:len(coAuthorTrailer)], coAuthorTrailer) {
+			trailers = append(trailers, trimmed)
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	if len(trailers) == 0 {
+		return commitMessage, nil
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n")), trailers
+}
+
+// ParseCoAuthors returns the authors credited by Co-authored-by trailers in a
+// commit message, in order and without duplicates. Trailers that do not hold a
+// "Name <email>" address are skipped.
+func ParseCoAuthors(commitMessage string) []Author {
+	_, trailers := SplitCoAuthors(commitMessage)
+
+	var authors []Author
+	seen := make(map[string]bool)
+	for _, trailer := range trailers {
+		address, err := mail.ParseAddress(strings.TrimSpace(trailer[len(coAuthorTrailer):]))
+		if err != nil || address.Name == "" {
+			continue
+		}
+
+		email := strings.ToLower(address.Address)
+		if seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		authors = append(authors, Author{Name: address.Name, Email: address.Address})
+	}
+
+	return authors
+}
+
+// pairingGuidance asks the model to reflect that a commit was written together,
+// in one haiku or in linked verses, and returns the number of linked verses
+// asked for, or 0 for a single haiku. Only the number of authors is shared, since names are not poem material.
+func pairingGuidance(coAuthors []Author, style PairingStyle) (string, int) {
+	authors := len(coAuthors) + 1
+	if style != PairingVerses {
+		return fmt.Sprintf(PairingGuidanceTemplate, authors), 0
+	}
+	verses := min(authors, MaxPairingVerses)
+	return fmt.Sprintf(PairingVersesGuidanceTemplate, authors, verses), verses
+}
@@ -0,0 +1,140 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const pairedCommit = "fix: resolved login issue\n\nCo-authored-by: Ada Lovelace <ada@example.com>\nco-authored-by: Grace Hopper <grace@example.com>\nCo-Authored-By: Ada Lovelace <ADA@example.com>"
+
+func TestParseCoAuthors(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		expected []Author
+	}{
+		{
+			name:    "Trailers in any case, duplicates dropped",
+			message: pairedCommit,
+			expected: []Author{
+				{Name: "Ada Lovelace", Email: "ada@example.com"},
+				{Name: "Grace Hopper", Email: "grace@example.com"},
+			},
+		},
+		{
+			name:    "Trailer without a name skipped",
+			message: "fix: resolved login issue\n\nCo-authored-by: ada@example.com",
+		},
+		{
+			name:    "No trailers",
+			message: "fix: resolved login issue",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			authors := ParseCoAuthors(tc.message)
+			if !reflect.DeepEqual(authors, tc.expected) {
+				t.Errorf("Expected authors %+v, got %+v", tc.expected, authors)
+			}
+		})
+	}
+}
+
+func TestSplitCoAuthors(t *testing.T) {
+	message, trailers := SplitCoAuthors(pairedCommit)
+
+	if message != "fix: resolved login issue" {
+		t.Errorf("Expected the trailers to be removed, got %q", message)
+	}
+	if len(trailers) != 3 || trailers[0] != "Co-authored-by: Ada Lovelace <ada@example.com>" {
+		t.Errorf("Expected the three trailer lines, got %q", trailers)
+	}
+}
+
+func TestCreateHaikuCoAuthors(t *testing.T) {
+	tests := []struct {
+		name             string
+		includePairing   bool
+		pairingStyle     PairingStyle
+		strict           bool
+		expectedGuidance string
+		expectedVerses   int
+		errorIs          error
+	}{
+		{
+			name:             "Pairing woven in",
+			includePairing:   true,
+			expectedGuidance: "3 people working together. Let the haiku",
+		},
+		{
+			name: "Credited without pairing",
+		},
+		{
+			name:             "Linked verses",
+			pairingStyle:     PairingVerses,
+			expectedGuidance: "write 3 linked haiku",
+			expectedVerses:   3,
+		},
+		{
+			name:         "Unknown pairing style",
+			pairingStyle: PairingStyle("chorus"),
+			errorIs:      ErrBadHaikuRequest,
+		},
+		{
+			name:         "Strict linked verses",
+			pairingStyle: PairingVerses,
+			strict:       true,
+			errorIs:      ErrBadHaikuRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku"}
+			service := NewHaikuService(mockClient, nil)
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage:  pairedCommit,
+				IncludePairing: tc.includePairing,
+				PairingStyle:   tc.pairingStyle,
+				Strict:         tc.strict,
+			})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if len(response.Metadata.CoAuthors) != 2 {
+				t.Errorf("Expected two co-authors in metadata, got %+v", response.Metadata.CoAuthors)
+			}
+			if strings.Contains(mockClient.LastPrompt, "example.com") {
+				t.Errorf("Expected trailers to be kept out of the prompt, got %q", mockClient.LastPrompt)
+			}
+
+			system := mockClient.LastOptions.System
+			if tc.expectedGuidance != "" && !strings.Contains(system, tc.expectedGuidance) {
+				t.Errorf("Expected pairing guidance %q, got system prompt %q", tc.expectedGuidance, system)
+			}
+			if tc.expectedGuidance == "" && strings.Contains(system, "people working together") {
+				t.Errorf("Expected no pairing guidance, got system prompt %q", system)
+			}
+			if response.Metadata.Verses != tc.expectedVerses {
+				t.Errorf("Expected %d verses, got %d", tc.expectedVerses, response.Metadata.Verses)
+			}
+		})
+	}
+}
+
+func TestPairingGuidanceCapsVerses(t *testing.T) {
+	coAuthors := make([]Author, 7)
+	guidance, verses := pairingGuidance(coAuthors, PairingVerses)
+	if verses != MaxPairingVerses || !strings.Contains(guidance, "8 people") {
+		t.Errorf("Expected %d verses for 8 people, got %d: %q", MaxPairingVerses, verses, guidance)
+	}
+}
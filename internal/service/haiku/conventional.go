@@ -0,0 +1,60 @@
+package haiku
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// conventionalSubjectPattern matches a Conventional Commit subject line,
+	// e.g. "feat(api)!: add pagination".
+	conventionalSubjectPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?:\s*(\S.*)$`)
+	// breakingFooterPattern matches the footer announcing a breaking change.
+	breakingFooterPattern = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE:`)
+	// scopePattern limits the scopes passed on to the model to short names, as
+	// the commit message is untrusted.
+	scopePattern = regexp.MustCompile(`^[\w./-]{1,32}$`)
+)
+
+// ConventionalCommit is the structure of a commit message following the
+// Conventional Commits specification.
+type ConventionalCommit struct {
+	Type        string // Lowercased type, e.g. "feat"
+	Scope       string // Empty when the subject names no scope
+	Breaking    bool   // Marked with "!" or a BREAKING CHANGE footer
+	Description string // Subject line after the prefix
+}
+
+// ParseConventionalCommit parses the Conventional Commit prefix of a commit
+// message, reporting false when its subject line has none.
+func ParseConventionalCommit(commitMessage string) (ConventionalCommit, bool) {
+	subject, _, _ := strings.Cut(strings.TrimSpace(commitMessage), "\n")
+	match := conventionalSubjectPattern.FindStringSubmatch(strings.TrimSpace(subject))
+	if match == nil {
+		return ConventionalCommit{}, false
+	}
+	return ConventionalCommit{
+		Type:        strings.ToLower(match[1]),
+		Scope:       strings.TrimSpace(match[2]),
+		Breaking:    match[3] == "!" || breakingFooterPattern.MatchString(commitMessage),
+		Description: strings.TrimSpace(match[4]),
+	}, true
+}
+
+// guidance describes the commit's structure to the model. Only known types
+// and short scopes are described, so the guidance never carries free text
+// from the commit message.
+func (c ConventionalCommit) guidance() string {
+	var parts []string
+	if tone, ok := CommitTypeGuidance[c.Type]; ok {
+		parts = append(parts, tone)
+	}
+	if c.Scope != "" && scopePattern.MatchString(c.Scope) {
+		parts = append(parts, fmt.Sprintf(CommitScopeGuidanceTemplate, c.Scope))
+	}
+	if c.Breaking {
+		parts = append(parts, BreakingChangeGuidance)
+	}
+	return strings.Join(parts, " ")
+}
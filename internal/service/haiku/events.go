@@ -0,0 +1,76 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+// MaxCommitRefLength is the longest commit reference a request may carry.
+const MaxCommitRefLength = 100
+
+// Reasons a haiku couldn't be written, as HaikuFailure gives them.
+const (
+	FailureContentBlocked   = "content_blocked"
+	FailureInvalidStructure = "invalid_structure"
+	FailureModelUnavailable = "model_unavailable"
+	FailureThrottled        = "throttled"
+	FailureError            = "error"
+)
+
+// HaikuEvent describes a haiku newly written by the model, for consumers
+// downstream that shouldn't read the datastore.
+type HaikuEvent struct {
+	ID            string    `json:"id,omitempty"`         // ID of the kept haiku, when haiku are kept
+	Tenant        string    `json:"tenant,omitempty"`     // Tenant the haiku was written for
+	CommitRef     string    `json:"commitRef,omitempty"`  // Commit the haiku was written for, as the request referred to it
+	Repository    string    `json:"repository,omitempty"` // Repository the commit was made in, if given
+	Mood          Mood      `json:"mood"`                 // Mood the haiku was written in
+	Haiku         string    `json:"haiku"`                // Haiku served
+	Model         string    `json:"model"`                // Model that wrote the haiku
+	PromptVersion string    `json:"promptVersion"`        // Prompt template version the haiku was written with
+	InputTokens   int       `json:"inputTokens"`          // Tokens spent on the prompt, over every variant
+	OutputTokens  int       `json:"outputTokens"`         // Tokens spent on the haiku, over every variant
+	CreatedAt     time.Time `json:"createdAt"`            // When the haiku was written
+}
+
+// HaikuFailure describes a haiku that couldn't be written, for consumers
+// downstream alerting on failures. Requests refused as bad aren't failures.
+type HaikuFailure struct {
+	Tenant     string    `json:"tenant,omitempty"`     // Tenant the haiku was requested for
+	CommitRef  string    `json:"commitRef,omitempty"`  // Commit the haiku was requested for, as the request referred to it
+	Repository string    `json:"repository,omitempty"` // Repository the commit was made in, if given
+	Mood       Mood      `json:"mood,omitempty"`       // Mood requested, if any
+	Reason     string    `json:"reason"`               // Why the haiku couldn't be written, one of the Failure* reasons
+	Error      string    `json:"error"`                // The error the request failed with
+	FailedAt   time.Time `json:"failedAt"`             // When the request failed
+}
+
+// failureReason classifies err, reporting false for bad requests and
+// requests given up on by their caller, which aren't failures.
+func failureReason(err error) (string, bool) {
+	switch {
+	case errors.Is(err, ErrBadHaikuRequest), errors.Is(err, context.Canceled):
+		return "", false
+	case errors.Is(err, ErrContentBlocked):
+		return FailureContentBlocked, true
+	case errors.Is(err, ErrInvalidStructure):
+		return FailureInvalidStructure, true
+	case errors.Is(err, bedrock.ErrModelUnavailable):
+		return FailureModelUnavailable, true
+	case errors.Is(err, bedrock.ErrThrottling), errors.Is(err, bedrock.ErrQuotaExceeded):
+		return FailureThrottled, true
+	default:
+		return FailureError, true
+	}
+}
+
+// EventPublisher is handed every haiku newly written by the model, and every
+// one that couldn't be written, and publishes them for consumers downstream,
+// e.g. to an SNS topic. It must not fail the request it is handed.
+type EventPublisher interface {
+	Publish(ctx context.Context, event HaikuEvent)
+	Failed(ctx context.Context, failure HaikuFailure)
+}
@@ -0,0 +1,166 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+)
+
+type MockEventPublisher struct {
+	Published []HaikuEvent
+	Failures  []HaikuFailure
+}
+
+func (m *MockEventPublisher) Publish(ctx context.Context, event HaikuEvent) {
+	m.Published = append(m.Published, event)
+}
+
+func (m *MockEventPublisher) Failed(ctx context.Context, failure HaikuFailure) {
+	m.Failures = append(m.Failures, failure)
+}
+
+func TestCreateHaikuPublishesEvents(t *testing.T) {
+	tests := []struct {
+		name              string
+		commitRef         string
+		off               []string
+		expectedPublished int
+		errorIs           error
+	}{
+		{
+			name:              "Published",
+			commitRef:         "9fceb02d0ae598e95dc970b74767f19372d61af8",
+			expectedPublished: 1,
+		},
+		{
+			name:      "Turned off by its feature flag",
+			commitRef: "9fceb02",
+			off:       []string{flags.HaikuEvents},
+		},
+		{
+			name:      "Commit ref too long",
+			commitRef: strings.Repeat("a", MaxCommitRefLength+1),
+			errorIs:   ErrBadHaikuRequest,
+		},
+		{
+			name:      "Commit ref with control characters",
+			commitRef: "9fceb02\n",
+			errorIs:   ErrBadHaikuRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			publisher := &MockEventPublisher{}
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku", UsageToReturn: bedrock.Usage{InputTokens: 100, OutputTokens: 20}}
+			service := NewHaikuService(mockClient, &Options{
+				Archive: &MockArchive{IDToReturn: "abc123"},
+				Events:  publisher,
+				Flags:   &MockFeatureFlags{Off: tc.off},
+			})
+
+			_, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage: "fix typo",
+				Mood:          MoodTechnical,
+				Repository:    &Repository{Name: "octo/leaves"},
+				CommitRef:     tc.commitRef,
+			})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+
+			if len(publisher.Published) != tc.expectedPublished {
+				t.Fatalf("Expected %d events published, got %d", tc.expectedPublished, len(publisher.Published))
+			}
+			if tc.expectedPublished == 0 {
+				return
+			}
+
+			event := publisher.Published[0]
+			if event.ID != "abc123" || event.CommitRef != tc.commitRef || event.Repository != "octo/leaves" || event.Mood != MoodTechnical || event.Haiku != "haiku" {
+				t.Errorf("Expected the haiku's event, got %+v", event)
+			}
+			if event.Model != bedrock.ClaudeModelID || event.PromptVersion == "" || event.InputTokens != 100 || event.OutputTokens != 20 {
+				t.Errorf("Expected the model and tokens spent, got %+v", event)
+			}
+		})
+	}
+}
+
+func TestCreateHaikuPublishesFailures(t *testing.T) {
+	tests := []struct {
+		name           string
+		response       string
+		modelError     error
+		mood           Mood
+		off            []string
+		expectedReason string // Empty when no failure is published
+	}{
+		{
+			name:           "Model unavailable",
+			modelError:     fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+			expectedReason: FailureModelUnavailable,
+		},
+		{
+			name:           "Throttled",
+			modelError:     fmt.Errorf("%w: ThrottlingException", bedrock.ErrThrottling),
+			expectedReason: FailureThrottled,
+		},
+		{
+			name:           "Other errors",
+			modelError:     errors.New("connection reset"),
+			expectedReason: FailureError,
+		},
+		{
+			name:     "Bad requests are not failures",
+			response: "haiku",
+			mood:     Mood("gloomy"),
+		},
+		{
+			name:       "Turned off by its feature flag",
+			modelError: fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+			off:        []string{flags.HaikuEvents},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			publisher := &MockEventPublisher{}
+			mockClient := &MockBedrockClient{ResponseToReturn: tc.response, ErrorToReturn: tc.modelError}
+			service := NewHaikuService(mockClient, &Options{
+				Events: publisher,
+				Flags:  &MockFeatureFlags{Off: tc.off},
+			})
+
+			_, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage: "fix typo",
+				Mood:          tc.mood,
+				Repository:    &Repository{Name: "octo/leaves"},
+				CommitRef:     "9fceb02",
+			})
+			if err == nil {
+				t.Fatalf("Expected an error")
+			}
+
+			if tc.expectedReason == "" {
+				if len(publisher.Failures) != 0 {
+					t.Errorf("Expected no failures published, got %+v", publisher.Failures)
+				}
+				return
+			}
+			if len(publisher.Failures) != 1 {
+				t.Fatalf("Expected one failure published, got %d", len(publisher.Failures))
+			}
+
+			failure := publisher.Failures[0]
+			if failure.Reason != tc.expectedReason || failure.CommitRef != "9fceb02" || failure.Repository != "octo/leaves" || failure.Error != err.Error() || failure.FailedAt.IsZero() {
+				t.Errorf("Expected a %s failure for the request, got %+v", tc.expectedReason, failure)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package haiku
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+// FallbackModel is reported as the model for haiku written locally while the
+// text model is unavailable.
+const FallbackModel = "fallback"
+
+// Warnings returned with a fallback haiku for options that need the model.
+const (
+	SummaryUnavailable      = "summary is unavailable while the model is unavailable"
+	IllustrationUnavailable = "illustration is unavailable while the model is unavailable"
+	VariantsUnavailable     = "variants are unavailable while the model is unavailable"
+	VersesUnavailable       = "linked verses are unavailable while the model is unavailable"
+)
+
+// Fallback lines are templates with one two-syllable slot, so every filled
+// line is 5, 7, or 5 syllables.
+var fallbackLines = [][]string{
+	{"a quiet %s", "one small %s stirs", "the last %s sleeps"},
+	{"a change drifts past the %s", "we leave the %s as is", "%s keeps what we mended"},
+	{"still as the %s", "home to the %s", "soft as a %s"},
+}
+
+// fallbackWords fill the slots in fallbackLines, and are all two syllables.
+var fallbackWords = map[Mood][]string{
+	MoodReflective: {"river", "lantern", "garden", "morning", "meadow", "willow", "harbor", "shadow", "ember", "moonlight"},
+	MoodTechnical:  {"server", "kernel", "cursor", "router", "process", "buffer", "module", "socket", "console", "network"},
+	MoodHumerous:   {"coffee", "pizza", "penguin", "pancake", "donut", "kitten", "goblin", "noodle", "waffle", "llama"},
+}
+
+// isModelOutage reports whether err means the text model could not be reached
+// at all, rather than that the request or its haiku was at fault.
+func isModelOutage(err error) bool {
+	return errors.Is(err, bedrock.ErrModelUnavailable) || errors.Is(err, bedrock.ErrThrottling)
+}
+
+// fallbackHaiku writes a 5-7-5 haiku from templates and word lists without
+// calling the model. The choices are seeded by a hash of the commit message,
+// so the same commit always gets the same haiku.
+func fallbackHaiku(commitMessage string, mood Mood) string {
+	words, ok := fallbackWords[mood]
+	if !ok {
+		words = fallbackWords[MoodReflective]
+	}
+
+	seed := sha256.Sum256([]byte(commitMessage))
+	lines := make([]string, len(fallbackLines))
+	for i, templates := range fallbackLines {
+		template := templates[int(seed[2*i])%len(templates)]
+		lines[i] = fmt.Sprintf(template, words[int(seed[2*i+1])%len(words)])
+	}
+
+	// Capitalize the first line like a generated haiku.
+	lines[0] = strings.ToUpper(lines[0][:1]) + lines[0][1:]
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,121 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/syllable"
+)
+
+func TestFallbackLinesAre575(t *testing.T) {
+	for i, templates := range fallbackLines {
+		for _, template := range templates {
+			for mood, words := range fallbackWords {
+				for _, word := range words {
+					line := fmt.Sprintf(template, word)
+					if count := syllable.Count(line); count != HaikuSyllables[i] {
+						t.Errorf("Expected %d syllables in %s line %q, got %d", HaikuSyllables[i], mood, line, count)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFallbackHaiku(t *testing.T) {
+	first := fallbackHaiku("fix: resolved login issue", MoodTechnical)
+	if again := fallbackHaiku("fix: resolved login issue", MoodTechnical); again != first {
+		t.Errorf("Expected the same haiku for the same commit, got %q and %q", first, again)
+	}
+	if err := CheckStructure(first); err != nil {
+		t.Errorf("Expected a 5-7-5 haiku, got %q: %v", first, err)
+	}
+
+	seen := make(map[string]bool)
+	for i := range 20 {
+		seen[fallbackHaiku(fmt.Sprintf("fix: bug %d", i), MoodReflective)] = true
+	}
+	if len(seen) < 10 {
+		t.Errorf("Expected varied haiku for different commits, got %d distinct", len(seen))
+	}
+}
+
+func TestCreateHaikuFallback(t *testing.T) {
+	tests := []struct {
+		name             string
+		fallback         bool
+		mockError        error
+		expectDegraded   bool
+		errorIs          error
+		expectedWarnings []string
+	}{
+		{
+			name:             "Model unavailable",
+			fallback:         true,
+			mockError:        fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+			expectDegraded:   true,
+			expectedWarnings: []string{SummaryUnavailable, IllustrationUnavailable},
+		},
+		{
+			name:             "Throttled",
+			fallback:         true,
+			mockError:        fmt.Errorf("%w: ThrottlingException", bedrock.ErrThrottling),
+			expectDegraded:   true,
+			expectedWarnings: []string{SummaryUnavailable, IllustrationUnavailable},
+		},
+		{
+			name:      "Fallback disabled",
+			mockError: fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+			errorIs:   bedrock.ErrModelUnavailable,
+		},
+		{
+			name:      "Other errors still fail",
+			fallback:  true,
+			mockError: fmt.Errorf("%w: ValidationException", bedrock.ErrValidation),
+			errorIs:   bedrock.ErrValidation,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{ErrorToReturn: tc.mockError}
+			service := NewHaikuService(mockClient, &Options{Fallback: tc.fallback})
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage:       "fix: resolved login issue",
+				IncludeSummary:      true,
+				IncludeIllustration: true,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if response.Degraded != tc.expectDegraded {
+				t.Errorf("Expected degraded %v, got %v", tc.expectDegraded, response.Degraded)
+			}
+			if expected := fallbackHaiku("fix: resolved login issue", MoodReflective); response.Haiku != expected {
+				t.Errorf("Expected haiku %q, got %q", expected, response.Haiku)
+			}
+			if response.Metadata.Model != FallbackModel {
+				t.Errorf("Expected model %q, got %q", FallbackModel, response.Metadata.Model)
+			}
+			if response.Summary != "" || response.Illustration != nil {
+				t.Errorf("Expected no summary or illustration, got %q and %+v", response.Summary, response.Illustration)
+			}
+			if !slices.Equal(response.Metadata.Warnings, tc.expectedWarnings) {
+				t.Errorf("Expected warnings %v, got %v", tc.expectedWarnings, response.Metadata.Warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+)
+
+// MockFeatureFlags turns off the flags it names and leaves the rest undefined.
+type MockFeatureFlags struct {
+	Off []string
+}
+
+func (m *MockFeatureFlags) Enabled(ctx context.Context, name string, def bool) bool {
+	if slices.Contains(m.Off, name) {
+		return false
+	}
+	return def
+}
+
+func TestCreateHaikuFeatureFlags(t *testing.T) {
+	tests := []struct {
+		name          string
+		off           []string
+		mood          Mood
+		expectedCalls int
+		expectGuide   bool
+		errorIs       error
+	}{
+		{
+			name:          "Nothing turned off",
+			expectedCalls: 1,
+			expectGuide:   true,
+		},
+		{
+			name:          "Response cache off",
+			off:           []string{flags.ResponseCache},
+			expectedCalls: 2,
+			expectGuide:   true,
+		},
+		{
+			name:          "Style guides off",
+			off:           []string{flags.StyleGuides},
+			expectedCalls: 1,
+		},
+		{
+			name:    "Mood off",
+			off:     []string{flags.MoodFlag("humorous")},
+			mood:    MoodHumerous,
+			errorIs: ErrBadHaikuRequest,
+		},
+		{
+			name:          "Another mood off",
+			off:           []string{flags.MoodFlag("humorous")},
+			mood:          MoodTechnical,
+			expectedCalls: 1,
+			expectGuide:   true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku"}
+			mockClient.InvokeClaudeFunc = func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+				calls++
+				return "haiku", nil
+			}
+			service := NewHaikuService(mockClient, &Options{
+				ResponseCache: NewMemoryResponseCache(10, 0),
+				Styles:        &MockStyleGuides{GuideToReturn: "Mention the office cat."},
+				Flags:         &MockFeatureFlags{Off: tc.off},
+			})
+
+			request := HaikuCommitRequest{CommitMessage: "fix typo", Mood: tc.mood}
+			for range 2 {
+				if _, err := service.CreateHaiku(context.Background(), request); !errors.Is(err, tc.errorIs) {
+					t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+				}
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d model calls for two requests, got %d", tc.expectedCalls, calls)
+			}
+			if guide := strings.Contains(mockClient.LastOptions.System, "office cat"); guide != tc.expectGuide {
+				t.Errorf("Expected style guide %v, got system prompt %q", tc.expectGuide, mockClient.LastOptions.System)
+			}
+		})
+	}
+}
@@ -4,60 +4,621 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/audit"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/canonical"
 	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/redact"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
 )
 
 var (
-	ErrBadHaikuRequest = errors.New("bad haiku request received")
-	ErrCreateHaiku     = errors.New("error creating commit message haiku")
+	ErrBadHaikuRequest  = errors.New("bad haiku request received")
+	ErrCreateHaiku      = errors.New("error creating commit message haiku")
+	ErrContentBlocked   = errors.New("generated haiku was blocked by the content filter")
+	ErrInvalidStructure = errors.New("generated haiku is not 5-7-5")
 )
 
 type BedrockClient interface {
-	InvokeClaude(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error)
+	InvokeClaude(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (bedrock.ClaudeResult, error)
+}
+
+// Redactor hides text from every log line until release is called.
+type Redactor interface {
+	Hide(text string) (release func())
+}
+
+type PromptProvider interface {
+	Select(ctx context.Context, key string) *prompt.Set
+}
+
+// FeatureFlags answers whether a flag is on for the caller of ctx, or def
+// when the flag isn't defined.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string, def bool) bool
 }
 
 type HaikuService struct {
-	bedrockClient BedrockClient
+	bedrockClient     BedrockClient
+	prompts           PromptProvider
+	moderator         moderation.Moderator
+	moderationRetries int
+	structureRetries  int
+	modelID           string
+	images            ImageGenerator
+	imageModelID      string
+	responseCache     ResponseCache
+	artifacts         ObjectStore
+	artifactURLTTL    time.Duration
+	speech            SpeechSynthesizer
+	voiceID           string
+	commitContent     redact.Mode
+	redactor          Redactor
+	pii               PIIRedactor
+	fallback          bool
+	usage             UsageRecorder
+	archive           Archive
+	styles            StyleGuides
+	flags             FeatureFlags
+	shadow            ShadowSampler
+	issues            IssueResolver
+	notifier          Notifier
+	events            EventPublisher
+	now               func() time.Time
 }
 
-func NewHaikuService(bedrockClient BedrockClient) *HaikuService {
-	return &HaikuService{
-		bedrockClient: bedrockClient,
+type Options struct {
+	ModelID           string               // Text model used for every request (default: Claude Haiku 4.5)
+	Prompts           PromptProvider       // Source of the commit haiku prompt (default: compiled-in templates)
+	Moderator         moderation.Moderator // Screens generated haiku (default: none)
+	ModerationRetries int                  // Regenerations allowed for blocked haiku (default: 0)
+	StructureRetries  int                  // Regenerations allowed for haiku that are not 5-7-5 in strict requests (default: 0)
+	Images            ImageGenerator       // Renders illustration prompts (default: prompt only)
+	ImageModelID      string               // Image model used by Images (default: Titan Image Generator v2)
+	ResponseCache     ResponseCache        // Shares haiku between equivalent requests (default: none)
+	Artifacts         ObjectStore          // Stores share cards and audio (default: none; both are unavailable)
+	ArtifactURLTTL    time.Duration        // Lifetime of artifact links (default: 1 hour)
+	Speech            SpeechSynthesizer    // Reads haiku aloud (default: none; audio is unavailable)
+	VoiceID           string               // Voice used by Speech (default: Joanna)
+	LogFullPrompts    bool                 // Log prompts in full rather than hashed, as CommitContent full does (default: false)
+	CommitContent     redact.Mode          // How prompts, which contain commit content, are logged (default: hashed)
+	Redactor          Redactor             // Hides the commit messages being written about from every log line (default: none, only prompts are hidden)
+	PII               PIIRedactor          // Removes personal data from commit text before it is sent to the model (default: none)
+	Fallback          bool                 // Write a haiku locally while the text model is unavailable (default: false)
+	Usage             UsageRecorder        // Keeps usage statistics (default: none)
+	Archive           Archive              // Keeps served haiku so they can be voted on (default: none, haiku have no ID)
+	Styles            StyleGuides          // Tenants' house styles merged into the prompt (default: none)
+	Flags             FeatureFlags         // Turns the response cache, moods and style guides off per caller (default: none, all on)
+	Shadow            ShadowSampler        // Writes a share of haiku again with an alternate prompt or model (default: none)
+	Issues            IssueResolver        // Looks up the titles of issues commits reference, for the prompt (default: none)
+	Notifier          Notifier             // Pushes newly written haiku to the channels following them (default: none)
+	Events            EventPublisher       // Publishes newly written haiku for consumers downstream (default: none)
+}
+
+func NewHaikuService(bedrockClient BedrockClient, opts *Options) *HaikuService {
+	service := &HaikuService{
+		bedrockClient:  bedrockClient,
+		modelID:        bedrock.ClaudeModelID,
+		artifactURLTTL: DefaultArtifactURLTTL,
+		prompts:        prompt.NewStaticStore(DefaultPromptDefinitions()),
+		commitContent:  redact.ModeHash,
+		now:            time.Now,
+	}
+
+	if opts != nil {
+		if opts.ModelID != "" {
+			service.modelID = opts.ModelID
+		}
+		if opts.Prompts != nil {
+			service.prompts = opts.Prompts
+		}
+		if opts.Moderator != nil {
+			service.moderator = opts.Moderator
+		}
+		if opts.ModerationRetries > 0 {
+			service.moderationRetries = opts.ModerationRetries
+		}
+		if opts.StructureRetries > 0 {
+			service.structureRetries = opts.StructureRetries
+		}
+		if opts.Images != nil {
+			service.images = opts.Images
+			service.imageModelID = opts.ImageModelID
+		}
+		if opts.ResponseCache != nil {
+			service.responseCache = opts.ResponseCache
+		}
+		if opts.Artifacts != nil {
+			service.artifacts = opts.Artifacts
+		}
+		if opts.ArtifactURLTTL > 0 {
+			service.artifactURLTTL = opts.ArtifactURLTTL
+		}
+		if opts.Speech != nil {
+			service.speech = opts.Speech
+			service.voiceID = opts.VoiceID
+		}
+		if opts.LogFullPrompts {
+			service.commitContent = redact.ModeFull
+		}
+		if opts.CommitContent.IsValid() {
+			service.commitContent = opts.CommitContent
+		}
+		service.redactor = opts.Redactor
+		service.pii = opts.PII
+		service.fallback = opts.Fallback
+		service.usage = opts.Usage
+		service.archive = opts.Archive
+		service.styles = opts.Styles
+		service.flags = opts.Flags
+		service.shadow = opts.Shadow
+		service.issues = opts.Issues
+		service.notifier = opts.Notifier
+		service.events = opts.Events
 	}
+
+	return service
 }
 
-func NewDefaultHaikuService(cfg aws.Config) *HaikuService {
-	return NewHaikuService(bedrock.NewDefaultBedrockClient(cfg))
+// flagEnabled reports whether the behaviour behind the flag name is on for
+// the caller of ctx. Flagged behaviour is on unless a flag turns it off.
+func (h *HaikuService) flagEnabled(ctx context.Context, name string) bool {
+	return h.flags == nil || h.flags.Enabled(ctx, name, true)
+}
+
+// DefaultPromptDefinitions returns the compiled-in commit haiku prompt.
+func DefaultPromptDefinitions() prompt.Definitions {
+	return prompt.Definitions{
+		Version: prompt.DefaultVersion,
+		System:  HaikuSystemPrompt,
+		Prompt:  HaikuPromptTemplate,
+	}
 }
 
 func (h *HaikuService) CreateHaiku(ctx context.Context, request HaikuCommitRequest) (HaikuCommitResponse, error) {
+	return h.createHaiku(ctx, request, nil)
+}
+
+// createHaiku writes a haiku for request, publishing an event when it can't
+// be written. The commit message is hidden from logs until it is written.
+func (h *HaikuService) createHaiku(ctx context.Context, request HaikuCommitRequest, follow followUp) (HaikuCommitResponse, error) {
+	defer h.hideCommitMessages(request.CommitMessage)()
+
+	response, err := h.writeHaiku(ctx, request, follow)
+	if err != nil {
+		h.publishFailure(ctx, request, err)
+	}
+	return response, err
+}
+
+// publishFailure publishes the failure of request with err, unless err is the
+// request's own fault.
+func (h *HaikuService) publishFailure(ctx context.Context, request HaikuCommitRequest, err error) {
+	reason, failed := failureReason(err)
+	if h.events == nil || !failed || !h.flagEnabled(ctx, flags.HaikuEvents) {
+		return
+	}
+
+	var repository string
+	if request.Repository != nil {
+		repository = request.Repository.Name
+	}
+	h.events.Failed(ctx, HaikuFailure{
+		Tenant:     keys.TenantFromContext(ctx),
+		CommitRef:  request.CommitRef,
+		Repository: repository,
+		Mood:       request.Mood,
+		Reason:     reason,
+		Error:      err.Error(),
+		FailedAt:   h.now().UTC(),
+	})
+}
+
+// writeHaiku writes a haiku for request. When follow is set, the model is
+// told how the haiku follows those written before it, and the haiku is kept
+// linked to any it replaces.
+func (h *HaikuService) writeHaiku(ctx context.Context, request HaikuCommitRequest, follow followUp) (HaikuCommitResponse, error) {
+	endValidate := timing.Start(ctx, timing.StageValidate)
 	mood := request.Mood
-	if mood != "" && !mood.IsValid() {
-		log.Printf("[HAIKU SERVICE] invalid mood: %s\n", mood)
+	if mood != "" && mood != MoodAuto && !mood.IsValid() {
+		logging.Warnf("[HAIKU SERVICE] invalid mood: %s\n", mood)
 		return HaikuCommitResponse{}, ErrBadHaikuRequest
 	}
 
-	if request.Mood == "" {
+	switch request.Mood {
+	case "":
 		mood = MoodReflective
+	case MoodAuto:
+		mood = h.inferMood(ctx, request.CommitMessage)
+	}
+	if request.Mood != "" && request.Mood != MoodAuto && !h.flagEnabled(ctx, flags.MoodFlag(string(mood))) {
+		logging.Warnf("[HAIKU SERVICE] mood turned off by its feature flag: %s\n", mood)
+		return HaikuCommitResponse{}, fmt.Errorf("%w: mood %s is not available", ErrBadHaikuRequest, mood)
+	}
+
+	if request.Register != "" && !request.Register.IsValid() {
+		logging.Warnf("[HAIKU SERVICE] invalid register: %s\n", request.Register)
+		return HaikuCommitResponse{}, ErrBadHaikuRequest
+	}
+
+	if request.PairingStyle != "" && !request.PairingStyle.IsValid() {
+		logging.Warnf("[HAIKU SERVICE] invalid pairing style: %s\n", request.PairingStyle)
+		return HaikuCommitResponse{}, ErrBadHaikuRequest
+	}
+	// Strict mode checks for a single 5-7-5 haiku, which linked verses aren't.
+	if request.Strict && request.PairingStyle == PairingVerses {
+		logging.Warnf("[HAIKU SERVICE] strict request for linked verses\n")
+		return HaikuCommitResponse{}, fmt.Errorf("%w: strict requests can't ask for linked verses", ErrBadHaikuRequest)
 	}
 
-	prompt := fmt.Sprintf("Create a %s haiku from this commit message: %s", mood, request.CommitMessage)
+	if len(request.CommitRef) > MaxCommitRefLength || strings.ContainsFunc(request.CommitRef, unicode.IsControl) {
+		logging.Warnf("[HAIKU SERVICE] invalid commit ref of %d bytes\n", len(request.CommitRef))
+		return HaikuCommitResponse{}, fmt.Errorf("%w: commitRef must be at most %d characters, without control characters", ErrBadHaikuRequest, MaxCommitRefLength)
+	}
+
+	if request.Variants < 0 {
+		logging.Warnf("[HAIKU SERVICE] invalid variants: %d\n", request.Variants)
+		return HaikuCommitResponse{}, fmt.Errorf("%w: variants must not be negative", ErrBadHaikuRequest)
+	}
+	variants := request.Variants
+	var adjusted []string
+	if variants > MaxVariants {
+		adjusted = append(adjusted, fmt.Sprintf("variants reduced from %d to the limit of %d", variants, MaxVariants))
+		variants = MaxVariants
+	}
+
+	// Deterministic haiku come from the cache first and are sampled at
+	// temperature 0, so anything varying them is refused.
+	if request.Deterministic && (variants > 1 || request.Temperature != 0 || request.NoCache) {
+		logging.Warnf("[HAIKU SERVICE] deterministic request with variants, temperature or noCache\n")
+		return HaikuCommitResponse{}, fmt.Errorf("%w: deterministic requests can't set variants, temperature or noCache", ErrBadHaikuRequest)
+	}
+
+	coAuthors := ParseCoAuthors(request.CommitMessage)
+	author := normalizeAuthor(request.Author)
+	commitMessage, _ := SplitCoAuthors(request.CommitMessage)
+	commitMessage, neutralized := sanitizeInput(commitMessage)
+	if neutralized {
+		logging.Warnf("[HAIKU SERVICE] neutralized instruction-like content in commit message\n")
+	}
+	commitMessage, redacted, err := h.redactPII(ctx, commitMessage)
+	if err != nil {
+		return HaikuCommitResponse{}, err
+	}
+	endValidate()
+
+	// Deterministic prompts are written from the canonical commit message, so
+	// commits differing only in ticket references, SHAs, case or whitespace
+	// get the same haiku.
+	endPrompt := timing.Start(ctx, timing.StagePrompt)
+	promptKey, promptMessage := request.CommitMessage, commitMessage
+	if request.Deterministic {
+		promptKey, promptMessage = canonical.Key(request.CommitMessage), canonical.Canonicalize(commitMessage)
+	}
+	prompts := h.prompts.Select(ctx, promptKey)
+
+	repository := repositoryPromptData(request.Repository)
+	issues := h.resolveIssues(ctx, commitMessage, request.Repository)
+	prompt, err := prompts.Render(prompt.PromptData{
+		Mood:          string(mood),
+		Register:      string(request.Register),
+		CommitMessage: promptMessage,
+		Repository:    repository,
+		Issues:        issues,
+	})
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error rendering prompt: %v\n", err)
+		return HaikuCommitResponse{}, fmt.Errorf("%w: rendering prompt: %w", ErrCreateHaiku, err)
+	}
+
+	system := prompts.System
+	if guidance, ok := RegisterGuidance[request.Register]; ok {
+		system = strings.TrimRight(system, "\n") + "\n\n" + guidance + "\n"
+	}
+	verses := 0
+	if (request.IncludePairing || request.PairingStyle != "") && len(coAuthors) > 0 {
+		var guidance string
+		guidance, verses = pairingGuidance(coAuthors, request.PairingStyle)
+		system = strings.TrimRight(system, "\n") + "\n\n" + guidance + "\n"
+	}
+	if request.IncludeAuthor && author != nil {
+		system = strings.TrimRight(system, "\n") + "\n\n" + authorGuidance(author) + "\n"
+	}
+	if commit, ok := ParseConventionalCommit(commitMessage); ok {
+		if guidance := commit.guidance(); guidance != "" {
+			system = strings.TrimRight(system, "\n") + "\n\n" + guidance + "\n"
+		}
+	}
+	if guide := h.styleGuide(ctx); guide != "" {
+		system = strings.TrimRight(system, "\n") + "\n\n" + fmt.Sprintf(StyleGuideTemplate, guide) + "\n"
+	}
+	if follow != nil {
+		system = strings.TrimRight(system, "\n") + "\n\n" + follow.guidance() + "\n"
+	}
+	endPrompt()
 
 	options := &bedrock.ClaudeOptions{
-		System: HaikuSystemPrompt,
+		ModelID:       h.modelID,
+		MaxTokens:     request.MaxTokens,
+		Temperature:   request.Temperature,
+		System:        system,
+		Deterministic: request.Deterministic,
+	}
+
+	// The summary is an independent model call, so run it alongside the haiku.
+	var summary summaryResult
+	var wg sync.WaitGroup
+	if request.IncludeSummary {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer timing.Start(ctx, timing.StageSummary)()
+			summary.text, summary.err = h.createSummary(ctx, commitMessage)
+		}()
 	}
 
-	log.Printf("[HAIKU SERVICE] sending request to Bedrock: %s\n", prompt)
-	response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+	logging.Debugf("[HAIKU SERVICE] sending request to Bedrock with prompt version %s: %s\n", prompts.Version, h.loggablePrompt(prompt))
+	generateStart := h.now()
+	responseCache := h.cacheFor(ctx)
+	var response bedrock.ClaudeResult
+	var cached bool
+	var candidates []generated
+	if variants > 1 {
+		candidates, err = h.generateVariants(ctx, responseCache, prompt, options, request.Strict, request.NoCache, variants)
+		if err == nil {
+			response = candidates[0].result
+			cached = !slices.ContainsFunc(candidates, func(candidate generated) bool { return !candidate.cached })
+		}
+	} else {
+		response, cached, err = h.generateCached(ctx, responseCache, prompt, options, request.Strict, request.NoCache)
+	}
+	latency := h.now().Sub(generateStart)
+	wg.Wait()
+
+	// A model outage shouldn't block a commit, so write a haiku locally and
+	// leave out anything else that needs the model.
+	degraded := false
+	if err != nil && h.fallback && isModelOutage(err) {
+		logging.Warnf("[HAIKU SERVICE] model unavailable, returning a fallback haiku: %v\n", err)
+		response = bedrock.ClaudeResult{Text: fallbackHaiku(commitMessage, mood), ModelID: FallbackModel}
+		degraded, err = true, nil
+	}
 	if err != nil {
-		log.Printf("[HAIKU SERVICE] error invoking Claude: %v\n", err)
-		return HaikuCommitResponse{}, fmt.Errorf("%w: invoking Claude: %v", ErrCreateHaiku, err)
+		return HaikuCommitResponse{}, err
+	}
+
+	warnings := response.Warnings
+	if len(adjusted) > 0 {
+		warnings = append(slices.Clone(warnings), adjusted...)
+	}
+	if variants > 1 && degraded {
+		warnings = append(slices.Clone(warnings), VariantsUnavailable)
+	}
+	if verses > 0 && degraded {
+		warnings = append(slices.Clone(warnings), VersesUnavailable)
+		verses = 0
+	}
+	if summary.err != nil {
+		if !degraded {
+			logging.Errorf("[HAIKU SERVICE] error creating summary: %v\n", summary.err)
+			return HaikuCommitResponse{}, fmt.Errorf("%w: creating summary: %w", ErrCreateHaiku, summary.err)
+		}
+		warnings = append(slices.Clone(warnings), SummaryUnavailable)
+	}
+
+	// The illustration is drawn from the finished haiku, so it has to wait for it.
+	var illustration *Illustration
+	if request.IncludeIllustration && degraded {
+		warnings = append(slices.Clone(warnings), IllustrationUnavailable)
+	} else if request.IncludeIllustration {
+		endIllustration := timing.Start(ctx, timing.StageIllustration)
+		illustration, err = h.createIllustration(ctx, response.Text)
+		endIllustration()
+		if err != nil {
+			logging.Errorf("[HAIKU SERVICE] error creating illustration: %v\n", err)
+			return HaikuCommitResponse{}, fmt.Errorf("%w: creating illustration: %w", ErrCreateHaiku, err)
+		}
+	}
+
+	var shareCard *Artifact
+	if request.IncludeShareCard {
+		if h.artifacts == nil {
+			logging.Warnf("[HAIKU SERVICE] share card requested but no object store is configured\n")
+			warnings = append(slices.Clone(warnings), ShareCardsNotConfigured)
+		} else {
+			endShareCard := timing.Start(ctx, timing.StageShareCard)
+			shareCard, err = h.createShareCard(ctx, response.Text, commitMessage, request.Repository)
+			endShareCard()
+			if err != nil {
+				logging.Errorf("[HAIKU SERVICE] error creating share card: %v\n", err)
+				return HaikuCommitResponse{}, fmt.Errorf("%w: creating share card: %w", ErrCreateHaiku, err)
+			}
+		}
+	}
+
+	var audio *Artifact
+	if request.IncludeAudio {
+		if h.artifacts == nil || h.speech == nil {
+			logging.Warnf("[HAIKU SERVICE] audio requested but speech or object store is not configured\n")
+			warnings = append(slices.Clone(warnings), AudioNotConfigured)
+		} else {
+			endAudio := timing.Start(ctx, timing.StageAudio)
+			audio, err = h.createAudio(ctx, response.Text)
+			endAudio()
+			if err != nil {
+				logging.Errorf("[HAIKU SERVICE] error creating audio: %v\n", err)
+				return HaikuCommitResponse{}, fmt.Errorf("%w: creating audio: %w", ErrCreateHaiku, err)
+			}
+		}
+	}
+
+	defer timing.Start(ctx, timing.StagePostProcess)()
+	promptVersion, model := prompts.Version, response.ModelID
+	if degraded {
+		promptVersion, model = "", ""
+	}
+
+	usage := Usage{Time: h.now(), Mood: mood, PromptVersion: promptVersion, Model: model, Cached: cached}
+	if request.Repository != nil {
+		usage.Repository = request.Repository.Name
+	}
+	if author != nil {
+		usage.Author = author.Key()
+	}
+	if !cached && !degraded {
+		usage.Latency = latency
+		usage.InputTokens = response.Usage.InputTokens
+		usage.OutputTokens = response.Usage.OutputTokens
+	}
+	if len(candidates) > 0 {
+		usage.Variants = len(candidates)
+		usage.InputTokens, usage.OutputTokens = 0, 0
+		for _, candidate := range candidates {
+			if !candidate.cached {
+				usage.InputTokens += candidate.result.Usage.InputTokens
+				usage.OutputTokens += candidate.result.Usage.OutputTokens
+			}
+		}
+	}
+	h.recordUsage(ctx, usage)
+
+	// Fallback haiku aren't the model's work, so they aren't kept for voting.
+	// Each variant is kept in its own right, so whichever the caller picks can
+	// be voted for.
+	var id, previous string
+	if follow != nil {
+		previous = follow.replaces()
+	}
+	var served []Variant
+	if !degraded {
+		archive := func(result bedrock.ClaudeResult, options *bedrock.ClaudeOptions) string {
+			var cacheKey string
+			if responseCache != nil {
+				cacheKey = responseCacheKey(keys.TenantFromContext(ctx), prompt, options)
+			}
+			return h.archiveHaiku(ctx, Stored{
+				Haiku:         result.Text,
+				Mood:          mood,
+				Repository:    usage.Repository,
+				Author:        usage.Author,
+				PromptVersion: promptVersion,
+				Model:         result.ModelID,
+				KeyID:         keys.IDFromContext(ctx),
+				Tenant:        keys.TenantFromContext(ctx),
+				CacheKey:      cacheKey,
+				Previous:      previous,
+				CreatedAt:     usage.Time,
+			})
+		}
+		if len(candidates) == 0 {
+			id = archive(response, options)
+		}
+		for _, candidate := range candidates {
+			variantOptions := *options
+			variantOptions.Temperature = candidate.temperature
+			served = append(served, Variant{
+				ID:          archive(candidate.result, &variantOptions),
+				Haiku:       candidate.result.Text,
+				Temperature: candidate.temperature,
+				Cached:      candidate.cached,
+			})
+		}
+		if len(served) > 0 {
+			id = served[0].ID
+		}
+
+		if h.shadow != nil {
+			var credited string
+			if author != nil {
+				credited = author.Key()
+			}
+			h.shadow.Sample(ctx, ShadowRequest{
+				HaikuID:       id,
+				Tenant:        keys.TenantFromContext(ctx),
+				Author:        credited,
+				Mood:          mood,
+				Register:      request.Register,
+				CommitMessage: commitMessage,
+				Repository:    repository,
+				Issues:        issues,
+				Haiku:         response.Text,
+				Model:         response.ModelID,
+				PromptVersion: promptVersion,
+			})
+		}
+	}
+
+	if len(served) == 0 {
+		audit.Served(ctx, audit.Haiku{ID: id, Model: response.ModelID, PromptVersion: promptVersion, Cached: cached})
+	}
+	for i, variant := range served {
+		audit.Served(ctx, audit.Haiku{ID: variant.ID, Model: candidates[i].result.ModelID, PromptVersion: promptVersion, Cached: variant.Cached})
+	}
+
+	var attribution string
+	if author != nil {
+		attribution = author.Attribution()
+	}
+
+	// Cached haiku were pushed when they were first written.
+	if h.notifier != nil && !degraded && !cached {
+		h.notifier.Notify(ctx, Notification{
+			HaikuID:     id,
+			Tenant:      keys.TenantFromContext(ctx),
+			Repository:  usage.Repository,
+			Attribution: attribution,
+			Mood:        mood,
+			Haiku:       response.Text,
+			CreatedAt:   usage.Time.UTC(),
+		})
+	}
+	if h.events != nil && !degraded && !cached && h.flagEnabled(ctx, flags.HaikuEvents) {
+		h.events.Publish(ctx, HaikuEvent{
+			ID:            id,
+			Tenant:        keys.TenantFromContext(ctx),
+			CommitRef:     request.CommitRef,
+			Repository:    usage.Repository,
+			Mood:          mood,
+			Haiku:         response.Text,
+			Model:         response.ModelID,
+			PromptVersion: promptVersion,
+			InputTokens:   usage.InputTokens,
+			OutputTokens:  usage.OutputTokens,
+			CreatedAt:     usage.Time.UTC(),
+		})
 	}
 
 	return HaikuCommitResponse{
-		Haiku: response,
+		ID:           id,
+		Previous:     previous,
+		Haiku:        response.Text,
+		Summary:      summary.text,
+		Illustration: illustration,
+		ShareCard:    shareCard,
+		Audio:        audio,
+		Variants:     served,
+		Degraded:     degraded,
+		Metadata: HaikuMetadata{
+			PromptVersion: promptVersion,
+			Mood:          mood,
+			Register:      request.Register,
+			Model:         response.ModelID,
+			Cached:        cached,
+			Warnings:      warnings,
+			CoAuthors:     coAuthors,
+			Attribution:   attribution,
+			Verses:        verses,
+			Redacted:      redacted,
+		},
 	}, nil
 }
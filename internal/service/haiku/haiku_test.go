@@ -3,19 +3,51 @@ package haiku
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
 )
 
 // MockBedrockClient implements the BedrockClient interface for testing
 type MockBedrockClient struct {
 	ResponseToReturn string
+	WarningsToReturn []string
+	UsageToReturn    bedrock.Usage
 	ErrorToReturn    error
+	InvokeClaudeFunc func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error)
+
+	mu          sync.Mutex
+	LastPrompt  string
+	LastOptions *bedrock.ClaudeOptions
 }
 
-func (m *MockBedrockClient) InvokeClaude(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
-	return m.ResponseToReturn, m.ErrorToReturn
+func (m *MockBedrockClient) InvokeClaude(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (bedrock.ClaudeResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.LastPrompt = prompt
+	m.LastOptions = opts
+
+	response, err := m.ResponseToReturn, m.ErrorToReturn
+	if m.InvokeClaudeFunc != nil {
+		response, err = m.InvokeClaudeFunc(ctx, prompt, opts)
+	}
+	if err != nil {
+		return bedrock.ClaudeResult{}, err
+	}
+	return bedrock.ClaudeResult{
+		Text:     response,
+		ModelID:  opts.ModelID,
+		Usage:    m.UsageToReturn,
+		Warnings: m.WarningsToReturn,
+	}, nil
 }
 
 func TestCreateHaiku(t *testing.T) {
@@ -35,7 +67,7 @@ func TestCreateHaiku(t *testing.T) {
 			name:           "Default mood",
 			commitMessage:  "fix: resolved login issue",
 			mood:           Mood(""), // Empty to test default
-			expectedPrompt: "Create a reflective haiku from this commit message: fix: resolved login issue",
+			expectedPrompt: "Create a reflective haiku from this commit message:\n<commit_message>\nfix: resolved login issue\n</commit_message>",
 			mockResponse:   "Code changes merged\nBugs squashed with precision now\nUsers rejoice, yay",
 			mockError:      nil,
 			expectError:    false,
@@ -44,7 +76,7 @@ func TestCreateHaiku(t *testing.T) {
 			name:           "Custom mood",
 			commitMessage:  "refactor: optimize database queries",
 			mood:           MoodTechnical,
-			expectedPrompt: "Create a technical haiku from this commit message: refactor: optimize database queries",
+			expectedPrompt: "Create a technical haiku from this commit message:\n<commit_message>\nrefactor: optimize database queries\n</commit_message>",
 			mockResponse:   "Technical changes\nRefactoring the codebase\nPerformance improved",
 			mockError:      nil,
 			expectError:    false,
@@ -63,12 +95,20 @@ func TestCreateHaiku(t *testing.T) {
 			name:           "Bedrock error",
 			commitMessage:  "fix: resolved login issue",
 			mood:           MoodReflective,
-			expectedPrompt: "Create a reflective haiku from this commit message: fix: resolved login issue",
+			expectedPrompt: "Create a reflective haiku from this commit message:\n<commit_message>\nfix: resolved login issue\n</commit_message>",
 			mockResponse:   "",
 			mockError:      mockError,
 			expectError:    true,
 			errorIs:        ErrCreateHaiku,
 		},
+		{
+			name:          "Bedrock throttling kept in the chain",
+			commitMessage: "fix: resolved login issue",
+			mood:          MoodReflective,
+			mockError:     fmt.Errorf("%w: ThrottlingException", bedrock.ErrThrottling),
+			expectError:   true,
+			errorIs:       bedrock.ErrThrottling,
+		},
 	}
 
 	for _, tc := range tests {
@@ -78,7 +118,7 @@ func TestCreateHaiku(t *testing.T) {
 				ErrorToReturn:    tc.mockError,
 			}
 
-			service := NewHaikuService(mockClient)
+			service := NewHaikuService(mockClient, nil)
 			request := HaikuCommitRequest{
 				CommitMessage: tc.commitMessage,
 				Mood:          tc.mood,
@@ -86,6 +126,10 @@ func TestCreateHaiku(t *testing.T) {
 
 			response, err := service.CreateHaiku(context.Background(), request)
 
+			if tc.expectedPrompt != "" && mockClient.LastPrompt != tc.expectedPrompt {
+				t.Errorf("Expected prompt %q, got %q", tc.expectedPrompt, mockClient.LastPrompt)
+			}
+
 			if tc.expectError {
 				if err == nil {
 					t.Errorf("Expected error but got nil")
@@ -108,3 +152,147 @@ func TestCreateHaiku(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateHaikuWithPromptStore(t *testing.T) {
+	store := prompt.NewStaticStore(prompt.Definitions{
+		Version: "v2",
+		System:  "tuned system prompt",
+		Prompt:  HaikuPromptTemplate,
+		Moods: map[string]string{
+			string(MoodHumerous): "Write a silly haiku about: {{.CommitMessage}}",
+		},
+	})
+
+	mockClient := &MockBedrockClient{ResponseToReturn: "haiku"}
+	service := NewHaikuService(mockClient, &Options{Prompts: store})
+
+	response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+		CommitMessage: "feat: add easter egg",
+		Mood:          MoodHumerous,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if expected := "Write a silly haiku about: feat: add easter egg"; mockClient.LastPrompt != expected {
+		t.Errorf("Expected prompt %q, got %q", expected, mockClient.LastPrompt)
+	}
+	if expected := "tuned system prompt\n\n" + CommitTypeGuidance["feat"] + "\n"; mockClient.LastOptions == nil || mockClient.LastOptions.System != expected {
+		t.Errorf("Expected tuned system prompt, got %+v", mockClient.LastOptions)
+	}
+	if response.Metadata.PromptVersion != "v2" {
+		t.Errorf("Expected prompt version %q, got %q", "v2", response.Metadata.PromptVersion)
+	}
+}
+
+func TestCreateHaikuRegister(t *testing.T) {
+	tests := []struct {
+		name             string
+		register         Register
+		expectedGuidance string
+		errorIs          error
+	}{
+		{
+			name:             "Formal register",
+			register:         RegisterFormal,
+			expectedGuidance: RegisterGuidance[RegisterFormal],
+		},
+		{
+			name:             "Playful register",
+			register:         RegisterPlayful,
+			expectedGuidance: RegisterGuidance[RegisterPlayful],
+		},
+		{
+			name:     "No register",
+			register: "",
+		},
+		{
+			name:     "Invalid register",
+			register: Register("sarcastic"),
+			errorIs:  ErrBadHaikuRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku"}
+			service := NewHaikuService(mockClient, nil)
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage: "fix: resolved login issue",
+				Register:      tc.register,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if response.Metadata.Register != tc.register {
+				t.Errorf("Expected register %q in metadata, got %q", tc.register, response.Metadata.Register)
+			}
+
+			system := mockClient.LastOptions.System
+			if tc.expectedGuidance != "" && !strings.Contains(system, tc.expectedGuidance) {
+				t.Errorf("Expected system prompt to contain %q", tc.expectedGuidance)
+			}
+			if tc.expectedGuidance == "" && system != strings.TrimRight(HaikuSystemPrompt, "\n")+"\n\n"+CommitTypeGuidance["fix"]+"\n" {
+				t.Errorf("Expected only the commit type guidance without a register")
+			}
+		})
+	}
+}
+
+func TestCreateHaikuModelOptions(t *testing.T) {
+	const modelID = "us.anthropic.claude-sonnet-4-5-20250929-v1:0"
+	warnings := []string{"temperature reduced from 1.5 to the model limit of 1"}
+
+	mockClient := &MockBedrockClient{ResponseToReturn: "haiku", WarningsToReturn: warnings}
+	service := NewHaikuService(mockClient, &Options{ModelID: modelID})
+
+	response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+		CommitMessage: "fix: resolved login issue",
+		MaxTokens:     200,
+		Temperature:   1.5,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	options := mockClient.LastOptions
+	if options.ModelID != modelID || options.MaxTokens != 200 || options.Temperature != 1.5 {
+		t.Errorf("Expected requested options to be passed to the client, got %+v", options)
+	}
+	if response.Metadata.Model != modelID {
+		t.Errorf("Expected model %q in metadata, got %q", modelID, response.Metadata.Model)
+	}
+	if len(response.Metadata.Warnings) != 1 || response.Metadata.Warnings[0] != warnings[0] {
+		t.Errorf("Expected warnings %v in metadata, got %v", warnings, response.Metadata.Warnings)
+	}
+}
+
+func TestCreateHaikuRecordsStageTimings(t *testing.T) {
+	service := NewHaikuService(&MockBedrockClient{ResponseToReturn: "leaves fall"}, &Options{
+		Moderator: &MockModerator{ResultsToReturn: []moderation.Result{{}}},
+	})
+
+	ctx, timings := timing.NewContext(context.Background(), nil)
+	if _, err := service.CreateHaiku(ctx, HaikuCommitRequest{CommitMessage: "fix: resolved login issue", IncludeSummary: true}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	var names []string
+	for _, stage := range timings.Stages() {
+		names = append(names, stage.Name)
+	}
+	for _, expected := range []string{timing.StageValidate, timing.StagePrompt, timing.StageProvider, timing.StageModeration, timing.StageSummary, timing.StagePostProcess} {
+		if !slices.Contains(names, expected) {
+			t.Errorf("Expected stage %q to be recorded, got %v", expected, names)
+		}
+	}
+}
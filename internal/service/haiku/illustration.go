@@ -0,0 +1,54 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// illustrationMaxTokens keeps the image prompt within the image model's limit.
+const illustrationMaxTokens = 150
+
+// ImageGenerator renders an image prompt as a base64-encoded PNG.
+type ImageGenerator interface {
+	GenerateImage(ctx context.Context, prompt string, opts *bedrock.ImageOptions) (string, error)
+}
+
+// createIllustration asks the model to describe a small seasonal illustration
+// for the haiku and, when an image model is configured, renders it.
+func (h *HaikuService) createIllustration(ctx context.Context, haiku string) (*Illustration, error) {
+	prompt := fmt.Sprintf(IllustrationPromptTemplate, haiku)
+
+	options := &bedrock.ClaudeOptions{
+		ModelID:   h.modelID,
+		MaxTokens: illustrationMaxTokens,
+		System:    IllustrationSystemPrompt,
+	}
+
+	logging.Debugf("[HAIKU SERVICE] sending illustration prompt request to Bedrock: %s\n", h.loggablePrompt(prompt))
+	response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+	if err != nil {
+		return nil, err
+	}
+
+	illustration := &Illustration{
+		Prompt: strings.TrimSpace(response.Text),
+	}
+
+	if h.images == nil {
+		return illustration, nil
+	}
+
+	image, err := h.images.GenerateImage(ctx, illustration.Prompt, &bedrock.ImageOptions{
+		ModelID: h.imageModelID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	illustration.Image = image
+	return illustration, nil
+}
@@ -0,0 +1,122 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+type MockImageGenerator struct {
+	ImageToReturn string
+	ErrorToReturn error
+	LastPrompt    string
+	LastOptions   *bedrock.ImageOptions
+}
+
+func (m *MockImageGenerator) GenerateImage(ctx context.Context, prompt string, opts *bedrock.ImageOptions) (string, error) {
+	m.LastPrompt = prompt
+	m.LastOptions = opts
+	return m.ImageToReturn, m.ErrorToReturn
+}
+
+func TestCreateHaikuWithIllustration(t *testing.T) {
+	const (
+		testHaiku       = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+		testImagePrompt = "A watercolor of maple leaves drifting past an open wooden gate, muted amber tones."
+		testImage       = "aW1hZ2U="
+	)
+
+	tests := []struct {
+		name                string
+		includeIllustration bool
+		images              *MockImageGenerator
+		promptError         error
+		expected            *Illustration
+		errorIs             error
+	}{
+		{
+			name: "Illustration not requested",
+		},
+		{
+			name:                "Prompt only without image model",
+			includeIllustration: true,
+			expected:            &Illustration{Prompt: testImagePrompt},
+		},
+		{
+			name:                "Prompt and image",
+			includeIllustration: true,
+			images:              &MockImageGenerator{ImageToReturn: testImage},
+			expected:            &Illustration{Prompt: testImagePrompt, Image: testImage},
+		},
+		{
+			name:                "Prompt error",
+			includeIllustration: true,
+			promptError:         errors.New("bedrock API error"),
+			errorIs:             ErrCreateHaiku,
+		},
+		{
+			name:                "Image error",
+			includeIllustration: true,
+			images:              &MockImageGenerator{ErrorToReturn: errors.New("image model error")},
+			errorIs:             ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{
+				InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+					if opts.System == IllustrationSystemPrompt {
+						if !strings.Contains(prompt, testHaiku) {
+							t.Errorf("Expected illustration prompt to contain the haiku, got %q", prompt)
+						}
+						return "\n" + testImagePrompt + " ", tc.promptError
+					}
+					return testHaiku, nil
+				},
+			}
+
+			opts := &Options{ImageModelID: "amazon.nova-canvas-v1:0"}
+			if tc.images != nil {
+				opts.Images = tc.images
+			}
+
+			service := NewHaikuService(mockClient, opts)
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage:       "fix: resolved login issue",
+				IncludeIllustration: tc.includeIllustration,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tc.expected == nil {
+				if response.Illustration != nil {
+					t.Errorf("Expected no illustration, got %+v", response.Illustration)
+				}
+				return
+			}
+			if response.Illustration == nil || *response.Illustration != *tc.expected {
+				t.Errorf("Expected illustration %+v, got %+v", tc.expected, response.Illustration)
+			}
+			if tc.images != nil {
+				if tc.images.LastPrompt != testImagePrompt {
+					t.Errorf("Expected image prompt %q, got %q", testImagePrompt, tc.images.LastPrompt)
+				}
+				if tc.images.LastOptions.ModelID != "amazon.nova-canvas-v1:0" {
+					t.Errorf("Expected image model %q, got %q", "amazon.nova-canvas-v1:0", tc.images.LastOptions.ModelID)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package haiku
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+)
+
+// MaxIssueRefs caps the issue references looked up for one commit.
+const MaxIssueRefs = 3
+
+const (
+	// issueLookupTimeout bounds the time spent looking up issue titles, which
+	// only enrich the prompt and must not hold up the haiku.
+	issueLookupTimeout = 2 * time.Second
+	// maxIssueTitleLength caps each issue title shared with the model.
+	maxIssueTitleLength = 200
+)
+
+var (
+	// issueKeyPattern matches issue tracker keys such as JIRA-4521.
+	issueKeyPattern = regexp.MustCompile(`\b[A-Z][A-Z0-9_]+-[0-9]+\b`)
+	// issueNumberPattern matches GitHub style references such as #456.
+	issueNumberPattern = regexp.MustCompile(`(?:^|[\s(\[])#([0-9]+)\b`)
+)
+
+// standardKeys look like issue keys but name standards, e.g. UTF-8.
+var standardKeys = []string{"UTF", "SHA", "ISO", "RFC", "MD", "ES", "X"}
+
+// IssueRef is an issue a commit message references.
+type IssueRef struct {
+	Key        string // Issue tracker key, e.g. "JIRA-4521"; empty for a #456 reference
+	Number     int    // Number of a #456 reference
+	Repository string // Repository a #456 reference belongs to, e.g. "octo-org/octo-repo"
+}
+
+// String returns the reference as written in the commit message.
+func (r IssueRef) String() string {
+	if r.Key != "" {
+		return r.Key
+	}
+	return "#" + strconv.Itoa(r.Number)
+}
+
+// IssueResolver looks up the titles of issues commits reference, e.g. the
+// issues service backed by Jira and GitHub. It returns an error for issues it
+// can't look up.
+type IssueResolver interface {
+	IssueTitle(ctx context.Context, ref IssueRef) (string, error)
+}
+
+// ParseIssueRefs returns up to MaxIssueRefs distinct issue references in a
+// commit message, in order. #456 references are only returned when the
+// repository they belong to is known.
+func ParseIssueRefs(commitMessage string, repository string) []IssueRef {
+	type found struct {
+		at  int
+		ref IssueRef
+	}
+	var all []found
+	for _, match := range issueKeyPattern.FindAllStringIndex(commitMessage, -1) {
+		key := commitMessage[match[0]:match[1]]
+		prefix, _, _ := strings.Cut(key, "-")
+		if !slices.Contains(standardKeys, prefix) {
+			all = append(all, found{at: match[0], ref: IssueRef{Key: key}})
+		}
+	}
+	if repository != "" {
+		for _, match := range issueNumberPattern.FindAllStringSubmatchIndex(commitMessage, -1) {
+			number, err := strconv.Atoi(commitMessage[match[2]:match[3]])
+			if err == nil && number > 0 {
+				all = append(all, found{at: match[2], ref: IssueRef{Number: number, Repository: repository}})
+			}
+		}
+	}
+
+	slices.SortFunc(all, func(a, b found) int { return a.at - b.at })
+
+	var refs []IssueRef
+	seen := make(map[IssueRef]bool)
+	for _, f := range all {
+		if len(refs) == MaxIssueRefs {
+			break
+		}
+		if !seen[f.ref] {
+			seen[f.ref] = true
+			refs = append(refs, f.ref)
+		}
+	}
+	return refs
+}
+
+// resolveIssues looks up the titles of the issues a commit references,
+// concurrently and within issueLookupTimeout. Titles have personal data
+// removed like the commit message. Issues that can't be looked up, or whose
+// titles can't be redacted, are left out.
+func (h *HaikuService) resolveIssues(ctx context.Context, commitMessage string, repository *Repository) []prompt.Issue {
+	if h.issues == nil {
+		return nil
+	}
+	var repositoryName string
+	if repository != nil {
+		repositoryName = repository.Name
+	}
+	refs := ParseIssueRefs(commitMessage, repositoryName)
+	if len(refs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, issueLookupTimeout)
+	defer cancel()
+
+	titles := make([]string, len(refs))
+	var wg sync.WaitGroup
+	for i, ref := range refs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			title, err := h.issues.IssueTitle(ctx, ref)
+			if err != nil {
+				logging.Errorf("[HAIKU SERVICE] error looking up issue %s: %v\n", ref, err)
+				return
+			}
+			title, _, err = h.redactPII(ctx, title)
+			if err != nil {
+				return
+			}
+			titles[i] = promptDetail(title, maxIssueTitleLength)
+		}()
+	}
+	wg.Wait()
+
+	var issues []prompt.Issue
+	for i, ref := range refs {
+		if titles[i] != "" {
+			issues = append(issues, prompt.Issue{Reference: ref.String(), Title: titles[i]})
+		}
+	}
+	return issues
+}
@@ -1,5 +1,10 @@
 package haiku
 
+import (
+	"slices"
+	"time"
+)
+
 type Mood string
 
 const (
@@ -8,19 +13,188 @@ const (
 	MoodTechnical  Mood = "technical"
 )
 
+// Moods lists every valid mood.
+var Moods = []Mood{MoodHumerous, MoodReflective, MoodTechnical}
+
+// MoodAuto asks for a commit haiku in the mood suiting the kind of commit,
+// as classified by ClassifyCommit. It isn't itself a mood, so it's only
+// accepted where a single commit's mood is chosen.
+const MoodAuto Mood = "auto"
+
+// Register controls the formality of the haiku's diction.
+type Register string
+
+const (
+	RegisterFormal  Register = "formal"
+	RegisterCasual  Register = "casual"
+	RegisterPlayful Register = "playful"
+)
+
+// Registers lists every valid register.
+var Registers = []Register{RegisterFormal, RegisterCasual, RegisterPlayful}
+
+// PairingStyle controls how a haiku reflects that a commit has co-authors.
+type PairingStyle string
+
+const (
+	PairingAcknowledge PairingStyle = "acknowledge" // One haiku quietly reflecting the shared effort
+	PairingVerses      PairingStyle = "verses"      // Linked haiku, one for each author's voice
+)
+
+// PairingStyles lists every valid pairing style.
+var PairingStyles = []PairingStyle{PairingAcknowledge, PairingVerses}
+
+// MaxPairingVerses caps the linked verses written for a commit, however many
+// authors it has.
+const MaxPairingVerses = 4
+
+// Largest sampling parameters a request may set. Values within them that the
+// configured model can't take are clamped to its limits.
+const (
+	MaxRequestTokens      = 1000
+	MaxRequestTemperature = 1.0
+)
+
 type HaikuCommitRequest struct {
-	CommitMessage string `json:"commitMessage" binding:"required"`
-	Mood          Mood   `json:"mood,omitempty"`
+	CommitMessage       string       `json:"commitMessage" binding:"required"`
+	Mood                Mood         `json:"mood,omitempty"`
+	Register            Register     `json:"register,omitempty"`
+	Repository          *Repository  `json:"repository,omitempty"`
+	Author              *Author      `json:"author,omitempty"`              // Commit author, ranked on the leaderboard by name or else handle
+	CommitRef           string       `json:"commitRef,omitempty"`           // Commit the message belongs to, e.g. its SHA, passed on in haiku events; up to MaxCommitRefLength
+	MaxTokens           int          `json:"maxTokens,omitempty"`           // Up to MaxRequestTokens; clamped to the model limit
+	Temperature         float64      `json:"temperature,omitempty"`         // Up to MaxRequestTemperature; clamped to the model limit
+	IncludeSummary      bool         `json:"includeSummary,omitempty"`      // Also return a plain-language summary of the commit
+	IncludeIllustration bool         `json:"includeIllustration,omitempty"` // Also return a companion illustration for the haiku
+	IncludeShareCard    bool         `json:"includeShareCard,omitempty"`    // Also return a link to a PNG share card
+	IncludeAudio        bool         `json:"includeAudio,omitempty"`        // Also return a link to an MP3 reading of the haiku
+	IncludePairing      bool         `json:"includePairing,omitempty"`      // Reflect Co-authored-by trailers in the haiku
+	PairingStyle        PairingStyle `json:"pairingStyle,omitempty"`        // How co-authors are reflected; implies IncludePairing (default: PairingAcknowledge)
+	IncludeAuthor       bool         `json:"includeAuthor,omitempty"`       // Let the haiku allude to the author, by handle or else name
+	Strict              bool         `json:"strict,omitempty"`              // Fail unless the haiku is 5-7-5, after any retries
+	NoCache             bool         `json:"noCache,omitempty"`             // Generate a new haiku rather than reuse a cached one
+	Variants            int          `json:"variants,omitempty"`            // Also return this many candidate haiku, written at varied temperatures; capped at MaxVariants
+	Deterministic       bool         `json:"deterministic,omitempty"`       // The same commit always gets the same haiku: cached first, canonical prompt, temperature 0
 }
 
 type HaikuCommitResponse struct {
-	Haiku string `json:"haiku"`
+	ID           string             `json:"id,omitempty"`       // Identifies the stored haiku, for voting
+	Previous     string             `json:"previous,omitempty"` // The haiku this one replaces, when regenerated
+	Haiku        string             `json:"haiku"`
+	Summary      string             `json:"summary,omitempty"`
+	Illustration *Illustration      `json:"illustration,omitempty"`
+	ShareCard    *Artifact          `json:"shareCard,omitempty"`
+	Audio        *Artifact          `json:"audio,omitempty"`
+	Variants     []Variant          `json:"variants,omitempty"` // Candidate haiku, when requested; the first is Haiku
+	SVG          string             `json:"svg,omitempty"`      // Haiku rendered as an SVG card, when requested with ?svg=true
+	Timings      map[string]float64 `json:"timings,omitempty"`  // Milliseconds spent in each stage, when requested with ?timings=true
+	Degraded     bool               `json:"degraded,omitempty"` // Haiku was written locally because the model was unavailable
+	Metadata     HaikuMetadata      `json:"metadata"`
+}
+
+// Repository identifies the repository a commit belongs to. Its language and
+// description, when given, are shared with the model to shape the imagery.
+type Repository struct {
+	Name        string `json:"name"`                  // e.g. "octo-org/octo-repo"
+	Language    string `json:"language,omitempty"`    // Primary language, e.g. "Go"
+	Description string `json:"description,omitempty"` // e.g. "Billing service for the storefront"
+}
+
+// Author is a person credited with a commit.
+type Author struct {
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Handle string `json:"handle,omitempty"` // e.g. "mona" for @mona; identifies the author when there is no name
+}
+
+// Artifact links to a generated file, such as a share card or recording.
+type Artifact struct {
+	URL       string    `json:"url"`       // Presigned link to the file
+	ExpiresAt time.Time `json:"expiresAt"` // When URL stops working
+}
+
+// Illustration is a small seasonal image to accompany a haiku. Image is only
+// set when an image model is configured.
+type Illustration struct {
+	Prompt string `json:"prompt"`          // Image-generation prompt describing the illustration
+	Image  string `json:"image,omitempty"` // Base64-encoded PNG rendered from Prompt
+}
+
+// HaikuMetadata describes how a haiku was generated.
+type HaikuMetadata struct {
+	PromptVersion string   `json:"promptVersion,omitempty"` // Prompt template version that produced the haiku
+	Mood          Mood     `json:"mood,omitempty"`          // Mood the haiku was written in, inferred when MoodAuto was requested
+	Register      Register `json:"register,omitempty"`      // Register requested for the haiku, if any
+	Model         string   `json:"model,omitempty"`         // Model that generated the haiku
+	Cached        bool     `json:"cached,omitempty"`        // Haiku was reused from the response cache rather than generated
+	Warnings      []string `json:"warnings,omitempty"`      // Requested options that were adjusted to fit the model
+	CoAuthors     []Author `json:"coAuthors,omitempty"`     // Authors credited by the commit's Co-authored-by trailers
+	Attribution   string   `json:"attribution,omitempty"`   // Credits the commit's author, when the request named one
+	Verses        int      `json:"verses,omitempty"`        // Linked verses in the haiku, separated by blank lines, when more than one was asked for
+	Redacted      []string `json:"redacted,omitempty"`      // Kinds of personal data removed from the commit message before it was sent to the model, e.g. "email"
 }
 
 func (m Mood) IsValid() bool {
-	switch m {
-	case MoodHumerous, MoodReflective, MoodTechnical:
-		return true
-	}
-	return false
+	return slices.Contains(Moods, m)
+}
+
+func (r Register) IsValid() bool {
+	return slices.Contains(Registers, r)
+}
+
+func (p PairingStyle) IsValid() bool {
+	return slices.Contains(PairingStyles, p)
+}
+
+type ReleaseNotesRequest struct {
+	ReleaseNotes string `json:"releaseNotes" binding:"required"`
+	Mood         Mood   `json:"mood,omitempty"`
+}
+
+type ThemeHaiku struct {
+	Theme string `json:"theme"`
+	Haiku string `json:"haiku"`
+}
+
+type ReleaseNotesResponse struct {
+	Haiku []ThemeHaiku `json:"haiku"`
+}
+
+type ChangelogRequest struct {
+	Changelog string `json:"changelog" binding:"required"`
+	Version   string `json:"version,omitempty"` // defaults to the most recent release
+	Mood      Mood   `json:"mood,omitempty"`
+}
+
+type CategoryHaiku struct {
+	Category string `json:"category"`
+	Haiku    string `json:"haiku"`
+}
+
+type ChangelogResponse struct {
+	Version string          `json:"version,omitempty"`
+	Date    string          `json:"date,omitempty"`
+	Haiku   []CategoryHaiku `json:"haiku"`
+}
+
+// PulseCommit is one commit in an organization's engineering pulse.
+type PulseCommit struct {
+	Repository string `json:"repository" binding:"required"` // e.g. "octo-org/octo-repo"
+	Message    string `json:"message" binding:"required"`
+}
+
+type PulseRequest struct {
+	Commits []PulseCommit `json:"commits" binding:"required,min=1,max=1000,dive"` // The day's commits across the organization's repositories
+	Mood    Mood          `json:"mood,omitempty"`
+}
+
+type RepositoryHaiku struct {
+	Repository string `json:"repository"`
+	Haiku      string `json:"haiku"`
+}
+
+type PulseResponse struct {
+	Poem     string            `json:"poem"`               // Verses joined into a single poem
+	Verses   []RepositoryHaiku `json:"verses"`             // Linked verses in order, each inspired by one repository's work
+	Redacted []string          `json:"redacted,omitempty"` // Kinds of personal data removed from the commits before they were sent to the model
 }
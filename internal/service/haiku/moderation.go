@@ -0,0 +1,61 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/audit"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/timing"
+)
+
+// generateModerated invokes the model and screens the result with the
+// configured moderator, regenerating blocked output up to the retry budget.
+// When strict, haiku that are not 5-7-5 are regenerated up to their own
+// budget.
+func (h *HaikuService) generateModerated(ctx context.Context, prompt string, options *bedrock.ClaudeOptions, strict bool) (bedrock.ClaudeResult, error) {
+	blocked, invalid := 0, 0
+	for {
+		endProvider := timing.Start(ctx, timing.StageProvider)
+		response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+		endProvider()
+		if err != nil {
+			logging.Errorf("[HAIKU SERVICE] error invoking Claude: %v\n", err)
+			return bedrock.ClaudeResult{}, fmt.Errorf("%w: invoking Claude: %w", ErrCreateHaiku, err)
+		}
+
+		if h.moderator != nil {
+			endModeration := timing.Start(ctx, timing.StageModeration)
+			result, err := h.moderator.Moderate(ctx, response.Text)
+			endModeration()
+			if err != nil {
+				// Fail closed: unmoderated output must not be returned.
+				logging.Errorf("[HAIKU SERVICE] error moderating haiku: %v\n", err)
+				return bedrock.ClaudeResult{}, fmt.Errorf("%w: moderating haiku: %w", ErrCreateHaiku, err)
+			}
+			audit.Moderated(ctx, audit.Moderation{Blocked: result.Blocked, Reason: result.Reason})
+			if result.Blocked {
+				blocked++
+				logging.Infof("[HAIKU SERVICE] haiku blocked by %s (attempt %d of %d)\n", result.Reason, blocked, h.moderationRetries+1)
+				if blocked > h.moderationRetries {
+					return bedrock.ClaudeResult{}, fmt.Errorf("%w: %s", ErrContentBlocked, result.Reason)
+				}
+				continue
+			}
+		}
+
+		if strict {
+			if err := CheckStructure(response.Text); err != nil {
+				invalid++
+				logging.Infof("[HAIKU SERVICE] %v (attempt %d of %d)\n", err, invalid, h.structureRetries+1)
+				if invalid > h.structureRetries {
+					return bedrock.ClaudeResult{}, err
+				}
+				continue
+			}
+		}
+
+		return response, nil
+	}
+}
@@ -0,0 +1,140 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/audit"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+)
+
+type MockModerator struct {
+	ResultsToReturn []moderation.Result // Returned in order; the last result repeats
+	ErrorToReturn   error
+	Calls           int
+}
+
+func (m *MockModerator) Moderate(ctx context.Context, text string) (moderation.Result, error) {
+	m.Calls++
+	if m.ErrorToReturn != nil {
+		return moderation.Result{}, m.ErrorToReturn
+	}
+	if m.Calls > len(m.ResultsToReturn) {
+		return m.ResultsToReturn[len(m.ResultsToReturn)-1], nil
+	}
+	return m.ResultsToReturn[m.Calls-1], nil
+}
+
+func TestCreateHaikuModeration(t *testing.T) {
+	blocked := moderation.Result{Blocked: true, Reason: "blocked word list"}
+	allowed := moderation.Result{}
+
+	tests := []struct {
+		name                string
+		results             []moderation.Result
+		moderatorError      error
+		retries             int
+		expectedInvocations int
+		expectedBlocked     int // Screenings audited as blocked
+		errorIs             error
+	}{
+		{
+			name:                "Allowed on first attempt",
+			results:             []moderation.Result{allowed},
+			retries:             2,
+			expectedInvocations: 1,
+		},
+		{
+			name:                "Regenerated after block",
+			results:             []moderation.Result{blocked, allowed},
+			retries:             2,
+			expectedInvocations: 2,
+			expectedBlocked:     1,
+		},
+		{
+			name:                "Blocked after retry budget",
+			results:             []moderation.Result{blocked},
+			retries:             2,
+			expectedInvocations: 3,
+			expectedBlocked:     3,
+			errorIs:             ErrContentBlocked,
+		},
+		{
+			name:                "No retries",
+			results:             []moderation.Result{blocked},
+			retries:             0,
+			expectedInvocations: 1,
+			expectedBlocked:     1,
+			errorIs:             ErrContentBlocked,
+		},
+		{
+			name:                "Moderator error fails closed",
+			moderatorError:      errors.New("guardrail unavailable"),
+			retries:             2,
+			expectedInvocations: 1,
+			errorIs:             ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			invocations := 0
+			mockClient := &MockBedrockClient{
+				InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+					invocations++
+					return "haiku", nil
+				},
+			}
+			moderator := &MockModerator{
+				ResultsToReturn: tc.results,
+				ErrorToReturn:   tc.moderatorError,
+			}
+
+			service := NewHaikuService(mockClient, &Options{
+				Moderator:         moderator,
+				ModerationRetries: tc.retries,
+			})
+			ctx, trail := audit.NewContext(context.Background())
+			_, err := service.CreateHaiku(ctx, HaikuCommitRequest{
+				CommitMessage: "fix: resolved login issue",
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+			} else if err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+
+			if invocations != tc.expectedInvocations {
+				t.Errorf("Expected %d model invocations, got %d", tc.expectedInvocations, invocations)
+			}
+
+			// Every screening is audited, whether or not a haiku is served.
+			screened := invocations
+			if tc.moderatorError != nil {
+				screened = 0
+			}
+			moderated := trail.Moderation()
+			blockedCount := 0
+			for _, outcome := range moderated {
+				if outcome.Blocked {
+					blockedCount++
+				}
+			}
+			if len(moderated) != screened || blockedCount != tc.expectedBlocked {
+				t.Errorf("Expected %d screenings audited, %d blocked, got %+v", screened, tc.expectedBlocked, moderated)
+			}
+			expectedServed := 1
+			if tc.errorIs != nil {
+				expectedServed = 0
+			}
+			if served := trail.Haiku(); len(served) != expectedServed {
+				t.Errorf("Expected %d haiku audited as served, got %+v", expectedServed, served)
+			}
+		})
+	}
+}
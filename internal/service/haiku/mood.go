@@ -0,0 +1,89 @@
+package haiku
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// CommitKind is the broad kind of change a commit makes, used to pick a mood
+// for it.
+type CommitKind string
+
+const (
+	CommitRevert  CommitKind = "revert"
+	CommitHotfix  CommitKind = "hotfix"
+	CommitFeature CommitKind = "feature"
+	CommitCleanup CommitKind = "cleanup"
+	CommitOther   CommitKind = "other"
+)
+
+// KindMoods maps each kind of commit to the mood an automatic mood picks for
+// it. Kinds without a mood get MoodReflective.
+var KindMoods = map[CommitKind]Mood{
+	CommitRevert:  MoodReflective,
+	CommitHotfix:  MoodTechnical,
+	CommitFeature: MoodHumerous,
+	CommitCleanup: MoodReflective,
+}
+
+// conventionalKinds classifies Conventional Commit types.
+var conventionalKinds = map[string]CommitKind{
+	"revert":   CommitRevert,
+	"fix":      CommitHotfix,
+	"hotfix":   CommitHotfix,
+	"feat":     CommitFeature,
+	"chore":    CommitCleanup,
+	"refactor": CommitCleanup,
+	"style":    CommitCleanup,
+}
+
+// Subject line keywords for commits that aren't Conventional Commits, checked
+// in this order.
+var (
+	revertPattern  = regexp.MustCompile(`(?i)^(revert|roll ?back|undo)\b`)
+	hotfixPattern  = regexp.MustCompile(`(?i)\b(hotfix|fix(es|ed)?|bug|crash|urgent|patch)\b`)
+	featurePattern = regexp.MustCompile(`(?i)^(add|introduce|implement|support|create|enable|allow)\b`)
+	cleanupPattern = regexp.MustCompile(`(?i)^(remove|delete|drop|clean ?up|tidy|refactor|rename|simplify|deprecate|bump)\b`)
+)
+
+// ClassifyCommit sorts a commit into a kind from its Conventional Commit type
+// or, failing that, the words of its subject line. It is a cheap heuristic
+// rather than a model call, so it adds nothing to a request's latency.
+func ClassifyCommit(commitMessage string) CommitKind {
+	if commit, ok := ParseConventionalCommit(commitMessage); ok {
+		if kind, ok := conventionalKinds[commit.Type]; ok {
+			return kind
+		}
+		return CommitOther
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(commitMessage), "\n")
+	switch {
+	case revertPattern.MatchString(subject):
+		return CommitRevert
+	case hotfixPattern.MatchString(subject):
+		return CommitHotfix
+	case featurePattern.MatchString(subject):
+		return CommitFeature
+	case cleanupPattern.MatchString(subject):
+		return CommitCleanup
+	}
+	return CommitOther
+}
+
+// inferMood picks the mood for a commit requested with MoodAuto. A mood
+// turned off by its feature flag gives way to MoodReflective, as if no mood
+// had been asked for.
+func (h *HaikuService) inferMood(ctx context.Context, commitMessage string) Mood {
+	kind := ClassifyCommit(commitMessage)
+	mood, ok := KindMoods[kind]
+	if !ok || !h.flagEnabled(ctx, flags.MoodFlag(string(mood))) {
+		mood = MoodReflective
+	}
+	logging.Debugf("[HAIKU SERVICE] inferred %s mood for %s commit\n", mood, kind)
+	return mood
+}
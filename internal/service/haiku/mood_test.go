@@ -0,0 +1,84 @@
+package haiku
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+)
+
+func TestClassifyCommit(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected CommitKind
+	}{
+		{message: "Revert \"Add caching layer\"", expected: CommitRevert},
+		{message: "revert: add caching layer", expected: CommitRevert},
+		{message: "Hotfix for login crash", expected: CommitHotfix},
+		{message: "fix(auth): handle expired tokens", expected: CommitHotfix},
+		{message: "Add dark mode", expected: CommitFeature},
+		{message: "feat!: drop Node 16", expected: CommitFeature},
+		{message: "Remove unused helpers", expected: CommitCleanup},
+		{message: "chore(deps): bump gin", expected: CommitCleanup},
+		{message: "docs: explain retries", expected: CommitOther},
+		{message: "Update README", expected: CommitOther},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.message, func(t *testing.T) {
+			if kind := ClassifyCommit(tc.message); kind != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, kind)
+			}
+		})
+	}
+}
+
+func TestCreateHaikuAutoMood(t *testing.T) {
+	tests := []struct {
+		name     string
+		message  string
+		off      []string
+		expected Mood
+	}{
+		{
+			name:     "Feature",
+			message:  "feat: add dark mode",
+			expected: MoodHumerous,
+		},
+		{
+			name:     "Hotfix",
+			message:  "Hotfix for login crash",
+			expected: MoodTechnical,
+		},
+		{
+			name:     "Unclassified",
+			message:  "Update README",
+			expected: MoodReflective,
+		},
+		{
+			name:     "Inferred mood turned off",
+			message:  "feat: add dark mode",
+			off:      []string{flags.MoodFlag("humorous")},
+			expected: MoodReflective,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku"}
+			archive := &MockArchive{IDToReturn: "abc123"}
+			service := NewHaikuService(mockClient, &Options{Archive: archive, Flags: &MockFeatureFlags{Off: tc.off}})
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: tc.message, Mood: MoodAuto})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if response.Metadata.Mood != tc.expected {
+				t.Errorf("Expected %s mood, got %s", tc.expected, response.Metadata.Mood)
+			}
+			if len(archive.Saved) != 1 || archive.Saved[0].Mood != tc.expected {
+				t.Errorf("Expected the haiku kept with the %s mood, got %+v", tc.expected, archive.Saved)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package haiku
+
+import (
+	"context"
+	"time"
+)
+
+// Notification describes a haiku newly written by the model, for pushing to
+// the channels that follow its tenant or repository.
+type Notification struct {
+	HaikuID     string    `json:"haikuId,omitempty"`     // ID of the kept haiku, when haiku are kept
+	Tenant      string    `json:"tenant,omitempty"`      // Tenant the haiku was written for
+	Repository  string    `json:"repository,omitempty"`  // Repository the commit was made in, if given
+	Attribution string    `json:"attribution,omitempty"` // Credit to the commit's author, if given
+	Mood        Mood      `json:"mood"`                  // Mood the haiku was written in
+	Haiku       string    `json:"haiku"`                 // Haiku served
+	CreatedAt   time.Time `json:"createdAt"`             // When the haiku was written
+}
+
+// Notifier is handed every haiku newly written by the model, and pushes it to
+// the channels following it, e.g. Slack incoming webhooks. It must not hold up
+// or fail the request it is handed.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification)
+}
@@ -0,0 +1,84 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+type MockNotifier struct {
+	Notified []Notification
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, notification Notification) {
+	m.Notified = append(m.Notified, notification)
+}
+
+func TestCreateHaikuNotifies(t *testing.T) {
+	tests := []struct {
+		name             string
+		requests         int
+		modelError       error
+		expectedNotified int
+	}{
+		{
+			name:             "Notified",
+			requests:         1,
+			expectedNotified: 1,
+		},
+		{
+			name:             "Cached haiku are notified once",
+			requests:         2,
+			expectedNotified: 1,
+		},
+		{
+			name:       "Fallback haiku are not notified",
+			requests:   1,
+			modelError: fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			notifier := &MockNotifier{}
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku", ErrorToReturn: tc.modelError}
+			service := NewHaikuService(mockClient, &Options{
+				Archive:       &MockArchive{IDToReturn: "abc123"},
+				ResponseCache: NewMemoryResponseCache(10, 0),
+				Notifier:      notifier,
+				Fallback:      true,
+			})
+
+			ctx := keys.NewTenantContext(context.Background(), "acme")
+			request := HaikuCommitRequest{
+				CommitMessage: "fix typo",
+				Mood:          MoodTechnical,
+				Repository:    &Repository{Name: "octo/leaves"},
+				Author:        &Author{Handle: "mona"},
+			}
+			for range tc.requests {
+				if _, err := service.CreateHaiku(ctx, request); err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+			}
+
+			if len(notifier.Notified) != tc.expectedNotified {
+				t.Fatalf("Expected %d haiku notified, got %d", tc.expectedNotified, len(notifier.Notified))
+			}
+			if tc.expectedNotified == 0 {
+				return
+			}
+
+			notified := notifier.Notified[0]
+			if notified.HaikuID != "abc123" || notified.Tenant != "acme" || notified.Repository != "octo/leaves" || notified.Haiku != "haiku" || notified.Mood != MoodTechnical {
+				t.Errorf("Expected the haiku notified, got %+v", notified)
+			}
+			if notified.Attribution != "after a commit by @mona" || notified.CreatedAt.IsZero() {
+				t.Errorf("Expected the author credited and the time written, got %+v", notified)
+			}
+		})
+	}
+}
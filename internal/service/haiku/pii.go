@@ -0,0 +1,32 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// PIIRedactor removes personal data from text, returning the kinds it
+// removed, e.g. "email".
+type PIIRedactor interface {
+	Redact(ctx context.Context, text string) (string, []string, error)
+}
+
+// redactPII removes personal data from commit text before it is sent to the
+// model. Text that can't be checked isn't sent at all.
+func (h *HaikuService) redactPII(ctx context.Context, text string) (string, []string, error) {
+	if h.pii == nil {
+		return text, nil, nil
+	}
+
+	redacted, kinds, err := h.pii.Redact(ctx, text)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error redacting personal data: %v\n", err)
+		return "", nil, fmt.Errorf("%w: redacting personal data: %w", ErrCreateHaiku, err)
+	}
+	if len(kinds) > 0 {
+		logging.Infof("[HAIKU SERVICE] redacted personal data from commit text: %v\n", kinds)
+	}
+	return redacted, kinds, nil
+}
@@ -0,0 +1,27 @@
+package haiku
+
+// loggablePrompt returns prompt as it may appear in logs. Prompts contain
+// commit content, so unless full prompt logging is enabled only a hash and the
+// length are logged, or only the length when commit content is redacted; the
+// hash still lets repeated prompts be correlated.
+func (h *HaikuService) loggablePrompt(prompt string) string {
+	return h.commitContent.Text(prompt)
+}
+
+// hideCommitMessages hides messages from every log line until release is
+// called, when a Redactor is configured.
+func (h *HaikuService) hideCommitMessages(messages ...string) (release func()) {
+	if h.redactor == nil {
+		return func() {}
+	}
+
+	releases := make([]func(), len(messages))
+	for i, message := range messages {
+		releases[i] = h.redactor.Hide(message)
+	}
+	return func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package haiku
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/redact"
+)
+
+func TestLoggablePrompt(t *testing.T) {
+	const prompt = "Create a reflective haiku from this commit message:\n<commit_message>\nfix: rotate leaked AWS key\n</commit_message>"
+
+	hashed := NewHaikuService(&MockBedrockClient{}, nil).loggablePrompt(prompt)
+	if strings.Contains(hashed, "leaked") {
+		t.Errorf("Expected commit content to be hashed by default, got %q", hashed)
+	}
+	if !strings.HasPrefix(hashed, "sha256:") {
+		t.Errorf("Expected a sha256 digest, got %q", hashed)
+	}
+	if again := NewHaikuService(&MockBedrockClient{}, nil).loggablePrompt(prompt); again != hashed {
+		t.Errorf("Expected the same prompt to hash the same, got %q and %q", hashed, again)
+	}
+
+	full := NewHaikuService(&MockBedrockClient{}, &Options{LogFullPrompts: true}).loggablePrompt(prompt)
+	if full != prompt {
+		t.Errorf("Expected full prompt when enabled, got %q", full)
+	}
+
+	redacted := NewHaikuService(&MockBedrockClient{}, &Options{LogFullPrompts: true, CommitContent: redact.ModeRedact}).loggablePrompt(prompt)
+	if !strings.HasPrefix(redacted, "[redacted]") {
+		t.Errorf("Expected commit content redaction to take precedence, got %q", redacted)
+	}
+}
+
+type MockRedactor struct {
+	Hidden map[string]int
+}
+
+func (m *MockRedactor) Hide(text string) func() {
+	m.Hidden[text]++
+	return func() { m.Hidden[text]-- }
+}
+
+func TestCreateHaikuHidesCommitMessage(t *testing.T) {
+	const message = "fix: rotate leaked AWS key"
+	redactor := &MockRedactor{Hidden: make(map[string]int)}
+	var hiddenWhileWriting bool
+	mockClient := &MockBedrockClient{
+		InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+			hiddenWhileWriting = redactor.Hidden[message] == 1
+			return "haiku", nil
+		},
+	}
+	service := NewHaikuService(mockClient, &Options{Redactor: redactor})
+
+	if _, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: message}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if !hiddenWhileWriting {
+		t.Errorf("Expected the commit message hidden while the haiku was written")
+	}
+	if redactor.Hidden[message] != 0 {
+		t.Errorf("Expected the commit message released once written, got %v", redactor.Hidden)
+	}
+}
@@ -0,0 +1,51 @@
+package haiku
+
+import (
+	"context"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metering"
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// providerClient generates haiku with an extension.Provider in place of Bedrock.
+type providerClient struct {
+	provider extension.Provider
+}
+
+// NewProviderClient adapts provider for use as the service's model client.
+func NewProviderClient(provider extension.Provider) BedrockClient {
+	return &providerClient{
+		provider: provider,
+	}
+}
+
+func (p *providerClient) InvokeClaude(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (bedrock.ClaudeResult, error) {
+	var options extension.GenerateOptions
+	if opts != nil {
+		options = extension.GenerateOptions{
+			ModelID:       opts.ModelID,
+			System:        opts.System,
+			MaxTokens:     opts.MaxTokens,
+			Temperature:   opts.Temperature,
+			Deterministic: opts.Deterministic,
+		}
+	}
+
+	generation, err := p.provider.Generate(ctx, prompt, options)
+	if err != nil {
+		return bedrock.ClaudeResult{}, err
+	}
+
+	metering.Add(ctx, generation.InputTokens, generation.OutputTokens)
+
+	return bedrock.ClaudeResult{
+		Text:    generation.Text,
+		ModelID: generation.ModelID,
+		Usage: bedrock.Usage{
+			InputTokens:  generation.InputTokens,
+			OutputTokens: generation.OutputTokens,
+		},
+		Warnings: generation.Warnings,
+	}, nil
+}
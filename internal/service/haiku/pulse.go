@@ -0,0 +1,193 @@
+package haiku
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const pulseMaxTokens = 1200
+
+// routineCommitTypes are Conventional Commit types left out of a pulse while
+// other commits are available.
+var routineCommitTypes = []string{"build", "chore", "ci", "docs", "style", "test"}
+
+// CreatePulseHaiku weaves notable commits from across an organization's
+// repositories into a single poem of linked verses, one per repository. The
+// caller supplies the commits, e.g. the day's pushes collected by a scheduled
+// job.
+func (h *HaikuService) CreatePulseHaiku(ctx context.Context, request PulseRequest) (PulseResponse, error) {
+	messages := make([]string, len(request.Commits))
+	for i, commit := range request.Commits {
+		messages[i] = commit.Message
+	}
+	defer h.hideCommitMessages(messages...)()
+
+	mood := request.Mood
+	if mood != "" && !mood.IsValid() {
+		logging.Warnf("[HAIKU SERVICE] invalid mood: %s\n", mood)
+		return PulseResponse{}, ErrBadHaikuRequest
+	}
+
+	if request.Mood == "" {
+		mood = MoodReflective
+	}
+
+	commits := notableCommits(request.Commits, MaxPulseCommits)
+	if len(commits) == 0 {
+		logging.Warnf("[HAIKU SERVICE] no notable commits in pulse\n")
+		return PulseResponse{}, ErrBadHaikuRequest
+	}
+
+	lines := make([]string, len(commits))
+	for i, commit := range commits {
+		lines[i] = fmt.Sprintf("[%s] %s", commit.Repository, commit.Message)
+	}
+	entries, neutralized := sanitizeInput(strings.Join(lines, "\n"))
+	if neutralized {
+		logging.Warnf("[HAIKU SERVICE] neutralized instruction-like content in pulse commits\n")
+	}
+	entries, redacted, err := h.redactPII(ctx, entries)
+	if err != nil {
+		return PulseResponse{}, err
+	}
+
+	prompt := fmt.Sprintf("Create a %s chain of linked haiku from today's commits:\n<commits>\n%s\n</commits>", mood, entries)
+
+	options := &bedrock.ClaudeOptions{
+		ModelID:   h.modelID,
+		MaxTokens: pulseMaxTokens,
+		System:    PulseSystemPrompt,
+	}
+
+	logging.Debugf("[HAIKU SERVICE] sending pulse request to Bedrock: %s\n", h.loggablePrompt(prompt))
+	response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error invoking Claude: %v\n", err)
+		return PulseResponse{}, fmt.Errorf("%w: invoking Claude: %w", ErrCreateHaiku, err)
+	}
+
+	verses, err := parseRepositoryHaiku(response.Text)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error parsing pulse haiku: %v\n", err)
+		return PulseResponse{}, fmt.Errorf("%w: parsing pulse haiku: %w: %v", ErrCreateHaiku, bedrock.ErrResponseParsing, err)
+	}
+
+	poem := make([]string, len(verses))
+	for i, verse := range verses {
+		poem[i] = verse.Haiku
+	}
+
+	return PulseResponse{
+		Poem:     strings.Join(poem, "\n\n"),
+		Verses:   verses,
+		Redacted: redacted,
+	}, nil
+}
+
+// notableCommits picks up to limit commits worth writing about. Merges,
+// repeated subjects (e.g. cherry-picks) and routine commits are skipped, and
+// the rest are taken in turn from each repository so that no single busy
+// repository crowds out the others. Only subject lines are kept.
+func notableCommits(commits []PulseCommit, limit int) []PulseCommit {
+	var repositories []string
+	notable := make(map[string][]PulseCommit)
+	routine := make(map[string][]PulseCommit)
+	seen := make(map[string]bool)
+
+	for _, commit := range commits {
+		repository := strings.TrimSpace(commit.Repository)
+		subject, _, _ := strings.Cut(strings.TrimSpace(commit.Message), "\n")
+		subject = strings.TrimSpace(subject)
+		if repository == "" || subject == "" || strings.HasPrefix(subject, "Merge ") {
+			continue
+		}
+
+		key := strings.ToLower(subject)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if !slices.Contains(repositories, repository) {
+			repositories = append(repositories, repository)
+		}
+		commit = PulseCommit{Repository: repository, Message: subject}
+		if isRoutineCommit(subject) {
+			routine[repository] = append(routine[repository], commit)
+		} else {
+			notable[repository] = append(notable[repository], commit)
+		}
+	}
+
+	selected := roundRobin(repositories, notable, limit)
+	if len(selected) == 0 {
+		selected = roundRobin(repositories, routine, limit)
+	}
+	return selected
+}
+
+// roundRobin takes commits from each repository in turn until limit are
+// taken or none are left.
+func roundRobin(repositories []string, commits map[string][]PulseCommit, limit int) []PulseCommit {
+	var selected []PulseCommit
+	for i := 0; len(selected) < limit; i++ {
+		taken := false
+		for _, repository := range repositories {
+			if i < len(commits[repository]) && len(selected) < limit {
+				selected = append(selected, commits[repository][i])
+				taken = true
+			}
+		}
+		if !taken {
+			break
+		}
+	}
+	return selected
+}
+
+// isRoutineCommit reports whether a subject line has a routine Conventional
+// Commit type, e.g. "chore(deps): bump gin".
+func isRoutineCommit(subject string) bool {
+	commit, ok := ParseConventionalCommit(subject)
+	return ok && slices.Contains(routineCommitTypes, commit.Type)
+}
+
+// parseRepositoryHaiku decodes the JSON array returned by the model,
+// tolerating a surrounding markdown code fence, and drops any empty entries.
+func parseRepositoryHaiku(response string) ([]RepositoryHaiku, error) {
+	body := strings.TrimSpace(response)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	body = strings.TrimSuffix(body, "```")
+
+	var parsed []RepositoryHaiku
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &parsed); err != nil {
+		return nil, err
+	}
+
+	verses := make([]RepositoryHaiku, 0, len(parsed))
+	for _, entry := range parsed {
+		entry.Repository = strings.TrimSpace(entry.Repository)
+		entry.Haiku = strings.TrimSpace(entry.Haiku)
+		if entry.Haiku == "" {
+			continue
+		}
+		verses = append(verses, entry)
+		if len(verses) == MaxPulseVerses {
+			break
+		}
+	}
+
+	if len(verses) == 0 {
+		return nil, errors.New("no haiku found in model response")
+	}
+
+	return verses, nil
+}
@@ -0,0 +1,164 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNotableCommits(t *testing.T) {
+	tests := []struct {
+		name     string
+		commits  []PulseCommit
+		limit    int
+		expected []PulseCommit
+	}{
+		{
+			name: "Repositories take turns",
+			commits: []PulseCommit{
+				{Repository: "octo-org/api", Message: "feat: add invoices"},
+				{Repository: "octo-org/api", Message: "fix: round totals\n\nThey were off by a cent."},
+				{Repository: "octo-org/api", Message: "feat: add refunds"},
+				{Repository: "octo-org/web", Message: "feat: invoice page"},
+			},
+			limit: 3,
+			expected: []PulseCommit{
+				{Repository: "octo-org/api", Message: "feat: add invoices"},
+				{Repository: "octo-org/web", Message: "feat: invoice page"},
+				{Repository: "octo-org/api", Message: "fix: round totals"},
+			},
+		},
+		{
+			name: "Merges, repeats and routine commits skipped",
+			commits: []PulseCommit{
+				{Repository: "octo-org/api", Message: "Merge pull request #12 from octo-org/invoices"},
+				{Repository: "octo-org/api", Message: "chore(deps): bump gin"},
+				{Repository: "octo-org/api", Message: "fix: round totals"},
+				{Repository: "octo-org/web", Message: "Fix: round totals"},
+				{Repository: "octo-org/web", Message: "docs: explain invoices"},
+			},
+			limit: 10,
+			expected: []PulseCommit{
+				{Repository: "octo-org/api", Message: "fix: round totals"},
+			},
+		},
+		{
+			name: "Routine commits when nothing else happened",
+			commits: []PulseCommit{
+				{Repository: "octo-org/api", Message: "chore(deps): bump gin"},
+				{Repository: "octo-org/web", Message: "ci!: run on arm"},
+			},
+			limit: 10,
+			expected: []PulseCommit{
+				{Repository: "octo-org/api", Message: "chore(deps): bump gin"},
+				{Repository: "octo-org/web", Message: "ci!: run on arm"},
+			},
+		},
+		{
+			name: "Blank commits",
+			commits: []PulseCommit{
+				{Repository: "octo-org/api", Message: "  "},
+			},
+			limit: 10,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			commits := notableCommits(tc.commits, tc.limit)
+			if !reflect.DeepEqual(commits, tc.expected) {
+				t.Errorf("Expected commits %+v, got %+v", tc.expected, commits)
+			}
+		})
+	}
+}
+
+func TestCreatePulseHaiku(t *testing.T) {
+	commits := []PulseCommit{
+		{Repository: "octo-org/api", Message: "fix: resolved login issue"},
+		{Repository: "octo-org/web", Message: "feat: new login page"},
+	}
+
+	tests := []struct {
+		name           string
+		commits        []PulseCommit
+		mood           Mood
+		mockResponse   string
+		mockError      error
+		expectedVerses []RepositoryHaiku
+		expectedPoem   string
+		errorIs        error
+	}{
+		{
+			name:         "Linked verses",
+			commits:      commits,
+			mockResponse: "```json\n[{\"repository\": \"octo-org/api\", \"haiku\": \"Old cracks mended now\"}, {\"repository\": \"octo-org/web\", \"haiku\": \"A new page unfolds\"}, {\"repository\": \"octo-org/web\", \"haiku\": \" \"}]\n```",
+			expectedVerses: []RepositoryHaiku{
+				{Repository: "octo-org/api", Haiku: "Old cracks mended now"},
+				{Repository: "octo-org/web", Haiku: "A new page unfolds"},
+			},
+			expectedPoem: "Old cracks mended now\n\nA new page unfolds",
+		},
+		{
+			name:    "Invalid mood",
+			commits: commits,
+			mood:    Mood("silly"),
+			errorIs: ErrBadHaikuRequest,
+		},
+		{
+			name:    "No notable commits",
+			commits: []PulseCommit{{Repository: "octo-org/api", Message: "Merge branch 'main'"}},
+			errorIs: ErrBadHaikuRequest,
+		},
+		{
+			name:         "Unparseable response",
+			commits:      commits,
+			mockResponse: "Old cracks mended now",
+			errorIs:      ErrCreateHaiku,
+		},
+		{
+			name:      "Bedrock error",
+			commits:   commits,
+			mockError: errors.New("bedrock API error"),
+			errorIs:   ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{
+				ResponseToReturn: tc.mockResponse,
+				ErrorToReturn:    tc.mockError,
+			}
+
+			service := NewHaikuService(mockClient, nil)
+			response, err := service.CreatePulseHaiku(context.Background(), PulseRequest{
+				Commits: tc.commits,
+				Mood:    tc.mood,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if !strings.Contains(mockClient.LastPrompt, "[octo-org/web] feat: new login page") {
+				t.Errorf("Expected the commits in the prompt, got %q", mockClient.LastPrompt)
+			}
+			if !reflect.DeepEqual(response.Verses, tc.expectedVerses) {
+				t.Errorf("Expected verses %+v, got %+v", tc.expectedVerses, response.Verses)
+			}
+			if response.Poem != tc.expectedPoem {
+				t.Errorf("Expected poem %q, got %q", tc.expectedPoem, response.Poem)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// MaxRegenerateDrafts is how many earlier drafts of a haiku the model is told
+// to avoid when it is regenerated.
+const MaxRegenerateDrafts = 5
+
+// followUp is a haiku written after others, which the model is told about.
+type followUp interface {
+	guidance() string
+	replaces() string // ID of the kept haiku it replaces, if any
+}
+
+// regeneration is a haiku being written again for the commit of a kept one.
+type regeneration struct {
+	previous string   // ID of the haiku being replaced
+	drafts   []string // Haiku already written for the commit, most recent first
+}
+
+// guidance tells the model which haiku not to write again.
+func (r *regeneration) guidance() string {
+	return fmt.Sprintf(RegenerateGuidanceTemplate, strings.Join(r.drafts, "\n\n"))
+}
+
+func (r *regeneration) replaces() string {
+	return r.previous
+}
+
+// RegenerateHaiku writes a new haiku for the commit of the kept haiku id,
+// telling the model to avoid it and the drafts it replaced. Commit messages
+// aren't kept, so request describes the commit again; its mood, repository
+// and author default to the kept haiku's. The new haiku is kept under its own
+// ID, linked to id, so that earlier drafts keep their votes.
+func (h *HaikuService) RegenerateHaiku(ctx context.Context, id string, request HaikuCommitRequest) (HaikuCommitResponse, error) {
+	if h.archive == nil {
+		return HaikuCommitResponse{}, fmt.Errorf("%w: haiku are not kept, so they can't be regenerated", ErrBadHaikuRequest)
+	}
+
+	if request.Deterministic {
+		return HaikuCommitResponse{}, fmt.Errorf("%w: deterministic haiku can't be regenerated", ErrBadHaikuRequest)
+	}
+
+	previous, err := h.archive.Load(ctx, id)
+	if err != nil {
+		return HaikuCommitResponse{}, err
+	}
+	if request.Mood == "" {
+		request.Mood = previous.Mood
+	}
+	if request.Repository == nil && previous.Repository != "" {
+		request.Repository = &Repository{Name: previous.Repository}
+	}
+	if request.Author == nil && previous.Author != "" {
+		request.Author = &Author{Name: previous.Author}
+	}
+	// Asking again for the same drafts should still write a new haiku.
+	request.NoCache = true
+
+	regen := &regeneration{previous: id, drafts: []string{previous.Haiku}}
+	for next := previous.Previous; next != "" && len(regen.drafts) < MaxRegenerateDrafts; {
+		draft, err := h.archive.Load(ctx, next)
+		if err != nil {
+			// Earlier drafts may have expired or been erased, which only
+			// means they aren't avoided.
+			logging.Errorf("[HAIKU SERVICE] error loading draft %s: %v\n", next, err)
+			break
+		}
+		regen.drafts = append(regen.drafts, draft.Haiku)
+		next = draft.Previous
+	}
+
+	return h.createHaiku(ctx, request, regen)
+}
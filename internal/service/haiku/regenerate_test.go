@@ -0,0 +1,100 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegenerateHaiku(t *testing.T) {
+	kept := map[string]Stored{
+		"first":  {Haiku: "first draft", Mood: MoodHumerous, Repository: "octo/leaves", Author: "Mona"},
+		"second": {Haiku: "second draft", Mood: MoodHumerous, Repository: "octo/leaves", Author: "Mona", Previous: "first"},
+		"third":  {Haiku: "third draft", Mood: MoodHumerous, Previous: "expired"},
+	}
+
+	tests := []struct {
+		name           string
+		id             string
+		request        HaikuCommitRequest
+		noArchive      bool
+		expectedDrafts []string
+		expectedMood   Mood
+		errorIs        error
+	}{
+		{
+			name:           "Replaces the kept haiku",
+			id:             "first",
+			request:        HaikuCommitRequest{CommitMessage: "fix typo"},
+			expectedDrafts: []string{"first draft"},
+			expectedMood:   MoodHumerous,
+		},
+		{
+			name:           "Avoids earlier drafts",
+			id:             "second",
+			request:        HaikuCommitRequest{CommitMessage: "fix typo", Mood: MoodTechnical},
+			expectedDrafts: []string{"second draft", "first draft"},
+			expectedMood:   MoodTechnical,
+		},
+		{
+			name:           "Earlier drafts expired",
+			id:             "third",
+			request:        HaikuCommitRequest{CommitMessage: "fix typo"},
+			expectedDrafts: []string{"third draft"},
+			expectedMood:   MoodHumerous,
+		},
+		{
+			name:    "Unknown haiku",
+			id:      "missing",
+			request: HaikuCommitRequest{CommitMessage: "fix typo"},
+			errorIs: errNotKept,
+		},
+		{
+			name:      "Haiku are not kept",
+			id:        "first",
+			request:   HaikuCommitRequest{CommitMessage: "fix typo"},
+			noArchive: true,
+			errorIs:   ErrBadHaikuRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			archive := &MockArchive{IDToReturn: "new", Kept: kept}
+			mockClient := &MockBedrockClient{ResponseToReturn: "new draft"}
+			opts := &Options{Archive: archive}
+			if tc.noArchive {
+				opts.Archive = nil
+			}
+			service := NewHaikuService(mockClient, opts)
+
+			response, err := service.RegenerateHaiku(context.Background(), tc.id, tc.request)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if response.ID != "new" || response.Previous != tc.id || response.Haiku != "new draft" {
+				t.Errorf("Expected the new haiku replacing %s, got %+v", tc.id, response)
+			}
+			for _, draft := range tc.expectedDrafts {
+				if !strings.Contains(mockClient.LastOptions.System, "\n"+draft+"\n") {
+					t.Errorf("Expected the system prompt to avoid %q, got %q", draft, mockClient.LastOptions.System)
+				}
+			}
+			if !strings.Contains(mockClient.LastPrompt, string(tc.expectedMood)) {
+				t.Errorf("Expected a %s haiku, got prompt %q", tc.expectedMood, mockClient.LastPrompt)
+			}
+
+			if len(archive.Saved) != 1 || archive.Saved[0].Previous != tc.id {
+				t.Fatalf("Expected the new haiku kept linked to %s, got %+v", tc.id, archive.Saved)
+			}
+			if saved := archive.Saved[0]; saved.Repository != kept[tc.id].Repository || saved.Author != kept[tc.id].Author {
+				t.Errorf("Expected the repository and author of the kept haiku, got %+v", saved)
+			}
+		})
+	}
+}
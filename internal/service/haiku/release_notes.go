@@ -0,0 +1,94 @@
+package haiku
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const releaseNotesMaxTokens = 1000
+
+func (h *HaikuService) CreateReleaseNotesHaiku(ctx context.Context, request ReleaseNotesRequest) (ReleaseNotesResponse, error) {
+	mood := request.Mood
+	if mood != "" && !mood.IsValid() {
+		logging.Warnf("[HAIKU SERVICE] invalid mood: %s\n", mood)
+		return ReleaseNotesResponse{}, ErrBadHaikuRequest
+	}
+
+	if request.Mood == "" {
+		mood = MoodReflective
+	}
+
+	if strings.TrimSpace(request.ReleaseNotes) == "" {
+		logging.Warnf("[HAIKU SERVICE] release notes are empty\n")
+		return ReleaseNotesResponse{}, ErrBadHaikuRequest
+	}
+
+	releaseNotes, neutralized := sanitizeInput(request.ReleaseNotes)
+	if neutralized {
+		logging.Warnf("[HAIKU SERVICE] neutralized instruction-like content in release notes\n")
+	}
+
+	prompt := fmt.Sprintf("Create a sequence of %s haiku, one per theme, from these release notes:\n<release_notes>\n%s\n</release_notes>", mood, releaseNotes)
+
+	options := &bedrock.ClaudeOptions{
+		ModelID:   h.modelID,
+		MaxTokens: releaseNotesMaxTokens,
+		System:    ReleaseNotesSystemPrompt,
+	}
+
+	logging.Debugf("[HAIKU SERVICE] sending release notes request to Bedrock: %s\n", h.loggablePrompt(prompt))
+	response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error invoking Claude: %v\n", err)
+		return ReleaseNotesResponse{}, fmt.Errorf("%w: invoking Claude: %w", ErrCreateHaiku, err)
+	}
+
+	haiku, err := parseThemeHaiku(response.Text)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error parsing release notes haiku: %v\n", err)
+		return ReleaseNotesResponse{}, fmt.Errorf("%w: parsing release notes haiku: %w: %v", ErrCreateHaiku, bedrock.ErrResponseParsing, err)
+	}
+
+	return ReleaseNotesResponse{
+		Haiku: haiku,
+	}, nil
+}
+
+// parseThemeHaiku decodes the JSON array returned by the model, tolerating a
+// surrounding markdown code fence, and drops any empty entries.
+func parseThemeHaiku(response string) ([]ThemeHaiku, error) {
+	body := strings.TrimSpace(response)
+	body = strings.TrimPrefix(body, "```json")
+	body = strings.TrimPrefix(body, "```")
+	body = strings.TrimSuffix(body, "```")
+
+	var parsed []ThemeHaiku
+	if err := json.Unmarshal([]byte(strings.TrimSpace(body)), &parsed); err != nil {
+		return nil, err
+	}
+
+	haiku := make([]ThemeHaiku, 0, len(parsed))
+	for _, entry := range parsed {
+		entry.Theme = strings.TrimSpace(entry.Theme)
+		entry.Haiku = strings.TrimSpace(entry.Haiku)
+		if entry.Haiku == "" {
+			continue
+		}
+		haiku = append(haiku, entry)
+		if len(haiku) == MaxReleaseNotesThemes {
+			break
+		}
+	}
+
+	if len(haiku) == 0 {
+		return nil, errors.New("no haiku found in model response")
+	}
+
+	return haiku, nil
+}
@@ -0,0 +1,113 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateReleaseNotesHaiku(t *testing.T) {
+	tests := []struct {
+		name          string
+		releaseNotes  string
+		mood          Mood
+		mockResponse  string
+		mockError     error
+		expectedHaiku []ThemeHaiku
+		errorIs       error
+	}{
+		{
+			name:         "Themes returned in order",
+			releaseNotes: "- Faster queries\n- Fixed login bug",
+			mockResponse: `[{"theme": "Performance", "haiku": "Queries slip like rain\nthrough channels carved anew\nthe pages load fast"}, {"theme": "Bug fixes", "haiku": "Old cracks mended now\nthe login door swings open\nquiet in the logs"}]`,
+			expectedHaiku: []ThemeHaiku{
+				{Theme: "Performance", Haiku: "Queries slip like rain\nthrough channels carved anew\nthe pages load fast"},
+				{Theme: "Bug fixes", Haiku: "Old cracks mended now\nthe login door swings open\nquiet in the logs"},
+			},
+		},
+		{
+			name:         "Code fenced response",
+			releaseNotes: "- Faster queries",
+			mockResponse: "```json\n[{\"theme\": \"Performance\", \"haiku\": \"Queries slip like rain\"}]\n```",
+			expectedHaiku: []ThemeHaiku{
+				{Theme: "Performance", Haiku: "Queries slip like rain"},
+			},
+		},
+		{
+			name:         "Theme count is capped",
+			releaseNotes: "- Everything changed",
+			mockResponse: `[{"theme": "1", "haiku": "a"}, {"theme": "2", "haiku": "b"}, {"theme": "3", "haiku": "c"}, {"theme": "4", "haiku": "d"}, {"theme": "5", "haiku": "e"}, {"theme": "6", "haiku": "f"}]`,
+			expectedHaiku: []ThemeHaiku{
+				{Theme: "1", Haiku: "a"},
+				{Theme: "2", Haiku: "b"},
+				{Theme: "3", Haiku: "c"},
+				{Theme: "4", Haiku: "d"},
+				{Theme: "5", Haiku: "e"},
+			},
+		},
+		{
+			name:         "Invalid mood",
+			releaseNotes: "- Faster queries",
+			mood:         Mood("silly"),
+			errorIs:      ErrBadHaikuRequest,
+		},
+		{
+			name:         "Blank release notes",
+			releaseNotes: "   ",
+			errorIs:      ErrBadHaikuRequest,
+		},
+		{
+			name:         "Unparseable response",
+			releaseNotes: "- Faster queries",
+			mockResponse: "Queries slip like rain",
+			errorIs:      ErrCreateHaiku,
+		},
+		{
+			name:         "Empty theme list",
+			releaseNotes: "- Faster queries",
+			mockResponse: "[]",
+			errorIs:      ErrCreateHaiku,
+		},
+		{
+			name:         "Bedrock error",
+			releaseNotes: "- Faster queries",
+			mockError:    errors.New("bedrock API error"),
+			errorIs:      ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{
+				ResponseToReturn: tc.mockResponse,
+				ErrorToReturn:    tc.mockError,
+			}
+
+			service := NewHaikuService(mockClient, nil)
+			response, err := service.CreateReleaseNotesHaiku(context.Background(), ReleaseNotesRequest{
+				ReleaseNotes: tc.releaseNotes,
+				Mood:         tc.mood,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if len(response.Haiku) != len(tc.expectedHaiku) {
+				t.Fatalf("Expected %d haiku, got %d", len(tc.expectedHaiku), len(response.Haiku))
+			}
+			for i, expected := range tc.expectedHaiku {
+				if response.Haiku[i] != expected {
+					t.Errorf("Expected haiku %d to be %+v, got %+v", i, expected, response.Haiku[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// continuation is a haiku written as the next verse of a renga.
+type continuation struct {
+	verse string // The verse before it
+}
+
+// guidance tells the model which verse the haiku follows.
+func (c *continuation) guidance() string {
+	return fmt.Sprintf(RengaGuidanceTemplate, c.verse)
+}
+
+func (c *continuation) replaces() string {
+	return ""
+}
+
+// ContinueHaiku writes a haiku for request's commit as the verse of a renga
+// following verse, telling the model to carry the poem on from it. The verse
+// before was itself written from a commit message, so it is sanitized as one
+// before it is added to the prompt. An empty verse starts a new renga.
+func (h *HaikuService) ContinueHaiku(ctx context.Context, verse string, request HaikuCommitRequest) (HaikuCommitResponse, error) {
+	if request.Variants > 1 || request.PairingStyle == PairingVerses {
+		return HaikuCommitResponse{}, fmt.Errorf("%w: a renga verse is a single haiku", ErrBadHaikuRequest)
+	}
+
+	verse, neutralized := sanitizeInput(strings.TrimSpace(verse))
+	if neutralized {
+		logging.Warnf("[HAIKU SERVICE] neutralized instruction-like content in renga verse\n")
+	}
+	if verse == "" {
+		return h.createHaiku(ctx, request, nil)
+	}
+	return h.createHaiku(ctx, request, &continuation{verse: verse})
+}
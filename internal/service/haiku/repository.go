@@ -0,0 +1,42 @@
+package haiku
+
+import (
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+)
+
+// Longest repository details shared with the model, in runes.
+const (
+	maxRepositoryNameLength        = 100
+	maxRepositoryLanguageLength    = 32
+	maxRepositoryDescriptionLength = 280
+)
+
+// repositoryPromptData describes the repository to the prompt template. The
+// details come from the caller, so each is sanitized, kept to one line and
+// shortened. It returns nil when there is nothing to describe.
+func repositoryPromptData(repository *Repository) *prompt.Repository {
+	if repository == nil {
+		return nil
+	}
+	data := &prompt.Repository{
+		Name:        promptDetail(repository.Name, maxRepositoryNameLength),
+		Language:    promptDetail(repository.Language, maxRepositoryLanguageLength),
+		Description: promptDetail(repository.Description, maxRepositoryDescriptionLength),
+	}
+	if data.Name == "" && data.Language == "" && data.Description == "" {
+		return nil
+	}
+	return data
+}
+
+// promptDetail sanitizes text for the prompt, collapses it to a single line
+// and truncates it to limit runes.
+func promptDetail(text string, limit int) string {
+	text, _ = sanitizeInput(strings.Join(strings.Fields(text), " "))
+	if runes := []rune(text); len(runes) > limit {
+		text = strings.TrimSpace(string(runes[:limit]))
+	}
+	return text
+}
@@ -0,0 +1,195 @@
+package haiku
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/cache"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/canonical"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+// ResponseCache stores moderated model responses by request key. A cache that
+// cannot be reached behaves as if it were empty, but failing to remove an
+// entry is an error, since removals erase what the entry was made from.
+type ResponseCache interface {
+	Get(ctx context.Context, key string) (bedrock.ClaudeResult, bool)
+	Add(ctx context.Context, key string, value bedrock.ClaudeResult)
+	Remove(ctx context.Context, key string) error
+}
+
+// CacheStore stores opaque values shared between service instances, e.g. in
+// DynamoDB.
+type CacheStore interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type memoryResponseCache struct {
+	lru *cache.LRU[string, bedrock.ClaudeResult]
+}
+
+// NewMemoryResponseCache keeps up to size responses in memory for ttl. In
+// Lambda, entries are only shared by requests served from the same instance.
+func NewMemoryResponseCache(size int, ttl time.Duration) ResponseCache {
+	return memoryResponseCache{lru: cache.New[string, bedrock.ClaudeResult](size, ttl)}
+}
+
+func (c memoryResponseCache) Get(ctx context.Context, key string) (bedrock.ClaudeResult, bool) {
+	return c.lru.Get(key)
+}
+
+func (c memoryResponseCache) Add(ctx context.Context, key string, value bedrock.ClaudeResult) {
+	c.lru.Add(key, value)
+}
+
+func (c memoryResponseCache) Remove(ctx context.Context, key string) error {
+	c.lru.Remove(key)
+	return nil
+}
+
+type storeResponseCache struct {
+	store CacheStore
+	ttl   time.Duration
+}
+
+// NewStoreResponseCache keeps responses in store for ttl, so that every
+// instance can serve them. Store errors are logged and treated as misses.
+func NewStoreResponseCache(store CacheStore, ttl time.Duration) ResponseCache {
+	return storeResponseCache{store: store, ttl: ttl}
+}
+
+func (c storeResponseCache) Get(ctx context.Context, key string) (bedrock.ClaudeResult, bool) {
+	value, ok, err := c.store.Get(ctx, key)
+	if err != nil || !ok {
+		return bedrock.ClaudeResult{}, false
+	}
+
+	var result bedrock.ClaudeResult
+	if err := json.Unmarshal(value, &result); err != nil {
+		logging.Errorf("[HAIKU SERVICE] error decoding cached response %s: %v\n", key[:12], err)
+		return bedrock.ClaudeResult{}, false
+	}
+	return result, true
+}
+
+func (c storeResponseCache) Add(ctx context.Context, key string, value bedrock.ClaudeResult) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error encoding response %s: %v\n", key[:12], err)
+		return
+	}
+	// Failing to cache a haiku shouldn't fail the request it was made for.
+	_ = c.store.Put(ctx, key, encoded, c.ttl)
+}
+
+func (c storeResponseCache) Remove(ctx context.Context, key string) error {
+	return c.store.Delete(ctx, key)
+}
+
+// tieredResponseCache checks faster caches before slower ones.
+type tieredResponseCache []ResponseCache
+
+// NewTieredResponseCache checks each cache in turn, e.g. memory before
+// DynamoDB, and copies hits into the caches checked before it.
+func NewTieredResponseCache(caches ...ResponseCache) ResponseCache {
+	return tieredResponseCache(caches)
+}
+
+func (c tieredResponseCache) Get(ctx context.Context, key string) (bedrock.ClaudeResult, bool) {
+	for i, tier := range c {
+		if value, ok := tier.Get(ctx, key); ok {
+			for _, faster := range c[:i] {
+				faster.Add(ctx, key, value)
+			}
+			return value, true
+		}
+	}
+	return bedrock.ClaudeResult{}, false
+}
+
+func (c tieredResponseCache) Add(ctx context.Context, key string, value bedrock.ClaudeResult) {
+	for _, tier := range c {
+		tier.Add(ctx, key, value)
+	}
+}
+
+// Remove removes key from every tier, even when one of them fails.
+func (c tieredResponseCache) Remove(ctx context.Context, key string) error {
+	var errs []error
+	for _, tier := range c {
+		errs = append(errs, tier.Remove(ctx, key))
+	}
+	return errors.Join(errs...)
+}
+
+// generateCached serves a previously generated haiku for an equivalent request
+// when a response cache is configured, and otherwise generates a new one. It
+// reports whether the haiku came from the cache. Only responses that passed
+// moderation are cached. Strict requests are only served cached haiku that are
+// 5-7-5, and bypass requests are never served one, though the haiku they get
+// replaces the cached one. A nil cache, e.g. one turned off by its feature
+// flag, always generates.
+func (h *HaikuService) generateCached(ctx context.Context, cache ResponseCache, prompt string, options *bedrock.ClaudeOptions, strict bool, bypass bool) (bedrock.ClaudeResult, bool, error) {
+	if cache == nil {
+		response, err := h.generateModerated(ctx, prompt, options, strict)
+		return response, false, err
+	}
+
+	key := responseCacheKey(keys.TenantFromContext(ctx), prompt, options)
+	if bypass {
+		logging.Debugf("[HAIKU SERVICE] bypassing cached response %s\n", key[:12])
+	} else if cached, ok := cache.Get(ctx, key); ok && (!strict || CheckStructure(cached.Text) == nil) {
+		logging.Debugf("[HAIKU SERVICE] serving cached response %s\n", key[:12])
+		return cached, true, nil
+	}
+
+	response, err := h.generateModerated(ctx, prompt, options, strict)
+	if err != nil {
+		return bedrock.ClaudeResult{}, false, err
+	}
+
+	cache.Add(ctx, key, response)
+	return response, false, nil
+}
+
+// cacheFor returns the response cache the request in ctx uses, or nil when
+// there is none or its feature flag is off for the caller.
+func (h *HaikuService) cacheFor(ctx context.Context) ResponseCache {
+	if h.responseCache == nil || !h.flagEnabled(ctx, flags.ResponseCache) {
+		return nil
+	}
+	return h.responseCache
+}
+
+// responseCacheKey hashes the model, the canonical prompt, and the options
+// that affect generation, so requests differing only in ticket references,
+// SHAs, case, or whitespace share an entry. Tenants never share entries, nor
+// do deterministic and other requests; the keys of the default tenant's other
+// requests are unchanged from before there were either.
+func responseCacheKey(tenant string, prompt string, options *bedrock.ClaudeOptions) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s\x00%s\x00%s\x00%d\x00%g",
+		options.ModelID,
+		canonical.Key(prompt),
+		options.System,
+		options.MaxTokens,
+		options.Temperature,
+	)
+	if tenant != "" {
+		fmt.Fprintf(hash, "\x00%s", tenant)
+	}
+	if options.Deterministic {
+		fmt.Fprint(hash, "\x00deterministic")
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
@@ -0,0 +1,290 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+func TestCreateHaikuResponseCache(t *testing.T) {
+	tests := []struct {
+		name          string
+		first         HaikuCommitRequest
+		second        HaikuCommitRequest
+		expectedCalls int
+	}{
+		{
+			name:          "Identical requests share a response",
+			first:         HaikuCommitRequest{CommitMessage: "fix typo"},
+			second:        HaikuCommitRequest{CommitMessage: "fix typo"},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Case and whitespace are normalized",
+			first:         HaikuCommitRequest{CommitMessage: "fix typo"},
+			second:        HaikuCommitRequest{CommitMessage: "Fix  Typo"},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Ticket references are ignored",
+			first:         HaikuCommitRequest{CommitMessage: "fix typo (#12)"},
+			second:        HaikuCommitRequest{CommitMessage: "PROJ-7: fix typo"},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Different mood",
+			first:         HaikuCommitRequest{CommitMessage: "fix typo"},
+			second:        HaikuCommitRequest{CommitMessage: "fix typo", Mood: MoodHumerous},
+			expectedCalls: 2,
+		},
+		{
+			name:          "Different options",
+			first:         HaikuCommitRequest{CommitMessage: "fix typo"},
+			second:        HaikuCommitRequest{CommitMessage: "fix typo", Temperature: 0.2},
+			expectedCalls: 2,
+		},
+		{
+			name:          "Cache bypassed",
+			first:         HaikuCommitRequest{CommitMessage: "fix typo"},
+			second:        HaikuCommitRequest{CommitMessage: "fix typo", NoCache: true},
+			expectedCalls: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			mockClient := &MockBedrockClient{
+				InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+					calls++
+					return "haiku", nil
+				},
+			}
+			service := NewHaikuService(mockClient, &Options{
+				ResponseCache: NewMemoryResponseCache(10, 0),
+			})
+
+			var responses []HaikuCommitResponse
+			for _, request := range []HaikuCommitRequest{tc.first, tc.second} {
+				response, err := service.CreateHaiku(context.Background(), request)
+				if err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+				responses = append(responses, response)
+			}
+
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d model calls, got %d", tc.expectedCalls, calls)
+			}
+			if responses[0].Metadata.Cached {
+				t.Errorf("Expected the first response to be generated")
+			}
+			if expected := tc.expectedCalls == 1; responses[1].Metadata.Cached != expected {
+				t.Errorf("Expected second response cached %v, got %v", expected, responses[1].Metadata.Cached)
+			}
+		})
+	}
+}
+
+func TestCreateHaikuResponseCacheByTenant(t *testing.T) {
+	calls := 0
+	mockClient := &MockBedrockClient{
+		InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+			calls++
+			return "haiku", nil
+		},
+	}
+	archive := &MockArchive{}
+	service := NewHaikuService(mockClient, &Options{ResponseCache: NewMemoryResponseCache(10, 0), Archive: archive})
+
+	request := HaikuCommitRequest{CommitMessage: "fix typo"}
+	for _, tenant := range []string{"", "acme", "acme", "globex"} {
+		if _, err := service.CreateHaiku(keys.NewTenantContext(context.Background(), tenant), request); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	if calls != 3 {
+		t.Errorf("Expected each tenant to have its own cached responses, got %d model calls", calls)
+	}
+	if archive.Saved[1].Tenant != "acme" || archive.Saved[0].Tenant != "" {
+		t.Errorf("Expected haiku kept for their tenant, got %+v", archive.Saved)
+	}
+	if archive.Saved[1].CacheKey == archive.Saved[0].CacheKey || archive.Saved[1].CacheKey != archive.Saved[2].CacheKey {
+		t.Errorf("Expected cache keys shared within a tenant only, got %+v", archive.Saved)
+	}
+}
+
+func TestCreateHaikuResponseCacheSkipsBlocked(t *testing.T) {
+	calls := 0
+	mockClient := &MockBedrockClient{
+		InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+			calls++
+			return "haiku", nil
+		},
+	}
+	service := NewHaikuService(mockClient, &Options{
+		Moderator:     &MockModerator{ResultsToReturn: []moderation.Result{{Blocked: true, Reason: "word list"}, {}}},
+		ResponseCache: NewMemoryResponseCache(10, 0),
+	})
+
+	request := HaikuCommitRequest{CommitMessage: "fix typo"}
+	if _, err := service.CreateHaiku(context.Background(), request); !errors.Is(err, ErrContentBlocked) {
+		t.Fatalf("Expected blocked haiku, got %v", err)
+	}
+	if _, err := service.CreateHaiku(context.Background(), request); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("Expected blocked response not to be cached, got %d model calls", calls)
+	}
+}
+
+func TestCreateHaikuDeterministic(t *testing.T) {
+	var calls []*bedrock.ClaudeOptions
+	var prompts []string
+	mockClient := &MockBedrockClient{
+		InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+			calls = append(calls, opts)
+			prompts = append(prompts, prompt)
+			return "haiku", nil
+		},
+	}
+	service := NewHaikuService(mockClient, &Options{ResponseCache: NewMemoryResponseCache(10, 0)})
+
+	for _, message := range []string{"JIRA-123: Fix the typo", "fix  the typo (#42)", "JIRA-123: Fix the typo"} {
+		if _, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: message, Deterministic: true}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	if len(calls) != 1 {
+		t.Fatalf("Expected later requests served from the cache, got %d model calls", len(calls))
+	}
+	if !calls[0].Deterministic || !strings.Contains(prompts[0], "fix the typo") {
+		t.Errorf("Expected a deterministic call written from the canonical commit message, got %+v and %q", calls[0], prompts[0])
+	}
+
+	// Ordinary requests don't share deterministic haiku.
+	if _, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: "fix the typo"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(calls) != 2 || calls[1].Deterministic {
+		t.Errorf("Expected an ordinary model call, got %d calls", len(calls))
+	}
+
+	for _, request := range []HaikuCommitRequest{
+		{CommitMessage: "fix typo", Deterministic: true, Variants: 3},
+		{CommitMessage: "fix typo", Deterministic: true, Temperature: 0.5},
+		{CommitMessage: "fix typo", Deterministic: true, NoCache: true},
+	} {
+		if _, err := service.CreateHaiku(context.Background(), request); !errors.Is(err, ErrBadHaikuRequest) {
+			t.Errorf("Expected %+v refused, got %v", request, err)
+		}
+	}
+}
+
+// MockCacheStore is an in-memory CacheStore recording the TTL of each entry.
+type MockCacheStore struct {
+	values        map[string][]byte
+	ttls          map[string]time.Duration
+	ErrorToReturn error
+}
+
+func NewMockCacheStore() *MockCacheStore {
+	return &MockCacheStore{values: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (m *MockCacheStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if m.ErrorToReturn != nil {
+		return nil, false, m.ErrorToReturn
+	}
+	value, ok := m.values[key]
+	return value, ok, nil
+}
+
+func (m *MockCacheStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if m.ErrorToReturn != nil {
+		return m.ErrorToReturn
+	}
+	m.values[key] = value
+	m.ttls[key] = ttl
+	return nil
+}
+
+func (m *MockCacheStore) Delete(ctx context.Context, key string) error {
+	if m.ErrorToReturn != nil {
+		return m.ErrorToReturn
+	}
+	delete(m.values, key)
+	return nil
+}
+
+func TestStoreResponseCache(t *testing.T) {
+	store := NewMockCacheStore()
+	responseCache := NewStoreResponseCache(store, time.Hour)
+	result := bedrock.ClaudeResult{Text: "haiku", ModelID: "claude"}
+
+	responseCache.Add(context.Background(), "0123456789abcdef", result)
+	if store.ttls["0123456789abcdef"] != time.Hour {
+		t.Errorf("Expected a TTL of %v, got %v", time.Hour, store.ttls["0123456789abcdef"])
+	}
+
+	cached, ok := responseCache.Get(context.Background(), "0123456789abcdef")
+	if !ok || !reflect.DeepEqual(cached, result) {
+		t.Errorf("Expected cached %+v, got %+v (found %v)", result, cached, ok)
+	}
+
+	store.ErrorToReturn = errors.New("table not found")
+	if _, ok := responseCache.Get(context.Background(), "0123456789abcdef"); ok {
+		t.Error("Expected a store error to be a miss")
+	}
+}
+
+func TestTieredResponseCache(t *testing.T) {
+	memory := NewMemoryResponseCache(10, 0)
+	store := NewMockCacheStore()
+	shared := NewStoreResponseCache(store, time.Hour)
+	responseCache := NewTieredResponseCache(memory, shared)
+	result := bedrock.ClaudeResult{Text: "haiku"}
+
+	// Another instance cached the haiku, so only the shared tier has it.
+	shared.Add(context.Background(), "0123456789abcdef", result)
+
+	if cached, ok := responseCache.Get(context.Background(), "0123456789abcdef"); !ok || !reflect.DeepEqual(cached, result) {
+		t.Fatalf("Expected cached %+v, got %+v (found %v)", result, cached, ok)
+	}
+	if cached, ok := memory.Get(context.Background(), "0123456789abcdef"); !ok || !reflect.DeepEqual(cached, result) {
+		t.Errorf("Expected the hit copied into memory, got %+v (found %v)", cached, ok)
+	}
+
+	responseCache.Add(context.Background(), "fedcba9876543210", result)
+	if _, ok := memory.Get(context.Background(), "fedcba9876543210"); !ok {
+		t.Error("Expected the memory tier to be added to")
+	}
+	if _, ok := store.values["fedcba9876543210"]; !ok {
+		t.Error("Expected the shared tier to be added to")
+	}
+
+	if err := responseCache.Remove(context.Background(), "fedcba9876543210"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, ok := responseCache.Get(context.Background(), "fedcba9876543210"); ok {
+		t.Error("Expected the entry removed from every tier")
+	}
+
+	store.ErrorToReturn = errors.New("table not found")
+	if err := responseCache.Remove(context.Background(), "0123456789abcdef"); err == nil {
+		t.Error("Expected a store error removing an entry")
+	}
+	if _, ok := memory.Get(context.Background(), "0123456789abcdef"); ok {
+		t.Error("Expected the memory tier removed from despite the store error")
+	}
+}
@@ -0,0 +1,66 @@
+package haiku
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// neutralizedText replaces instruction-like content removed from user input.
+const neutralizedText = "[removed]"
+
+// injectionPatterns match phrases commonly used to override a model's
+// instructions. Commit messages are attacker-controlled in public repositories,
+// so these are neutralized before the message is interpolated into a prompt.
+var injectionPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(ignore|disregard|forget|override|bypass)\b[^.\n]{0,40}?\b(previous|prior|above|earlier|preceding|system|your)\b[^.\n]{0,30}?\b(instructions?|prompts?|directions|rules)\b`),
+	regexp.MustCompile(`(?i)\b(new|updated|revised|real)\s+(system\s+)?(instructions?|prompt|rules)\s*:`),
+	regexp.MustCompile(`(?i)\byou\s+are\s+(now|no\s+longer)\b`),
+	regexp.MustCompile(`(?i)\b(act|behave)\s+as\s+if\s+you\s+(are|were)\b`),
+	regexp.MustCompile(`(?i)\bpretend\s+(to\s+be|you\s+are)\b`),
+	regexp.MustCompile(`(?i)\b(reveal|print|repeat|output|show)\b[^.\n]{0,20}?\b(system\s+prompt|instructions|prompt\s+above)\b`),
+	regexp.MustCompile(`(?im)^\s*(system|assistant|user|human)\s*:`),
+}
+
+// delimiterPattern matches the tags used to fence user content in prompts, so
+// input cannot close its own block and append instructions after it.
+var delimiterPattern = regexp.MustCompile(`(?i)</?\s*(commit_message|release_notes|changelog_entries|style_guide|author|repository|issues|previous_verse|haiku|system|instructions?)\s*>`)
+
+// ContainsInstructions reports whether text holds instruction-like content,
+// prompt delimiters or control characters, which would be neutralized before
+// it reached the model.
+func ContainsInstructions(text string) bool {
+	cleaned, neutralized := sanitizeInput(text)
+	return neutralized || cleaned != strings.TrimSpace(text)
+}
+
+// sanitizeInput neutralizes instruction-like content and prompt delimiters in
+// user supplied text, and strips control characters other than newlines and
+// tabs. It reports whether anything was neutralized.
+func sanitizeInput(input string) (string, bool) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\t' {
+			return r
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, input)
+
+	neutralized := false
+
+	if delimiterPattern.MatchString(cleaned) {
+		cleaned = delimiterPattern.ReplaceAllString(cleaned, "")
+		neutralized = true
+	}
+
+	for _, pattern := range injectionPatterns {
+		if pattern.MatchString(cleaned) {
+			cleaned = pattern.ReplaceAllString(cleaned, neutralizedText)
+			neutralized = true
+		}
+	}
+
+	return strings.TrimSpace(cleaned), neutralized
+}
@@ -0,0 +1,30 @@
+package haiku
+
+import (
+	"context"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+)
+
+// ShadowRequest describes a served commit haiku, so that it can be written
+// again with an alternate prompt or model and the two compared offline.
+type ShadowRequest struct {
+	HaikuID       string             `json:"haikuId,omitempty"`    // ID of the kept haiku, when haiku are kept
+	Tenant        string             `json:"tenant,omitempty"`     // Tenant the haiku was written for
+	Author        string             `json:"author,omitempty"`     // Commit author credited with the haiku, if any
+	Mood          Mood               `json:"mood"`                 // Mood the haiku was written in
+	Register      Register           `json:"register,omitempty"`   // Register requested, if any
+	CommitMessage string             `json:"commitMessage"`        // Commit message as sent to the model, sanitized
+	Repository    *prompt.Repository `json:"repository,omitempty"` // Repository as described to the model, if any
+	Issues        []prompt.Issue     `json:"issues,omitempty"`     // Issues the commit references, as described to the model
+	Haiku         string             `json:"haiku"`                // Haiku served
+	Model         string             `json:"model"`                // Model that wrote the haiku served
+	PromptVersion string             `json:"promptVersion"`        // Prompt template version the haiku served was written with
+}
+
+// ShadowSampler is handed every haiku written by the model, and writes a share
+// of them again after the response, e.g. the shadow service. It must not hold
+// up or fail the request it is handed.
+type ShadowSampler interface {
+	Sample(ctx context.Context, request ShadowRequest)
+}
@@ -0,0 +1,76 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+type MockShadowSampler struct {
+	Sampled []ShadowRequest
+}
+
+func (m *MockShadowSampler) Sample(ctx context.Context, request ShadowRequest) {
+	m.Sampled = append(m.Sampled, request)
+}
+
+func TestCreateHaikuSamplesShadow(t *testing.T) {
+	tests := []struct {
+		name            string
+		modelError      error
+		expectedSampled int
+	}{
+		{
+			name:            "Sampled",
+			expectedSampled: 1,
+		},
+		{
+			name:       "Fallback haiku are not sampled",
+			modelError: fmt.Errorf("%w: ServiceUnavailableException", bedrock.ErrModelUnavailable),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			shadow := &MockShadowSampler{}
+			mockClient := &MockBedrockClient{ResponseToReturn: "haiku", ErrorToReturn: tc.modelError}
+			service := NewHaikuService(mockClient, &Options{
+				Archive:  &MockArchive{IDToReturn: "abc123"},
+				Shadow:   shadow,
+				Fallback: true,
+			})
+
+			ctx := keys.NewTenantContext(context.Background(), "acme")
+			request := HaikuCommitRequest{CommitMessage: "fix typo\n\nCo-authored-by: Hubot <hubot@example.com>", Mood: MoodTechnical, Register: RegisterFormal, Author: &Author{Name: "Mona"}}
+			if _, err := service.CreateHaiku(ctx, request); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if len(shadow.Sampled) != tc.expectedSampled {
+				t.Fatalf("Expected %d haiku sampled, got %d", tc.expectedSampled, len(shadow.Sampled))
+			}
+			if tc.expectedSampled == 0 {
+				return
+			}
+
+			expected := ShadowRequest{
+				HaikuID:       "abc123",
+				Tenant:        "acme",
+				Author:        "Mona",
+				Mood:          MoodTechnical,
+				Register:      RegisterFormal,
+				CommitMessage: "fix typo",
+				Haiku:         "haiku",
+				Model:         bedrock.ClaudeModelID,
+				PromptVersion: shadow.Sampled[0].PromptVersion,
+			}
+			if !reflect.DeepEqual(shadow.Sampled[0], expected) || expected.PromptVersion == "" {
+				t.Errorf("Expected %+v, got %+v", expected, shadow.Sampled[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package haiku
+
+import (
+	"context"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/render"
+)
+
+// createShareCard renders the haiku as a PNG card and stores it.
+func (h *HaikuService) createShareCard(ctx context.Context, haiku string, commitMessage string, repository *Repository) (*Artifact, error) {
+	card := render.Card{
+		Haiku:   haiku,
+		Subject: strings.TrimSpace(strings.SplitN(commitMessage, "\n", 2)[0]),
+	}
+	if repository != nil {
+		card.Repository = repository.Name
+	}
+
+	image, err := render.PNG(card)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.storeArtifact(ctx, "cards", "png", "image/png", image)
+}
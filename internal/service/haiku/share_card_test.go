@@ -0,0 +1,114 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockObjectStore struct {
+	PutErrorToReturn error
+	URLToReturn      string
+
+	LastKey string
+	LastTTL time.Duration
+	Objects map[string][]byte
+}
+
+func (m *MockObjectStore) Put(ctx context.Context, key string, contentType string, body []byte) error {
+	if m.PutErrorToReturn != nil {
+		return m.PutErrorToReturn
+	}
+	if m.Objects == nil {
+		m.Objects = make(map[string][]byte)
+	}
+	m.Objects[key] = body
+	return nil
+}
+
+func (m *MockObjectStore) PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	m.LastKey = key
+	m.LastTTL = ttl
+	return m.URLToReturn, nil
+}
+
+func TestCreateHaikuWithShareCard(t *testing.T) {
+	const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+	tests := []struct {
+		name            string
+		store           *MockObjectStore
+		expectedURL     string
+		expectedWarning string
+		errorIs         error
+	}{
+		{
+			name:        "Card stored",
+			store:       &MockObjectStore{URLToReturn: "https://cards.example/a.png?sig"},
+			expectedURL: "https://cards.example/a.png?sig",
+		},
+		{
+			name:            "No card store",
+			expectedWarning: ShareCardsNotConfigured,
+		},
+		{
+			name:    "Store error",
+			store:   &MockObjectStore{PutErrorToReturn: errors.New("access denied")},
+			errorIs: ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &Options{ArtifactURLTTL: 15 * time.Minute}
+			if tc.store != nil {
+				opts.Artifacts = tc.store
+			}
+			service := NewHaikuService(&MockBedrockClient{ResponseToReturn: testHaiku}, opts)
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage:    "fix: resolved login issue\n\nThe session cookie expired early.",
+				Repository:       &Repository{Name: "octo-org/octo-repo"},
+				IncludeShareCard: true,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if tc.expectedWarning != "" {
+				if response.ShareCard != nil {
+					t.Errorf("Expected no share card, got %+v", response.ShareCard)
+				}
+				if len(response.Metadata.Warnings) != 1 || response.Metadata.Warnings[0] != tc.expectedWarning {
+					t.Errorf("Expected warning %q, got %v", tc.expectedWarning, response.Metadata.Warnings)
+				}
+				return
+			}
+
+			if response.ShareCard == nil || response.ShareCard.URL != tc.expectedURL {
+				t.Fatalf("Expected share card url %q, got %+v", tc.expectedURL, response.ShareCard)
+			}
+			if response.ShareCard.ExpiresAt.IsZero() {
+				t.Errorf("Expected share card expiry to be set")
+			}
+			if tc.store.LastTTL != 15*time.Minute {
+				t.Errorf("Expected link ttl of %v, got %v", 15*time.Minute, tc.store.LastTTL)
+			}
+			if !strings.HasPrefix(tc.store.LastKey, "cards/") || !strings.HasSuffix(tc.store.LastKey, ".png") {
+				t.Errorf("Unexpected card key %q", tc.store.LastKey)
+			}
+			if len(tc.store.Objects[tc.store.LastKey]) == 0 {
+				t.Errorf("Expected the card to be stored under the presigned key")
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package haiku
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/syllable"
+)
+
+// HaikuSyllables is the number of syllables in each line of a haiku.
+var HaikuSyllables = []int{5, 7, 5}
+
+// syllableTolerance is how far a line's estimated syllable count may stray
+// from HaikuSyllables, since the estimate itself can be off by one.
+const syllableTolerance = 1
+
+// LineSyllables returns the estimated syllable count of each non-blank line
+// of text.
+func LineSyllables(text string) []int {
+	var counts []int
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			counts = append(counts, syllable.Count(line))
+		}
+	}
+	return counts
+}
+
+// CheckStructure returns an error describing how text differs from a 5-7-5
+// haiku, or nil when it is one.
+func CheckStructure(text string) error {
+	lines := LineSyllables(text)
+	if len(lines) != len(HaikuSyllables) {
+		return fmt.Errorf("%w: %d lines instead of %d", ErrInvalidStructure, len(lines), len(HaikuSyllables))
+	}
+
+	counts := make([]string, len(lines))
+	valid := true
+	for i, count := range lines {
+		counts[i] = fmt.Sprint(count)
+		if count < HaikuSyllables[i]-syllableTolerance || count > HaikuSyllables[i]+syllableTolerance {
+			valid = false
+		}
+	}
+	if !valid {
+		return fmt.Errorf("%w: lines of %s syllables", ErrInvalidStructure, strings.Join(counts, "-"))
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+const validHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+func TestCheckStructure(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		errorIs error
+	}{
+		{
+			name: "5-7-5",
+			text: validHaiku,
+		},
+		{
+			name: "Blank lines ignored",
+			text: "\nOld cracks mended now\n\nthe login door swings open\nquiet in the logs\n",
+		},
+		{
+			name:    "Two lines",
+			text:    "Old cracks mended now\nthe login door swings open",
+			errorIs: ErrInvalidStructure,
+		},
+		{
+			name:    "Long middle line",
+			text:    "Old cracks mended now\nthe login door swings open wide for everyone\nquiet in the logs",
+			errorIs: ErrInvalidStructure,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckStructure(tc.text)
+			if tc.errorIs == nil && err != nil {
+				t.Errorf("Expected no error but got: %v", err)
+			}
+			if tc.errorIs != nil && !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestCreateHaikuStrict(t *testing.T) {
+	tests := []struct {
+		name          string
+		strict        bool
+		retries       int
+		responses     []string
+		expectedCalls int
+		errorIs       error
+	}{
+		{
+			name:          "Valid haiku",
+			strict:        true,
+			responses:     []string{validHaiku},
+			expectedCalls: 1,
+		},
+		{
+			name:          "Regenerated within the retry budget",
+			strict:        true,
+			retries:       1,
+			responses:     []string{"leaves fall", validHaiku},
+			expectedCalls: 2,
+		},
+		{
+			name:          "Retry budget exhausted",
+			strict:        true,
+			retries:       1,
+			responses:     []string{"leaves fall", "leaves fall"},
+			expectedCalls: 2,
+			errorIs:       ErrInvalidStructure,
+		},
+		{
+			name:          "Not strict",
+			responses:     []string{"leaves fall"},
+			expectedCalls: 1,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			mockClient := &MockBedrockClient{
+				InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+					calls++
+					return tc.responses[min(calls, len(tc.responses))-1], nil
+				},
+			}
+			service := NewHaikuService(mockClient, &Options{StructureRetries: tc.retries})
+
+			_, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage: "fix: resolved login issue",
+				Strict:        tc.strict,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d model calls, got %d", tc.expectedCalls, calls)
+			}
+		})
+	}
+}
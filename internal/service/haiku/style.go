@@ -0,0 +1,37 @@
+package haiku
+
+import (
+	"context"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/flags"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// StyleGuides returns the house style of the context's tenant, or "" when it
+// has none, e.g. the style service.
+type StyleGuides interface {
+	Guide(ctx context.Context) (string, error)
+}
+
+// styleGuide returns the tenant's style guide, ready to be fenced into the
+// system prompt. Guides are checked when they are stored, but are sanitized
+// again here like any other user input. A guide that can't be read is left
+// out rather than failing the haiku, as is every guide while its feature flag
+// is off.
+func (h *HaikuService) styleGuide(ctx context.Context) string {
+	if h.styles == nil || !h.flagEnabled(ctx, flags.StyleGuides) {
+		return ""
+	}
+
+	guide, err := h.styles.Guide(ctx)
+	if err != nil {
+		logging.Errorf("[HAIKU SERVICE] error reading style guide, writing without it: %v\n", err)
+		return ""
+	}
+
+	guide, neutralized := sanitizeInput(guide)
+	if neutralized {
+		logging.Warnf("[HAIKU SERVICE] neutralized instruction-like content in style guide\n")
+	}
+	return guide
+}
@@ -0,0 +1,38 @@
+package haiku
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// summaryMaxTokens is ample for a single sentence.
+const summaryMaxTokens = 100
+
+type summaryResult struct {
+	text string
+	err  error
+}
+
+// createSummary returns a one-sentence, plain-language description of the
+// commit for readers who want the facts rather than the poem.
+func (h *HaikuService) createSummary(ctx context.Context, commitMessage string) (string, error) {
+	prompt := fmt.Sprintf(SummaryPromptTemplate, commitMessage)
+
+	options := &bedrock.ClaudeOptions{
+		ModelID:   h.modelID,
+		MaxTokens: summaryMaxTokens,
+		System:    SummarySystemPrompt,
+	}
+
+	logging.Debugf("[HAIKU SERVICE] sending summary request to Bedrock: %s\n", h.loggablePrompt(prompt))
+	response, err := h.bedrockClient.InvokeClaude(ctx, prompt, options)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(response.Text), nil
+}
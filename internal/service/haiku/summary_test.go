@@ -0,0 +1,86 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+func TestCreateHaikuWithSummary(t *testing.T) {
+	const (
+		testHaiku   = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+		testSummary = "This change fixes a problem that stopped people from logging in."
+	)
+
+	tests := []struct {
+		name            string
+		includeSummary  bool
+		summaryError    error
+		expectedSummary string
+		errorIs         error
+	}{
+		{
+			name:            "Summary requested",
+			includeSummary:  true,
+			expectedSummary: testSummary,
+		},
+		{
+			name:           "Summary not requested",
+			includeSummary: false,
+		},
+		{
+			name:           "Summary error",
+			includeSummary: true,
+			summaryError:   errors.New("bedrock API error"),
+			errorIs:        ErrCreateHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			mockClient := &MockBedrockClient{
+				InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+					calls++
+					if opts.System == SummarySystemPrompt {
+						return " " + testSummary + "\n", tc.summaryError
+					}
+					return testHaiku, nil
+				},
+			}
+
+			service := NewHaikuService(mockClient, nil)
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{
+				CommitMessage:  "fix: resolved login issue",
+				IncludeSummary: tc.includeSummary,
+			})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if response.Haiku != testHaiku {
+				t.Errorf("Expected haiku %q, got %q", testHaiku, response.Haiku)
+			}
+			if response.Summary != tc.expectedSummary {
+				t.Errorf("Expected summary %q, got %q", tc.expectedSummary, response.Summary)
+			}
+
+			expectedCalls := 1
+			if tc.includeSummary {
+				expectedCalls = 2
+			}
+			if calls != expectedCalls {
+				t.Errorf("Expected %d model calls, got %d", expectedCalls, calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package haiku
+
+import (
+	"context"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// Usage describes one commit haiku served, for usage statistics.
+type Usage struct {
+	Time          time.Time
+	Mood          Mood
+	Repository    string        // Repository name, when the request gave one
+	Author        string        // Author name, when the request gave one
+	PromptVersion string        // Prompt template version; empty for fallback haiku
+	Model         string        // Model that wrote the haiku, e.g. a canary; empty for fallback haiku
+	Cached        bool          // Served from the response cache, without calling the model
+	Latency       time.Duration // Time spent generating the haiku; zero when none was generated
+	InputTokens   int           // Summed over every variant generated
+	OutputTokens  int           // Summed over every variant generated
+	Variants      int           // Candidate haiku written, when more than one was requested
+}
+
+// UsageRecorder keeps usage statistics, e.g. daily counters in DynamoDB.
+type UsageRecorder interface {
+	Record(ctx context.Context, usage Usage) error
+}
+
+// recordUsage hands usage to the recorder. Statistics are best effort, so a
+// failure is logged rather than failing the request.
+func (h *HaikuService) recordUsage(ctx context.Context, usage Usage) {
+	if h.usage == nil {
+		return
+	}
+	if err := h.usage.Record(ctx, usage); err != nil {
+		logging.Errorf("[HAIKU SERVICE] error recording usage: %v\n", err)
+	}
+}
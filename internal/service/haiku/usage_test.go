@@ -0,0 +1,66 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+type MockUsageRecorder struct {
+	ErrorToReturn error
+	Recorded      []Usage
+}
+
+func (m *MockUsageRecorder) Record(ctx context.Context, usage Usage) error {
+	m.Recorded = append(m.Recorded, usage)
+	return m.ErrorToReturn
+}
+
+func TestCreateHaikuRecordsUsage(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	recorder := &MockUsageRecorder{ErrorToReturn: errors.New("table not found")}
+	mockClient := &MockBedrockClient{
+		ResponseToReturn: "haiku",
+		UsageToReturn:    bedrock.Usage{InputTokens: 120, OutputTokens: 30},
+	}
+	service := NewHaikuService(mockClient, &Options{
+		ResponseCache: NewMemoryResponseCache(10, 0),
+		Usage:         recorder,
+	})
+	// Each call to now advances the clock by 300ms.
+	service.now = func() time.Time {
+		now = now.Add(300 * time.Millisecond)
+		return now
+	}
+
+	request := HaikuCommitRequest{CommitMessage: "fix typo", Mood: MoodTechnical, Repository: &Repository{Name: "octo/leaves"}, Author: &Author{Name: "Mona", Email: "mona@example.com"}}
+	for range 2 {
+		// A recorder failure must not fail the request.
+		if _, err := service.CreateHaiku(context.Background(), request); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	if len(recorder.Recorded) != 2 {
+		t.Fatalf("Expected usage for both requests, got %d", len(recorder.Recorded))
+	}
+
+	generated := recorder.Recorded[0]
+	if generated.Mood != MoodTechnical || generated.Repository != "octo/leaves" || generated.Author != "Mona" || generated.Cached {
+		t.Errorf("Expected a generated technical haiku by Mona for octo/leaves, got %+v", generated)
+	}
+	if generated.Model != bedrock.ClaudeModelID {
+		t.Errorf("Expected the haiku written by %s, got %q", bedrock.ClaudeModelID, generated.Model)
+	}
+	if generated.Latency != 300*time.Millisecond || generated.InputTokens != 120 || generated.OutputTokens != 30 {
+		t.Errorf("Expected the model latency and tokens, got %+v", generated)
+	}
+
+	cached := recorder.Recorded[1]
+	if !cached.Cached || cached.Latency != 0 || cached.InputTokens != 0 || cached.OutputTokens != 0 {
+		t.Errorf("Expected a cached haiku without latency or tokens, got %+v", cached)
+	}
+}
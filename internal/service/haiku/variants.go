@@ -0,0 +1,70 @@
+package haiku
+
+import (
+	"context"
+	"sync"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+// MaxVariants is the most candidate haiku one request can ask for.
+const MaxVariants = 5
+
+// Variants are written at temperatures spread evenly over this range, so that
+// they range from the likeliest haiku to the most adventurous.
+const (
+	variantMinTemperature = 0.4
+	variantMaxTemperature = 1.0
+)
+
+// Variant is one of the candidate haiku written for a request, for the caller
+// to pick from.
+type Variant struct {
+	ID          string  `json:"id,omitempty"`     // Identifies the stored haiku, for voting
+	Haiku       string  `json:"haiku"`            // Candidate haiku
+	Temperature float64 `json:"temperature"`      // Temperature the candidate was written at
+	Cached      bool    `json:"cached,omitempty"` // Candidate was reused from the response cache rather than generated
+}
+
+// generated is one haiku written for a request, and how.
+type generated struct {
+	result      bedrock.ClaudeResult
+	cached      bool
+	temperature float64
+}
+
+// variantTemperatures spreads n temperatures evenly over the variant range.
+func variantTemperatures(n int) []float64 {
+	temperatures := make([]float64, n)
+	for i := range temperatures {
+		temperatures[i] = variantMinTemperature + (variantMaxTemperature-variantMinTemperature)*float64(i)/float64(n-1)
+	}
+	return temperatures
+}
+
+// generateVariants writes n candidate haiku concurrently, each at its own
+// temperature and each moderated and cached as a single haiku would be. The
+// caller asked for every candidate, so any one failing fails them all.
+func (h *HaikuService) generateVariants(ctx context.Context, cache ResponseCache, prompt string, options *bedrock.ClaudeOptions, strict bool, bypass bool, n int) ([]generated, error) {
+	variants := make([]generated, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i, temperature := range variantTemperatures(n) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			variantOptions := *options
+			variantOptions.Temperature = temperature
+			variants[i].temperature = temperature
+			variants[i].result, variants[i].cached, errs[i] = h.generateCached(ctx, cache, prompt, &variantOptions, strict, bypass)
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return variants, nil
+}
@@ -0,0 +1,136 @@
+package haiku
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+)
+
+func TestVariantTemperatures(t *testing.T) {
+	tests := []struct {
+		n        int
+		expected []float64
+	}{
+		{n: 2, expected: []float64{0.4, 1.0}},
+		{n: 3, expected: []float64{0.4, 0.7, 1.0}},
+	}
+
+	for _, tc := range tests {
+		t.Run(fmt.Sprint(tc.n), func(t *testing.T) {
+			temperatures := variantTemperatures(tc.n)
+			if len(temperatures) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, temperatures)
+			}
+			for i := range temperatures {
+				if diff := temperatures[i] - tc.expected[i]; diff > 1e-9 || diff < -1e-9 {
+					t.Errorf("Expected %v, got %v", tc.expected, temperatures)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateHaikuVariants(t *testing.T) {
+	tests := []struct {
+		name             string
+		variants         int
+		failAt           float64 // Temperature whose invocation fails, if any
+		modelError       error
+		expectedVariants int
+		expectedWarning  string
+		errorIs          error
+	}{
+		{
+			name:             "Three variants",
+			variants:         3,
+			expectedVariants: 3,
+		},
+		{
+			name:             "One variant is a single haiku",
+			variants:         1,
+			expectedVariants: 0,
+		},
+		{
+			name:             "Capped",
+			variants:         MaxVariants + 3,
+			expectedVariants: MaxVariants,
+			expectedWarning:  fmt.Sprintf("variants reduced from %d to the limit of %d", MaxVariants+3, MaxVariants),
+		},
+		{
+			name:     "Negative",
+			variants: -1,
+			errorIs:  ErrBadHaikuRequest,
+		},
+		{
+			name:       "One variant fails",
+			variants:   3,
+			failAt:     1.0,
+			modelError: bedrock.ErrValidation,
+			errorIs:    bedrock.ErrValidation,
+		},
+		{
+			name:            "Model unavailable",
+			variants:        3,
+			failAt:          0.4,
+			modelError:      bedrock.ErrModelUnavailable,
+			expectedWarning: VariantsUnavailable,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockClient := &MockBedrockClient{
+				UsageToReturn: bedrock.Usage{InputTokens: 100, OutputTokens: 20},
+				InvokeClaudeFunc: func(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (string, error) {
+					if tc.modelError != nil && opts.Temperature == tc.failAt {
+						return "", tc.modelError
+					}
+					return fmt.Sprintf("haiku at %g", opts.Temperature), nil
+				},
+			}
+			archive := &MockArchive{IDToReturn: "abc123"}
+			recorder := &MockUsageRecorder{}
+			service := NewHaikuService(mockClient, &Options{Archive: archive, Usage: recorder, Fallback: true})
+
+			response, err := service.CreateHaiku(context.Background(), HaikuCommitRequest{CommitMessage: "fix typo", Variants: tc.variants})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if len(response.Variants) != tc.expectedVariants {
+				t.Fatalf("Expected %d variants, got %+v", tc.expectedVariants, response.Variants)
+			}
+			if tc.expectedWarning != "" && !slices.Contains(response.Metadata.Warnings, tc.expectedWarning) {
+				t.Errorf("Expected warning %q, got %v", tc.expectedWarning, response.Metadata.Warnings)
+			}
+			if tc.expectedVariants == 0 {
+				return
+			}
+
+			temperatures := variantTemperatures(tc.expectedVariants)
+			for i, variant := range response.Variants {
+				if variant.Temperature != temperatures[i] || variant.Haiku != fmt.Sprintf("haiku at %g", temperatures[i]) || variant.ID != "abc123" {
+					t.Errorf("Expected variant %d written at %g and kept, got %+v", i, temperatures[i], variant)
+				}
+			}
+			if response.Haiku != response.Variants[0].Haiku || response.ID != response.Variants[0].ID {
+				t.Errorf("Expected the first variant as the haiku, got %+v", response)
+			}
+			if len(archive.Saved) != tc.expectedVariants {
+				t.Errorf("Expected every variant kept, got %d", len(archive.Saved))
+			}
+
+			usage := recorder.Recorded[0]
+			if usage.Variants != tc.expectedVariants || usage.InputTokens != 100*tc.expectedVariants || usage.OutputTokens != 20*tc.expectedVariants {
+				t.Errorf("Expected usage summed over %d variants, got %+v", tc.expectedVariants, usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Package issues looks up the titles of the issues commits reference, in Jira
+// for keys like JIRA-4521 and on GitHub for references like #456, so that
+// terse commits can be described to the model. A tenant only sees the issues
+// of the Jira projects and repositories bound to it.
+package issues
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/cache"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	// titleCacheSize and titleCacheTTL bound the titles kept in memory, so
+	// that busy repositories don't look up the same issue for every commit.
+	titleCacheSize = 1000
+	titleCacheTTL  = time.Hour
+)
+
+var (
+	ErrUnsupported = errors.New("no issue tracker configured for reference")
+	ErrBadBindings = errors.New("bad issue bindings")
+)
+
+// Binding gives a tenant the issues of Jira projects and GitHub repositories.
+type Binding struct {
+	Tenant       string   `json:"tenant,omitempty"`       // (default: the default tenant)
+	Projects     []string `json:"projects,omitempty"`     // Jira project keys, e.g. "JIRA" for JIRA-4521
+	Repositories []string `json:"repositories,omitempty"` // e.g. "octo-org/octo-repo", or "octo-org/*" for every repository of the owner
+}
+
+// ParseBindings reads bindings from JSON, e.g.
+// [{"tenant": "acme", "projects": ["ACME"], "repositories": ["acme/*"]}].
+func ParseBindings(raw string) ([]Binding, error) {
+	var bindings []Binding
+	if err := json.Unmarshal([]byte(raw), &bindings); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadBindings, err)
+	}
+
+	for _, binding := range bindings {
+		if binding.Tenant != "" && !keys.ValidTenant(binding.Tenant) {
+			return nil, fmt.Errorf("%w: invalid tenant %q", ErrBadBindings, binding.Tenant)
+		}
+		for _, repository := range binding.Repositories {
+			if owner, repo, ok := strings.Cut(repository, "/"); !ok || owner == "" || repo == "" {
+				return nil, fmt.Errorf("%w: %q is not an owner/repo repository", ErrBadBindings, repository)
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// binds reports whether the binding gives its tenant the referenced issue.
+func (b Binding) binds(ref haiku.IssueRef) bool {
+	if ref.Key != "" {
+		project, _, _ := strings.Cut(ref.Key, "-")
+		return slices.Contains(b.Projects, project)
+	}
+	owner, _, _ := strings.Cut(ref.Repository, "/")
+	return slices.ContainsFunc(b.Repositories, func(repository string) bool {
+		return strings.EqualFold(repository, ref.Repository) || strings.EqualFold(repository, owner+"/*")
+	})
+}
+
+// titleKey caches titles per tenant, so that one tenant's lookups are never
+// served to another.
+type titleKey struct {
+	tenant string
+	ref    haiku.IssueRef
+}
+
+// JiraAPI looks up Jira issue summaries, e.g. the Jira client.
+type JiraAPI interface {
+	IssueSummary(ctx context.Context, key string) (string, error)
+}
+
+// GitHubAPI looks up GitHub issue titles, e.g. the GitHub App client.
+type GitHubAPI interface {
+	IssueTitle(ctx context.Context, owner, repo string, number int) (string, error)
+}
+
+type IssueService struct {
+	jira     JiraAPI
+	github   GitHubAPI
+	bindings []Binding
+	titles   *cache.LRU[titleKey, string]
+}
+
+type Options struct {
+	Jira     JiraAPI   // Looks up issue keys such as JIRA-4521 (default: none)
+	GitHub   GitHubAPI // Looks up references such as #456 in the commit's repository (default: none)
+	Bindings []Binding // Projects and repositories whose issues each tenant sees (default: none, nothing looked up)
+}
+
+func NewIssueService(opts *Options) *IssueService {
+	service := &IssueService{
+		titles: cache.New[titleKey, string](titleCacheSize, titleCacheTTL),
+	}
+
+	if opts != nil {
+		service.jira = opts.Jira
+		service.github = opts.GitHub
+		service.bindings = opts.Bindings
+	}
+
+	return service
+}
+
+// IssueTitle returns the title of the referenced issue, from Jira for issue
+// keys and from GitHub for numbered references, when its project or
+// repository is bound to the context's tenant.
+func (s *IssueService) IssueTitle(ctx context.Context, ref haiku.IssueRef) (string, error) {
+	tenant := keys.TenantFromContext(ctx)
+	if !slices.ContainsFunc(s.bindings, func(b Binding) bool { return b.Tenant == tenant && b.binds(ref) }) {
+		return "", fmt.Errorf("%w: %s is not bound to the tenant", ErrUnsupported, ref)
+	}
+
+	key := titleKey{tenant: tenant, ref: ref}
+	if title, ok := s.titles.Get(key); ok {
+		return title, nil
+	}
+
+	title, err := s.lookup(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	s.titles.Add(key, title)
+	return title, nil
+}
+
+func (s *IssueService) lookup(ctx context.Context, ref haiku.IssueRef) (string, error) {
+	if ref.Key != "" {
+		if s.jira == nil {
+			return "", fmt.Errorf("%w: %s", ErrUnsupported, ref)
+		}
+		return s.jira.IssueSummary(ctx, ref.Key)
+	}
+
+	owner, repo, ok := strings.Cut(ref.Repository, "/")
+	if s.github == nil || !ok {
+		return "", fmt.Errorf("%w: %s in %q", ErrUnsupported, ref, ref.Repository)
+	}
+	return s.github.IssueTitle(ctx, owner, repo, ref.Number)
+}
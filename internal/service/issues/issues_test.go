@@ -0,0 +1,151 @@
+package issues
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+var testBindings = []Binding{
+	{Projects: []string{"LEAF"}, Repositories: []string{"octo/*"}},
+	{Tenant: "acme", Projects: []string{"ACME"}, Repositories: []string{"acme/api"}},
+}
+
+type MockJira struct {
+	Summaries map[string]string
+	Lookups   int
+}
+
+func (m *MockJira) IssueSummary(ctx context.Context, key string) (string, error) {
+	m.Lookups++
+	summary, ok := m.Summaries[key]
+	if !ok {
+		return "", errors.New("issue does not exist")
+	}
+	return summary, nil
+}
+
+type MockGitHub struct {
+	LastRepository string
+}
+
+func (m *MockGitHub) IssueTitle(ctx context.Context, owner, repo string, number int) (string, error) {
+	m.LastRepository = owner + "/" + repo
+	return "Leaves fall out of order", nil
+}
+
+func TestIssueTitle(t *testing.T) {
+	tests := []struct {
+		name     string
+		ref      haiku.IssueRef
+		tenant   string
+		noGitHub bool
+		expected string
+		errorIs  error
+	}{
+		{
+			name:     "Jira key",
+			ref:      haiku.IssueRef{Key: "LEAF-4521"},
+			expected: "Login times out behind the proxy",
+		},
+		{
+			name:     "GitHub reference",
+			ref:      haiku.IssueRef{Number: 456, Repository: "octo/leaves"},
+			expected: "Leaves fall out of order",
+		},
+		{
+			name:     "GitHub not configured",
+			ref:      haiku.IssueRef{Number: 456, Repository: "octo/leaves"},
+			noGitHub: true,
+			errorIs:  ErrUnsupported,
+		},
+		{
+			name:    "Repository without an owner",
+			ref:     haiku.IssueRef{Number: 456, Repository: "leaves"},
+			errorIs: ErrUnsupported,
+		},
+		{
+			name:    "Project not bound",
+			ref:     haiku.IssueRef{Key: "OPS-12"},
+			errorIs: ErrUnsupported,
+		},
+		{
+			name:    "Another tenant's project",
+			ref:     haiku.IssueRef{Key: "LEAF-4521"},
+			tenant:  "acme",
+			errorIs: ErrUnsupported,
+		},
+		{
+			name:     "Tenant's repository",
+			ref:      haiku.IssueRef{Number: 456, Repository: "ACME/api"},
+			tenant:   "acme",
+			expected: "Leaves fall out of order",
+		},
+		{
+			name:    "Another of the owner's repositories",
+			ref:     haiku.IssueRef{Number: 456, Repository: "acme/billing"},
+			tenant:  "acme",
+			errorIs: ErrUnsupported,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := &Options{
+				Jira:     &MockJira{Summaries: map[string]string{"LEAF-4521": "Login times out behind the proxy"}},
+				GitHub:   &MockGitHub{},
+				Bindings: testBindings,
+			}
+			if tc.noGitHub {
+				opts.GitHub = nil
+			}
+			service := NewIssueService(opts)
+
+			title, err := service.IssueTitle(keys.NewTenantContext(context.Background(), tc.tenant), tc.ref)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if title != tc.expected {
+				t.Errorf("Expected title %q, got %q", tc.expected, title)
+			}
+		})
+	}
+}
+
+func TestIssueTitleCaching(t *testing.T) {
+	jira := &MockJira{Summaries: map[string]string{"LEAF-4521": "Login times out behind the proxy"}}
+	bindings := append([]Binding{{Tenant: "globex", Projects: []string{"LEAF"}}}, testBindings...)
+	service := NewIssueService(&Options{Jira: jira, Bindings: bindings})
+
+	for _, key := range []string{"LEAF-4521", "LEAF-4521", "LEAF-1", "LEAF-1"} {
+		_, _ = service.IssueTitle(context.Background(), haiku.IssueRef{Key: key})
+	}
+	if jira.Lookups != 3 {
+		t.Errorf("Expected titles cached but failures retried, got %d lookups", jira.Lookups)
+	}
+
+	// Titles are cached per tenant.
+	_, _ = service.IssueTitle(keys.NewTenantContext(context.Background(), "globex"), haiku.IssueRef{Key: "LEAF-4521"})
+	if jira.Lookups != 4 {
+		t.Errorf("Expected another tenant's title looked up again, got %d lookups", jira.Lookups)
+	}
+}
+
+func TestParseBindings(t *testing.T) {
+	bindings, err := ParseBindings(`[{"projects": ["LEAF"]}, {"tenant": "acme", "repositories": ["acme/*"]}]`)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(bindings) != 2 || bindings[1].Tenant != "acme" || bindings[1].Repositories[0] != "acme/*" {
+		t.Errorf("Expected two bindings, got %+v", bindings)
+	}
+
+	for _, raw := range []string{`{}`, `[{"tenant": "Acme"}]`, `[{"repositories": ["acme"]}]`, `[{"repositories": ["/api"]}]`} {
+		if _, err := ParseBindings(raw); !errors.Is(err, ErrBadBindings) {
+			t.Errorf("Expected %s to be refused, got %v", raw, err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+const (
+	// SignatureHeader carries "sha256=<hex>", the HMAC-SHA256 of the timestamp,
+	// a ".", and the body, keyed with the callback secret.
+	SignatureHeader = "X-Haiku-Signature"
+	// TimestampHeader carries the Unix time the callback was signed, so that
+	// receivers can refuse replayed deliveries.
+	TimestampHeader = "X-Haiku-Timestamp"
+
+	DefaultCallbackAttempts = 3
+	DefaultCallbackBackoff  = time.Second
+)
+
+// sharedAddressSpace is the carrier-grade NAT range, which IsPrivate does not
+// cover but which is no more reachable from the internet.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+var (
+	ErrBadCallback     = errors.New("invalid callback url")
+	ErrDeliverCallback = errors.New("error delivering callback")
+)
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// CallbackSender posts finished jobs to the URL their caller supplied,
+// signed so that the caller can tell the delivery came from this service.
+// Failed deliveries are retried with exponential backoff.
+type CallbackSender struct {
+	httpClient HTTPClient
+	secret     []byte
+	attempts   int
+	backoff    time.Duration
+	now        func() time.Time
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+type CallbackOptions struct {
+	Attempts int           // Deliveries tried before giving up (default: 3)
+	Backoff  time.Duration // Wait before the first retry, doubling after each (default: 1 second)
+}
+
+func NewCallbackSender(httpClient HTTPClient, secret []byte, opts *CallbackOptions) *CallbackSender {
+	sender := &CallbackSender{
+		httpClient: httpClient,
+		secret:     secret,
+		attempts:   DefaultCallbackAttempts,
+		backoff:    DefaultCallbackBackoff,
+		now:        time.Now,
+		sleep:      sleep,
+	}
+
+	if opts != nil {
+		if opts.Attempts > 0 {
+			sender.attempts = opts.Attempts
+		}
+		if opts.Backoff > 0 {
+			sender.backoff = opts.Backoff
+		}
+	}
+
+	return sender
+}
+
+// NewDefaultCallbackSender delivers callbacks over a client that only
+// connects to public addresses: every address a callback host resolves to is
+// checked as it is dialled, and redirects are not followed, so neither DNS nor
+// a redirect can steer a signed delivery into the service's own network.
+func NewDefaultCallbackSender(secret []byte) *CallbackSender {
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: dialPublicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	client := &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return NewCallbackSender(client, secret, nil)
+}
+
+// ValidateCallbackURL checks that rawURL is an https URL whose host is not
+// localhost or a literal loopback, private, link-local, unspecified or
+// carrier-grade NAT address. Hostnames are not resolved here; the default
+// sender refuses to connect to such addresses when it dials them.
+func ValidateCallbackURL(rawURL string) error {
+	callbackURL, err := url.Parse(rawURL)
+	if err != nil || callbackURL.Scheme != "https" || callbackURL.Hostname() == "" {
+		return fmt.Errorf("%w: %q is not an https url", ErrBadCallback, rawURL)
+	}
+
+	host := strings.ToLower(callbackURL.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %q is not a public host", ErrBadCallback, host)
+	}
+	if ip := net.ParseIP(host); ip != nil && !isPublic(ip) {
+		return fmt.Errorf("%w: %q is not a public address", ErrBadCallback, host)
+	}
+	return nil
+}
+
+// dialPublicOnly is a net.Dialer Control hook refusing connections to
+// addresses that are not public, after the host name has been resolved.
+func dialPublicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBadCallback, err)
+	}
+	if ip := net.ParseIP(host); ip == nil || !isPublic(ip) {
+		return fmt.Errorf("%w: %q is not a public address", ErrBadCallback, host)
+	}
+	return nil
+}
+
+func isPublic(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsLinkLocalUnicast() && !ip.IsUnspecified() && !sharedAddressSpace.Contains(ip)
+}
+
+// Send posts job to callbackURL. Network errors, 429s and 5xx responses are
+// retried; other responses are final.
+func (s *CallbackSender) Send(ctx context.Context, callbackURL string, job Job) error {
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("%w: encoding job: %w", ErrDeliverCallback, err)
+	}
+
+	backoff := s.backoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ctx, callbackURL, payload)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= s.attempts {
+			return fmt.Errorf("%w: %w", ErrDeliverCallback, err)
+		}
+
+		logging.Warnf("[JOBS SERVICE] callback for job %s failed, retrying in %s (attempt %d of %d): %v\n", job.ID, backoff, attempt, s.attempts, err)
+		if err := s.sleep(ctx, backoff); err != nil {
+			return fmt.Errorf("%w: %w", ErrDeliverCallback, err)
+		}
+		backoff *= 2
+	}
+}
+
+// post delivers the payload once, and reports whether a failure is worth
+// retrying.
+func (s *CallbackSender) post(ctx context.Context, callbackURL string, payload []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(s.secret, timestamp, payload))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return retry, fmt.Errorf("callback url returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return false, nil
+}
+
+// Sign returns the hex encoded signature of a callback body sent at
+// timestamp, for receivers to compare against SignatureHeader.
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
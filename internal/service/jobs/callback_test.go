@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+type MockHTTPClient struct {
+	StatusCodes []int // Returned in turn; the last repeats
+	Requests    []*http.Request
+	Bodies      []string
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	body, _ := io.ReadAll(req.Body)
+	m.Requests = append(m.Requests, req)
+	m.Bodies = append(m.Bodies, string(body))
+
+	status := m.StatusCodes[min(len(m.Requests), len(m.StatusCodes))-1]
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader(""))}, nil
+}
+
+type MockCallbacks struct {
+	ErrorToReturn error
+	LastURL       string
+	LastJob       Job
+	Calls         int
+}
+
+func (m *MockCallbacks) Send(ctx context.Context, callbackURL string, job Job) error {
+	m.Calls++
+	m.LastURL = callbackURL
+	m.LastJob = job
+	return m.ErrorToReturn
+}
+
+func TestCallbackSenderSend(t *testing.T) {
+	tests := []struct {
+		name             string
+		statusCodes      []int
+		expectedAttempts int
+		expectedBackoffs []time.Duration
+		expectErr        bool
+	}{
+		{
+			name:             "Delivered",
+			statusCodes:      []int{http.StatusNoContent},
+			expectedAttempts: 1,
+		},
+		{
+			name:             "Retried after server errors",
+			statusCodes:      []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusOK},
+			expectedAttempts: 3,
+			expectedBackoffs: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:             "Gives up after every attempt fails",
+			statusCodes:      []int{http.StatusServiceUnavailable},
+			expectedAttempts: 3,
+			expectedBackoffs: []time.Duration{time.Second, 2 * time.Second},
+			expectErr:        true,
+		},
+		{
+			name:             "Client errors are not retried",
+			statusCodes:      []int{http.StatusNotFound},
+			expectedAttempts: 1,
+			expectErr:        true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			httpClient := &MockHTTPClient{StatusCodes: tc.statusCodes}
+			sender := NewCallbackSender(httpClient, []byte("callback-secret"), nil)
+			sender.now = func() time.Time { return time.Unix(1760000000, 0) }
+			var backoffs []time.Duration
+			sender.sleep = func(ctx context.Context, d time.Duration) error {
+				backoffs = append(backoffs, d)
+				return nil
+			}
+
+			err := sender.Send(context.Background(), "https://example.com/haiku", Job{ID: "job", Status: StatusSucceeded})
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+			if err != nil && !errors.Is(err, ErrDeliverCallback) {
+				t.Errorf("Expected ErrDeliverCallback, got %v", err)
+			}
+			if len(httpClient.Requests) != tc.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tc.expectedAttempts, len(httpClient.Requests))
+			}
+			if !slices.Equal(backoffs, tc.expectedBackoffs) {
+				t.Errorf("Expected backoffs %v, got %v", tc.expectedBackoffs, backoffs)
+			}
+
+			req := httpClient.Requests[0]
+			if req.Header.Get(TimestampHeader) != "1760000000" {
+				t.Errorf("Expected timestamp 1760000000, got %q", req.Header.Get(TimestampHeader))
+			}
+			expected := "sha256=" + Sign([]byte("callback-secret"), "1760000000", []byte(httpClient.Bodies[0]))
+			if req.Header.Get(SignatureHeader) != expected {
+				t.Errorf("Expected signature %q, got %q", expected, req.Header.Get(SignatureHeader))
+			}
+			if !strings.Contains(httpClient.Bodies[0], `"status":"succeeded"`) {
+				t.Errorf("Expected the job in the body, got %s", httpClient.Bodies[0])
+			}
+		})
+	}
+}
+
+func TestValidateCallbackURL(t *testing.T) {
+	tests := []struct {
+		url   string
+		valid bool
+	}{
+		{url: "https://example.com/haiku", valid: true},
+		{url: "https://203.0.113.7:8443/haiku", valid: true},
+		{url: "http://example.com/haiku"},
+		{url: "example.com/haiku"},
+		{url: "https://localhost/haiku"},
+		{url: "https://127.0.0.1/haiku"},
+		{url: "https://10.0.0.8/haiku"},
+		{url: "https://169.254.169.254/latest"},
+		{url: "https://[::1]/haiku"},
+		{url: "https://100.64.12.1/haiku"},
+		{url: "https://0.0.0.0/haiku"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.url, func(t *testing.T) {
+			err := ValidateCallbackURL(tc.url)
+			if tc.valid && err != nil {
+				t.Errorf("Expected %q to be accepted, got %v", tc.url, err)
+			}
+			if !tc.valid && !errors.Is(err, ErrBadCallback) {
+				t.Errorf("Expected %q to be refused, got %v", tc.url, err)
+			}
+		})
+	}
+}
+
+func TestRunSendsCallback(t *testing.T) {
+	tests := []struct {
+		name          string
+		callbackError error
+	}{
+		{name: "Delivered"},
+		{name: "Undeliverable", callbackError: ErrDeliverCallback},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			callbacks := &MockCallbacks{ErrorToReturn: tc.callbackError}
+			haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+			service := NewJobService(haikuService, NewMemoryStore(), &MockScheduler{}, &Options{Callbacks: callbacks})
+
+			job, err := service.Submit(context.Background(), haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, &SubmitOptions{CallbackURL: "https://example.com/haiku"})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			// An undeliverable callback doesn't fail the job, which can still be polled.
+			if err := service.Run(context.Background(), job.ID); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if callbacks.Calls != 1 || callbacks.LastURL != "https://example.com/haiku" {
+				t.Fatalf("Expected one callback to https://example.com/haiku, got %d to %q", callbacks.Calls, callbacks.LastURL)
+			}
+			if callbacks.LastJob.Status != StatusSucceeded || callbacks.LastJob.Result == nil {
+				t.Errorf("Expected the finished job, got %+v", callbacks.LastJob)
+			}
+
+			stored, err := service.Get(context.Background(), job.ID)
+			if err != nil || stored.Status != StatusSucceeded {
+				t.Errorf("Expected the job to succeed, got %+v: %v", stored, err)
+			}
+		})
+	}
+}
+
+func TestSubmitRefusesCallbacks(t *testing.T) {
+	tests := []struct {
+		name      string
+		callbacks Callbacks
+		url       string
+	}{
+		{name: "Callbacks disabled", url: "https://example.com/haiku"},
+		{name: "Private URL", callbacks: &MockCallbacks{}, url: "https://10.0.0.8/haiku"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler := &MockScheduler{}
+			service := NewJobService(&MockHaikuService{}, NewMemoryStore(), scheduler, &Options{Callbacks: tc.callbacks})
+
+			_, err := service.Submit(context.Background(), haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, &SubmitOptions{CallbackURL: tc.url})
+			if !errors.Is(err, ErrBadCallback) {
+				t.Errorf("Expected ErrBadCallback, got %v", err)
+			}
+			if len(scheduler.Scheduled) != 0 {
+				t.Errorf("Expected no job to be scheduled, got %v", scheduler.Scheduled)
+			}
+		})
+	}
+}
+
+func TestDialPublicOnly(t *testing.T) {
+	tests := []struct {
+		address string
+		allowed bool
+	}{
+		{address: "203.0.113.7:443", allowed: true},
+		{address: "[2001:db8::1]:443", allowed: true},
+		{address: "127.0.0.1:443"},
+		{address: "10.1.2.3:443"},
+		{address: "172.16.0.1:443"},
+		{address: "192.168.1.1:443"},
+		{address: "169.254.169.254:80"},
+		{address: "100.100.0.1:443"},
+		{address: "0.0.0.0:443"},
+		{address: "[::1]:443"},
+		{address: "[fd00::1]:443"},
+		{address: "[fe80::1]:443"},
+		{address: "[::ffff:127.0.0.1]:443"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.address, func(t *testing.T) {
+			err := dialPublicOnly("tcp", tc.address, nil)
+			if tc.allowed && err != nil {
+				t.Errorf("Expected %q to be dialled, got %v", tc.address, err)
+			}
+			if !tc.allowed && !errors.Is(err, ErrBadCallback) {
+				t.Errorf("Expected %q to be refused, got %v", tc.address, err)
+			}
+		})
+	}
+}
+
+func TestDefaultCallbackSenderRefusesInternalAddresses(t *testing.T) {
+	calls := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	sender := NewDefaultCallbackSender([]byte("secret"))
+	sender.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+
+	err := sender.Send(context.Background(), server.URL, Job{ID: "job-1"})
+	if !errors.Is(err, ErrBadCallback) {
+		t.Errorf("Expected loopback callback to be refused, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no delivery, got %d", calls)
+	}
+}
+
+func TestDefaultCallbackSenderDoesNotFollowRedirects(t *testing.T) {
+	client := NewDefaultCallbackSender([]byte("secret")).httpClient.(*http.Client)
+	if err := client.CheckRedirect(nil, nil); !errors.Is(err, http.ErrUseLastResponse) {
+		t.Errorf("Expected redirects not to be followed, got %v", err)
+	}
+}
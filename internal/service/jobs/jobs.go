@@ -0,0 +1,331 @@
+// Package jobs generates haiku in the background, for requests that take
+// longer than API Gateway waits. A job is stored as pending, run by a later
+// invocation, and polled for its result until it expires.
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metering"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+// DefaultJobTTL is how long a job and its result can be fetched.
+const DefaultJobTTL = 24 * time.Hour
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrStoreJob    = errors.New("error storing job")
+	ErrScheduleJob = errors.New("error scheduling job")
+)
+
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+)
+
+// Statuses lists every job status.
+var Statuses = []Status{StatusPending, StatusRunning, StatusSucceeded, StatusFailed}
+
+// Job is a haiku request running in the background.
+type Job struct {
+	ID          string                     `json:"id"`
+	Status      Status                     `json:"status"`
+	Result      *haiku.HaikuCommitResponse `json:"result,omitempty"`      // Set once the job succeeds
+	Error       *Error                     `json:"error,omitempty"`       // Set once the job fails
+	CallbackURL string                     `json:"callbackUrl,omitempty"` // Receives the job once it finishes
+	CreatedAt   time.Time                  `json:"createdAt"`
+	UpdatedAt   time.Time                  `json:"updatedAt"`
+}
+
+// Error describes why a job failed, in the same terms as a failed request.
+type Error struct {
+	Status int    `json:"status"` // HTTP status the request would have failed with
+	Code   string `json:"code"`
+	Title  string `json:"title"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// record is a stored job, along with the request it runs and the API key and
+// tenant it was submitted with, if any.
+type record struct {
+	Job
+	Request haiku.HaikuCommitRequest `json:"request"`
+	KeyID   string                   `json:"keyId,omitempty"`
+	Tenant  string                   `json:"tenant,omitempty"`
+}
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+// Store keeps jobs by ID until they expire, e.g. in DynamoDB. PutIfAbsent
+// stores a value only when the key holds none, so that a job delivered to two
+// invocations at once is claimed by one.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Callbacks delivers finished jobs to the URL their caller supplied.
+type Callbacks interface {
+	Send(ctx context.Context, callbackURL string, job Job) error
+}
+
+// Quotas charges API keys for the output tokens their jobs consume.
+type Quotas interface {
+	AddOutputTokens(ctx context.Context, keyID string, tokens int) error
+}
+
+// Scheduler arranges for a stored job to be run, e.g. by an asynchronous
+// invocation of the function.
+type Scheduler interface {
+	Schedule(ctx context.Context, id string) error
+}
+
+type JobService struct {
+	haikuService  HaikuService
+	store         Store
+	scheduler     Scheduler
+	ttl           time.Duration
+	describeError func(err error) Error
+	callbacks     Callbacks
+	quotas        Quotas
+	now           func() time.Time
+}
+
+type Options struct {
+	TTL           time.Duration         // How long jobs can be fetched (default: 24 hours)
+	DescribeError func(err error) Error // Describes failed jobs to callers (default: a generic internal error)
+	Callbacks     Callbacks             // Delivers finished jobs to callback URLs (default: none, callbacks refused)
+	Quotas        Quotas                // Charges keys for the tokens their jobs consume (default: none, tokens not charged)
+}
+
+type SubmitOptions struct {
+	CallbackURL string // Receives the job once it finishes (default: none, poll instead)
+}
+
+func NewJobService(haikuService HaikuService, store Store, scheduler Scheduler, opts *Options) *JobService {
+	service := &JobService{
+		haikuService:  haikuService,
+		store:         store,
+		scheduler:     scheduler,
+		ttl:           DefaultJobTTL,
+		describeError: describeError,
+		now:           time.Now,
+	}
+
+	if opts != nil {
+		if opts.TTL > 0 {
+			service.ttl = opts.TTL
+		}
+		if opts.DescribeError != nil {
+			service.describeError = opts.DescribeError
+		}
+		service.callbacks = opts.Callbacks
+		service.quotas = opts.Quotas
+	}
+
+	return service
+}
+
+// describeError reveals nothing about the failure, since its error text may
+// not be safe to show.
+func describeError(err error) Error {
+	return Error{Status: 500, Code: "internal_error", Title: "Haiku could not be generated"}
+}
+
+// Submit stores a pending job for request and schedules it to run.
+func (s *JobService) Submit(ctx context.Context, request haiku.HaikuCommitRequest, opts *SubmitOptions) (Job, error) {
+	var callbackURL string
+	if opts != nil && opts.CallbackURL != "" {
+		if s.callbacks == nil {
+			return Job{}, fmt.Errorf("%w: callbacks are not enabled", ErrBadCallback)
+		}
+		if err := ValidateCallbackURL(opts.CallbackURL); err != nil {
+			return Job{}, err
+		}
+		callbackURL = opts.CallbackURL
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, fmt.Errorf("%w: %w", ErrStoreJob, err)
+	}
+
+	now := s.now()
+	job := record{
+		Job:     Job{ID: id, Status: StatusPending, CallbackURL: callbackURL, CreatedAt: now, UpdatedAt: now},
+		Request: request,
+		KeyID:   keys.IDFromContext(ctx),
+		Tenant:  keys.TenantFromContext(ctx),
+	}
+	if err := s.put(ctx, job); err != nil {
+		return Job{}, err
+	}
+
+	if err := s.scheduler.Schedule(ctx, id); err != nil {
+		logging.Errorf("[JOBS SERVICE] error scheduling job %s: %v\n", id, err)
+		return Job{}, fmt.Errorf("%w: %w", ErrScheduleJob, err)
+	}
+
+	logging.Infof("[JOBS SERVICE] submitted job %s\n", id)
+	return job.Job, nil
+}
+
+// Get returns the job with id. Jobs submitted in another tenant are reported
+// as not found.
+func (s *JobService) Get(ctx context.Context, id string) (Job, error) {
+	job, err := s.get(ctx, id)
+	if err != nil {
+		return Job{}, err
+	}
+	if job.Tenant != keys.TenantFromContext(ctx) {
+		return Job{}, fmt.Errorf("%w: %q", ErrJobNotFound, id)
+	}
+	return job.Job, nil
+}
+
+// Run generates the haiku for a pending job, stores the result, and posts it to
+// the job's callback URL. Jobs that are no longer pending, or that another
+// invocation has claimed, are left alone, so a job delivered twice only runs
+// once. A failed haiku fails the job, not Run;
+// Run only fails when the job cannot be loaded or stored. Undeliverable
+// callbacks are logged, since the result can still be polled. The output
+// tokens the haiku consumed are charged to the key that submitted the job,
+// since they are spent after that request's quota was enforced.
+func (s *JobService) Run(ctx context.Context, id string) error {
+	job, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if job.Status != StatusPending {
+		logging.Infof("[JOBS SERVICE] job %s is already %s\n", id, job.Status)
+		return nil
+	}
+
+	// Both deliveries of a job can find it pending, so it is claimed before
+	// it runs.
+	claimed, err := s.store.PutIfAbsent(ctx, claimKey(id), []byte(StatusRunning), s.ttl)
+	if err != nil {
+		logging.Errorf("[JOBS SERVICE] error claiming job %s: %v\n", id, err)
+		return fmt.Errorf("%w: %w", ErrStoreJob, err)
+	}
+	if !claimed {
+		logging.Infof("[JOBS SERVICE] job %s is already claimed\n", id)
+		return nil
+	}
+
+	job.Status = StatusRunning
+	job.UpdatedAt = s.now()
+	if err := s.put(ctx, job); err != nil {
+		// The job is still pending, so a retried delivery may claim it.
+		if err := s.store.Delete(ctx, claimKey(id)); err != nil {
+			logging.Errorf("[JOBS SERVICE] error releasing job %s: %v\n", id, err)
+		}
+		return err
+	}
+
+	// The haiku is written after the request that submitted it, so it is
+	// attributed to that request's key and tenant here.
+	if job.KeyID != "" {
+		ctx = keys.NewContext(ctx, job.KeyID)
+	}
+	if job.Tenant != "" {
+		ctx = keys.NewTenantContext(ctx, job.Tenant)
+	}
+	ctx, meter := metering.NewContext(ctx)
+	response, err := s.haikuService.CreateHaiku(ctx, job.Request)
+	if job.KeyID != "" && s.quotas != nil {
+		if err := s.quotas.AddOutputTokens(ctx, job.KeyID, meter.OutputTokens()); err != nil {
+			logging.Errorf("[JOBS SERVICE] error charging key %s for %d output tokens of job %s: %v\n", job.KeyID, meter.OutputTokens(), id, err)
+		}
+	}
+	if err != nil {
+		logging.Errorf("[JOBS SERVICE] job %s failed: %v\n", id, err)
+		description := s.describeError(err)
+		job.Status = StatusFailed
+		job.Error = &description
+	} else {
+		job.Status = StatusSucceeded
+		job.Result = &response
+	}
+
+	job.UpdatedAt = s.now()
+	if err := s.put(ctx, job); err != nil {
+		return err
+	}
+
+	if job.CallbackURL != "" && s.callbacks != nil {
+		if err := s.callbacks.Send(ctx, job.CallbackURL, job.Job); err != nil {
+			logging.Errorf("[JOBS SERVICE] error delivering callback for job %s: %v\n", id, err)
+		}
+	}
+	return nil
+}
+
+func (s *JobService) get(ctx context.Context, id string) (record, error) {
+	if !validJobID(id) {
+		return record{}, fmt.Errorf("%w: %q", ErrJobNotFound, id)
+	}
+
+	value, ok, err := s.store.Get(ctx, id)
+	if err != nil {
+		return record{}, fmt.Errorf("%w: %w", ErrStoreJob, err)
+	}
+	if !ok {
+		return record{}, fmt.Errorf("%w: %q", ErrJobNotFound, id)
+	}
+
+	var job record
+	if err := json.Unmarshal(value, &job); err != nil {
+		return record{}, fmt.Errorf("%w: decoding job %s: %w", ErrStoreJob, id, err)
+	}
+	return job, nil
+}
+
+func (s *JobService) put(ctx context.Context, job record) error {
+	value, err := json.Marshal(job)
+	if err != nil {
+		return fmt.Errorf("%w: encoding job %s: %w", ErrStoreJob, job.ID, err)
+	}
+	if err := s.store.Put(ctx, job.ID, value, s.ttl); err != nil {
+		logging.Errorf("[JOBS SERVICE] error storing job %s: %v\n", job.ID, err)
+		return fmt.Errorf("%w: %w", ErrStoreJob, err)
+	}
+	return nil
+}
+
+// claimKey names the item marking the job id as taken by an invocation.
+func claimKey(id string) string {
+	return "claim:" + id
+}
+
+// newJobID returns a random ID. IDs are the only thing guarding a job's
+// result, so they must not be guessable.
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func validJobID(id string) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == 16
+}
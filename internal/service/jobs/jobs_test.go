@@ -0,0 +1,286 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/metering"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+	OutputTokens     int // Metered on each call, as the model client would
+	Calls            int
+	LastTenant       string
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.Calls++
+	m.LastTenant = keys.TenantFromContext(ctx)
+	metering.Add(ctx, 100, m.OutputTokens)
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+type MockScheduler struct {
+	ErrorToReturn error
+	Scheduled     []string
+}
+
+func (m *MockScheduler) Schedule(ctx context.Context, id string) error {
+	m.Scheduled = append(m.Scheduled, id)
+	return m.ErrorToReturn
+}
+
+type MockQuotas struct {
+	ErrorToReturn error
+	Tokens        map[string]int
+}
+
+func (m *MockQuotas) AddOutputTokens(ctx context.Context, keyID string, tokens int) error {
+	if m.Tokens == nil {
+		m.Tokens = map[string]int{}
+	}
+	m.Tokens[keyID] += tokens
+	return m.ErrorToReturn
+}
+
+func TestSubmit(t *testing.T) {
+	tests := []struct {
+		name          string
+		scheduleError error
+		errorIs       error
+	}{
+		{name: "Scheduled"},
+		{name: "Scheduling fails", scheduleError: errors.New("invoke failed"), errorIs: ErrScheduleJob},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler := &MockScheduler{ErrorToReturn: tc.scheduleError}
+			service := NewJobService(&MockHaikuService{}, NewMemoryStore(), scheduler, nil)
+
+			job, err := service.Submit(context.Background(), haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, nil)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if job.Status != StatusPending || !validJobID(job.ID) {
+				t.Errorf("Expected a pending job with a valid ID, got %+v", job)
+			}
+			if len(scheduler.Scheduled) != 1 || scheduler.Scheduled[0] != job.ID {
+				t.Errorf("Expected job %s to be scheduled, got %v", job.ID, scheduler.Scheduled)
+			}
+
+			stored, err := service.Get(context.Background(), job.ID)
+			if err != nil || stored.Status != StatusPending {
+				t.Errorf("Expected the pending job to be stored, got %+v: %v", stored, err)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name           string
+		haikuError     error
+		expectedStatus Status
+		expectedError  *Error
+	}{
+		{
+			name:           "Succeeded",
+			expectedStatus: StatusSucceeded,
+		},
+		{
+			name:           "Failed",
+			haikuError:     haiku.ErrContentBlocked,
+			expectedStatus: StatusFailed,
+			expectedError:  &Error{Status: 422, Code: "content_blocked", Title: "Content Blocked"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku},
+				ErrorToReturn:    tc.haikuError,
+			}
+			service := NewJobService(haikuService, NewMemoryStore(), &MockScheduler{}, &Options{
+				DescribeError: func(err error) Error {
+					return Error{Status: 422, Code: "content_blocked", Title: "Content Blocked"}
+				},
+			})
+
+			job, err := service.Submit(context.Background(), haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, nil)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if err := service.Run(context.Background(), job.ID); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			// A second delivery of the same job is ignored.
+			if err := service.Run(context.Background(), job.ID); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if haikuService.Calls != 1 {
+				t.Errorf("Expected the job to run once, got %d", haikuService.Calls)
+			}
+
+			job, err = service.Get(context.Background(), job.ID)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if job.Status != tc.expectedStatus {
+				t.Errorf("Expected status %s, got %s", tc.expectedStatus, job.Status)
+			}
+			if tc.expectedError != nil {
+				if job.Error == nil || *job.Error != *tc.expectedError || job.Result != nil {
+					t.Errorf("Expected error %+v and no result, got %+v and %+v", tc.expectedError, job.Error, job.Result)
+				}
+			} else if job.Result == nil || job.Result.Haiku != testHaiku || job.Error != nil {
+				t.Errorf("Expected haiku %q and no error, got %+v and %+v", testHaiku, job.Result, job.Error)
+			}
+		})
+	}
+}
+
+// StaleStore returns the first value read for each key, as an invocation
+// racing another would have.
+type StaleStore struct {
+	*MemoryStore
+	read map[string][]byte
+}
+
+func (s *StaleStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if value, ok := s.read[key]; ok {
+		return value, true, nil
+	}
+	value, ok, err := s.MemoryStore.Get(ctx, key)
+	if ok {
+		s.read[key] = value
+	}
+	return value, ok, err
+}
+
+func TestRunClaimsJob(t *testing.T) {
+	haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+	store := &StaleStore{MemoryStore: NewMemoryStore(), read: map[string][]byte{}}
+	service := NewJobService(haikuService, store, &MockScheduler{}, nil)
+
+	job, err := service.Submit(context.Background(), haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Both deliveries find the job pending, but only the first claims it.
+	for range 2 {
+		if err := service.Run(context.Background(), job.ID); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	if haikuService.Calls != 1 {
+		t.Errorf("Expected the job to run once, got %d", haikuService.Calls)
+	}
+}
+
+func TestRunChargesOutputTokens(t *testing.T) {
+	tests := []struct {
+		name           string
+		keyID          string
+		haikuError     error
+		quotaError     error
+		expectedTokens map[string]int
+	}{
+		{name: "Charged to the submitting key", keyID: "key-1", expectedTokens: map[string]int{"key-1": 42}},
+		{name: "Charged when the haiku fails", keyID: "key-1", haikuError: haiku.ErrContentBlocked, expectedTokens: map[string]int{"key-1": 42}},
+		{name: "Charging fails", keyID: "key-1", quotaError: errors.New("throttled"), expectedTokens: map[string]int{"key-1": 42}},
+		{name: "Submitted without a key"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}, ErrorToReturn: tc.haikuError, OutputTokens: 42}
+			quotas := &MockQuotas{ErrorToReturn: tc.quotaError}
+			service := NewJobService(haikuService, NewMemoryStore(), &MockScheduler{}, &Options{Quotas: quotas})
+
+			ctx := context.Background()
+			if tc.keyID != "" {
+				ctx = keys.NewContext(ctx, tc.keyID)
+			}
+			job, err := service.Submit(ctx, haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, nil)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if err := service.Run(context.Background(), job.ID); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if len(quotas.Tokens) != len(tc.expectedTokens) || quotas.Tokens[tc.keyID] != tc.expectedTokens[tc.keyID] {
+				t.Errorf("Expected tokens %v, got %v", tc.expectedTokens, quotas.Tokens)
+			}
+		})
+	}
+}
+
+func TestGet(t *testing.T) {
+	service := NewJobService(&MockHaikuService{}, NewMemoryStore(), &MockScheduler{}, nil)
+
+	for _, id := range []string{"0123456789abcdef0123456789abcdef", "not-a-job", ""} {
+		if _, err := service.Get(context.Background(), id); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("Expected job %q not to be found, got %v", id, err)
+		}
+	}
+}
+
+func TestTenants(t *testing.T) {
+	haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+	service := NewJobService(haikuService, NewMemoryStore(), &MockScheduler{}, nil)
+	acme := keys.NewTenantContext(context.Background(), "acme")
+
+	job, err := service.Submit(acme, haiku.HaikuCommitRequest{CommitMessage: "fix: resolved login issue"}, nil)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	// Jobs run in a later invocation, without the submitting request's context.
+	if err := service.Run(context.Background(), job.ID); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if haikuService.LastTenant != "acme" {
+		t.Errorf("Expected the haiku written for acme, got %q", haikuService.LastTenant)
+	}
+
+	if _, err := service.Get(acme, job.ID); err != nil {
+		t.Errorf("Expected the job found in its tenant, got %v", err)
+	}
+	for _, ctx := range []context.Context{context.Background(), keys.NewTenantContext(context.Background(), "globex")} {
+		if _, err := service.Get(ctx, job.ID); !errors.Is(err, ErrJobNotFound) {
+			t.Errorf("Expected the job hidden from tenant %q, got %v", keys.TenantFromContext(ctx), err)
+		}
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.SetClock(func() time.Time { return now })
+
+	_ = store.Put(context.Background(), "job", []byte("pending"), time.Hour)
+	if _, ok, _ := store.Get(context.Background(), "job"); !ok {
+		t.Fatal("Expected the job before it expires")
+	}
+
+	now = now.Add(time.Hour)
+	if _, ok, _ := store.Get(context.Background(), "job"); ok {
+		t.Error("Expected the job to expire")
+	}
+}
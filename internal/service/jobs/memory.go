@@ -0,0 +1,11 @@
+package jobs
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps jobs in memory for a single server, which polls and runs
+// only the jobs it was given.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
@@ -0,0 +1,75 @@
+package keys
+
+import (
+	"context"
+	"regexp"
+)
+
+type contextKey struct{}
+
+type tenantContextKey struct{}
+
+type operatorContextKey struct{}
+
+// NewContext returns a context carrying the ID of the API key a request was
+// made with, so that what the request produces can be attributed to the key.
+func NewContext(ctx context.Context, keyID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, keyID)
+}
+
+// IDFromContext returns the ID of the context's API key, or "" when the
+// request was made without one.
+func IDFromContext(ctx context.Context) string {
+	keyID, _ := ctx.Value(contextKey{}).(string)
+	return keyID
+}
+
+// NewTenantContext returns a context carrying the tenant a request was made
+// for, so that what it reads and writes stays within the tenant's data.
+func NewTenantContext(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, tenant)
+}
+
+// TenantFromContext returns the context's tenant, or "" for the default
+// tenant, which requests made without a tenant's key belong to.
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantContextKey{}).(string)
+	return tenant
+}
+
+// NewOperatorContext returns a context acting for the deployment's operator,
+// who manages every tenant's keys and sets their quotas.
+func NewOperatorContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, operatorContextKey{}, true)
+}
+
+// IsOperator reports whether the context acts for the operator: a request
+// made with the admin token or a key with the operator scope.
+func IsOperator(ctx context.Context) bool {
+	operator, _ := ctx.Value(operatorContextKey{}).(bool)
+	return operator
+}
+
+// EveryTenant reports whether the context reaches every tenant's data rather
+// than its own tenant's: only the operator's does, outside any tenant.
+func EveryTenant(ctx context.Context) bool {
+	return IsOperator(ctx) && TenantFromContext(ctx) == ""
+}
+
+// Namespace prefixes a store key with the context's tenant, so that tenants'
+// items never collide. The default tenant's keys are left as they are, so a
+// deployment's data from before tenants is still its default tenant's.
+func Namespace(ctx context.Context, key string) string {
+	if tenant := TenantFromContext(ctx); tenant != "" {
+		return "tenant:" + tenant + ":" + key
+	}
+	return key
+}
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9](?:[a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// ValidTenant reports whether tenant can name a tenant: lowercase letters,
+// digits and inner hyphens, at most 63 characters.
+func ValidTenant(tenant string) bool {
+	return tenantPattern.MatchString(tenant)
+}
@@ -0,0 +1,339 @@
+// Package keys issues and checks the API keys callers use. A key is shown
+// once, when it is created; only a hash of its secret is stored. Keys may
+// belong to a tenant, an organization whose data is kept apart from every
+// other's.
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+var (
+	ErrKeyNotFound   = errors.New("api key not found")
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrBadKeyRequest = errors.New("invalid api key request")
+	ErrStoreKey      = errors.New("error storing api key")
+	ErrNotOperator   = errors.New("only the operator can do this")
+)
+
+// Scope grants a key access to a group of routes.
+type Scope string
+
+const (
+	ScopeHaiku    Scope = "haiku"    // The haiku endpoints
+	ScopeAdmin    Scope = "admin"    // Managing keys
+	ScopeSampling Scope = "sampling" // Setting temperature and maxTokens on haiku requests
+	ScopeOperator Scope = "operator" // Managing every tenant's keys and raising quotas, alongside admin
+)
+
+// Scopes lists every scope.
+var Scopes = []Scope{ScopeHaiku, ScopeAdmin, ScopeSampling, ScopeOperator}
+
+func (s Scope) IsValid() bool {
+	return slices.Contains(Scopes, s)
+}
+
+// Key describes an API key, without its secret.
+type Key struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []Scope   `json:"scopes"`
+	Tenant    string    `json:"tenant,omitempty"` // The organization the key belongs to, empty for the default tenant
+	Quota     Quota     `json:"quota"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k Key) HasScope(scope Scope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// Quota limits a key's use each calendar month. Zero means unlimited.
+type Quota struct {
+	MonthlyRequests     int64 `json:"monthlyRequests" binding:"min=0"`
+	MonthlyOutputTokens int64 `json:"monthlyOutputTokens" binding:"min=0"`
+}
+
+// Within reports whether none of the quota's limits is above ceiling's.
+func (q Quota) Within(ceiling Quota) bool {
+	return withinLimit(q.MonthlyRequests, ceiling.MonthlyRequests) &&
+		withinLimit(q.MonthlyOutputTokens, ceiling.MonthlyOutputTokens)
+}
+
+// inherit returns the quota with its unlimited limits taken from ceiling.
+func (q Quota) inherit(ceiling Quota) Quota {
+	if q.MonthlyRequests == 0 {
+		q.MonthlyRequests = ceiling.MonthlyRequests
+	}
+	if q.MonthlyOutputTokens == 0 {
+		q.MonthlyOutputTokens = ceiling.MonthlyOutputTokens
+	}
+	return q
+}
+
+func withinLimit(limit int64, ceiling int64) bool {
+	return ceiling == 0 || (limit != 0 && limit <= ceiling)
+}
+
+// CreateKeyRequest describes a key to create.
+type CreateKeyRequest struct {
+	Name   string  `json:"name" binding:"required,max=100"`
+	Scopes []Scope `json:"scopes,omitempty"` // Granted scopes (default: haiku)
+	Tenant string  `json:"tenant,omitempty"` // The organization the key belongs to (default: the creator's tenant)
+	Quota  Quota   `json:"quota"`
+}
+
+// CreatedKey is a new key along with its token, which is never shown again.
+type CreatedKey struct {
+	Key
+	Token string `json:"token"`
+}
+
+// record is a stored key, along with the hash of its secret.
+type record struct {
+	Key
+	SecretHash string `json:"secretHash"`
+}
+
+// Store keeps keys by ID, e.g. in DynamoDB.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type KeyService struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewKeyService(store Store) *KeyService {
+	return &KeyService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Create stores a new key and returns it with its token, "<id>.<secret>".
+// Only the operator creates keys for other tenants, operator keys, and keys
+// with quotas above the creating key's; other admins' keys inherit the limits
+// of the key creating them.
+func (s *KeyService) Create(ctx context.Context, request CreateKeyRequest) (CreatedKey, error) {
+	tenant := TenantFromContext(ctx)
+	operator := IsOperator(ctx)
+	switch {
+	case request.Tenant == "":
+		request.Tenant = tenant
+	case request.Tenant != tenant && !operator:
+		return CreatedKey{}, fmt.Errorf("%w: cannot create keys for another tenant", ErrBadKeyRequest)
+	case !ValidTenant(request.Tenant):
+		return CreatedKey{}, fmt.Errorf("%w: invalid tenant %q", ErrBadKeyRequest, request.Tenant)
+	}
+	if request.Quota.MonthlyRequests < 0 || request.Quota.MonthlyOutputTokens < 0 {
+		return CreatedKey{}, fmt.Errorf("%w: quotas cannot be negative", ErrBadKeyRequest)
+	}
+	if !operator {
+		ceiling, err := s.callerQuota(ctx)
+		if err != nil {
+			return CreatedKey{}, err
+		}
+		request.Quota = request.Quota.inherit(ceiling)
+		if !request.Quota.Within(ceiling) {
+			return CreatedKey{}, fmt.Errorf("%w: quotas above your own key's", ErrNotOperator)
+		}
+	}
+
+	scopes := request.Scopes
+	if len(scopes) == 0 {
+		scopes = []Scope{ScopeHaiku}
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return CreatedKey{}, fmt.Errorf("%w: unknown scope %q", ErrBadKeyRequest, scope)
+		}
+	}
+	slices.Sort(scopes)
+	scopes = slices.Compact(scopes)
+	if slices.Contains(scopes, ScopeOperator) {
+		if !operator {
+			return CreatedKey{}, fmt.Errorf("%w: operator keys", ErrNotOperator)
+		}
+		if request.Tenant != "" {
+			return CreatedKey{}, fmt.Errorf("%w: a tenant's keys cannot have the operator scope", ErrBadKeyRequest)
+		}
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return CreatedKey{}, fmt.Errorf("%w: %w", ErrStoreKey, err)
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return CreatedKey{}, fmt.Errorf("%w: %w", ErrStoreKey, err)
+	}
+
+	now := s.now()
+	key := record{
+		Key:        Key{ID: id, Name: request.Name, Scopes: scopes, Tenant: request.Tenant, Quota: request.Quota, CreatedAt: now, UpdatedAt: now},
+		SecretHash: hashSecret(secret),
+	}
+	if err := s.put(ctx, key); err != nil {
+		return CreatedKey{}, err
+	}
+
+	logging.Infof("[KEYS SERVICE] created key %s (%s)\n", id, request.Name)
+	return CreatedKey{Key: key.Key, Token: id + "." + secret}, nil
+}
+
+// Delete revokes the key with id. A tenant's admin can only revoke its own
+// tenant's keys.
+func (s *KeyService) Delete(ctx context.Context, id string) error {
+	if _, err := s.manage(ctx, id); err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, id); err != nil {
+		logging.Errorf("[KEYS SERVICE] error deleting key %s: %v\n", id, err)
+		return fmt.Errorf("%w: %w", ErrStoreKey, err)
+	}
+
+	logging.Infof("[KEYS SERVICE] deleted key %s\n", id)
+	return nil
+}
+
+// SetQuota replaces the quota of the key with id. Only the operator raises
+// quotas; other admins can only lower those of their own tenant's keys.
+func (s *KeyService) SetQuota(ctx context.Context, id string, quota Quota) (Key, error) {
+	if quota.MonthlyRequests < 0 || quota.MonthlyOutputTokens < 0 {
+		return Key{}, fmt.Errorf("%w: quotas cannot be negative", ErrBadKeyRequest)
+	}
+
+	key, err := s.manage(ctx, id)
+	if err != nil {
+		return Key{}, err
+	}
+	if !IsOperator(ctx) && !quota.Within(key.Quota) {
+		return Key{}, fmt.Errorf("%w: raising quotas", ErrNotOperator)
+	}
+
+	key.Quota = quota
+	key.UpdatedAt = s.now()
+	if err := s.put(ctx, key); err != nil {
+		return Key{}, err
+	}
+	return key.Key, nil
+}
+
+// Authenticate returns the key a token belongs to.
+func (s *KeyService) Authenticate(ctx context.Context, token string) (Key, error) {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		return Key{}, ErrInvalidKey
+	}
+
+	key, err := s.get(ctx, id)
+	if errors.Is(err, ErrKeyNotFound) {
+		return Key{}, ErrInvalidKey
+	}
+	if err != nil {
+		return Key{}, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(key.SecretHash)) != 1 {
+		return Key{}, ErrInvalidKey
+	}
+	return key.Key, nil
+}
+
+// manage returns the key with id for an admin acting in the context's tenant.
+// Other tenants' keys are reported as not found, unless the operator manages
+// them.
+func (s *KeyService) manage(ctx context.Context, id string) (record, error) {
+	key, err := s.get(ctx, id)
+	if err != nil {
+		return record{}, err
+	}
+	if !IsOperator(ctx) && key.Tenant != TenantFromContext(ctx) {
+		return record{}, fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+	return key, nil
+}
+
+// callerQuota returns the quota of the key an admin who isn't the operator
+// acts with, which caps the quotas they set.
+func (s *KeyService) callerQuota(ctx context.Context) (Quota, error) {
+	id := IDFromContext(ctx)
+	if id == "" {
+		return Quota{}, fmt.Errorf("%w: setting quotas without a key", ErrNotOperator)
+	}
+	key, err := s.get(ctx, id)
+	if err != nil {
+		return Quota{}, err
+	}
+	return key.Quota, nil
+}
+
+func (s *KeyService) get(ctx context.Context, id string) (record, error) {
+	if !validKeyID(id) {
+		return record{}, fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+
+	value, ok, err := s.store.Get(ctx, id)
+	if err != nil {
+		return record{}, fmt.Errorf("%w: %w", ErrStoreKey, err)
+	}
+	if !ok {
+		return record{}, fmt.Errorf("%w: %q", ErrKeyNotFound, id)
+	}
+
+	var key record
+	if err := json.Unmarshal(value, &key); err != nil {
+		return record{}, fmt.Errorf("%w: decoding key %s: %w", ErrStoreKey, id, err)
+	}
+	return key, nil
+}
+
+// put stores key until it is deleted.
+func (s *KeyService) put(ctx context.Context, key record) error {
+	value, err := json.Marshal(key)
+	if err != nil {
+		return fmt.Errorf("%w: encoding key %s: %w", ErrStoreKey, key.ID, err)
+	}
+	if err := s.store.Put(ctx, key.ID, value, 0); err != nil {
+		logging.Errorf("[KEYS SERVICE] error storing key %s: %v\n", key.ID, err)
+		return fmt.Errorf("%w: %w", ErrStoreKey, err)
+	}
+	return nil
+}
+
+// hashSecret hashes a key's secret for storage. Secrets are random, so a
+// plain hash is enough.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func validKeyID(id string) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == 16
+}
@@ -0,0 +1,297 @@
+package keys
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockStore struct {
+	ErrorToReturn error
+}
+
+func (m *MockStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, m.ErrorToReturn
+}
+
+func (m *MockStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.ErrorToReturn
+}
+
+func (m *MockStore) Delete(ctx context.Context, key string) error {
+	return m.ErrorToReturn
+}
+
+func TestCreateAndAuthenticate(t *testing.T) {
+	operator := NewOperatorContext(context.Background())
+	store := NewMemoryStore()
+	service := NewKeyService(store)
+
+	created, err := service.Create(operator, CreateKeyRequest{
+		Name:   "ci",
+		Scopes: []Scope{ScopeHaiku, ScopeAdmin, ScopeHaiku},
+		Quota:  Quota{MonthlyRequests: 1000},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !strings.HasPrefix(created.Token, created.ID+".") {
+		t.Errorf("Expected a token for key %s, got %q", created.ID, created.Token)
+	}
+	if !reflect.DeepEqual(created.Scopes, []Scope{ScopeAdmin, ScopeHaiku}) {
+		t.Errorf("Expected admin and haiku scopes, got %v", created.Scopes)
+	}
+
+	// Only the secret's hash is stored.
+	_, secret, _ := strings.Cut(created.Token, ".")
+	if stored, _, _ := store.Get(context.Background(), created.ID); strings.Contains(string(stored), secret) {
+		t.Errorf("Expected the secret not to be stored, got %s", stored)
+	}
+
+	key, err := service.Authenticate(context.Background(), created.Token)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if key.ID != created.ID || !key.HasScope(ScopeAdmin) || key.Quota.MonthlyRequests != 1000 {
+		t.Errorf("Expected key %+v, got %+v", created.Key, key)
+	}
+
+	for _, token := range []string{"", created.ID, created.ID + ".guess", "0123456789abcdef0123456789abcdef." + secret} {
+		if _, err := service.Authenticate(context.Background(), token); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("Expected token %q to be refused, got %v", token, err)
+		}
+	}
+}
+
+func TestCreateDefaultsToHaikuScope(t *testing.T) {
+	operator := NewOperatorContext(context.Background())
+	service := NewKeyService(NewMemoryStore())
+
+	created, err := service.Create(operator, CreateKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !reflect.DeepEqual(created.Scopes, []Scope{ScopeHaiku}) {
+		t.Errorf("Expected the haiku scope, got %v", created.Scopes)
+	}
+
+	if _, err := service.Create(operator, CreateKeyRequest{Name: "ci", Scopes: []Scope{"root"}}); !errors.Is(err, ErrBadKeyRequest) {
+		t.Errorf("Expected ErrBadKeyRequest, got %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	operator := NewOperatorContext(context.Background())
+	service := NewKeyService(NewMemoryStore())
+
+	created, err := service.Create(operator, CreateKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if err := service.Delete(operator, created.ID); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Authenticate(context.Background(), created.Token); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("Expected a deleted key to be refused, got %v", err)
+	}
+	if err := service.Delete(operator, created.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected ErrKeyNotFound, got %v", err)
+	}
+}
+
+func TestSetQuota(t *testing.T) {
+	operator := NewOperatorContext(context.Background())
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	service := NewKeyService(NewMemoryStore())
+	service.now = func() time.Time { return now }
+
+	created, err := service.Create(operator, CreateKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	key, err := service.SetQuota(operator, created.ID, Quota{MonthlyRequests: 500, MonthlyOutputTokens: 20000})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if key.Quota != (Quota{MonthlyRequests: 500, MonthlyOutputTokens: 20000}) || !key.UpdatedAt.Equal(now) {
+		t.Errorf("Expected the new quota, got %+v", key)
+	}
+
+	tests := []struct {
+		name    string
+		id      string
+		quota   Quota
+		errorIs error
+	}{
+		{name: "Negative quota", id: created.ID, quota: Quota{MonthlyRequests: -1}, errorIs: ErrBadKeyRequest},
+		{name: "Unknown key", id: "0123456789abcdef0123456789abcdef", errorIs: ErrKeyNotFound},
+		{name: "Malformed ID", id: "../keys", errorIs: ErrKeyNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := service.SetQuota(operator, tc.id, tc.quota); !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	operator := NewOperatorContext(context.Background())
+	service := NewKeyService(&MockStore{ErrorToReturn: errors.New("table not found")})
+
+	if _, err := service.Create(operator, CreateKeyRequest{Name: "ci"}); !errors.Is(err, ErrStoreKey) {
+		t.Errorf("Expected ErrStoreKey, got %v", err)
+	}
+	// A store failure is not the caller's fault, so it isn't an invalid key.
+	if _, err := service.Authenticate(context.Background(), "0123456789abcdef0123456789abcdef.secret"); !errors.Is(err, ErrStoreKey) {
+		t.Errorf("Expected ErrStoreKey, got %v", err)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	service := NewKeyService(NewMemoryStore())
+	operator := NewOperatorContext(context.Background())
+
+	globex, err := service.Create(operator, CreateKeyRequest{Name: "globex", Tenant: "globex"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if globex.Tenant != "globex" {
+		t.Errorf("Expected a globex key, got %+v", globex.Key)
+	}
+	admin, err := service.Create(operator, CreateKeyRequest{Name: "admin", Scopes: []Scope{ScopeAdmin}, Tenant: "acme"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	acme := NewTenantContext(NewContext(context.Background(), admin.ID), "acme")
+
+	// A tenant's admin creates keys for its own tenant only.
+	created, err := service.Create(acme, CreateKeyRequest{Name: "ci"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if created.Tenant != "acme" {
+		t.Errorf("Expected an acme key, got %+v", created.Key)
+	}
+	if _, err := service.Create(acme, CreateKeyRequest{Name: "ci", Tenant: "globex"}); !errors.Is(err, ErrBadKeyRequest) {
+		t.Errorf("Expected ErrBadKeyRequest, got %v", err)
+	}
+	for _, tenant := range []string{"Acme", "acme:ci", "-acme", strings.Repeat("a", 64)} {
+		if _, err := service.Create(operator, CreateKeyRequest{Name: "ci", Tenant: tenant}); !errors.Is(err, ErrBadKeyRequest) {
+			t.Errorf("Expected tenant %q to be refused, got %v", tenant, err)
+		}
+	}
+
+	if _, err := service.SetQuota(acme, globex.ID, Quota{MonthlyRequests: 1}); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected another tenant's key not to be found, got %v", err)
+	}
+	if err := service.Delete(acme, globex.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected another tenant's key not to be found, got %v", err)
+	}
+	if err := service.Delete(acme, created.ID); err != nil {
+		t.Errorf("Expected the tenant's own key to be revoked, got %v", err)
+	}
+	if err := service.Delete(operator, globex.ID); err != nil {
+		t.Errorf("Expected the operator to revoke any tenant's key, got %v", err)
+	}
+}
+
+func TestOperatorScope(t *testing.T) {
+	service := NewKeyService(NewMemoryStore())
+	operator := NewOperatorContext(context.Background())
+
+	globex, err := service.Create(operator, CreateKeyRequest{Name: "globex", Tenant: "globex"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	admin, err := service.Create(operator, CreateKeyRequest{Name: "admin", Scopes: []Scope{ScopeAdmin}})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// A default tenant's admin key is a tenant's admin like any other.
+	ctx := NewContext(context.Background(), admin.ID)
+	if _, err := service.SetQuota(ctx, globex.ID, Quota{MonthlyRequests: 1}); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected another tenant's key not to be found, got %v", err)
+	}
+	if err := service.Delete(ctx, globex.ID); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("Expected another tenant's key not to be found, got %v", err)
+	}
+	if _, err := service.Create(ctx, CreateKeyRequest{Name: "ci", Tenant: "globex"}); !errors.Is(err, ErrBadKeyRequest) {
+		t.Errorf("Expected ErrBadKeyRequest, got %v", err)
+	}
+	if _, err := service.Create(ctx, CreateKeyRequest{Name: "ops", Scopes: []Scope{ScopeAdmin, ScopeOperator}}); !errors.Is(err, ErrNotOperator) {
+		t.Errorf("Expected ErrNotOperator, got %v", err)
+	}
+
+	// Operator keys belong to the default tenant.
+	if _, err := service.Create(operator, CreateKeyRequest{Name: "ops", Scopes: []Scope{ScopeOperator}, Tenant: "globex"}); !errors.Is(err, ErrBadKeyRequest) {
+		t.Errorf("Expected ErrBadKeyRequest, got %v", err)
+	}
+	if _, err := service.Create(operator, CreateKeyRequest{Name: "ops", Scopes: []Scope{ScopeAdmin, ScopeOperator}}); err != nil {
+		t.Errorf("Expected the operator to create an operator key, got %v", err)
+	}
+	if _, err := service.SetQuota(operator, globex.ID, Quota{MonthlyRequests: 1}); err != nil {
+		t.Errorf("Expected the operator to set any tenant's quota, got %v", err)
+	}
+}
+
+func TestQuotaCeiling(t *testing.T) {
+	service := NewKeyService(NewMemoryStore())
+	operator := NewOperatorContext(context.Background())
+
+	admin, err := service.Create(operator, CreateKeyRequest{Name: "admin", Scopes: []Scope{ScopeAdmin}, Tenant: "acme", Quota: Quota{MonthlyRequests: 1000}})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	acme := NewTenantContext(NewContext(context.Background(), admin.ID), "acme")
+
+	// A tenant's keys inherit the limits of the admin key creating them.
+	inherited, err := service.Create(acme, CreateKeyRequest{Name: "ci", Quota: Quota{MonthlyOutputTokens: 5000}})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if inherited.Quota != (Quota{MonthlyRequests: 1000, MonthlyOutputTokens: 5000}) {
+		t.Errorf("Expected the admin key's request limit, got %+v", inherited.Quota)
+	}
+	if _, err := service.Create(acme, CreateKeyRequest{Name: "ci", Quota: Quota{MonthlyRequests: 2000}}); !errors.Is(err, ErrNotOperator) {
+		t.Errorf("Expected ErrNotOperator, got %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		quota   Quota
+		errorIs error
+	}{
+		{name: "Lowered", ctx: acme, quota: Quota{MonthlyRequests: 500, MonthlyOutputTokens: 5000}},
+		{name: "Raised", ctx: acme, quota: Quota{MonthlyRequests: 600, MonthlyOutputTokens: 5000}, errorIs: ErrNotOperator},
+		{name: "Unlimited", ctx: acme, quota: Quota{MonthlyRequests: 500}, errorIs: ErrNotOperator},
+		{name: "Raised by the operator", ctx: operator, quota: Quota{MonthlyRequests: 5000}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := service.SetQuota(tc.ctx, inherited.ID, tc.quota); !errors.Is(err, tc.errorIs) {
+				t.Errorf("Expected error %v, got %v", tc.errorIs, err)
+			}
+		})
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	if got := Namespace(context.Background(), "voted:2025-10-09"); got != "voted:2025-10-09" {
+		t.Errorf("Expected the default tenant's key unchanged, got %q", got)
+	}
+	if got := Namespace(NewTenantContext(context.Background(), "acme"), "voted:2025-10-09"); got != "tenant:acme:voted:2025-10-09" {
+		t.Errorf("Expected the key in the acme namespace, got %q", got)
+	}
+}
@@ -0,0 +1,11 @@
+package keys
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps keys in memory for trying the admin API out locally. A key
+// only authenticates with the process that created it.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
@@ -0,0 +1,11 @@
+package notify
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore queues haiku in memory for a single server, whose flushes post
+// only the haiku it queued itself.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
@@ -0,0 +1,326 @@
+// Package notify pushes newly written haiku to Slack incoming webhooks
+// following a tenant or one of its repositories. Haiku are queued as they are
+// written and posted in batches by a scheduled flush, one message per webhook
+// at a time, so that a busy repository doesn't flood its channel.
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const (
+	// DefaultBatchSize is the most haiku one message lists, unless configured
+	// otherwise; the rest are counted.
+	DefaultBatchSize = 10
+	// DefaultInterval is the least time between two messages to a webhook,
+	// unless configured otherwise.
+	DefaultInterval = time.Minute
+
+	// webhookHost is the only host Slack issues incoming webhooks on.
+	// Refusing other hosts keeps the notifier from posting haiku elsewhere.
+	webhookHost = "hooks.slack.com"
+
+	pendingPrefix = "notify:"
+	flushPrefix   = "notify-flush:"
+	// pendingRetention bounds how long a haiku waits for a webhook that keeps
+	// failing.
+	pendingRetention = 24 * time.Hour
+	// flushRetention keeps a record of each message posted for longer than its
+	// window could be retried.
+	flushRetention = time.Hour
+)
+
+var (
+	ErrBadWebhooks = errors.New("bad slack notification webhooks")
+	ErrFlush       = errors.New("error posting slack notifications")
+)
+
+// Webhook is a Slack incoming webhook following a tenant's haiku, or one of
+// its repositories'.
+type Webhook struct {
+	URL        string `json:"url"`
+	Tenant     string `json:"tenant,omitempty"`     // The tenant whose haiku are posted (default: the default tenant)
+	Repository string `json:"repository,omitempty"` // Only this repository's haiku (default: every haiku of the tenant)
+}
+
+// ParseWebhooks reads webhooks from JSON, e.g.
+// [{"url": "https://hooks.slack.com/services/...", "repository": "octo/leaves"}].
+func ParseWebhooks(raw string) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := json.Unmarshal([]byte(raw), &webhooks); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBadWebhooks, err)
+	}
+
+	for _, webhook := range webhooks {
+		parsed, err := url.Parse(webhook.URL)
+		if err != nil || parsed.Scheme != "https" || parsed.Host != webhookHost {
+			return nil, fmt.Errorf("%w: %q is not a slack incoming webhook url", ErrBadWebhooks, webhook.URL)
+		}
+	}
+	return webhooks, nil
+}
+
+// matches reports whether the webhook follows the haiku.
+func (w Webhook) matches(notification haiku.Notification) bool {
+	if w.Tenant != notification.Tenant {
+		return false
+	}
+	return w.Repository == "" || strings.EqualFold(w.Repository, notification.Repository)
+}
+
+// id identifies the webhook in stored keys without revealing its URL, which
+// is its only credential.
+func (w Webhook) id() string {
+	sum := sha256.Sum256([]byte(w.URL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Run is the outcome of a flush.
+type Run struct {
+	Messages int `json:"messages"` // Messages posted
+	Haiku    int `json:"haiku"`    // Haiku they carried, listed or counted
+	Failed   int `json:"failed"`   // Webhooks whose message couldn't be posted, kept for the next flush
+}
+
+// Store queues haiku for their webhooks, e.g. in DynamoDB. PutIfAbsent
+// records each message as it is posted, so that a retried or concurrent
+// flush doesn't post it twice.
+type Store interface {
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+	ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+}
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Message is an incoming webhook message.
+type Message struct {
+	Text string `json:"text"`
+}
+
+type NotifyService struct {
+	webhooks   []Webhook
+	store      Store
+	httpClient HTTPClient
+	batchSize  int
+	interval   time.Duration
+	now        func() time.Time
+}
+
+type Options struct {
+	BatchSize int           // Most haiku one message lists (default: 10)
+	Interval  time.Duration // Least time between two messages to a webhook (default: 1 minute)
+}
+
+func NewNotifyService(webhooks []Webhook, store Store, httpClient HTTPClient, opts *Options) *NotifyService {
+	service := &NotifyService{
+		webhooks:   webhooks,
+		store:      store,
+		httpClient: httpClient,
+		batchSize:  DefaultBatchSize,
+		interval:   DefaultInterval,
+		now:        time.Now,
+	}
+
+	if opts != nil {
+		if opts.BatchSize > 0 {
+			service.batchSize = opts.BatchSize
+		}
+		if opts.Interval > 0 {
+			service.interval = opts.Interval
+		}
+	}
+
+	return service
+}
+
+func NewDefaultNotifyService(webhooks []Webhook, store Store, opts *Options) *NotifyService {
+	return NewNotifyService(webhooks, store, &http.Client{Timeout: 10 * time.Second}, opts)
+}
+
+// Notify queues a haiku for every webhook following it, to be posted by the
+// next flush. Queueing is fire and forget: a failure is logged, and never
+// reaches the caller.
+func (s *NotifyService) Notify(ctx context.Context, notification haiku.Notification) {
+	value, err := json.Marshal(notification)
+	if err != nil {
+		logging.Errorf("[NOTIFY SERVICE] error encoding notification: %v\n", err)
+		return
+	}
+
+	suffix, err := newSuffix()
+	if err != nil {
+		logging.Errorf("[NOTIFY SERVICE] error queueing notification: %v\n", err)
+		return
+	}
+	// Keys sort in the order haiku were written.
+	stamp := fmt.Sprintf("%020d-%s", notification.CreatedAt.UnixNano(), suffix)
+
+	for _, webhook := range s.webhooks {
+		if !webhook.matches(notification) {
+			continue
+		}
+		if err := s.store.Put(ctx, pendingPrefix+webhook.id()+"#"+stamp, value, pendingRetention); err != nil {
+			logging.Errorf("[NOTIFY SERVICE] error queueing notification for webhook %s: %v\n", webhook.id(), err)
+		}
+	}
+}
+
+// Flush posts each webhook's queued haiku as one message, newest first. A
+// webhook is posted to at most once an interval, however often the flush
+// runs; haiku beyond the batch size are counted rather than listed. A webhook
+// that can't be posted to keeps its haiku for the next flush.
+func (s *NotifyService) Flush(ctx context.Context) (Run, error) {
+	var run Run
+	var errs []error
+	window := s.now().UTC().Truncate(s.interval)
+
+	for _, webhook := range s.webhooks {
+		prefix := pendingPrefix + webhook.id() + "#"
+		var keys []string
+		var pending []haiku.Notification
+		err := s.store.ScanPrefix(ctx, prefix, func(key string, value []byte) error {
+			var notification haiku.Notification
+			if err := json.Unmarshal(value, &notification); err != nil {
+				logging.Warnf("[NOTIFY SERVICE] skipping unreadable notification %s: %v\n", key, err)
+				return nil
+			}
+			keys = append(keys, key)
+			pending = append(pending, notification)
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reading notifications for webhook %s: %w", webhook.id(), err))
+			continue
+		}
+		if len(pending) == 0 {
+			continue
+		}
+
+		mark := fmt.Sprintf("%s%s:%d", flushPrefix, webhook.id(), window.Unix())
+		first, err := s.store.PutIfAbsent(ctx, mark, []byte(s.now().UTC().Format(time.RFC3339)), flushRetention)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("marking webhook %s: %w", webhook.id(), err))
+			continue
+		}
+		if !first {
+			// Posted to already this interval; the haiku wait for the next.
+			continue
+		}
+
+		slices.SortFunc(pending, func(a, b haiku.Notification) int {
+			return b.CreatedAt.Compare(a.CreatedAt)
+		})
+		if err := s.post(ctx, webhook.URL, Message{Text: FormatMessage(pending, s.batchSize)}); err != nil {
+			logging.Errorf("[NOTIFY SERVICE] error posting to webhook %s: %v\n", webhook.id(), err)
+			run.Failed++
+			errs = append(errs, fmt.Errorf("webhook %s: %w", webhook.id(), err))
+			if err := s.store.Delete(ctx, mark); err != nil {
+				logging.Errorf("[NOTIFY SERVICE] error unmarking webhook %s: %v\n", webhook.id(), err)
+			}
+			continue
+		}
+		run.Messages++
+		run.Haiku += len(pending)
+
+		for _, key := range keys {
+			if err := s.store.Delete(ctx, key); err != nil {
+				// The haiku would be posted again, so the flush should be
+				// looked at.
+				errs = append(errs, fmt.Errorf("dequeueing %s: %w", key, err))
+			}
+		}
+	}
+
+	logging.Infof("[NOTIFY SERVICE] posted %d messages of %d haiku, %d failed\n", run.Messages, run.Haiku, run.Failed)
+	if len(errs) > 0 {
+		return run, fmt.Errorf("%w: %w", ErrFlush, errors.Join(errs...))
+	}
+	return run, nil
+}
+
+func (s *NotifyService) post(ctx context.Context, webhookURL string, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("encoding message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, bytes.TrimSpace(detail))
+	}
+	return nil
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// FormatMessage renders queued haiku, newest first, as Slack quotes each
+// followed by its repository and author, listing at most batchSize of them.
+func FormatMessage(pending []haiku.Notification, batchSize int) string {
+	var message strings.Builder
+	if len(pending) > 1 {
+		fmt.Fprintf(&message, "*%d new commit haiku*\n\n", len(pending))
+	}
+
+	for i, notification := range pending {
+		if i == batchSize {
+			fmt.Fprintf(&message, "…and %d more\n", len(pending)-batchSize)
+			break
+		}
+		for _, line := range strings.Split(strings.TrimSpace(notification.Haiku), "\n") {
+			message.WriteString(">")
+			message.WriteString(escaper.Replace(strings.TrimSpace(line)))
+			message.WriteString("\n")
+		}
+
+		var credits []string
+		if notification.Repository != "" {
+			credits = append(credits, escaper.Replace(notification.Repository))
+		}
+		if notification.Attribution != "" {
+			credits = append(credits, escaper.Replace(notification.Attribution))
+		}
+		fmt.Fprintf(&message, "🍂 %s\n\n", cmp.Or(strings.Join(credits, ", "), "a commit haiku"))
+	}
+	return strings.TrimSpace(message.String())
+}
+
+func newSuffix() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
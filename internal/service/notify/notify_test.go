@@ -0,0 +1,208 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const (
+	teamWebhook = "https://hooks.slack.com/services/T0/B0/team"
+	repoWebhook = "https://hooks.slack.com/services/T0/B0/repo"
+)
+
+type MockHTTPClient struct {
+	StatusToReturn int
+	Posted         map[string][]Message
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	var message Message
+	_ = json.NewDecoder(req.Body).Decode(&message)
+	if m.Posted == nil {
+		m.Posted = make(map[string][]Message)
+	}
+	m.Posted[req.URL.String()] = append(m.Posted[req.URL.String()], message)
+
+	status := m.StatusToReturn
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+}
+
+func TestParseWebhooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []Webhook
+		errorIs  error
+	}{
+		{
+			name:     "Webhooks",
+			raw:      `[{"url": "` + teamWebhook + `", "tenant": "acme"}, {"url": "` + repoWebhook + `", "repository": "octo/leaves"}]`,
+			expected: []Webhook{{URL: teamWebhook, Tenant: "acme"}, {URL: repoWebhook, Repository: "octo/leaves"}},
+		},
+		{
+			name:    "Not JSON",
+			raw:     teamWebhook,
+			errorIs: ErrBadWebhooks,
+		},
+		{
+			name:    "Not a Slack webhook",
+			raw:     `[{"url": "https://example.com/hook"}]`,
+			errorIs: ErrBadWebhooks,
+		},
+		{
+			name:    "Not HTTPS",
+			raw:     `[{"url": "http://hooks.slack.com/services/T0/B0/team"}]`,
+			errorIs: ErrBadWebhooks,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			webhooks, err := ParseWebhooks(tc.raw)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if len(webhooks) != len(tc.expected) {
+				t.Fatalf("Expected %+v, got %+v", tc.expected, webhooks)
+			}
+			for i := range webhooks {
+				if webhooks[i] != tc.expected[i] {
+					t.Errorf("Expected %+v, got %+v", tc.expected[i], webhooks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFlush(t *testing.T) {
+	now := time.Date(2025, 10, 10, 9, 0, 30, 0, time.UTC)
+	written := []haiku.Notification{
+		{Tenant: "acme", Repository: "octo/leaves", Haiku: "first haiku", CreatedAt: now.Add(-3 * time.Second)},
+		{Tenant: "acme", Repository: "Octo/Leaves", Haiku: "second haiku", CreatedAt: now.Add(-2 * time.Second)},
+		{Tenant: "acme", Repository: "octo/roots", Haiku: "third haiku", CreatedAt: now.Add(-time.Second)},
+		{Repository: "octo/leaves", Haiku: "default tenant haiku", CreatedAt: now},
+	}
+
+	tests := []struct {
+		name             string
+		batchSize        int
+		status           int
+		expectedMessages int
+		expectedHaiku    int
+		expectedTeam     []string // Listed in the team webhook's message, in order
+		expectedRepo     []string
+		expectedFailed   int
+	}{
+		{
+			name:             "One message per webhook",
+			expectedMessages: 2,
+			expectedHaiku:    5,
+			expectedTeam:     []string{"third haiku", "second haiku", "first haiku"},
+			expectedRepo:     []string{"second haiku", "first haiku"},
+		},
+		{
+			name:             "Beyond the batch size",
+			batchSize:        1,
+			expectedMessages: 2,
+			expectedHaiku:    5,
+			expectedTeam:     []string{"third haiku", "…and 2 more"},
+			expectedRepo:     []string{"second haiku", "…and 1 more"},
+		},
+		{
+			name:           "Webhook fails",
+			status:         http.StatusNotFound,
+			expectedFailed: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			httpClient := &MockHTTPClient{StatusToReturn: tc.status}
+			webhooks := []Webhook{
+				{URL: teamWebhook, Tenant: "acme"},
+				{URL: repoWebhook, Tenant: "acme", Repository: "octo/leaves"},
+			}
+			service := NewNotifyService(webhooks, NewMemoryStore(), httpClient, &Options{BatchSize: tc.batchSize})
+			service.now = func() time.Time { return now }
+
+			for _, notification := range written {
+				service.Notify(context.Background(), notification)
+			}
+
+			run, err := service.Flush(context.Background())
+			if (err != nil) != (tc.expectedFailed > 0) {
+				t.Fatalf("Expected %d webhooks to fail, got error %v", tc.expectedFailed, err)
+			}
+			if run.Messages != tc.expectedMessages || run.Haiku != tc.expectedHaiku || run.Failed != tc.expectedFailed {
+				t.Errorf("Expected %d messages of %d haiku and %d failed, got %+v", tc.expectedMessages, tc.expectedHaiku, tc.expectedFailed, run)
+			}
+
+			for url, expected := range map[string][]string{teamWebhook: tc.expectedTeam, repoWebhook: tc.expectedRepo} {
+				if len(expected) == 0 {
+					continue
+				}
+				posted := httpClient.Posted[url]
+				if len(posted) != 1 {
+					t.Fatalf("Expected one message to %s, got %+v", url, posted)
+				}
+				text := posted[0].Text
+				last := -1
+				for _, listed := range expected {
+					i := strings.Index(text, listed)
+					if i <= last {
+						t.Errorf("Expected %q listed in order in %q", listed, text)
+					}
+					last = i
+				}
+				if strings.Contains(text, "default tenant haiku") {
+					t.Errorf("Expected only the tenant's haiku, got %q", text)
+				}
+			}
+
+			// The same interval posts nothing more; a failed webhook keeps
+			// its haiku for the next.
+			before := len(httpClient.Posted[teamWebhook])
+			if _, err := service.Flush(context.Background()); (err != nil) != (tc.expectedFailed > 0) {
+				t.Fatalf("Expected the retry to fail as the flush did, got %v", err)
+			}
+			if again := len(httpClient.Posted[teamWebhook]) - before; (again > 0) != (tc.expectedFailed > 0) {
+				t.Errorf("Expected a retry only after a failure, got %d more messages", again)
+			}
+
+			service.now = func() time.Time { return now.Add(DefaultInterval) }
+			httpClient.StatusToReturn = http.StatusOK
+			next, err := service.Flush(context.Background())
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if tc.expectedFailed == 0 && next.Messages != 0 {
+				t.Errorf("Expected sent haiku dequeued, got %+v", next)
+			}
+			if tc.expectedFailed > 0 && next.Haiku != 5 {
+				t.Errorf("Expected the failed haiku posted next interval, got %+v", next)
+			}
+		})
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	pending := []haiku.Notification{
+		{Repository: "octo/leaves", Attribution: "after a commit by @mona", Haiku: "a <script> line\nsecond"},
+	}
+
+	expected := ">a &lt;script&gt; line\n>second\n🍂 octo/leaves, after a commit by @mona"
+	if message := FormatMessage(pending, DefaultBatchSize); message != expected {
+		t.Errorf("Expected %q, got %q", expected, message)
+	}
+}
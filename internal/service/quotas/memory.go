@@ -0,0 +1,11 @@
+package quotas
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore counts usage in memory, where a key's quota is only enforced
+// against the requests its own process served.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
@@ -0,0 +1,171 @@
+// Package quotas counts each API key's requests and output tokens per
+// calendar month (UTC), and refuses requests once a key's quota is used up.
+package quotas
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	// retention keeps a month's counters past its end, for billing.
+	retention = 62 * 24 * time.Hour
+	// monthFormat names the month counters are kept for.
+	monthFormat = "2006-01"
+
+	counterRequests     = "requests"
+	counterOutputTokens = "outputTokens"
+	// counterRefused counts refused requests, so that only a month's first
+	// refusal is published.
+	counterRefused = "refused"
+
+	// Limits a key can exceed, as ExceededEvent gives them.
+	LimitRequests     = "requests"
+	LimitOutputTokens = "outputTokens"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("api key quota exceeded")
+	ErrTrackUsage    = errors.New("error tracking api key usage")
+)
+
+// Store keeps named counters per key, e.g. in DynamoDB. AddCounters returns
+// every counter under key once added to.
+type Store interface {
+	AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error)
+}
+
+// Usage is a key's use of its quota this month.
+type Usage struct {
+	Quota        keys.Quota
+	Requests     int64     // Requests admitted this month, including the current one
+	OutputTokens int64     // Output tokens consumed this month, before the current request
+	ResetAt      time.Time // Start of next month, when the counts start over
+}
+
+// RemainingRequests returns how many more requests the quota allows, or -1
+// when requests are unlimited.
+func (u Usage) RemainingRequests() int64 {
+	if u.Quota.MonthlyRequests == 0 {
+		return -1
+	}
+	return max(0, u.Quota.MonthlyRequests-u.Requests)
+}
+
+// RemainingOutputTokens returns how many more output tokens the quota allows,
+// or -1 when output tokens are unlimited.
+func (u Usage) RemainingOutputTokens() int64 {
+	if u.Quota.MonthlyOutputTokens == 0 {
+		return -1
+	}
+	return max(0, u.Quota.MonthlyOutputTokens-u.OutputTokens)
+}
+
+// ExceededEvent describes a key that used up its quota for the month, for
+// consumers downstream alerting on it.
+type ExceededEvent struct {
+	KeyID   string    `json:"keyId"`
+	Tenant  string    `json:"tenant,omitempty"` // Tenant the key belongs to
+	Limit   string    `json:"limit"`            // The limit used up, one of the Limit* limits
+	Quota   int64     `json:"quota"`            // The key's monthly quota of the limit
+	Used    int64     `json:"used"`             // How much of the limit was used when the request was refused
+	ResetAt time.Time `json:"resetAt"`          // When the quota starts over
+}
+
+// EventPublisher is handed the first request a key is refused each month,
+// e.g. to publish it to EventBridge. It must not fail the request it is
+// handed.
+type EventPublisher interface {
+	QuotaExceeded(ctx context.Context, event ExceededEvent)
+}
+
+type QuotaService struct {
+	store  Store
+	events EventPublisher
+	now    func() time.Time
+}
+
+type Options struct {
+	Events EventPublisher // Told when a key first exceeds its quota each month (default: none)
+}
+
+func NewQuotaService(store Store, opts *Options) *QuotaService {
+	service := &QuotaService{
+		store: store,
+		now:   time.Now,
+	}
+
+	if opts != nil {
+		service.events = opts.Events
+	}
+
+	return service
+}
+
+// Admit counts a request against key, unless its quota is already used up.
+// Requests are counted atomically, so concurrent requests cannot overrun the
+// request quota. Output tokens are only known once a request finishes, so a
+// key can overrun its token quota by the tokens of the requests in flight.
+func (s *QuotaService) Admit(ctx context.Context, key keys.Key) (Usage, error) {
+	now := s.now().UTC()
+	storeKey := monthKey(key.ID, now)
+
+	counters, err := s.store.AddCounters(ctx, storeKey, map[string]int64{counterRequests: 1}, retention)
+	if err != nil {
+		return Usage{}, fmt.Errorf("%w: %w", ErrTrackUsage, err)
+	}
+
+	usage := Usage{
+		Quota:        key.Quota,
+		Requests:     counters[counterRequests],
+		OutputTokens: counters[counterOutputTokens],
+		ResetAt:      time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	var exceeded string
+	event := ExceededEvent{KeyID: key.ID, Tenant: key.Tenant, ResetAt: usage.ResetAt}
+	switch {
+	case key.Quota.MonthlyRequests > 0 && usage.Requests > key.Quota.MonthlyRequests:
+		exceeded = fmt.Sprintf("%d monthly requests", key.Quota.MonthlyRequests)
+		event.Limit, event.Quota, event.Used = LimitRequests, key.Quota.MonthlyRequests, usage.Requests-1
+	case key.Quota.MonthlyOutputTokens > 0 && usage.OutputTokens >= key.Quota.MonthlyOutputTokens:
+		exceeded = fmt.Sprintf("%d monthly output tokens", key.Quota.MonthlyOutputTokens)
+		event.Limit, event.Quota, event.Used = LimitOutputTokens, key.Quota.MonthlyOutputTokens, usage.OutputTokens
+	default:
+		return usage, nil
+	}
+
+	// Refused requests aren't counted, so the count stays what was served.
+	uncounted, err := s.store.AddCounters(ctx, storeKey, map[string]int64{counterRequests: -1, counterRefused: 1}, retention)
+	if err != nil {
+		logging.Errorf("[QUOTAS SERVICE] error uncounting refused request for key %s: %v\n", key.ID, err)
+	}
+	usage.Requests--
+
+	if s.events != nil && err == nil && uncounted[counterRefused] == 1 {
+		s.events.QuotaExceeded(ctx, event)
+	}
+
+	logging.Infof("[QUOTAS SERVICE] key %s used its %s\n", key.ID, exceeded)
+	return usage, fmt.Errorf("%w: used all %s", ErrQuotaExceeded, exceeded)
+}
+
+// AddOutputTokens counts output tokens a request consumed against keyID.
+func (s *QuotaService) AddOutputTokens(ctx context.Context, keyID string, tokens int) error {
+	if tokens <= 0 {
+		return nil
+	}
+	if _, err := s.store.AddCounters(ctx, monthKey(keyID, s.now().UTC()), map[string]int64{counterOutputTokens: int64(tokens)}, retention); err != nil {
+		return fmt.Errorf("%w: %w", ErrTrackUsage, err)
+	}
+	return nil
+}
+
+func monthKey(keyID string, t time.Time) string {
+	return "usage:" + keyID + ":" + t.Format(monthFormat)
+}
@@ -0,0 +1,149 @@
+package quotas
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+type MockStore struct {
+	ErrorToReturn error
+}
+
+func (m *MockStore) AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error) {
+	return nil, m.ErrorToReturn
+}
+
+type MockEventPublisher struct {
+	Published []ExceededEvent
+}
+
+func (m *MockEventPublisher) QuotaExceeded(ctx context.Context, event ExceededEvent) {
+	m.Published = append(m.Published, event)
+}
+
+func TestAdmit(t *testing.T) {
+	now := time.Date(2025, 10, 31, 23, 0, 0, 0, time.UTC)
+	service := NewQuotaService(NewMemoryStore(), nil)
+	service.now = func() time.Time { return now }
+
+	key := keys.Key{ID: "ci", Quota: keys.Quota{MonthlyRequests: 2}}
+
+	for i := range 2 {
+		usage, err := service.Admit(context.Background(), key)
+		if err != nil {
+			t.Fatalf("Expected request %d to be admitted, got %v", i+1, err)
+		}
+		if usage.RemainingRequests() != int64(1-i) || usage.RemainingOutputTokens() != -1 {
+			t.Errorf("Expected %d requests and unlimited tokens left, got %d and %d", 1-i, usage.RemainingRequests(), usage.RemainingOutputTokens())
+		}
+		if !usage.ResetAt.Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected the quota to reset on November 1st, got %s", usage.ResetAt)
+		}
+	}
+
+	usage, err := service.Admit(context.Background(), key)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if usage.Requests != 2 {
+		t.Errorf("Expected the refused request not to be counted, got %d requests", usage.Requests)
+	}
+
+	// A new month starts over.
+	now = now.Add(2 * time.Hour)
+	if _, err := service.Admit(context.Background(), key); err != nil {
+		t.Errorf("Expected a request next month to be admitted, got %v", err)
+	}
+}
+
+func TestAdmitPublishesFirstRefusal(t *testing.T) {
+	now := time.Date(2025, 10, 31, 23, 0, 0, 0, time.UTC)
+	publisher := &MockEventPublisher{}
+	service := NewQuotaService(NewMemoryStore(), &Options{Events: publisher})
+	service.now = func() time.Time { return now }
+
+	key := keys.Key{ID: "ci", Tenant: "acme", Quota: keys.Quota{MonthlyRequests: 1}}
+	for range 3 {
+		_, _ = service.Admit(context.Background(), key)
+	}
+
+	expected := ExceededEvent{
+		KeyID:   "ci",
+		Tenant:  "acme",
+		Limit:   LimitRequests,
+		Quota:   1,
+		Used:    1,
+		ResetAt: time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if len(publisher.Published) != 1 || publisher.Published[0] != expected {
+		t.Fatalf("Expected only the first refusal published as %+v, got %+v", expected, publisher.Published)
+	}
+
+	// A new month starts over.
+	now = now.Add(2 * time.Hour)
+	for range 2 {
+		_, _ = service.Admit(context.Background(), key)
+	}
+	if len(publisher.Published) != 2 {
+		t.Errorf("Expected the first refusal next month published, got %d events", len(publisher.Published))
+	}
+}
+
+func TestAdmitOutputTokens(t *testing.T) {
+	service := NewQuotaService(NewMemoryStore(), nil)
+	key := keys.Key{ID: "ci", Quota: keys.Quota{MonthlyOutputTokens: 100}}
+
+	if _, err := service.Admit(context.Background(), key); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := service.AddOutputTokens(context.Background(), key.ID, 60); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	usage, err := service.Admit(context.Background(), key)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if usage.RemainingOutputTokens() != 40 || usage.RemainingRequests() != -1 {
+		t.Errorf("Expected 40 tokens and unlimited requests left, got %d and %d", usage.RemainingOutputTokens(), usage.RemainingRequests())
+	}
+
+	// The request in flight may overrun the quota, but none follow it.
+	if err := service.AddOutputTokens(context.Background(), key.ID, 60); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Admit(context.Background(), key); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+}
+
+func TestUnlimitedKeysAreCounted(t *testing.T) {
+	service := NewQuotaService(NewMemoryStore(), nil)
+	key := keys.Key{ID: "ci"}
+
+	for range 3 {
+		if _, err := service.Admit(context.Background(), key); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	usage, _ := service.Admit(context.Background(), key)
+	if usage.Requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", usage.Requests)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	service := NewQuotaService(&MockStore{ErrorToReturn: errors.New("table not found")}, nil)
+
+	if _, err := service.Admit(context.Background(), keys.Key{ID: "ci"}); !errors.Is(err, ErrTrackUsage) {
+		t.Errorf("Expected ErrTrackUsage, got %v", err)
+	}
+	if err := service.AddOutputTokens(context.Background(), "ci", 10); !errors.Is(err, ErrTrackUsage) {
+		t.Errorf("Expected ErrTrackUsage, got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package renga
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps renga in memory for a single server, which starts every
+// renga over when it restarts.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
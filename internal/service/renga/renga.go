@@ -0,0 +1,363 @@
+// Package renga chains the haiku of a repository's commits into a renga, a
+// linked poem whose every verse is written to follow the one before it, so
+// that a repository's history reads as one evolving poem.
+package renga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	keyPrefix = "renga:"
+	// maxAttempts bounds how many times a verse is written again when other
+	// commits' verses claim its place in the chain first.
+	maxAttempts = 3
+	// DefaultLimit and MaxLimit bound how many of the latest verses are read.
+	DefaultLimit = 10
+	MaxLimit     = 100
+)
+
+var (
+	ErrBadRenga      = errors.New("bad renga request")
+	ErrRengaNotFound = errors.New("renga not found")
+	ErrRengaBusy     = errors.New("renga is busy")
+	ErrStoreRenga    = errors.New("error storing renga")
+)
+
+// repositoryPattern matches repository identifiers such as "octo/leaves" or
+// GitLab's "group/subgroup/project".
+var repositoryPattern = regexp.MustCompile(`^[\w.-]+(/[\w.-]+)+$`)
+
+// maxRepositoryLength is the longest repository identifier accepted.
+const maxRepositoryLength = 100
+
+// Verse is one commit's haiku in a renga.
+type Verse struct {
+	Position  int        `json:"position"`         // Place in the renga, from 1 for the first verse
+	Haiku     string     `json:"haiku"`            // The verse
+	ID        string     `json:"id,omitempty"`     // Identifies the kept haiku, for voting
+	Mood      haiku.Mood `json:"mood"`             // Mood the verse was written in
+	Author    string     `json:"author,omitempty"` // Commit author credited with the verse
+	Erased    bool       `json:"erased,omitempty"` // Whether the verse was erased with its author's haiku
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// Renga is the latest verses of a repository's renga, oldest first.
+type Renga struct {
+	Repository string  `json:"repository"`
+	Length     int     `json:"length"` // Verses in the whole renga
+	Verses     []Verse `json:"verses"`
+}
+
+// ContinueRequest adds a commit's verse to its repository's renga.
+type ContinueRequest struct {
+	Repository    string        `json:"repository" binding:"required"`    // Repository identifier, e.g. "octo/leaves"
+	CommitMessage string        `json:"commitMessage" binding:"required"` // Commit the verse is written from
+	Mood          haiku.Mood    `json:"mood,omitempty"`                   // Mood of the verse (default: reflective)
+	Author        *haiku.Author `json:"author,omitempty"`                 // Commit author, credited with the verse
+}
+
+type HaikuService interface {
+	ContinueHaiku(ctx context.Context, verse string, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+// Store keeps each renga's verses, e.g. in DynamoDB. PutIfAbsent stores a
+// value only when the key holds none, so that verses written at the same time
+// can't take the same place in a renga. ScanPrefix finds the verses credited
+// to an author being erased.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+}
+
+// head records how long a renga was when its last verse was added. Verses
+// added at the same time may leave it behind, so the verses after it are
+// looked for too.
+type head struct {
+	Length int `json:"length"`
+}
+
+type RengaService struct {
+	haikuService HaikuService
+	store        Store
+	retention    time.Duration
+	now          func() time.Time
+}
+
+type Options struct {
+	Retention time.Duration // How long each verse is kept (default: forever)
+}
+
+func NewRengaService(haikuService HaikuService, store Store, opts *Options) *RengaService {
+	service := &RengaService{
+		haikuService: haikuService,
+		store:        store,
+		now:          time.Now,
+	}
+
+	if opts != nil && opts.Retention > 0 {
+		service.retention = opts.Retention
+	}
+
+	return service
+}
+
+// Continue writes the verse for request's commit following the last verse of
+// its repository's renga, starting the renga when it has none, and adds it to
+// the renga. When another commit's verse is added first, the verse is written
+// again to follow that one. A haiku written locally while the model is
+// unavailable wouldn't follow the verse before it, so it isn't added. Each
+// verse is kept for the retention, and the head with it.
+func (s *RengaService) Continue(ctx context.Context, request ContinueRequest) (Verse, error) {
+	repository, err := validRepository(request.Repository)
+	if err != nil {
+		return Verse{}, err
+	}
+
+	haikuRequest := haiku.HaikuCommitRequest{
+		CommitMessage: request.CommitMessage,
+		Mood:          request.Mood,
+		Repository:    &haiku.Repository{Name: repository},
+		Author:        request.Author,
+	}
+
+	for range maxAttempts {
+		length, last, err := s.tail(ctx, repository)
+		if err != nil {
+			return Verse{}, err
+		}
+
+		response, err := s.haikuService.ContinueHaiku(ctx, last.Haiku, haikuRequest)
+		if err != nil {
+			return Verse{}, err
+		}
+		if response.Degraded {
+			return Verse{}, fmt.Errorf("%w: a fallback haiku can't continue the renga", bedrock.ErrModelUnavailable)
+		}
+
+		verse := Verse{
+			Position:  length + 1,
+			Haiku:     response.Haiku,
+			ID:        response.ID,
+			Mood:      response.Metadata.Mood,
+			CreatedAt: s.now(),
+		}
+		if author := request.Author; author != nil {
+			verse.Author = author.Key()
+		}
+		value, err := json.Marshal(verse)
+		if err != nil {
+			return Verse{}, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+		}
+		stored, err := s.store.PutIfAbsent(ctx, verseKey(ctx, repository, verse.Position), value, s.retention)
+		if err != nil {
+			return Verse{}, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+		}
+		if !stored {
+			logging.Infof("[RENGA SERVICE] verse %d of %s was already added, writing ours again\n", verse.Position, repository)
+			continue
+		}
+
+		// The head only saves looking through every verse; a stale one is
+		// caught up with on the next read.
+		value, err = json.Marshal(head{Length: verse.Position})
+		if err != nil {
+			return Verse{}, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+		}
+		if err := s.store.Put(ctx, headKey(ctx, repository), value, s.retention); err != nil {
+			logging.Errorf("[RENGA SERVICE] error updating the head of %s: %v\n", repository, err)
+		}
+		return verse, nil
+	}
+
+	return Verse{}, fmt.Errorf("%w: other verses kept being added to %s, try again", ErrRengaBusy, repository)
+}
+
+// Get returns up to limit of the latest verses of repository's renga. Verses
+// expire oldest first, so fewer are returned once the earliest are gone.
+func (s *RengaService) Get(ctx context.Context, repository string, limit int) (Renga, error) {
+	repository, err := validRepository(repository)
+	if err != nil {
+		return Renga{}, err
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	limit = min(limit, MaxLimit)
+
+	length, last, err := s.tail(ctx, repository)
+	if err != nil {
+		return Renga{}, err
+	}
+	if length == 0 {
+		return Renga{}, ErrRengaNotFound
+	}
+
+	verses := make([]Verse, min(limit, length))
+	verses[len(verses)-1] = last
+	for i := len(verses) - 2; i >= 0; i-- {
+		position := length - (len(verses) - 1 - i)
+		verse, found, err := s.verse(ctx, repository, position)
+		if err != nil {
+			return Renga{}, err
+		}
+		if !found {
+			verses = verses[i+1:]
+			break
+		}
+		verses[i] = verse
+	}
+
+	return Renga{Repository: repository, Length: length, Verses: verses}, nil
+}
+
+// tail returns the length of repository's renga and its last verse, starting
+// from the head and looking past it for verses added since. The head outlives
+// the last verse by no more than the time between storing the two, so once
+// that verse has expired the renga starts over.
+func (s *RengaService) tail(ctx context.Context, repository string) (int, Verse, error) {
+	var h head
+	value, found, err := s.store.Get(ctx, headKey(ctx, repository))
+	if err != nil {
+		return 0, Verse{}, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+	}
+	if found {
+		if err := json.Unmarshal(value, &h); err != nil {
+			return 0, Verse{}, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+		}
+	}
+
+	var last Verse
+	if h.Length > 0 {
+		last, found, err = s.verse(ctx, repository, h.Length)
+		if err != nil {
+			return 0, Verse{}, err
+		}
+		if !found {
+			logging.Infof("[RENGA SERVICE] verse %d of %s has expired, starting the renga over\n", h.Length, repository)
+			h = head{}
+		}
+	}
+	for {
+		next, found, err := s.verse(ctx, repository, h.Length+1)
+		if err != nil {
+			return 0, Verse{}, err
+		}
+		if !found {
+			return h.Length, last, nil
+		}
+		h.Length, last = h.Length+1, next
+	}
+}
+
+func (s *RengaService) verse(ctx context.Context, repository string, position int) (Verse, bool, error) {
+	value, found, err := s.store.Get(ctx, verseKey(ctx, repository, position))
+	if err != nil {
+		return Verse{}, false, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+	}
+	if !found {
+		return Verse{}, false, nil
+	}
+
+	var verse Verse
+	if err := json.Unmarshal(value, &verse); err != nil {
+		return Verse{}, false, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+	}
+	return verse, true, nil
+}
+
+// EraseAuthor removes the verses credited to author, matched exactly, and
+// returns how many it removed. An erased verse keeps its place, so that the
+// renga still chains, but loses its haiku and author. Only the context's
+// tenant's renga are searched, unless the operator asks outside any tenant,
+// when every tenant's are.
+func (s *RengaService) EraseAuthor(ctx context.Context, author string) (int, error) {
+	prefix := keys.Namespace(ctx, keyPrefix)
+	if keys.EveryTenant(ctx) {
+		prefix = ""
+	}
+
+	found := map[string]Verse{}
+	err := s.store.ScanPrefix(ctx, prefix, func(key string, value []byte) error {
+		if !isVerseKey(key) {
+			return nil
+		}
+		var verse Verse
+		if err := json.Unmarshal(value, &verse); err != nil {
+			logging.Warnf("[RENGA SERVICE] skipping unreadable %s: %v\n", key, err)
+			return nil
+		}
+		if verse.Author == author {
+			found[key] = verse
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+	}
+
+	erased := 0
+	for key, verse := range found {
+		// The erased verse expires when the verse would have.
+		ttl := time.Duration(0)
+		if s.retention > 0 {
+			ttl = verse.CreatedAt.Add(s.retention).Sub(s.now())
+			if ttl <= 0 {
+				continue
+			}
+		}
+		verse.Haiku, verse.ID, verse.Author, verse.Erased = "", "", "", true
+		value, err := json.Marshal(verse)
+		if err != nil {
+			return erased, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+		}
+		if err := s.store.Put(ctx, key, value, ttl); err != nil {
+			return erased, fmt.Errorf("%w: %w", ErrStoreRenga, err)
+		}
+		erased++
+	}
+	return erased, nil
+}
+
+// validRepository checks a repository identifier and returns it in lower
+// case, as repository names are matched case-insensitively.
+func validRepository(repository string) (string, error) {
+	repository = strings.TrimSpace(repository)
+	if len(repository) > maxRepositoryLength || !repositoryPattern.MatchString(repository) {
+		return "", fmt.Errorf("%w: repository must be an identifier such as owner/name, at most %d characters", ErrBadRenga, maxRepositoryLength)
+	}
+	return strings.ToLower(repository), nil
+}
+
+// headKey names the item recording the length of repository's renga, and
+// verseKey the item holding one of its verses. Repository identifiers can't
+// hold '#', so the two never collide.
+func headKey(ctx context.Context, repository string) string {
+	return keys.Namespace(ctx, keyPrefix+repository)
+}
+
+func verseKey(ctx context.Context, repository string, position int) string {
+	return keys.Namespace(ctx, keyPrefix+repository+"#"+strconv.Itoa(position))
+}
+
+// isVerseKey reports whether key, of any tenant, holds a verse.
+func isVerseKey(key string) bool {
+	if rest, ok := strings.CutPrefix(key, "tenant:"); ok {
+		_, key, _ = strings.Cut(rest, ":")
+	}
+	return strings.HasPrefix(key, keyPrefix) && strings.Contains(key, "#")
+}
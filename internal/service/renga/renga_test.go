@@ -0,0 +1,339 @@
+package renga
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+// MockHaikuService numbers the haiku it writes and records the verse each
+// one followed. BeforeWrite, when set, runs before each haiku is returned.
+type MockHaikuService struct {
+	Degraded    bool
+	Followed    []string
+	BeforeWrite func(ctx context.Context)
+}
+
+func (m *MockHaikuService) ContinueHaiku(ctx context.Context, verse string, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.Followed = append(m.Followed, verse)
+	if m.BeforeWrite != nil {
+		m.BeforeWrite(ctx)
+	}
+	n := len(m.Followed)
+	return haiku.HaikuCommitResponse{
+		Haiku:    fmt.Sprintf("verse %d", n),
+		ID:       fmt.Sprintf("id%d", n),
+		Degraded: m.Degraded,
+		Metadata: haiku.HaikuMetadata{Mood: haiku.MoodReflective},
+	}, nil
+}
+
+// MockStore loses every race to add a verse.
+type MockStore struct{}
+
+func (m *MockStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, nil
+}
+
+func (m *MockStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return nil
+}
+
+func (m *MockStore) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return false, nil
+}
+
+func (m *MockStore) ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	return nil
+}
+
+func TestContinue(t *testing.T) {
+	store := NewMemoryStore()
+	haikuService := &MockHaikuService{}
+	service := NewRengaService(haikuService, store, nil)
+
+	for i := 1; i <= 3; i++ {
+		verse, err := service.Continue(context.Background(), ContinueRequest{
+			Repository:    "Octo/Leaves",
+			CommitMessage: fmt.Sprintf("fix: bug %d", i),
+			Author:        &haiku.Author{Name: "Mona"},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if verse.Position != i || verse.Haiku != fmt.Sprintf("verse %d", i) || verse.ID != fmt.Sprintf("id%d", i) || verse.Author != "Mona" {
+			t.Errorf("Expected verse %d, got %+v", i, verse)
+		}
+	}
+
+	expectedFollowed := []string{"", "verse 1", "verse 2"}
+	if fmt.Sprint(haikuService.Followed) != fmt.Sprint(expectedFollowed) {
+		t.Errorf("Expected each verse to follow the last, got %q", haikuService.Followed)
+	}
+
+	renga, err := service.Get(context.Background(), "octo/leaves", 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if renga.Repository != "octo/leaves" || renga.Length != 3 || len(renga.Verses) != 2 || renga.Verses[0].Position != 2 || renga.Verses[1].Position != 3 {
+		t.Errorf("Expected the last two of three verses, oldest first, got %+v", renga)
+	}
+}
+
+func TestContinueErrors(t *testing.T) {
+	tests := []struct {
+		name          string
+		repository    string
+		store         Store
+		degraded      bool
+		expectedCalls int
+		errorIs       error
+	}{
+		{
+			name:       "Bad repository",
+			repository: "leaves",
+			store:      NewMemoryStore(),
+			errorIs:    ErrBadRenga,
+		},
+		{
+			name:          "Fallback haiku are not added",
+			repository:    "octo/leaves",
+			store:         NewMemoryStore(),
+			degraded:      true,
+			expectedCalls: 1,
+			errorIs:       bedrock.ErrModelUnavailable,
+		},
+		{
+			name:          "Always beaten to the next place",
+			repository:    "octo/leaves",
+			store:         &MockStore{},
+			expectedCalls: maxAttempts,
+			errorIs:       ErrRengaBusy,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{Degraded: tc.degraded}
+			service := NewRengaService(haikuService, tc.store, nil)
+
+			_, err := service.Continue(context.Background(), ContinueRequest{Repository: tc.repository, CommitMessage: "fix typo"})
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if len(haikuService.Followed) != tc.expectedCalls {
+				t.Errorf("Expected %d haiku written, got %d", tc.expectedCalls, len(haikuService.Followed))
+			}
+			if _, err := service.Get(context.Background(), "octo/leaves", 0); tc.repository != "leaves" && !errors.Is(err, ErrRengaNotFound) {
+				t.Errorf("Expected nothing added, got %v", err)
+			}
+		})
+	}
+}
+
+func TestContinueFollowsVerseAddedMeanwhile(t *testing.T) {
+	store := NewMemoryStore()
+	haikuService := &MockHaikuService{}
+	haikuService.BeforeWrite = func(ctx context.Context) {
+		// Another instance adds the first verse while ours is written.
+		if len(haikuService.Followed) == 1 {
+			value, _ := json.Marshal(Verse{Position: 1, Haiku: "theirs"})
+			store.PutIfAbsent(ctx, verseKey(ctx, "octo/leaves", 1), value, 0)
+		}
+	}
+	service := NewRengaService(haikuService, store, nil)
+
+	verse, err := service.Continue(context.Background(), ContinueRequest{Repository: "octo/leaves", CommitMessage: "fix typo"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if verse.Position != 2 || haikuService.Followed[1] != "theirs" {
+		t.Errorf("Expected our verse written again after theirs, got %+v following %q", verse, haikuService.Followed)
+	}
+}
+
+func TestGet(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewRengaService(&MockHaikuService{}, store, nil)
+	ctx := keys.NewTenantContext(context.Background(), "acme")
+	for i := 1; i <= 3; i++ {
+		if _, err := service.Continue(ctx, ContinueRequest{Repository: "octo/leaves", CommitMessage: "fix typo"}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	// A verse added without moving the head, as when two are added at once.
+	value, _ := json.Marshal(Verse{Position: 4, Haiku: "verse 4"})
+	store.PutIfAbsent(ctx, verseKey(ctx, "octo/leaves", 4), value, 0)
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		repository     string
+		limit          int
+		expectedLength int
+		expectedFirst  int
+		errorIs        error
+	}{
+		{
+			name:           "Default limit",
+			ctx:            ctx,
+			repository:     "octo/leaves",
+			expectedLength: 4,
+			expectedFirst:  1,
+		},
+		{
+			name:           "Latest verses",
+			ctx:            ctx,
+			repository:     "octo/leaves",
+			limit:          1,
+			expectedLength: 4,
+			expectedFirst:  4,
+		},
+		{
+			name:       "Another tenant's renga",
+			ctx:        context.Background(),
+			repository: "octo/leaves",
+			errorIs:    ErrRengaNotFound,
+		},
+		{
+			name:       "Bad repository",
+			ctx:        ctx,
+			repository: "octo/leaves#1",
+			errorIs:    ErrBadRenga,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			renga, err := service.Get(tc.ctx, tc.repository, tc.limit)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if renga.Length != tc.expectedLength || renga.Verses[0].Position != tc.expectedFirst || renga.Verses[len(renga.Verses)-1].Position != tc.expectedLength {
+				t.Errorf("Expected verses %d to %d, got %+v", tc.expectedFirst, tc.expectedLength, renga)
+			}
+		})
+	}
+}
+
+func TestVersesExpire(t *testing.T) {
+	now := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store := NewMemoryStore()
+	store.SetClock(clock)
+	haikuService := &MockHaikuService{}
+	service := NewRengaService(haikuService, store, &Options{Retention: 36 * time.Hour})
+	service.now = clock
+
+	request := ContinueRequest{Repository: "octo/leaves", CommitMessage: "fix typo"}
+	for i := range 3 {
+		if i > 0 {
+			now = now.Add(24 * time.Hour)
+		}
+		if _, err := service.Continue(context.Background(), request); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	// The first verse was added two days ago, so only the last two are left.
+	renga, err := service.Get(context.Background(), "octo/leaves", 0)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if renga.Length != 3 || len(renga.Verses) != 2 || renga.Verses[0].Position != 2 {
+		t.Errorf("Expected the two verses not yet expired, got %+v", renga)
+	}
+
+	// Once the last verse has expired too, the renga starts over.
+	now = now.Add(48 * time.Hour)
+	verse, err := service.Continue(context.Background(), request)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if verse.Position != 1 || haikuService.Followed[3] != "" {
+		t.Errorf("Expected the renga started over, got %+v following %q", verse, haikuService.Followed[3])
+	}
+}
+
+func TestEraseAuthor(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewRengaService(&MockHaikuService{}, store, nil)
+	acme := keys.NewTenantContext(context.Background(), "acme")
+	globex := keys.NewTenantContext(context.Background(), "globex")
+	for _, ctx := range []context.Context{acme, globex} {
+		for _, name := range []string{"Mona", "Hubot", "Mona"} {
+			request := ContinueRequest{Repository: "octo/leaves", CommitMessage: "fix typo", Author: &haiku.Author{Name: name}}
+			if _, err := service.Continue(ctx, request); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		expectedErased int
+		expectedKept   map[context.Context]int
+	}{
+		{
+			name:           "Tenant's renga only",
+			ctx:            acme,
+			expectedErased: 2,
+			expectedKept:   map[context.Context]int{acme: 0, globex: 2},
+		},
+		{
+			name:           "Default tenant's renga only",
+			ctx:            context.Background(),
+			expectedErased: 0,
+			expectedKept:   map[context.Context]int{acme: 0, globex: 2},
+		},
+		{
+			name:           "Every tenant's renga, for the operator",
+			ctx:            keys.NewOperatorContext(context.Background()),
+			expectedErased: 2,
+			expectedKept:   map[context.Context]int{acme: 0, globex: 0},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			erased, err := service.EraseAuthor(tc.ctx, "Mona")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if erased != tc.expectedErased {
+				t.Errorf("Expected %d verses erased, got %d", tc.expectedErased, erased)
+			}
+
+			for ctx, expected := range tc.expectedKept {
+				renga, err := service.Get(ctx, "octo/leaves", 0)
+				if err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+				kept := 0
+				for _, verse := range renga.Verses {
+					if verse.Author == "Mona" {
+						kept++
+					}
+					if verse.Erased && (verse.Haiku != "" || verse.ID != "") {
+						t.Errorf("Expected erased verse %d emptied, got %+v", verse.Position, verse)
+					}
+				}
+				if renga.Length != 3 || kept != expected {
+					t.Errorf("Expected 3 verses with %d by Mona, got %+v", expected, renga)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package shadow
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps shadow results and the daily token budget in memory for
+// a single server, whose budget isn't shared with any other.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
@@ -0,0 +1,313 @@
+// Package shadow writes a share of served commit haiku again with an
+// alternate prompt or model, after the response has been returned, and keeps
+// both for offline comparison. Shadow haiku are never returned to callers,
+// and stop once the day's token budget is spent.
+package shadow
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	// DefaultDailyTokens is the shadow's token budget per UTC day, unless
+	// configured otherwise.
+	DefaultDailyTokens = 100_000
+	// DefaultRetention is how long shadow results are kept, unless configured
+	// otherwise.
+	DefaultRetention = 30 * 24 * time.Hour
+
+	dayFormat      = "2006-01-02"
+	resultPrefix   = "shadow:"
+	counterTokens  = "tokens"
+	counterRuns    = "runs"
+	budgetDuration = 48 * time.Hour
+)
+
+var (
+	ErrRunShadow   = errors.New("error writing shadow haiku")
+	ErrStoreShadow = errors.New("error storing shadow haiku")
+)
+
+// Store keeps shadow results and the day's budget counters, e.g. in DynamoDB.
+// ScanPrefix and Delete find and remove the results of an author being
+// erased.
+type Store interface {
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+	AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error)
+	GetCounters(ctx context.Context, key string) (map[string]int64, error)
+}
+
+// Scheduler runs a shadow request after its response has been returned, e.g.
+// in an asynchronous Lambda invocation.
+type Scheduler interface {
+	Schedule(ctx context.Context, request haiku.ShadowRequest) error
+}
+
+// Result is one served haiku and its shadow, as kept for comparison.
+type Result struct {
+	ID            string    `json:"id"`
+	Served        Served    `json:"served"`             // The haiku served
+	Haiku         string    `json:"haiku,omitempty"`    // Haiku written by the shadow
+	Model         string    `json:"model"`              // Model that wrote the shadow haiku
+	PromptVersion string    `json:"promptVersion"`      // Prompt template version of the shadow haiku
+	Valid         bool      `json:"valid"`              // Whether the shadow haiku is 5-7-5
+	Error         string    `json:"error,omitempty"`    // Why the shadow haiku couldn't be written
+	LatencyMs     int64     `json:"latencyMs"`          // Time the model took to write the shadow haiku
+	InputTokens   int       `json:"inputTokens"`        // Tokens spent on the prompt
+	OutputTokens  int       `json:"outputTokens"`       // Tokens spent on the shadow haiku
+	CreatedAt     time.Time `json:"createdAt"`          // When the shadow haiku was written
+	Warnings      []string  `json:"warnings,omitempty"` // Adjustments made to the shadow's options
+}
+
+// Served is the haiku a shadow is compared with. The commit message and
+// author are kept only as hashes, so that a result holds neither, yet results
+// for the same commit can be matched and an author's results found when they
+// are erased.
+type Served struct {
+	HaikuID       string         `json:"haikuId,omitempty"`    // ID of the kept haiku, when haiku are kept
+	Tenant        string         `json:"tenant,omitempty"`     // Tenant the haiku was written for
+	Author        string         `json:"author,omitempty"`     // SHA-256 of the commit author, hex encoded
+	CommitHash    string         `json:"commitHash"`           // SHA-256 of the commit message as sent to the model, hex encoded
+	Mood          haiku.Mood     `json:"mood"`                 // Mood the haiku was written in
+	Register      haiku.Register `json:"register,omitempty"`   // Register requested, if any
+	Repository    string         `json:"repository,omitempty"` // Repository name, if any
+	Haiku         string         `json:"haiku"`                // Haiku served
+	Model         string         `json:"model"`                // Model that wrote the haiku served
+	PromptVersion string         `json:"promptVersion"`        // Prompt template version the haiku served was written with
+}
+
+type ShadowService struct {
+	modelClient haiku.BedrockClient
+	store       Store
+	scheduler   Scheduler
+	prompts     haiku.PromptProvider
+	modelID     string
+	percent     int
+	dailyTokens int64
+	retention   time.Duration
+	roll        func(n int) int
+	now         func() time.Time
+}
+
+type Options struct {
+	Prompts     haiku.PromptProvider // Alternate prompt templates (default: the compiled-in templates)
+	ModelID     string               // Alternate model (default: Claude Haiku 4.5)
+	Percent     int                  // Share of served haiku written again, 0 to 100 (default: 0, none)
+	DailyTokens int64                // Tokens the shadow may spend per UTC day (default: 100,000)
+	Retention   time.Duration        // How long results are kept (default: 30 days)
+}
+
+func NewShadowService(modelClient haiku.BedrockClient, store Store, scheduler Scheduler, opts *Options) *ShadowService {
+	service := &ShadowService{
+		modelClient: modelClient,
+		store:       store,
+		scheduler:   scheduler,
+		prompts:     prompt.NewStaticStore(haiku.DefaultPromptDefinitions()),
+		modelID:     bedrock.ClaudeModelID,
+		dailyTokens: DefaultDailyTokens,
+		retention:   DefaultRetention,
+		roll:        roll,
+		now:         time.Now,
+	}
+
+	if opts != nil {
+		if opts.Prompts != nil {
+			service.prompts = opts.Prompts
+		}
+		if opts.ModelID != "" {
+			service.modelID = opts.ModelID
+		}
+		service.percent = opts.Percent
+		if opts.DailyTokens > 0 {
+			service.dailyTokens = opts.DailyTokens
+		}
+		if opts.Retention > 0 {
+			service.retention = opts.Retention
+		}
+	}
+
+	return service
+}
+
+// Sample schedules the shadow of a share of served haiku. Scheduling is fire
+// and forget: a failure is logged, and never reaches the caller.
+func (s *ShadowService) Sample(ctx context.Context, request haiku.ShadowRequest) {
+	if s.percent <= 0 || s.roll(100) >= s.percent {
+		return
+	}
+	if err := s.scheduler.Schedule(ctx, request); err != nil {
+		logging.Errorf("[SHADOW SERVICE] error scheduling shadow haiku: %v\n", err)
+	}
+}
+
+// Run writes the shadow of a served haiku and keeps it with the haiku served.
+// Once the day's budget is spent, requests are dropped until the next UTC day.
+// The shadow is written without moderation or retries, as it is never shown.
+func (s *ShadowService) Run(ctx context.Context, request haiku.ShadowRequest) error {
+	now := s.now()
+	budget := budgetKey(now)
+	spent, err := s.store.GetCounters(ctx, budget)
+	if err != nil {
+		return fmt.Errorf("%w: reading budget: %w", ErrRunShadow, err)
+	}
+	if spent[counterTokens] >= s.dailyTokens {
+		logging.Warnf("[SHADOW SERVICE] daily budget of %d tokens spent, dropping shadow haiku\n", s.dailyTokens)
+		return nil
+	}
+
+	prompts := s.prompts.Select(ctx, request.CommitMessage)
+	rendered, err := prompts.Render(prompt.PromptData{
+		Mood:          string(request.Mood),
+		Register:      string(request.Register),
+		CommitMessage: request.CommitMessage,
+		Repository:    request.Repository,
+		Issues:        request.Issues,
+	})
+	if err != nil {
+		return fmt.Errorf("%w: rendering prompt: %w", ErrRunShadow, err)
+	}
+	system := prompts.System
+	if guidance, ok := haiku.RegisterGuidance[request.Register]; ok {
+		system = strings.TrimRight(system, "\n") + "\n\n" + guidance + "\n"
+	}
+
+	id, err := newResultID()
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRunShadow, err)
+	}
+	result := Result{
+		ID:            id,
+		Served:        served(request),
+		Model:         s.modelID,
+		PromptVersion: prompts.Version,
+		CreatedAt:     now.UTC(),
+	}
+
+	generated, err := s.modelClient.InvokeClaude(ctx, rendered, &bedrock.ClaudeOptions{
+		ModelID: s.modelID,
+		System:  system,
+	})
+	result.LatencyMs = s.now().Sub(now).Milliseconds()
+	if err != nil {
+		// Failures are kept too, since a model that fails more often is worth
+		// knowing about.
+		logging.Errorf("[SHADOW SERVICE] error writing shadow haiku: %v\n", err)
+		result.Error = err.Error()
+	} else {
+		result.Haiku = generated.Text
+		result.Model = generated.ModelID
+		result.Valid = haiku.CheckStructure(generated.Text) == nil
+		result.InputTokens = generated.Usage.InputTokens
+		result.OutputTokens = generated.Usage.OutputTokens
+		result.Warnings = generated.Warnings
+	}
+
+	if _, err := s.store.AddCounters(ctx, budget, map[string]int64{
+		counterTokens: int64(result.InputTokens + result.OutputTokens),
+		counterRuns:   1,
+	}, budgetDuration); err != nil {
+		logging.Errorf("[SHADOW SERVICE] error recording tokens spent: %v\n", err)
+	}
+
+	value, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreShadow, err)
+	}
+	if err := s.store.Put(ctx, resultPrefix+result.ID, value, s.retention); err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreShadow, err)
+	}
+	return nil
+}
+
+// EraseAuthor deletes the results of the haiku credited to author, matched
+// exactly, and returns how many it deleted. Only the context's tenant's
+// results are deleted, unless the operator asks outside any tenant, when
+// every tenant's are.
+func (s *ShadowService) EraseAuthor(ctx context.Context, author string) (int, error) {
+	hash := hashHex(author)
+	tenant, every := keys.TenantFromContext(ctx), keys.EveryTenant(ctx)
+
+	var found []string
+	err := s.store.ScanPrefix(ctx, resultPrefix, func(key string, value []byte) error {
+		var result Result
+		if err := json.Unmarshal(value, &result); err != nil {
+			logging.Warnf("[SHADOW SERVICE] skipping unreadable %s: %v\n", key, err)
+			return nil
+		}
+		if result.Served.Author == hash && (every || result.Served.Tenant == tenant) {
+			found = append(found, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrStoreShadow, err)
+	}
+
+	for i, key := range found {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return i, fmt.Errorf("%w: %w", ErrStoreShadow, err)
+		}
+	}
+	return len(found), nil
+}
+
+// served describes request's haiku as kept with its shadow, hashing what
+// would identify the commit or its author.
+func served(request haiku.ShadowRequest) Served {
+	kept := Served{
+		HaikuID:       request.HaikuID,
+		Tenant:        request.Tenant,
+		CommitHash:    hashHex(request.CommitMessage),
+		Mood:          request.Mood,
+		Register:      request.Register,
+		Haiku:         request.Haiku,
+		Model:         request.Model,
+		PromptVersion: request.PromptVersion,
+	}
+	if request.Author != "" {
+		kept.Author = hashHex(request.Author)
+	}
+	if request.Repository != nil {
+		kept.Repository = request.Repository.Name
+	}
+	return kept
+}
+
+func hashHex(text string) string {
+	hash := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(hash[:])
+}
+
+// budgetKey names the counters of the tokens spent on day.
+func budgetKey(day time.Time) string {
+	return "shadowBudget:" + day.UTC().Format(dayFormat)
+}
+
+func newResultID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func roll(n int) int {
+	return mathrand.IntN(n) // #nosec G404 -- sampling is not security sensitive
+}
@@ -0,0 +1,312 @@
+package shadow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/prompt"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockBedrockClient struct {
+	ErrorToReturn error
+	Calls         int
+	LastPrompt    string
+	LastOptions   *bedrock.ClaudeOptions
+}
+
+func (m *MockBedrockClient) InvokeClaude(ctx context.Context, prompt string, opts *bedrock.ClaudeOptions) (bedrock.ClaudeResult, error) {
+	m.Calls++
+	m.LastPrompt = prompt
+	m.LastOptions = opts
+	if m.ErrorToReturn != nil {
+		return bedrock.ClaudeResult{}, m.ErrorToReturn
+	}
+	return bedrock.ClaudeResult{
+		Text:    testHaiku,
+		ModelID: opts.ModelID,
+		Usage:   bedrock.Usage{InputTokens: 120, OutputTokens: 30},
+	}, nil
+}
+
+type MockScheduler struct {
+	ErrorToReturn error
+	Scheduled     []haiku.ShadowRequest
+}
+
+func (m *MockScheduler) Schedule(ctx context.Context, request haiku.ShadowRequest) error {
+	m.Scheduled = append(m.Scheduled, request)
+	return m.ErrorToReturn
+}
+
+type MockStore struct {
+	ErrorToReturn error
+}
+
+func (m *MockStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return m.ErrorToReturn
+}
+
+func (m *MockStore) Delete(ctx context.Context, key string) error {
+	return m.ErrorToReturn
+}
+
+func (m *MockStore) ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error {
+	return m.ErrorToReturn
+}
+
+func (m *MockStore) AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error) {
+	return nil, m.ErrorToReturn
+}
+
+func (m *MockStore) GetCounters(ctx context.Context, key string) (map[string]int64, error) {
+	return nil, m.ErrorToReturn
+}
+
+func TestSample(t *testing.T) {
+	tests := []struct {
+		name           string
+		percent        int
+		roll           int
+		scheduleError  error
+		expectSchedule bool
+	}{
+		{name: "Off by default", roll: 0},
+		{name: "Within the share", percent: 10, roll: 9, expectSchedule: true},
+		{name: "Outside the share", percent: 10, roll: 10},
+		// A failure to schedule never reaches the request.
+		{name: "Scheduling fails", percent: 100, roll: 50, scheduleError: errors.New("throttled"), expectSchedule: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler := &MockScheduler{ErrorToReturn: tc.scheduleError}
+			service := NewShadowService(&MockBedrockClient{}, NewMemoryStore(), scheduler, &Options{Percent: tc.percent})
+			service.roll = func(n int) int { return tc.roll }
+
+			service.Sample(context.Background(), haiku.ShadowRequest{HaikuID: "abc"})
+			if (len(scheduler.Scheduled) == 1) != tc.expectSchedule {
+				t.Errorf("Expected scheduled %v, got %v", tc.expectSchedule, scheduler.Scheduled)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	now := time.Date(2025, 10, 9, 14, 0, 0, 0, time.UTC)
+	prompts := prompt.NewStaticStore(prompt.Definitions{
+		Version: "v3",
+		System:  "You write terse haiku.",
+		Prompt:  "Commit: {{.CommitMessage}}",
+	})
+	request := haiku.ShadowRequest{
+		HaikuID:       "abc",
+		Tenant:        "acme",
+		Author:        "Mona",
+		Mood:          haiku.MoodTechnical,
+		Register:      haiku.RegisterFormal,
+		CommitMessage: "fix: resolved login issue",
+		Repository:    &prompt.Repository{Name: "octo/leaves", Description: "Commit haiku"},
+		Haiku:         "leaves fall",
+		Model:         bedrock.ClaudeModelID,
+		PromptVersion: "v1",
+	}
+	expectedServed := Served{
+		HaikuID:       "abc",
+		Tenant:        "acme",
+		Author:        hashHex("Mona"),
+		CommitHash:    hashHex("fix: resolved login issue"),
+		Mood:          haiku.MoodTechnical,
+		Register:      haiku.RegisterFormal,
+		Repository:    "octo/leaves",
+		Haiku:         "leaves fall",
+		Model:         bedrock.ClaudeModelID,
+		PromptVersion: "v1",
+	}
+
+	tests := []struct {
+		name          string
+		modelError    error
+		spent         int64
+		expectCalls   int
+		expectResult  bool
+		expectError   string
+		expectedSpent int64
+	}{
+		{
+			name:          "Written",
+			expectCalls:   1,
+			expectResult:  true,
+			expectedSpent: 150,
+		},
+		{
+			name:          "Model fails",
+			modelError:    errors.New("throttled"),
+			expectCalls:   1,
+			expectResult:  true,
+			expectError:   "throttled",
+			expectedSpent: 0,
+		},
+		{
+			name:          "Budget spent",
+			spent:         1000,
+			expectedSpent: 1000,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			modelClient := &MockBedrockClient{ErrorToReturn: tc.modelError}
+			store := NewMemoryStore()
+			if tc.spent > 0 {
+				store.AddCounters(context.Background(), budgetKey(now), map[string]int64{counterTokens: tc.spent}, time.Hour)
+			}
+			service := NewShadowService(modelClient, store, &MockScheduler{}, &Options{
+				Prompts:     prompts,
+				ModelID:     "us.anthropic.claude-sonnet-4-5-20250929-v1:0",
+				Percent:     100,
+				DailyTokens: 1000,
+			})
+			service.now = func() time.Time { return now }
+
+			if err := service.Run(context.Background(), request); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if modelClient.Calls != tc.expectCalls {
+				t.Fatalf("Expected %d model calls, got %d", tc.expectCalls, modelClient.Calls)
+			}
+			spent, _ := store.GetCounters(context.Background(), budgetKey(now))
+			if spent[counterTokens] != tc.expectedSpent {
+				t.Errorf("Expected %d tokens spent, got %d", tc.expectedSpent, spent[counterTokens])
+			}
+
+			var results []Result
+			err := store.ScanPrefix(context.Background(), "shadow:", func(key string, value []byte) error {
+				if strings.Contains(string(value), "resolved") || strings.Contains(string(value), "Mona") {
+					t.Errorf("Expected the commit and author kept only as hashes, got %s", value)
+				}
+				var result Result
+				if err := json.Unmarshal(value, &result); err != nil {
+					return err
+				}
+				results = append(results, result)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Failed to read results: %v", err)
+			}
+			if !tc.expectResult {
+				if len(results) != 0 {
+					t.Errorf("Expected no result, got %+v", results)
+				}
+				return
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected one result, got %d", len(results))
+			}
+
+			if modelClient.LastPrompt != "Commit: fix: resolved login issue" {
+				t.Errorf("Expected the alternate prompt, got %q", modelClient.LastPrompt)
+			}
+			if modelClient.LastOptions.System != "You write terse haiku.\n\n"+haiku.RegisterGuidance[haiku.RegisterFormal]+"\n" {
+				t.Errorf("Expected the alternate system prompt with the register, got %q", modelClient.LastOptions.System)
+			}
+
+			result := results[0]
+			if !reflect.DeepEqual(result.Served, expectedServed) || result.PromptVersion != "v3" || result.Model != "us.anthropic.claude-sonnet-4-5-20250929-v1:0" {
+				t.Errorf("Expected the served haiku kept with the shadow's prompt and model, got %+v", result)
+			}
+			if result.Error != tc.expectError {
+				t.Errorf("Expected error %q, got %q", tc.expectError, result.Error)
+			}
+			if tc.expectError == "" && (result.Haiku != testHaiku || !result.Valid) {
+				t.Errorf("Expected a valid shadow haiku, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestRunStoreErrors(t *testing.T) {
+	store := &MockStore{ErrorToReturn: errors.New("table not found")}
+	service := NewShadowService(&MockBedrockClient{}, store, &MockScheduler{}, nil)
+
+	if err := service.Run(context.Background(), haiku.ShadowRequest{CommitMessage: "fix"}); !errors.Is(err, ErrRunShadow) {
+		t.Errorf("Expected ErrRunShadow, got %v", err)
+	}
+}
+
+func TestEraseAuthor(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewShadowService(&MockBedrockClient{}, store, &MockScheduler{}, &Options{Percent: 100})
+	for _, request := range []haiku.ShadowRequest{
+		{Tenant: "acme", Author: "Mona", CommitMessage: "fix typo"},
+		{Tenant: "acme", Author: "Hubot", CommitMessage: "fix typo"},
+		{Tenant: "globex", Author: "Mona", CommitMessage: "fix typo"},
+		{Author: "Mona", CommitMessage: "fix typo"},
+		{CommitMessage: "fix typo"},
+	} {
+		if err := service.Run(context.Background(), request); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name           string
+		ctx            context.Context
+		expectedErased int
+		expectedKept   int
+	}{
+		{
+			name:           "Tenant's results only",
+			ctx:            keys.NewTenantContext(context.Background(), "acme"),
+			expectedErased: 1,
+			expectedKept:   4,
+		},
+		{
+			name:           "Default tenant's results only",
+			ctx:            context.Background(),
+			expectedErased: 1,
+			expectedKept:   3,
+		},
+		{
+			name:           "Every tenant's results, for the operator",
+			ctx:            keys.NewOperatorContext(context.Background()),
+			expectedErased: 1,
+			expectedKept:   2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			erased, err := service.EraseAuthor(tc.ctx, "Mona")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if erased != tc.expectedErased {
+				t.Errorf("Expected %d results erased, got %d", tc.expectedErased, erased)
+			}
+
+			kept := 0
+			_ = store.ScanPrefix(context.Background(), "shadow:", func(key string, value []byte) error {
+				kept++
+				return nil
+			})
+			if kept != tc.expectedKept {
+				t.Errorf("Expected %d results kept, got %d", tc.expectedKept, kept)
+			}
+		})
+	}
+
+	if _, err := NewShadowService(&MockBedrockClient{}, &MockStore{ErrorToReturn: errors.New("table not found")}, &MockScheduler{}, nil).EraseAuthor(context.Background(), "Mona"); !errors.Is(err, ErrStoreShadow) {
+		t.Errorf("Expected ErrStoreShadow, got %v", err)
+	}
+}
@@ -0,0 +1,168 @@
+// Package slack answers the /haiku slash command. Slack waits only three
+// seconds for a reply, which is less than generation can take, so commands are
+// acknowledged straight away and the haiku is posted to the command's response
+// URL once it is ready.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+// responseHost is the only host Slack issues response URLs on. Refusing other
+// hosts keeps a forged command from turning the service into a proxy.
+const responseHost = "hooks.slack.com"
+
+var (
+	ErrBadCommand = errors.New("bad slack command received")
+	ErrRespond    = errors.New("error posting slack response")
+)
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Command is a verified slash command invocation. It is serialized when the
+// response is deferred to another invocation.
+type Command struct {
+	Text        string `json:"text"`
+	ResponseURL string `json:"responseUrl"`
+	UserID      string `json:"userId,omitempty"`
+}
+
+// Validate checks that the command has text and a Slack response URL.
+func (c Command) Validate() error {
+	if strings.TrimSpace(c.Text) == "" {
+		return fmt.Errorf("%w: text is required", ErrBadCommand)
+	}
+
+	responseURL, err := url.Parse(c.ResponseURL)
+	if err != nil || responseURL.Scheme != "https" || responseURL.Host != responseHost {
+		return fmt.Errorf("%w: response url %q is not a slack url", ErrBadCommand, c.ResponseURL)
+	}
+	return nil
+}
+
+// Message is a slash command response.
+type Message struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+type SlackService struct {
+	haikuService HaikuService
+	httpClient   HTTPClient
+}
+
+func NewSlackService(haikuService HaikuService, httpClient HTTPClient) *SlackService {
+	return &SlackService{
+		haikuService: haikuService,
+		httpClient:   httpClient,
+	}
+}
+
+func NewDefaultSlackService(haikuService HaikuService) *SlackService {
+	return NewSlackService(haikuService, &http.Client{Timeout: 10 * time.Second})
+}
+
+// Respond generates a haiku for the command and posts it to the channel. When
+// generation fails the user is told privately and the generation error is
+// returned.
+func (s *SlackService) Respond(ctx context.Context, command Command) error {
+	if err := command.Validate(); err != nil {
+		logging.Warnf("[SLACK SERVICE] %v\n", err)
+		return err
+	}
+
+	response, err := s.haikuService.CreateHaiku(ctx, haiku.HaikuCommitRequest{
+		CommitMessage: command.Text,
+	})
+	if err != nil {
+		logging.Errorf("[SLACK SERVICE] error generating haiku: %v\n", err)
+		return errors.Join(err, s.post(ctx, command.ResponseURL, errorMessage(err)))
+	}
+
+	return s.post(ctx, command.ResponseURL, Message{
+		ResponseType: "in_channel",
+		Text:         FormatMessage(response.Haiku, command),
+	})
+}
+
+func (s *SlackService) post(ctx context.Context, responseURL string, message Message) error {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("%w: encoding message: %v", ErrRespond, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRespond, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		logging.Errorf("[SLACK SERVICE] error posting response: %v\n", err)
+		return fmt.Errorf("%w: %v", ErrRespond, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		logging.Errorf("[SLACK SERVICE] response url returned %d\n", resp.StatusCode)
+		return fmt.Errorf("%w: response url returned %d: %s", ErrRespond, resp.StatusCode, bytes.TrimSpace(detail))
+	}
+
+	return nil
+}
+
+// errorMessage tells only the user who ran the command that it failed.
+func errorMessage(err error) Message {
+	text := "Sorry, the haiku could not be written right now. Please try again."
+	if errors.Is(err, haiku.ErrContentBlocked) {
+		text = "Sorry, that haiku was blocked by the content filter."
+	} else if errors.Is(err, haiku.ErrBadHaikuRequest) {
+		text = "Sorry, a haiku cannot be written for that message."
+	}
+
+	return Message{
+		ResponseType: "ephemeral",
+		Text:         text,
+	}
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// FormatMessage renders a haiku as a Slack quote, followed by who asked and
+// for which message.
+func FormatMessage(text string, command Command) string {
+	var message strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		message.WriteString(">")
+		message.WriteString(escaper.Replace(strings.TrimSpace(line)))
+		message.WriteString("\n")
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(command.Text), "\n")
+	if command.UserID != "" {
+		fmt.Fprintf(&message, "🍂 <@%s>: %s", command.UserID, escaper.Replace(subject))
+	} else {
+		fmt.Fprintf(&message, "🍂 %s", escaper.Replace(subject))
+	}
+	return message.String()
+}
@@ -0,0 +1,135 @@
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const (
+	testHaiku       = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+	testResponseURL = "https://hooks.slack.com/commands/T1/2/abc"
+)
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+	LastRequest      haiku.HaikuCommitRequest
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.LastRequest = request
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+type MockHTTPClient struct {
+	StatusToReturn int
+	LastURL        string
+	LastMessage    *Message
+}
+
+func (m *MockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	m.LastURL = req.URL.String()
+	var message Message
+	_ = json.NewDecoder(req.Body).Decode(&message)
+	m.LastMessage = &message
+
+	status := m.StatusToReturn
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &http.Response{StatusCode: status, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+}
+
+func TestRespond(t *testing.T) {
+	command := Command{Text: "fix: resolved login issue", ResponseURL: testResponseURL, UserID: "U123"}
+
+	tests := []struct {
+		name                 string
+		command              Command
+		haikuError           error
+		status               int
+		expectedResponseType string
+		expectedText         string
+		errorIs              error
+	}{
+		{
+			name:                 "Haiku posted to channel",
+			command:              command,
+			expectedResponseType: "in_channel",
+			expectedText:         FormatMessage(testHaiku, command),
+		},
+		{
+			name:                 "Blocked haiku reported privately",
+			command:              command,
+			haikuError:           haiku.ErrContentBlocked,
+			expectedResponseType: "ephemeral",
+			expectedText:         "Sorry, that haiku was blocked by the content filter.",
+			errorIs:              haiku.ErrContentBlocked,
+		},
+		{
+			name:    "Response url rejected",
+			command: command,
+			status:  http.StatusNotFound,
+			errorIs: ErrRespond,
+		},
+		{
+			name:    "Non-slack response url",
+			command: Command{Text: "fix: resolved login issue", ResponseURL: "https://169.254.169.254/latest"},
+			errorIs: ErrBadCommand,
+		},
+		{
+			name:    "Empty text",
+			command: Command{Text: " ", ResponseURL: testResponseURL},
+			errorIs: ErrBadCommand,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku},
+				ErrorToReturn:    tc.haikuError,
+			}
+			httpClient := &MockHTTPClient{StatusToReturn: tc.status}
+
+			err := NewSlackService(haikuService, httpClient).Respond(context.Background(), tc.command)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				if errors.Is(tc.errorIs, ErrBadCommand) && httpClient.LastMessage != nil {
+					t.Errorf("Expected nothing to be posted for a bad command, got %+v", httpClient.LastMessage)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if tc.expectedText == "" {
+				return
+			}
+			if httpClient.LastURL != testResponseURL {
+				t.Errorf("Expected the response to be posted to %s, got %s", testResponseURL, httpClient.LastURL)
+			}
+			if httpClient.LastMessage.ResponseType != tc.expectedResponseType || httpClient.LastMessage.Text != tc.expectedText {
+				t.Errorf("Expected %s message %q, got %+v", tc.expectedResponseType, tc.expectedText, httpClient.LastMessage)
+			}
+		})
+	}
+}
+
+func TestFormatMessage(t *testing.T) {
+	command := Command{Text: "fix: <script> & friends\n\nlong body", UserID: "U123"}
+	expected := ">Old cracks mended now\n>the login door swings open\n>quiet in the logs\n🍂 <@U123>: fix: &lt;script&gt; &amp; friends"
+
+	if got := FormatMessage(testHaiku, command); got != expected {
+		t.Errorf("Expected message %q, got %q", expected, got)
+	}
+}
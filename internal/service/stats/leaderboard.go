@@ -0,0 +1,154 @@
+package stats
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultLeaderboardSize is how many entries a leaderboard lists when no
+	// limit is asked for.
+	DefaultLeaderboardSize = 10
+	// MaxLeaderboardSize is the most entries a leaderboard lists.
+	MaxLeaderboardSize = 100
+)
+
+// Board is what a leaderboard ranks.
+type Board string
+
+const (
+	BoardRepositories Board = "repositories"
+	BoardAuthors      Board = "authors"
+)
+
+// Boards lists every board.
+var Boards = []Board{BoardRepositories, BoardAuthors}
+
+func (b Board) IsValid() bool {
+	return slices.Contains(Boards, b)
+}
+
+// Ranking is how a leaderboard's entries are ordered.
+type Ranking string
+
+const (
+	RankingHaiku  Ranking = "haiku"  // Most haiku
+	RankingStreak Ranking = "streak" // Longest run of consecutive days with a haiku
+	RankingVotes  Ranking = "votes"  // Most votes for their haiku
+)
+
+// Rankings lists every ranking, in the order ties are broken.
+var Rankings = []Ranking{RankingHaiku, RankingStreak, RankingVotes}
+
+func (r Ranking) IsValid() bool {
+	return slices.Contains(Rankings, r)
+}
+
+// Leaderboard ranks repositories or authors over a range of days.
+type Leaderboard struct {
+	Board   Board   `json:"board"`
+	Ranking Ranking `json:"ranking"`
+	From    string  `json:"from"` // First day covered, as YYYY-MM-DD in UTC
+	To      string  `json:"to"`   // Last day covered, today
+	Entries []Entry `json:"entries"`
+}
+
+// Entry is one repository or author on a leaderboard.
+type Entry struct {
+	Rank   int    `json:"rank"`
+	Name   string `json:"name"`
+	Haiku  int64  `json:"haiku"`
+	Streak int    `json:"streak"` // Longest run of consecutive days with a haiku
+	Votes  int64  `json:"votes"`  // Cast on the days covered, for haiku served on any day
+}
+
+// measure returns the entry's standing by ranking.
+func (e Entry) measure(ranking Ranking) int64 {
+	switch ranking {
+	case RankingStreak:
+		return int64(e.Streak)
+	case RankingVotes:
+		return e.Votes
+	default:
+		return e.Haiku
+	}
+}
+
+// Leaderboard ranks the repositories or authors of the last days, up to and
+// including today, listing at most limit entries. Ties are ranked by the
+// other measures, in the order of Rankings, then by name.
+func (s *StatsService) Leaderboard(ctx context.Context, board Board, ranking Ranking, days int, limit int) (Leaderboard, error) {
+	window, err := s.window(ctx, days)
+	if err != nil {
+		return Leaderboard{}, err
+	}
+
+	prefix, votesPrefix := repositoryPrefix, repositoryVotesPrefix
+	if board == BoardAuthors {
+		prefix, votesPrefix = authorPrefix, authorVotesPrefix
+	}
+
+	// Streaks are counted as the days go by: a name missing a day starts over.
+	entries := make(map[string]*Entry)
+	entry := func(name string) *Entry {
+		if _, ok := entries[name]; !ok {
+			entries[name] = &Entry{Name: name}
+		}
+		return entries[name]
+	}
+	current := make(map[string]int)
+	for _, day := range window {
+		for name, count := range day.counters {
+			if count <= 0 {
+				continue
+			}
+			if name, ok := strings.CutPrefix(name, prefix); ok {
+				entry(name).Haiku += count
+			}
+			if name, ok := strings.CutPrefix(name, votesPrefix); ok {
+				entry(name).Votes += count
+			}
+		}
+
+		for name, entry := range entries {
+			if day.counters[prefix+name] > 0 {
+				current[name]++
+				entry.Streak = max(entry.Streak, current[name])
+			} else {
+				current[name] = 0
+			}
+		}
+	}
+
+	ranked := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		ranked = append(ranked, *entry)
+	}
+	slices.SortFunc(ranked, func(a, b Entry) int {
+		if order := cmp.Compare(b.measure(ranking), a.measure(ranking)); order != 0 {
+			return order
+		}
+		for _, tiebreak := range Rankings {
+			if order := cmp.Compare(b.measure(tiebreak), a.measure(tiebreak)); order != 0 {
+				return order
+			}
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	limit = max(1, min(limit, MaxLeaderboardSize))
+	ranked = ranked[:min(limit, len(ranked))]
+	for i := range ranked {
+		ranked[i].Rank = i + 1
+	}
+
+	return Leaderboard{
+		Board:   board,
+		Ranking: ranking,
+		From:    window[0].date,
+		To:      window[len(window)-1].date,
+		Entries: ranked,
+	}, nil
+}
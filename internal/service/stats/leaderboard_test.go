@@ -0,0 +1,135 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+func TestLeaderboard(t *testing.T) {
+	today := time.Date(2025, 10, 10, 18, 0, 0, 0, time.UTC)
+	service := NewStatsService(NewMemoryStore(), nil)
+	service.now = func() time.Time { return today }
+
+	// octo/leaves writes the most haiku, in two bursts; octo/roots writes
+	// fewer, every day.
+	served := map[string][]int{
+		"octo/leaves": {0, 0, 0, 0, 4, 4},
+		"octo/roots":  {0, 1, 2, 3, 4},
+		"octo/bark":   {5},
+	}
+	for repository, daysAgo := range served {
+		for _, ago := range daysAgo {
+			usage := haiku.Usage{Time: today.AddDate(0, 0, -ago), Mood: haiku.MoodTechnical, Repository: repository, Author: "Mona"}
+			if err := service.Record(context.Background(), usage); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+		}
+	}
+
+	// octo/bark's only haiku, five days ago, gets two votes today.
+	votes := []haiku.Stored{
+		{Repository: "octo/bark", Author: "Mona"},
+		{Repository: "octo/bark", Author: "Mona"},
+		{Repository: "octo/roots", Author: "Mona"},
+	}
+	for _, voted := range votes {
+		if err := service.RecordVote(context.Background(), voted); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		board    Board
+		ranking  Ranking
+		days     int
+		limit    int
+		expected []Entry
+	}{
+		{
+			name:    "Most haiku",
+			board:   BoardRepositories,
+			ranking: RankingHaiku,
+			days:    7,
+			limit:   10,
+			expected: []Entry{
+				{Rank: 1, Name: "octo/leaves", Haiku: 6, Streak: 1},
+				{Rank: 2, Name: "octo/roots", Haiku: 5, Streak: 5, Votes: 1},
+				{Rank: 3, Name: "octo/bark", Haiku: 1, Streak: 1, Votes: 2},
+			},
+		},
+		{
+			name:    "Longest streak",
+			board:   BoardRepositories,
+			ranking: RankingStreak,
+			days:    7,
+			limit:   2,
+			expected: []Entry{
+				{Rank: 1, Name: "octo/roots", Haiku: 5, Streak: 5, Votes: 1},
+				{Rank: 2, Name: "octo/leaves", Haiku: 6, Streak: 1},
+			},
+		},
+		{
+			name:    "Window",
+			board:   BoardRepositories,
+			ranking: RankingHaiku,
+			days:    2,
+			limit:   10,
+			expected: []Entry{
+				{Rank: 1, Name: "octo/leaves", Haiku: 4, Streak: 1},
+				{Rank: 2, Name: "octo/roots", Haiku: 2, Streak: 2, Votes: 1},
+				{Rank: 3, Name: "octo/bark", Votes: 2},
+			},
+		},
+		{
+			name:    "Authors",
+			board:   BoardAuthors,
+			ranking: RankingStreak,
+			days:    7,
+			limit:   10,
+			expected: []Entry{
+				{Rank: 1, Name: "Mona", Haiku: 12, Streak: 6, Votes: 3},
+			},
+		},
+		{
+			name:    "Most votes",
+			board:   BoardRepositories,
+			ranking: RankingVotes,
+			days:    7,
+			limit:   10,
+			expected: []Entry{
+				{Rank: 1, Name: "octo/bark", Haiku: 1, Streak: 1, Votes: 2},
+				{Rank: 2, Name: "octo/roots", Haiku: 5, Streak: 5, Votes: 1},
+				{Rank: 3, Name: "octo/leaves", Haiku: 6, Streak: 1},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			leaderboard, err := service.Leaderboard(context.Background(), tc.board, tc.ranking, tc.days, tc.limit)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(leaderboard.Entries, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, leaderboard.Entries)
+			}
+			if leaderboard.To != "2025-10-10" {
+				t.Errorf("Expected the leaderboard to end today, got %s", leaderboard.To)
+			}
+		})
+	}
+}
+
+func TestLeaderboardStoreError(t *testing.T) {
+	service := NewStatsService(&MockStore{ErrorToReturn: errors.New("table not found")}, nil)
+
+	if _, err := service.Leaderboard(context.Background(), BoardRepositories, RankingHaiku, DefaultDays, DefaultLeaderboardSize); !errors.Is(err, ErrGetStats) {
+		t.Errorf("Expected ErrGetStats, got %v", err)
+	}
+}
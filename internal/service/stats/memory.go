@@ -0,0 +1,11 @@
+package stats
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps usage counters in memory for a single server. Each day's
+// counters expire with the retention, as they do in DynamoDB.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
@@ -0,0 +1,421 @@
+// Package stats keeps usage statistics for commit haiku as daily counters,
+// and summarizes them over a range of days. Each tenant's statistics are
+// counted apart. Haiku are counted as they are served, or, when aggregated,
+// as they are kept, from the stream of the table keeping them, so that
+// serving a haiku doesn't wait on its counters. Aggregated counts differ from
+// served ones: each variant counts as a haiku of its own, and fallback haiku,
+// which aren't kept, aren't counted.
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	// DefaultDays is the range a summary covers when none is asked for.
+	DefaultDays = 7
+	// MaxDays is the longest range a summary can cover. Counters are kept
+	// for as long, then expire.
+	MaxDays = 90
+
+	retention = MaxDays * 24 * time.Hour
+	dayFormat = "2006-01-02"
+	keyPrefix = "stats:"
+	// countedTTL is how long a kept haiku is remembered as counted. Streams
+	// keep records for a day, so no record is delivered again after it.
+	countedTTL = 48 * time.Hour
+)
+
+var (
+	ErrRecordUsage = errors.New("error recording usage")
+	ErrRecordVote  = errors.New("error recording vote")
+	ErrGetStats    = errors.New("error getting stats")
+	ErrEraseStats  = errors.New("error erasing stats")
+)
+
+// Counter names in each day's item. Moods, repositories, authors, prompt
+// versions and models are counted under their own prefixed names, e.g. "mood:technical"
+// or "repo:octo/leaves", and so are the votes cast for their haiku, e.g.
+// "repoVotes:octo/leaves".
+const (
+	counterHaiku          = "haiku"
+	counterCached         = "cached"
+	counterLatencyMs      = "latencyMs"
+	counterLatencySamples = "latencySamples"
+	counterInputTokens    = "inputTokens"
+	counterOutputTokens   = "outputTokens"
+	counterVariants       = "variants"
+	counterVotes          = "votes"
+	moodPrefix            = "mood:"
+	repositoryPrefix      = "repo:"
+	authorPrefix          = "author:"
+	promptPrefix          = "prompt:"
+	modelPrefix           = "model:"
+	repositoryVotesPrefix = "repoVotes:"
+	authorVotesPrefix     = "authorVotes:"
+	promptVotesPrefix     = "promptVotes:"
+	modelVotesPrefix      = "modelVotes:"
+)
+
+// Store keeps named counters per key, e.g. in DynamoDB. BatchGetCounters
+// reads the counters of many keys at once, leaving out keys without any, so
+// that a summary reads every day it covers together. ScanCounters and
+// RemoveCounters find and remove the counters of an author being erased.
+// PutIfAbsent marks a kept haiku counted, so that a stream record delivered
+// again isn't, and Delete unmarks it when counting fails.
+type Store interface {
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	Delete(ctx context.Context, key string) error
+	AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error)
+	BatchGetCounters(ctx context.Context, keys []string) (map[string]map[string]int64, error)
+	ScanCounters(ctx context.Context, prefix string, fn func(key string, counters map[string]int64) error) error
+	RemoveCounters(ctx context.Context, key string, names []string) error
+}
+
+// Stats summarizes the haiku served over a range of days.
+type Stats struct {
+	From             string                   `json:"from"` // First day covered, as YYYY-MM-DD in UTC
+	To               string                   `json:"to"`   // Last day covered, today
+	Haiku            int64                    `json:"haiku"`
+	Cached           int64                    `json:"cached"` // Served from the response cache
+	Days             []Day                    `json:"days"`
+	Moods            map[string]int64         `json:"moods"`
+	Repositories     map[string]int64         `json:"repositories"`
+	Authors          map[string]int64         `json:"authors"`
+	Votes            int64                    `json:"votes"`            // Cast on the days covered, for haiku served on any day
+	PromptVersions   map[string]PromptVersion `json:"promptVersions"`   // Keyed by prompt template version
+	Models           map[string]Model         `json:"models"`           // Keyed by model ID
+	AverageLatencyMs int64                    `json:"averageLatencyMs"` // Mean time generating a haiku that wasn't cached
+	InputTokens      int64                    `json:"inputTokens"`
+	OutputTokens     int64                    `json:"outputTokens"`
+	Variants         int64                    `json:"variants"` // Candidate haiku written for requests asking for more than one
+}
+
+// PromptVersion compares the haiku written with one prompt template version
+// to the votes cast for them.
+type PromptVersion struct {
+	Haiku int64 `json:"haiku"`
+	Votes int64 `json:"votes"`
+}
+
+// Model compares the haiku written by one model, e.g. a canary, to the votes
+// cast for them.
+type Model struct {
+	Haiku int64 `json:"haiku"`
+	Votes int64 `json:"votes"`
+}
+
+// Day counts the haiku served on one day.
+type Day struct {
+	Date  string `json:"date"`
+	Haiku int64  `json:"haiku"`
+}
+
+type StatsService struct {
+	store      Store
+	aggregated bool
+	now        func() time.Time
+}
+
+type Options struct {
+	Aggregated bool // Haiku are counted by RecordKept from the stream of the table keeping them, and Record counts only what serving them cost (default: false, Record counts both)
+}
+
+func NewStatsService(store Store, opts *Options) *StatsService {
+	service := &StatsService{
+		store: store,
+		now:   time.Now,
+	}
+
+	if opts != nil {
+		service.aggregated = opts.Aggregated
+	}
+
+	return service
+}
+
+// haikuDeltas counts one haiku, its mood, repository, author, prompt version
+// and model.
+func haikuDeltas(mood haiku.Mood, repository, author, promptVersion, model string) map[string]int64 {
+	deltas := map[string]int64{
+		counterHaiku:              1,
+		moodPrefix + string(mood): 1,
+	}
+	if repository != "" {
+		deltas[repositoryPrefix+repository] = 1
+	}
+	if author != "" {
+		deltas[authorPrefix+author] = 1
+	}
+	if promptVersion != "" {
+		deltas[promptPrefix+promptVersion] = 1
+	}
+	if model != "" {
+		deltas[modelPrefix+model] = 1
+	}
+	return deltas
+}
+
+// Record counts one haiku served against the day it was served on. When
+// aggregated, the haiku itself is left to RecordKept, and only whether it was
+// cached, its latency, tokens and variants are counted.
+func (s *StatsService) Record(ctx context.Context, usage haiku.Usage) error {
+	deltas := make(map[string]int64)
+	if !s.aggregated {
+		deltas = haikuDeltas(usage.Mood, usage.Repository, usage.Author, usage.PromptVersion, usage.Model)
+	}
+	if usage.Cached {
+		deltas[counterCached] = 1
+	}
+	if usage.Latency > 0 {
+		deltas[counterLatencyMs] = usage.Latency.Milliseconds()
+		deltas[counterLatencySamples] = 1
+	}
+	if usage.InputTokens > 0 {
+		deltas[counterInputTokens] = int64(usage.InputTokens)
+	}
+	if usage.OutputTokens > 0 {
+		deltas[counterOutputTokens] = int64(usage.OutputTokens)
+	}
+	if usage.Variants > 1 {
+		deltas[counterVariants] = int64(usage.Variants)
+	}
+
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	served := usage.Time
+	if served.IsZero() {
+		served = s.now()
+	}
+	if _, err := s.store.AddCounters(ctx, dayKey(ctx, served), deltas, retention); err != nil {
+		return fmt.Errorf("%w: %w", ErrRecordUsage, err)
+	}
+	return nil
+}
+
+// RecordKept counts the kept haiku id against the day it was written on, in
+// its tenant, e.g. as the stream of the table keeping haiku delivers it. Each
+// haiku is counted once, however often its record is delivered: it is marked
+// counted before its counters are added to, and unmarked if they can't be.
+func (s *StatsService) RecordKept(ctx context.Context, id string, kept haiku.Stored) error {
+	written := kept.CreatedAt
+	if written.IsZero() {
+		written = s.now()
+	}
+
+	ctx = keys.NewTenantContext(ctx, kept.Tenant)
+	marker := countedKey(ctx, id)
+	first, err := s.store.PutIfAbsent(ctx, marker, []byte(written.UTC().Format(time.RFC3339)), countedTTL)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrRecordUsage, err)
+	}
+	if !first {
+		logging.Infof("[STATS SERVICE] haiku %s was already counted\n", id)
+		return nil
+	}
+
+	deltas := haikuDeltas(kept.Mood, kept.Repository, kept.Author, kept.PromptVersion, kept.Model)
+	if _, err := s.store.AddCounters(ctx, dayKey(ctx, written), deltas, retention); err != nil {
+		if err := s.store.Delete(ctx, marker); err != nil {
+			logging.Errorf("[STATS SERVICE] error unmarking haiku %s, it won't be counted: %v\n", id, err)
+		}
+		return fmt.Errorf("%w: %w", ErrRecordUsage, err)
+	}
+	return nil
+}
+
+// RecordVote counts one vote for a stored haiku against today, crediting its
+// repository, author, prompt version and model.
+func (s *StatsService) RecordVote(ctx context.Context, voted haiku.Stored) error {
+	deltas := map[string]int64{counterVotes: 1}
+	if voted.Repository != "" {
+		deltas[repositoryVotesPrefix+voted.Repository] = 1
+	}
+	if voted.Author != "" {
+		deltas[authorVotesPrefix+voted.Author] = 1
+	}
+	if voted.PromptVersion != "" {
+		deltas[promptVotesPrefix+voted.PromptVersion] = 1
+	}
+	if voted.Model != "" {
+		deltas[modelVotesPrefix+voted.Model] = 1
+	}
+
+	if _, err := s.store.AddCounters(ctx, dayKey(ctx, s.now()), deltas, retention); err != nil {
+		return fmt.Errorf("%w: %w", ErrRecordVote, err)
+	}
+	return nil
+}
+
+// Summary totals the last days, up to and including today. A days outside 1
+// to MaxDays is clamped to that range.
+func (s *StatsService) Summary(ctx context.Context, days int) (Stats, error) {
+	window, err := s.window(ctx, days)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	stats := Stats{
+		From:           window[0].date,
+		To:             window[len(window)-1].date,
+		Days:           make([]Day, 0, len(window)),
+		Moods:          make(map[string]int64),
+		Repositories:   make(map[string]int64),
+		Authors:        make(map[string]int64),
+		PromptVersions: make(map[string]PromptVersion),
+		Models:         make(map[string]Model),
+	}
+
+	var latencyMs, latencySamples int64
+	for _, day := range window {
+		counters := day.counters
+		stats.Days = append(stats.Days, Day{Date: day.date, Haiku: counters[counterHaiku]})
+		for name, value := range counters {
+			switch {
+			case name == counterHaiku:
+				stats.Haiku += value
+			case name == counterCached:
+				stats.Cached += value
+			case name == counterLatencyMs:
+				latencyMs += value
+			case name == counterLatencySamples:
+				latencySamples += value
+			case name == counterInputTokens:
+				stats.InputTokens += value
+			case name == counterOutputTokens:
+				stats.OutputTokens += value
+			case name == counterVariants:
+				stats.Variants += value
+			case name == counterVotes:
+				stats.Votes += value
+			case strings.HasPrefix(name, moodPrefix):
+				stats.Moods[strings.TrimPrefix(name, moodPrefix)] += value
+			case strings.HasPrefix(name, repositoryPrefix):
+				stats.Repositories[strings.TrimPrefix(name, repositoryPrefix)] += value
+			case strings.HasPrefix(name, authorPrefix):
+				stats.Authors[strings.TrimPrefix(name, authorPrefix)] += value
+			case strings.HasPrefix(name, promptPrefix):
+				version := strings.TrimPrefix(name, promptPrefix)
+				counts := stats.PromptVersions[version]
+				counts.Haiku += value
+				stats.PromptVersions[version] = counts
+			case strings.HasPrefix(name, promptVotesPrefix):
+				version := strings.TrimPrefix(name, promptVotesPrefix)
+				counts := stats.PromptVersions[version]
+				counts.Votes += value
+				stats.PromptVersions[version] = counts
+			case strings.HasPrefix(name, modelPrefix):
+				model := strings.TrimPrefix(name, modelPrefix)
+				counts := stats.Models[model]
+				counts.Haiku += value
+				stats.Models[model] = counts
+			case strings.HasPrefix(name, modelVotesPrefix):
+				model := strings.TrimPrefix(name, modelVotesPrefix)
+				counts := stats.Models[model]
+				counts.Votes += value
+				stats.Models[model] = counts
+			}
+		}
+	}
+
+	if latencySamples > 0 {
+		stats.AverageLatencyMs = latencyMs / latencySamples
+	}
+	return stats, nil
+}
+
+// EraseAuthor removes the counters of author, matched exactly, and of the
+// votes cast for their haiku from every day kept, and returns how many days
+// held them. The days' other counters are left as they are, so totals still
+// count the author's haiku, but no longer name them. Only the context's
+// tenant's statistics are changed, unless the operator asks outside any
+// tenant, when every tenant's are.
+func (s *StatsService) EraseAuthor(ctx context.Context, author string) (int, error) {
+	prefix := keys.Namespace(ctx, keyPrefix)
+	if keys.EveryTenant(ctx) {
+		prefix = ""
+	}
+	names := []string{authorPrefix + author, authorVotesPrefix + author}
+
+	var found []string
+	err := s.store.ScanCounters(ctx, prefix, func(key string, counters map[string]int64) error {
+		if !isDayKey(key) {
+			return nil
+		}
+		for _, name := range names {
+			if _, ok := counters[name]; ok {
+				found = append(found, key)
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w", ErrEraseStats, err)
+	}
+
+	for i, key := range found {
+		if err := s.store.RemoveCounters(ctx, key, names); err != nil {
+			return i, fmt.Errorf("%w: %w", ErrEraseStats, err)
+		}
+	}
+	return len(found), nil
+}
+
+// dayCounters are the counters recorded on one day.
+type dayCounters struct {
+	date     string
+	counters map[string]int64
+}
+
+// window returns the counters of the last days, oldest first, up to and
+// including today. A days outside 1 to MaxDays is clamped to that range.
+func (s *StatsService) window(ctx context.Context, days int) ([]dayCounters, error) {
+	days = max(1, min(days, MaxDays))
+	today := s.now().UTC()
+
+	dayKeys := make([]string, 0, days)
+	for i := days - 1; i >= 0; i-- {
+		dayKeys = append(dayKeys, dayKey(ctx, today.AddDate(0, 0, -i)))
+	}
+	found, err := s.store.BatchGetCounters(ctx, dayKeys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrGetStats, err)
+	}
+
+	window := make([]dayCounters, 0, days)
+	for i, key := range dayKeys {
+		day := today.AddDate(0, 0, i-days+1)
+		window = append(window, dayCounters{date: day.Format(dayFormat), counters: found[key]})
+	}
+	return window, nil
+}
+
+// dayKey names the item counting a day's statistics in the tenant of ctx.
+func dayKey(ctx context.Context, t time.Time) string {
+	return keys.Namespace(ctx, keyPrefix+t.UTC().Format(dayFormat))
+}
+
+// countedKey names the item marking the kept haiku id counted in the tenant
+// of ctx.
+func countedKey(ctx context.Context, id string) string {
+	return keys.Namespace(ctx, "statsCounted:"+id)
+}
+
+// isDayKey reports whether key, of any tenant, counts a day's statistics.
+func isDayKey(key string) bool {
+	if rest, ok := strings.CutPrefix(key, "tenant:"); ok {
+		_, key, _ = strings.Cut(rest, ":")
+	}
+	return strings.HasPrefix(key, keyPrefix)
+}
@@ -0,0 +1,330 @@
+package stats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+type MockStore struct {
+	ErrorToReturn error
+	LastTTL       time.Duration
+}
+
+func (m *MockStore) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return m.ErrorToReturn == nil, m.ErrorToReturn
+}
+
+func (m *MockStore) Delete(ctx context.Context, key string) error {
+	return m.ErrorToReturn
+}
+
+func (m *MockStore) AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error) {
+	m.LastTTL = ttl
+	return nil, m.ErrorToReturn
+}
+
+func (m *MockStore) BatchGetCounters(ctx context.Context, keys []string) (map[string]map[string]int64, error) {
+	return nil, m.ErrorToReturn
+}
+
+func (m *MockStore) ScanCounters(ctx context.Context, prefix string, fn func(key string, counters map[string]int64) error) error {
+	return m.ErrorToReturn
+}
+
+func (m *MockStore) RemoveCounters(ctx context.Context, key string, names []string) error {
+	return m.ErrorToReturn
+}
+
+func TestSummary(t *testing.T) {
+	today := time.Date(2025, 10, 3, 18, 0, 0, 0, time.UTC)
+	yesterday := today.AddDate(0, 0, -1)
+
+	service := NewStatsService(NewMemoryStore(), nil)
+	service.now = func() time.Time { return today }
+
+	usages := []haiku.Usage{
+		{Time: yesterday, Mood: haiku.MoodTechnical, Repository: "octo/leaves", Author: "Mona", PromptVersion: "v2", Model: "canary", Latency: 1200 * time.Millisecond, InputTokens: 100, OutputTokens: 20},
+		{Time: today, Mood: haiku.MoodTechnical, Repository: "octo/leaves", Cached: true},
+		{Time: today, Mood: haiku.MoodReflective, Model: "haiku", Latency: 800 * time.Millisecond, InputTokens: 140, OutputTokens: 25, Variants: 3},
+		// Outside a two day summary
+		{Time: today.AddDate(0, 0, -2), Mood: haiku.MoodTechnical, Repository: "octo/roots"},
+	}
+	for _, usage := range usages {
+		if err := service.Record(context.Background(), usage); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	// Votes count on the day they are cast.
+	if err := service.RecordVote(context.Background(), haiku.Stored{Repository: "octo/leaves", Author: "Mona", PromptVersion: "v2", Model: "canary", CreatedAt: yesterday}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	stats, err := service.Summary(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	expected := Stats{
+		From:             "2025-10-02",
+		To:               "2025-10-03",
+		Haiku:            3,
+		Cached:           1,
+		Days:             []Day{{Date: "2025-10-02", Haiku: 1}, {Date: "2025-10-03", Haiku: 2}},
+		Moods:            map[string]int64{"technical": 2, "reflective": 1},
+		Repositories:     map[string]int64{"octo/leaves": 2},
+		Authors:          map[string]int64{"Mona": 1},
+		Votes:            1,
+		PromptVersions:   map[string]PromptVersion{"v2": {Haiku: 1, Votes: 1}},
+		Models:           map[string]Model{"canary": {Haiku: 1, Votes: 1}, "haiku": {Haiku: 1}},
+		AverageLatencyMs: 1000,
+		InputTokens:      240,
+		OutputTokens:     45,
+		Variants:         3,
+	}
+	if !reflect.DeepEqual(stats, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+}
+
+func TestSummaryByTenant(t *testing.T) {
+	service := NewStatsService(NewMemoryStore(), nil)
+	acme := keys.NewTenantContext(context.Background(), "acme")
+
+	if err := service.Record(acme, haiku.Usage{Mood: haiku.MoodTechnical}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if err := service.RecordVote(acme, haiku.Stored{Author: "Mona"}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		ctx           context.Context
+		expectedHaiku int64
+		expectedVotes int64
+	}{
+		{ctx: acme, expectedHaiku: 1, expectedVotes: 1},
+		{ctx: context.Background()},
+		{ctx: keys.NewTenantContext(context.Background(), "globex")},
+	}
+
+	for _, tc := range tests {
+		stats, err := service.Summary(tc.ctx, 1)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if stats.Haiku != tc.expectedHaiku || stats.Votes != tc.expectedVotes {
+			t.Errorf("Expected %d haiku and %d votes for tenant %q, got %+v", tc.expectedHaiku, tc.expectedVotes, keys.TenantFromContext(tc.ctx), stats)
+		}
+	}
+}
+
+func TestSummaryAggregated(t *testing.T) {
+	today := time.Date(2025, 10, 3, 18, 0, 0, 0, time.UTC)
+	service := NewStatsService(NewMemoryStore(), &Options{Aggregated: true})
+	service.now = func() time.Time { return today }
+
+	// Serving counts only what the haiku cost; the stream counts the haiku.
+	acme := keys.NewTenantContext(context.Background(), "acme")
+	if err := service.Record(acme, haiku.Usage{Time: today, Mood: haiku.MoodTechnical, Repository: "octo/leaves", Latency: time.Second, OutputTokens: 20}); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	kept := []haiku.Stored{
+		{Tenant: "acme", Mood: haiku.MoodTechnical, Repository: "octo/leaves", Model: "haiku", CreatedAt: today},
+		{Tenant: "acme", Mood: haiku.MoodReflective, Repository: "octo/leaves", CreatedAt: today.AddDate(0, 0, -1)},
+		{Mood: haiku.MoodTechnical, Repository: "octo/roots", CreatedAt: today},
+	}
+	for i, stored := range kept {
+		if err := service.RecordKept(context.Background(), fmt.Sprintf("id%d", i), stored); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+	// The stream may deliver a record again.
+	if err := service.RecordKept(context.Background(), "id0", kept[0]); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	stats, err := service.Summary(acme, 2)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if stats.Haiku != 2 || stats.Moods["technical"] != 1 || stats.Repositories["octo/leaves"] != 2 || stats.Models["haiku"].Haiku != 1 {
+		t.Errorf("Expected the tenant's kept haiku counted once each, got %+v", stats)
+	}
+	if stats.AverageLatencyMs != 1000 || stats.OutputTokens != 20 {
+		t.Errorf("Expected what serving cost counted, got %+v", stats)
+	}
+}
+
+// FailingCountersStore fails to add to counters once, so that a kept haiku
+// must be counted again.
+type FailingCountersStore struct {
+	*MemoryStore
+	failed bool
+}
+
+func (f *FailingCountersStore) AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error) {
+	if !f.failed {
+		f.failed = true
+		return nil, errors.New("throttled")
+	}
+	return f.MemoryStore.AddCounters(ctx, key, deltas, ttl)
+}
+
+func TestRecordKeptRetried(t *testing.T) {
+	store := &FailingCountersStore{MemoryStore: NewMemoryStore()}
+	service := NewStatsService(store, &Options{Aggregated: true})
+	kept := haiku.Stored{Mood: haiku.MoodTechnical, CreatedAt: time.Now()}
+
+	if err := service.RecordKept(context.Background(), "id0", kept); !errors.Is(err, ErrRecordUsage) {
+		t.Fatalf("Expected ErrRecordUsage, got %v", err)
+	}
+	// Counting failed, so the haiku is counted when delivered again.
+	if err := service.RecordKept(context.Background(), "id0", kept); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	stats, err := service.Summary(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if stats.Haiku != 1 {
+		t.Errorf("Expected the haiku counted once, got %d", stats.Haiku)
+	}
+}
+
+func TestSummaryClampsDays(t *testing.T) {
+	service := NewStatsService(NewMemoryStore(), nil)
+
+	tests := []struct {
+		days     int
+		expected int
+	}{
+		{days: 0, expected: 1},
+		{days: 30, expected: 30},
+		{days: 365, expected: MaxDays},
+	}
+
+	for _, tc := range tests {
+		stats, err := service.Summary(context.Background(), tc.days)
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if len(stats.Days) != tc.expected {
+			t.Errorf("Expected %d days for %d, got %d", tc.expected, tc.days, len(stats.Days))
+		}
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	store := &MockStore{ErrorToReturn: errors.New("table not found")}
+	service := NewStatsService(store, nil)
+
+	if err := service.Record(context.Background(), haiku.Usage{Mood: haiku.MoodTechnical}); !errors.Is(err, ErrRecordUsage) {
+		t.Errorf("Expected ErrRecordUsage, got %v", err)
+	}
+	if store.LastTTL != MaxDays*24*time.Hour {
+		t.Errorf("Expected counters to be kept for %d days, got %s", MaxDays, store.LastTTL)
+	}
+	if err := service.RecordKept(context.Background(), "id0", haiku.Stored{Mood: haiku.MoodTechnical}); !errors.Is(err, ErrRecordUsage) {
+		t.Errorf("Expected ErrRecordUsage, got %v", err)
+	}
+	if err := service.RecordVote(context.Background(), haiku.Stored{Repository: "octo/leaves"}); !errors.Is(err, ErrRecordVote) {
+		t.Errorf("Expected ErrRecordVote, got %v", err)
+	}
+	if _, err := service.Summary(context.Background(), DefaultDays); !errors.Is(err, ErrGetStats) {
+		t.Errorf("Expected ErrGetStats, got %v", err)
+	}
+	if _, err := service.EraseAuthor(context.Background(), "Mona"); !errors.Is(err, ErrEraseStats) {
+		t.Errorf("Expected ErrEraseStats, got %v", err)
+	}
+}
+
+func TestEraseAuthor(t *testing.T) {
+	today := time.Date(2025, 10, 3, 18, 0, 0, 0, time.UTC)
+	service := NewStatsService(NewMemoryStore(), nil)
+	service.now = func() time.Time { return today }
+
+	acme := keys.NewTenantContext(context.Background(), "acme")
+	globex := keys.NewTenantContext(context.Background(), "globex")
+	for _, ctx := range []context.Context{acme, globex} {
+		for i, author := range []string{"Mona", "Hubot", "Mona"} {
+			usage := haiku.Usage{Time: today.AddDate(0, 0, -i), Mood: haiku.MoodTechnical, Author: author}
+			if err := service.Record(ctx, usage); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+		}
+		if err := service.RecordVote(ctx, haiku.Stored{Author: "Mona"}); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		ctx          context.Context
+		expectedDays int
+		expectedMona map[context.Context]bool
+	}{
+		{
+			name:         "Tenant's statistics only",
+			ctx:          acme,
+			expectedDays: 2,
+			expectedMona: map[context.Context]bool{acme: false, globex: true},
+		},
+		{
+			name:         "Default tenant's statistics only",
+			ctx:          context.Background(),
+			expectedDays: 0,
+			expectedMona: map[context.Context]bool{acme: false, globex: true},
+		},
+		{
+			name:         "Every tenant's statistics, for the operator",
+			ctx:          keys.NewOperatorContext(context.Background()),
+			expectedDays: 2,
+			expectedMona: map[context.Context]bool{acme: false, globex: false},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			days, err := service.EraseAuthor(tc.ctx, "Mona")
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if days != tc.expectedDays {
+				t.Errorf("Expected %d days erased, got %d", tc.expectedDays, days)
+			}
+
+			for ctx, expected := range tc.expectedMona {
+				stats, err := service.Summary(ctx, DefaultDays)
+				if err != nil {
+					t.Fatalf("Expected no error but got: %v", err)
+				}
+				if _, ok := stats.Authors["Mona"]; ok != expected {
+					t.Errorf("Expected Mona in the summary %v, got %v", expected, stats.Authors)
+				}
+				if stats.Haiku != 3 || stats.Authors["Hubot"] != 1 {
+					t.Errorf("Expected the other counters kept, got %+v", stats)
+				}
+
+				for _, ranking := range Rankings {
+					board, err := service.Leaderboard(ctx, BoardAuthors, ranking, DefaultDays, 0)
+					if err != nil {
+						t.Fatalf("Expected no error but got: %v", err)
+					}
+					listed := slices.ContainsFunc(board.Entries, func(entry Entry) bool { return entry.Name == "Mona" })
+					if listed != expected {
+						t.Errorf("Expected Mona on the %s leaderboard %v, got %+v", ranking, expected, board.Entries)
+					}
+				}
+			}
+		})
+	}
+}
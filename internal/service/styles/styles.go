@@ -0,0 +1,137 @@
+// Package styles keeps each tenant's style guide: the house style and in-jokes
+// merged into the prompt whenever the tenant's commit haiku are written.
+package styles
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+// MaxGuideLength is the longest style guide a tenant can store, in
+// characters. Guides are sent with every haiku request, so they are kept
+// short.
+const MaxGuideLength = 1000
+
+var (
+	ErrBadStyle      = errors.New("bad style guide")
+	ErrStyleNotFound = errors.New("style guide not found")
+	ErrStoreStyle    = errors.New("error storing style guide")
+)
+
+// Style is a tenant's style guide.
+type Style struct {
+	Tenant    string    `json:"tenant,omitempty"` // The tenant the guide belongs to, empty for the default tenant
+	Guide     string    `json:"guide"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SetStyleRequest replaces a tenant's style guide.
+type SetStyleRequest struct {
+	Guide string `json:"guide" binding:"required"`
+}
+
+// Store keeps style guides by tenant, e.g. in DynamoDB alongside keys.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+type StyleService struct {
+	store Store
+	now   func() time.Time
+}
+
+func NewStyleService(store Store) *StyleService {
+	return &StyleService{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Get returns the style guide of the context's tenant.
+func (s *StyleService) Get(ctx context.Context) (Style, error) {
+	style, found, err := s.load(ctx)
+	if err != nil {
+		return Style{}, err
+	}
+	if !found {
+		return Style{}, ErrStyleNotFound
+	}
+	return style, nil
+}
+
+// Set replaces the style guide of the context's tenant. Guides are refused
+// when they are too long or hold anything that reads as instructions to the
+// model rather than a style, so that a tenant can't steer the model beyond
+// its verse.
+func (s *StyleService) Set(ctx context.Context, request SetStyleRequest) (Style, error) {
+	guide := strings.TrimSpace(request.Guide)
+	switch {
+	case guide == "":
+		return Style{}, fmt.Errorf("%w: guide is empty", ErrBadStyle)
+	case utf8.RuneCountInString(guide) > MaxGuideLength:
+		return Style{}, fmt.Errorf("%w: guide is longer than %d characters", ErrBadStyle, MaxGuideLength)
+	case haiku.ContainsInstructions(guide):
+		return Style{}, fmt.Errorf("%w: guide contains instructions, prompt tags or control characters", ErrBadStyle)
+	}
+
+	style := Style{Tenant: keys.TenantFromContext(ctx), Guide: guide, UpdatedAt: s.now()}
+	value, err := json.Marshal(style)
+	if err != nil {
+		return Style{}, fmt.Errorf("%w: %w", ErrStoreStyle, err)
+	}
+	if err := s.store.Put(ctx, styleKey(ctx), value, 0); err != nil {
+		return Style{}, fmt.Errorf("%w: %w", ErrStoreStyle, err)
+	}
+	return style, nil
+}
+
+// Delete removes the style guide of the context's tenant, so that its haiku
+// are written in the default style again.
+func (s *StyleService) Delete(ctx context.Context) error {
+	if _, err := s.Get(ctx); err != nil {
+		return err
+	}
+	if err := s.store.Delete(ctx, styleKey(ctx)); err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreStyle, err)
+	}
+	return nil
+}
+
+// Guide returns the style guide of the context's tenant, or "" when it has
+// none, for merging into the prompt.
+func (s *StyleService) Guide(ctx context.Context) (string, error) {
+	style, _, err := s.load(ctx)
+	return style.Guide, err
+}
+
+func (s *StyleService) load(ctx context.Context) (Style, bool, error) {
+	value, found, err := s.store.Get(ctx, styleKey(ctx))
+	if err != nil {
+		return Style{}, false, fmt.Errorf("%w: %w", ErrStoreStyle, err)
+	}
+	if !found {
+		return Style{}, false, nil
+	}
+
+	var style Style
+	if err := json.Unmarshal(value, &style); err != nil {
+		return Style{}, false, fmt.Errorf("%w: %w", ErrStoreStyle, err)
+	}
+	return style, true, nil
+}
+
+// styleKey names the item holding the style guide of the context's tenant.
+// Key IDs are hex, so it never collides with a key kept in the same table.
+func styleKey(ctx context.Context) string {
+	return keys.Namespace(ctx, "style")
+}
@@ -0,0 +1,152 @@
+// Package teams answers messages sent to a Microsoft Teams outgoing webhook.
+// Teams waits only a few seconds for the reply and offers no way to respond
+// later, so the haiku is generated while Teams waits and returned as an
+// Adaptive Card.
+package teams
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const (
+	cardContentType = "application/vnd.microsoft.card.adaptive"
+	cardSchema      = "http://adaptivecards.io/schemas/adaptive-card.json"
+	cardVersion     = "1.4"
+)
+
+var ErrBadMessage = errors.New("bad teams message received")
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+// Message is a verified message sent to the outgoing webhook, with the
+// mention of the webhook removed.
+type Message struct {
+	Text     string
+	UserName string
+}
+
+// Activity is the part of a Bot Framework activity an outgoing webhook
+// replies with.
+type Activity struct {
+	Type        string       `json:"type"`
+	Text        string       `json:"text,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+}
+
+type Attachment struct {
+	ContentType string `json:"contentType"`
+	Content     Card   `json:"content"`
+}
+
+// Card is an Adaptive Card made of text blocks.
+type Card struct {
+	Schema  string      `json:"$schema"`
+	Type    string      `json:"type"`
+	Version string      `json:"version"`
+	Body    []TextBlock `json:"body"`
+}
+
+type TextBlock struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Wrap     bool   `json:"wrap,omitempty"`
+	Size     string `json:"size,omitempty"`
+	IsSubtle bool   `json:"isSubtle,omitempty"`
+	Spacing  string `json:"spacing,omitempty"`
+}
+
+type TeamsService struct {
+	haikuService HaikuService
+}
+
+func NewTeamsService(haikuService HaikuService) *TeamsService {
+	return &TeamsService{
+		haikuService: haikuService,
+	}
+}
+
+// Reply generates a haiku for the message and returns it as a card. When
+// generation fails the reply explains why and the generation error is
+// returned alongside it.
+func (s *TeamsService) Reply(ctx context.Context, message Message) (Activity, error) {
+	if strings.TrimSpace(message.Text) == "" {
+		err := fmt.Errorf("%w: text is required", ErrBadMessage)
+		logging.Warnf("[TEAMS SERVICE] %v\n", err)
+		return TextReply(errorText(err)), err
+	}
+
+	response, err := s.haikuService.CreateHaiku(ctx, haiku.HaikuCommitRequest{
+		CommitMessage: message.Text,
+	})
+	if err != nil {
+		logging.Errorf("[TEAMS SERVICE] error generating haiku: %v\n", err)
+		return TextReply(errorText(err)), err
+	}
+
+	return Activity{
+		Type: "message",
+		Attachments: []Attachment{{
+			ContentType: cardContentType,
+			Content:     FormatCard(response.Haiku, message),
+		}},
+	}, nil
+}
+
+// TextReply is a plain text reply.
+func TextReply(text string) Activity {
+	return Activity{
+		Type: "message",
+		Text: text,
+	}
+}
+
+func errorText(err error) string {
+	if errors.Is(err, haiku.ErrContentBlocked) {
+		return "Sorry, that haiku was blocked by the content filter."
+	} else if errors.Is(err, haiku.ErrBadHaikuRequest) || errors.Is(err, ErrBadMessage) {
+		return "Sorry, a haiku cannot be written for that message."
+	}
+	return "Sorry, the haiku could not be written right now. Please try again."
+}
+
+// FormatCard renders a haiku as one text block per line, followed by who
+// asked and for which message.
+func FormatCard(text string, message Message) Card {
+	card := Card{
+		Schema:  cardSchema,
+		Type:    "AdaptiveCard",
+		Version: cardVersion,
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		card.Body = append(card.Body, TextBlock{
+			Type:    "TextBlock",
+			Text:    strings.TrimSpace(line),
+			Wrap:    true,
+			Size:    "Medium",
+			Spacing: "None",
+		})
+	}
+
+	subject, _, _ := strings.Cut(strings.TrimSpace(message.Text), "\n")
+	attribution := "🍂 " + subject
+	if message.UserName != "" {
+		attribution = "🍂 " + message.UserName + ": " + subject
+	}
+	card.Body = append(card.Body, TextBlock{
+		Type:     "TextBlock",
+		Text:     attribution,
+		Wrap:     true,
+		IsSubtle: true,
+		Spacing:  "Medium",
+	})
+	return card
+}
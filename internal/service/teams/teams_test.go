@@ -0,0 +1,115 @@
+package teams
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+	LastRequest      *haiku.HaikuCommitRequest
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.LastRequest = &request
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+func TestReply(t *testing.T) {
+	message := Message{Text: "fix: resolved login issue", UserName: "Ada Lovelace"}
+
+	tests := []struct {
+		name         string
+		message      Message
+		haikuError   error
+		expectedText string
+		errorIs      error
+	}{
+		{
+			name:    "Haiku card",
+			message: message,
+		},
+		{
+			name:         "Blocked haiku explained",
+			message:      message,
+			haikuError:   haiku.ErrContentBlocked,
+			expectedText: "Sorry, that haiku was blocked by the content filter.",
+			errorIs:      haiku.ErrContentBlocked,
+		},
+		{
+			name:         "Generation failure explained",
+			message:      message,
+			haikuError:   context.DeadlineExceeded,
+			expectedText: "Sorry, the haiku could not be written right now. Please try again.",
+			errorIs:      context.DeadlineExceeded,
+		},
+		{
+			name:         "Empty text",
+			message:      Message{Text: " "},
+			expectedText: "Sorry, a haiku cannot be written for that message.",
+			errorIs:      ErrBadMessage,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku},
+				ErrorToReturn:    tc.haikuError,
+			}
+
+			reply, err := NewTeamsService(haikuService).Reply(context.Background(), tc.message)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				if reply.Text != tc.expectedText || len(reply.Attachments) != 0 {
+					t.Errorf("Expected text reply %q, got %+v", tc.expectedText, reply)
+				}
+				if errors.Is(tc.errorIs, ErrBadMessage) && haikuService.LastRequest != nil {
+					t.Errorf("Expected no haiku to be generated for a bad message")
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(reply.Attachments) != 1 || reply.Attachments[0].ContentType != cardContentType {
+				t.Fatalf("Expected an adaptive card attachment, got %+v", reply)
+			}
+			if haikuService.LastRequest.CommitMessage != tc.message.Text {
+				t.Errorf("Expected a haiku for %q, got %q", tc.message.Text, haikuService.LastRequest.CommitMessage)
+			}
+		})
+	}
+}
+
+func TestFormatCard(t *testing.T) {
+	card := FormatCard(testHaiku, Message{Text: "fix: resolved login issue\n\nlong body", UserName: "Ada Lovelace"})
+
+	expected := []string{
+		"Old cracks mended now",
+		"the login door swings open",
+		"quiet in the logs",
+		"🍂 Ada Lovelace: fix: resolved login issue",
+	}
+	if card.Type != "AdaptiveCard" || len(card.Body) != len(expected) {
+		t.Fatalf("Expected an adaptive card with %d blocks, got %+v", len(expected), card)
+	}
+	for i, text := range expected {
+		if card.Body[i].Text != text {
+			t.Errorf("Expected block %d to be %q, got %q", i, text, card.Body[i].Text)
+		}
+	}
+	if !card.Body[3].IsSubtle {
+		t.Errorf("Expected the attribution to be subtle")
+	}
+}
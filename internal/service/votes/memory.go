@@ -0,0 +1,11 @@
+package votes
+
+import "github.com/brianherrera/commits-fall-like-leaves/internal/memstore"
+
+// MemoryStore keeps haiku, their votes and the key histories in memory for a
+// single server, whose haiku can only be voted on through it.
+type MemoryStore = memstore.Store
+
+func NewMemoryStore() *MemoryStore {
+	return memstore.New()
+}
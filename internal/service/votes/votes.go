@@ -0,0 +1,578 @@
+// Package votes keeps served haiku so that users can upvote them, once each,
+// and lists the most voted haiku over a range of days. When a moderator is
+// configured, haiku are screened after they are saved and only those it
+// passes are published for voting and listing. Haiku, votes and rankings
+// belong to the tenant of the API key that served them.
+package votes
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const (
+	// DefaultDays is the range the top haiku are drawn from when none is
+	// asked for.
+	DefaultDays = 7
+	// MaxDays is the longest range the top haiku can be drawn from.
+	MaxDays = 90
+	// DefaultRetention is how long haiku, votes and their counters are kept
+	// before they expire, unless configured otherwise.
+	DefaultRetention = MaxDays * 24 * time.Hour
+	// DefaultTopSize is how many haiku the top lists when no limit is asked
+	// for.
+	DefaultTopSize = 10
+	// MaxTopSize is the most haiku the top lists.
+	MaxTopSize = 100
+
+	dayFormat    = "2006-01-02"
+	counterVotes = "votes"
+)
+
+var (
+	ErrHaikuNotFound = errors.New("haiku not found")
+	ErrAlreadyVoted  = errors.New("already voted for haiku")
+	ErrNotPublished  = errors.New("haiku is awaiting moderation")
+	ErrStoreVote     = errors.New("error storing vote")
+	ErrGetVotes      = errors.New("error getting votes")
+	ErrModerate      = errors.New("error moderating haiku")
+)
+
+// Status is where a saved haiku is in moderation.
+type Status string
+
+const (
+	StatusPending     Status = "pending"     // Saved, awaiting moderation
+	StatusPublished   Status = "published"   // Passed moderation, or none is configured
+	StatusUnpublished Status = "unpublished" // Blocked by moderation, never shown
+)
+
+// entry is a haiku as it is stored, with its publication status.
+type entry struct {
+	haiku.Stored
+	Status Status `json:"status"`
+	Reason string `json:"reason,omitempty"` // Why moderation unpublished the haiku
+}
+
+// Haiku is a stored haiku and its votes.
+type Haiku struct {
+	ID string `json:"id"`
+	haiku.Stored
+	Votes int64 `json:"votes"`
+}
+
+// Top lists the most voted haiku over a range of days.
+type Top struct {
+	From  string  `json:"from"`  // First day covered, as YYYY-MM-DD in UTC
+	To    string  `json:"to"`    // Last day covered, today
+	Haiku []Haiku `json:"haiku"` // Votes counts those cast on the days covered
+}
+
+// Saved is a kept haiku with its publication status, whether or not it is
+// published, e.g. for export.
+type Saved struct {
+	ID string `json:"id"`
+	haiku.Stored
+	Status Status `json:"status"`
+}
+
+// Store keeps haiku, who voted for them, and vote counters, e.g. in
+// DynamoDB. PutIfAbsent stores a value only when the key holds none, so that
+// each voter is counted once however many requests they race. ScanPrefix
+// reads every value whose key starts with a prefix. Delete removes values and
+// counters alike.
+type Store interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+	PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	AddCounters(ctx context.Context, key string, deltas map[string]int64, ttl time.Duration) (map[string]int64, error)
+	GetCounters(ctx context.Context, key string) (map[string]int64, error)
+	ScanPrefix(ctx context.Context, prefix string, fn func(key string, value []byte) error) error
+}
+
+// VoteRecorder credits votes to the repository, author and prompt version of
+// the haiku voted for, e.g. for the leaderboard.
+type VoteRecorder interface {
+	RecordVote(ctx context.Context, voted haiku.Stored) error
+}
+
+// ModerationScheduler runs Moderate for a saved haiku after the request that
+// served it was answered.
+type ModerationScheduler interface {
+	Schedule(ctx context.Context, id string) error
+}
+
+type VoteService struct {
+	store     Store
+	recorder  VoteRecorder
+	moderator moderation.Moderator
+	scheduler ModerationScheduler
+	retention time.Duration
+	now       func() time.Time
+}
+
+type Options struct {
+	Recorder  VoteRecorder         // Credits votes in usage statistics (default: none)
+	Moderator moderation.Moderator // Screens haiku before they are published (default: none, haiku are published as saved)
+	Scheduler ModerationScheduler  // Moderates saved haiku in the background (default: moderate as they are saved)
+	Retention time.Duration        // How long haiku and their votes are kept (default: 90 days)
+}
+
+func NewVoteService(store Store, opts *Options) *VoteService {
+	service := &VoteService{
+		store:     store,
+		retention: DefaultRetention,
+		now:       time.Now,
+	}
+
+	if opts != nil {
+		service.recorder = opts.Recorder
+		service.moderator = opts.Moderator
+		service.scheduler = opts.Scheduler
+		if opts.Retention > 0 {
+			service.retention = opts.Retention
+		}
+	}
+
+	return service
+}
+
+// Save stores a served haiku and returns the ID it can be voted for by. With
+// a moderator the haiku is saved unpublished and moderated before it can be
+// voted for or listed.
+func (s *VoteService) Save(ctx context.Context, stored haiku.Stored) (string, error) {
+	id, err := newHaikuID()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+
+	saved := entry{Stored: stored, Status: StatusPublished}
+	if s.moderator != nil {
+		saved.Status = StatusPending
+	}
+	if err := s.put(ctx, id, saved); err != nil {
+		return "", err
+	}
+	if err := s.index(ctx, id, stored); err != nil {
+		return "", err
+	}
+	if s.moderator == nil {
+		return id, nil
+	}
+
+	if s.scheduler == nil {
+		if err := s.Moderate(ctx, id); err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	// A haiku whose moderation can't be scheduled would never be published,
+	// so it is served without an ID rather than one that can't be voted for.
+	if err := s.scheduler.Schedule(ctx, id); err != nil {
+		return "", fmt.Errorf("%w: scheduling %s: %w", ErrModerate, id, err)
+	}
+	return id, nil
+}
+
+// index lists a haiku written for an API key, so that the key's history can
+// be found among the other haiku. Each haiku is an item of its own, named by
+// key, month and haiku ID and holding the Unix time the haiku was written, so
+// that no item grows with a busy key.
+func (s *VoteService) index(ctx context.Context, id string, stored haiku.Stored) error {
+	if stored.KeyID == "" {
+		return nil
+	}
+
+	createdAt := stored.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = s.now()
+	}
+	value := []byte(strconv.FormatInt(createdAt.Unix(), 10))
+	if err := s.store.Put(ctx, historyKey(stored.KeyID, createdAt, id), value, s.retention); err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+	return nil
+}
+
+// History calls fn with every haiku kept for the API key keyID, oldest
+// first, whatever its publication status, until fn returns an error.
+func (s *VoteService) History(ctx context.Context, keyID string, fn func(saved Saved) error) error {
+	type indexed struct {
+		id        string
+		createdAt int64
+	}
+
+	var listed []indexed
+	err := s.store.ScanPrefix(ctx, historyPrefix(keyID), func(key string, value []byte) error {
+		createdAt, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			logging.Warnf("[VOTE SERVICE] skipping unreadable history item %s: %v\n", key, err)
+			return nil
+		}
+		listed = append(listed, indexed{id: key[strings.LastIndex(key, ":")+1:], createdAt: createdAt})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGetVotes, err)
+	}
+
+	// Haiku indexed before each had an item of its own are listed as
+	// counters on an item per month, until they expire.
+	now := s.now().UTC()
+	month := now.Add(-s.retention)
+	month = time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for ; !month.After(now); month = month.AddDate(0, 1, 0) {
+		counters, err := s.store.GetCounters(ctx, legacyHistoryKey(keyID, month))
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrGetVotes, err)
+		}
+		for id, createdAt := range counters {
+			listed = append(listed, indexed{id: id, createdAt: createdAt})
+		}
+	}
+	slices.SortFunc(listed, func(a, b indexed) int {
+		return cmp.Or(cmp.Compare(a.createdAt, b.createdAt), strings.Compare(a.id, b.id))
+	})
+
+	// Haiku are loaded as they are listed, so a long history is never held
+	// in memory at once.
+	for _, item := range listed {
+		saved, err := s.load(ctx, item.id)
+		if errors.Is(err, ErrHaikuNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if saved.KeyID != keyID {
+			continue
+		}
+		if err := fn(Saved{ID: item.id, Stored: saved.Stored, Status: saved.Status}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes the haiku id and its vote count, e.g. to erase it. Who
+// voted for it is left to expire, since it names the voters rather than the
+// haiku, and the days it was voted on skip it once it is gone. Deleting a
+// haiku that isn't kept succeeds.
+func (s *VoteService) Delete(ctx context.Context, id string) error {
+	if !validHaikuID(id) {
+		return nil
+	}
+
+	for _, key := range []string{haikuKey(id), votesKey(id)} {
+		if err := s.store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("%w: deleting %s: %w", ErrStoreVote, key, err)
+		}
+	}
+	return nil
+}
+
+// Moderate screens the saved haiku id and publishes it, or unpublishes it when
+// the moderator blocks it. Haiku already moderated are left as they are, so
+// that a retried moderation is harmless.
+func (s *VoteService) Moderate(ctx context.Context, id string) error {
+	saved, err := s.load(ctx, id)
+	if err != nil {
+		return err
+	}
+	if saved.Status != StatusPending || s.moderator == nil {
+		return nil
+	}
+
+	result, err := s.moderator.Moderate(ctx, saved.Haiku)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrModerate, id, err)
+	}
+
+	saved.Status = StatusPublished
+	if result.Blocked {
+		logging.Infof("[VOTES SERVICE] unpublished haiku %s: %s\n", id, result.Reason)
+		saved.Status = StatusUnpublished
+		saved.Reason = result.Reason
+	}
+	return s.put(ctx, id, saved)
+}
+
+// Get returns a published haiku and every vote cast for it.
+func (s *VoteService) Get(ctx context.Context, id string) (Haiku, error) {
+	stored, err := s.loadPublished(ctx, id)
+	if err != nil {
+		return Haiku{}, err
+	}
+
+	counters, err := s.store.GetCounters(ctx, votesKey(id))
+	if err != nil {
+		return Haiku{}, fmt.Errorf("%w: %w", ErrGetVotes, err)
+	}
+	return Haiku{ID: id, Stored: stored, Votes: counters[counterVotes]}, nil
+}
+
+// Load returns a kept haiku to the caller it was served to, whatever its
+// publication status, e.g. so that it can be written again. Haiku served to
+// another tenant or API key are reported as not found.
+func (s *VoteService) Load(ctx context.Context, id string) (haiku.Stored, error) {
+	saved, err := s.load(ctx, id)
+	if err != nil {
+		return haiku.Stored{}, err
+	}
+	if saved.Tenant != keys.TenantFromContext(ctx) || saved.KeyID != keys.IDFromContext(ctx) {
+		return haiku.Stored{}, ErrHaikuNotFound
+	}
+	return saved.Stored, nil
+}
+
+// Vote counts voter's vote for the haiku id and returns the haiku with its
+// votes. voter identifies who is voting, e.g. by API key; only their first
+// vote for a haiku counts. Only published haiku can be voted for, so votes
+// are never credited to a haiku moderation blocked.
+func (s *VoteService) Vote(ctx context.Context, id string, voter string) (Haiku, error) {
+	stored, err := s.loadPublished(ctx, id)
+	if err != nil {
+		return Haiku{}, err
+	}
+
+	// Voters are kept hashed, since they may be user IDs or email addresses.
+	hash := sha256.Sum256([]byte(voter))
+	first, err := s.store.PutIfAbsent(ctx, "voter:"+id+":"+hex.EncodeToString(hash[:]), []byte(s.now().UTC().Format(time.RFC3339)), s.retention)
+	if err != nil {
+		return Haiku{}, fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+	if !first {
+		return Haiku{}, ErrAlreadyVoted
+	}
+
+	counters, err := s.store.AddCounters(ctx, votesKey(id), map[string]int64{counterVotes: 1}, s.retention)
+	if err != nil {
+		return Haiku{}, fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+	if _, err := s.store.AddCounters(ctx, dayKey(ctx, s.now()), map[string]int64{id: 1}, s.retention); err != nil {
+		return Haiku{}, fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+
+	// Statistics are best effort, so a failure is logged rather than failing
+	// the vote.
+	if s.recorder != nil {
+		if err := s.recorder.RecordVote(ctx, stored); err != nil {
+			logging.Errorf("[VOTES SERVICE] error recording vote for %s: %v\n", id, err)
+		}
+	}
+
+	return Haiku{ID: id, Stored: stored, Votes: counters[counterVotes]}, nil
+}
+
+// Top lists the haiku with the most votes cast over the last days, up to and
+// including today, most voted first, with at most limit haiku. Ties are
+// ranked by ID, so the order is stable. days and limit are clamped to their
+// ranges. Haiku that have expired or are not published are left out.
+func (s *VoteService) Top(ctx context.Context, days int, limit int) (Top, error) {
+	days = max(1, min(days, MaxDays))
+	limit = max(1, min(limit, MaxTopSize))
+	today := s.now().UTC()
+
+	votes := make(map[string]int64)
+	for i := days - 1; i >= 0; i-- {
+		counters, err := s.store.GetCounters(ctx, dayKey(ctx, today.AddDate(0, 0, -i)))
+		if err != nil {
+			return Top{}, fmt.Errorf("%w: %w", ErrGetVotes, err)
+		}
+		for id, count := range counters {
+			votes[id] += count
+		}
+	}
+
+	top := Top{
+		From:  today.AddDate(0, 0, -(days - 1)).Format(dayFormat),
+		To:    today.Format(dayFormat),
+		Haiku: make([]Haiku, 0, min(limit, len(votes))),
+	}
+	ranked := make([]Haiku, 0, len(votes))
+	for id, count := range votes {
+		ranked = append(ranked, Haiku{ID: id, Votes: count})
+	}
+	slices.SortFunc(ranked, func(a, b Haiku) int {
+		return cmp.Or(cmp.Compare(b.Votes, a.Votes), strings.Compare(a.ID, b.ID))
+	})
+	// Haiku are loaded as they are listed, since most never are.
+	for _, candidate := range ranked {
+		if len(top.Haiku) == limit {
+			break
+		}
+		stored, err := s.loadPublished(ctx, candidate.ID)
+		if errors.Is(err, ErrHaikuNotFound) || errors.Is(err, ErrNotPublished) {
+			continue
+		}
+		if err != nil {
+			return Top{}, err
+		}
+		candidate.Stored = stored
+		top.Haiku = append(top.Haiku, candidate)
+	}
+	return top, nil
+}
+
+// Each calls fn with every kept haiku that hasn't expired, in no particular
+// order, until fn returns an error. It reads every haiku, so it suits
+// scheduled work such as exports rather than requests.
+func (s *VoteService) Each(ctx context.Context, fn func(saved Saved) error) error {
+	var fnErr error
+	err := s.store.ScanPrefix(ctx, haikuKey(""), func(key string, value []byte) error {
+		var saved entry
+		if err := json.Unmarshal(value, &saved); err != nil {
+			logging.Warnf("[VOTES SERVICE] skipping unreadable %s: %v\n", key, err)
+			return nil
+		}
+		fnErr = fn(Saved{ID: strings.TrimPrefix(key, haikuKey("")), Stored: saved.Stored, Status: saved.Status})
+		return fnErr
+	})
+	if fnErr != nil {
+		return fnErr
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrGetVotes, err)
+	}
+	return nil
+}
+
+// loadPublished returns a haiku that can be shown. Unpublished haiku, and
+// those of another tenant, are reported as not found, so that callers can't
+// tell they were ever served.
+func (s *VoteService) loadPublished(ctx context.Context, id string) (haiku.Stored, error) {
+	saved, err := s.load(ctx, id)
+	if err != nil {
+		return haiku.Stored{}, err
+	}
+	if saved.Tenant != keys.TenantFromContext(ctx) {
+		return haiku.Stored{}, ErrHaikuNotFound
+	}
+
+	switch saved.Status {
+	case StatusPublished:
+		return saved.Stored, nil
+	case StatusPending:
+		return haiku.Stored{}, ErrNotPublished
+	default:
+		return haiku.Stored{}, ErrHaikuNotFound
+	}
+}
+
+func (s *VoteService) load(ctx context.Context, id string) (entry, error) {
+	if !validHaikuID(id) {
+		return entry{}, ErrHaikuNotFound
+	}
+
+	value, found, err := s.store.Get(ctx, haikuKey(id))
+	if err != nil {
+		return entry{}, fmt.Errorf("%w: %w", ErrGetVotes, err)
+	}
+	if !found {
+		return entry{}, ErrHaikuNotFound
+	}
+
+	var saved entry
+	if err := json.Unmarshal(value, &saved); err != nil {
+		return entry{}, fmt.Errorf("%w: %w", ErrGetVotes, err)
+	}
+	return saved, nil
+}
+
+// put stores a haiku until the retention period after it was served, so that moderating
+// it doesn't extend its life.
+func (s *VoteService) put(ctx context.Context, id string, saved entry) error {
+	value, err := json.Marshal(saved)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+
+	ttl := s.retention
+	if !saved.CreatedAt.IsZero() {
+		ttl -= s.now().Sub(saved.CreatedAt)
+	}
+	if err := s.store.Put(ctx, haikuKey(id), value, max(ttl, time.Minute)); err != nil {
+		return fmt.Errorf("%w: %w", ErrStoreVote, err)
+	}
+	return nil
+}
+
+// HaikuKeyPrefix starts the key of every kept haiku, e.g. for a stream
+// filter matching only haiku.
+const HaikuKeyPrefix = "haiku:"
+
+// ParseItem reads a kept haiku from an item of the table keeping haiku, e.g.
+// as its stream delivers it, and reports false for items other than haiku.
+func ParseItem(key string, value []byte) (Saved, bool, error) {
+	id, ok := strings.CutPrefix(key, HaikuKeyPrefix)
+	if !ok || !validHaikuID(id) {
+		return Saved{}, false, nil
+	}
+
+	var saved entry
+	if err := json.Unmarshal(value, &saved); err != nil {
+		return Saved{}, false, fmt.Errorf("%w: %w", ErrGetVotes, err)
+	}
+	return Saved{ID: id, Stored: saved.Stored, Status: saved.Status}, true, nil
+}
+
+func haikuKey(id string) string {
+	return HaikuKeyPrefix + id
+}
+
+func votesKey(id string) string {
+	return "votes:" + id
+}
+
+// historyKey names the item indexing the haiku id, written for an API key in
+// the month of t.
+func historyKey(keyID string, t time.Time, id string) string {
+	return historyPrefix(keyID) + t.UTC().Format("2006-01") + ":" + id
+}
+
+// historyPrefix starts the key of every item indexing a haiku written for an
+// API key.
+func historyPrefix(keyID string) string {
+	return "history:" + keyID + ":"
+}
+
+// legacyHistoryKey names the item that listed the haiku written for an API
+// key in the month of t, before each had an item of its own.
+func legacyHistoryKey(keyID string, t time.Time) string {
+	return historyPrefix(keyID) + t.UTC().Format("2006-01")
+}
+
+// dayKey names the item counting the votes cast for each haiku on a day, in
+// the tenant of ctx.
+func dayKey(ctx context.Context, t time.Time) string {
+	return keys.Namespace(ctx, "voted:"+t.UTC().Format(dayFormat))
+}
+
+func newHaikuID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func validHaikuID(id string) bool {
+	decoded, err := hex.DecodeString(id)
+	return err == nil && len(decoded) == 16
+}
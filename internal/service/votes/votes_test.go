@@ -0,0 +1,531 @@
+package votes
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/keys"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockVoteRecorder struct {
+	ErrorToReturn error
+	Recorded      []haiku.Stored
+}
+
+func (m *MockVoteRecorder) RecordVote(ctx context.Context, voted haiku.Stored) error {
+	m.Recorded = append(m.Recorded, voted)
+	return m.ErrorToReturn
+}
+
+type MockModerator struct {
+	ResultToReturn moderation.Result
+	ErrorToReturn  error
+	Calls          int
+}
+
+func (m *MockModerator) Moderate(ctx context.Context, text string) (moderation.Result, error) {
+	m.Calls++
+	return m.ResultToReturn, m.ErrorToReturn
+}
+
+type MockScheduler struct {
+	ErrorToReturn error
+	Scheduled     []string
+}
+
+func (m *MockScheduler) Schedule(ctx context.Context, id string) error {
+	m.Scheduled = append(m.Scheduled, id)
+	return m.ErrorToReturn
+}
+
+type MockStore struct {
+	*MemoryStore
+	ErrorToReturn error
+}
+
+func (m *MockStore) PutIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	if m.ErrorToReturn != nil {
+		return false, m.ErrorToReturn
+	}
+	return m.MemoryStore.PutIfAbsent(ctx, key, value, ttl)
+}
+
+func TestVote(t *testing.T) {
+	// A recorder failure must not fail the vote.
+	recorder := &MockVoteRecorder{ErrorToReturn: errors.New("table not found")}
+	service := NewVoteService(NewMemoryStore(), &Options{Recorder: recorder})
+
+	id, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku, Repository: "octo/leaves", PromptVersion: "v2"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		id            string
+		voter         string
+		expectedVotes int64
+		errorIs       error
+	}{
+		{name: "First vote", id: id, voter: "key:abc", expectedVotes: 1},
+		{name: "Second voter", id: id, voter: "key:abc/user:mona", expectedVotes: 2},
+		{name: "Repeat vote", id: id, voter: "key:abc", errorIs: ErrAlreadyVoted},
+		{name: "Unknown haiku", id: "00000000000000000000000000000000", voter: "key:abc", errorIs: ErrHaikuNotFound},
+		{name: "Malformed ID", id: "../haiku", voter: "key:abc", errorIs: ErrHaikuNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			voted, err := service.Vote(context.Background(), tc.id, tc.voter)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+			if voted.ID != id || voted.Haiku != testHaiku || voted.Votes != tc.expectedVotes {
+				t.Errorf("Expected the haiku with %d votes, got %+v", tc.expectedVotes, voted)
+			}
+		})
+	}
+
+	if len(recorder.Recorded) != 2 || recorder.Recorded[0].PromptVersion != "v2" {
+		t.Errorf("Expected both votes credited to the haiku, got %+v", recorder.Recorded)
+	}
+	stored, err := service.Get(context.Background(), id)
+	if err != nil || stored.Votes != 2 {
+		t.Errorf("Expected 2 votes, got %+v: %v", stored, err)
+	}
+}
+
+func TestVoteStoreError(t *testing.T) {
+	store := &MockStore{MemoryStore: NewMemoryStore(), ErrorToReturn: errors.New("access denied")}
+	service := NewVoteService(store, nil)
+
+	id, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Vote(context.Background(), id, "key:abc"); !errors.Is(err, ErrStoreVote) {
+		t.Errorf("Expected ErrStoreVote, got %v", err)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewVoteService(store, &Options{Retention: time.Hour})
+
+	id, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Get(context.Background(), id); err != nil {
+		t.Fatalf("Expected the haiku to be kept, got %v", err)
+	}
+
+	store.SetClock(func() time.Time { return time.Now().Add(time.Hour) })
+	if _, err := service.Get(context.Background(), id); !errors.Is(err, ErrHaikuNotFound) {
+		t.Errorf("Expected the haiku to expire after an hour, got %v", err)
+	}
+}
+
+func TestTop(t *testing.T) {
+	today := time.Date(2025, 10, 10, 18, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	service := NewVoteService(store, nil)
+
+	save := func(text string) string {
+		id, err := service.Save(context.Background(), haiku.Stored{Haiku: text})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		return id
+	}
+	vote := func(id string, daysAgo int, voters ...string) {
+		service.now = func() time.Time { return today.AddDate(0, 0, -daysAgo) }
+		for _, voter := range voters {
+			if _, err := service.Vote(context.Background(), id, voter); err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+		}
+	}
+
+	leaves, roots, bark := save("leaves"), save("roots"), save("bark")
+	vote(leaves, 0, "a", "b")
+	vote(roots, 1, "a", "b", "c")
+	vote(bark, 5, "a", "b", "c", "d")
+	service.now = func() time.Time { return today }
+
+	tests := []struct {
+		name     string
+		days     int
+		limit    int
+		expected []string
+		votes    []int64
+	}{
+		{name: "Week", days: 7, limit: 10, expected: []string{"bark", "roots", "leaves"}, votes: []int64{4, 3, 2}},
+		{name: "Limit", days: 7, limit: 1, expected: []string{"bark"}, votes: []int64{4}},
+		{name: "Window", days: 2, limit: 10, expected: []string{"roots", "leaves"}, votes: []int64{3, 2}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			top, err := service.Top(context.Background(), tc.days, tc.limit)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(top.Haiku) != len(tc.expected) {
+				t.Fatalf("Expected %d haiku, got %+v", len(tc.expected), top.Haiku)
+			}
+			for i, voted := range top.Haiku {
+				if voted.Haiku != tc.expected[i] || voted.Votes != tc.votes[i] {
+					t.Errorf("Expected %s with %d votes at %d, got %+v", tc.expected[i], tc.votes[i], i+1, voted)
+				}
+			}
+			if top.To != "2025-10-10" {
+				t.Errorf("Expected the top to end today, got %s", top.To)
+			}
+		})
+	}
+
+	// Expired haiku are left out.
+	store.SetClock(func() time.Time { return time.Now().Add(DefaultRetention) })
+	top, err := service.Top(context.Background(), 7, 10)
+	if err != nil || len(top.Haiku) != 0 {
+		t.Errorf("Expected no haiku once they expire, got %+v: %v", top.Haiku, err)
+	}
+}
+
+func TestModerate(t *testing.T) {
+	tests := []struct {
+		name       string
+		moderator  *MockModerator
+		voteError  error
+		expectErr  bool
+		expectedIn bool // Whether the haiku is listed in the top once voted for
+	}{
+		{
+			name:       "Published",
+			moderator:  &MockModerator{},
+			expectedIn: true,
+		},
+		{
+			name:      "Unpublished",
+			moderator: &MockModerator{ResultToReturn: moderation.Result{Blocked: true, Reason: "guardrail gr-1"}},
+			voteError: ErrHaikuNotFound,
+		},
+		{
+			name:      "Moderator fails",
+			moderator: &MockModerator{ErrorToReturn: errors.New("guardrail unavailable")},
+			voteError: ErrNotPublished,
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scheduler := &MockScheduler{}
+			service := NewVoteService(NewMemoryStore(), &Options{Moderator: tc.moderator, Scheduler: scheduler})
+
+			id, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku, CreatedAt: time.Now()})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if len(scheduler.Scheduled) != 1 || scheduler.Scheduled[0] != id {
+				t.Fatalf("Expected moderation of %s to be scheduled, got %v", id, scheduler.Scheduled)
+			}
+			if _, err := service.Vote(context.Background(), id, "key:abc"); !errors.Is(err, ErrNotPublished) {
+				t.Fatalf("Expected ErrNotPublished before moderation, got %v", err)
+			}
+
+			err = service.Moderate(context.Background(), id)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+			// Moderating again, e.g. when the invocation is retried, is harmless.
+			_ = service.Moderate(context.Background(), id)
+			if !tc.expectErr && tc.moderator.Calls != 1 {
+				t.Errorf("Expected the haiku to be moderated once, got %d", tc.moderator.Calls)
+			}
+
+			if _, err := service.Vote(context.Background(), id, "key:def"); !errors.Is(err, tc.voteError) {
+				t.Fatalf("Expected error %v, got %v", tc.voteError, err)
+			}
+			top, err := service.Top(context.Background(), 1, 10)
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if got := len(top.Haiku) == 1; got != tc.expectedIn {
+				t.Errorf("Expected the haiku listed %v, got %+v", tc.expectedIn, top.Haiku)
+			}
+		})
+	}
+}
+
+func TestSaveModeration(t *testing.T) {
+	t.Run("Without a scheduler", func(t *testing.T) {
+		moderator := &MockModerator{ResultToReturn: moderation.Result{Blocked: true}}
+		service := NewVoteService(NewMemoryStore(), &Options{Moderator: moderator})
+
+		id, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		if _, err := service.Get(context.Background(), id); !errors.Is(err, ErrHaikuNotFound) {
+			t.Errorf("Expected the haiku to be unpublished as it is saved, got %v", err)
+		}
+	})
+
+	t.Run("Scheduling fails", func(t *testing.T) {
+		scheduler := &MockScheduler{ErrorToReturn: errors.New("lambda unavailable")}
+		service := NewVoteService(NewMemoryStore(), &Options{Moderator: &MockModerator{}, Scheduler: scheduler})
+
+		if _, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku}); !errors.Is(err, ErrModerate) {
+			t.Errorf("Expected ErrModerate, got %v", err)
+		}
+	})
+}
+
+func TestEach(t *testing.T) {
+	service := NewVoteService(NewMemoryStore(), nil)
+
+	ids := make(map[string]bool)
+	for _, text := range []string{"leaves", "roots"} {
+		id, err := service.Save(context.Background(), haiku.Stored{Haiku: text})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		ids[id] = true
+	}
+	// Votes are kept alongside the haiku but are not haiku.
+	for id := range ids {
+		if _, err := service.Vote(context.Background(), id, "key:abc"); err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+	}
+
+	var saved []Saved
+	err := service.Each(context.Background(), func(s Saved) error {
+		saved = append(saved, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("Expected 2 haiku, got %+v", saved)
+	}
+	for _, s := range saved {
+		if !ids[s.ID] || s.Status != StatusPublished {
+			t.Errorf("Expected a published haiku saved earlier, got %+v", s)
+		}
+	}
+
+	stop := errors.New("stop")
+	if err := service.Each(context.Background(), func(Saved) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("Expected the error from fn, got %v", err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	service := NewVoteService(NewMemoryStore(), nil)
+
+	id, err := service.Save(context.Background(), haiku.Stored{Haiku: "leaves", KeyID: "abc"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Vote(context.Background(), id, "key:abc"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	if err := service.Delete(context.Background(), id); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Get(context.Background(), id); !errors.Is(err, ErrHaikuNotFound) {
+		t.Errorf("Expected ErrHaikuNotFound, got %v", err)
+	}
+	if counters, _ := service.store.GetCounters(context.Background(), votesKey(id)); len(counters) != 0 {
+		t.Errorf("Expected the vote count deleted, got %v", counters)
+	}
+	top, err := service.Top(context.Background(), 1, 10)
+	if err != nil || len(top.Haiku) != 0 {
+		t.Errorf("Expected a deleted haiku left out of the top, got %+v: %v", top, err)
+	}
+	err = service.History(context.Background(), "abc", func(saved Saved) error {
+		t.Errorf("Expected a deleted haiku left out of the history, got %+v", saved)
+		return nil
+	})
+	if err != nil {
+		t.Errorf("Expected no error but got: %v", err)
+	}
+
+	if err := service.Delete(context.Background(), id); err != nil {
+		t.Errorf("Expected deleting again to succeed, got %v", err)
+	}
+}
+
+func TestTenants(t *testing.T) {
+	service := NewVoteService(NewMemoryStore(), nil)
+	acme := keys.NewTenantContext(context.Background(), "acme")
+	globex := keys.NewTenantContext(context.Background(), "globex")
+
+	id, err := service.Save(acme, haiku.Stored{Haiku: "leaves", Tenant: "acme"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := service.Vote(acme, id, "key:abc"); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	for name, ctx := range map[string]context.Context{"another tenant": globex, "the default tenant": context.Background()} {
+		if _, err := service.Get(ctx, id); !errors.Is(err, ErrHaikuNotFound) {
+			t.Errorf("Expected the haiku hidden from %s, got %v", name, err)
+		}
+		if _, err := service.Vote(ctx, id, "key:def"); !errors.Is(err, ErrHaikuNotFound) {
+			t.Errorf("Expected %s unable to vote, got %v", name, err)
+		}
+		if top, err := service.Top(ctx, 1, 10); err != nil || len(top.Haiku) != 0 {
+			t.Errorf("Expected an empty top for %s, got %+v: %v", name, top, err)
+		}
+	}
+
+	top, err := service.Top(acme, 1, 10)
+	if err != nil || len(top.Haiku) != 1 || top.Haiku[0].ID != id || top.Haiku[0].Votes != 1 {
+		t.Errorf("Expected the haiku in its tenant's top, got %+v: %v", top, err)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	service := NewVoteService(NewMemoryStore(), &Options{Moderator: &MockModerator{}, Scheduler: &MockScheduler{}})
+	acme := keys.NewTenantContext(keys.NewContext(context.Background(), "abc"), "acme")
+
+	id, err := service.Save(acme, haiku.Stored{Haiku: testHaiku, KeyID: "abc", Tenant: "acme"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		id      string
+		errorIs error
+	}{
+		// Haiku awaiting moderation were still served, so they can be loaded.
+		{name: "Served to the caller", ctx: acme, id: id},
+		{name: "Another API key", ctx: keys.NewTenantContext(keys.NewContext(context.Background(), "def"), "acme"), id: id, errorIs: ErrHaikuNotFound},
+		{name: "Another tenant", ctx: keys.NewTenantContext(keys.NewContext(context.Background(), "abc"), "globex"), id: id, errorIs: ErrHaikuNotFound},
+		{name: "Without an API key", ctx: keys.NewTenantContext(context.Background(), "acme"), id: id, errorIs: ErrHaikuNotFound},
+		{name: "Unknown haiku", ctx: acme, id: "00000000000000000000000000000000", errorIs: ErrHaikuNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stored, err := service.Load(tc.ctx, tc.id)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs == nil && stored.Haiku != testHaiku {
+				t.Errorf("Expected the kept haiku, got %+v", stored)
+			}
+		})
+	}
+}
+
+func TestParseItem(t *testing.T) {
+	store := NewMemoryStore()
+	service := NewVoteService(store, nil)
+	id, err := service.Save(context.Background(), haiku.Stored{Haiku: testHaiku, Tenant: "acme"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	value, _, _ := store.Get(context.Background(), haikuKey(id))
+
+	tests := []struct {
+		name     string
+		key      string
+		value    []byte
+		expected bool
+		errorIs  error
+	}{
+		{name: "Haiku", key: haikuKey(id), value: value, expected: true},
+		{name: "Votes", key: votesKey(id), value: value},
+		{name: "Unreadable haiku", key: haikuKey(id), value: []byte("haiku"), errorIs: ErrGetVotes},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			saved, ok, err := ParseItem(tc.key, tc.value)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if ok != tc.expected {
+				t.Fatalf("Expected haiku %v, got %v", tc.expected, ok)
+			}
+			if ok && (saved.ID != id || saved.Haiku != testHaiku || saved.Tenant != "acme" || saved.Status != StatusPublished) {
+				t.Errorf("Expected the kept haiku, got %+v", saved)
+			}
+		})
+	}
+}
+
+func TestHistory(t *testing.T) {
+	now := time.Now().UTC()
+	store := NewMemoryStore()
+	service := NewVoteService(store, nil)
+
+	save := func(text string, keyID string, createdAt time.Time) string {
+		id, err := service.Save(context.Background(), haiku.Stored{Haiku: text, KeyID: keyID, CreatedAt: createdAt})
+		if err != nil {
+			t.Fatalf("Expected no error but got: %v", err)
+		}
+		return id
+	}
+	save("roots", "key-a", now.Add(-time.Hour))
+	save("bark", "key-b", now.Add(-2*time.Hour))
+	save("leaves", "key-a", now.AddDate(0, -1, 0))
+	save("moss", "", now)
+
+	// Haiku indexed as counters on the month's item are still listed.
+	twigsAt := now.Add(-3 * time.Hour)
+	twigs := save("twigs", "key-a", twigsAt)
+	if err := store.Delete(context.Background(), historyKey("key-a", twigsAt, twigs)); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if _, err := store.AddCounters(context.Background(), legacyHistoryKey("key-a", twigsAt), map[string]int64{twigs: twigsAt.Unix()}, time.Hour); err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+
+	// Each haiku is indexed by an item of its own.
+	items := 0
+	err := store.ScanPrefix(context.Background(), historyPrefix("key-a"), func(key string, value []byte) error {
+		items++
+		return nil
+	})
+	if err != nil || items != 2 {
+		t.Errorf("Expected an index item per haiku of key-a, got %d: %v", items, err)
+	}
+
+	var history []string
+	err = service.History(context.Background(), "key-a", func(saved Saved) error {
+		history = append(history, saved.Haiku)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if !slices.Equal(history, []string{"leaves", "twigs", "roots"}) {
+		t.Errorf("Expected key-a's haiku oldest first, got %v", history)
+	}
+
+	stop := errors.New("stop")
+	if err := service.History(context.Background(), "key-a", func(Saved) error { return stop }); !errors.Is(err, stop) {
+		t.Errorf("Expected the error from fn, got %v", err)
+	}
+}
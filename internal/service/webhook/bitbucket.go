@@ -0,0 +1,29 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+)
+
+type BitbucketAPI interface {
+	CreateCommitComment(ctx context.Context, repository, sha, body string) error
+}
+
+// BitbucketCommenter comments on the target commit. Target repositories are
+// full names such as "workspace/repo-slug".
+type BitbucketCommenter struct {
+	client BitbucketAPI
+}
+
+func NewBitbucketCommenter(client BitbucketAPI) *BitbucketCommenter {
+	return &BitbucketCommenter{
+		client: client,
+	}
+}
+
+func (c *BitbucketCommenter) Comment(ctx context.Context, target Target, body string) error {
+	if target.Repository == "" || target.CommitSHA == "" {
+		return fmt.Errorf("%w: repository and commit are required", ErrBadEvent)
+	}
+	return c.client.CreateCommitComment(ctx, target.Repository, target.CommitSHA, body)
+}
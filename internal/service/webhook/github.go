@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type GitHubAPI interface {
+	CreateCommitComment(ctx context.Context, installationID int64, owner, repo, sha, body string) error
+	CreateIssueComment(ctx context.Context, installationID int64, owner, repo string, number int, body string) error
+}
+
+// GitHubCommenter comments on pull requests when the target has one, and on
+// the commit otherwise.
+type GitHubCommenter struct {
+	client GitHubAPI
+}
+
+func NewGitHubCommenter(client GitHubAPI) *GitHubCommenter {
+	return &GitHubCommenter{
+		client: client,
+	}
+}
+
+func (c *GitHubCommenter) Comment(ctx context.Context, target Target, body string) error {
+	owner, repo, ok := strings.Cut(target.Repository, "/")
+	if !ok || owner == "" || repo == "" {
+		return fmt.Errorf("%w: repository %q is not owner/name", ErrBadEvent, target.Repository)
+	}
+
+	if target.PullRequest > 0 {
+		return c.client.CreateIssueComment(ctx, target.InstallationID, owner, repo, target.PullRequest, body)
+	}
+	return c.client.CreateCommitComment(ctx, target.InstallationID, owner, repo, target.CommitSHA, body)
+}
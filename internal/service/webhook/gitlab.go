@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+)
+
+type GitLabAPI interface {
+	CreateCommitComment(ctx context.Context, project, sha, note string) error
+	CreateMergeRequestNote(ctx context.Context, project string, iid int, body string) error
+}
+
+// GitLabCommenter comments on merge requests when the target has one, and on
+// the commit otherwise. Target repositories are full project paths.
+type GitLabCommenter struct {
+	client GitLabAPI
+}
+
+func NewGitLabCommenter(client GitLabAPI) *GitLabCommenter {
+	return &GitLabCommenter{
+		client: client,
+	}
+}
+
+func (c *GitLabCommenter) Comment(ctx context.Context, target Target, body string) error {
+	if target.Repository == "" {
+		return fmt.Errorf("%w: repository is required", ErrBadEvent)
+	}
+
+	if target.PullRequest > 0 {
+		return c.client.CreateMergeRequestNote(ctx, target.Repository, target.PullRequest, body)
+	}
+	return c.client.CreateCommitComment(ctx, target.Repository, target.CommitSHA, body)
+}
@@ -0,0 +1,25 @@
+package webhook
+
+import "strings"
+
+// DefaultMergeQueueBranches are the branch prefixes merge queues push to while
+// testing queued pull requests: GitHub's merge queue and Mergify's.
+var DefaultMergeQueueBranches = []string{
+	"gh-readonly-queue/",
+	"mergify/merge-queue/",
+}
+
+// isMergeQueueBranch reports whether branch is a temporary merge queue branch.
+// Its commits reach the base branch in a single push once the queue merges
+// them, which gets the haiku instead.
+func isMergeQueueBranch(prefixes []string, branch string) bool {
+	if branch == "" {
+		return false
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(branch, prefix) {
+			return true
+		}
+	}
+	return false
+}
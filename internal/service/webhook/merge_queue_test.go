@@ -0,0 +1,84 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+func TestHandleEventMergeQueues(t *testing.T) {
+	tests := []struct {
+		name         string
+		branch       string
+		opts         *Options
+		expectIgnore bool
+	}{
+		{
+			name:         "GitHub merge queue ignored",
+			branch:       "gh-readonly-queue/main/pr-42-0123456789abcdef",
+			expectIgnore: true,
+		},
+		{
+			name:         "Mergify merge queue ignored",
+			branch:       "mergify/merge-queue/5a4d1c",
+			expectIgnore: true,
+		},
+		{
+			name:   "Base branch push",
+			branch: "main",
+		},
+		{
+			name:         "Configured prefixes",
+			branch:       "staging.tmp",
+			opts:         &Options{MergeQueueBranches: []string{"staging.tmp", "trying.tmp"}},
+			expectIgnore: true,
+		},
+		{
+			name:   "Configured prefixes replace the defaults",
+			branch: "gh-readonly-queue/main/pr-42-0123456789abcdef",
+			opts:   &Options{MergeQueueBranches: []string{"staging.tmp"}},
+		},
+		{
+			name:   "Merge queues included",
+			branch: "gh-readonly-queue/main/pr-42-0123456789abcdef",
+			opts:   &Options{IncludeMergeQueues: true},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+			commenter := &MockCommenter{}
+
+			opts := tc.opts
+			if opts == nil {
+				opts = &Options{}
+			}
+			opts.Commenter = commenter
+
+			_, err := NewWebhookService(haikuService, opts).HandleEvent(context.Background(), Event{
+				CommitMessage: "fix: resolved login issue",
+				Target:        Target{Repository: "octo/leaves", CommitSHA: "abc123"},
+				Branch:        tc.branch,
+			})
+
+			if tc.expectIgnore {
+				if !errors.Is(err, ErrIgnored) {
+					t.Errorf("Expected error to wrap %v, got %v", ErrIgnored, err)
+				}
+				if commenter.Calls != 0 {
+					t.Errorf("Expected no comment, got %d", commenter.Calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if commenter.Calls != 1 {
+				t.Errorf("Expected one comment, got %d", commenter.Calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+var ErrInvalidMoodRule = errors.New("invalid mood rule")
+
+// MoodRule picks the mood of haiku for events on matching branches or
+// repositories. At most one of Branch and PathPrefix is set; a rule with
+// neither is a default, matching every event.
+type MoodRule struct {
+	Branch     string // Branch pattern, e.g. "release/*", matched with path.Match
+	PathPrefix string // Repository path prefix, e.g. "octo-org/" or "octo-org/leaves"
+	Mood       haiku.Mood
+}
+
+// Matches reports whether the rule applies to an event.
+func (r MoodRule) Matches(event Event) bool {
+	if r.Branch != "" {
+		if event.Branch == "" {
+			return false
+		}
+		matched, err := path.Match(r.Branch, event.Branch)
+		return err == nil && matched
+	}
+	if r.PathPrefix != "" {
+		return strings.HasPrefix(event.Target.Repository, r.PathPrefix)
+	}
+	return true
+}
+
+// ParseMoodRules parses comma-separated rules of the form
+// "branch:<pattern>=<mood>", "path:<prefix>=<mood>" or "default=<mood>", e.g.
+// "branch:release/*=humorous,branch:hotfix/*=reflective,default=auto". The
+// mood may be haiku.MoodAuto, inferring it from each commit.
+func ParseMoodRules(definition string) ([]MoodRule, error) {
+	var rules []MoodRule
+
+	for _, part := range strings.Split(definition, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		selector, mood, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: expected kind:match=mood, got %q", ErrInvalidMoodRule, part)
+		}
+
+		rule := MoodRule{Mood: haiku.Mood(strings.TrimSpace(mood))}
+		if rule.Mood != haiku.MoodAuto && !rule.Mood.IsValid() {
+			return nil, fmt.Errorf("%w: unknown mood in %q", ErrInvalidMoodRule, part)
+		}
+
+		kind, match, _ := strings.Cut(selector, ":")
+		if strings.TrimSpace(kind) == "default" && strings.TrimSpace(match) == "" {
+			rules = append(rules, rule)
+			continue
+		}
+		match = strings.TrimSpace(match)
+		if match == "" {
+			return nil, fmt.Errorf("%w: missing match in %q", ErrInvalidMoodRule, part)
+		}
+
+		switch strings.TrimSpace(kind) {
+		case "branch":
+			if _, err := path.Match(match, ""); err != nil {
+				return nil, fmt.Errorf("%w: invalid branch pattern in %q", ErrInvalidMoodRule, part)
+			}
+			rule.Branch = match
+		case "path":
+			rule.PathPrefix = match
+		default:
+			return nil, fmt.Errorf("%w: expected branch or path, got %q", ErrInvalidMoodRule, part)
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// moodFor returns the mood of the first rule matching the event, or the
+// service default when none match.
+func moodFor(rules []MoodRule, event Event) haiku.Mood {
+	for _, rule := range rules {
+		if rule.Matches(event) {
+			return rule.Mood
+		}
+	}
+	return ""
+}
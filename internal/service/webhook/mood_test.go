@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+)
+
+func TestParseMoodRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		definition string
+		expected   []MoodRule
+		errorIs    error
+	}{
+		{
+			name:       "Branch and path rules",
+			definition: "branch:release/*=humorous, path:octo-org/=technical",
+			expected: []MoodRule{
+				{Branch: "release/*", Mood: haiku.MoodHumerous},
+				{PathPrefix: "octo-org/", Mood: haiku.MoodTechnical},
+			},
+		},
+		{
+			name:       "Automatic default",
+			definition: "branch:release/*=humorous,default=auto",
+			expected: []MoodRule{
+				{Branch: "release/*", Mood: haiku.MoodHumerous},
+				{Mood: haiku.MoodAuto},
+			},
+		},
+		{
+			name:       "Empty",
+			definition: "",
+		},
+		{
+			name:       "Unknown mood",
+			definition: "branch:hotfix/*=somber",
+			errorIs:    ErrInvalidMoodRule,
+		},
+		{
+			name:       "Unknown kind",
+			definition: "tag:v*=humorous",
+			errorIs:    ErrInvalidMoodRule,
+		},
+		{
+			name:       "Missing mood",
+			definition: "branch:release/*",
+			errorIs:    ErrInvalidMoodRule,
+		},
+		{
+			name:       "Invalid pattern",
+			definition: "branch:release/[=humorous",
+			errorIs:    ErrInvalidMoodRule,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rules, err := ParseMoodRules(tc.definition)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if !reflect.DeepEqual(rules, tc.expected) {
+				t.Errorf("Expected rules %+v, got %+v", tc.expected, rules)
+			}
+		})
+	}
+}
+
+func TestHandleEventMoodRules(t *testing.T) {
+	rules := []MoodRule{
+		{Branch: "release/*", Mood: haiku.MoodHumerous},
+		{Branch: "hotfix/*", Mood: haiku.MoodReflective},
+		{PathPrefix: "octo/infra", Mood: haiku.MoodTechnical},
+	}
+
+	tests := []struct {
+		name       string
+		repository string
+		branch     string
+		expected   haiku.Mood
+	}{
+		{
+			name:       "Release branch",
+			repository: "octo/leaves",
+			branch:     "release/1.2",
+			expected:   haiku.MoodHumerous,
+		},
+		{
+			name:       "Hotfix branch",
+			repository: "octo/leaves",
+			branch:     "hotfix/login",
+			expected:   haiku.MoodReflective,
+		},
+		{
+			name:       "Branch rule wins over a later path rule",
+			repository: "octo/infra",
+			branch:     "release/1.2",
+			expected:   haiku.MoodHumerous,
+		},
+		{
+			name:       "Repository path",
+			repository: "octo/infra-modules",
+			branch:     "main",
+			expected:   haiku.MoodTechnical,
+		},
+		{
+			name:       "Nested branch not matched",
+			repository: "octo/leaves",
+			branch:     "release/1.2/rc",
+		},
+		{
+			name:       "No branch",
+			repository: "octo/leaves",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+			service := NewWebhookService(haikuService, &Options{MoodRules: rules})
+
+			_, err := service.HandleEvent(context.Background(), Event{
+				CommitMessage: "fix: resolved login issue",
+				Target:        Target{Repository: tc.repository, CommitSHA: "abc123"},
+				Branch:        tc.branch,
+			})
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if haikuService.LastRequest.Mood != tc.expected {
+				t.Errorf("Expected mood %q, got %q", tc.expected, haikuService.LastRequest.Mood)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+// Package webhook generates haiku for events delivered by code hosts and
+// posts them back to the commit or pull request that triggered them.
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+var (
+	ErrBadEvent = errors.New("bad webhook event received")
+	ErrComment  = errors.New("error posting haiku comment")
+	ErrIgnored  = errors.New("webhook event ignored")
+)
+
+// commentFooter signs each comment so readers know where the haiku came from.
+const commentFooter = "<sub>🍂 commits fall like leaves</sub>"
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+// Target identifies the commit or pull request a haiku is posted back to.
+type Target = extension.Target
+
+type Event struct {
+	CommitMessage string
+	Target        Target
+	Branch        string // Branch pushed to, or the pull request's source branch
+}
+
+// Commenter posts a rendered comment to a code host.
+type Commenter = extension.VCS
+
+type WebhookService struct {
+	haikuService HaikuService
+	commenter    Commenter
+	notifiers    []extension.Notifier
+	moodRules    []MoodRule
+	mergeQueues  []string
+}
+
+type Options struct {
+	Commenter Commenter            // Posts haiku back to the code host (default: none, haiku are not posted)
+	Notifiers []extension.Notifier // Told about every generated haiku; failures are logged (default: none)
+	MoodRules []MoodRule           // Pick the mood by branch or repository; the first match wins (default: none)
+
+	// MergeQueueBranches are prefixes of temporary merge queue branches whose
+	// events are ignored (default: DefaultMergeQueueBranches). Set
+	// IncludeMergeQueues to generate haiku for them anyway.
+	MergeQueueBranches []string
+	IncludeMergeQueues bool
+}
+
+// NewWebhookService generates haiku for webhook events with haikuService.
+func NewWebhookService(haikuService HaikuService, opts *Options) *WebhookService {
+	service := &WebhookService{
+		haikuService: haikuService,
+		mergeQueues:  DefaultMergeQueueBranches,
+	}
+
+	if opts != nil {
+		service.commenter = opts.Commenter
+		service.notifiers = opts.Notifiers
+		service.moodRules = opts.MoodRules
+		if len(opts.MergeQueueBranches) > 0 {
+			service.mergeQueues = opts.MergeQueueBranches
+		}
+		if opts.IncludeMergeQueues {
+			service.mergeQueues = nil
+		}
+	}
+
+	return service
+}
+
+// HandleEvent generates a haiku for the event's commit message and posts it
+// back to the event's target. Events on merge queue branches return
+// ErrIgnored.
+func (s *WebhookService) HandleEvent(ctx context.Context, event Event) (haiku.HaikuCommitResponse, error) {
+	if strings.TrimSpace(event.CommitMessage) == "" || event.Target.Repository == "" {
+		logging.Warnf("[WEBHOOK SERVICE] event is missing a commit message or repository\n")
+		return haiku.HaikuCommitResponse{}, ErrBadEvent
+	}
+
+	if isMergeQueueBranch(s.mergeQueues, event.Branch) {
+		logging.Infof("[WEBHOOK SERVICE] ignoring event on merge queue branch %s\n", event.Branch)
+		return haiku.HaikuCommitResponse{}, ErrIgnored
+	}
+
+	response, err := s.haikuService.CreateHaiku(ctx, haiku.HaikuCommitRequest{
+		CommitMessage: event.CommitMessage,
+		Mood:          moodFor(s.moodRules, event),
+		Repository:    &haiku.Repository{Name: event.Target.Repository},
+		CommitRef:     event.Target.CommitSHA,
+	})
+	if err != nil {
+		return haiku.HaikuCommitResponse{}, err
+	}
+
+	if s.commenter != nil {
+		if err := s.commenter.Comment(ctx, event.Target, FormatComment(response.Haiku)); err != nil {
+			logging.Errorf("[WEBHOOK SERVICE] error posting haiku comment: %v\n", err)
+			return haiku.HaikuCommitResponse{}, fmt.Errorf("%w: %v", ErrComment, err)
+		}
+	} else {
+		logging.Warnf("[WEBHOOK SERVICE] no commenter configured, not posting haiku for %s\n", event.Target.Repository)
+	}
+
+	s.notify(ctx, event, response.Haiku)
+
+	return response, nil
+}
+
+// notify tells each notifier about a new haiku. Notifications are best
+// effort, so a failing notifier only logs.
+func (s *WebhookService) notify(ctx context.Context, event Event, text string) {
+	for _, notifier := range s.notifiers {
+		err := notifier.Notify(ctx, extension.Notification{
+			Haiku:         text,
+			CommitMessage: event.CommitMessage,
+			Repository:    event.Target.Repository,
+		})
+		if err != nil {
+			logging.Errorf("[WEBHOOK SERVICE] error notifying about haiku for %s: %v\n", event.Target.Repository, err)
+		}
+	}
+}
+
+// FormatComment renders a haiku as a markdown quote, one line per line.
+func FormatComment(text string) string {
+	var comment strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		comment.WriteString("> ")
+		comment.WriteString(strings.TrimSpace(line))
+		comment.WriteString("\n")
+	}
+	comment.WriteString("\n")
+	comment.WriteString(commentFooter)
+	return comment.String()
+}
@@ -0,0 +1,379 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+	LastRequest      haiku.HaikuCommitRequest
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.LastRequest = request
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+type MockCommenter struct {
+	ErrorToReturn error
+	LastTarget    Target
+	LastBody      string
+	Calls         int
+}
+
+func (m *MockCommenter) Comment(ctx context.Context, target Target, body string) error {
+	m.Calls++
+	m.LastTarget = target
+	m.LastBody = body
+	return m.ErrorToReturn
+}
+
+type MockNotifier struct {
+	ErrorToReturn    error
+	LastNotification *extension.Notification
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, note extension.Notification) error {
+	m.LastNotification = &note
+	return m.ErrorToReturn
+}
+
+type MockGitHubAPI struct {
+	CommitSHA   string
+	IssueNumber int
+}
+
+func (m *MockGitHubAPI) CreateCommitComment(ctx context.Context, installationID int64, owner, repo, sha, body string) error {
+	m.CommitSHA = sha
+	return nil
+}
+
+func (m *MockGitHubAPI) CreateIssueComment(ctx context.Context, installationID int64, owner, repo string, number int, body string) error {
+	m.IssueNumber = number
+	return nil
+}
+
+type MockGitLabAPI struct {
+	CommitSHA       string
+	MergeRequestIID int
+}
+
+func (m *MockGitLabAPI) CreateCommitComment(ctx context.Context, project, sha, note string) error {
+	m.CommitSHA = sha
+	return nil
+}
+
+func (m *MockGitLabAPI) CreateMergeRequestNote(ctx context.Context, project string, iid int, body string) error {
+	m.MergeRequestIID = iid
+	return nil
+}
+
+type MockBitbucketAPI struct {
+	Repository string
+	CommitSHA  string
+}
+
+func (m *MockBitbucketAPI) CreateCommitComment(ctx context.Context, repository, sha, body string) error {
+	m.Repository = repository
+	m.CommitSHA = sha
+	return nil
+}
+
+func TestHandleEvent(t *testing.T) {
+	target := Target{Repository: "octo/leaves", CommitSHA: "abc123", InstallationID: 42}
+
+	tests := []struct {
+		name          string
+		event         Event
+		commenter     *MockCommenter
+		haikuError    error
+		expectComment bool
+		errorIs       error
+	}{
+		{
+			name:          "Haiku posted",
+			event:         Event{CommitMessage: "fix: resolved login issue", Target: target},
+			commenter:     &MockCommenter{},
+			expectComment: true,
+		},
+		{
+			name:  "No commenter",
+			event: Event{CommitMessage: "fix: resolved login issue", Target: target},
+		},
+		{
+			name:      "Empty commit message",
+			event:     Event{CommitMessage: " ", Target: target},
+			commenter: &MockCommenter{},
+			errorIs:   ErrBadEvent,
+		},
+		{
+			name:       "Generation error",
+			event:      Event{CommitMessage: "fix: resolved login issue", Target: target},
+			commenter:  &MockCommenter{},
+			haikuError: haiku.ErrContentBlocked,
+			errorIs:    haiku.ErrContentBlocked,
+		},
+		{
+			name:      "Comment error",
+			event:     Event{CommitMessage: "fix: resolved login issue", Target: target},
+			commenter: &MockCommenter{ErrorToReturn: errors.New("forbidden")},
+			errorIs:   ErrComment,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			haikuService := &MockHaikuService{
+				ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku},
+				ErrorToReturn:    tc.haikuError,
+			}
+
+			var commenter Commenter
+			if tc.commenter != nil {
+				commenter = tc.commenter
+			}
+
+			response, err := NewWebhookService(haikuService, &Options{Commenter: commenter}).HandleEvent(context.Background(), tc.event)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			if response.Haiku != testHaiku {
+				t.Errorf("Expected haiku %q, got %q", testHaiku, response.Haiku)
+			}
+			if haikuService.LastRequest.Repository == nil || haikuService.LastRequest.Repository.Name != "octo/leaves" {
+				t.Errorf("Expected the repository to be passed to the haiku service, got %+v", haikuService.LastRequest.Repository)
+			}
+			if haikuService.LastRequest.CommitRef != target.CommitSHA {
+				t.Errorf("Expected the commit SHA to be passed to the haiku service, got %q", haikuService.LastRequest.CommitRef)
+			}
+			if tc.expectComment {
+				if tc.commenter.LastTarget != target || tc.commenter.LastBody != FormatComment(testHaiku) {
+					t.Errorf("Expected comment on %+v, got %q on %+v", target, tc.commenter.LastBody, tc.commenter.LastTarget)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleEventNotifies(t *testing.T) {
+	event := Event{CommitMessage: "fix: resolved login issue", Target: Target{Repository: "octo/leaves", CommitSHA: "abc123"}}
+
+	tests := []struct {
+		name           string
+		commentError   error
+		notifierErrors []error
+		expectNotified bool
+		errorIs        error
+	}{
+		{
+			name:           "Notified after comment",
+			notifierErrors: []error{nil, nil},
+			expectNotified: true,
+		},
+		{
+			name:           "Failing notifier does not fail the event",
+			notifierErrors: []error{errors.New("slack is down"), nil},
+			expectNotified: true,
+		},
+		{
+			name:           "Not notified when the comment fails",
+			commentError:   errors.New("forbidden"),
+			notifierErrors: []error{nil},
+			errorIs:        ErrComment,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var notifiers []extension.Notifier
+			var mocks []*MockNotifier
+			for _, err := range tc.notifierErrors {
+				mock := &MockNotifier{ErrorToReturn: err}
+				mocks = append(mocks, mock)
+				notifiers = append(notifiers, mock)
+			}
+
+			service := NewWebhookService(&MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}, &Options{
+				Commenter: &MockCommenter{ErrorToReturn: tc.commentError},
+				Notifiers: notifiers,
+			})
+			_, err := service.HandleEvent(context.Background(), event)
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+			} else if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+
+			for i, mock := range mocks {
+				if !tc.expectNotified {
+					if mock.LastNotification != nil {
+						t.Errorf("Expected notifier %d not to be called, got %+v", i, mock.LastNotification)
+					}
+					continue
+				}
+				expected := extension.Notification{Haiku: testHaiku, CommitMessage: event.CommitMessage, Repository: "octo/leaves"}
+				if mock.LastNotification == nil || *mock.LastNotification != expected {
+					t.Errorf("Expected notifier %d to receive %+v, got %+v", i, expected, mock.LastNotification)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatComment(t *testing.T) {
+	expected := "> Old cracks mended now\n> the login door swings open\n> quiet in the logs\n\n" + commentFooter
+	if got := FormatComment(testHaiku + "\n"); got != expected {
+		t.Errorf("Expected comment %q, got %q", expected, got)
+	}
+}
+
+func TestGitHubCommenter(t *testing.T) {
+	tests := []struct {
+		name          string
+		target        Target
+		expectedSHA   string
+		expectedIssue int
+		errorIs       error
+	}{
+		{
+			name:        "Commit",
+			target:      Target{Repository: "octo/leaves", CommitSHA: "abc123"},
+			expectedSHA: "abc123",
+		},
+		{
+			name:          "Pull request",
+			target:        Target{Repository: "octo/leaves", CommitSHA: "abc123", PullRequest: 7},
+			expectedIssue: 7,
+		},
+		{
+			name:    "Invalid repository",
+			target:  Target{Repository: "leaves", CommitSHA: "abc123"},
+			errorIs: ErrBadEvent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &MockGitHubAPI{}
+			err := NewGitHubCommenter(client).Comment(context.Background(), tc.target, "body")
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if client.CommitSHA != tc.expectedSHA || client.IssueNumber != tc.expectedIssue {
+				t.Errorf("Expected comment on sha %q / issue %d, got %q / %d", tc.expectedSHA, tc.expectedIssue, client.CommitSHA, client.IssueNumber)
+			}
+		})
+	}
+}
+
+func TestGitLabCommenter(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      Target
+		expectedSHA string
+		expectedIID int
+		errorIs     error
+	}{
+		{
+			name:        "Commit",
+			target:      Target{Repository: "group/sub/leaves", CommitSHA: "abc123"},
+			expectedSHA: "abc123",
+		},
+		{
+			name:        "Merge request",
+			target:      Target{Repository: "group/sub/leaves", PullRequest: 7},
+			expectedIID: 7,
+		},
+		{
+			name:    "Missing repository",
+			target:  Target{CommitSHA: "abc123"},
+			errorIs: ErrBadEvent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &MockGitLabAPI{}
+			err := NewGitLabCommenter(client).Comment(context.Background(), tc.target, "body")
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if client.CommitSHA != tc.expectedSHA || client.MergeRequestIID != tc.expectedIID {
+				t.Errorf("Expected comment on sha %q / merge request %d, got %q / %d", tc.expectedSHA, tc.expectedIID, client.CommitSHA, client.MergeRequestIID)
+			}
+		})
+	}
+}
+
+func TestBitbucketCommenter(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      Target
+		expectedSHA string
+		errorIs     error
+	}{
+		{
+			name:        "Commit",
+			target:      Target{Repository: "leafy/leaves", CommitSHA: "abc123"},
+			expectedSHA: "abc123",
+		},
+		{
+			name:    "Missing commit",
+			target:  Target{Repository: "leafy/leaves"},
+			errorIs: ErrBadEvent,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &MockBitbucketAPI{}
+			err := NewBitbucketCommenter(client).Comment(context.Background(), tc.target, "body")
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if client.Repository != tc.target.Repository || client.CommitSHA != tc.expectedSHA {
+				t.Errorf("Expected comment on %s@%s, got %s@%s", tc.target.Repository, tc.expectedSHA, client.Repository, client.CommitSHA)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+// Package workflow exposes the steps of writing and posting a haiku as
+// separate tasks, so that a Step Functions state machine can orchestrate them
+// outside the HTTP path, e.g. to retry a post without regenerating the haiku
+// or to route blocked haiku to a human. Each task takes and returns a typed,
+// JSON encoded struct.
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+)
+
+var (
+	ErrBadTaskInput  = errors.New("invalid task input")
+	ErrUnknownTask   = errors.New("unknown task")
+	ErrNotConfigured = errors.New("task is not configured")
+	ErrPostHaiku     = errors.New("error posting haiku")
+)
+
+// Task names, as given in the task field of a state's payload.
+const (
+	TaskGenerate          = "generate"
+	TaskValidateSyllables = "validateSyllables"
+	TaskModerate          = "moderate"
+	TaskPostToGitHub      = "postToGitHub"
+)
+
+// GenerateInput is the same request POST /haiku accepts.
+type GenerateInput struct {
+	haiku.HaikuCommitRequest
+}
+
+// GenerateOutput is the same response POST /haiku returns.
+type GenerateOutput struct {
+	haiku.HaikuCommitResponse
+}
+
+type ValidateSyllablesInput struct {
+	Haiku string `json:"haiku"`
+}
+
+// ValidateSyllablesOutput reports whether a haiku is 5-7-5. An invalid haiku is
+// an output rather than a failure, so that a Choice state can branch on it.
+type ValidateSyllablesOutput struct {
+	Valid     bool   `json:"valid"`
+	Syllables []int  `json:"syllables"`        // Estimated syllables per line
+	Reason    string `json:"reason,omitempty"` // How the haiku differs from 5-7-5; empty when valid
+}
+
+type ModerateInput struct {
+	Text string `json:"text"`
+}
+
+// ModerateOutput reports whether text would be blocked. Like an invalid haiku,
+// blocked text is an output rather than a failure.
+type ModerateOutput struct {
+	Blocked bool   `json:"blocked"`
+	Reason  string `json:"reason,omitempty"` // Why the text was blocked; empty when it was allowed
+}
+
+// PostToGitHubInput is a haiku and where to post it. The haiku is posted on
+// the pull request when one is given, and on the commit otherwise.
+type PostToGitHubInput struct {
+	Haiku          string `json:"haiku"`
+	Repository     string `json:"repository"`            // owner/name
+	CommitSHA      string `json:"commitSha,omitempty"`   // Commit to comment on, when there is no pull request
+	PullRequest    int    `json:"pullRequest,omitempty"` // Pull request to comment on, when set
+	InstallationID int64  `json:"installationId"`        // GitHub App installation for the repository
+}
+
+type PostToGitHubOutput struct {
+	Posted bool `json:"posted"`
+}
+
+type HaikuService interface {
+	CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error)
+}
+
+type WorkflowService struct {
+	haikuService HaikuService
+	moderator    moderation.Moderator
+	github       webhook.Commenter
+}
+
+// NewWorkflowService builds the task handlers. The moderate task allows
+// everything without a moderator, and the GitHub task fails with
+// ErrNotConfigured without a commenter.
+func NewWorkflowService(haikuService HaikuService, moderator moderation.Moderator, github webhook.Commenter) *WorkflowService {
+	return &WorkflowService{
+		haikuService: haikuService,
+		moderator:    moderator,
+		github:       github,
+	}
+}
+
+// Run decodes input for the named task, runs it, and returns its output.
+func (s *WorkflowService) Run(ctx context.Context, task string, input json.RawMessage) (any, error) {
+	switch task {
+	case TaskGenerate:
+		return run(ctx, input, s.Generate)
+	case TaskValidateSyllables:
+		return run(ctx, input, s.ValidateSyllables)
+	case TaskModerate:
+		return run(ctx, input, s.Moderate)
+	case TaskPostToGitHub:
+		return run(ctx, input, s.PostToGitHub)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTask, task)
+	}
+}
+
+func run[I any, O any](ctx context.Context, input json.RawMessage, handler func(context.Context, I) (O, error)) (any, error) {
+	var in I
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &in); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadTaskInput, err)
+		}
+	}
+	return handler(ctx, in)
+}
+
+// Generate writes a haiku exactly as POST /haiku would.
+func (s *WorkflowService) Generate(ctx context.Context, input GenerateInput) (GenerateOutput, error) {
+	if input.CommitMessage == "" {
+		return GenerateOutput{}, fmt.Errorf("%w: commitMessage is required", ErrBadTaskInput)
+	}
+
+	response, err := s.haikuService.CreateHaiku(ctx, input.HaikuCommitRequest)
+	if err != nil {
+		return GenerateOutput{}, err
+	}
+	return GenerateOutput{HaikuCommitResponse: response}, nil
+}
+
+// ValidateSyllables checks that a haiku is 5-7-5, within the same tolerance
+// strict requests allow.
+func (s *WorkflowService) ValidateSyllables(ctx context.Context, input ValidateSyllablesInput) (ValidateSyllablesOutput, error) {
+	if input.Haiku == "" {
+		return ValidateSyllablesOutput{}, fmt.Errorf("%w: haiku is required", ErrBadTaskInput)
+	}
+
+	output := ValidateSyllablesOutput{Valid: true, Syllables: haiku.LineSyllables(input.Haiku)}
+	if err := haiku.CheckStructure(input.Haiku); err != nil {
+		output.Valid = false
+		output.Reason = err.Error()
+	}
+	return output, nil
+}
+
+// Moderate screens text with the same moderators generated haiku go through.
+func (s *WorkflowService) Moderate(ctx context.Context, input ModerateInput) (ModerateOutput, error) {
+	if input.Text == "" {
+		return ModerateOutput{}, fmt.Errorf("%w: text is required", ErrBadTaskInput)
+	}
+	if s.moderator == nil {
+		return ModerateOutput{}, nil
+	}
+
+	result, err := s.moderator.Moderate(ctx, input.Text)
+	if err != nil {
+		return ModerateOutput{}, fmt.Errorf("moderating text: %w", err)
+	}
+	return ModerateOutput{Blocked: result.Blocked, Reason: result.Reason}, nil
+}
+
+// PostToGitHub comments the haiku on GitHub, formatted as webhook comments
+// are.
+func (s *WorkflowService) PostToGitHub(ctx context.Context, input PostToGitHubInput) (PostToGitHubOutput, error) {
+	if s.github == nil {
+		return PostToGitHubOutput{}, fmt.Errorf("%w: no GitHub App", ErrNotConfigured)
+	}
+	if input.Haiku == "" {
+		return PostToGitHubOutput{}, fmt.Errorf("%w: haiku is required", ErrBadTaskInput)
+	}
+	if input.CommitSHA == "" && input.PullRequest <= 0 {
+		return PostToGitHubOutput{}, fmt.Errorf("%w: commitSha or pullRequest is required", ErrBadTaskInput)
+	}
+
+	target := webhook.Target{
+		Repository:     input.Repository,
+		CommitSHA:      input.CommitSHA,
+		PullRequest:    input.PullRequest,
+		InstallationID: input.InstallationID,
+	}
+	if err := s.github.Comment(ctx, target, webhook.FormatComment(input.Haiku)); err != nil {
+		if errors.Is(err, webhook.ErrBadEvent) {
+			return PostToGitHubOutput{}, fmt.Errorf("%w: %w", ErrBadTaskInput, err)
+		}
+		logging.Errorf("[WORKFLOW SERVICE] error posting haiku to %s: %v\n", input.Repository, err)
+		return PostToGitHubOutput{}, fmt.Errorf("%w: %w", ErrPostHaiku, err)
+	}
+	return PostToGitHubOutput{Posted: true}, nil
+}
@@ -0,0 +1,245 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/moderation"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+)
+
+const testHaiku = "Old cracks mended now\nthe login door swings open\nquiet in the logs"
+
+type MockHaikuService struct {
+	ResponseToReturn haiku.HaikuCommitResponse
+	ErrorToReturn    error
+	LastRequest      haiku.HaikuCommitRequest
+}
+
+func (m *MockHaikuService) CreateHaiku(ctx context.Context, request haiku.HaikuCommitRequest) (haiku.HaikuCommitResponse, error) {
+	m.LastRequest = request
+	return m.ResponseToReturn, m.ErrorToReturn
+}
+
+type MockModerator struct {
+	ResultToReturn moderation.Result
+	ErrorToReturn  error
+}
+
+func (m *MockModerator) Moderate(ctx context.Context, text string) (moderation.Result, error) {
+	return m.ResultToReturn, m.ErrorToReturn
+}
+
+type MockCommenter struct {
+	ErrorToReturn error
+	LastTarget    webhook.Target
+	LastBody      string
+}
+
+func (m *MockCommenter) Comment(ctx context.Context, target webhook.Target, body string) error {
+	m.LastTarget = target
+	m.LastBody = body
+	return m.ErrorToReturn
+}
+
+func TestRun(t *testing.T) {
+	haikuService := &MockHaikuService{ResponseToReturn: haiku.HaikuCommitResponse{Haiku: testHaiku}}
+	service := NewWorkflowService(haikuService, &MockModerator{}, &MockCommenter{})
+
+	tests := []struct {
+		name     string
+		task     string
+		input    string
+		expected string
+		errorIs  error
+	}{
+		{
+			name:     "Generate",
+			task:     TaskGenerate,
+			input:    `{"commitMessage":"fix: resolved login issue","mood":"technical"}`,
+			expected: `{"haiku":"Old cracks mended now\nthe login door swings open\nquiet in the logs","metadata":{}}`,
+		},
+		{
+			name:     "Validate syllables",
+			task:     TaskValidateSyllables,
+			input:    `{"haiku":"Old cracks mended now\nthe login door swings open\nquiet in the logs"}`,
+			expected: `{"valid":true,"syllables":[5,7,5]}`,
+		},
+		{
+			name:     "Moderate",
+			task:     TaskModerate,
+			input:    `{"text":"Old cracks mended now"}`,
+			expected: `{"blocked":false}`,
+		},
+		{
+			name:     "Post to GitHub",
+			task:     TaskPostToGitHub,
+			input:    `{"haiku":"Old cracks mended now","repository":"octo/leaves","pullRequest":7,"installationId":42}`,
+			expected: `{"posted":true}`,
+		},
+		{
+			name:    "Unknown task",
+			task:    "translate",
+			input:   `{}`,
+			errorIs: ErrUnknownTask,
+		},
+		{
+			name:    "Malformed input",
+			task:    TaskModerate,
+			input:   `{"text":7}`,
+			errorIs: ErrBadTaskInput,
+		},
+		{
+			name:    "Missing input",
+			task:    TaskGenerate,
+			errorIs: ErrBadTaskInput,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := service.Run(context.Background(), tc.task, json.RawMessage(tc.input))
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			encoded, err := json.Marshal(output)
+			if err != nil {
+				t.Fatalf("Failed to marshal output: %v", err)
+			}
+			if string(encoded) != tc.expected {
+				t.Errorf("Expected output %s, got %s", tc.expected, encoded)
+			}
+		})
+	}
+
+	if haikuService.LastRequest.Mood != haiku.MoodTechnical {
+		t.Errorf("Expected the generate input to reach the haiku service, got %+v", haikuService.LastRequest)
+	}
+}
+
+func TestValidateSyllables(t *testing.T) {
+	service := NewWorkflowService(&MockHaikuService{}, nil, nil)
+
+	output, err := service.ValidateSyllables(context.Background(), ValidateSyllablesInput{Haiku: "Old cracks mended now\nthe login door swings open"})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if output.Valid || !reflect.DeepEqual(output.Syllables, []int{5, 7}) || output.Reason == "" {
+		t.Errorf("Expected an invalid haiku of 5-7 syllables with a reason, got %+v", output)
+	}
+}
+
+func TestModerate(t *testing.T) {
+	tests := []struct {
+		name      string
+		moderator moderation.Moderator
+		expected  ModerateOutput
+		expectErr bool
+	}{
+		{
+			name:      "Blocked",
+			moderator: &MockModerator{ResultToReturn: moderation.Result{Blocked: true, Reason: "blocked word list"}},
+			expected:  ModerateOutput{Blocked: true, Reason: "blocked word list"},
+		},
+		{
+			name:      "Moderator fails",
+			moderator: &MockModerator{ErrorToReturn: errors.New("guardrail unavailable")},
+			expectErr: true,
+		},
+		{
+			name:     "No moderator",
+			expected: ModerateOutput{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := NewWorkflowService(&MockHaikuService{}, tc.moderator, nil)
+
+			output, err := service.Moderate(context.Background(), ModerateInput{Text: testHaiku})
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Expected error %v, got %v", tc.expectErr, err)
+			}
+			if output != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestPostToGitHub(t *testing.T) {
+	input := PostToGitHubInput{Haiku: testHaiku, Repository: "octo/leaves", CommitSHA: "abc123", InstallationID: 42}
+
+	tests := []struct {
+		name      string
+		commenter *MockCommenter
+		input     PostToGitHubInput
+		errorIs   error
+	}{
+		{
+			name:      "Posted",
+			commenter: &MockCommenter{},
+			input:     input,
+		},
+		{
+			name:    "No GitHub App",
+			input:   input,
+			errorIs: ErrNotConfigured,
+		},
+		{
+			name:      "No commit or pull request",
+			commenter: &MockCommenter{},
+			input:     PostToGitHubInput{Haiku: testHaiku, Repository: "octo/leaves"},
+			errorIs:   ErrBadTaskInput,
+		},
+		{
+			name:      "Bad repository",
+			commenter: &MockCommenter{ErrorToReturn: webhook.ErrBadEvent},
+			input:     input,
+			errorIs:   ErrBadTaskInput,
+		},
+		{
+			name:      "GitHub fails",
+			commenter: &MockCommenter{ErrorToReturn: errors.New("502 bad gateway")},
+			input:     input,
+			errorIs:   ErrPostHaiku,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var commenter webhook.Commenter
+			if tc.commenter != nil {
+				commenter = tc.commenter
+			}
+			service := NewWorkflowService(&MockHaikuService{}, nil, commenter)
+
+			output, err := service.PostToGitHub(context.Background(), tc.input)
+			if !errors.Is(err, tc.errorIs) {
+				t.Fatalf("Expected error %v, got %v", tc.errorIs, err)
+			}
+			if tc.errorIs != nil {
+				return
+			}
+
+			if !output.Posted {
+				t.Error("Expected the haiku to be posted")
+			}
+			if tc.commenter.LastTarget.CommitSHA != "abc123" || tc.commenter.LastTarget.InstallationID != 42 {
+				t.Errorf("Expected the commit target, got %+v", tc.commenter.LastTarget)
+			}
+			if !strings.HasPrefix(tc.commenter.LastBody, "> Old cracks mended now\n") {
+				t.Errorf("Expected the haiku formatted as a comment, got %q", tc.commenter.LastBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Package syllable estimates the number of syllables in English text.
+//
+// Counts come from spelling rules rather than a pronunciation dictionary, so
+// they are usually right for common words and may be off by one for unusual
+// ones. Callers comparing counts should allow for that.
+package syllable
+
+import (
+	"strings"
+	"unicode"
+)
+
+// digitSyllables is how many syllables each digit has when read aloud.
+var digitSyllables = map[rune]int{
+	'0': 2, '1': 1, '2': 1, '3': 1, '4': 1, '5': 1, '6': 1, '7': 2, '8': 1, '9': 1,
+}
+
+// extraSyllables are letter sequences read as two syllables that the vowel
+// group rule counts as one, e.g. "quiet" and "going".
+var extraSyllables = []string{"iet", "ien", "ia", "io", "ua", "uo", "ii", "oem", "oet", "uel", "eing", "oing", "uing"}
+
+// notExtraSyllables are exceptions to extraSyllables read as one syllable,
+// e.g. "nation" and "gracious".
+var notExtraSyllables = []string{"tion", "sion", "cious", "tious", "gious", "cial", "tial"}
+
+// Count returns the estimated number of syllables in text, summed over its
+// words.
+func Count(text string) int {
+	total := 0
+	for _, word := range strings.FieldsFunc(text, isSeparator) {
+		total += CountWord(word)
+	}
+	return total
+}
+
+// CountWord returns the estimated number of syllables in a single word.
+// Numbers are counted digit by digit; punctuation is ignored.
+func CountWord(word string) int {
+	var letters strings.Builder
+	digits := 0
+	for _, r := range strings.ToLower(word) {
+		switch {
+		case r >= 'a' && r <= 'z':
+			letters.WriteRune(r)
+		case unicode.IsDigit(r):
+			digits += digitSyllables[r]
+		}
+	}
+	if letters.Len() == 0 {
+		return digits
+	}
+	return digits + countLetters(letters.String())
+}
+
+func countLetters(word string) int {
+	if len(word) <= 3 {
+		return 1
+	}
+
+	// A "u" after "q" is part of the consonant, as in "quiet".
+	word = strings.ReplaceAll(word, "qu", "qw")
+
+	word = trimSilentEnding(word)
+
+	// A "y" is a vowel unless it starts the word or follows one, as in "yes"
+	// and "beyond".
+	count := 0
+	inVowel := false
+	for i, r := range word {
+		vowel := isVowel(r) || (r == 'y' && i > 0 && !inVowel)
+		if vowel && !inVowel {
+			count++
+		}
+		inVowel = vowel
+	}
+
+	for _, sequence := range extraSyllables {
+		count += strings.Count(word, sequence)
+	}
+	for _, sequence := range notExtraSyllables {
+		count -= strings.Count(word, sequence)
+	}
+
+	return max(count, 1)
+}
+
+// trimSilentEnding drops endings that are spelled with a vowel but not
+// pronounced as a syllable, such as the "e" in "stone" and the "ed" in
+// "jumped".
+func trimSilentEnding(word string) string {
+	switch {
+	case strings.HasSuffix(word, "le") && len(word) > 2 && !isVowel(rune(word[len(word)-3])):
+		// "table" and "bottle" keep their final syllable.
+		return word
+	case strings.HasSuffix(word, "ed") && !strings.HasSuffix(word, "ted") && !strings.HasSuffix(word, "ded"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "es") && !hasSibilantEnding(word[:len(word)-2]):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "e") && !strings.HasSuffix(word, "ee"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// hasSibilantEnding reports whether a plural "es" after stem is pronounced,
+// as in "boxes" and "branches".
+func hasSibilantEnding(stem string) bool {
+	for _, ending := range []string{"s", "x", "z", "ch", "sh", "c", "g"} {
+		if strings.HasSuffix(stem, ending) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVowel(r rune) bool {
+	return strings.ContainsRune("aeiou", r)
+}
+
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r) || r == '-' || r == '/' || r == '_'
+}
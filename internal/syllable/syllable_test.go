@@ -0,0 +1,53 @@
+package syllable
+
+import "testing"
+
+func TestCountWord(t *testing.T) {
+	tests := []struct {
+		word     string
+		expected int
+	}{
+		{"old", 1},
+		{"mended", 2},
+		{"jumped", 1},
+		{"stone", 1},
+		{"table", 2},
+		{"branches", 2},
+		{"quiet", 2},
+		{"going", 2},
+		{"beyond", 2},
+		{"nation", 2},
+		{"deployment", 3},
+		{"Silence,", 2},
+		{"404", 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.word, func(t *testing.T) {
+			if count := CountWord(tc.word); count != tc.expected {
+				t.Errorf("Expected %d syllables in %q, got %d", tc.expected, tc.word, count)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{"Old cracks mended now", 5},
+		{"the login door swings open", 7},
+		{"quiet in the logs", 5},
+		{"follow-up", 3},
+		{"", 0},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.text, func(t *testing.T) {
+			if count := Count(tc.text); count != tc.expected {
+				t.Errorf("Expected %d syllables in %q, got %d", tc.expected, tc.text, count)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Package timing records how long each stage of a request takes, so slow
+// requests can be traced to the stage responsible. Stages are recorded on a
+// Timings carried by the request context and rendered as a Server-Timing
+// header. With a Tracer, each stage is also traced as a span of its own.
+package timing
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stage names used across the generation pipeline.
+const (
+	StageValidate     = "validate"
+	StagePrompt       = "prompt"
+	StageProvider     = "provider"
+	StageBedrock      = "bedrock" // The Bedrock call itself, within provider
+	StageParse        = "parse"   // Decoding the model's response, within provider
+	StageModeration   = "moderation"
+	StageSummary      = "summary"
+	StageIllustration = "illustration"
+	StageShareCard    = "share-card"
+	StageAudio        = "audio"
+	StageRender       = "render"
+	StagePostProcess  = "post-process"
+)
+
+type contextKey struct{}
+
+// Stage is the total time spent in one named stage.
+type Stage struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Tracer records each stage as a span of a distributed trace, e.g. an X-Ray
+// subsegment.
+type Tracer interface {
+	Trace(name string, start time.Time, end time.Time)
+}
+
+// Timings collects stage durations for one request. It is safe for concurrent
+// use, since some stages run in parallel.
+type Timings struct {
+	mu      sync.Mutex
+	stages  []Stage
+	started time.Time
+	tracer  Tracer
+}
+
+// NewContext returns a context carrying a new Timings, started now. Stages
+// are traced with tracer, when it isn't nil.
+func NewContext(ctx context.Context, tracer Tracer) (context.Context, *Timings) {
+	timings := &Timings{started: time.Now(), tracer: tracer}
+	return context.WithValue(ctx, contextKey{}, timings), timings
+}
+
+// FromContext returns the context's Timings, or nil when it has none.
+func FromContext(ctx context.Context) *Timings {
+	timings, _ := ctx.Value(contextKey{}).(*Timings)
+	return timings
+}
+
+// Start begins timing a stage and returns the function that ends it. Without
+// Timings in the context it does nothing, so callers need not check.
+func Start(ctx context.Context, name string) func() {
+	timings := FromContext(ctx)
+	if timings == nil {
+		return func() {}
+	}
+
+	started := time.Now()
+	return func() {
+		ended := time.Now()
+		timings.Add(name, ended.Sub(started))
+		if timings.tracer != nil {
+			timings.tracer.Trace(name, started, ended)
+		}
+	}
+}
+
+// Elapsed returns the time since the Timings was started.
+func (t *Timings) Elapsed() time.Duration {
+	return time.Since(t.started)
+}
+
+// Add records time spent in a stage. A stage entered more than once, such as
+// a provider call repeated after moderation, accumulates its durations.
+func (t *Timings) Add(name string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.stages {
+		if t.stages[i].Name == name {
+			t.stages[i].Duration += duration
+			return
+		}
+	}
+	t.stages = append(t.stages, Stage{Name: name, Duration: duration})
+}
+
+// Stages returns the recorded stages in the order they were first entered.
+func (t *Timings) Stages() []Stage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Stage(nil), t.stages...)
+}
+
+// Header renders the stages, followed by total, as a Server-Timing header
+// value with durations in milliseconds, e.g. "prompt;dur=0.4, total;dur=812.3".
+func (t *Timings) Header(total time.Duration) string {
+	var header strings.Builder
+	for _, stage := range t.Stages() {
+		fmt.Fprintf(&header, "%s;dur=%s, ", stage.Name, milliseconds(stage.Duration))
+	}
+	fmt.Fprintf(&header, "total;dur=%s", milliseconds(total))
+	return header.String()
+}
+
+// Milliseconds returns the milliseconds spent in each stage, and in total, as
+// the Server-Timing header reports them.
+func (t *Timings) Milliseconds(total time.Duration) map[string]float64 {
+	stages := t.Stages()
+	report := make(map[string]float64, len(stages)+1)
+	for _, stage := range stages {
+		report[stage.Name] = roundMilliseconds(stage.Duration)
+	}
+	report["total"] = roundMilliseconds(total)
+	return report
+}
+
+func milliseconds(duration time.Duration) string {
+	return fmt.Sprintf("%.1f", roundMilliseconds(duration))
+}
+
+// roundMilliseconds returns duration in milliseconds, to the tenth.
+func roundMilliseconds(duration time.Duration) float64 {
+	return math.Round(float64(duration.Microseconds())/100) / 10
+}
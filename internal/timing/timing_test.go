@@ -0,0 +1,85 @@
+package timing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTimings(t *testing.T) {
+	timings := &Timings{}
+	timings.Add(StagePrompt, 400*time.Microsecond)
+	timings.Add(StageProvider, 800*time.Millisecond)
+	timings.Add(StageProvider, 12300*time.Microsecond)
+
+	expected := "prompt;dur=0.4, provider;dur=812.3, total;dur=900.0"
+	if got := timings.Header(900 * time.Millisecond); got != expected {
+		t.Errorf("Expected header %q, got %q", expected, got)
+	}
+}
+
+func TestStart(t *testing.T) {
+	// Without Timings in the context, stages are not recorded.
+	Start(context.Background(), StagePrompt)()
+
+	ctx, timings := NewContext(context.Background(), nil)
+	if FromContext(ctx) != timings {
+		t.Fatal("Expected the context to carry the timings")
+	}
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Start(ctx, StageSummary)()
+		}()
+	}
+	wg.Wait()
+
+	stages := timings.Stages()
+	if len(stages) != 1 || stages[0].Name != StageSummary {
+		t.Errorf("Expected concurrent stages to accumulate into one, got %+v", stages)
+	}
+}
+
+type MockTracer struct {
+	mu    sync.Mutex
+	Names []string
+}
+
+func (m *MockTracer) Trace(name string, start time.Time, end time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.Names = append(m.Names, name)
+}
+
+func TestStartTraces(t *testing.T) {
+	tracer := &MockTracer{}
+	ctx, _ := NewContext(context.Background(), tracer)
+
+	Start(ctx, StageBedrock)()
+	Start(ctx, StageParse)()
+
+	if len(tracer.Names) != 2 || tracer.Names[0] != StageBedrock || tracer.Names[1] != StageParse {
+		t.Errorf("Expected each stage traced, got %v", tracer.Names)
+	}
+}
+
+func TestMilliseconds(t *testing.T) {
+	timings := &Timings{}
+	timings.Add(StagePrompt, 400*time.Microsecond)
+	timings.Add(StageBedrock, 812345*time.Microsecond)
+
+	report := timings.Milliseconds(900 * time.Millisecond)
+	expected := map[string]float64{StagePrompt: 0.4, StageBedrock: 812.3, "total": 900}
+	if len(report) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, report)
+	}
+	for name, ms := range expected {
+		if report[name] != ms {
+			t.Errorf("Expected %s of %v ms, got %v", name, ms, report[name])
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package timing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/logging"
+)
+
+// TraceHeaderEnv holds the X-Ray trace header of the invocation being
+// handled. The Lambda runtime sets it for each invocation.
+const TraceHeaderEnv = "_X_AMZN_TRACE_ID"
+
+// xrayHeader precedes every document sent to the X-Ray daemon.
+const xrayHeader = `{"format": "json", "version": 1}` + "\n"
+
+// XRayTracer sends each stage to the X-Ray daemon as a subsegment of the
+// invocation's segment, so that stages show on the invocation's trace. Only
+// sampled invocations are traced.
+type XRayTracer struct {
+	conn   net.Conn
+	header func() string
+}
+
+// NewXRayTracer sends subsegments to the daemon at address, e.g.
+// "169.254.79.129:2000", as Lambda sets it in AWS_XRAY_DAEMON_ADDRESS.
+func NewXRayTracer(address string) (*XRayTracer, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to the X-Ray daemon: %w", err)
+	}
+
+	return &XRayTracer{
+		conn:   conn,
+		header: func() string { return os.Getenv(TraceHeaderEnv) },
+	}, nil
+}
+
+type subsegment struct {
+	Name      string  `json:"name"`
+	ID        string  `json:"id"`
+	TraceID   string  `json:"trace_id"`
+	ParentID  string  `json:"parent_id"`
+	Type      string  `json:"type"`
+	StartTime float64 `json:"start_time"`
+	EndTime   float64 `json:"end_time"`
+}
+
+func (t *XRayTracer) Trace(name string, start time.Time, end time.Time) {
+	traceID, parentID, sampled := parseTraceHeader(t.header())
+	if !sampled {
+		return
+	}
+
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	document, err := json.Marshal(subsegment{
+		Name:      name,
+		ID:        hex.EncodeToString(id),
+		TraceID:   traceID,
+		ParentID:  parentID,
+		Type:      "subsegment",
+		StartTime: epochSeconds(start),
+		EndTime:   epochSeconds(end),
+	})
+	if err != nil {
+		logging.Errorf("[TIMING] error encoding subsegment %s: %v", name, err)
+		return
+	}
+
+	// Tracing is best effort: a lost subsegment only leaves a gap in the trace.
+	if _, err := t.conn.Write(append([]byte(xrayHeader), document...)); err != nil {
+		logging.Warnf("[TIMING] error sending subsegment %s to the X-Ray daemon: %v", name, err)
+	}
+}
+
+// parseTraceHeader returns the trace and parent segment IDs of a header such
+// as "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+// and whether the trace is sampled.
+func parseTraceHeader(header string) (traceID string, parentID string, sampled bool) {
+	for _, field := range strings.Split(header, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "Root":
+			traceID = value
+		case "Parent":
+			parentID = value
+		case "Sampled":
+			sampled = value == "1"
+		}
+	}
+	return traceID, parentID, sampled && traceID != "" && parentID != ""
+}
+
+func epochSeconds(t time.Time) float64 {
+	return float64(t.UnixMicro()) / 1e6
+}
@@ -0,0 +1,92 @@
+package timing
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestParseTraceHeader(t *testing.T) {
+	tests := []struct {
+		name            string
+		header          string
+		expectedTraceID string
+		expectedParent  string
+		expectedSampled bool
+	}{
+		{
+			name:            "Sampled",
+			header:          "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1",
+			expectedTraceID: "1-5759e988-bd862e3fe1be46a994272793",
+			expectedParent:  "53995c3f42cd8ad8",
+			expectedSampled: true,
+		},
+		{
+			name:            "Not sampled",
+			header:          "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0",
+			expectedTraceID: "1-5759e988-bd862e3fe1be46a994272793",
+			expectedParent:  "53995c3f42cd8ad8",
+		},
+		{
+			name:            "No parent",
+			header:          "Root=1-5759e988-bd862e3fe1be46a994272793;Sampled=1",
+			expectedTraceID: "1-5759e988-bd862e3fe1be46a994272793",
+		},
+		{
+			name: "Empty",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			traceID, parentID, sampled := parseTraceHeader(tc.header)
+			if traceID != tc.expectedTraceID || parentID != tc.expectedParent || sampled != tc.expectedSampled {
+				t.Errorf("Expected %q, %q (%t), got %q, %q (%t)", tc.expectedTraceID, tc.expectedParent, tc.expectedSampled, traceID, parentID, sampled)
+			}
+		})
+	}
+}
+
+func TestXRayTracer(t *testing.T) {
+	daemon, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer daemon.Close()
+
+	tracer, err := NewXRayTracer(daemon.LocalAddr().String())
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	header := "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=0"
+	tracer.header = func() string { return header }
+
+	start := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	tracer.Trace(StagePrompt, start, start.Add(time.Millisecond))
+	header = "Root=1-5759e988-bd862e3fe1be46a994272793;Parent=53995c3f42cd8ad8;Sampled=1"
+	tracer.Trace(StageBedrock, start, start.Add(800*time.Millisecond))
+
+	buf := make([]byte, 64*1024)
+	_ = daemon.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := daemon.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("Expected a subsegment sent, got: %v", err)
+	}
+
+	prefix, document, ok := bytes.Cut(buf[:n], []byte("\n"))
+	if !ok || string(prefix)+"\n" != xrayHeader {
+		t.Fatalf("Expected the X-Ray header first, got %q", buf[:n])
+	}
+	var sent subsegment
+	if err := json.Unmarshal(document, &sent); err != nil {
+		t.Fatalf("Failed to unmarshal subsegment: %v", err)
+	}
+	if sent.Name != StageBedrock || sent.TraceID != "1-5759e988-bd862e3fe1be46a994272793" || sent.ParentID != "53995c3f42cd8ad8" || sent.Type != "subsegment" || len(sent.ID) != 16 {
+		t.Errorf("Expected the sampled bedrock stage as a subsegment, got %+v", sent)
+	}
+	if duration := sent.EndTime - sent.StartTime; duration < 0.799 || duration > 0.801 {
+		t.Errorf("Expected a subsegment of 0.8s, got %vs", duration)
+	}
+}
@@ -0,0 +1,27 @@
+package extension_test
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// giteaCommenter is what a third-party Gitea integration might look like.
+type giteaCommenter struct {
+	comments []string
+}
+
+func (g *giteaCommenter) Comment(ctx context.Context, target extension.Target, body string) error {
+	g.comments = append(g.comments, fmt.Sprintf("%s@%s: %s", target.Repository, target.CommitSHA, body))
+	return nil
+}
+
+func ExampleVCS() {
+	var vcs extension.VCS = &giteaCommenter{}
+
+	_ = vcs.Comment(context.Background(), extension.Target{Repository: "octo/leaves", CommitSHA: "abc123"}, "leaves fall")
+
+	fmt.Println(vcs.(*giteaCommenter).comments[0])
+	// Output: octo/leaves@abc123: leaves fall
+}
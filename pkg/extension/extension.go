@@ -0,0 +1,87 @@
+// Package extension defines the interfaces the haiku service is built from,
+// so that plugins such as a Gitea commenter or a Mattermost notifier can be
+// written outside this repository.
+//
+// The package only depends on the standard library. Within a major version,
+// existing interfaces and types are not changed incompatibly: methods are not
+// added to interfaces and struct fields are not removed or retyped, though
+// new fields and new interfaces may be added. The built-in implementations are
+// checked against these interfaces at compile time.
+package extension
+
+import (
+	"context"
+	"time"
+)
+
+// Provider generates text from a prompt, e.g. a hosted language model.
+type Provider interface {
+	Generate(ctx context.Context, prompt string, opts GenerateOptions) (Generation, error)
+}
+
+// GenerateOptions tune a single generation. Zero values select the provider's
+// defaults.
+type GenerateOptions struct {
+	ModelID     string
+	System      string
+	MaxTokens   int
+	Temperature float64
+	// Deterministic asks for the likeliest text, e.g. at temperature 0, in
+	// place of Temperature, so that the same prompt gets the same reply.
+	Deterministic bool
+}
+
+// Generation is the text a Provider generated and what it cost.
+type Generation struct {
+	Text         string
+	ModelID      string
+	InputTokens  int
+	OutputTokens int
+	// Warnings describe options the provider adjusted or ignored.
+	Warnings []string
+}
+
+// Storage stores generated artifacts, such as share cards and audio, and
+// issues time-limited links to them.
+type Storage interface {
+	Put(ctx context.Context, key string, contentType string, body []byte) error
+	PresignGet(ctx context.Context, key string, ttl time.Duration) (string, error)
+}
+
+// Card is what a Renderer draws: the haiku and where it came from.
+type Card struct {
+	Haiku      string
+	Subject    string // First line of the commit message
+	Repository string // Repository name, when known
+}
+
+// Renderer draws a haiku card in a single format.
+type Renderer interface {
+	ContentType() string
+	Render(card Card) ([]byte, error)
+}
+
+// Notification announces a newly generated haiku.
+type Notification struct {
+	Haiku         string
+	CommitMessage string
+	Repository    string
+}
+
+// Notifier delivers notifications, e.g. to a chat channel.
+type Notifier interface {
+	Notify(ctx context.Context, notification Notification) error
+}
+
+// Target identifies the commit or pull request a haiku is posted back to.
+type Target struct {
+	Repository     string // Repository path, e.g. owner/name or group/subgroup/name
+	CommitSHA      string // Commit to comment on, for push events
+	PullRequest    int    // Pull or merge request to comment on, when set
+	InstallationID int64  // GitHub App installation that received the event
+}
+
+// VCS posts a rendered comment to a code host.
+type VCS interface {
+	Comment(ctx context.Context, target Target, body string) error
+}
@@ -0,0 +1,53 @@
+package extension_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/bedrock"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/clients/storage"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/render"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/haiku"
+	"github.com/brianherrera/commits-fall-like-leaves/internal/service/webhook"
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// The built-in implementations must keep satisfying the public interfaces;
+// these fail to compile if either side drifts.
+var (
+	_ extension.Provider = (*bedrock.BedrockClient)(nil)
+	_ extension.Storage  = (*storage.S3Client)(nil)
+	_ extension.Renderer = render.SVGRenderer{}
+	_ extension.Renderer = render.PNGRenderer{}
+	_ extension.VCS      = (*webhook.GitHubCommenter)(nil)
+)
+
+// The service accepts extension implementations wherever it takes the
+// corresponding internal interface.
+var (
+	_ haiku.ObjectStore = extension.Storage(nil)
+	_ webhook.Commenter = extension.VCS(nil)
+)
+
+type staticProvider struct{}
+
+func (staticProvider) Generate(ctx context.Context, prompt string, opts extension.GenerateOptions) (extension.Generation, error) {
+	return extension.Generation{
+		Text:    "Old cracks mended now\nthe login door swings open\nquiet in the logs",
+		ModelID: "static",
+	}, nil
+}
+
+func TestProviderPlugsIntoHaikuService(t *testing.T) {
+	service := haiku.NewHaikuService(haiku.NewProviderClient(staticProvider{}), nil)
+
+	response, err := service.CreateHaiku(context.Background(), haiku.HaikuCommitRequest{
+		CommitMessage: "fix: resolved login issue",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error but got: %v", err)
+	}
+	if response.Metadata.Model != "static" {
+		t.Errorf("Expected the provider's model in metadata, got %q", response.Metadata.Model)
+	}
+}
@@ -0,0 +1,411 @@
+package plugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+const (
+	DefaultStartTimeout = 5 * time.Second
+	DefaultCallTimeout  = 30 * time.Second
+	DefaultMaxRestarts  = 3
+
+	// stopTimeout is how long a plugin has to exit after its stdin is closed
+	// before it is killed.
+	stopTimeout = 2 * time.Second
+)
+
+type Config struct {
+	Path string   // Plugin executable
+	Args []string // Arguments passed to the plugin
+	// Env is the plugin's entire environment. Nothing is inherited from the
+	// host, so a plugin only sees credentials it is explicitly given.
+	Env          []string
+	StartTimeout time.Duration // How long the plugin has to complete its handshake (default: 5s)
+	CallTimeout  time.Duration // How long a call may run before the plugin is killed (default: 30s)
+	MaxRestarts  int           // How many times an exited plugin is restarted (default: 3)
+}
+
+// Client manages one plugin process. Calls are sent over a single process
+// that is started by Start and restarted, up to MaxRestarts times, when it
+// exits or hangs. Plugin failures are returned as errors; they never take the
+// host down.
+type Client struct {
+	config Config
+	name   string
+
+	mu           sync.Mutex
+	proc         *process
+	restarts     int
+	capabilities []string
+	closed       bool
+}
+
+// Start launches the plugin and waits for its handshake.
+func Start(ctx context.Context, config Config) (*Client, error) {
+	if config.StartTimeout <= 0 {
+		config.StartTimeout = DefaultStartTimeout
+	}
+	if config.CallTimeout <= 0 {
+		config.CallTimeout = DefaultCallTimeout
+	}
+	if config.MaxRestarts <= 0 {
+		config.MaxRestarts = DefaultMaxRestarts
+	}
+
+	client := &Client{
+		config: config,
+		name:   filepath.Base(config.Path),
+	}
+
+	proc, err := client.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	client.proc = proc
+	client.capabilities = proc.capabilities
+
+	return client, nil
+}
+
+// Provider returns the plugin's provider, or nil when it does not offer one.
+func (c *Client) Provider() extension.Provider {
+	if !slices.Contains(c.capabilities, CapabilityProvider) {
+		return nil
+	}
+	return &pluginProvider{client: c}
+}
+
+// Notifier returns the plugin's notifier, or nil when it does not offer one.
+func (c *Client) Notifier() extension.Notifier {
+	if !slices.Contains(c.capabilities, CapabilityNotifier) {
+		return nil
+	}
+	return &pluginNotifier{client: c}
+}
+
+// Close asks the plugin to exit by closing its stdin, and kills it if it has
+// not exited shortly after.
+func (c *Client) Close(ctx context.Context) error {
+	c.mu.Lock()
+	c.closed = true
+	proc := c.proc
+	c.proc = nil
+	c.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+	return proc.stop(ctx)
+}
+
+// running returns the live plugin process, restarting it when it has exited.
+func (c *Client) running(ctx context.Context) (*process, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, fmt.Errorf("%w: %s is closed", ErrExited, c.name)
+	}
+	if c.proc != nil && !c.proc.exited() {
+		return c.proc, nil
+	}
+	if c.restarts >= c.config.MaxRestarts {
+		return nil, fmt.Errorf("%w: %s restarted %d times", ErrExited, c.name, c.restarts)
+	}
+
+	c.restarts++
+	slog.Warn(fmt.Sprintf("[PLUGIN %s] restarting plugin (%d of %d)", c.name, c.restarts, c.config.MaxRestarts))
+
+	proc, err := c.start(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.proc = proc
+	return proc, nil
+}
+
+func (c *Client) start(ctx context.Context) (*process, error) {
+	cmd := exec.Command(c.config.Path, c.config.Args...) // #nosec G204 -- plugins are configured by the operator
+	cmd.Env = append([]string{}, c.config.Env...)
+	cmd.Stderr = &logWriter{prefix: "[PLUGIN " + c.name + "] "}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHandshake, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHandshake, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: starting %s: %v", ErrHandshake, c.name, err)
+	}
+
+	proc := &process{
+		cmd:       cmd,
+		stdin:     stdin,
+		encoder:   json.NewEncoder(stdin),
+		pending:   make(map[uint64]chan response),
+		done:      make(chan struct{}),
+		reaped:    make(chan struct{}),
+		handshake: make(chan handshake, 1),
+	}
+	go proc.read(stdout)
+
+	timer := time.NewTimer(c.config.StartTimeout)
+	defer timer.Stop()
+
+	select {
+	case hello := <-proc.handshake:
+		if hello.Protocol != ProtocolVersion {
+			proc.kill()
+			return nil, fmt.Errorf("%w: %s speaks protocol %d, want %d", ErrHandshake, c.name, hello.Protocol, ProtocolVersion)
+		}
+		proc.capabilities = hello.Capabilities
+		return proc, nil
+	case <-proc.done:
+		return nil, fmt.Errorf("%w: %s exited during handshake", ErrHandshake, c.name)
+	case <-timer.C:
+		proc.kill()
+		return nil, fmt.Errorf("%w: %s did not complete its handshake within %s", ErrHandshake, c.name, c.config.StartTimeout)
+	case <-ctx.Done():
+		proc.kill()
+		return nil, ctx.Err()
+	}
+}
+
+// call sends one request and decodes its result into out.
+func (c *Client) call(ctx context.Context, method string, params any, out any) error {
+	proc, err := c.running(ctx)
+	if err != nil {
+		return err
+	}
+
+	responses, err := proc.send(method, params)
+	if err != nil {
+		proc.kill()
+		return fmt.Errorf("%w: %s: %v", ErrExited, c.name, err)
+	}
+
+	timer := time.NewTimer(c.config.CallTimeout)
+	defer timer.Stop()
+
+	var resp response
+	select {
+	case resp = <-responses:
+	case <-proc.done:
+		// The plugin may have answered just before exiting.
+		select {
+		case resp = <-responses:
+		default:
+			return fmt.Errorf("%w: %s exited during %s", ErrExited, c.name, method)
+		}
+	case <-timer.C:
+		// A hung plugin would block every later call, so it is replaced.
+		slog.Warn(fmt.Sprintf("[PLUGIN %s] %s timed out after %s, killing plugin", c.name, method, c.config.CallTimeout))
+		proc.kill()
+		return fmt.Errorf("%w: %s after %s", ErrTimeout, method, c.config.CallTimeout)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if resp.Error != "" {
+		return fmt.Errorf("%w: %s: %s", ErrCall, method, resp.Error)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, out); err != nil {
+		return fmt.Errorf("%w: %s: decoding result: %v", ErrCall, method, err)
+	}
+	return nil
+}
+
+// process is a single run of a plugin executable.
+type process struct {
+	cmd          *exec.Cmd
+	stdin        io.WriteCloser
+	encoder      *json.Encoder
+	capabilities []string
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan response
+
+	handshake chan handshake
+	killed    atomic.Bool
+	done      chan struct{} // closed once stdout is drained
+	reaped    chan struct{} // closed once the process has been waited on
+}
+
+func (p *process) send(method string, params any) (<-chan response, error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	responses := make(chan response, 1)
+	p.pending[p.nextID] = responses
+
+	if err := p.encoder.Encode(request{ID: p.nextID, Method: method, Params: raw}); err != nil {
+		delete(p.pending, p.nextID)
+		return nil, err
+	}
+	return responses, nil
+}
+
+// read delivers the handshake and then each response to its caller until the
+// plugin closes stdout, and then reaps the process.
+func (p *process) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	// An unreadable handshake is delivered as protocol 0, which the host rejects.
+	if scanner.Scan() {
+		var hello handshake
+		_ = json.Unmarshal(scanner.Bytes(), &hello)
+		p.handshake <- hello
+	}
+
+	for scanner.Scan() {
+		var resp response
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			slog.Warn(fmt.Sprintf("[PLUGIN] discarding malformed response: %v", err))
+			continue
+		}
+
+		p.mu.Lock()
+		responses, ok := p.pending[resp.ID]
+		delete(p.pending, resp.ID)
+		p.mu.Unlock()
+
+		if ok {
+			responses <- resp
+		}
+	}
+
+	close(p.done)
+	_ = p.cmd.Wait()
+	close(p.reaped)
+}
+
+func (p *process) exited() bool {
+	if p.killed.Load() {
+		return true
+	}
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *process) kill() {
+	p.killed.Store(true)
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+}
+
+func (p *process) stop(ctx context.Context) error {
+	_ = p.stdin.Close()
+
+	timer := time.NewTimer(stopTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-p.reaped:
+		return nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	p.kill()
+	<-p.reaped
+	return nil
+}
+
+type pluginProvider struct {
+	client *Client
+}
+
+func (p *pluginProvider) Generate(ctx context.Context, prompt string, opts extension.GenerateOptions) (extension.Generation, error) {
+	var result generation
+	err := p.client.call(ctx, methodGenerate, generateParams{
+		Prompt: prompt,
+		Options: generateOptions{
+			ModelID:     opts.ModelID,
+			System:      opts.System,
+			MaxTokens:   opts.MaxTokens,
+			Temperature: opts.Temperature,
+		},
+	}, &result)
+	if err != nil {
+		return extension.Generation{}, err
+	}
+
+	return extension.Generation{
+		Text:         result.Text,
+		ModelID:      result.ModelID,
+		InputTokens:  result.InputTokens,
+		OutputTokens: result.OutputTokens,
+		Warnings:     result.Warnings,
+	}, nil
+}
+
+type pluginNotifier struct {
+	client *Client
+}
+
+func (n *pluginNotifier) Notify(ctx context.Context, note extension.Notification) error {
+	return n.client.call(ctx, methodNotify, notification{
+		Haiku:         note.Haiku,
+		CommitMessage: note.CommitMessage,
+		Repository:    note.Repository,
+	}, nil)
+}
+
+// logWriter forwards plugin stderr to the host log a line at a time.
+type logWriter struct {
+	prefix string
+
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *logWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(data)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// Keep the partial line for the next write.
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		slog.Info(w.prefix + line[:len(line)-1])
+	}
+	return len(data), nil
+}
@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// helperEnv makes the test binary act as a plugin; see TestHelperPlugin.
+const helperEnv = "PLUGIN_TEST_HELPER"
+
+type echoProvider struct{}
+
+func (echoProvider) Generate(ctx context.Context, prompt string, opts extension.GenerateOptions) (extension.Generation, error) {
+	switch prompt {
+	case "panic":
+		panic("provider bug")
+	case "fail":
+		return extension.Generation{}, errors.New("provider failed")
+	case "crash":
+		os.Exit(3)
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	return extension.Generation{Text: "echo: " + prompt, ModelID: opts.ModelID, OutputTokens: 3}, nil
+}
+
+// TestHelperPlugin is not a real test: when run with helperEnv set, the test
+// binary serves the protocol on stdin and stdout so the host side can be
+// tested against a real subprocess.
+func TestHelperPlugin(t *testing.T) {
+	switch os.Getenv(helperEnv) {
+	case "":
+		return
+	case "provider":
+		_ = Serve(Plugin{Provider: echoProvider{}})
+	case "silent":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+func startHelper(t *testing.T, mode string, config Config) *Client {
+	t.Helper()
+
+	config.Path = os.Args[0]
+	config.Args = []string{"-test.run=^TestHelperPlugin$"}
+	config.Env = []string{helperEnv + "=" + mode}
+
+	client, err := Start(context.Background(), config)
+	if err != nil {
+		t.Fatalf("Expected plugin to start, got %v", err)
+	}
+	t.Cleanup(func() { _ = client.Close(context.Background()) })
+	return client
+}
+
+func TestPluginProvider(t *testing.T) {
+	client := startHelper(t, "provider", Config{CallTimeout: 500 * time.Millisecond, MaxRestarts: 1})
+
+	if client.Notifier() != nil {
+		t.Error("Expected no notifier capability")
+	}
+	provider := client.Provider()
+	if provider == nil {
+		t.Fatal("Expected a provider capability")
+	}
+
+	tests := []struct {
+		name         string
+		prompt       string
+		expectedText string
+		errorIs      error
+	}{
+		{name: "Generates", prompt: "leaves", expectedText: "echo: leaves"},
+		{name: "Provider error", prompt: "fail", errorIs: ErrCall},
+		{name: "Panic is contained", prompt: "panic", errorIs: ErrCall},
+		{name: "Still serving after panic", prompt: "again", expectedText: "echo: again"},
+		{name: "Hang is killed", prompt: "hang", errorIs: ErrTimeout},
+		{name: "Restarted after hang", prompt: "back", expectedText: "echo: back"},
+		{name: "Crash", prompt: "crash", errorIs: ErrExited},
+		{name: "Restart budget exhausted", prompt: "gone", errorIs: ErrExited},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := provider.Generate(context.Background(), tc.prompt, extension.GenerateOptions{ModelID: "static"})
+
+			if tc.errorIs != nil {
+				if !errors.Is(err, tc.errorIs) {
+					t.Errorf("Expected error to wrap %v, got %v", tc.errorIs, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error but got: %v", err)
+			}
+			if result.Text != tc.expectedText || result.ModelID != "static" || result.OutputTokens != 3 {
+				t.Errorf("Expected %q from model static, got %+v", tc.expectedText, result)
+			}
+		})
+	}
+}
+
+func TestPluginStartFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{
+			name:   "Missing executable",
+			config: Config{Path: "/nonexistent/plugin"},
+		},
+		{
+			name: "No handshake",
+			config: Config{
+				Path:         os.Args[0],
+				Args:         []string{"-test.run=^TestHelperPlugin$"},
+				Env:          []string{helperEnv + "=silent"},
+				StartTimeout: 200 * time.Millisecond,
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Start(context.Background(), tc.config)
+			if !errors.Is(err, ErrHandshake) {
+				t.Errorf("Expected error to wrap %v, got %v", ErrHandshake, err)
+			}
+		})
+	}
+}
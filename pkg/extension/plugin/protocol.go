@@ -0,0 +1,88 @@
+// Package plugin runs extensions as separate processes that speak JSON over
+// stdin and stdout, so self-hosters can add providers and notifiers without
+// forking or rebuilding the service.
+//
+// A plugin is any executable that calls Serve from its main function:
+//
+//	func main() {
+//		if err := plugin.Serve(plugin.Plugin{Notifier: &myNotifier{}}); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// The host starts it with Start. On startup the plugin writes a handshake
+// line naming its protocol version and capabilities, then answers one JSON
+// request per line with one JSON response per line. Plugins must log to
+// stderr, which the host forwards to its own log; stdout carries the protocol.
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ProtocolVersion is bumped on incompatible protocol changes. The host refuses
+// plugins that speak a different version.
+const ProtocolVersion = 1
+
+// Capabilities a plugin can offer.
+const (
+	CapabilityProvider = "provider"
+	CapabilityNotifier = "notifier"
+)
+
+const (
+	methodGenerate = "Provider.Generate"
+	methodNotify   = "Notifier.Notify"
+)
+
+var (
+	ErrHandshake   = errors.New("plugin handshake failed")
+	ErrUnsupported = errors.New("plugin does not support this capability")
+	ErrExited      = errors.New("plugin exited")
+	ErrTimeout     = errors.New("plugin call timed out")
+	ErrCall        = errors.New("plugin call failed")
+)
+
+type handshake struct {
+	Protocol     int      `json:"protocol"`
+	Capabilities []string `json:"capabilities"`
+}
+
+type request struct {
+	ID     uint64          `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+type generateParams struct {
+	Prompt  string          `json:"prompt"`
+	Options generateOptions `json:"options"`
+}
+
+type generateOptions struct {
+	ModelID     string  `json:"modelId,omitempty"`
+	System      string  `json:"system,omitempty"`
+	MaxTokens   int     `json:"maxTokens,omitempty"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+type generation struct {
+	Text         string   `json:"text"`
+	ModelID      string   `json:"modelId,omitempty"`
+	InputTokens  int      `json:"inputTokens,omitempty"`
+	OutputTokens int      `json:"outputTokens,omitempty"`
+	Warnings     []string `json:"warnings,omitempty"`
+}
+
+type notification struct {
+	Haiku         string `json:"haiku"`
+	CommitMessage string `json:"commitMessage,omitempty"`
+	Repository    string `json:"repository,omitempty"`
+}
@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/brianherrera/commits-fall-like-leaves/pkg/extension"
+)
+
+// maxLineSize bounds a single protocol message.
+const maxLineSize = 4 << 20
+
+// Plugin is what a plugin process offers. Nil implementations are not
+// advertised.
+type Plugin struct {
+	Provider extension.Provider
+	Notifier extension.Notifier
+}
+
+func (p Plugin) capabilities() []string {
+	capabilities := []string{}
+	if p.Provider != nil {
+		capabilities = append(capabilities, CapabilityProvider)
+	}
+	if p.Notifier != nil {
+		capabilities = append(capabilities, CapabilityNotifier)
+	}
+	return capabilities
+}
+
+// Serve answers host requests on stdin and stdout until the host closes stdin.
+func Serve(p Plugin) error {
+	return ServeIO(context.Background(), os.Stdin, os.Stdout, p)
+}
+
+// ServeIO answers host requests read from r, writing responses to w. Requests
+// are handled one at a time, and a panicking handler is reported to the host
+// as a failed call rather than ending the plugin.
+func ServeIO(ctx context.Context, r io.Reader, w io.Writer, p Plugin) error {
+	encoder := json.NewEncoder(w)
+	if err := encoder.Encode(handshake{Protocol: ProtocolVersion, Capabilities: p.capabilities()}); err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if err := encoder.Encode(response{Error: fmt.Sprintf("malformed request: %v", err)}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := encoder.Encode(p.handle(ctx, req)); err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (p Plugin) handle(ctx context.Context, req request) (resp response) {
+	resp.ID = req.ID
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			resp.Result = nil
+			resp.Error = fmt.Sprintf("plugin panicked: %v", recovered)
+		}
+	}()
+
+	var result any
+	var err error
+
+	switch req.Method {
+	case methodGenerate:
+		result, err = p.generate(ctx, req.Params)
+	case methodNotify:
+		result, err = p.notify(ctx, req.Params)
+	default:
+		err = fmt.Errorf("unknown method %q", req.Method)
+	}
+
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+
+	resp.Result, err = json.Marshal(result)
+	if err != nil {
+		resp.Error = fmt.Sprintf("encoding result: %v", err)
+	}
+	return resp
+}
+
+func (p Plugin) generate(ctx context.Context, raw json.RawMessage) (any, error) {
+	if p.Provider == nil {
+		return nil, ErrUnsupported
+	}
+
+	var params generateParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+
+	result, err := p.Provider.Generate(ctx, params.Prompt, extension.GenerateOptions{
+		ModelID:     params.Options.ModelID,
+		System:      params.Options.System,
+		MaxTokens:   params.Options.MaxTokens,
+		Temperature: params.Options.Temperature,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return generation{
+		Text:         result.Text,
+		ModelID:      result.ModelID,
+		InputTokens:  result.InputTokens,
+		OutputTokens: result.OutputTokens,
+		Warnings:     result.Warnings,
+	}, nil
+}
+
+func (p Plugin) notify(ctx context.Context, raw json.RawMessage) (any, error) {
+	if p.Notifier == nil {
+		return nil, ErrUnsupported
+	}
+
+	var params notification
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+
+	return struct{}{}, p.Notifier.Notify(ctx, extension.Notification{
+		Haiku:         params.Haiku,
+		CommitMessage: params.CommitMessage,
+		Repository:    params.Repository,
+	})
+}